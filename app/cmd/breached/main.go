@@ -0,0 +1,182 @@
+// Command breached rebuilds the breached password filter that ships inside
+// the server binary.
+//
+// The input is one entry per line, either plaintext passwords (a public
+// corpus such as a SecLists common passwords list) or SHA-1 hashes in the
+// "HASH" or "HASH:COUNT" form Have I Been Pwned publishes:
+//
+//	go run ./cmd/breached -in passwords.txt
+//	go run ./cmd/breached -in pwned-passwords-sha1.txt -format sha1 -min-count 100
+//
+// With -variants, every plaintext entry is also added capitalised and with the
+// suffixes people reach for to satisfy composition rules ("123!", "2024"),
+// which is how "Password123!" ends up in the filter from the word "password".
+//
+// The filter in the repository is built from seedList, a public list recorded
+// in seedSource, with variants:
+//
+//	go run ./cmd/breached -variants
+//
+// The seed is taken from the source as it is. seedEntries is checked on every
+// rebuild, so the list cannot drift from what is recorded here; a new source
+// means a new list and new constants.
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"server/util/bloom"
+)
+
+const (
+	seedList = "cmd/breached/burnett-top-10k.txt"
+
+	// seedSource is Mark Burnett's list of the 10,000 most common passwords,
+	// as zxcvbn ships it: without the entries zxcvbn's other matchers catch,
+	// most common first. Extracted from data/data/Passwords.json in
+	// github.com/ccojocar/zxcvbn-go v1.0.4 (MIT), one password per line.
+	seedSource  = "Mark Burnett's top 10,000 passwords (xato.net), via zxcvbn-go v1.0.4 data/data/Passwords.json"
+	seedEntries = 7141
+)
+
+// variantSuffixes are appended to plaintext entries under -variants.
+var variantSuffixes = []string{
+	"", "1", "12", "123", "1234", "12345", "123456",
+	"!", "1!", "12!", "123!", "1234!", "12345!", "123456!",
+	"@1", "@123", "@1234", "#1", "#123",
+	"2023", "2024", "2025", "2026", "2023!", "2024!", "2025!", "2026!",
+}
+
+func main() {
+	in := flag.String("in", seedList, "input list, one entry per line (- for stdin)")
+	out := flag.String("out", "util/securityutil/breached-passwords.bloom", "where to write the filter")
+	format := flag.String("format", "plain", "input format: plain or sha1")
+	variants := flag.Bool("variants", false, "also add common variants of plaintext entries")
+	minCount := flag.Int("min-count", 0, "sha1 format: skip hashes seen fewer times than this")
+	falsePositiveRate := flag.Float64("fp", 0.001, "false positive rate to size the filter for")
+	flag.Parse()
+
+	if *in == "" || (*format != "plain" && *format != "sha1") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			log.Fatalf("Could not open the input: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	digests, entries, err := readDigests(input, *format, *variants, *minCount)
+	if err != nil {
+		log.Fatalf("Could not read the input: %v", err)
+	}
+	if *in == seedList && entries != seedEntries {
+		log.Fatalf("%s has %d entries, but %d are recorded from %s", seedList, entries, seedEntries, seedSource)
+	}
+
+	filter := bloom.New(len(digests), *falsePositiveRate)
+	for digest := range digests {
+		filter.Add(digest)
+	}
+
+	output, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Could not create the output: %v", err)
+	}
+
+	size, err := filter.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("Could not write the filter: %v", err)
+	}
+
+	fmt.Printf("Wrote %d digests from %d entries to %s (%d bytes)\n", len(digests), entries, *out, size)
+}
+
+// readDigests returns the digests to add and how many entries the input had.
+func readDigests(input io.Reader, format string, variants bool, minCount int) (map[[sha1.Size]byte]struct{}, int, error) {
+	digests := make(map[[sha1.Size]byte]struct{})
+	entries := 0
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimRight(scanner.Text(), "\r")
+		if entry == "" {
+			continue
+		}
+		entries++
+
+		if format == "sha1" {
+			digest, ok, err := parseHashLine(entry, minCount)
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", line, err)
+			}
+			if ok {
+				digests[digest] = struct{}{}
+			}
+			continue
+		}
+
+		if !variants {
+			digests[sha1.Sum([]byte(entry))] = struct{}{}
+			continue
+		}
+
+		for _, base := range []string{entry, capitalise(entry)} {
+			for _, suffix := range variantSuffixes {
+				digests[sha1.Sum([]byte(base+suffix))] = struct{}{}
+			}
+		}
+	}
+
+	return digests, entries, scanner.Err()
+}
+
+// parseHashLine reads "HASH" or "HASH:COUNT". ok is false for hashes below
+// minCount.
+func parseHashLine(entry string, minCount int) (digest [sha1.Size]byte, ok bool, err error) {
+	hash, countText, hasCount := strings.Cut(entry, ":")
+
+	if hasCount && minCount > 0 {
+		count, err := strconv.Atoi(strings.TrimSpace(countText))
+		if err != nil {
+			return digest, false, fmt.Errorf("invalid count %q", countText)
+		}
+		if count < minCount {
+			return digest, false, nil
+		}
+	}
+
+	raw, err := hex.DecodeString(strings.TrimSpace(hash))
+	if err != nil || len(raw) != sha1.Size {
+		return digest, false, fmt.Errorf("invalid SHA-1 %q", hash)
+	}
+
+	copy(digest[:], raw)
+	return digest, true, nil
+}
+
+func capitalise(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
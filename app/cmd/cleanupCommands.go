@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"server/internal/application/media"
+	"server/internal/domain/image"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/storage"
+)
+
+// expiringTokens is a table of one-time tokens that can be swept.
+type expiringTokens interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func cleanupTokens(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	db := openDatabase()
+	tables := []struct {
+		name   string
+		tokens expiringTokens
+	}{
+		{"password reset", user.NewPasswordResetTokenRepository(db)},
+		{"email verification", user.NewEmailVerificationTokenRepository(db)},
+		{"email change", user.NewEmailChangeTokenRepository(db)},
+		{"account deletion", user.NewAccountDeletionTokenRepository(db)},
+		{"account unlock", user.NewAccountUnlockTokenRepository(db)},
+	}
+
+	for _, table := range tables {
+		deleted, err := table.tokens.DeleteExpired(ctx)
+		if err != nil {
+			return fmt.Errorf("%s tokens: %w", table.name, err)
+		}
+		fmt.Printf("%s: deleted %d expired tokens\n", table.name, deleted)
+	}
+
+	return nil
+}
+
+func cleanupOrphans(ctx context.Context, args []string) error {
+	flags := newFlags("cleanup", "orphans")
+	remove := flags.Bool("delete", false, "delete the orphans instead of listing them")
+	minAge := flags.Duration("min-age", 24*time.Hour, "leave uploads younger than this alone")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	store, err := storage.New()
+	if err != nil {
+		return err
+	}
+
+	db := openDatabase()
+	orphans := media.NewOrphanService(store, posts.NewPostRepository(db), image.NewImageRepository(db))
+	found, err := orphans.Find(ctx, *minAge)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var total int64
+	for _, orphan := range found {
+		fmt.Fprintf(table, "%s\t%d KB\t%s\n", orphan.Key, orphan.Bytes/1024, orphan.CreatedAt.Format(time.DateOnly))
+		total += orphan.Bytes
+	}
+	table.Flush()
+
+	if !*remove {
+		fmt.Printf("%d orphaned uploads, %d KB. Run with -delete to remove them.\n", len(found), total/1024)
+		return nil
+	}
+
+	deleted := orphans.Delete(ctx, found)
+	fmt.Printf("Deleted %d of %d orphaned uploads\n", deleted, len(found))
+	if deleted < len(found) {
+		return fmt.Errorf("%d could not be deleted", len(found)-deleted)
+	}
+
+	return nil
+}
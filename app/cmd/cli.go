@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"server/cmd/db/database"
+)
+
+// command is one administrative operation, run as "server <group> <name>".
+type command struct {
+	group   string
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands is everything the binary does besides serving. Each command
+// parses its own flags, so "server user create -h" shows that command's.
+var commands = []command{
+	{"migrate", "up", "", "apply every pending migration", migrateUp},
+	{"migrate", "down", "[-steps n]", "roll back the last migration, or the last n", migrateDown},
+	{"migrate", "status", "", "list migrations and which are applied", migrateStatus},
+	{"migrate", "force", "<version>", "mark a version as applied and clean, after fixing a failed migration by hand", migrateForce},
+	{"migrate", "create", "<name>", "write an empty up and down migration", migrateCreate},
+
+	{"user", "create", "[-admin] <email>", "create an active account; the password is read from standard input", userCreate},
+	{"user", "promote", "[-role ADMIN] <email>", "grant a role", userPromote},
+	{"user", "suspend", "<email>", "suspend an account and end its sessions", userSuspend},
+	{"user", "reset-password", "<email>", "clear the password and mail a reset link", userResetPassword},
+	{"user", "revoke-sessions", "<email>", "sign the account out everywhere", userRevokeSessions},
+
+	{"posts", "reindex", "", "rebuild the search index", postsReindex},
+	{"posts", "export", "[-o file]", "write every post as JSON", postsExport},
+	{"posts", "import", "[-author email] <file>", "create the posts of an export, skipping taken slugs", postsImport},
+	{"posts", "schedule", "<slug> <time>", "publish an unpublished post at an RFC 3339 time", postsSchedule},
+	{"posts", "publish-scheduled", "", "publish the scheduled posts that are due", postsPublishScheduled},
+
+	{"cleanup", "tokens", "", "delete expired one-time tokens", cleanupTokens},
+	{"cleanup", "orphans", "[-delete] [-min-age 24h]", "list uploads outside the media library that nothing refers to, and delete them with -delete", cleanupOrphans},
+
+	{"config", "check", "", "report configuration problems and check the database", configCheck},
+	{"config", "print", "", "show the configuration and where each value came from, secrets redacted", configPrint},
+}
+
+// errUsage means the arguments were wrong; the command's usage is printed
+// instead of an error.
+var errUsage = errors.New("usage")
+
+// actor is how changes made from the command line are attributed in logs.
+const actor = "cli"
+
+// run executes the command named by args and returns the exit status.
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "serve" {
+		serve()
+		return 0
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	if len(args) < 2 {
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.group != args[0] || cmd.name != args[1] {
+			continue
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		err := cmd.run(ctx, args[2:])
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(os.Stderr, "usage: server %s %s %s\n", cmd.group, cmd.name, cmd.args)
+			return 2
+		default:
+			fmt.Fprintf(os.Stderr, "server %s %s: %v\n", cmd.group, cmd.name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0]+" "+args[1])
+	printUsage(os.Stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: server [command]")
+	fmt.Fprintln(w, "\nWith no command the web server starts. Commands:")
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(table, "  %s %s %s\t%s\n", cmd.group, cmd.name, cmd.args, cmd.summary)
+	}
+	table.Flush()
+}
+
+// newFlags is a flag set for one command that reports errors instead of
+// exiting, so run decides the exit status.
+func newFlags(group, name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(group+" "+name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+// openDatabase connects with the server's settings. Unlike serve it leaves
+// the schema alone; only the migrate commands change it.
+func openDatabase() *sql.DB {
+	return database.ConnectDatabase()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"server/cmd/db/database"
+	"server/internal/config"
+	"server/internal/infrastructure/imaging"
+	"server/internal/infrastructure/storage"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// configCheck lists every configuration problem, then connects to the
+// database and compares its schema with the migrations on disk. It fails when
+// anything is wrong, so a deploy script can run it before switching over.
+func configCheck(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	problems := config.Check()
+	for _, problem := range problems {
+		fmt.Println("✗", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d configuration problems", len(problems))
+	}
+	fmt.Println("✓ configuration")
+
+	m, err := database.NewMigrator(openDatabase())
+	if err != nil {
+		return err
+	}
+	fmt.Println("✓ database connection")
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	migrations, err := database.ListMigrations()
+	if err != nil {
+		return err
+	}
+
+	var latest uint
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("migration %d failed part way; see \"migrate status\"", version)
+	case version < latest:
+		return fmt.Errorf("schema is at version %d, %d is available; run \"migrate up\"", version, latest)
+	case version > latest:
+		return fmt.Errorf("schema is at version %d, newer than this build's %d", version, latest)
+	}
+	fmt.Printf("✓ schema at version %d\n", version)
+
+	if _, err := storage.New(); err != nil {
+		return fmt.Errorf("%s storage: %w", config.StorageBackend(), err)
+	}
+	fmt.Printf("✓ %s storage\n", config.StorageBackend())
+
+	// A missing encoder only means fewer formats, so it is reported rather
+	// than failed on.
+	processor := imaging.NewProcessor(imaging.Options{
+		WebPEncoder: config.ImageWebPEncoder(),
+		AVIFEncoder: config.ImageAVIFEncoder(),
+	})
+	fmt.Printf("✓ image formats: %s\n", strings.Join(append([]string{imaging.JPEG, imaging.PNG}, processor.Formats()...), ", "))
+
+	return nil
+}
+
+// configPrint shows the configuration the server would run with. It prints
+// even when the configuration is invalid, since that is when it is most
+// wanted, and then lists the problems.
+func configPrint(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		fmt.Printf("# CONFIG_FILE=%s\n", path)
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if printErr := cfg.Print(os.Stdout); printErr != nil {
+		return printErr
+	}
+
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		for _, problem := range invalid.Problems {
+			fmt.Fprintln(os.Stderr, "✗", problem)
+		}
+		return fmt.Errorf("%d configuration problems", len(invalid.Problems))
+	}
+
+	return err
+}
@@ -10,13 +10,15 @@ import (
 	"os"
 	"path/filepath"
 	"server/internal/config"
+	"server/internal/infrastructure/tracing"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	pgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 func ConnectDatabase() *sql.DB {
@@ -24,10 +26,14 @@ func ConnectDatabase() *sql.DB {
 		config.DBHost(), config.DBPort(), config.DBUser(),
 		config.DBName(), config.DBPassword(), config.DBSSLMode())
 
-	db, err := sql.Open("pgx", connStr)
+	connConfig, err := pgx.ParseConfig(connStr)
 	if err != nil {
 		log.Fatalf("Could not connect to database: %v", err)
 	}
+	// Every statement gets a span; with tracing off the spans are no-ops.
+	connConfig.Tracer = tracing.QueryTracer{}
+
+	db := stdlib.OpenDB(*connConfig)
 
 	db.SetMaxOpenConns(config.DBMaxConns())
 	db.SetMaxIdleConns(config.DBMaxConns() / 2)
@@ -50,34 +56,44 @@ func ConnectDatabase() *sql.DB {
 func RunMigrations(db *sql.DB) {
 	slog.Info("Running database migrations..")
 
-	migrationsPath := getMigrationsPath()
+	m, err := NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Could not execute migrations: %v", err)
+	}
 
-	applyMigrations(db, migrationsPath)
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		log.Fatalf("Error applying migrations. Error=%v", err)
+	}
 
 	slog.Info("Successfully applied migrations")
 }
 
-func applyMigrations(db *sql.DB, path string) {
-	driver, err := pgx.WithInstance(db, &pgx.Config{})
+// NewMigrator is the migration runner over the migrations directory, for the
+// commands that step through migrations one at a time.
+func NewMigrator(db *sql.DB) (*migrate.Migrate, error) {
+	driver, err := migratepgx.WithInstance(db, &migratepgx.Config{})
 	if err != nil {
-		log.Fatalf("Could not execute migrations: %v", err)
+		return nil, err
 	}
 
+	path := getMigrationsPath()
 	m, err := migrate.NewWithDatabaseInstance(path, "pgx5", driver)
 	if err != nil {
-		log.Fatalf("Could not instantiate migrations. Path=%s Error=%v", path, err)
+		return nil, fmt.Errorf("could not instantiate migrations. Path=%s: %w", path, err)
 	}
 
-	err = m.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		log.Fatalf("Error applying migrations. Path=%s, Error=%v", path, err)
-	}
+	return m, nil
 }
 
 // getMigrationsPath resolves the migrations directory relative to the working
 // directory: ./app locally, /app in the container. It must not be derived from
 // runtime.Caller, which yields the build machine's source path.
 func getMigrationsPath() string {
+	return fmt.Sprintf("file://%s", migrationsDir())
+}
+
+func migrationsDir() string {
 	migrationsPath, err := filepath.Abs("cmd/db/migrations")
 	if err != nil {
 		log.Fatalf("Could not resolve migrations path: %v", err)
@@ -87,5 +103,5 @@ func getMigrationsPath() string {
 		log.Fatalf("Migrations folder not found at path: %s", migrationsPath)
 	}
 
-	return fmt.Sprintf("file://%s", migrationsPath)
+	return migrationsPath
 }
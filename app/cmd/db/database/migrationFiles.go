@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration is one migration in the migrations directory.
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// migrationFileName is "00012_email_outbox.up.sql": a zero padded sequence
+// number, a name and the direction.
+var migrationFileName = regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+
+// invalidNameChars are replaced in the names given to CreateMigration, so a
+// description typed with spaces still makes a sensible file name.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ListMigrations returns the migrations on disk in the order they apply.
+func ListMigrations() ([]Migration, error) {
+	entries, err := os.ReadDir(migrationsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{Version: uint(version), Name: match[2]})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// CreateMigration writes an empty up and down migration numbered after the
+// last one and returns their paths.
+func CreateMigration(name string) (string, string, error) {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("a migration needs a name")
+	}
+
+	migrations, err := ListMigrations()
+	if err != nil {
+		return "", "", err
+	}
+
+	next := uint(1)
+	if len(migrations) > 0 {
+		next = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := filepath.Join(migrationsDir(), fmt.Sprintf("%05d_%s", next, name))
+	up, down := base+".up.sql", base+".down.sql"
+
+	for _, path := range []string{up, down} {
+		// O_EXCL: never overwrite a migration someone already wrote.
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		if err := file.Close(); err != nil {
+			return "", "", err
+		}
+	}
+
+	return up, down, nil
+}
@@ -0,0 +1,27 @@
+-- Set once the owner of the address follows the link sent to it. Accounts that
+-- existed before verification was introduced are treated as verified: they
+-- were already receiving password reset links at that address.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+UPDATE users SET email_verified_at = created_at WHERE status = 'Active';
+
+-- Same shape as password_reset_tokens: only the SHA-256 of the token is
+-- stored, so a leaked table cannot be used to verify someone else's address.
+CREATE TABLE email_verification_tokens
+(
+  id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_email_verification_tokens_id PRIMARY KEY(id),
+  CONSTRAINT fk_email_verification_tokens_user_id FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_email_verification_tokens_hash ON email_verification_tokens (token_hash) WHERE used_at IS NULL;
+CREATE INDEX idx_email_verification_tokens_user ON email_verification_tokens (user_id, expires_at DESC);
+
+-- Backs the purge of registrations that were never confirmed.
+CREATE INDEX idx_users_unverified ON users (created_at) WHERE email_verified_at IS NULL;
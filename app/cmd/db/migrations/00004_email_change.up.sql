@@ -0,0 +1,19 @@
+-- A pending change of address. The new address only replaces the old one once
+-- its owner follows the link sent to it, so a typo or a hijacked session
+-- cannot move an account to an address nobody controls.
+CREATE TABLE email_change_tokens
+(
+  id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  new_email VARCHAR NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_email_change_tokens_id PRIMARY KEY(id),
+  CONSTRAINT fk_email_change_tokens_user_id FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_email_change_tokens_hash ON email_change_tokens (token_hash) WHERE used_at IS NULL;
+CREATE INDEX idx_email_change_tokens_user ON email_change_tokens (user_id, expires_at DESC);
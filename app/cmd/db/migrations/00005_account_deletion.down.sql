@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_deletion_scheduled;
+DROP TABLE IF EXISTS account_deletion_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
@@ -0,0 +1,24 @@
+-- Set once the owner confirms a deletion request. The account is anonymised
+-- when this passes; until then the owner can sign in and cancel.
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMPTZ;
+
+-- Confirms a deletion request from the account's inbox. Same shape as the
+-- other token tables: only the SHA-256 of the token is stored.
+CREATE TABLE account_deletion_tokens
+(
+  id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_account_deletion_tokens_id PRIMARY KEY(id),
+  CONSTRAINT fk_account_deletion_tokens_user_id FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_account_deletion_tokens_hash ON account_deletion_tokens (token_hash) WHERE used_at IS NULL;
+CREATE INDEX idx_account_deletion_tokens_user ON account_deletion_tokens (user_id, expires_at DESC);
+
+-- Backs the sweep that carries out deletions once their grace period is over.
+CREATE INDEX idx_users_deletion_scheduled ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,21 @@
+-- The per-user copies removed by the up migration are not restored; the roles
+-- still grant the same permissions.
+DELETE FROM users_roles
+WHERE role_id IN ('05643480-37c8-4ef7-aceb-23bae24bfdfa', 'f5d9c0ed-733b-480a-b65d-dba61d248591');
+
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions
+                        WHERE name IN ('admin:access', 'posts:create', 'posts:edit', 'posts:edit-own', 'posts:publish',
+                                       'posts:delete', 'media:upload', 'categories:manage', 'users:manage'));
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions
+                        WHERE name IN ('admin:access', 'posts:create', 'posts:edit', 'posts:edit-own', 'posts:publish',
+                                       'posts:delete', 'media:upload', 'categories:manage', 'users:manage'));
+
+DELETE FROM roles
+WHERE id IN ('05643480-37c8-4ef7-aceb-23bae24bfdfa', 'f5d9c0ed-733b-480a-b65d-dba61d248591');
+
+DELETE FROM permissions
+WHERE name IN ('admin:access', 'posts:create', 'posts:edit', 'posts:edit-own', 'posts:publish',
+               'posts:delete', 'media:upload', 'categories:manage', 'users:manage');
@@ -0,0 +1,46 @@
+-- The permission catalogue checked by the routes. Names must match the
+-- constants in internal/domain/user/permission.go.
+INSERT INTO permissions (id, name)
+VALUES ('f5e2082b-6abb-425f-b3f4-dfa16730a30d', 'admin:access'),
+       ('5ef1af36-2d27-41f8-9452-c3f447037e11', 'posts:create'),
+       ('306c0d5b-094c-4c22-81ac-d92114bf55cc', 'posts:edit'),
+       ('cda96dc8-3f7a-4de5-9a6a-76fca57f54bf', 'posts:edit-own'),
+       ('468963be-18f7-420c-b03b-f3d87c754b32', 'posts:publish'),
+       ('98ccb597-2122-4c12-a8e0-02773ac0d902', 'posts:delete'),
+       ('cb537daa-8033-4b15-a5f1-f53b93821fc2', 'media:upload'),
+       ('d52ff3b9-5cbc-41dc-98e1-e47ff2c7505b', 'categories:manage'),
+       ('6de85df6-8222-4f11-ab81-e6ff1c181f6c', 'users:manage');
+
+-- EDITOR writes and edits any post but cannot put anything live; AUTHOR
+-- writes posts and edits only their own.
+INSERT INTO roles (id, name)
+VALUES ('05643480-37c8-4ef7-aceb-23bae24bfdfa', 'EDITOR'),
+       ('f5d9c0ed-733b-480a-b65d-dba61d248591', 'AUTHOR');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name IN ('admin:access', 'posts:create', 'posts:edit', 'posts:edit-own', 'posts:publish',
+                 'posts:delete', 'media:upload', 'categories:manage', 'users:manage')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT '05643480-37c8-4ef7-aceb-23bae24bfdfa', id
+FROM permissions
+WHERE name IN ('admin:access', 'posts:create', 'posts:edit', 'media:upload');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT 'f5d9c0ed-733b-480a-b65d-dba61d248591', id
+FROM permissions
+WHERE name IN ('admin:access', 'posts:create', 'posts:edit-own', 'media:upload');
+
+-- Registration used to copy the role's permissions onto the user, so taking
+-- the role away left them behind. Permissions now come through the role, and
+-- users_permissions holds only grants made to the user directly.
+DELETE FROM users_permissions up
+USING users_roles ur, roles_permissions rp
+WHERE ur.user_id = up.user_id
+  AND rp.role_id = ur.role_id
+  AND rp.permission_id = up.permission_id;
@@ -0,0 +1,11 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'audit:read');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'audit:read');
+
+DELETE FROM permissions WHERE name = 'audit:read';
+
+DROP TRIGGER IF EXISTS trg_audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+DROP TABLE IF EXISTS audit_events;
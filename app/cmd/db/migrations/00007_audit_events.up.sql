@@ -0,0 +1,53 @@
+-- Security relevant events: sign-ins, session and password changes, and what
+-- administrators do to accounts and posts. Rows are only ever added; the
+-- retention sweep is the one thing that removes them.
+--
+-- actor_id has no foreign key so the trail outlives the accounts it mentions,
+-- including anonymised ones.
+CREATE TABLE audit_events
+(
+  id UUID NOT NULL,
+  occurred_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  action VARCHAR(50) NOT NULL,
+  outcome VARCHAR(10) NOT NULL,
+  -- Why the attempt failed, or what was changed: a role name, a status.
+  detail VARCHAR(100),
+  actor_id UUID,
+  -- The address typed by someone not signed in: on a sign-in that matched no
+  -- account, or on a password reset request. There is no actor to point at.
+  identifier VARCHAR(255),
+  target_type VARCHAR(20),
+  target_id VARCHAR(36),
+  ip VARCHAR(45),
+  user_agent VARCHAR(512),
+  request_id VARCHAR(36),
+
+  CONSTRAINT pk_audit_events_id PRIMARY KEY(id),
+  CONSTRAINT ck_audit_events_outcome CHECK (outcome IN ('success', 'failure'))
+);
+
+CREATE INDEX idx_audit_events_occurred ON audit_events (occurred_at DESC);
+CREATE INDEX idx_audit_events_actor ON audit_events (actor_id, occurred_at DESC);
+
+-- An audit trail that can be edited proves nothing, so updates are refused
+-- outright. Deletes stay possible for the retention sweep.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_audit_events_append_only
+BEFORE UPDATE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+INSERT INTO permissions (id, name)
+VALUES ('0b7c6f1e-5d2a-4f7e-9b1c-3a8e2d4f6c51', 'audit:read');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name = 'audit:read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,24 @@
+-- Failed sign-ins are counted per account, not per address, so spreading
+-- guesses over many addresses does not help. Kept in the database so every
+-- instance sees the same count and a restart does not reset it.
+ALTER TABLE users ADD COLUMN failed_login_count INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN last_failed_login_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMPTZ;
+
+-- Lets the owner of a locked account lift the lock from the notice emailed to
+-- them. Same shape as the other token tables: only the SHA-256 is stored.
+CREATE TABLE account_unlock_tokens
+(
+  id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_account_unlock_tokens_id PRIMARY KEY(id),
+  CONSTRAINT fk_account_unlock_tokens_user_id FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_account_unlock_tokens_hash ON account_unlock_tokens (token_hash) WHERE used_at IS NULL;
+CREATE INDEX idx_account_unlock_tokens_user ON account_unlock_tokens (user_id, expires_at DESC);
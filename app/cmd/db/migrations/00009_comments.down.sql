@@ -0,0 +1,9 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'comments:moderate');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'comments:moderate');
+
+DELETE FROM permissions WHERE name = 'comments:moderate';
+
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,39 @@
+-- Reader discussion under posts. A reply points at the comment it answers;
+-- the thread is built from parent_id when the post is rendered.
+--
+-- New comments wait in the moderation queue as 'pending'. Spam and deleted
+-- comments are kept rather than removed so a reply never loses its parent and
+-- the moderation decision stays visible to administrators.
+CREATE TABLE comments
+(
+  id UUID NOT NULL,
+  post_id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  parent_id UUID,
+  body TEXT NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  moderated_at TIMESTAMPTZ,
+  moderated_by UUID,
+
+  CONSTRAINT pk_comments_id PRIMARY KEY(id),
+  CONSTRAINT fk_comments_post_id FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  CONSTRAINT fk_comments_user_id FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT fk_comments_parent_id FOREIGN KEY(parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+  CONSTRAINT fk_comments_moderated_by FOREIGN KEY(moderated_by) REFERENCES users(id) ON DELETE SET NULL,
+  CONSTRAINT ck_comments_status CHECK (status IN ('pending', 'approved', 'spam', 'deleted'))
+);
+
+CREATE INDEX idx_comments_post ON comments (post_id, status, created_at);
+CREATE INDEX idx_comments_status ON comments (status, created_at);
+
+INSERT INTO permissions (id, name)
+VALUES ('3f9d2c71-8a4e-4b6f-a1d5-7c2e9b0f4a68', 'comments:moderate');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('ADMIN', 'EDITOR')
+  AND p.name = 'comments:moderate'
+ON CONFLICT DO NOTHING;
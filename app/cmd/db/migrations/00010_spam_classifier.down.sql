@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS spam_documents;
+DROP TABLE IF EXISTS spam_tokens;
@@ -0,0 +1,26 @@
+-- Word counts for the spam classifier, learned from moderation decisions.
+-- Each token records how many spam and how many legitimate ("ham") documents
+-- it appeared in; a document counts a token once however often it repeats.
+CREATE TABLE spam_tokens
+(
+  token VARCHAR(64) NOT NULL,
+  spam_count INTEGER NOT NULL DEFAULT 0,
+  ham_count INTEGER NOT NULL DEFAULT 0,
+
+  CONSTRAINT pk_spam_tokens_token PRIMARY KEY(token),
+  CONSTRAINT ck_spam_tokens_counts CHECK (spam_count >= 0 AND ham_count >= 0)
+);
+
+-- Every document the classifier has learned from, with the label it was
+-- taught. A moderator changing their mind moves the document to the other
+-- label instead of counting it twice. There is no foreign key: the id belongs
+-- to whatever was submitted (a comment today) and the counts outlive it.
+CREATE TABLE spam_documents
+(
+  id UUID NOT NULL,
+  label VARCHAR(4) NOT NULL,
+  trained_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_spam_documents_id PRIMARY KEY(id),
+  CONSTRAINT ck_spam_documents_label CHECK (label IN ('spam', 'ham'))
+);
@@ -0,0 +1,11 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'newsletter:manage');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'newsletter:manage');
+
+DELETE FROM permissions WHERE name = 'newsletter:manage';
+
+DROP TABLE IF EXISTS newsletter_sends;
+DROP TABLE IF EXISTS newsletter_consents;
+DROP TABLE IF EXISTS newsletter_subscribers;
@@ -0,0 +1,72 @@
+-- Newsletter subscribers. Signing up only records the address as 'pending';
+-- it takes the emailed confirmation link to become 'confirmed' (double opt
+-- in). Unsubscribing keeps the row as 'unsubscribed' so the consent history
+-- below still has something to belong to.
+CREATE TABLE newsletter_subscribers
+(
+  id UUID NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  status VARCHAR(12) NOT NULL DEFAULT 'pending',
+  confirm_token_hash VARCHAR(64),
+  confirm_expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  confirmed_at TIMESTAMPTZ,
+  unsubscribed_at TIMESTAMPTZ,
+
+  CONSTRAINT pk_newsletter_subscribers_id PRIMARY KEY(id),
+  CONSTRAINT uq_newsletter_subscribers_email UNIQUE(email),
+  CONSTRAINT ck_newsletter_subscribers_status CHECK (status IN ('pending', 'confirmed', 'unsubscribed'))
+);
+
+CREATE INDEX idx_newsletter_subscribers_status ON newsletter_subscribers (status, created_at);
+CREATE UNIQUE INDEX idx_newsletter_subscribers_token ON newsletter_subscribers (confirm_token_hash) WHERE confirm_token_hash IS NOT NULL;
+
+-- Proof of consent, kept for GDPR: every sign up, confirmation and
+-- unsubscription, with the wording the subscriber agreed to and where the
+-- request came from. Rows are only removed with their subscriber.
+CREATE TABLE newsletter_consents
+(
+  id UUID NOT NULL,
+  subscriber_id UUID NOT NULL,
+  action VARCHAR(12) NOT NULL,
+  consent_text TEXT NOT NULL DEFAULT '',
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_newsletter_consents_id PRIMARY KEY(id),
+  CONSTRAINT fk_newsletter_consents_subscriber_id FOREIGN KEY(subscriber_id) REFERENCES newsletter_subscribers(id) ON DELETE CASCADE,
+  CONSTRAINT ck_newsletter_consents_action CHECK (action IN ('subscribe', 'confirm', 'unsubscribe'))
+);
+
+CREATE INDEX idx_newsletter_consents_subscriber ON newsletter_consents (subscriber_id, created_at);
+
+-- One row per digest sent. The newest decides when the next one is due and
+-- which posts it covers.
+CREATE TABLE newsletter_sends
+(
+  id UUID NOT NULL,
+  subject TEXT NOT NULL,
+  period_start TIMESTAMPTZ NOT NULL,
+  period_end TIMESTAMPTZ NOT NULL,
+  post_count INTEGER NOT NULL,
+  recipient_count INTEGER NOT NULL,
+  failed_count INTEGER NOT NULL,
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ NOT NULL,
+
+  CONSTRAINT pk_newsletter_sends_id PRIMARY KEY(id)
+);
+
+CREATE INDEX idx_newsletter_sends_finished ON newsletter_sends (finished_at DESC);
+
+INSERT INTO permissions (id, name)
+VALUES ('8e1a4c2d-6b3f-4d7a-9c5e-2f0b7a1d3e94', 'newsletter:manage');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name = 'newsletter:manage'
+ON CONFLICT DO NOTHING;
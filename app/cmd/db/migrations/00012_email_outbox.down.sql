@@ -0,0 +1,9 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'emails:manage');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'emails:manage');
+
+DELETE FROM permissions WHERE name = 'emails:manage';
+
+DROP TABLE IF EXISTS email_outbox;
@@ -0,0 +1,36 @@
+-- Outgoing email. Requests only queue a message here; a background worker
+-- delivers it, retrying with backoff, so a slow or failing mail server never
+-- holds up a request or loses a password reset. A message that keeps failing
+-- ends up 'dead' and is listed in the admin panel.
+CREATE TABLE email_outbox
+(
+  id UUID NOT NULL,
+  recipient VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL,
+  headers JSONB NOT NULL DEFAULT '{}',
+  status VARCHAR(8) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  last_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  sent_at TIMESTAMPTZ,
+
+  CONSTRAINT pk_email_outbox_id PRIMARY KEY(id),
+  CONSTRAINT ck_email_outbox_status CHECK (status IN ('pending', 'sent', 'dead'))
+);
+
+-- The worker's only question: what is due now.
+CREATE INDEX idx_email_outbox_due ON email_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_email_outbox_status ON email_outbox (status, created_at);
+
+INSERT INTO permissions (id, name)
+VALUES ('3c7f9e21-5a4d-4b8e-a6f2-9d1e0b4c7a58', 'emails:manage');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name = 'emails:manage'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'inbox:manage');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'inbox:manage');
+
+DELETE FROM permissions WHERE name = 'inbox:manage';
+
+DROP TABLE IF EXISTS contact_replies;
+DROP TABLE IF EXISTS contact_messages;
@@ -0,0 +1,56 @@
+-- Messages sent through the contact form. Suspicious ones are stored as
+-- 'spam' rather than dropped, so a real message caught by the spam check can
+-- still be found. Privacy requests get a due date: the GDPR allows a month to
+-- answer a data subject (Art. 12(3)), and the inbox counts down to it until
+-- the message is closed.
+CREATE TABLE contact_messages
+(
+  id UUID NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  category VARCHAR(16) NOT NULL,
+  subject VARCHAR(200) NOT NULL,
+  body TEXT NOT NULL,
+  status VARCHAR(8) NOT NULL DEFAULT 'open',
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  read_at TIMESTAMPTZ,
+  due_at TIMESTAMPTZ,
+  closed_at TIMESTAMPTZ,
+
+  CONSTRAINT pk_contact_messages_id PRIMARY KEY(id),
+  CONSTRAINT ck_contact_messages_status CHECK (status IN ('open', 'closed', 'spam')),
+  CONSTRAINT ck_contact_messages_category CHECK (category IN ('general', 'privacy', 'content', 'partnership'))
+);
+
+CREATE INDEX idx_contact_messages_status ON contact_messages (status, created_at DESC);
+CREATE INDEX idx_contact_messages_due ON contact_messages (due_at) WHERE due_at IS NOT NULL AND status = 'open';
+
+-- Answers sent from the inbox, kept with the message they answer. The author
+-- is cleared, not the reply, when their account is deleted.
+CREATE TABLE contact_replies
+(
+  id UUID NOT NULL,
+  message_id UUID NOT NULL,
+  author_id UUID,
+  body TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+
+  CONSTRAINT pk_contact_replies_id PRIMARY KEY(id),
+  CONSTRAINT fk_contact_replies_message_id FOREIGN KEY(message_id) REFERENCES contact_messages(id) ON DELETE CASCADE,
+  CONSTRAINT fk_contact_replies_author_id FOREIGN KEY(author_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_contact_replies_message ON contact_replies (message_id, created_at);
+
+INSERT INTO permissions (id, name)
+VALUES ('5d2b8f4e-1c6a-4e39-b7d0-8a3f6c9e2b17', 'inbox:manage');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name = 'inbox:manage'
+ON CONFLICT DO NOTHING;
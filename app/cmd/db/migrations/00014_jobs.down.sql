@@ -0,0 +1,9 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'jobs:manage');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'jobs:manage');
+
+DELETE FROM permissions WHERE name = 'jobs:manage';
+
+DROP TABLE IF EXISTS jobs;
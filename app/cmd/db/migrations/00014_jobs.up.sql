@@ -0,0 +1,39 @@
+-- Background jobs. Anything that should not hold up a request, or has to run
+-- on a schedule, is queued here and picked up by the worker started in main.
+-- A running job keeps run_at as its lease: should its worker die, the job
+-- comes due again then. A job that keeps failing ends up 'dead' and is listed
+-- in the admin panel.
+CREATE TABLE jobs
+(
+  id UUID NOT NULL,
+  kind VARCHAR(100) NOT NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  unique_key VARCHAR(255),
+  status VARCHAR(8) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  run_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  last_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT(now() at time zone 'utc'),
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+
+  CONSTRAINT pk_jobs_id PRIMARY KEY(id),
+  CONSTRAINT ck_jobs_status CHECK (status IN ('pending', 'running', 'done', 'dead'))
+);
+
+-- A unique key holds only while its job is waiting or running, so the same
+-- work can be queued again once it is done.
+CREATE UNIQUE INDEX uq_jobs_unique_key ON jobs (unique_key) WHERE status IN ('pending', 'running');
+CREATE INDEX idx_jobs_due ON jobs (run_at) WHERE status IN ('pending', 'running');
+CREATE INDEX idx_jobs_status ON jobs (status, created_at);
+
+INSERT INTO permissions (id, name)
+VALUES ('8e4a1d6c-3b7f-4c2e-9a05-6f1b2d8e7c34', 'jobs:manage');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name = 'jobs:manage'
+ON CONFLICT DO NOTHING;
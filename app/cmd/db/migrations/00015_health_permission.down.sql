@@ -0,0 +1,7 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'health:read');
+
+DELETE FROM roles_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE name = 'health:read');
+
+DELETE FROM permissions WHERE name = 'health:read';
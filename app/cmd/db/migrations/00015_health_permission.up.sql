@@ -0,0 +1,12 @@
+-- The detailed health report names the mail server, the migration version
+-- and the state of the disk, which is more than anonymous visitors need.
+INSERT INTO permissions (id, name)
+VALUES ('2b093b1c-d696-419a-a249-39cf146a2f34', 'health:read');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'ADMIN'
+  AND p.name = 'health:read'
+ON CONFLICT DO NOTHING;
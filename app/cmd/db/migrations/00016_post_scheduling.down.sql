@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_posts_scheduled;
+ALTER TABLE posts DROP COLUMN IF EXISTS scheduled_at;
@@ -0,0 +1,6 @@
+-- When an unpublished post is due to go out. Cleared once it is published,
+-- by the scheduler or by hand.
+ALTER TABLE posts ADD COLUMN scheduled_at TIMESTAMPTZ;
+
+-- Backs the scheduler's query for due posts.
+CREATE INDEX idx_posts_scheduled ON posts (scheduled_at) WHERE scheduled_at IS NOT NULL AND is_deleted = FALSE;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS image_variants;
+DROP INDEX IF EXISTS idx_images_key;
+DROP INDEX IF EXISTS idx_images_url;
+ALTER TABLE images
+  DROP COLUMN IF EXISTS height,
+  DROP COLUMN IF EXISTS width,
+  DROP COLUMN IF EXISTS key;
@@ -0,0 +1,27 @@
+-- Uploaded images, until now an unused table. The key is the storage key the
+-- object is deleted by, and the size is after the upload was turned upright.
+ALTER TABLE images
+  ADD COLUMN key VARCHAR,
+  ADD COLUMN width INT,
+  ADD COLUMN height INT;
+
+CREATE UNIQUE INDEX idx_images_url ON images (url);
+CREATE UNIQUE INDEX idx_images_key ON images (key);
+
+-- Smaller copies and other formats of an image, for srcset and <picture>.
+-- They go with the image.
+CREATE TABLE image_variants
+(
+  image_id UUID NOT NULL,
+  format VARCHAR NOT NULL,
+  width INT NOT NULL,
+  url VARCHAR NOT NULL,
+  key VARCHAR NOT NULL,
+  bytes BIGINT NOT NULL,
+
+  CONSTRAINT pk_image_variants PRIMARY KEY(image_id, format, width),
+  CONSTRAINT fk_image_variants_image FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+-- The orphan sweep looks variants up by the key it finds in storage.
+CREATE UNIQUE INDEX idx_image_variants_key ON image_variants (key);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS image_usages;
+DROP INDEX IF EXISTS idx_images_unused;
+ALTER TABLE images
+  DROP CONSTRAINT IF EXISTS fk_images_uploaded_by,
+  DROP COLUMN IF EXISTS unused_since,
+  DROP COLUMN IF EXISTS uploaded_by,
+  DROP COLUMN IF EXISTS alt,
+  DROP COLUMN IF EXISTS bytes,
+  DROP COLUMN IF EXISTS filename;
@@ -0,0 +1,31 @@
+-- The media library. An image keeps who uploaded it, under what name and at
+-- what size, and the alt text the editor's picker inserts with it.
+-- unused_since is set by the usage scan when nothing shows the image any
+-- more; once it is older than the grace period the image is deleted.
+ALTER TABLE images
+  ADD COLUMN filename VARCHAR NOT NULL DEFAULT '',
+  ADD COLUMN bytes BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN alt VARCHAR NOT NULL DEFAULT '',
+  ADD COLUMN uploaded_by UUID,
+  ADD COLUMN unused_since TIMESTAMPTZ,
+  ADD CONSTRAINT fk_images_uploaded_by FOREIGN KEY(uploaded_by) REFERENCES users(id) ON DELETE SET NULL;
+
+-- Backs the sweep's query for images unused past the grace period.
+CREATE INDEX idx_images_unused ON images (unused_since) WHERE unused_since IS NOT NULL;
+
+-- Where each image is shown: as a post's cover or inside its content. Found
+-- by the usage scan, which rebuilds it from the posts themselves.
+CREATE TABLE image_usages
+(
+  image_id UUID NOT NULL,
+  post_id UUID NOT NULL,
+  field VARCHAR(8) NOT NULL,
+
+  CONSTRAINT pk_image_usages PRIMARY KEY(image_id, post_id, field),
+  CONSTRAINT fk_image_usages_image FOREIGN KEY(image_id) REFERENCES images(id) ON DELETE CASCADE,
+  CONSTRAINT fk_image_usages_post FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  CONSTRAINT ck_image_usages_field CHECK (field IN ('cover', 'content'))
+);
+
+-- Saving a post replaces its rows.
+CREATE INDEX idx_image_usages_post ON image_usages (post_id);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS newsletter_send_recipients;
+
+DELETE FROM newsletter_sends WHERE finished_at IS NULL;
+
+DROP INDEX IF EXISTS idx_newsletter_sends_started;
+CREATE INDEX idx_newsletter_sends_finished ON newsletter_sends (finished_at DESC);
+
+ALTER TABLE newsletter_sends
+  DROP CONSTRAINT IF EXISTS uq_newsletter_sends_period_start,
+  ALTER COLUMN finished_at SET NOT NULL;
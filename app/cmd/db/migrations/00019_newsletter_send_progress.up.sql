@@ -0,0 +1,25 @@
+-- A digest is recorded before any of it is queued, so one cut short is
+-- resumed rather than sent again. Each digest starts where the last one
+-- ended, so the unique start keeps two workers from sending the same one.
+-- finished_at stays empty until every recipient's copy is queued.
+ALTER TABLE newsletter_sends
+  ALTER COLUMN finished_at DROP NOT NULL,
+  ADD CONSTRAINT uq_newsletter_sends_period_start UNIQUE(period_start);
+
+DROP INDEX IF EXISTS idx_newsletter_sends_finished;
+CREATE INDEX idx_newsletter_sends_started ON newsletter_sends (started_at DESC);
+
+-- Who a digest goes to, fixed when it starts. queued_at is set once their
+-- copy is in the outbox; a resumed send only mails those still without it.
+CREATE TABLE newsletter_send_recipients
+(
+  send_id UUID NOT NULL,
+  subscriber_id UUID NOT NULL,
+  queued_at TIMESTAMPTZ,
+
+  CONSTRAINT pk_newsletter_send_recipients PRIMARY KEY(send_id, subscriber_id),
+  CONSTRAINT fk_newsletter_send_recipients_send_id FOREIGN KEY(send_id) REFERENCES newsletter_sends(id) ON DELETE CASCADE,
+  CONSTRAINT fk_newsletter_send_recipients_subscriber_id FOREIGN KEY(subscriber_id) REFERENCES newsletter_subscribers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_newsletter_send_recipients_subscriber ON newsletter_send_recipients (subscriber_id);
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"server/internal/application/account"
+	appAudit "server/internal/application/audit"
+	"server/internal/application/auth"
+	appContact "server/internal/application/contact"
+	appJobs "server/internal/application/jobs"
+	"server/internal/application/media"
+	appNewsletter "server/internal/application/newsletter"
+	appOutbox "server/internal/application/outbox"
+	appPosts "server/internal/application/posts"
+	"server/internal/config"
+)
+
+// purgeResetTokens sweeps expired password reset tokens.
+const purgeResetTokens appJobs.Kind[struct{}] = "auth.purge_reset_tokens"
+
+// purgeUnverified deletes self-registered accounts that never verified their
+// address, which would otherwise hold it forever.
+const purgeUnverified appJobs.Kind[struct{}] = "auth.purge_unverified"
+
+// deleteAccounts carries out the deletions whose grace period is over.
+const deleteAccounts appJobs.Kind[struct{}] = "account.delete_due"
+
+// purgeSentEmails deletes the record of delivered emails.
+const purgeSentEmails appJobs.Kind[struct{}] = "outbox.purge_sent"
+
+// purgeAuditEvents deletes audit events past their retention.
+const purgeAuditEvents appJobs.Kind[struct{}] = "audit.purge_expired"
+
+// purgeContactMessages deletes handled contact messages and caught spam past
+// their retention.
+const purgeContactMessages appJobs.Kind[struct{}] = "contact.purge_expired"
+
+// publishScheduled publishes the scheduled posts that are due.
+const publishScheduled appJobs.Kind[struct{}] = "posts.publish_scheduled"
+
+// sweepImages deletes the uploaded images nothing has shown for the grace
+// period.
+const sweepImages appJobs.Kind[struct{}] = "media.sweep_images"
+
+// sendDigest sends the newsletter digest when it is due, or resumes the one
+// that did not finish.
+const sendDigest appJobs.Kind[struct{}] = "newsletter.send_digest"
+
+// purgeNewsletterPending drops newsletter sign ups nobody confirmed.
+const purgeNewsletterPending appJobs.Kind[struct{}] = "newsletter.purge_pending"
+
+// jobServices are what the background jobs run.
+type jobServices struct {
+	jobs         *appJobs.JobService
+	resets       *auth.PasswordResetService
+	verification *auth.EmailVerificationService
+	privacy      *account.PrivacyService
+	outbox       *appOutbox.OutboxService
+	audit        *appAudit.AuditService
+	contact      *appContact.ContactService
+	newsletter   *appNewsletter.NewsletterService
+	scheduler    *appPosts.Scheduler
+	// library is nil without upload storage.
+	library *media.LibraryService
+}
+
+// registerJobs tells the worker how to run each kind of job and schedules the
+// recurring ones. Cron expressions are in UTC.
+func registerJobs(worker *appJobs.Worker, services jobServices) error {
+	appJobs.Register(worker, appJobs.PurgeFinished, services.jobs.Purge, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "17 3 * * *", appJobs.PurgeFinished, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, purgeResetTokens, func(ctx context.Context, _ struct{}) error {
+		return services.resets.PurgeExpiredTokens(ctx)
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "@hourly", purgeResetTokens, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, purgeUnverified, func(ctx context.Context, _ struct{}) error {
+		_, err := services.verification.PurgeUnverified(ctx, config.UnverifiedAccountTTL())
+		return err
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "3 * * * *", purgeUnverified, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, deleteAccounts, func(ctx context.Context, _ struct{}) error {
+		_, err := services.privacy.DeleteDue(ctx)
+		return err
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "13 * * * *", deleteAccounts, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, purgeSentEmails, func(ctx context.Context, _ struct{}) error {
+		return services.outbox.PurgeSent(ctx)
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "23 * * * *", purgeSentEmails, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, purgeAuditEvents, func(ctx context.Context, _ struct{}) error {
+		_, err := services.audit.PurgeExpired(ctx)
+		return err
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "29 * * * *", purgeAuditEvents, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, purgeContactMessages, func(ctx context.Context, _ struct{}) error {
+		return services.contact.PurgeExpired(ctx)
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "37 * * * *", purgeContactMessages, struct{}{}); err != nil {
+		return err
+	}
+
+	// Checking hourly keeps the digest within the hour it falls due. Queueing
+	// a copy per subscriber takes a while on a long list; a run cut short is
+	// picked up by the retry for whoever is left.
+	appJobs.Register(worker, sendDigest, func(ctx context.Context, _ struct{}) error {
+		return services.newsletter.SendDigest(ctx)
+	}, appJobs.HandlerOptions{Timeout: 15 * time.Minute})
+	if err := appJobs.Schedule(worker, "7 * * * *", sendDigest, struct{}{}); err != nil {
+		return err
+	}
+
+	appJobs.Register(worker, purgeNewsletterPending, func(ctx context.Context, _ struct{}) error {
+		return services.newsletter.PurgePending(ctx)
+	}, appJobs.HandlerOptions{})
+	if err := appJobs.Schedule(worker, "@hourly", purgeNewsletterPending, struct{}{}); err != nil {
+		return err
+	}
+
+	// Without storage there is nothing to delete from, so the sweep only runs
+	// with it. Deleting from Cloudinary is a request per file, hence the time.
+	if services.library != nil {
+		appJobs.Register(worker, sweepImages, func(ctx context.Context, _ struct{}) error {
+			_, err := services.library.Sweep(ctx)
+			return err
+		}, appJobs.HandlerOptions{Timeout: 30 * time.Minute})
+		if err := appJobs.Schedule(worker, "41 * * * *", sweepImages, struct{}{}); err != nil {
+			return err
+		}
+	}
+
+	// Every minute, so a post goes out within a minute of its time.
+	appJobs.Register(worker, publishScheduled, func(ctx context.Context, _ struct{}) error {
+		_, err := services.scheduler.PublishDue(ctx)
+		return err
+	}, appJobs.HandlerOptions{})
+	return appJobs.Schedule(worker, "* * * * *", publishScheduled, struct{}{})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"server/cmd/db/database"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func migrateUp(ctx context.Context, args []string) error {
+	m, err := newMigrator(args)
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("Already up to date")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return printVersion(m)
+}
+
+func migrateDown(ctx context.Context, args []string) error {
+	flags := newFlags("migrate", "down")
+	steps := flags.Int("steps", 1, "how many migrations to roll back")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *steps < 1 || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	m, err := newMigrator(nil)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Steps(-*steps); err != nil {
+		return err
+	}
+
+	return printVersion(m)
+}
+
+func migrateStatus(ctx context.Context, args []string) error {
+	m, err := newMigrator(args)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	migrations, err := database.ListMigrations()
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, migration := range migrations {
+		state := "pending"
+		switch {
+		case migration.Version == version && dirty:
+			state = "FAILED"
+		case migration.Version <= version:
+			state = "applied"
+		}
+		fmt.Fprintf(table, "%05d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	table.Flush()
+
+	if dirty {
+		fmt.Printf("\nMigration %d failed part way. Repair the schema by hand, then run \"migrate force\" with the last version that is fully applied.\n", version)
+	}
+
+	return nil
+}
+
+func migrateForce(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	version, err := strconv.Atoi(args[0])
+	if err != nil {
+		return errUsage
+	}
+
+	m, err := newMigrator(nil)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Force(version); err != nil {
+		return err
+	}
+
+	return printVersion(m)
+}
+
+func migrateCreate(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	up, down, err := database.CreateMigration(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(up)
+	fmt.Println(down)
+
+	return nil
+}
+
+// newMigrator connects and loads the migrations. Commands that take no
+// arguments pass theirs through so stray ones are refused.
+func newMigrator(args []string) (*migrate.Migrate, error) {
+	if len(args) != 0 {
+		return nil, errUsage
+	}
+
+	return database.NewMigrator(openDatabase())
+}
+
+func printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("No migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("At version %d (dirty)\n", version)
+	} else {
+		fmt.Printf("At version %d\n", version)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	appPosts "server/internal/application/posts"
+	"server/internal/domain/category"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+)
+
+func postsReindex(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	if err := posts.NewPostRepository(openDatabase()).Reindex(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("Search index rebuilt")
+	return nil
+}
+
+func postsExport(ctx context.Context, args []string) error {
+	flags := newFlags("posts", "export")
+	out := flags.String("o", "-", "file to write, - for standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := newTransferService().Export(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	// Standard error, so the count does not end up inside the export.
+	fmt.Fprintf(os.Stderr, "Exported %d posts\n", count)
+	return nil
+}
+
+func postsImport(ctx context.Context, args []string) error {
+	flags := newFlags("posts", "import")
+	author := flags.String("author", "", "email of the author for posts whose author is not found")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	var r io.Reader = os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	result, err := newTransferService().Import(ctx, r, *author)
+	for _, slug := range result.Skipped {
+		fmt.Printf("skipped %s: the slug is taken\n", slug)
+	}
+	fmt.Printf("Imported %d posts, skipped %d\n", len(result.Created), len(result.Skipped))
+
+	return err
+}
+
+func postsSchedule(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+
+	at, err := time.Parse(time.RFC3339, args[1])
+	if err != nil {
+		return fmt.Errorf("%q is not an RFC 3339 time such as 2026-05-01T08:00:00+03:00", args[1])
+	}
+
+	scheduler := appPosts.NewScheduler(posts.NewPostRepository(openDatabase()))
+	if err := scheduler.Schedule(ctx, args[0], at, actor); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s will be published at %s\n", args[0], at.Format(time.RFC3339))
+	return nil
+}
+
+func postsPublishScheduled(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	scheduler := appPosts.NewScheduler(posts.NewPostRepository(openDatabase()))
+	slugs, err := scheduler.PublishDue(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, slug := range slugs {
+		fmt.Printf("published %s\n", slug)
+	}
+	fmt.Printf("Published %d posts\n", len(slugs))
+	return nil
+}
+
+func newTransferService() *appPosts.TransferService {
+	db := openDatabase()
+	return appPosts.NewTransferService(posts.NewPostRepository(db), category.NewCategoryRepository(db), user.NewUserRepository(db))
+}
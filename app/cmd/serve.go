@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"server/cmd/db/database"
+	"server/internal/application/account"
+	appAudit "server/internal/application/audit"
+	"server/internal/application/auth"
+	appContact "server/internal/application/contact"
+	appHealth "server/internal/application/health"
+	appJobs "server/internal/application/jobs"
+	"server/internal/application/media"
+	appNewsletter "server/internal/application/newsletter"
+	appOutbox "server/internal/application/outbox"
+	appPosts "server/internal/application/posts"
+	appSpam "server/internal/application/spam"
+	"server/internal/application/users"
+	"server/internal/config"
+	"server/internal/domain/audit"
+	"server/internal/domain/comments"
+	"server/internal/domain/contact"
+	"server/internal/domain/image"
+	"server/internal/domain/jobs"
+	"server/internal/domain/newsletter"
+	"server/internal/domain/outbox"
+	"server/internal/domain/posts"
+	"server/internal/domain/spam"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/email"
+	"server/internal/infrastructure/storage"
+	"server/internal/infrastructure/tracing"
+	"server/internal/server"
+	"syscall"
+	"time"
+)
+
+// serve runs the web server and the background workers until a signal
+// arrives. It is what the binary does when given no command.
+func serve() {
+	// The whole configuration is checked before anything starts, so a bad
+	// deploy stops here with every problem listed instead of failing on
+	// whichever setting happens to be read first.
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	// Tracing comes first so the spans of startup queries have somewhere to go.
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		slog.Error("Failed to configure tracing", "error", err)
+		os.Exit(1)
+	}
+
+	db := database.ConnectDatabase()
+
+	// The server listens from here on so /livez answers, but /readyz fails and
+	// other requests are turned away until startup is done.
+	readiness := appHealth.NewState()
+	if err := server.Initialize(db, readiness); err != nil {
+		slog.Error("Failed to configure the server", "error", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		if err := server.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server failed to start", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	database.RunMigrations(db)
+
+	// A fresh database has no administrator, and registration only grants the
+	// USER role, so the admin panel would otherwise be unreachable.
+	bootstrapCtx, cancelBootstrap := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := users.EnsureAdmin(bootstrapCtx, user.NewUserRepository(db), config.AdminEmail(), config.AdminPassword()); err != nil {
+		cancelBootstrap()
+		slog.Error("Failed to bootstrap the administrator", "error", err)
+		os.Exit(1)
+	}
+	cancelBootstrap()
+
+	// Email is queued by requests and delivered from here, retried while the
+	// mail server is unreachable.
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	outboxRepo := outbox.NewOutboxRepository(db)
+	emailService := email.NewEmailService(outboxRepo)
+	mailSender, err := email.NewMailSender()
+	if err != nil {
+		slog.Error("Failed to configure mail delivery", "error", err)
+		os.Exit(1)
+	}
+	outboxService := appOutbox.NewOutboxService(outboxRepo, mailSender, config.EmailMaxAttempts())
+	go outboxService.Run(purgeCtx, 5*time.Second)
+
+	// Everything else in the background goes through the job queue: the
+	// sweeps of expired records, deletions past their grace period, the
+	// digest and scheduled posts. The worker stops claiming with purgeCtx and
+	// is given the rest of the shutdown to let running jobs finish.
+	jobRepo := jobs.NewJobRepository(db)
+	jobWorker := appJobs.NewWorker(jobRepo, config.JobConcurrency())
+	services := jobServices{
+		jobs:         appJobs.NewJobService(jobRepo),
+		resets:       auth.NewPasswordResetService(user.NewUserRepository(db), user.NewPasswordResetTokenRepository(db), emailService),
+		verification: auth.NewEmailVerificationService(user.NewUserRepository(db), user.NewEmailVerificationTokenRepository(db), emailService),
+		privacy:      account.NewPrivacyService(user.NewUserRepository(db), posts.NewPostRepository(db), comments.NewCommentRepository(db), user.NewAccountDeletionTokenRepository(db), emailService, config.AccountDeletionGrace()),
+		outbox:       outboxService,
+		audit:        appAudit.NewAuditService(audit.NewAuditRepository(db), config.AuditRetention()),
+		contact:      appContact.NewContactService(contact.NewMessageRepository(db), emailService, appSpam.NewGuard(spam.NewTokenRepository(db), config.SpamThreshold())),
+		newsletter:   appNewsletter.NewNewsletterService(newsletter.NewSubscriberRepository(db), newsletter.NewSendRepository(db), posts.NewPostRepository(db), emailService),
+		scheduler:    appPosts.NewScheduler(posts.NewPostRepository(db)),
+	}
+	// Unused images are deleted from the storage they are in; without it
+	// they are only kept.
+	if store, err := storage.New(); err != nil {
+		slog.Warn("Unused images will not be deleted without upload storage", "error", err)
+	} else {
+		services.library = media.NewLibraryService(image.NewImageRepository(db), store, config.ImageUnusedGrace())
+	}
+	if err := registerJobs(jobWorker, services); err != nil {
+		slog.Error("Failed to register background jobs", "error", err)
+		os.Exit(1)
+	}
+	go jobWorker.Run(purgeCtx)
+
+	readiness.MarkReady()
+	slog.Info("Ready to serve")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Fail readiness first and keep serving while load balancers notice; only
+	// then stop accepting connections.
+	readiness.MarkDraining()
+	slog.Info("Draining before shutdown", "delay", config.ShutdownDrain())
+	time.Sleep(config.ShutdownDrain())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Server forced to shutdown", "error", err)
+	}
+
+	stopPurge()
+
+	if err := jobWorker.Shutdown(ctx); err != nil {
+		slog.Error("Background jobs interrupted by shutdown", "error", err)
+	}
+
+	if err := db.Close(); err != nil {
+		slog.Error("Error closing database connection", "error", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+
+	slog.Info("Server exited")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"server/internal/application/auth"
+	"server/internal/application/users"
+	"server/internal/domain/outbox"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/email"
+
+	"golang.org/x/term"
+)
+
+func userCreate(ctx context.Context, args []string) error {
+	flags := newFlags("user", "create")
+	admin := flags.Bool("admin", false, "grant the ADMIN role")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	id, err := users.CreateAccount(ctx, user.NewUserRepository(openDatabase()), flags.Arg(0), password, *admin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s (%s)\n", flags.Arg(0), id)
+	return nil
+}
+
+func userPromote(ctx context.Context, args []string) error {
+	flags := newFlags("user", "promote")
+	role := flags.String("role", "ADMIN", "the role to grant")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	admin, account, err := findAccount(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if err := admin.GrantRole(ctx, actor, account.Id, *role); err != nil {
+		return err
+	}
+
+	fmt.Printf("Granted %s to %s. It applies from their next sign in.\n", *role, account.Email)
+	return nil
+}
+
+func userSuspend(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	admin, account, err := findAccount(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := admin.SetStatus(ctx, actor, account.Id, user.Suspended); err != nil {
+		return err
+	}
+
+	fmt.Printf("Suspended %s\n", account.Email)
+	return nil
+}
+
+func userResetPassword(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	admin, account, err := findAccount(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := admin.ForcePasswordReset(ctx, actor, account.Id); err != nil {
+		return err
+	}
+
+	// The link goes through the outbox like all mail, so it leaves once a
+	// server is running to deliver it.
+	fmt.Printf("Password cleared and a reset link queued for %s\n", account.Email)
+	return nil
+}
+
+func userRevokeSessions(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	admin, account, err := findAccount(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := admin.RevokeSessions(ctx, actor, account.Id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed %s out everywhere\n", account.Email)
+	return nil
+}
+
+// findAccount connects, builds the service the admin panel uses for the same
+// changes, and looks up the account by email.
+func findAccount(ctx context.Context, emailAddr string) (*users.UserAdminService, user.User, error) {
+	db := openDatabase()
+	userRepo := user.NewUserRepository(db)
+
+	account, err := userRepo.FindByEmail(ctx, emailAddr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, user.User{}, fmt.Errorf("no account with the email %q", emailAddr)
+	}
+	if err != nil {
+		return nil, user.User{}, err
+	}
+
+	emailService := email.NewEmailService(outbox.NewOutboxRepository(db))
+	resets := auth.NewPasswordResetService(userRepo, user.NewPasswordResetTokenRepository(db), emailService)
+
+	return users.NewUserAdminService(userRepo, resets), account, nil
+}
+
+// readPassword prompts without echo on a terminal and otherwise reads the
+// first line of standard input, so scripts can pipe the password in rather
+// than pass it where ps and shell history would see it.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading the password from standard input: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
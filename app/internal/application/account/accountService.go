@@ -0,0 +1,274 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+	"server/util/securityutil"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWrongPassword = errors.New("current password does not match")
+	ErrPasswordWeak  = errors.New("password too weak")
+	ErrEmailTaken    = errors.New("email already registered")
+	ErrSameEmail     = errors.New("new email matches the current one")
+	ErrInvalidToken  = errors.New("invalid or expired token")
+)
+
+type accountUserRepository interface {
+	FindById(ctx context.Context, userId string) (user.User, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	UpdateName(ctx context.Context, userId string, firstName, lastName sql.NullString) error
+	UpdatePassword(ctx context.Context, userId string, hashedPassword string) error
+	UpdateEmail(ctx context.Context, userId uuid.UUID, email string) error
+	RevokeTokensIssuedBefore(ctx context.Context, userId string, cutoff time.Time) error
+}
+
+type emailChangeTokenRepository interface {
+	Create(ctx context.Context, userId uuid.UUID, newEmail, tokenHash string) (*user.EmailChangeToken, error)
+	FindValidByHash(ctx context.Context, tokenHash string) (*user.EmailChangeToken, error)
+	InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error
+}
+
+// resetTokenInvalidator retires outstanding password reset links. Those were
+// mailed to the old address, so they must not outlive a change of address.
+type resetTokenInvalidator interface {
+	InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error
+}
+
+type accountMailer interface {
+	SendEmailChangeConfirmation(ctx context.Context, toEmail, token string) error
+	SendProfileChangedNotice(ctx context.Context, toEmail string) error
+	SendPasswordChangedNotice(ctx context.Context, toEmail string) error
+	SendEmailChangeRequestedNotice(ctx context.Context, toEmail, newEmail string) error
+	SendEmailChangedNotice(ctx context.Context, toEmail, newEmail string) error
+}
+
+// AccountService is what a signed in user can do to their own account.
+type AccountService struct {
+	users       accountUserRepository
+	changes     emailChangeTokenRepository
+	resetTokens resetTokenInvalidator
+	mailer      accountMailer
+}
+
+func NewAccountService(
+	users accountUserRepository,
+	changes emailChangeTokenRepository,
+	resetTokens resetTokenInvalidator,
+	mailer accountMailer,
+) *AccountService {
+	return &AccountService{
+		users:       users,
+		changes:     changes,
+		resetTokens: resetTokens,
+		mailer:      mailer,
+	}
+}
+
+func (s *AccountService) GetAccount(ctx context.Context, userId string) (user.User, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.GetAccount")
+	defer span.End()
+
+	return s.users.FindById(ctx, userId)
+}
+
+// UpdateProfile sets the user's name. Blank values clear the field.
+func (s *AccountService) UpdateProfile(ctx context.Context, userId, firstName, lastName string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.UpdateProfile")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.users.UpdateName(ctx, userId, optionalString(firstName), optionalString(lastName)); err != nil {
+		return err
+	}
+
+	if err := s.mailer.SendProfileChangedNotice(ctx, u.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the profile change notice", "error", err, "userId", userId)
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the password once the current one is proven and ends
+// every other session. The returned user is reloaded so the caller can issue a
+// fresh session for the request that made the change.
+func (s *AccountService) ChangePassword(ctx context.Context, userId, currentPassword, newPassword string) (user.User, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ChangePassword")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	// A session alone is not enough: whoever holds a stolen cookie must not be
+	// able to lock the owner out.
+	if !securityutil.CompareHash(u.Password, currentPassword) {
+		return user.User{}, ErrWrongPassword
+	}
+
+	if !securityutil.IsPasswordStrong(newPassword) {
+		return user.User{}, ErrPasswordWeak
+	}
+
+	if securityutil.IsPasswordBreached(newPassword) {
+		return user.User{}, securityutil.ErrPasswordBreached
+	}
+
+	hashed, err := securityutil.HashPassword(newPassword)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if err := s.users.UpdatePassword(ctx, userId, hashed); err != nil {
+		return user.User{}, err
+	}
+
+	// UpdatePassword revokes everything issued up to now, including the
+	// session about to be handed back. iat has second resolution, so the
+	// cutoff is pulled back to the start of the current second: the new token
+	// minted right after this is then still accepted.
+	if err := s.users.RevokeTokensIssuedBefore(ctx, userId, sessionCutoff(time.Now())); err != nil {
+		// Fails closed: the old cutoff still stands and the user signs in again.
+		slog.WarnContext(ctx, "Failed to keep the current session after a password change", "error", err, "userId", userId)
+	}
+
+	if err := s.mailer.SendPasswordChangedNotice(ctx, u.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the password change notice", "error", err, "userId", userId)
+	}
+
+	slog.InfoContext(ctx, "Password changed", "userId", userId)
+
+	return u, nil
+}
+
+// sessionCutoff is the revocation point that ends sessions issued before the
+// current second while sparing one issued within it.
+func sessionCutoff(now time.Time) time.Time {
+	return now.UTC().Truncate(time.Second).Add(-time.Nanosecond)
+}
+
+// RequestEmailChange mails a confirmation link to the new address and tells
+// the old one. Nothing changes until the link is followed.
+func (s *AccountService) RequestEmailChange(ctx context.Context, userId, password, newEmail string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.RequestEmailChange")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !securityutil.CompareHash(u.Password, password) {
+		return ErrWrongPassword
+	}
+
+	if strings.EqualFold(u.Email, newEmail) {
+		return ErrSameEmail
+	}
+
+	taken, err := s.users.ExistsByEmail(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+
+	if taken {
+		return ErrEmailTaken
+	}
+
+	plainToken, tokenHash, err := user.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	if invalidateErr := s.changes.InvalidateAllForUser(ctx, u.Id); invalidateErr != nil {
+		slog.WarnContext(ctx, "Failed to invalidate earlier email change requests", "error", invalidateErr)
+	}
+
+	if _, err := s.changes.Create(ctx, u.Id, newEmail, tokenHash); err != nil {
+		return err
+	}
+
+	if err := s.mailer.SendEmailChangeConfirmation(ctx, newEmail, plainToken); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the email change confirmation", "error", err, "userId", userId)
+	}
+
+	if err := s.mailer.SendEmailChangeRequestedNotice(ctx, u.Email, newEmail); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the email change notice", "error", err, "userId", userId)
+	}
+
+	slog.InfoContext(ctx, "Email change requested", "userId", userId)
+
+	return nil
+}
+
+// ConfirmEmailChange applies the change a token was issued for.
+func (s *AccountService) ConfirmEmailChange(ctx context.Context, plainToken string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ConfirmEmailChange")
+	defer span.End()
+
+	token, err := s.changes.FindValidByHash(ctx, user.HashToken(plainToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	u, err := s.users.FindById(ctx, token.UserId.String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	// Someone may have registered the address while the link sat unread.
+	taken, err := s.users.ExistsByEmail(ctx, token.NewEmail)
+	if err != nil {
+		return err
+	}
+
+	if taken {
+		return ErrEmailTaken
+	}
+
+	if err := s.users.UpdateEmail(ctx, token.UserId, token.NewEmail); err != nil {
+		return err
+	}
+
+	if err := s.changes.InvalidateAllForUser(ctx, token.UserId); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate email change tokens", "error", err)
+	}
+
+	if err := s.resetTokens.InvalidateAllForUser(ctx, token.UserId); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate password reset tokens", "error", err)
+	}
+
+	if err := s.mailer.SendEmailChangedNotice(ctx, u.Email, token.NewEmail); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the email changed notice", "error", err, "userId", token.UserId)
+	}
+
+	slog.InfoContext(ctx, "Email changed", "userId", token.UserId)
+
+	return nil
+}
+
+func optionalString(value string) sql.NullString {
+	trimmed := strings.TrimSpace(value)
+
+	return sql.NullString{String: trimmed, Valid: trimmed != ""}
+}
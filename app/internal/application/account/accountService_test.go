@@ -0,0 +1,358 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"server/internal/domain/user"
+	"server/util/securityutil"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const currentPassword = "Current-Passw0rd!"
+
+type stubAccountUsers struct {
+	users        map[string]user.User
+	revokeCutoff time.Time
+}
+
+func newStubAccountUsers(t *testing.T, emails ...string) *stubAccountUsers {
+	t.Helper()
+
+	hashed, err := securityutil.HashPassword(currentPassword)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	s := &stubAccountUsers{users: map[string]user.User{}}
+	for _, email := range emails {
+		u := user.User{Id: uuid.New(), Email: email, Password: hashed, Status: user.Active}
+		s.users[u.Id.String()] = u
+	}
+	return s
+}
+
+func (s *stubAccountUsers) byEmail(email string) user.User {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return user.User{}
+}
+
+func (s *stubAccountUsers) FindById(_ context.Context, userId string) (user.User, error) {
+	u, ok := s.users[userId]
+	if !ok {
+		return user.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (s *stubAccountUsers) ExistsByEmail(_ context.Context, email string) (bool, error) {
+	return s.byEmail(email).Email != "", nil
+}
+
+func (s *stubAccountUsers) UpdateName(_ context.Context, userId string, firstName, lastName sql.NullString) error {
+	u := s.users[userId]
+	u.FirstName, u.LastName = firstName, lastName
+	s.users[userId] = u
+	return nil
+}
+
+func (s *stubAccountUsers) UpdatePassword(_ context.Context, userId string, hashedPassword string) error {
+	u := s.users[userId]
+	u.Password = hashedPassword
+	s.users[userId] = u
+	return nil
+}
+
+func (s *stubAccountUsers) UpdateEmail(_ context.Context, userId uuid.UUID, email string) error {
+	u := s.users[userId.String()]
+	u.Email = email
+	s.users[userId.String()] = u
+	return nil
+}
+
+func (s *stubAccountUsers) RevokeTokensIssuedBefore(_ context.Context, _ string, cutoff time.Time) error {
+	s.revokeCutoff = cutoff
+	return nil
+}
+
+type stubChangeTokens struct {
+	tokens map[string]*user.EmailChangeToken
+}
+
+func newStubChangeTokens() *stubChangeTokens {
+	return &stubChangeTokens{tokens: map[string]*user.EmailChangeToken{}}
+}
+
+func (s *stubChangeTokens) Create(_ context.Context, userId uuid.UUID, newEmail, tokenHash string) (*user.EmailChangeToken, error) {
+	token := &user.EmailChangeToken{
+		Id:        uuid.New(),
+		UserId:    userId,
+		NewEmail:  newEmail,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(user.EmailChangeTokenExpirationDuration),
+	}
+	s.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (s *stubChangeTokens) FindValidByHash(_ context.Context, tokenHash string) (*user.EmailChangeToken, error) {
+	token, ok := s.tokens[tokenHash]
+	if !ok || token.UsedAt.Valid || time.Now().After(token.ExpiresAt) {
+		return nil, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *stubChangeTokens) InvalidateAllForUser(_ context.Context, userId uuid.UUID) error {
+	for _, token := range s.tokens {
+		if token.UserId == userId && !token.UsedAt.Valid {
+			token.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+type stubResetTokens struct {
+	invalidated []uuid.UUID
+}
+
+func (s *stubResetTokens) InvalidateAllForUser(_ context.Context, userId uuid.UUID) error {
+	s.invalidated = append(s.invalidated, userId)
+	return nil
+}
+
+type sentMail struct {
+	kind  string
+	to    string
+	token string
+}
+
+type stubAccountMailer struct {
+	sent []sentMail
+}
+
+func (s *stubAccountMailer) SendEmailChangeConfirmation(_ context.Context, toEmail, token string) error {
+	s.sent = append(s.sent, sentMail{kind: "confirm", to: toEmail, token: token})
+	return nil
+}
+
+func (s *stubAccountMailer) SendProfileChangedNotice(_ context.Context, toEmail string) error {
+	s.sent = append(s.sent, sentMail{kind: "profile", to: toEmail})
+	return nil
+}
+
+func (s *stubAccountMailer) SendPasswordChangedNotice(_ context.Context, toEmail string) error {
+	s.sent = append(s.sent, sentMail{kind: "password", to: toEmail})
+	return nil
+}
+
+func (s *stubAccountMailer) SendEmailChangeRequestedNotice(_ context.Context, toEmail, _ string) error {
+	s.sent = append(s.sent, sentMail{kind: "requested", to: toEmail})
+	return nil
+}
+
+func (s *stubAccountMailer) SendEmailChangedNotice(_ context.Context, toEmail, _ string) error {
+	s.sent = append(s.sent, sentMail{kind: "changed", to: toEmail})
+	return nil
+}
+
+func (s *stubAccountMailer) find(kind string) (sentMail, bool) {
+	for _, mail := range s.sent {
+		if mail.kind == kind {
+			return mail, true
+		}
+	}
+	return sentMail{}, false
+}
+
+// A stolen session cookie must not be enough to take over the account by
+// replacing the password.
+func TestChangePassword_RequiresCurrentPassword(t *testing.T) {
+	users := newStubAccountUsers(t, "owner@example.com")
+	owner := users.byEmail("owner@example.com")
+	service := NewAccountService(users, newStubChangeTokens(), &stubResetTokens{}, &stubAccountMailer{})
+
+	_, err := service.ChangePassword(context.Background(), owner.Id.String(), "wrong", "New-Passw0rd!long")
+	if !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("ChangePassword() error = %v, want ErrWrongPassword", err)
+	}
+
+	if users.users[owner.Id.String()].Password != owner.Password {
+		t.Error("the password should be unchanged")
+	}
+}
+
+func TestChangePassword_RejectsWeakPassword(t *testing.T) {
+	users := newStubAccountUsers(t, "owner@example.com")
+	owner := users.byEmail("owner@example.com")
+	service := NewAccountService(users, newStubChangeTokens(), &stubResetTokens{}, &stubAccountMailer{})
+
+	if _, err := service.ChangePassword(context.Background(), owner.Id.String(), currentPassword, "short"); !errors.Is(err, ErrPasswordWeak) {
+		t.Errorf("ChangePassword() error = %v, want ErrPasswordWeak", err)
+	}
+}
+
+// Every other session has to end, yet the old address must hear about it in
+// case the change was not the owner's doing.
+func TestChangePassword_RevokesOtherSessionsAndNotifies(t *testing.T) {
+	users := newStubAccountUsers(t, "owner@example.com")
+	owner := users.byEmail("owner@example.com")
+	mailer := &stubAccountMailer{}
+	service := NewAccountService(users, newStubChangeTokens(), &stubResetTokens{}, mailer)
+
+	before := time.Now()
+	if _, err := service.ChangePassword(context.Background(), owner.Id.String(), currentPassword, "New-Passw0rd!long"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+
+	if !securityutil.CompareHash(users.users[owner.Id.String()].Password, "New-Passw0rd!long") {
+		t.Error("the new password should be stored")
+	}
+
+	if users.revokeCutoff.IsZero() || users.revokeCutoff.Before(before.Add(-time.Second)) {
+		t.Errorf("revoke cutoff = %v, want just before %v", users.revokeCutoff, before)
+	}
+
+	if mail, ok := mailer.find("password"); !ok || mail.to != owner.Email {
+		t.Errorf("expected a password notice to %s, got %+v", owner.Email, mailer.sent)
+	}
+}
+
+// Sessions are compared by a whole second iat, so a token minted in the same
+// second as the change must still land after the cutoff.
+func TestSessionCutoff_SparesTheCurrentSecond(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 5, 700_000_000, time.UTC)
+	issuedAt := now.Truncate(time.Second)
+
+	cutoff := sessionCutoff(now)
+
+	if !issuedAt.After(cutoff) {
+		t.Errorf("token issued at %v should be after cutoff %v", issuedAt, cutoff)
+	}
+
+	if issuedAt.Add(-time.Second).After(cutoff) {
+		t.Errorf("token issued a second earlier should not be after cutoff %v", cutoff)
+	}
+}
+
+// The address only changes once the new inbox proves it can receive mail, and
+// the old one is told at both steps.
+func TestEmailChange_AppliesOnlyAfterConfirmation(t *testing.T) {
+	users := newStubAccountUsers(t, "old@example.com")
+	owner := users.byEmail("old@example.com")
+	mailer := &stubAccountMailer{}
+	resetTokens := &stubResetTokens{}
+	service := NewAccountService(users, newStubChangeTokens(), resetTokens, mailer)
+	ctx := context.Background()
+
+	if err := service.RequestEmailChange(ctx, owner.Id.String(), currentPassword, "new@example.com"); err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+
+	if users.users[owner.Id.String()].Email != "old@example.com" {
+		t.Fatal("the address should not change before confirmation")
+	}
+
+	confirm, ok := mailer.find("confirm")
+	if !ok || confirm.to != "new@example.com" {
+		t.Fatalf("expected a confirmation to the new address, got %+v", mailer.sent)
+	}
+
+	if notice, ok := mailer.find("requested"); !ok || notice.to != "old@example.com" {
+		t.Errorf("expected a notice to the old address, got %+v", mailer.sent)
+	}
+
+	if err := service.ConfirmEmailChange(ctx, confirm.token); err != nil {
+		t.Fatalf("ConfirmEmailChange() error = %v", err)
+	}
+
+	if got := users.users[owner.Id.String()].Email; got != "new@example.com" {
+		t.Errorf("email = %q, want new@example.com", got)
+	}
+
+	if notice, ok := mailer.find("changed"); !ok || notice.to != "old@example.com" {
+		t.Errorf("expected a changed notice to the old address, got %+v", mailer.sent)
+	}
+
+	// Reset links went to the old inbox and must not outlive the change.
+	if len(resetTokens.invalidated) != 1 || resetTokens.invalidated[0] != owner.Id {
+		t.Errorf("reset tokens invalidated for %v, want [%v]", resetTokens.invalidated, owner.Id)
+	}
+
+	if err := service.ConfirmEmailChange(ctx, confirm.token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("second ConfirmEmailChange() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestEmailChange_RequiresPassword(t *testing.T) {
+	users := newStubAccountUsers(t, "old@example.com")
+	owner := users.byEmail("old@example.com")
+	mailer := &stubAccountMailer{}
+	service := NewAccountService(users, newStubChangeTokens(), &stubResetTokens{}, mailer)
+
+	err := service.RequestEmailChange(context.Background(), owner.Id.String(), "wrong", "new@example.com")
+	if !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("RequestEmailChange() error = %v, want ErrWrongPassword", err)
+	}
+
+	if len(mailer.sent) != 0 {
+		t.Errorf("expected no emails, got %+v", mailer.sent)
+	}
+}
+
+// Another account may register the address while the link sits unread; the
+// confirmation must not then hand over a duplicate.
+func TestEmailChange_ConfirmRechecksAddress(t *testing.T) {
+	users := newStubAccountUsers(t, "old@example.com")
+	owner := users.byEmail("old@example.com")
+	mailer := &stubAccountMailer{}
+	service := NewAccountService(users, newStubChangeTokens(), &stubResetTokens{}, mailer)
+	ctx := context.Background()
+
+	if err := service.RequestEmailChange(ctx, owner.Id.String(), currentPassword, "new@example.com"); err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+
+	other := uuid.New()
+	users.users[other.String()] = user.User{Id: other, Email: "new@example.com"}
+
+	confirm, _ := mailer.find("confirm")
+	if err := service.ConfirmEmailChange(ctx, confirm.token); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("ConfirmEmailChange() error = %v, want ErrEmailTaken", err)
+	}
+
+	if users.users[owner.Id.String()].Email != "old@example.com" {
+		t.Error("the address should be unchanged")
+	}
+}
+
+func TestUpdateProfile_BlankClearsAndNotifies(t *testing.T) {
+	users := newStubAccountUsers(t, "owner@example.com")
+	owner := users.byEmail("owner@example.com")
+	mailer := &stubAccountMailer{}
+	service := NewAccountService(users, newStubChangeTokens(), &stubResetTokens{}, mailer)
+
+	if err := service.UpdateProfile(context.Background(), owner.Id.String(), "  Ива ", "  "); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+
+	updated := users.users[owner.Id.String()]
+	if updated.FirstName.String != "Ива" || !updated.FirstName.Valid {
+		t.Errorf("first name = %+v, want trimmed Ива", updated.FirstName)
+	}
+
+	if updated.LastName.Valid {
+		t.Errorf("last name = %+v, want NULL", updated.LastName)
+	}
+
+	if _, ok := mailer.find("profile"); !ok {
+		t.Error("expected a profile notice")
+	}
+}
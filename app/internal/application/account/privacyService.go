@@ -0,0 +1,398 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/comments"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+	"server/util/securityutil"
+
+	"github.com/google/uuid"
+)
+
+var ErrDeletionNotPending = errors.New("no deletion is pending")
+
+type privacyUserRepository interface {
+	FindById(ctx context.Context, userId string) (user.User, error)
+	FindTokenHistory(ctx context.Context, userId uuid.UUID) ([]user.TokenRecord, error)
+	ScheduleDeletion(ctx context.Context, userId uuid.UUID, at time.Time) error
+	CancelDeletion(ctx context.Context, userId string) (bool, error)
+	FindDueForDeletion(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	Anonymise(ctx context.Context, userId uuid.UUID) error
+	EnsureNotLastAdmin(ctx context.Context, userId uuid.UUID) error
+}
+
+type authoredPostRepository interface {
+	FindByCreator(ctx context.Context, creatorId uuid.UUID) ([]posts.Post, error)
+}
+
+type authoredCommentRepository interface {
+	FindByAuthor(ctx context.Context, userId uuid.UUID) ([]comments.QueueItem, error)
+}
+
+type deletionTokenRepository interface {
+	Create(ctx context.Context, userId uuid.UUID, tokenHash string) (*user.AccountDeletionToken, error)
+	FindValidByHash(ctx context.Context, tokenHash string) (*user.AccountDeletionToken, error)
+	InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error
+}
+
+type privacyMailer interface {
+	SendDeletionConfirmation(ctx context.Context, toEmail, token string) error
+	SendDeletionScheduledNotice(ctx context.Context, toEmail string, at time.Time) error
+	SendDeletionCancelledNotice(ctx context.Context, toEmail string) error
+}
+
+// PrivacyService honours the data subject rights the privacy page promises:
+// a copy of the data, and erasure.
+type PrivacyService struct {
+	users    privacyUserRepository
+	posts    authoredPostRepository
+	comments authoredCommentRepository
+	tokens   deletionTokenRepository
+	mailer   privacyMailer
+	grace    time.Duration
+}
+
+func NewPrivacyService(
+	users privacyUserRepository,
+	posts authoredPostRepository,
+	comments authoredCommentRepository,
+	tokens deletionTokenRepository,
+	mailer privacyMailer,
+	grace time.Duration,
+) *PrivacyService {
+	return &PrivacyService{
+		users:    users,
+		posts:    posts,
+		comments: comments,
+		tokens:   tokens,
+		mailer:   mailer,
+		grace:    grace,
+	}
+}
+
+// Export is everything the site holds about one person. Field names are part
+// of what the user downloads, so they stay stable and readable.
+type Export struct {
+	ExportedAt  time.Time         `json:"exportedAt"`
+	Profile     ExportedProfile   `json:"profile"`
+	Roles       []string          `json:"roles"`
+	Permissions []string          `json:"permissions"`
+	Posts       []ExportedPost    `json:"posts"`
+	Comments    []ExportedComment `json:"comments"`
+	Links       []ExportedLink    `json:"emailedLinks"`
+	Consents    ExportedConsents  `json:"consents"`
+}
+
+type ExportedProfile struct {
+	Id                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	FirstName           string     `json:"firstName,omitempty"`
+	LastName            string     `json:"lastName,omitempty"`
+	Status              string     `json:"status"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           *time.Time `json:"updatedAt,omitempty"`
+	EmailVerifiedAt     *time.Time `json:"emailVerifiedAt,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty"`
+}
+
+type ExportedPost struct {
+	Id          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Status      string     `json:"status"`
+	Excerpt     string     `json:"excerpt,omitempty"`
+	Content     string     `json:"content"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	Deleted     bool       `json:"deleted"`
+}
+
+// ExportedComment is a comment the user wrote, with the post it was left on.
+// Status says whether it was published, is still waiting, or was taken down.
+type ExportedComment struct {
+	Id        uuid.UUID  `json:"id"`
+	PostTitle string     `json:"postTitle"`
+	PostSlug  string     `json:"postSlug"`
+	ReplyTo   *uuid.UUID `json:"replyTo,omitempty"`
+	Body      string     `json:"body"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ExportedLink is a reset, verification, email change or deletion link that
+// was mailed to the user. Only its history is kept, never the link itself.
+type ExportedLink struct {
+	Purpose   string     `json:"purpose"`
+	NewEmail  string     `json:"newEmail,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// ExportedConsents reports the advertising choice. It lives only in the
+// browser's consent cookie, so the caller passes in what this browser holds.
+type ExportedConsents struct {
+	Advertising string `json:"advertising"`
+	StoredIn    string `json:"storedIn"`
+}
+
+func (s *PrivacyService) Export(ctx context.Context, userId string, consentCookie string) (Export, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Export")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId)
+	if err != nil {
+		return Export{}, err
+	}
+
+	history, err := s.users.FindTokenHistory(ctx, u.Id)
+	if err != nil {
+		return Export{}, err
+	}
+
+	authored, err := s.posts.FindByCreator(ctx, u.Id)
+	if err != nil {
+		return Export{}, err
+	}
+
+	written, err := s.comments.FindByAuthor(ctx, u.Id)
+	if err != nil {
+		return Export{}, err
+	}
+
+	export := Export{
+		ExportedAt: time.Now().UTC(),
+		Profile: ExportedProfile{
+			Id:                  u.Id,
+			Email:               u.Email,
+			FirstName:           u.FirstName.String,
+			LastName:            u.LastName.String,
+			Status:              string(u.Status),
+			CreatedAt:           u.CreatedAt,
+			UpdatedAt:           optionalTime(u.UpdatedAt),
+			EmailVerifiedAt:     optionalTime(u.EmailVerifiedAt),
+			DeletionScheduledAt: optionalTime(u.DeletionScheduledAt),
+		},
+		Roles:       []string{},
+		Permissions: []string{},
+		Posts:       []ExportedPost{},
+		Comments:    []ExportedComment{},
+		Links:       []ExportedLink{},
+		Consents:    ExportedConsents{Advertising: "not set", StoredIn: "browser cookie"},
+	}
+
+	for _, role := range u.Roles {
+		export.Roles = append(export.Roles, role.Name)
+	}
+
+	for _, permission := range u.Permissions {
+		export.Permissions = append(export.Permissions, permission.Name)
+	}
+
+	for _, post := range authored {
+		export.Posts = append(export.Posts, ExportedPost{
+			Id:          post.Id,
+			Title:       post.Title,
+			Slug:        post.Slug,
+			Status:      string(post.Status),
+			Excerpt:     post.Excerpt,
+			Content:     post.Content,
+			CreatedAt:   post.CreatedAt,
+			PublishedAt: optionalTime(post.PublishedAt),
+			Deleted:     post.IsDeleted,
+		})
+	}
+
+	for _, comment := range written {
+		exported := ExportedComment{
+			Id:        comment.Id,
+			PostTitle: comment.PostTitle,
+			PostSlug:  comment.PostSlug,
+			Body:      comment.Body,
+			Status:    string(comment.Status),
+			CreatedAt: comment.CreatedAt,
+		}
+		if comment.ParentId.Valid {
+			exported.ReplyTo = &comment.ParentId.UUID
+		}
+		export.Comments = append(export.Comments, exported)
+	}
+
+	for _, record := range history {
+		export.Links = append(export.Links, ExportedLink{
+			Purpose:   record.Purpose,
+			NewEmail:  record.NewEmail.String,
+			CreatedAt: record.CreatedAt,
+			ExpiresAt: record.ExpiresAt,
+			UsedAt:    optionalTime(record.UsedAt),
+		})
+	}
+
+	if consentCookie != "" {
+		export.Consents.Advertising = consentCookie
+	}
+
+	slog.InfoContext(ctx, "Personal data exported", "userId", userId)
+
+	return export, nil
+}
+
+// RequestDeletion mails a confirmation link. Like the other sensitive changes
+// it wants the password, so an unattended session cannot be used for it. The
+// last active administrator is refused with user.ErrLastAdmin: nobody would be
+// left to run the site.
+func (s *PrivacyService) RequestDeletion(ctx context.Context, userId, password string) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.RequestDeletion")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !securityutil.CompareHash(u.Password, password) {
+		return ErrWrongPassword
+	}
+
+	if err := s.users.EnsureNotLastAdmin(ctx, u.Id); err != nil {
+		return err
+	}
+
+	plainToken, tokenHash, err := user.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	if invalidateErr := s.tokens.InvalidateAllForUser(ctx, u.Id); invalidateErr != nil {
+		slog.WarnContext(ctx, "Failed to invalidate earlier deletion requests", "error", invalidateErr)
+	}
+
+	if _, err := s.tokens.Create(ctx, u.Id, tokenHash); err != nil {
+		return err
+	}
+
+	if err := s.mailer.SendDeletionConfirmation(ctx, u.Email, plainToken); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Account deletion requested", "userId", userId)
+
+	return nil
+}
+
+// ConfirmDeletion schedules the deletion the token was issued for and returns
+// when it will happen.
+func (s *PrivacyService) ConfirmDeletion(ctx context.Context, plainToken string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ConfirmDeletion")
+	defer span.End()
+
+	token, err := s.tokens.FindValidByHash(ctx, user.HashToken(plainToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrInvalidToken
+		}
+		return time.Time{}, err
+	}
+
+	u, err := s.users.FindById(ctx, token.UserId.String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrInvalidToken
+		}
+		return time.Time{}, err
+	}
+
+	at := time.Now().UTC().Add(s.grace)
+	if err := s.users.ScheduleDeletion(ctx, u.Id, at); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.tokens.InvalidateAllForUser(ctx, u.Id); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate deletion tokens", "error", err)
+	}
+
+	if err := s.mailer.SendDeletionScheduledNotice(ctx, u.Email, at); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the deletion notice", "error", err, "userId", u.Id)
+	}
+
+	slog.InfoContext(ctx, "Account deletion scheduled", "userId", u.Id, "at", at)
+
+	return at, nil
+}
+
+func (s *PrivacyService) CancelDeletion(ctx context.Context, userId string) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.CancelDeletion")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := s.users.CancelDeletion(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !cancelled {
+		return ErrDeletionNotPending
+	}
+
+	if err := s.mailer.SendDeletionCancelledNotice(ctx, u.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the deletion cancelled notice", "error", err, "userId", userId)
+	}
+
+	slog.InfoContext(ctx, "Account deletion cancelled", "userId", userId)
+
+	return nil
+}
+
+// DeleteDue anonymises every account whose grace period is over. One failure
+// does not stop the rest; the account is simply retried on the next run. So
+// is an administrator who became the last one during the grace period, until
+// another is appointed or the deletion is cancelled.
+// Sessions end with the account: the revocation cutoff is set and the row no
+// longer loads, which the session cache picks up within its TTL.
+func (s *PrivacyService) DeleteDue(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.DeleteDue")
+	defer span.End()
+
+	due, err := s.users.FindDueForDeletion(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userId := range due {
+		if err := s.users.Anonymise(ctx, userId); err != nil {
+			switch {
+			case errors.Is(err, user.ErrDeletionNotDue):
+			case errors.Is(err, user.ErrLastAdmin):
+				slog.WarnContext(ctx, "Not deleting the last active administrator", "userId", userId)
+			default:
+				slog.ErrorContext(ctx, "Failed to anonymise an account", "error", err, "userId", userId)
+			}
+			continue
+		}
+
+		deleted++
+		slog.InfoContext(ctx, "Account anonymised", "userId", userId)
+	}
+
+	return deleted, nil
+}
+
+func optionalTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
@@ -0,0 +1,311 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"server/internal/domain/comments"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/util/securityutil"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type stubPrivacyUsers struct {
+	user       user.User
+	history    []user.TokenRecord
+	scheduled  sql.NullTime
+	due        []uuid.UUID
+	anonymised []uuid.UUID
+	failFor    uuid.UUID
+	lastAdmin  bool
+}
+
+func newStubPrivacyUsers(t *testing.T) *stubPrivacyUsers {
+	t.Helper()
+
+	hashed, err := securityutil.HashPassword(currentPassword)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	return &stubPrivacyUsers{user: user.User{
+		Id:        uuid.New(),
+		Email:     "owner@example.com",
+		Password:  hashed,
+		FirstName: sql.NullString{String: "Ива", Valid: true},
+		Status:    user.Active,
+		Roles:     []user.Role{{Name: user.RoleAdmin}},
+		CreatedAt: time.Now().Add(-time.Hour),
+	}}
+}
+
+func (s *stubPrivacyUsers) FindById(_ context.Context, userId string) (user.User, error) {
+	if userId != s.user.Id.String() {
+		return user.User{}, sql.ErrNoRows
+	}
+	u := s.user
+	u.DeletionScheduledAt = s.scheduled
+	return u, nil
+}
+
+func (s *stubPrivacyUsers) FindTokenHistory(context.Context, uuid.UUID) ([]user.TokenRecord, error) {
+	return s.history, nil
+}
+
+func (s *stubPrivacyUsers) ScheduleDeletion(_ context.Context, _ uuid.UUID, at time.Time) error {
+	s.scheduled = sql.NullTime{Time: at, Valid: true}
+	return nil
+}
+
+func (s *stubPrivacyUsers) CancelDeletion(context.Context, string) (bool, error) {
+	pending := s.scheduled.Valid
+	s.scheduled = sql.NullTime{}
+	return pending, nil
+}
+
+func (s *stubPrivacyUsers) FindDueForDeletion(context.Context, time.Time) ([]uuid.UUID, error) {
+	return s.due, nil
+}
+
+func (s *stubPrivacyUsers) Anonymise(_ context.Context, userId uuid.UUID) error {
+	if userId == s.failFor {
+		return errors.New("database down")
+	}
+	s.anonymised = append(s.anonymised, userId)
+	return nil
+}
+
+func (s *stubPrivacyUsers) EnsureNotLastAdmin(context.Context, uuid.UUID) error {
+	if s.lastAdmin {
+		return user.ErrLastAdmin
+	}
+	return nil
+}
+
+type stubAuthoredPosts struct {
+	posts []posts.Post
+}
+
+func (s *stubAuthoredPosts) FindByCreator(context.Context, uuid.UUID) ([]posts.Post, error) {
+	return s.posts, nil
+}
+
+type stubAuthoredComments struct {
+	comments []comments.QueueItem
+}
+
+func (s *stubAuthoredComments) FindByAuthor(context.Context, uuid.UUID) ([]comments.QueueItem, error) {
+	return s.comments, nil
+}
+
+type stubDeletionTokens struct {
+	tokens map[string]*user.AccountDeletionToken
+}
+
+func newStubDeletionTokens() *stubDeletionTokens {
+	return &stubDeletionTokens{tokens: map[string]*user.AccountDeletionToken{}}
+}
+
+func (s *stubDeletionTokens) Create(_ context.Context, userId uuid.UUID, tokenHash string) (*user.AccountDeletionToken, error) {
+	token := &user.AccountDeletionToken{
+		Id:        uuid.New(),
+		UserId:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(user.DeletionTokenExpirationDuration),
+	}
+	s.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (s *stubDeletionTokens) FindValidByHash(_ context.Context, tokenHash string) (*user.AccountDeletionToken, error) {
+	token, ok := s.tokens[tokenHash]
+	if !ok || token.UsedAt.Valid || time.Now().After(token.ExpiresAt) {
+		return nil, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *stubDeletionTokens) InvalidateAllForUser(_ context.Context, userId uuid.UUID) error {
+	for _, token := range s.tokens {
+		if token.UserId == userId && !token.UsedAt.Valid {
+			token.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+type stubPrivacyMailer struct {
+	confirmations []string
+	scheduled     int
+	cancelled     int
+}
+
+func (s *stubPrivacyMailer) SendDeletionConfirmation(_ context.Context, _ string, token string) error {
+	s.confirmations = append(s.confirmations, token)
+	return nil
+}
+
+func (s *stubPrivacyMailer) SendDeletionScheduledNotice(context.Context, string, time.Time) error {
+	s.scheduled++
+	return nil
+}
+
+func (s *stubPrivacyMailer) SendDeletionCancelledNotice(context.Context, string) error {
+	s.cancelled++
+	return nil
+}
+
+// The export has to cover every kind of data the privacy page lists, and must
+// never carry secrets such as the password hash.
+func TestExport_CoversProfileRolesPostsCommentsAndLinks(t *testing.T) {
+	users := newStubPrivacyUsers(t)
+	users.history = []user.TokenRecord{{Purpose: "password_reset", CreatedAt: time.Now()}}
+	authored := &stubAuthoredPosts{posts: []posts.Post{{Id: uuid.New(), Title: "Първа", Status: posts.PostStatus("published")}}}
+	written := &stubAuthoredComments{comments: []comments.QueueItem{{
+		Comment:   comments.Comment{Id: uuid.New(), Body: "Чудесна рецепта", Status: comments.StatusApproved},
+		PostTitle: "Чужда",
+	}}}
+	service := NewPrivacyService(users, authored, written, newStubDeletionTokens(), &stubPrivacyMailer{}, 14*24*time.Hour)
+
+	export, err := service.Export(context.Background(), users.user.Id.String(), "ads")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if export.Profile.Email != users.user.Email || export.Profile.FirstName != "Ива" {
+		t.Errorf("profile = %+v", export.Profile)
+	}
+
+	if len(export.Roles) != 1 || export.Roles[0] != user.RoleAdmin {
+		t.Errorf("roles = %v, want [%s]", export.Roles, user.RoleAdmin)
+	}
+
+	if len(export.Posts) != 1 || export.Posts[0].Title != "Първа" {
+		t.Errorf("posts = %+v", export.Posts)
+	}
+
+	if len(export.Comments) != 1 || export.Comments[0].Body != "Чудесна рецепта" || export.Comments[0].PostTitle != "Чужда" {
+		t.Errorf("comments = %+v", export.Comments)
+	}
+
+	if len(export.Links) != 1 || export.Links[0].Purpose != "password_reset" {
+		t.Errorf("links = %+v", export.Links)
+	}
+
+	if export.Consents.Advertising != "ads" {
+		t.Errorf("advertising consent = %q, want ads", export.Consents.Advertising)
+	}
+}
+
+// Nothing is scheduled until the link in the inbox is followed, and the
+// grace period is counted from that moment.
+func TestDeletion_ScheduledOnlyAfterConfirmation(t *testing.T) {
+	users := newStubPrivacyUsers(t)
+	mailer := &stubPrivacyMailer{}
+	grace := 14 * 24 * time.Hour
+	service := NewPrivacyService(users, &stubAuthoredPosts{}, &stubAuthoredComments{}, newStubDeletionTokens(), mailer, grace)
+	ctx := context.Background()
+
+	if err := service.RequestDeletion(ctx, users.user.Id.String(), currentPassword); err != nil {
+		t.Fatalf("RequestDeletion() error = %v", err)
+	}
+
+	if users.scheduled.Valid {
+		t.Fatal("deletion should not be scheduled before confirmation")
+	}
+
+	if len(mailer.confirmations) != 1 {
+		t.Fatalf("expected one confirmation email, got %d", len(mailer.confirmations))
+	}
+
+	at, err := service.ConfirmDeletion(ctx, mailer.confirmations[0])
+	if err != nil {
+		t.Fatalf("ConfirmDeletion() error = %v", err)
+	}
+
+	if diff := at.Sub(time.Now().Add(grace)); diff < -time.Minute || diff > time.Minute {
+		t.Errorf("scheduled at %v, want about %v from now", at, grace)
+	}
+
+	if !users.scheduled.Valid || mailer.scheduled != 1 {
+		t.Errorf("expected a scheduled deletion and a notice, got %+v and %d notices", users.scheduled, mailer.scheduled)
+	}
+
+	if _, err := service.ConfirmDeletion(ctx, mailer.confirmations[0]); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("second ConfirmDeletion() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestDeletion_RequiresPassword(t *testing.T) {
+	users := newStubPrivacyUsers(t)
+	mailer := &stubPrivacyMailer{}
+	service := NewPrivacyService(users, &stubAuthoredPosts{}, &stubAuthoredComments{}, newStubDeletionTokens(), mailer, time.Hour)
+
+	if err := service.RequestDeletion(context.Background(), users.user.Id.String(), "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("RequestDeletion() error = %v, want ErrWrongPassword", err)
+	}
+
+	if len(mailer.confirmations) != 0 {
+		t.Error("no confirmation should be sent")
+	}
+}
+
+// The only administrator deleting their account would leave nobody able to
+// run the site, so the request is refused before any link is sent.
+func TestDeletion_RefusesTheLastAdmin(t *testing.T) {
+	users := newStubPrivacyUsers(t)
+	users.lastAdmin = true
+	mailer := &stubPrivacyMailer{}
+	service := NewPrivacyService(users, &stubAuthoredPosts{}, &stubAuthoredComments{}, newStubDeletionTokens(), mailer, time.Hour)
+
+	if err := service.RequestDeletion(context.Background(), users.user.Id.String(), currentPassword); !errors.Is(err, user.ErrLastAdmin) {
+		t.Fatalf("RequestDeletion() error = %v, want ErrLastAdmin", err)
+	}
+
+	if len(mailer.confirmations) != 0 {
+		t.Error("no confirmation should be sent")
+	}
+}
+
+func TestDeletion_CancelDuringGracePeriod(t *testing.T) {
+	users := newStubPrivacyUsers(t)
+	users.scheduled = sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	mailer := &stubPrivacyMailer{}
+	service := NewPrivacyService(users, &stubAuthoredPosts{}, &stubAuthoredComments{}, newStubDeletionTokens(), mailer, time.Hour)
+	ctx := context.Background()
+
+	if err := service.CancelDeletion(ctx, users.user.Id.String()); err != nil {
+		t.Fatalf("CancelDeletion() error = %v", err)
+	}
+
+	if users.scheduled.Valid || mailer.cancelled != 1 {
+		t.Errorf("expected the deletion cancelled with a notice, got %+v and %d notices", users.scheduled, mailer.cancelled)
+	}
+
+	if err := service.CancelDeletion(ctx, users.user.Id.String()); !errors.Is(err, ErrDeletionNotPending) {
+		t.Errorf("second CancelDeletion() error = %v, want ErrDeletionNotPending", err)
+	}
+}
+
+// One account that cannot be anonymised must not hold up the others; it is
+// picked up again on the next run.
+func TestDeleteDue_ContinuesPastFailures(t *testing.T) {
+	users := newStubPrivacyUsers(t)
+	failing, ok := uuid.New(), uuid.New()
+	users.due = []uuid.UUID{failing, ok}
+	users.failFor = failing
+	service := NewPrivacyService(users, &stubAuthoredPosts{}, &stubAuthoredComments{}, newStubDeletionTokens(), &stubPrivacyMailer{}, time.Hour)
+
+	deleted, err := service.DeleteDue(context.Background())
+	if err != nil {
+		t.Fatalf("DeleteDue() error = %v", err)
+	}
+
+	if deleted != 1 || len(users.anonymised) != 1 || users.anonymised[0] != ok {
+		t.Errorf("deleted = %d, anonymised = %v, want only %v", deleted, users.anonymised, ok)
+	}
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/audit"
+	"server/internal/infrastructure/tracing"
+	"server/util/ctxutils"
+
+	"github.com/google/uuid"
+)
+
+type eventRepository interface {
+	Append(ctx context.Context, event audit.Event) error
+	Find(ctx context.Context, filter audit.Filter, limit, offset int) ([]audit.Event, int, error)
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// AuditService keeps the audit trail: who did what, from where, and whether
+// it worked.
+type AuditService struct {
+	events    eventRepository
+	retention time.Duration
+}
+
+func NewAuditService(events eventRepository, retention time.Duration) *AuditService {
+	return &AuditService{
+		events:    events,
+		retention: retention,
+	}
+}
+
+// Record stores the event, completing it from the request: the request id,
+// the client's address and user agent, and, unless the caller named one, the
+// signed in user as the actor.
+//
+// A failure is logged and otherwise ignored. The action being recorded has
+// already happened, and refusing it after the fact would not undo it.
+func (s *AuditService) Record(ctx context.Context, event audit.Event) {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	event.Id = uuid.New()
+	event.OccurredAt = time.Now().UTC()
+	event.RequestId = ctxutils.RequestIdFromContext(ctx)
+
+	client := ctxutils.ClientFromContext(ctx)
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+
+	if event.Outcome == "" {
+		event.Outcome = audit.Success
+	}
+
+	if !event.ActorId.Valid {
+		if loggedUser, err := ctxutils.GetUser(ctx); err == nil {
+			if id, err := uuid.Parse(loggedUser.Id); err == nil {
+				event.ActorId = uuid.NullUUID{UUID: id, Valid: true}
+			}
+		}
+	}
+
+	if err := s.events.Append(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "Failed to record an audit event", "error", err, "action", event.Action, "outcome", event.Outcome)
+	}
+}
+
+// EventPage is one page of the audit trail.
+type EventPage struct {
+	Events []audit.Event
+	Total  int
+}
+
+func (s *AuditService) Search(ctx context.Context, filter audit.Filter, page, pageSize int) (EventPage, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Search")
+	defer span.End()
+
+	events, total, err := s.events.Find(ctx, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return EventPage{}, err
+	}
+
+	return EventPage{Events: events, Total: total}, nil
+}
+
+// PurgeExpired deletes the events older than the retention period.
+func (s *AuditService) PurgeExpired(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.PurgeExpired")
+	defer span.End()
+
+	purged, err := s.events.DeleteBefore(ctx, time.Now().UTC().Add(-s.retention))
+	if purged > 0 {
+		slog.InfoContext(ctx, "Purged expired audit events", "count", purged)
+	}
+	return purged, err
+}
+
+// ActorOf names an account as the actor of an event, for events where the
+// account acting is not the signed in user, such as a sign-in.
+func ActorOf(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: true}
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"server/internal/domain/audit"
+	"server/util/ctxutils"
+	"server/util/securityutil"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type stubEvents struct {
+	appended []audit.Event
+	cutoff   time.Time
+	err      error
+}
+
+func (s *stubEvents) Append(_ context.Context, event audit.Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.appended = append(s.appended, event)
+	return nil
+}
+
+func (s *stubEvents) Find(context.Context, audit.Filter, int, int) ([]audit.Event, int, error) {
+	return s.appended, len(s.appended), nil
+}
+
+func (s *stubEvents) DeleteBefore(_ context.Context, cutoff time.Time) (int64, error) {
+	s.cutoff = cutoff
+	return 0, nil
+}
+
+func requestContext(userId string) context.Context {
+	ctx := ctxutils.WithRequestId(context.Background(), "req-1")
+	ctx = ctxutils.WithClient(ctx, ctxutils.Client{IP: "203.0.113.7", UserAgent: "test-agent"})
+	if userId != "" {
+		ctx = ctxutils.WithLoggedUser(ctx, &securityutil.LoggedInUser{Id: userId})
+	}
+	return ctx
+}
+
+// Handlers only say what happened; where it came from is taken from the
+// request, so no call site can forget it.
+func TestRecord_CompletesEventFromRequest(t *testing.T) {
+	events := &stubEvents{}
+	service := NewAuditService(events, time.Hour)
+	admin := uuid.New()
+
+	service.Record(requestContext(admin.String()), audit.Event{Action: audit.ActionPostDelete, TargetType: audit.TargetPost, TargetId: "p1"})
+
+	if len(events.appended) != 1 {
+		t.Fatalf("appended %d events, want 1", len(events.appended))
+	}
+
+	got := events.appended[0]
+	if got.RequestId != "req-1" || got.IP != "203.0.113.7" || got.UserAgent != "test-agent" {
+		t.Errorf("request details = %q %q %q", got.RequestId, got.IP, got.UserAgent)
+	}
+	if got.ActorId.UUID != admin || got.Outcome != audit.Success || got.Id == uuid.Nil || got.OccurredAt.IsZero() {
+		t.Errorf("event = %+v", got)
+	}
+}
+
+// At sign-in nobody is signed in yet, so the account being signed in to is
+// named explicitly and must not be replaced.
+func TestRecord_KeepsExplicitActor(t *testing.T) {
+	events := &stubEvents{}
+	service := NewAuditService(events, time.Hour)
+	account := uuid.New()
+
+	service.Record(requestContext(uuid.NewString()), audit.Event{
+		Action:  audit.ActionLogin,
+		Outcome: audit.Failure,
+		Detail:  audit.ReasonWrongPassword,
+		ActorId: ActorOf(account),
+	})
+
+	if got := events.appended[0].ActorId.UUID; got != account {
+		t.Errorf("actor = %v, want %v", got, account)
+	}
+}
+
+// The action has already happened by the time it is recorded, so a storage
+// failure must not surface as a failed request.
+func TestRecord_StorageFailureIsSwallowed(t *testing.T) {
+	service := NewAuditService(&stubEvents{err: errors.New("database down")}, time.Hour)
+
+	service.Record(requestContext(""), audit.Event{Action: audit.ActionLogout})
+}
+
+func TestPurgeExpired_UsesRetention(t *testing.T) {
+	events := &stubEvents{}
+	service := NewAuditService(events, 30*24*time.Hour)
+
+	if _, err := service.PurgeExpired(context.Background()); err != nil {
+		t.Fatalf("PurgeExpired() error = %v", err)
+	}
+
+	want := time.Now().UTC().Add(-30 * 24 * time.Hour)
+	if diff := events.cutoff.Sub(want); diff < -time.Minute || diff > time.Minute {
+		t.Errorf("cutoff = %v, want about %v", events.cutoff, want)
+	}
+}
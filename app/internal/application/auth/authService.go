@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
 	"server/util/securityutil"
 	"time"
 )
@@ -24,6 +25,9 @@ func NewAuthService() *AuthService {
 }
 
 func (auth *AuthService) Authenticate(user user.User, password string, rememberMe bool, ctx context.Context) (*TokenResult, error) {
+	_, span := tracing.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
 	hashMatch := securityutil.CompareHash(user.Password, password)
 	if !hashMatch {
 		return nil, ErrHashNotMatch
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The slices of the repositories and mailer verification needs. Narrow so the
+// service can be tested without a database or an SMTP server.
+type verificationUserRepository interface {
+	FindByEmail(ctx context.Context, email string) (user.User, error)
+	MarkEmailVerified(ctx context.Context, userId uuid.UUID) (bool, error)
+	DeleteUnverifiedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type verificationTokenRepository interface {
+	Create(ctx context.Context, userId uuid.UUID, tokenHash string) (*user.EmailVerificationToken, error)
+	FindValidByHash(ctx context.Context, tokenHash string) (*user.EmailVerificationToken, error)
+	MarkAsUsed(ctx context.Context, tokenId uuid.UUID) error
+	InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type verificationMailer interface {
+	SendVerificationEmail(ctx context.Context, toEmail, token string) error
+}
+
+type EmailVerificationService struct {
+	userRepo     verificationUserRepository
+	tokenRepo    verificationTokenRepository
+	emailService verificationMailer
+}
+
+func NewEmailVerificationService(
+	userRepo verificationUserRepository,
+	tokenRepo verificationTokenRepository,
+	emailService verificationMailer,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		emailService: emailService,
+	}
+}
+
+// SendVerification issues a fresh verification link for the account and mails
+// it. Earlier links stop working, so only the newest email can be used.
+func (s *EmailVerificationService) SendVerification(ctx context.Context, userId uuid.UUID, emailAddr string) error {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.SendVerification")
+	defer span.End()
+
+	plainToken, tokenHash, err := user.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	if invalidateErr := s.tokenRepo.InvalidateAllForUser(ctx, userId); invalidateErr != nil {
+		slog.WarnContext(ctx, "Failed to invalidate existing verification tokens", "error", invalidateErr)
+	}
+
+	if _, err := s.tokenRepo.Create(ctx, userId, tokenHash); err != nil {
+		return err
+	}
+
+	if err := s.emailService.SendVerificationEmail(ctx, emailAddr, plainToken); err != nil {
+		// The account exists either way; the user can ask for another link.
+		slog.ErrorContext(ctx, "Failed to send verification email", "error", err, "userId", userId)
+	}
+
+	return nil
+}
+
+// ResendVerification mails a new link to an unverified account.
+// Always returns nil for unknown or already verified addresses, so the
+// endpoint cannot be used to find out which addresses are registered.
+func (s *EmailVerificationService) ResendVerification(ctx context.Context, emailAddr string) error {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.ResendVerification")
+	defer span.End()
+
+	u, err := s.userRepo.FindByEmail(ctx, emailAddr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.InfoContext(ctx, "Verification resend requested for non-existent email", "email", emailAddr)
+			return nil
+		}
+		return err
+	}
+
+	if u.EmailVerifiedAt.Valid {
+		slog.InfoContext(ctx, "Verification resend requested for a verified account", "userId", u.Id)
+		return nil
+	}
+
+	return s.SendVerification(ctx, u.Id, u.Email)
+}
+
+// Verify consumes a verification token and activates its account.
+func (s *EmailVerificationService) Verify(ctx context.Context, plainToken string) error {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.Verify")
+	defer span.End()
+
+	token, err := s.tokenRepo.FindValidByHash(ctx, user.HashToken(plainToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	activated, err := s.userRepo.MarkEmailVerified(ctx, token.UserId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.InvalidateAllForUser(ctx, token.UserId); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate verification tokens", "error", err)
+	}
+
+	// A valid token for an account that is already verified or gone has
+	// nothing left to confirm.
+	if !activated {
+		return ErrInvalidToken
+	}
+
+	slog.InfoContext(ctx, "Email address verified", "userId", token.UserId)
+	return nil
+}
+
+// PurgeUnverified deletes accounts that were never verified within maxAge of
+// registering, along with expired verification tokens. Returns the number of
+// accounts removed.
+func (s *EmailVerificationService) PurgeUnverified(ctx context.Context, maxAge time.Duration) (int64, error) {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.PurgeUnverified")
+	defer span.End()
+
+	if _, err := s.tokenRepo.DeleteExpired(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to delete expired verification tokens", "error", err)
+	}
+
+	deleted, err := s.userRepo.DeleteUnverifiedBefore(ctx, time.Now().UTC().Add(-maxAge))
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Purged unverified accounts", "count", deleted)
+	}
+	return deleted, err
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"server/internal/domain/user"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type stubVerificationUsers struct {
+	byEmail  map[string]user.User
+	verified map[uuid.UUID]bool
+	cutoff   time.Time
+}
+
+func newStubVerificationUsers(users ...user.User) *stubVerificationUsers {
+	s := &stubVerificationUsers{byEmail: map[string]user.User{}, verified: map[uuid.UUID]bool{}}
+	for _, u := range users {
+		s.byEmail[u.Email] = u
+		s.verified[u.Id] = u.EmailVerifiedAt.Valid
+	}
+	return s
+}
+
+func (s *stubVerificationUsers) FindByEmail(_ context.Context, email string) (user.User, error) {
+	u, ok := s.byEmail[email]
+	if !ok {
+		return user.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (s *stubVerificationUsers) MarkEmailVerified(_ context.Context, userId uuid.UUID) (bool, error) {
+	verified, ok := s.verified[userId]
+	if !ok || verified {
+		return false, nil
+	}
+	s.verified[userId] = true
+	return true, nil
+}
+
+func (s *stubVerificationUsers) DeleteUnverifiedBefore(_ context.Context, cutoff time.Time) (int64, error) {
+	s.cutoff = cutoff
+	return 0, nil
+}
+
+type stubVerificationTokens struct {
+	tokens map[string]*user.EmailVerificationToken
+}
+
+func newStubVerificationTokens() *stubVerificationTokens {
+	return &stubVerificationTokens{tokens: map[string]*user.EmailVerificationToken{}}
+}
+
+func (s *stubVerificationTokens) Create(_ context.Context, userId uuid.UUID, tokenHash string) (*user.EmailVerificationToken, error) {
+	token := &user.EmailVerificationToken{
+		Id:        uuid.New(),
+		UserId:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(user.VerificationTokenExpirationDuration),
+	}
+	s.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (s *stubVerificationTokens) FindValidByHash(_ context.Context, tokenHash string) (*user.EmailVerificationToken, error) {
+	token, ok := s.tokens[tokenHash]
+	if !ok || token.UsedAt.Valid || time.Now().After(token.ExpiresAt) {
+		return nil, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *stubVerificationTokens) MarkAsUsed(_ context.Context, tokenId uuid.UUID) error {
+	for _, token := range s.tokens {
+		if token.Id == tokenId {
+			token.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *stubVerificationTokens) InvalidateAllForUser(_ context.Context, userId uuid.UUID) error {
+	for _, token := range s.tokens {
+		if token.UserId == userId && !token.UsedAt.Valid {
+			token.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *stubVerificationTokens) DeleteExpired(context.Context) (int64, error) {
+	return 0, nil
+}
+
+type stubVerificationMailer struct {
+	sent []sentEmail
+	err  error
+}
+
+func (s *stubVerificationMailer) SendVerificationEmail(_ context.Context, toEmail, token string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, sentEmail{to: toEmail, token: token})
+	return nil
+}
+
+func unverifiedUser() user.User {
+	return user.User{Id: uuid.New(), Email: "new@example.com", Status: user.Inactive}
+}
+
+// The link in the email is the only proof of ownership, so it has to be the
+// token that activates the account - and only the account it was issued for.
+func TestEmailVerification_LinkActivatesTheAccount(t *testing.T) {
+	account := unverifiedUser()
+	users := newStubVerificationUsers(account)
+	mailer := &stubVerificationMailer{}
+	service := NewEmailVerificationService(users, newStubVerificationTokens(), mailer)
+	ctx := context.Background()
+
+	if err := service.SendVerification(ctx, account.Id, account.Email); err != nil {
+		t.Fatalf("SendVerification() error = %v", err)
+	}
+
+	if len(mailer.sent) != 1 || mailer.sent[0].to != account.Email {
+		t.Fatalf("expected one email to %s, got %+v", account.Email, mailer.sent)
+	}
+
+	if err := service.Verify(ctx, mailer.sent[0].token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if !users.verified[account.Id] {
+		t.Error("the account should be verified")
+	}
+}
+
+// A link must not be replayable: a forwarded or leaked email would otherwise
+// keep working after the owner used it.
+func TestEmailVerification_LinkIsSingleUse(t *testing.T) {
+	account := unverifiedUser()
+	mailer := &stubVerificationMailer{}
+	service := NewEmailVerificationService(newStubVerificationUsers(account), newStubVerificationTokens(), mailer)
+	ctx := context.Background()
+
+	_ = service.SendVerification(ctx, account.Id, account.Email)
+	token := mailer.sent[0].token
+
+	if err := service.Verify(ctx, token); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+
+	if err := service.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("second Verify() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+// Only the most recent email should work, so asking for a new link retires any
+// copy of the old one that might be sitting in someone else's inbox.
+func TestEmailVerification_ResendRetiresEarlierLinks(t *testing.T) {
+	account := unverifiedUser()
+	mailer := &stubVerificationMailer{}
+	service := NewEmailVerificationService(newStubVerificationUsers(account), newStubVerificationTokens(), mailer)
+	ctx := context.Background()
+
+	_ = service.SendVerification(ctx, account.Id, account.Email)
+	if err := service.ResendVerification(ctx, account.Email); err != nil {
+		t.Fatalf("ResendVerification() error = %v", err)
+	}
+
+	if len(mailer.sent) != 2 {
+		t.Fatalf("expected two emails, got %d", len(mailer.sent))
+	}
+
+	if err := service.Verify(ctx, mailer.sent[0].token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(old link) error = %v, want ErrInvalidToken", err)
+	}
+
+	if err := service.Verify(ctx, mailer.sent[1].token); err != nil {
+		t.Errorf("Verify(new link) error = %v", err)
+	}
+}
+
+// The resend form answers the same way for every address, so it must not
+// error or mail anything for unknown or already verified ones.
+func TestEmailVerification_ResendRevealsNothing(t *testing.T) {
+	verified := user.User{
+		Id:              uuid.New(),
+		Email:           "done@example.com",
+		Status:          user.Active,
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	mailer := &stubVerificationMailer{}
+	service := NewEmailVerificationService(newStubVerificationUsers(verified), newStubVerificationTokens(), mailer)
+	ctx := context.Background()
+
+	for _, email := range []string{"nobody@example.com", verified.Email} {
+		if err := service.ResendVerification(ctx, email); err != nil {
+			t.Errorf("ResendVerification(%q) error = %v, want nil", email, err)
+		}
+	}
+
+	if len(mailer.sent) != 0 {
+		t.Errorf("expected no emails, got %d", len(mailer.sent))
+	}
+}
+
+// A mail outage must not fail the registration: the account exists and the
+// user can ask for another link.
+func TestEmailVerification_MailFailureIsNotFatal(t *testing.T) {
+	account := unverifiedUser()
+	mailer := &stubVerificationMailer{err: errors.New("smtp down")}
+	service := NewEmailVerificationService(newStubVerificationUsers(account), newStubVerificationTokens(), mailer)
+
+	if err := service.SendVerification(context.Background(), account.Id, account.Email); err != nil {
+		t.Errorf("SendVerification() error = %v, want nil", err)
+	}
+}
+
+func TestEmailVerification_UnknownTokenIsRejected(t *testing.T) {
+	service := NewEmailVerificationService(newStubVerificationUsers(), newStubVerificationTokens(), &stubVerificationMailer{})
+
+	if err := service.Verify(context.Background(), "not-a-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+// The purge works from registration time, so the cutoff has to lie maxAge in
+// the past - getting the sign wrong would delete every pending account.
+func TestEmailVerification_PurgeCutoffIsInThePast(t *testing.T) {
+	users := newStubVerificationUsers()
+	service := NewEmailVerificationService(users, newStubVerificationTokens(), &stubVerificationMailer{})
+
+	if _, err := service.PurgeUnverified(context.Background(), 7*24*time.Hour); err != nil {
+		t.Fatalf("PurgeUnverified() error = %v", err)
+	}
+
+	want := time.Now().UTC().Add(-7 * 24 * time.Hour)
+	if diff := users.cutoff.Sub(want); diff < -time.Minute || diff > time.Minute {
+		t.Errorf("cutoff = %v, want about %v", users.cutoff, want)
+	}
+}
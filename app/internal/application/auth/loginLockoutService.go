@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAccountLocked  = errors.New("account is locked")
+	ErrLoginThrottled = errors.New("too soon after a failed login")
+)
+
+const (
+	// freeLoginAttempts failures may follow each other without delay, which
+	// covers an ordinary mistype or two.
+	freeLoginAttempts = 3
+
+	// lockoutThreshold failures within failureWindow lock the account for
+	// LockoutDuration.
+	lockoutThreshold = 10
+	failureWindow    = 24 * time.Hour
+	LockoutDuration  = time.Hour
+)
+
+type lockoutUserRepository interface {
+	FindLoginState(ctx context.Context, userId uuid.UUID) (user.LoginState, error)
+	RecordFailedLogin(ctx context.Context, userId uuid.UUID, window time.Duration) (user.LoginState, error)
+	Lock(ctx context.Context, userId uuid.UUID, until time.Time) (bool, error)
+	ClearFailedLogins(ctx context.Context, userId uuid.UUID) error
+}
+
+type unlockTokenRepository interface {
+	Create(ctx context.Context, userId uuid.UUID, tokenHash string) (*user.AccountUnlockToken, error)
+	FindValidByHash(ctx context.Context, tokenHash string) (*user.AccountUnlockToken, error)
+	InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error
+}
+
+type lockoutMailer interface {
+	SendAccountLockedEmail(ctx context.Context, toEmail, token string, until time.Time) error
+}
+
+// LoginLockoutService slows down password guessing against a single account,
+// wherever the guesses come from. After a few failures every further attempt
+// has to wait twice as long as the one before, and after lockoutThreshold the
+// account is locked and its owner is mailed a link to lift the lock.
+type LoginLockoutService struct {
+	users  lockoutUserRepository
+	tokens unlockTokenRepository
+	mailer lockoutMailer
+}
+
+func NewLoginLockoutService(users lockoutUserRepository, tokens unlockTokenRepository, mailer lockoutMailer) *LoginLockoutService {
+	return &LoginLockoutService{
+		users:  users,
+		tokens: tokens,
+		mailer: mailer,
+	}
+}
+
+// Check reports whether the account may attempt a sign-in now. It is asked
+// before the password is looked at, so a locked account cannot be guessed at
+// even with the right password.
+func (s *LoginLockoutService) Check(ctx context.Context, userId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "LoginLockoutService.Check")
+	defer span.End()
+
+	state, err := s.users.FindLoginState(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if state.LockedAt(now) {
+		return ErrAccountLocked
+	}
+
+	if state.LastFailedAt.Valid && now.Before(state.LastFailedAt.Time.Add(loginBackoff(state.FailedCount))) {
+		return ErrLoginThrottled
+	}
+
+	return nil
+}
+
+// RecordFailure counts a wrong password and locks the account once the count
+// reaches lockoutThreshold.
+func (s *LoginLockoutService) RecordFailure(ctx context.Context, account user.User) error {
+	ctx, span := tracing.Start(ctx, "LoginLockoutService.RecordFailure")
+	defer span.End()
+
+	state, err := s.users.RecordFailedLogin(ctx, account.Id, failureWindow)
+	if err != nil {
+		return err
+	}
+
+	if state.FailedCount < lockoutThreshold {
+		return nil
+	}
+
+	until := time.Now().UTC().Add(LockoutDuration)
+	locked, err := s.users.Lock(ctx, account.Id, until)
+	if err != nil || !locked {
+		return err
+	}
+
+	slog.WarnContext(ctx, "Account locked after repeated failed logins", "userId", account.Id, "until", until)
+
+	// The lock is in place whether or not the notice goes out; it only decides
+	// whether the owner can lift it early.
+	if err := s.sendUnlockLink(ctx, account, until); err != nil {
+		slog.ErrorContext(ctx, "Failed to send the account locked notice", "error", err, "userId", account.Id)
+	}
+
+	return nil
+}
+
+// RecordSuccess forgets earlier failures once the right password is given.
+func (s *LoginLockoutService) RecordSuccess(ctx context.Context, userId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "LoginLockoutService.RecordSuccess")
+	defer span.End()
+
+	return s.users.ClearFailedLogins(ctx, userId)
+}
+
+// Unlock consumes an unlock token, lifts the lock on its account and returns
+// the account's id.
+func (s *LoginLockoutService) Unlock(ctx context.Context, plainToken string) (uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "LoginLockoutService.Unlock")
+	defer span.End()
+
+	token, err := s.tokens.FindValidByHash(ctx, user.HashToken(plainToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrInvalidToken
+		}
+		return uuid.Nil, err
+	}
+
+	if err := s.users.ClearFailedLogins(ctx, token.UserId); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := s.tokens.InvalidateAllForUser(ctx, token.UserId); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate unlock tokens", "error", err)
+	}
+
+	slog.InfoContext(ctx, "Account unlocked by its owner", "userId", token.UserId)
+	return token.UserId, nil
+}
+
+func (s *LoginLockoutService) sendUnlockLink(ctx context.Context, account user.User, until time.Time) error {
+	plainToken, tokenHash, err := user.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.tokens.InvalidateAllForUser(ctx, account.Id); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate existing unlock tokens", "error", err)
+	}
+
+	if _, err := s.tokens.Create(ctx, account.Id, tokenHash); err != nil {
+		return err
+	}
+
+	return s.mailer.SendAccountLockedEmail(ctx, account.Email, plainToken, until)
+}
+
+// loginBackoff is how long to wait after the failedCount-th failure: nothing
+// for the first few, then one second, doubling with each failure after that.
+func loginBackoff(failedCount int) time.Duration {
+	if failedCount < freeLoginAttempts {
+		return 0
+	}
+
+	return time.Second << (failedCount - freeLoginAttempts)
+}
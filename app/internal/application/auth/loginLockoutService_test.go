@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"server/internal/domain/user"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// stubLockoutUsers keeps one account's login state the way the repository
+// would, including the once-only Lock.
+type stubLockoutUsers struct {
+	state user.LoginState
+}
+
+func (s *stubLockoutUsers) FindLoginState(context.Context, uuid.UUID) (user.LoginState, error) {
+	return s.state, nil
+}
+
+func (s *stubLockoutUsers) RecordFailedLogin(context.Context, uuid.UUID, time.Duration) (user.LoginState, error) {
+	s.state.FailedCount++
+	s.state.LastFailedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	return s.state, nil
+}
+
+func (s *stubLockoutUsers) Lock(_ context.Context, _ uuid.UUID, until time.Time) (bool, error) {
+	if s.state.LockedAt(time.Now().UTC()) {
+		return false, nil
+	}
+	s.state.LockedUntil = sql.NullTime{Time: until, Valid: true}
+	s.state.FailedCount = 0
+	return true, nil
+}
+
+func (s *stubLockoutUsers) ClearFailedLogins(context.Context, uuid.UUID) error {
+	s.state = user.LoginState{}
+	return nil
+}
+
+type stubUnlockTokens struct {
+	tokens map[string]*user.AccountUnlockToken
+}
+
+func (s *stubUnlockTokens) Create(_ context.Context, userId uuid.UUID, tokenHash string) (*user.AccountUnlockToken, error) {
+	token := &user.AccountUnlockToken{Id: uuid.New(), UserId: userId, TokenHash: tokenHash}
+	s.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (s *stubUnlockTokens) FindValidByHash(_ context.Context, tokenHash string) (*user.AccountUnlockToken, error) {
+	token, ok := s.tokens[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *stubUnlockTokens) InvalidateAllForUser(_ context.Context, userId uuid.UUID) error {
+	for hash, token := range s.tokens {
+		if token.UserId == userId {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+type stubLockoutMailer struct {
+	sent   int
+	token  string
+	toAddr string
+}
+
+func (m *stubLockoutMailer) SendAccountLockedEmail(_ context.Context, toEmail, token string, _ time.Time) error {
+	m.sent++
+	m.token = token
+	m.toAddr = toEmail
+	return nil
+}
+
+func newTestLockoutService() (*LoginLockoutService, *stubLockoutUsers, *stubLockoutMailer) {
+	users := &stubLockoutUsers{}
+	mailer := &stubLockoutMailer{}
+	return NewLoginLockoutService(users, &stubUnlockTokens{tokens: map[string]*user.AccountUnlockToken{}}, mailer), users, mailer
+}
+
+func TestLoginBackoff_DoublesAfterFreeAttempts(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:                     0,
+		freeLoginAttempts - 1: 0,
+		freeLoginAttempts:     time.Second,
+		freeLoginAttempts + 1: 2 * time.Second,
+		freeLoginAttempts + 3: 8 * time.Second,
+	}
+
+	for failures, want := range cases {
+		if got := loginBackoff(failures); got != want {
+			t.Errorf("loginBackoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+// Once the free attempts are used up, the next one has to wait, even with the
+// right password: the check comes before the password is looked at.
+func TestCheck_ThrottlesRightAfterRepeatedFailures(t *testing.T) {
+	service, _, _ := newTestLockoutService()
+	ctx := context.Background()
+	account := user.User{Id: uuid.New(), Email: "owner@example.com"}
+
+	for i := 0; i < freeLoginAttempts; i++ {
+		if err := service.Check(ctx, account.Id); err != nil {
+			t.Fatalf("attempt %d: Check() = %v, want nil", i+1, err)
+		}
+		if err := service.RecordFailure(ctx, account); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+
+	if err := service.Check(ctx, account.Id); !errors.Is(err, ErrLoginThrottled) {
+		t.Errorf("Check() after %d failures = %v, want ErrLoginThrottled", freeLoginAttempts, err)
+	}
+}
+
+// Reaching the threshold locks the account and mails its owner exactly once;
+// the link in that mail lifts the lock.
+func TestRecordFailure_LocksAndUnlockLiftsIt(t *testing.T) {
+	service, users, mailer := newTestLockoutService()
+	ctx := context.Background()
+	account := user.User{Id: uuid.New(), Email: "owner@example.com"}
+
+	for i := 0; i < lockoutThreshold; i++ {
+		if err := service.RecordFailure(ctx, account); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+
+	if err := service.Check(ctx, account.Id); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Check() after lockout = %v, want ErrAccountLocked", err)
+	}
+	if mailer.sent != 1 || mailer.toAddr != account.Email {
+		t.Fatalf("notices sent = %d to %q, want 1 to the owner", mailer.sent, mailer.toAddr)
+	}
+
+	unlocked, err := service.Unlock(ctx, mailer.token)
+	if err != nil || unlocked != account.Id {
+		t.Fatalf("Unlock() = %v, %v", unlocked, err)
+	}
+	if users.state.LockedAt(time.Now().UTC()) {
+		t.Error("account still locked after Unlock()")
+	}
+
+	if _, err := service.Unlock(ctx, mailer.token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("second Unlock() = %v, want ErrInvalidToken", err)
+	}
+}
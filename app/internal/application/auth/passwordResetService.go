@@ -7,7 +7,10 @@ import (
 	"log/slog"
 	"server/internal/domain/user"
 	"server/internal/infrastructure/email"
+	"server/internal/infrastructure/tracing"
 	"server/util/securityutil"
+
+	"github.com/google/uuid"
 )
 
 var (
@@ -37,6 +40,9 @@ func NewPasswordResetService(
 // RequestReset initiates a password reset for the given email
 // Always returns nil to prevent email enumeration attacks
 func (s *PasswordResetService) RequestReset(ctx context.Context, emailAddr string) error {
+	ctx, span := tracing.Start(ctx, "PasswordResetService.RequestReset")
+	defer span.End()
+
 	// Find user by email
 	u, err := s.userRepo.FindByEmail(ctx, emailAddr)
 	if err != nil {
@@ -76,11 +82,19 @@ func (s *PasswordResetService) RequestReset(ctx context.Context, emailAddr strin
 	return nil
 }
 
-// ResetPassword resets the password using the token
-func (s *PasswordResetService) ResetPassword(ctx context.Context, plainToken, newPassword string) error {
+// ResetPassword resets the password using the token and returns the account
+// it belonged to
+func (s *PasswordResetService) ResetPassword(ctx context.Context, plainToken, newPassword string) (uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "PasswordResetService.ResetPassword")
+	defer span.End()
+
 	// Validate password strength against the shared policy
 	if !securityutil.IsPasswordStrong(newPassword) {
-		return ErrPasswordWeak
+		return uuid.Nil, ErrPasswordWeak
+	}
+
+	if securityutil.IsPasswordBreached(newPassword) {
+		return uuid.Nil, securityutil.ErrPasswordBreached
 	}
 
 	// Hash the token and find it
@@ -88,21 +102,21 @@ func (s *PasswordResetService) ResetPassword(ctx context.Context, plainToken, ne
 	token, err := s.tokenRepo.FindValidByHash(ctx, tokenHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return ErrInvalidToken
+			return uuid.Nil, ErrInvalidToken
 		}
-		return err
+		return uuid.Nil, err
 	}
 
 	// Hash the new password
 	hashedPassword, err := securityutil.HashPassword(newPassword)
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	}
 
 	// Update user's password
 	err = s.userRepo.UpdatePassword(ctx, token.UserId.String(), hashedPassword)
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	}
 
 	// Mark token as used
@@ -118,11 +132,14 @@ func (s *PasswordResetService) ResetPassword(ctx context.Context, plainToken, ne
 	}
 
 	slog.InfoContext(ctx, "Password reset successful", "userId", token.UserId)
-	return nil
+	return token.UserId, nil
 }
 
 // ValidateToken checks if a token is valid without using it
 func (s *PasswordResetService) ValidateToken(ctx context.Context, plainToken string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "PasswordResetService.ValidateToken")
+	defer span.End()
+
 	tokenHash := user.HashToken(plainToken)
 	_, err := s.tokenRepo.FindValidByHash(ctx, tokenHash)
 	if err != nil {
@@ -133,3 +150,20 @@ func (s *PasswordResetService) ValidateToken(ctx context.Context, plainToken str
 	}
 	return true, nil
 }
+
+// PurgeExpiredTokens deletes reset tokens past their expiry, used or not.
+// They can no longer reset anything and only show who asked for a reset.
+func (s *PasswordResetService) PurgeExpiredTokens(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "PasswordResetService.PurgeExpiredTokens")
+	defer span.End()
+
+	deleted, err := s.tokenRepo.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Purged expired password reset tokens", "count", deleted)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"server/internal/domain/category"
 	"server/internal/http/handlers/models"
+	"server/internal/infrastructure/tracing"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,10 +24,16 @@ func NewCategoryService(categoryRepository categoryRepository) *CategoryService
 }
 
 func (categoryService *CategoryService) GetCategories(ctx context.Context) ([]category.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetCategories")
+	defer span.End()
+
 	return categoryService.categoryRepository.FindAll(ctx)
 }
 
 func (categoryService *CategoryService) Create(ctx context.Context, resource models.CreateCategoryResource) (*category.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Create")
+	defer span.End()
+
 	toCreate := category.Category{
 		Id:        uuid.New(),
 		Name:      resource.Name,
@@ -0,0 +1,201 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"server/internal/domain/comments"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyComment   = errors.New("comment is empty")
+	ErrParentNotFound = errors.New("the comment being answered was not found")
+	ErrInvalidStatus  = errors.New("unknown moderation status")
+)
+
+type commentRepository interface {
+	Create(ctx context.Context, comment comments.Comment) error
+	FindById(ctx context.Context, id uuid.UUID) (*comments.Comment, error)
+	FindVisible(ctx context.Context, postId uuid.UUID, viewerId uuid.NullUUID) ([]comments.Comment, error)
+	FindByStatus(ctx context.Context, status comments.Status, limit, offset int) ([]comments.QueueItem, int, error)
+	SetStatus(ctx context.Context, ids []uuid.UUID, status comments.Status, moderatorId uuid.UUID) (int64, error)
+}
+
+// classifier learns from moderation decisions which comments are spam.
+type classifier interface {
+	Train(ctx context.Context, documentId uuid.UUID, text string, isSpam bool) error
+}
+
+// NewComment is a comment as a reader submits it.
+type NewComment struct {
+	PostId   uuid.UUID
+	UserId   uuid.UUID
+	ParentId uuid.NullUUID
+	Body     string
+
+	// Trusted comments skip the queue. Moderators would only be approving
+	// their own words.
+	Trusted bool
+
+	// Suspicious comments are held as spam rather than pending, so they wait
+	// in the spam tab instead of cluttering the queue. Their author still
+	// sees them as awaiting moderation.
+	Suspicious bool
+}
+
+// QueuePage is one page of the moderation queue and the total in that state.
+type QueuePage struct {
+	Items []comments.QueueItem
+	Total int
+}
+
+// CommentService takes comments from readers and holds them for moderation
+// until someone approves them.
+type CommentService struct {
+	comments   commentRepository
+	classifier classifier
+}
+
+func NewCommentService(repository commentRepository, classifier classifier) *CommentService {
+	return &CommentService{comments: repository, classifier: classifier}
+}
+
+// Post stores the comment, pending unless it is trusted or suspicious. A reply must answer
+// an approved comment on the same post.
+func (s *CommentService) Post(ctx context.Context, input NewComment) (*comments.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.Post")
+	defer span.End()
+
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	if input.ParentId.Valid {
+		parent, err := s.comments.FindById(ctx, input.ParentId.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrParentNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.PostId != input.PostId || parent.Status != comments.StatusApproved {
+			return nil, ErrParentNotFound
+		}
+	}
+
+	comment := comments.Comment{
+		Id:        uuid.New(),
+		PostId:    input.PostId,
+		UserId:    input.UserId,
+		ParentId:  input.ParentId,
+		Body:      body,
+		Status:    comments.StatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	switch {
+	case input.Trusted:
+		comment.Status = comments.StatusApproved
+	case input.Suspicious:
+		comment.Status = comments.StatusSpam
+	}
+
+	if err := s.comments.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// Thread returns the discussion under a post as the viewer may see it.
+func (s *CommentService) Thread(ctx context.Context, postId uuid.UUID, viewerId uuid.NullUUID) ([]*comments.Node, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.Thread")
+	defer span.End()
+
+	visible, err := s.comments.FindVisible(ctx, postId, viewerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments.Thread(visible), nil
+}
+
+func (s *CommentService) Queue(ctx context.Context, status comments.Status, page, pageSize int) (QueuePage, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.Queue")
+	defer span.End()
+
+	if !isStatus(status) {
+		return QueuePage{}, ErrInvalidStatus
+	}
+
+	items, total, err := s.comments.FindByStatus(ctx, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return QueuePage{}, err
+	}
+
+	return QueuePage{Items: items, Total: total}, nil
+}
+
+// Moderate moves the comments to the given status and reports how many were
+// changed. Nothing is moved back to pending: the queue is for comments no one
+// has looked at yet.
+//
+// Approving and marking as spam also teach the spam classifier. Rejection
+// does not: a comment can be off topic or rude without being spam.
+func (s *CommentService) Moderate(ctx context.Context, moderatorId uuid.UUID, ids []uuid.UUID, status comments.Status) (int64, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.Moderate")
+	defer span.End()
+
+	if status == comments.StatusPending || !isStatus(status) {
+		return 0, ErrInvalidStatus
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	moved, err := s.comments.SetStatus(ctx, ids, status, moderatorId)
+	if err != nil {
+		return 0, err
+	}
+
+	if status == comments.StatusApproved || status == comments.StatusSpam {
+		s.train(ctx, ids, status == comments.StatusSpam)
+	}
+
+	return moved, nil
+}
+
+// train is best effort. The decision is already stored, and a classifier that
+// missed one lesson is still a working classifier.
+func (s *CommentService) train(ctx context.Context, ids []uuid.UUID, isSpam bool) {
+	for _, id := range ids {
+		comment, err := s.comments.FindById(ctx, id)
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not load a moderated comment for spam training", "error", err, "commentId", id)
+			continue
+		}
+
+		if err := s.classifier.Train(ctx, comment.Id, comment.Body, isSpam); err != nil {
+			slog.ErrorContext(ctx, "Could not train the spam classifier", "error", err, "commentId", id)
+		}
+	}
+}
+
+func isStatus(status comments.Status) bool {
+	for _, known := range comments.Statuses {
+		if status == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,174 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"server/internal/domain/comments"
+
+	"github.com/google/uuid"
+)
+
+type stubComments struct {
+	byId    map[uuid.UUID]comments.Comment
+	created []comments.Comment
+	moved   []uuid.UUID
+}
+
+func (s *stubComments) Create(_ context.Context, comment comments.Comment) error {
+	s.created = append(s.created, comment)
+	return nil
+}
+
+func (s *stubComments) FindById(_ context.Context, id uuid.UUID) (*comments.Comment, error) {
+	comment, ok := s.byId[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &comment, nil
+}
+
+func (s *stubComments) FindVisible(context.Context, uuid.UUID, uuid.NullUUID) ([]comments.Comment, error) {
+	return nil, nil
+}
+
+func (s *stubComments) FindByStatus(context.Context, comments.Status, int, int) ([]comments.QueueItem, int, error) {
+	return nil, 0, nil
+}
+
+func (s *stubComments) SetStatus(_ context.Context, ids []uuid.UUID, _ comments.Status, _ uuid.UUID) (int64, error) {
+	s.moved = append(s.moved, ids...)
+	return int64(len(ids)), nil
+}
+
+type lesson struct {
+	id     uuid.UUID
+	isSpam bool
+}
+
+type stubClassifier struct {
+	lessons []lesson
+}
+
+func (s *stubClassifier) Train(_ context.Context, documentId uuid.UUID, _ string, isSpam bool) error {
+	s.lessons = append(s.lessons, lesson{documentId, isSpam})
+	return nil
+}
+
+// Readers' comments wait for a moderator; a moderator's own go straight up.
+func TestPost_HoldsCommentsUnlessTrusted(t *testing.T) {
+	store := &stubComments{}
+	service := NewCommentService(store, &stubClassifier{})
+	postId := uuid.New()
+
+	if _, err := service.Post(context.Background(), NewComment{PostId: postId, UserId: uuid.New(), Body: "  Страхотна статия  "}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if _, err := service.Post(context.Background(), NewComment{PostId: postId, UserId: uuid.New(), Body: "Благодаря", Trusted: true}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	if got := store.created[0]; got.Status != comments.StatusPending || got.Body != "Страхотна статия" {
+		t.Errorf("reader comment = %q %q, want a trimmed pending comment", got.Status, got.Body)
+	}
+	if got := store.created[1].Status; got != comments.StatusApproved {
+		t.Errorf("trusted comment status = %q, want approved", got)
+	}
+}
+
+// A reply may only answer an approved comment on the same post. Anything else
+// would either hang under a comment no one can see or leak into another
+// post's discussion.
+func TestPost_RefusesRepliesToUnseenComments(t *testing.T) {
+	postId := uuid.New()
+	pending, elsewhere := uuid.New(), uuid.New()
+	store := &stubComments{byId: map[uuid.UUID]comments.Comment{
+		pending:   {Id: pending, PostId: postId, Status: comments.StatusPending},
+		elsewhere: {Id: elsewhere, PostId: uuid.New(), Status: comments.StatusApproved},
+	}}
+	service := NewCommentService(store, &stubClassifier{})
+
+	for _, parent := range []uuid.UUID{pending, elsewhere, uuid.New()} {
+		_, err := service.Post(context.Background(), NewComment{
+			PostId:   postId,
+			UserId:   uuid.New(),
+			ParentId: uuid.NullUUID{UUID: parent, Valid: true},
+			Body:     "Отговор",
+		})
+		if !errors.Is(err, ErrParentNotFound) {
+			t.Errorf("Post() error = %v, want ErrParentNotFound", err)
+		}
+	}
+
+	if len(store.created) != 0 {
+		t.Errorf("created %d comments, want none", len(store.created))
+	}
+}
+
+// Moderation decides; it never puts a comment back in the queue.
+func TestModerate_RefusesPendingAndUnknownStatuses(t *testing.T) {
+	store := &stubComments{}
+	service := NewCommentService(store, &stubClassifier{})
+
+	for _, status := range []comments.Status{comments.StatusPending, "published"} {
+		if _, err := service.Moderate(context.Background(), uuid.New(), []uuid.UUID{uuid.New()}, status); !errors.Is(err, ErrInvalidStatus) {
+			t.Errorf("Moderate(%q) error = %v, want ErrInvalidStatus", status, err)
+		}
+	}
+
+	if len(store.moved) != 0 {
+		t.Errorf("moved %d comments, want none", len(store.moved))
+	}
+}
+
+// A comment the spam check flagged goes to the spam tab, unless a moderator
+// wrote it: they would only be reviewing their own words.
+func TestPost_HoldsSuspiciousCommentsAsSpam(t *testing.T) {
+	store := &stubComments{}
+	service := NewCommentService(store, &stubClassifier{})
+	postId := uuid.New()
+
+	for _, trusted := range []bool{false, true} {
+		if _, err := service.Post(context.Background(), NewComment{PostId: postId, UserId: uuid.New(), Body: "Купете сега", Trusted: trusted, Suspicious: true}); err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+	}
+
+	if got := store.created[0].Status; got != comments.StatusSpam {
+		t.Errorf("suspicious comment status = %q, want spam", got)
+	}
+	if got := store.created[1].Status; got != comments.StatusApproved {
+		t.Errorf("suspicious trusted comment status = %q, want approved", got)
+	}
+}
+
+// Approving and marking as spam are the labels the classifier learns from.
+// Rejecting says nothing about spam, so it teaches nothing.
+func TestModerate_TrainsClassifierOnSpamAndApproval(t *testing.T) {
+	approved, spam, rejected := uuid.New(), uuid.New(), uuid.New()
+	store := &stubComments{byId: map[uuid.UUID]comments.Comment{
+		approved: {Id: approved, Body: "Полезна статия"},
+		spam:     {Id: spam, Body: "Евтини часовници"},
+		rejected: {Id: rejected, Body: "Не по темата"},
+	}}
+	classifier := &stubClassifier{}
+	service := NewCommentService(store, classifier)
+
+	for id, status := range map[uuid.UUID]comments.Status{approved: comments.StatusApproved, spam: comments.StatusSpam, rejected: comments.StatusDeleted} {
+		if _, err := service.Moderate(context.Background(), uuid.New(), []uuid.UUID{id}, status); err != nil {
+			t.Fatalf("Moderate(%q) error = %v", status, err)
+		}
+	}
+
+	want := map[uuid.UUID]bool{approved: false, spam: true}
+	if len(classifier.lessons) != len(want) {
+		t.Fatalf("trained %d times, want %d", len(classifier.lessons), len(want))
+	}
+	for _, got := range classifier.lessons {
+		if isSpam, ok := want[got.id]; !ok || isSpam != got.isSpam {
+			t.Errorf("trained %v as spam=%v, want %v", got.id, got.isSpam, want[got.id])
+		}
+	}
+}
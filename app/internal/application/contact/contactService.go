@@ -0,0 +1,351 @@
+package contact
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"server/internal/domain/contact"
+	"server/internal/infrastructure/tracing"
+	"server/util/ctxutils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound        = errors.New("contact message not found")
+	ErrEmptyMessage    = errors.New("contact message is empty")
+	ErrEmptyReply      = errors.New("reply is empty")
+	ErrInvalidStatus   = errors.New("unknown inbox status")
+	ErrInvalidCategory = errors.New("unknown message category")
+)
+
+const (
+	// dueSoonWindow is how close to its due date a privacy request is flagged.
+	dueSoonWindow = 7 * 24 * time.Hour
+
+	// closedRetention keeps a handled message long enough to answer a follow
+	// up about it, or show how a privacy request was dealt with.
+	closedRetention = 365 * 24 * time.Hour
+
+	// spamRetention leaves time to find a real message the spam check caught.
+	spamRetention = 30 * 24 * time.Hour
+)
+
+type messageRepository interface {
+	Create(ctx context.Context, message contact.Message) error
+	FindById(ctx context.Context, id uuid.UUID) (*contact.Message, error)
+	FindByStatus(ctx context.Context, status contact.Status, category contact.Category, limit, offset int) ([]contact.Message, int, error)
+	Summary(ctx context.Context, now, soon time.Time) (contact.Summary, error)
+	SetRead(ctx context.Context, id uuid.UUID, read bool) (bool, error)
+	SetCategory(ctx context.Context, id uuid.UUID, category contact.Category, dueAt sql.NullTime) (bool, error)
+	SetStatus(ctx context.Context, id uuid.UUID, status contact.Status) (bool, error)
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+	DeleteExpired(ctx context.Context, closedCutoff, spamCutoff time.Time) (int64, error)
+	AddReply(ctx context.Context, reply contact.Reply) error
+	FindReplies(ctx context.Context, messageId uuid.UUID) ([]contact.Reply, error)
+}
+
+type replyMailer interface {
+	SendContactReply(ctx context.Context, toEmail, name, subject, reply, original string) error
+}
+
+// classifier learns from what is moved in and out of the spam folder.
+type classifier interface {
+	Train(ctx context.Context, documentId uuid.UUID, text string, isSpam bool) error
+}
+
+// NewMessage is a message as the contact form sends it.
+type NewMessage struct {
+	Name     string
+	Email    string
+	Category contact.Category
+	Subject  string
+	Body     string
+
+	// Suspicious messages go straight to the spam folder, where they can
+	// still be found and moved back.
+	Suspicious bool
+}
+
+// InboxPage is one page of the inbox and the total in that folder.
+type InboxPage struct {
+	Messages []contact.Message
+	Total    int
+}
+
+// ContactService keeps what readers send through the contact form and lets
+// the team answer it by email.
+type ContactService struct {
+	messages   messageRepository
+	mailer     replyMailer
+	classifier classifier
+	now        func() time.Time
+}
+
+func NewContactService(messages messageRepository, mailer replyMailer, classifier classifier) *ContactService {
+	return &ContactService{messages: messages, mailer: mailer, classifier: classifier, now: time.Now}
+}
+
+// Submit stores a message from the contact form. A privacy request starts the
+// clock on its answer from here.
+func (s *ContactService) Submit(ctx context.Context, input NewMessage) (*contact.Message, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.Submit")
+	defer span.End()
+
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, ErrEmptyMessage
+	}
+	if !isCategory(input.Category) {
+		return nil, ErrInvalidCategory
+	}
+
+	client := ctxutils.ClientFromContext(ctx)
+	now := s.now().UTC()
+
+	message := contact.Message{
+		Id:        uuid.New(),
+		Name:      strings.TrimSpace(input.Name),
+		Email:     strings.ToLower(strings.TrimSpace(input.Email)),
+		Category:  input.Category,
+		Subject:   strings.TrimSpace(input.Subject),
+		Body:      body,
+		Status:    contact.StatusOpen,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		CreatedAt: now,
+		DueAt:     contact.DueDate(input.Category, now),
+	}
+	if input.Suspicious {
+		message.Status = contact.StatusSpam
+	}
+
+	if err := s.messages.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// Inbox lists a folder, narrowed to one category unless category is empty.
+func (s *ContactService) Inbox(ctx context.Context, status contact.Status, category contact.Category, page, pageSize int) (InboxPage, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.Inbox")
+	defer span.End()
+
+	if !isStatus(status) {
+		return InboxPage{}, ErrInvalidStatus
+	}
+	if category != "" && !isCategory(category) {
+		return InboxPage{}, ErrInvalidCategory
+	}
+
+	messages, total, err := s.messages.FindByStatus(ctx, status, category, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return InboxPage{}, err
+	}
+
+	return InboxPage{Messages: messages, Total: total}, nil
+}
+
+func (s *ContactService) Summary(ctx context.Context) (contact.Summary, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.Summary")
+	defer span.End()
+
+	now := s.now()
+
+	return s.messages.Summary(ctx, now, now.Add(dueSoonWindow))
+}
+
+// Open returns a message with its replies and marks it read.
+func (s *ContactService) Open(ctx context.Context, id uuid.UUID) (*contact.Message, []contact.Reply, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.Open")
+	defer span.End()
+
+	message, err := s.find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !message.ReadAt.Valid {
+		if _, err := s.messages.SetRead(ctx, id, true); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	replies, err := s.messages.FindReplies(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return message, replies, nil
+}
+
+// MarkUnread puts the message back in bold, for whoever should look at it
+// next.
+func (s *ContactService) MarkUnread(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "ContactService.MarkUnread")
+	defer span.End()
+
+	return found(s.messages.SetRead(ctx, id, false))
+}
+
+// Categorize files the message under another category. Filing it as a
+// privacy request gives it a due date counted from when it was sent, not from
+// when someone noticed what it was.
+func (s *ContactService) Categorize(ctx context.Context, id uuid.UUID, category contact.Category) error {
+	ctx, span := tracing.Start(ctx, "ContactService.Categorize")
+	defer span.End()
+
+	if !isCategory(category) {
+		return ErrInvalidCategory
+	}
+
+	message, err := s.find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return found(s.messages.SetCategory(ctx, id, category, contact.DueDate(category, message.CreatedAt)))
+}
+
+// SetStatus moves the message to another folder. Moving it into or out of
+// spam also teaches the spam classifier.
+func (s *ContactService) SetStatus(ctx context.Context, id uuid.UUID, status contact.Status) error {
+	ctx, span := tracing.Start(ctx, "ContactService.SetStatus")
+	defer span.End()
+
+	if !isStatus(status) {
+		return ErrInvalidStatus
+	}
+
+	message, err := s.find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := found(s.messages.SetStatus(ctx, id, status)); err != nil {
+		return err
+	}
+
+	switch {
+	case status == contact.StatusSpam && message.Status != contact.StatusSpam:
+		s.train(ctx, message, true)
+	case status != contact.StatusSpam && message.Status == contact.StatusSpam:
+		s.train(ctx, message, false)
+	}
+
+	return nil
+}
+
+// Reply emails an answer to the sender and keeps it with the message. The
+// email is queued first, so a reply on record was always sent.
+func (s *ContactService) Reply(ctx context.Context, id uuid.UUID, authorId uuid.UUID, body string) (*contact.Reply, error) {
+	ctx, span := tracing.Start(ctx, "ContactService.Reply")
+	defer span.End()
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyReply
+	}
+
+	message, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mailer.SendContactReply(ctx, message.Email, message.Name, message.Subject, body, message.Body); err != nil {
+		return nil, err
+	}
+
+	reply := contact.Reply{
+		Id:        uuid.New(),
+		MessageId: id,
+		AuthorId:  uuid.NullUUID{UUID: authorId, Valid: true},
+		Body:      body,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.messages.AddReply(ctx, reply); err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// Delete erases the message and its replies, for an erasure request or a
+// message that should never have been kept.
+func (s *ContactService) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "ContactService.Delete")
+	defer span.End()
+
+	return found(s.messages.Delete(ctx, id))
+}
+
+// PurgeExpired deletes messages past their retention. Open messages are never
+// purged, however old.
+func (s *ContactService) PurgeExpired(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "ContactService.PurgeExpired")
+	defer span.End()
+
+	now := s.now()
+	deleted, err := s.messages.DeleteExpired(ctx, now.Add(-closedRetention), now.Add(-spamRetention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Purged contact messages", "count", deleted)
+	}
+	return nil
+}
+
+func (s *ContactService) find(ctx context.Context, id uuid.UUID) (*contact.Message, error) {
+	message, err := s.messages.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return message, err
+}
+
+// train is best effort, as for comments: the move is already stored.
+func (s *ContactService) train(ctx context.Context, message *contact.Message, isSpam bool) {
+	if err := s.classifier.Train(ctx, message.Id, message.Subject+"\n"+message.Body, isSpam); err != nil {
+		slog.ErrorContext(ctx, "Could not train the spam classifier", "error", err, "messageId", message.Id)
+	}
+}
+
+// found turns an update that matched no row into ErrNotFound.
+func found(changed bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func isStatus(status contact.Status) bool {
+	for _, known := range contact.Statuses {
+		if status == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isCategory(category contact.Category) bool {
+	for _, known := range contact.Categories {
+		if category == known {
+			return true
+		}
+	}
+
+	return false
+}
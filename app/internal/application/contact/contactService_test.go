@@ -0,0 +1,246 @@
+package contact
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/domain/contact"
+
+	"github.com/google/uuid"
+)
+
+type stubMessages struct {
+	byId    map[uuid.UUID]*contact.Message
+	replies []contact.Reply
+}
+
+func newStubMessages() *stubMessages {
+	return &stubMessages{byId: map[uuid.UUID]*contact.Message{}}
+}
+
+func (s *stubMessages) Create(_ context.Context, message contact.Message) error {
+	s.byId[message.Id] = &message
+	return nil
+}
+
+func (s *stubMessages) FindById(_ context.Context, id uuid.UUID) (*contact.Message, error) {
+	if message, ok := s.byId[id]; ok {
+		copied := *message
+		return &copied, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubMessages) FindByStatus(context.Context, contact.Status, contact.Category, int, int) ([]contact.Message, int, error) {
+	return nil, 0, nil
+}
+
+func (s *stubMessages) Summary(context.Context, time.Time, time.Time) (contact.Summary, error) {
+	return contact.Summary{}, nil
+}
+
+func (s *stubMessages) SetRead(_ context.Context, id uuid.UUID, read bool) (bool, error) {
+	message, ok := s.byId[id]
+	if ok {
+		message.ReadAt = sql.NullTime{Time: time.Now(), Valid: read}
+	}
+	return ok, nil
+}
+
+func (s *stubMessages) SetCategory(_ context.Context, id uuid.UUID, category contact.Category, dueAt sql.NullTime) (bool, error) {
+	message, ok := s.byId[id]
+	if ok {
+		message.Category, message.DueAt = category, dueAt
+	}
+	return ok, nil
+}
+
+func (s *stubMessages) SetStatus(_ context.Context, id uuid.UUID, status contact.Status) (bool, error) {
+	message, ok := s.byId[id]
+	if ok {
+		message.Status = status
+	}
+	return ok, nil
+}
+
+func (s *stubMessages) Delete(_ context.Context, id uuid.UUID) (bool, error) {
+	_, ok := s.byId[id]
+	delete(s.byId, id)
+	return ok, nil
+}
+
+func (s *stubMessages) DeleteExpired(context.Context, time.Time, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *stubMessages) AddReply(_ context.Context, reply contact.Reply) error {
+	s.replies = append(s.replies, reply)
+	return nil
+}
+
+func (s *stubMessages) FindReplies(context.Context, uuid.UUID) ([]contact.Reply, error) {
+	return s.replies, nil
+}
+
+type stubMailer struct {
+	sent []string
+	err  error
+}
+
+func (m *stubMailer) SendContactReply(_ context.Context, toEmail, _, _, _, _ string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, toEmail)
+	return nil
+}
+
+type trained struct {
+	id     uuid.UUID
+	isSpam bool
+}
+
+type stubClassifier struct {
+	lessons []trained
+}
+
+func (c *stubClassifier) Train(_ context.Context, documentId uuid.UUID, _ string, isSpam bool) error {
+	c.lessons = append(c.lessons, trained{documentId, isSpam})
+	return nil
+}
+
+func newTestService() (*ContactService, *stubMessages, *stubMailer, *stubClassifier) {
+	messages, mailer, classifier := newStubMessages(), &stubMailer{}, &stubClassifier{}
+	service := NewContactService(messages, mailer, classifier)
+	service.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	return service, messages, mailer, classifier
+}
+
+// A privacy request must carry its 30 day deadline from the moment it arrives;
+// other questions have none.
+func TestSubmit_PrivacyRequestGetsDeadline(t *testing.T) {
+	service, _, _, _ := newTestService()
+	ctx := context.Background()
+
+	privacy, err := service.Submit(ctx, NewMessage{Name: "Мария", Email: " Reader@Example.com ", Category: contact.CategoryPrivacy, Subject: "Изтриване", Body: "Изтрийте данните ми."})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if want := service.now().UTC().Add(contact.PrivacyResponseTime); !privacy.DueAt.Valid || !privacy.DueAt.Time.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", privacy.DueAt, want)
+	}
+	if privacy.Email != "reader@example.com" {
+		t.Errorf("Email = %q, want it trimmed and lower case", privacy.Email)
+	}
+
+	general, err := service.Submit(ctx, NewMessage{Name: "Иван", Email: "ivan@example.com", Category: contact.CategoryGeneral, Subject: "Въпрос", Body: "Здравейте"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if general.DueAt.Valid {
+		t.Errorf("general question has a deadline: %v", general.DueAt)
+	}
+}
+
+// What the spam check held goes to the spam folder, not the inbox, and an
+// unknown category is refused rather than stored.
+func TestSubmit_SuspiciousAndInvalid(t *testing.T) {
+	service, _, _, _ := newTestService()
+	ctx := context.Background()
+
+	held, err := service.Submit(ctx, NewMessage{Name: "x", Email: "x@example.com", Category: contact.CategoryGeneral, Subject: "x", Body: "buy now", Suspicious: true})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if held.Status != contact.StatusSpam {
+		t.Errorf("Status = %q, want spam", held.Status)
+	}
+
+	if _, err := service.Submit(ctx, NewMessage{Name: "x", Email: "x@example.com", Category: "billing", Subject: "x", Body: "x"}); !errors.Is(err, ErrInvalidCategory) {
+		t.Errorf("Submit() with unknown category error = %v, want ErrInvalidCategory", err)
+	}
+	if _, err := service.Submit(ctx, NewMessage{Name: "x", Email: "x@example.com", Category: contact.CategoryGeneral, Subject: "x", Body: "  "}); !errors.Is(err, ErrEmptyMessage) {
+		t.Errorf("Submit() with blank body error = %v, want ErrEmptyMessage", err)
+	}
+}
+
+// Refiling a general question as a privacy request counts the deadline from
+// when it was sent: the reader's month did not start when we noticed.
+func TestCategorize_DeadlineCountsFromReceipt(t *testing.T) {
+	service, messages, _, _ := newTestService()
+	ctx := context.Background()
+
+	message, err := service.Submit(ctx, NewMessage{Name: "Мария", Email: "m@example.com", Category: contact.CategoryGeneral, Subject: "Данни", Body: "Какво пазите за мен?"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	service.now = func() time.Time { return message.CreatedAt.Add(10 * 24 * time.Hour) }
+	if err := service.Categorize(ctx, message.Id, contact.CategoryPrivacy); err != nil {
+		t.Fatalf("Categorize() error = %v", err)
+	}
+	if want := message.CreatedAt.Add(contact.PrivacyResponseTime); !messages.byId[message.Id].DueAt.Time.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", messages.byId[message.Id].DueAt.Time, want)
+	}
+
+	if err := service.Categorize(ctx, message.Id, contact.CategoryContent); err != nil {
+		t.Fatalf("Categorize() error = %v", err)
+	}
+	if messages.byId[message.Id].DueAt.Valid {
+		t.Error("deadline kept after the message stopped being a privacy request")
+	}
+}
+
+// Moving a message into spam and back teaches the classifier both ways;
+// closing an ordinary message teaches it nothing.
+func TestSetStatus_TrainsOnSpamMoves(t *testing.T) {
+	service, _, _, classifier := newTestService()
+	ctx := context.Background()
+
+	message, err := service.Submit(ctx, NewMessage{Name: "x", Email: "x@example.com", Category: contact.CategoryGeneral, Subject: "x", Body: "x"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	for _, status := range []contact.Status{contact.StatusClosed, contact.StatusSpam, contact.StatusOpen} {
+		if err := service.SetStatus(ctx, message.Id, status); err != nil {
+			t.Fatalf("SetStatus(%q) error = %v", status, err)
+		}
+	}
+
+	want := []trained{{message.Id, true}, {message.Id, false}}
+	if len(classifier.lessons) != len(want) || classifier.lessons[0] != want[0] || classifier.lessons[1] != want[1] {
+		t.Errorf("lessons = %+v, want %+v", classifier.lessons, want)
+	}
+}
+
+// A reply that could not be queued must not be recorded as sent.
+func TestReply_NotRecordedWhenMailFails(t *testing.T) {
+	service, messages, mailer, _ := newTestService()
+	ctx := context.Background()
+
+	message, err := service.Submit(ctx, NewMessage{Name: "x", Email: "x@example.com", Category: contact.CategoryGeneral, Subject: "x", Body: "x"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	mailer.err = errors.New("queue unavailable")
+	if _, err := service.Reply(ctx, message.Id, uuid.New(), "Благодарим!"); err == nil {
+		t.Fatal("Reply() succeeded with the mail queue down")
+	}
+	if len(messages.replies) != 0 {
+		t.Errorf("replies stored = %d, want none", len(messages.replies))
+	}
+
+	mailer.err = nil
+	if _, err := service.Reply(ctx, message.Id, uuid.New(), "Благодарим!"); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if len(messages.replies) != 1 || len(mailer.sent) != 1 {
+		t.Errorf("replies = %d, sent = %d, want one of each", len(messages.replies), len(mailer.sent))
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package health
+
+import "syscall"
+
+// diskSpace reports the free and total bytes of the filesystem holding path,
+// counting only what an unprivileged process may use.
+func diskSpace(path string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	return stat.Bavail * uint64(stat.Bsize), stat.Blocks * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,294 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"server/internal/domain/jobs"
+	"server/internal/infrastructure/tracing"
+)
+
+const (
+	// checkTimeout bounds each check, so one hung dependency cannot hold up
+	// the whole report.
+	checkTimeout = 3 * time.Second
+
+	// slowPing is when database latency is worth a warning. A healthy local
+	// round trip is a millisecond or two.
+	slowPing = 250 * time.Millisecond
+
+	// overdueAfter is how late a pending job may be before the backlog is
+	// reported: the worker polls every few seconds, so minutes means it is
+	// stuck, stopped or swamped.
+	overdueAfter = 5 * time.Minute
+
+	// Free disk space below these fractions warns, then fails. Uploads spill
+	// to temporary files and the logs grow, so a full disk breaks both.
+	diskWarnFraction = 0.10
+	diskFailFraction = 0.02
+)
+
+var (
+	ErrStarting = errors.New("starting")
+	ErrDraining = errors.New("draining")
+)
+
+// Status is the outcome of a check, and of the report as a whole: the worst
+// of its checks.
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarn    Status = "warn"
+	StatusFail    Status = "fail"
+	StatusSkipped Status = "skipped"
+)
+
+// severity orders statuses for picking the worst.
+func (s Status) severity() int {
+	switch s {
+	case StatusFail:
+		return 2
+	case StatusWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type Check struct {
+	Name       string  `json:"name"`
+	Status     Status  `json:"status"`
+	Detail     string  `json:"detail,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+type Report struct {
+	Status    Status    `json:"status"`
+	Phase     string    `json:"phase"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Checks    []Check   `json:"checks"`
+}
+
+type database interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (int, bool, error)
+}
+
+type jobBacklog interface {
+	Counts(ctx context.Context) (map[jobs.Status]int, error)
+	CountOverdue(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// Options describe the dependencies that are configured rather than queried.
+type Options struct {
+	// SMTPAddr is the mail server's host:port, empty when mail does not go
+	// out over SMTP.
+	SMTPAddr string
+	// Storage names the upload backend, empty when it could not be set up.
+	Storage string
+	// DiskPath is a path on the filesystem whose free space is checked.
+	DiskPath string
+}
+
+type HealthService struct {
+	state *State
+	db    database
+	jobs  jobBacklog
+	opts  Options
+
+	dial      func(ctx context.Context, network, address string) (net.Conn, error)
+	diskSpace func(path string) (free, total uint64, err error)
+	now       func() time.Time
+}
+
+func NewHealthService(state *State, db database, jobs jobBacklog, opts Options) *HealthService {
+	return &HealthService{
+		state:     state,
+		db:        db,
+		jobs:      jobs,
+		opts:      opts,
+		dial:      (&net.Dialer{}).DialContext,
+		diskSpace: diskSpace,
+		now:       time.Now,
+	}
+}
+
+// Ready reports whether the process should be sent traffic: it has finished
+// starting, is not draining and can reach the database.
+func (s *HealthService) Ready(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "HealthService.Ready")
+	defer span.End()
+
+	switch s.state.Phase() {
+	case PhaseStarting:
+		return ErrStarting
+	case PhaseDraining:
+		return ErrDraining
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	return s.db.Ping(ctx)
+}
+
+// Details runs every check at once and reports them in a fixed order.
+func (s *HealthService) Details(ctx context.Context) Report {
+	ctx, span := tracing.Start(ctx, "HealthService.Details")
+	defer span.End()
+
+	checks := []struct {
+		name string
+		run  func(context.Context) (Status, string)
+	}{
+		{"database", s.checkDatabase},
+		{"migrations", s.checkMigrations},
+		{"smtp", s.checkSMTP},
+		{"storage", s.checkStorage},
+		{"jobs", s.checkJobs},
+		{"disk", s.checkDisk},
+	}
+
+	report := Report{
+		Status:    StatusOK,
+		Phase:     s.state.Phase().String(),
+		CheckedAt: s.now().UTC(),
+		Checks:    make([]Check, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Go(func() {
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			started := time.Now()
+			status, detail := check.run(checkCtx)
+			report.Checks[i] = Check{
+				Name:       check.name,
+				Status:     status,
+				Detail:     detail,
+				DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status.severity() > report.Status.severity() {
+			report.Status = check.Status
+		}
+	}
+
+	return report
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) (Status, string) {
+	started := time.Now()
+	if err := s.db.Ping(ctx); err != nil {
+		return StatusFail, err.Error()
+	}
+
+	latency := time.Since(started)
+	if latency > slowPing {
+		return StatusWarn, fmt.Sprintf("slow ping: %s", latency.Round(time.Millisecond))
+	}
+
+	return StatusOK, fmt.Sprintf("ping %s", latency.Round(time.Microsecond))
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) (Status, string) {
+	version, dirty, err := s.db.MigrationVersion(ctx)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	if dirty {
+		return StatusFail, fmt.Sprintf("version %d is dirty: a migration failed partway", version)
+	}
+
+	return StatusOK, fmt.Sprintf("version %d", version)
+}
+
+// checkSMTP only opens a connection. Saying hello would be more thorough, but
+// a probe run every few seconds should not log in to the mail server.
+func (s *HealthService) checkSMTP(ctx context.Context) (Status, string) {
+	if s.opts.SMTPAddr == "" {
+		return StatusSkipped, "mail is not sent over SMTP"
+	}
+
+	started := time.Now()
+	conn, err := s.dial(ctx, "tcp", s.opts.SMTPAddr)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	conn.Close()
+
+	return StatusOK, fmt.Sprintf("%s reachable in %s", s.opts.SMTPAddr, time.Since(started).Round(time.Millisecond))
+}
+
+func (s *HealthService) checkStorage(context.Context) (Status, string) {
+	if s.opts.Storage == "" {
+		return StatusWarn, "not available; image and file uploads are off"
+	}
+
+	return StatusOK, s.opts.Storage
+}
+
+func (s *HealthService) checkJobs(ctx context.Context) (Status, string) {
+	counts, err := s.jobs.Counts(ctx)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	overdue, err := s.jobs.CountOverdue(ctx, s.now().Add(-overdueAfter))
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+
+	detail := fmt.Sprintf("%d pending, %d running, %d dead", counts[jobs.StatusPending], counts[jobs.StatusRunning], counts[jobs.StatusDead])
+	if overdue > 0 {
+		return StatusWarn, fmt.Sprintf("%d overdue by more than %s; %s", overdue, overdueAfter, detail)
+	}
+
+	return StatusOK, detail
+}
+
+func (s *HealthService) checkDisk(context.Context) (Status, string) {
+	free, total, err := s.diskSpace(s.opts.DiskPath)
+	if err != nil {
+		return StatusSkipped, err.Error()
+	}
+	if total == 0 {
+		return StatusSkipped, "filesystem reports no size"
+	}
+
+	fraction := float64(free) / float64(total)
+	detail := fmt.Sprintf("%s free of %s (%.1f%%)", formatBytes(free), formatBytes(total), fraction*100)
+	switch {
+	case fraction < diskFailFraction:
+		return StatusFail, detail
+	case fraction < diskWarnFraction:
+		return StatusWarn, detail
+	}
+
+	return StatusOK, detail
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value, suffix := float64(n)/unit, 0
+	for value >= unit && suffix < 4 {
+		value /= unit
+		suffix++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[suffix])
+}
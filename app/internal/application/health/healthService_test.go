@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"server/internal/domain/jobs"
+)
+
+type stubDatabase struct {
+	pingErr error
+	version int
+	dirty   bool
+}
+
+func (s *stubDatabase) Ping(context.Context) error { return s.pingErr }
+func (s *stubDatabase) MigrationVersion(context.Context) (int, bool, error) {
+	return s.version, s.dirty, nil
+}
+
+type stubJobs struct {
+	counts  map[jobs.Status]int
+	overdue int
+}
+
+func (s *stubJobs) Counts(context.Context) (map[jobs.Status]int, error) { return s.counts, nil }
+func (s *stubJobs) CountOverdue(context.Context, time.Time) (int, error) {
+	return s.overdue, nil
+}
+
+func newTestService(state *State, db *stubDatabase, backlog *stubJobs, opts Options) *HealthService {
+	s := NewHealthService(state, db, backlog, opts)
+	s.diskSpace = func(string) (uint64, uint64, error) { return 50, 100, nil }
+	s.dial = func(context.Context, string, string) (net.Conn, error) {
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	return s
+}
+
+func findCheck(t *testing.T, report Report, name string) Check {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no %s check in the report", name)
+	return Check{}
+}
+
+// Load balancers must not send traffic before migrations have run or once
+// draining has begun, however healthy the database is.
+func TestReadyFollowsThePhase(t *testing.T) {
+	state := NewState()
+	s := newTestService(state, &stubDatabase{}, &stubJobs{}, Options{})
+
+	if err := s.Ready(context.Background()); !errors.Is(err, ErrStarting) {
+		t.Errorf("starting: got %v", err)
+	}
+
+	state.MarkReady()
+	if err := s.Ready(context.Background()); err != nil {
+		t.Errorf("ready: got %v", err)
+	}
+
+	state.MarkDraining()
+	state.MarkReady()
+	if err := s.Ready(context.Background()); !errors.Is(err, ErrDraining) {
+		t.Errorf("draining: got %v; MarkReady must not undo draining", err)
+	}
+}
+
+// A ready process that lost its database cannot serve pages, so readiness
+// fails with it.
+func TestReadyFailsWithoutTheDatabase(t *testing.T) {
+	state := NewState()
+	state.MarkReady()
+	s := newTestService(state, &stubDatabase{pingErr: errors.New("connection refused")}, &stubJobs{}, Options{})
+
+	if err := s.Ready(context.Background()); err == nil {
+		t.Error("ready with the database down")
+	}
+}
+
+// The report is as bad as its worst check, and a dirty migration is a
+// failure: the next deploy will refuse to migrate.
+func TestDetailsReportsTheWorstCheck(t *testing.T) {
+	s := newTestService(NewState(), &stubDatabase{version: 15, dirty: true}, &stubJobs{overdue: 3}, Options{Storage: "local"})
+
+	report := s.Details(context.Background())
+
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want fail", report.Status)
+	}
+	if got := findCheck(t, report, "migrations").Status; got != StatusFail {
+		t.Errorf("migrations = %s", got)
+	}
+	if got := findCheck(t, report, "jobs").Status; got != StatusWarn {
+		t.Errorf("jobs = %s, want a warning for overdue jobs", got)
+	}
+	if got := findCheck(t, report, "smtp").Status; got != StatusSkipped {
+		t.Errorf("smtp = %s, want skipped without an SMTP transport", got)
+	}
+	if report.Phase != "starting" {
+		t.Errorf("phase = %s", report.Phase)
+	}
+}
+
+// Free space thresholds decide between ok, warn and fail.
+func TestDiskCheckThresholds(t *testing.T) {
+	tests := []struct {
+		free uint64
+		want Status
+	}{
+		{50, StatusOK},
+		{5, StatusWarn},
+		{1, StatusFail},
+	}
+
+	for _, tt := range tests {
+		s := newTestService(NewState(), &stubDatabase{}, &stubJobs{}, Options{})
+		s.diskSpace = func(string) (uint64, uint64, error) { return tt.free, 100, nil }
+
+		if got, detail := s.checkDisk(context.Background()); got != tt.want {
+			t.Errorf("%d%% free: got %s (%s), want %s", tt.free, got, detail, tt.want)
+		}
+	}
+}
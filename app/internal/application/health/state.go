@@ -0,0 +1,52 @@
+package health
+
+import "sync/atomic"
+
+// Phase is where the process is in its life, as far as taking traffic goes.
+type Phase int32
+
+const (
+	// PhaseStarting lasts until migrations have run and the administrator
+	// exists. The server listens meanwhile so that liveness can be checked.
+	PhaseStarting Phase = iota
+	PhaseReady
+	// PhaseDraining begins on SIGTERM, before the server stops accepting
+	// connections, so load balancers move traffic elsewhere first.
+	PhaseDraining
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseReady:
+		return "ready"
+	case PhaseDraining:
+		return "draining"
+	default:
+		return "starting"
+	}
+}
+
+// State is the process's phase, set by main and read by the probes.
+type State struct {
+	phase atomic.Int32
+}
+
+// NewState starts in PhaseStarting.
+func NewState() *State {
+	return &State{}
+}
+
+func (s *State) Phase() Phase { return Phase(s.phase.Load()) }
+
+// MarkReady ends startup. It does nothing once draining has begun.
+func (s *State) MarkReady() {
+	s.phase.CompareAndSwap(int32(PhaseStarting), int32(PhaseReady))
+}
+
+// MarkDraining is final.
+func (s *State) MarkDraining() {
+	s.phase.Store(int32(PhaseDraining))
+}
+
+// Starting reports whether startup is still under way.
+func (s *State) Starting() bool { return s.Phase() == PhaseStarting }
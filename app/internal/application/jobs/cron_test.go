@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func at(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Next must land on the first matching minute strictly after the given time,
+// including across month and year ends, so a job that just ran is not queued
+// for the minute it ran in.
+func TestCron_Next(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"@hourly", "2026-03-01 12:00", "2026-03-01 13:00"},
+		{"@hourly", "2026-03-01 12:59", "2026-03-01 13:00"},
+		{"17 3 * * *", "2026-03-01 03:17", "2026-03-02 03:17"},
+		{"*/15 * * * *", "2026-03-01 12:07", "2026-03-01 12:15"},
+		{"0 9-17/4 * * *", "2026-03-01 13:30", "2026-03-01 17:00"},
+		{"0 0 1 * *", "2026-12-15 08:00", "2027-01-01 00:00"},
+		// Sunday written as 7; 1 March 2026 is a Sunday.
+		{"30 6 * * 7", "2026-02-27 00:00", "2026-03-01 06:30"},
+		// With both day fields restricted either one matches, as in cron:
+		// the 10th, or any Monday (2 March).
+		{"0 0 10 * 1", "2026-03-01 00:00", "2026-03-02 00:00"},
+		{"0 12 29 2 *", "2026-03-01 00:00", "2028-02-29 12:00"},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) error = %v", tt.spec, err)
+		}
+		if got := cron.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("ParseCron(%q).Next(%s) = %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+// A date that never comes must not loop forever.
+func TestCron_NextImpossible(t *testing.T) {
+	cron, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+	if got := cron.Next(at("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("Next() = %v, want the zero time", got)
+	}
+}
+
+// Mistakes in a schedule are caught when it is registered, at startup.
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@yearly"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded", spec)
+		}
+	}
+}
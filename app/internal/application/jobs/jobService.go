@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"server/internal/domain/jobs"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// doneRetention is how long the record of a finished job is kept, to see
+	// what ran recently.
+	doneRetention = 7 * 24 * time.Hour
+
+	// deadRetention leaves time to notice a dead job and retry it.
+	deadRetention = 30 * 24 * time.Hour
+)
+
+var (
+	ErrNotFound      = errors.New("job not found")
+	ErrInvalidStatus = errors.New("unknown job status")
+	// ErrNotRetryable is a job that is running or done, or one whose unique
+	// key has been taken by a job queued since.
+	ErrNotRetryable = errors.New("job cannot be retried")
+)
+
+// Kind names a job type and ties it to the type of its arguments, so that
+// what is queued under a name is what its handler expects. The name is stored
+// with each job: renaming a kind strands the jobs queued under the old one.
+type Kind[T any] string
+
+// PurgeFinished drops the record of done and dead jobs past their retention.
+const PurgeFinished Kind[struct{}] = "jobs.purge_finished"
+
+type jobRepository interface {
+	Enqueue(ctx context.Context, job jobs.Job) (bool, error)
+	Retry(ctx context.Context, id uuid.UUID) (bool, error)
+	FindById(ctx context.Context, id uuid.UUID) (*jobs.Job, error)
+	Find(ctx context.Context, status jobs.Status, kind string, limit, offset int) ([]jobs.Job, int, error)
+	Counts(ctx context.Context) (map[jobs.Status]int, error)
+	Kinds(ctx context.Context) ([]string, error)
+	DeleteFinishedBefore(ctx context.Context, doneCutoff, deadCutoff time.Time) (int64, error)
+}
+
+// EnqueueOptions delay a job or keep it from being queued twice.
+type EnqueueOptions struct {
+	// RunAt is when the job is due; the zero time means now.
+	RunAt time.Time
+	// UniqueKey makes Enqueue a no-op while another pending or running job
+	// holds the same key.
+	UniqueKey string
+}
+
+// JobService queues background work and shows what became of it. The Worker
+// runs it.
+type JobService struct {
+	jobs jobRepository
+	now  func() time.Time
+}
+
+func NewJobService(jobs jobRepository) *JobService {
+	return &JobService{jobs: jobs, now: time.Now}
+}
+
+// Enqueue queues a job of the given kind. It reports false when the unique
+// key in opts is already held, in which case nothing was queued.
+func Enqueue[T any](ctx context.Context, s *JobService, kind Kind[T], args T, opts EnqueueOptions) (bool, error) {
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return false, err
+	}
+
+	return s.enqueue(ctx, string(kind), payload, opts)
+}
+
+func (s *JobService) enqueue(ctx context.Context, kind string, payload []byte, opts EnqueueOptions) (bool, error) {
+	now := s.now().UTC()
+
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = now
+	}
+
+	return s.jobs.Enqueue(ctx, jobs.Job{
+		Id:        uuid.New(),
+		Kind:      kind,
+		Payload:   payload,
+		UniqueKey: opts.UniqueKey,
+		RunAt:     runAt,
+		CreatedAt: now,
+	})
+}
+
+// JobPage is one page of jobs and the total matching the filter.
+type JobPage struct {
+	Jobs  []jobs.Job
+	Total int
+}
+
+// Jobs lists jobs, narrowed to a status and a kind unless they are empty.
+func (s *JobService) Jobs(ctx context.Context, status jobs.Status, kind string, page, pageSize int) (JobPage, error) {
+	ctx, span := tracing.Start(ctx, "JobService.Jobs")
+	defer span.End()
+
+	if status != "" && !slices.Contains(jobs.Statuses, status) {
+		return JobPage{}, ErrInvalidStatus
+	}
+
+	found, total, err := s.jobs.Find(ctx, status, kind, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return JobPage{}, err
+	}
+
+	return JobPage{Jobs: found, Total: total}, nil
+}
+
+func (s *JobService) Job(ctx context.Context, id uuid.UUID) (*jobs.Job, error) {
+	ctx, span := tracing.Start(ctx, "JobService.Job")
+	defer span.End()
+
+	job, err := s.jobs.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return job, err
+}
+
+func (s *JobService) Counts(ctx context.Context) (map[jobs.Status]int, error) {
+	ctx, span := tracing.Start(ctx, "JobService.Counts")
+	defer span.End()
+
+	return s.jobs.Counts(ctx)
+}
+
+func (s *JobService) Kinds(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "JobService.Kinds")
+	defer span.End()
+
+	return s.jobs.Kinds(ctx)
+}
+
+// Retry runs a dead job again with a fresh set of attempts, or a waiting one
+// now rather than after its backoff.
+func (s *JobService) Retry(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "JobService.Retry")
+	defer span.End()
+
+	retried, err := s.jobs.Retry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if retried {
+		return nil
+	}
+
+	if _, err := s.Job(ctx, id); err != nil {
+		return err
+	}
+	return ErrNotRetryable
+}
+
+// Purge is the handler for PurgeFinished.
+func (s *JobService) Purge(ctx context.Context, _ struct{}) error {
+	ctx, span := tracing.Start(ctx, "JobService.Purge")
+	defer span.End()
+
+	now := s.now()
+	_, err := s.jobs.DeleteFinishedBefore(ctx, now.Add(-doneRetention), now.Add(-deadRetention))
+
+	return err
+}
@@ -0,0 +1,353 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"server/internal/domain/jobs"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// pollInterval is how often an idle worker looks for due jobs.
+	pollInterval = 2 * time.Second
+
+	// scheduleInterval is how often recurring jobs are checked. Schedules have
+	// a resolution of one minute.
+	scheduleInterval = time.Minute
+
+	// defaultTimeout and defaultMaxAttempts apply to handlers registered
+	// without their own.
+	defaultTimeout     = 5 * time.Minute
+	defaultMaxAttempts = 10
+
+	// leaseMargin is added to a kind's timeout to make its lease, so a job is
+	// never claimed again while its first run may still finish.
+	leaseMargin = time.Minute
+
+	// The delay after a failed attempt doubles from firstRetryDelay up to
+	// maxRetryDelay.
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 6 * time.Hour
+
+	// recordTimeout bounds writing down how an attempt went. It does not
+	// derive from the job's context, which may be what ended the attempt.
+	recordTimeout = 10 * time.Second
+)
+
+// ErrPermanent marks a failure that retrying cannot fix, such as arguments
+// that do not decode. A handler wraps it to send the job straight to dead.
+var ErrPermanent = errors.New("permanent failure")
+
+type workerRepository interface {
+	Enqueue(ctx context.Context, job jobs.Job) (bool, error)
+	Claim(ctx context.Context, leases map[string]time.Duration, limit int) ([]jobs.Job, error)
+	Complete(ctx context.Context, id uuid.UUID, attempt int) error
+	Fail(ctx context.Context, id uuid.UUID, attempt int, lastError string, runAt time.Time) error
+	Kill(ctx context.Context, id uuid.UUID, attempt int, lastError string) error
+	Release(ctx context.Context, id uuid.UUID, attempt int) error
+}
+
+// HandlerOptions tune how a kind is run. Zero values take the defaults.
+type HandlerOptions struct {
+	// Timeout bounds one attempt.
+	Timeout time.Duration
+	// MaxAttempts is how many attempts a job gets before it is dead.
+	MaxAttempts int
+}
+
+type handler struct {
+	run         func(ctx context.Context, payload json.RawMessage) error
+	timeout     time.Duration
+	maxAttempts int
+}
+
+// recurring is a kind queued on a schedule, with fixed arguments.
+type recurring struct {
+	kind    string
+	cron    Cron
+	payload []byte
+}
+
+// Worker runs queued jobs, up to concurrency at a time, and queues recurring
+// ones as they fall due. Any number of workers, in any number of processes,
+// can share the table.
+type Worker struct {
+	jobs        workerRepository
+	concurrency int
+	handlers    map[string]handler
+	schedules   []recurring
+	now         func() time.Time
+
+	// finished hears from each job as it ends, so its slot can be refilled.
+	finished chan struct{}
+
+	// abort cancels running jobs when Shutdown runs out of time.
+	jobCtx context.Context
+	abort  context.CancelFunc
+
+	mu       sync.Mutex
+	stopping bool
+	stop     chan struct{}
+	running  sync.WaitGroup
+}
+
+func NewWorker(jobs workerRepository, concurrency int) *Worker {
+	concurrency = max(concurrency, 1)
+	jobCtx, abort := context.WithCancel(context.Background())
+
+	return &Worker{
+		jobs:        jobs,
+		concurrency: concurrency,
+		handlers:    map[string]handler{},
+		now:         time.Now,
+		finished:    make(chan struct{}, concurrency),
+		jobCtx:      jobCtx,
+		abort:       abort,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Register sets the handler for a kind. Jobs of kinds no handler is
+// registered for are left pending, for a worker that knows them. Register
+// before Run.
+func Register[T any](w *Worker, kind Kind[T], handle func(ctx context.Context, args T) error, opts HandlerOptions) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+
+	w.handlers[string(kind)] = handler{
+		run: func(ctx context.Context, payload json.RawMessage) error {
+			var args T
+			if err := json.Unmarshal(payload, &args); err != nil {
+				return fmt.Errorf("%w: decoding arguments: %v", ErrPermanent, err)
+			}
+			return handle(ctx, args)
+		},
+		timeout:     opts.Timeout,
+		maxAttempts: opts.MaxAttempts,
+	}
+}
+
+// Schedule queues a job of the given kind every time spec falls due. The
+// kind is its own unique key, so a run that is still waiting or retrying is
+// never joined by the next, and runs missed while the app was down are made
+// up by a single one. Schedule before Run.
+func Schedule[T any](w *Worker, spec string, kind Kind[T], args T) error {
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	w.schedules = append(w.schedules, recurring{kind: string(kind), cron: cron, payload: payload})
+	return nil
+}
+
+// Run claims and runs due jobs until ctx is cancelled or Shutdown is called.
+// Jobs still running when it returns carry on; Shutdown waits for them.
+func (w *Worker) Run(ctx context.Context) {
+	leases := w.leases()
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	scheduleTick := time.NewTicker(scheduleInterval)
+	defer scheduleTick.Stop()
+
+	w.enqueueScheduled(ctx)
+
+	active := 0
+	for {
+		if free := w.concurrency - active; free > 0 && ctx.Err() == nil {
+			claimed, err := w.jobs.Claim(ctx, leases, free)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to claim jobs", "error", err)
+			}
+			for _, job := range claimed {
+				if w.start(job) {
+					active++
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-w.finished:
+			active--
+		case <-scheduleTick.C:
+			w.enqueueScheduled(ctx)
+		case <-poll.C:
+		}
+	}
+}
+
+// leases gives each registered kind a lease of its own timeout. A job that
+// dies with its worker is then retried as soon as a run of its own kind could
+// have ended, and a quick scheduled job is not held up by a slow kind.
+func (w *Worker) leases() map[string]time.Duration {
+	leases := make(map[string]time.Duration, len(w.handlers))
+	for kind, h := range w.handlers {
+		leases[kind] = h.timeout + leaseMargin
+	}
+
+	return leases
+}
+
+// Shutdown stops the worker claiming jobs and waits for the running ones to
+// finish. If ctx ends first, their contexts are cancelled and they are handed
+// back to the queue without the attempt counting, and ctx's error is
+// returned.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.stopping {
+		w.stopping = true
+		close(w.stop)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.abort()
+		return nil
+	case <-ctx.Done():
+		w.abort()
+		// Cancelled jobs only need to record their release.
+		select {
+		case <-done:
+		case <-time.After(recordTimeout):
+		}
+		return ctx.Err()
+	}
+}
+
+// start runs a claimed job in its own goroutine, or hands it straight back if
+// the worker is shutting down.
+func (w *Worker) start(job jobs.Job) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopping {
+		w.record(job, func(ctx context.Context) error { return w.jobs.Release(ctx, job.Id, job.Attempts) })
+		return false
+	}
+
+	w.running.Add(1)
+	go func() {
+		defer func() {
+			w.running.Done()
+			w.finished <- struct{}{}
+		}()
+		w.execute(job)
+	}()
+
+	return true
+}
+
+func (w *Worker) execute(job jobs.Job) {
+	h := w.handlers[job.Kind]
+
+	ctx, cancel := context.WithTimeout(w.jobCtx, h.timeout)
+	err := runHandler(ctx, h, job.Payload)
+	cancel()
+
+	log := slog.With("jobId", job.Id, "kind", job.Kind, "attempt", job.Attempts)
+
+	switch {
+	case err == nil:
+		w.record(job, func(ctx context.Context) error { return w.jobs.Complete(ctx, job.Id, job.Attempts) })
+	case w.jobCtx.Err() != nil:
+		log.Warn("Job interrupted by shutdown, handing it back", "error", err)
+		w.record(job, func(ctx context.Context) error { return w.jobs.Release(ctx, job.Id, job.Attempts) })
+	case errors.Is(err, ErrPermanent) || job.Attempts >= h.maxAttempts:
+		log.Error("Giving up on a job", "error", err)
+		w.record(job, func(ctx context.Context) error { return w.jobs.Kill(ctx, job.Id, job.Attempts, err.Error()) })
+	default:
+		log.Warn("Job failed, will retry", "error", err)
+		runAt := w.now().Add(retryDelay(job.Attempts))
+		w.record(job, func(ctx context.Context) error { return w.jobs.Fail(ctx, job.Id, job.Attempts, err.Error(), runAt) })
+	}
+}
+
+// runHandler turns a panicking handler into a failed attempt rather than a
+// dead process.
+func runHandler(ctx context.Context, h handler, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h.run(ctx, payload)
+}
+
+// record writes down how an attempt went. The lease still guards the job, so
+// a lost update only means it is run again once the lease runs out.
+func (w *Worker) record(job jobs.Job, update func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	if err := update(ctx); err != nil {
+		slog.Error("Failed to record a job attempt", "error", err, "jobId", job.Id, "kind", job.Kind)
+	}
+}
+
+// enqueueScheduled queues the next run of every schedule that has none
+// waiting.
+func (w *Worker) enqueueScheduled(ctx context.Context) {
+	now := w.now().UTC()
+
+	for _, s := range w.schedules {
+		next := s.cron.Next(now)
+		if next.IsZero() {
+			continue
+		}
+
+		_, err := w.jobs.Enqueue(ctx, jobs.Job{
+			Id:        uuid.New(),
+			Kind:      s.kind,
+			Payload:   s.payload,
+			UniqueKey: scheduleKey(s.kind),
+			RunAt:     next,
+			CreatedAt: now,
+		})
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to queue a scheduled job", "error", err, "kind", s.kind)
+		}
+	}
+}
+
+func scheduleKey(kind string) string {
+	return "schedule:" + kind
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,225 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"server/internal/domain/jobs"
+
+	"github.com/google/uuid"
+)
+
+// stubWorkerJobs records how each attempt was reported.
+type stubWorkerJobs struct {
+	mu       sync.Mutex
+	queued   []jobs.Job
+	outcomes []string
+	lastErr  string
+	runAt    time.Time
+}
+
+func (s *stubWorkerJobs) Enqueue(_ context.Context, job jobs.Job) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, queued := range s.queued {
+		if queued.UniqueKey != "" && queued.UniqueKey == job.UniqueKey {
+			return false, nil
+		}
+	}
+	s.queued = append(s.queued, job)
+	return true, nil
+}
+
+func (s *stubWorkerJobs) Claim(context.Context, map[string]time.Duration, int) ([]jobs.Job, error) {
+	return nil, nil
+}
+
+func (s *stubWorkerJobs) report(outcome, lastErr string, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outcomes = append(s.outcomes, outcome)
+	s.lastErr, s.runAt = lastErr, runAt
+	return nil
+}
+
+func (s *stubWorkerJobs) Complete(context.Context, uuid.UUID, int) error {
+	return s.report("done", "", time.Time{})
+}
+
+func (s *stubWorkerJobs) Fail(_ context.Context, _ uuid.UUID, _ int, lastError string, runAt time.Time) error {
+	return s.report("retry", lastError, runAt)
+}
+
+func (s *stubWorkerJobs) Kill(_ context.Context, _ uuid.UUID, _ int, lastError string) error {
+	return s.report("dead", lastError, time.Time{})
+}
+
+func (s *stubWorkerJobs) Release(context.Context, uuid.UUID, int) error {
+	return s.report("released", "", time.Time{})
+}
+
+func (s *stubWorkerJobs) outcome() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return strings.Join(s.outcomes, ",")
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+const greet Kind[greeting] = "test.greet"
+
+func newTestWorker(handle func(ctx context.Context, args greeting) error) (*Worker, *stubWorkerJobs) {
+	repo := &stubWorkerJobs{}
+	worker := NewWorker(repo, 2)
+	worker.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+	Register(worker, greet, handle, HandlerOptions{MaxAttempts: 3})
+
+	return worker, repo
+}
+
+func claimed(attempts int, payload string) jobs.Job {
+	return jobs.Job{Id: uuid.New(), Kind: string(greet), Payload: []byte(payload), Status: jobs.StatusRunning, Attempts: attempts}
+}
+
+// The handler gets its arguments decoded into the type its kind declares.
+func TestWorker_DecodesArguments(t *testing.T) {
+	var got greeting
+	worker, repo := newTestWorker(func(_ context.Context, args greeting) error {
+		got = args
+		return nil
+	})
+
+	worker.execute(claimed(1, `{"name":"Мария"}`))
+
+	if got.Name != "Мария" {
+		t.Errorf("handler got %+v", got)
+	}
+	if repo.outcome() != "done" {
+		t.Errorf("outcome = %q, want done", repo.outcome())
+	}
+}
+
+// A failure is retried with a growing delay until the attempts run out; a
+// panic counts as a failure rather than taking the process down.
+func TestWorker_RetriesThenGivesUp(t *testing.T) {
+	worker, repo := newTestWorker(func(context.Context, greeting) error {
+		return errors.New("mail server down")
+	})
+
+	worker.execute(claimed(2, `{}`))
+	if repo.outcome() != "retry" || repo.lastErr != "mail server down" {
+		t.Fatalf("outcome = %q (%q), want retry", repo.outcome(), repo.lastErr)
+	}
+	if want := worker.now().Add(time.Minute); !repo.runAt.Equal(want) {
+		t.Errorf("second retry due %v, want %v", repo.runAt, want)
+	}
+
+	worker.execute(claimed(3, `{}`))
+	if repo.outcome() != "retry,dead" {
+		t.Errorf("outcome after the last attempt = %q, want dead", repo.outcome())
+	}
+
+	panicking, repo := newTestWorker(func(context.Context, greeting) error {
+		panic("nil map")
+	})
+	panicking.execute(claimed(1, `{}`))
+	if repo.outcome() != "retry" || !strings.Contains(repo.lastErr, "nil map") {
+		t.Errorf("panic outcome = %q (%q), want a retry", repo.outcome(), repo.lastErr)
+	}
+}
+
+// Arguments that do not decode will not decode next time either, so the job
+// is dead on its first attempt.
+func TestWorker_PermanentFailure(t *testing.T) {
+	worker, repo := newTestWorker(func(context.Context, greeting) error {
+		t.Error("handler ran with undecodable arguments")
+		return nil
+	})
+
+	worker.execute(claimed(1, `{"name":42}`))
+
+	if repo.outcome() != "dead" {
+		t.Errorf("outcome = %q, want dead", repo.outcome())
+	}
+}
+
+// A job still running when shutdown runs out of time is cancelled and handed
+// back without the attempt counting against it.
+func TestWorker_ShutdownReleasesInterruptedJobs(t *testing.T) {
+	started := make(chan struct{})
+	worker, repo := newTestWorker(func(ctx context.Context, _ greeting) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !worker.start(claimed(1, `{}`)) {
+		t.Fatal("start() refused a job before shutdown")
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := worker.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want the deadline", err)
+	}
+	if repo.outcome() != "released" {
+		t.Errorf("outcome = %q, want released", repo.outcome())
+	}
+
+	// Once stopping, a job claimed in the meantime goes straight back.
+	if worker.start(claimed(1, `{}`)) {
+		t.Error("start() ran a job after shutdown")
+	}
+	if repo.outcome() != "released,released" {
+		t.Errorf("outcome = %q, want the late job released too", repo.outcome())
+	}
+}
+
+// A schedule queues its next run only while none is waiting, so a slow or
+// failing run is never joined by the next.
+func TestWorker_EnqueueScheduled(t *testing.T) {
+	worker, repo := newTestWorker(func(context.Context, greeting) error { return nil })
+	if err := Schedule(worker, "0 3 * * *", greet, greeting{Name: "cron"}); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	worker.enqueueScheduled(context.Background())
+	worker.enqueueScheduled(context.Background())
+
+	if len(repo.queued) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(repo.queued))
+	}
+	job := repo.queued[0]
+	if want := time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC); !job.RunAt.Equal(want) {
+		t.Errorf("RunAt = %v, want %v", job.RunAt, want)
+	}
+	if string(job.Payload) != `{"name":"cron"}` {
+		t.Errorf("Payload = %s", job.Payload)
+	}
+}
+
+// A job whose worker died comes due again once its own kind could have
+// finished, not after the slowest kind registered.
+func TestWorker_LeasesPerKind(t *testing.T) {
+	worker, _ := newTestWorker(func(context.Context, greeting) error { return nil })
+	Register(worker, Kind[struct{}]("test.slow"), func(context.Context, struct{}) error { return nil }, HandlerOptions{Timeout: 30 * time.Minute})
+
+	leases := worker.leases()
+
+	if want := defaultTimeout + leaseMargin; leases[string(greet)] != want {
+		t.Errorf("lease of %s = %v, want %v", greet, leases[string(greet)], want)
+	}
+	if want := 30*time.Minute + leaseMargin; leases["test.slow"] != want {
+		t.Errorf("lease of test.slow = %v, want %v", leases["test.slow"], want)
+	}
+}
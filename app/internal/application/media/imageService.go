@@ -0,0 +1,155 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"server/internal/domain/image"
+	"server/internal/infrastructure/imaging"
+	"server/internal/infrastructure/storage"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+type imageStore interface {
+	UploadImage(ctx context.Context, file io.Reader, filename string) (*storage.UploadResult, error)
+}
+
+type imageRepository interface {
+	Create(ctx context.Context, img image.Image, variants []image.Variant) error
+}
+
+type imageProcessor interface {
+	Clean(ctx context.Context, data []byte) (*imaging.Cleaned, error)
+	Variants(ctx context.Context, cleaned *imaging.Cleaned, widths []int) ([]imaging.Variant, error)
+}
+
+// maxProcessing bounds how many uploads are decoded at once. A phone photo
+// takes a couple of hundred megabytes while it is.
+const maxProcessing = 2
+
+// ImageService stores uploaded images: without their metadata, and with
+// copies at the widths and in the formats the pages offer.
+type ImageService struct {
+	store     imageStore
+	images    imageRepository
+	processor imageProcessor
+	index     *VariantIndex
+	// variants is off for Cloudinary, which resizes on delivery.
+	variants bool
+	widths   []int
+	slots    chan struct{}
+	now      func() time.Time
+}
+
+func NewImageService(store imageStore, images imageRepository, processor imageProcessor, index *VariantIndex, variants bool, widths []int) *ImageService {
+	return &ImageService{
+		store:     store,
+		images:    images,
+		processor: processor,
+		index:     index,
+		variants:  variants,
+		widths:    widths,
+		slots:     make(chan struct{}, maxProcessing),
+		now:       time.Now,
+	}
+}
+
+// Upload cleans an image and stores it with its copies, and records it in the
+// media library as uploaded by the given user. An image that cannot be
+// decoded fails with imaging.ErrUnreadable or imaging.ErrTooLarge.
+//
+// Copies are an optimisation: one that cannot be made or stored is logged
+// and left out, and the upload still succeeds.
+func (s *ImageService) Upload(ctx context.Context, file io.Reader, filename string, uploadedBy uuid.UUID) (*storage.UploadResult, error) {
+	ctx, span := tracing.Start(ctx, "ImageService.Upload")
+	defer span.End()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	cleaned, err := s.processor.Clean(ctx, data)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	result, err := s.store.UploadImage(ctx, bytes.NewReader(cleaned.Data), filename)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	img := image.Image{
+		Id:         uuid.New(),
+		Url:        result.URL,
+		Key:        result.Key,
+		Width:      cleaned.Width,
+		Height:     cleaned.Height,
+		Bytes:      int64(len(cleaned.Data)),
+		Filename:   filename,
+		UploadedBy: uuid.NullUUID{UUID: uploadedBy, Valid: uploadedBy != uuid.Nil},
+		CreatedAt:  s.now(),
+	}
+
+	var variants []image.Variant
+	if s.variants {
+		variants = s.storeVariants(ctx, cleaned, filename)
+	}
+
+	// The image itself is stored and its URL works, so a failure here costs
+	// only the copies and the library entry: unrecorded, the copies are never
+	// offered, and `cleanup orphans` finds all of it once unused, as it finds
+	// every upload outside the library.
+	if err := s.images.Create(ctx, img, variants); err != nil {
+		slog.ErrorContext(ctx, "Could not record the uploaded image", "error", err, "key", result.Key)
+		return result, nil
+	}
+	s.index.Add(img, variants)
+
+	return result, nil
+}
+
+func (s *ImageService) storeVariants(ctx context.Context, cleaned *imaging.Cleaned, filename string) []image.Variant {
+	made, err := s.processor.Variants(ctx, cleaned, s.widths)
+	if err != nil {
+		slog.WarnContext(ctx, "Some image variants could not be made", "error", err, "filename", filename)
+	}
+
+	name := strings.TrimSuffix(filename, path.Ext(filename))
+
+	var stored []image.Variant
+	for _, variant := range made {
+		result, err := s.store.UploadImage(ctx, bytes.NewReader(variant.Data), fmt.Sprintf("%s-%dw.%s", name, variant.Width, variant.Format))
+		if err != nil {
+			slog.WarnContext(ctx, "Could not store an image variant", "error", err, "filename", filename, "format", variant.Format, "width", variant.Width)
+			continue
+		}
+
+		stored = append(stored, image.Variant{
+			Format: variant.Format,
+			Width:  variant.Width,
+			Url:    result.URL,
+			Key:    result.Key,
+			Bytes:  int64(len(variant.Data)),
+		})
+	}
+
+	return stored
+}
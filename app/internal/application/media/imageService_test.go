@@ -0,0 +1,138 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"server/internal/domain/image"
+	"server/internal/infrastructure/imaging"
+	"server/internal/infrastructure/storage"
+
+	"github.com/google/uuid"
+)
+
+type stubImageStore struct {
+	uploaded map[string]string
+	names    []string
+}
+
+func (s *stubImageStore) UploadImage(_ context.Context, file io.Reader, filename string) (*storage.UploadResult, error) {
+	data, _ := io.ReadAll(file)
+	if s.uploaded == nil {
+		s.uploaded = map[string]string{}
+	}
+	s.uploaded[filename] = string(data)
+	s.names = append(s.names, filename)
+
+	key := "images/" + filename
+	return &storage.UploadResult{URL: "/media/" + key, Key: key}, nil
+}
+
+type stubImageRepository struct {
+	created  []image.Image
+	variants []image.Variant
+}
+
+func (s *stubImageRepository) Create(_ context.Context, img image.Image, variants []image.Variant) error {
+	s.created = append(s.created, img)
+	s.variants = append(s.variants, variants...)
+	return nil
+}
+
+func (s *stubImageRepository) FindWithVariants(context.Context) ([]image.WithVariants, error) {
+	return nil, nil
+}
+
+// stubProcessor "cleans" by upper-casing, and makes a WebP at each width.
+type stubProcessor struct {
+	err           error
+	variantsAsked bool
+}
+
+func (s *stubProcessor) Clean(_ context.Context, data []byte) (*imaging.Cleaned, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &imaging.Cleaned{Data: []byte(strings.ToUpper(string(data))), Format: imaging.JPEG, Width: 1000, Height: 500}, nil
+}
+
+func (s *stubProcessor) Variants(_ context.Context, _ *imaging.Cleaned, widths []int) ([]imaging.Variant, error) {
+	s.variantsAsked = true
+	var variants []imaging.Variant
+	for _, width := range widths {
+		variants = append(variants, imaging.Variant{Format: imaging.WebP, Width: width, Data: []byte("webp")})
+	}
+	return variants, nil
+}
+
+var uploader = uuid.MustParse("7f0c2a1e-4b5d-4e8f-9a6b-3c2d1e0f9a8b")
+
+func newTestImageService(variants bool, processor *stubProcessor) (*ImageService, *stubImageStore, *stubImageRepository) {
+	store := &stubImageStore{}
+	repo := &stubImageRepository{}
+	service := NewImageService(store, repo, processor, NewVariantIndex(repo, 0), variants, []int{400, 800})
+	return service, store, repo
+}
+
+// What goes to storage is what the processor made of the upload, never the
+// upload itself, which still has the GPS position in it. The copies are
+// recorded against the image and findable by its URL straight away.
+func TestImageService_UploadStoresTheCleanedImageAndItsCopies(t *testing.T) {
+	service, store, repo := newTestImageService(true, &stubProcessor{})
+
+	result, err := service.Upload(context.Background(), strings.NewReader("photo"), "Run.jpg", uploader)
+	if err != nil {
+		t.Fatalf("Upload() = %v", err)
+	}
+
+	if store.uploaded["Run.jpg"] != "PHOTO" {
+		t.Errorf("stored %q, want the cleaned image", store.uploaded["Run.jpg"])
+	}
+	if strings.Join(store.names, ",") != "Run.jpg,Run-400w.webp,Run-800w.webp" {
+		t.Errorf("uploaded %v", store.names)
+	}
+	if len(repo.created) != 1 || repo.created[0].Url != result.URL || repo.created[0].Width != 1000 || len(repo.variants) != 2 {
+		t.Errorf("recorded %+v with %+v", repo.created, repo.variants)
+	}
+	if img := repo.created[0]; img.Filename != "Run.jpg" || img.Bytes != int64(len("PHOTO")) || img.UploadedBy.UUID != uploader {
+		t.Errorf("recorded %+v, want the name, size and uploader", img)
+	}
+
+	found := service.index.Lookup(result.URL)
+	if len(found) != 3 || found[0].Format != "jpeg" || found[2].URL != "/media/images/Run-800w.webp" {
+		t.Errorf("Lookup() = %+v, want the image and both copies", found)
+	}
+}
+
+// Cloudinary resizes on delivery, so copies would only be paid for twice.
+func TestImageService_NoCopiesForCloudinary(t *testing.T) {
+	processor := &stubProcessor{}
+	service, store, repo := newTestImageService(false, processor)
+
+	if _, err := service.Upload(context.Background(), strings.NewReader("photo"), "run.jpg", uploader); err != nil {
+		t.Fatalf("Upload() = %v", err)
+	}
+
+	if processor.variantsAsked || len(store.names) != 1 || len(repo.variants) != 0 {
+		t.Errorf("copies were made: uploaded %v", store.names)
+	}
+	if len(repo.created) != 1 {
+		t.Error("the image was not recorded")
+	}
+}
+
+func TestImageService_UnreadableImageIsNotStored(t *testing.T) {
+	service, store, _ := newTestImageService(true, &stubProcessor{err: imaging.ErrUnreadable})
+
+	_, err := service.Upload(context.Background(), strings.NewReader("<svg/>"), "logo.svg", uploader)
+
+	if !errors.Is(err, imaging.ErrUnreadable) {
+		t.Errorf("Upload() = %v, want ErrUnreadable", err)
+	}
+	if len(store.names) != 0 {
+		t.Errorf("uploaded %v", store.names)
+	}
+}
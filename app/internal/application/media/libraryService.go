@@ -0,0 +1,167 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"server/internal/domain/image"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+// MaxAltLength is the longest alt text the library keeps. Screen readers read
+// it out in full, so anything longer belongs in a caption.
+const MaxAltLength = 250
+
+var ErrAltTooLong = errors.New("alt text too long")
+
+type libraryRepository interface {
+	FindPage(ctx context.Context, filter image.LibraryFilter, limit, offset int) ([]image.LibraryItem, int, error)
+	UpdateAlt(ctx context.Context, id uuid.UUID, alt string) error
+	TrackPost(ctx context.Context, postId uuid.UUID) error
+	ScanUsage(ctx context.Context, now time.Time) error
+	FindUnusedSince(ctx context.Context, cutoff time.Time) ([]image.WithVariants, error)
+	Claim(ctx context.Context, id uuid.UUID, cutoff time.Time) (bool, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type assetDeleter interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// LibraryPage is one page of the media library.
+type LibraryPage struct {
+	Items []image.LibraryItem
+	Total int
+}
+
+// LibraryService is the media library: every uploaded image, where it is
+// used, and the sweep that deletes those nothing has shown for the grace
+// period.
+type LibraryService struct {
+	images libraryRepository
+	store  assetDeleter
+	grace  time.Duration
+	now    func() time.Time
+}
+
+func NewLibraryService(images libraryRepository, store assetDeleter, grace time.Duration) *LibraryService {
+	return &LibraryService{
+		images: images,
+		store:  store,
+		grace:  grace,
+		now:    time.Now,
+	}
+}
+
+// Grace is how long an image goes unused before the sweep deletes it.
+func (s *LibraryService) Grace() time.Duration {
+	return s.grace
+}
+
+func (s *LibraryService) Search(ctx context.Context, filter image.LibraryFilter, page, pageSize int) (*LibraryPage, error) {
+	ctx, span := tracing.Start(ctx, "LibraryService.Search")
+	defer span.End()
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	items, total, err := s.images.FindPage(ctx, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LibraryPage{Items: items, Total: total}, nil
+}
+
+// SetAlt sets the alt text the picker inserts with an image. Images already
+// in posts keep the text they were inserted with.
+func (s *LibraryService) SetAlt(ctx context.Context, id uuid.UUID, alt string) error {
+	ctx, span := tracing.Start(ctx, "LibraryService.SetAlt")
+	defer span.End()
+
+	alt = strings.TrimSpace(alt)
+	if utf8.RuneCountInString(alt) > MaxAltLength {
+		return ErrAltTooLong
+	}
+
+	return s.images.UpdateAlt(ctx, id, alt)
+}
+
+// TrackPost records where a post just saved or deleted uses images, so the
+// library is current without waiting for the sweep.
+func (s *LibraryService) TrackPost(ctx context.Context, postId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "LibraryService.TrackPost")
+	defer span.End()
+
+	return s.images.TrackPost(ctx, postId)
+}
+
+// Sweep scans the posts for where images are used, then deletes the images
+// that have gone unused for the grace period, from storage and from the
+// library, and returns how many went.
+//
+// An image that cannot be deleted from storage is logged and kept for the
+// next sweep, so one stuck file does not hold up the rest.
+func (s *LibraryService) Sweep(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "LibraryService.Sweep")
+	defer span.End()
+
+	if err := s.images.ScanUsage(ctx, s.now()); err != nil {
+		tracing.Fail(span, err)
+		return 0, err
+	}
+
+	cutoff := s.now().Add(-s.grace)
+	unused, err := s.images.FindUnusedSince(ctx, cutoff)
+	if err != nil {
+		tracing.Fail(span, err)
+		return 0, err
+	}
+
+	deleted := 0
+	for _, img := range unused {
+		claimed, err := s.images.Claim(ctx, img.Id, cutoff)
+		if err != nil {
+			return deleted, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if !s.deleteStored(ctx, img) {
+			continue
+		}
+		if err := s.images.Delete(ctx, img.Id); err != nil {
+			return deleted, err
+		}
+
+		slog.InfoContext(ctx, "Unused image deleted", "key", img.Key, "filename", img.Filename, "bytes", img.Bytes)
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// deleteStored deletes an image and its variants from storage, and reports
+// whether all of them went. If not, the image stays recorded and the next
+// sweep tries again.
+func (s *LibraryService) deleteStored(ctx context.Context, img image.WithVariants) bool {
+	keys := make([]string, 0, len(img.Variants)+1)
+	for _, variant := range img.Variants {
+		keys = append(keys, variant.Key)
+	}
+	keys = append(keys, img.Key)
+
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			slog.ErrorContext(ctx, "Could not delete an unused image", "key", key, "error", err)
+			return false
+		}
+	}
+
+	return true
+}
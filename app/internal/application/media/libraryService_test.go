@@ -0,0 +1,150 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/domain/image"
+
+	"github.com/google/uuid"
+)
+
+// stubLibrary holds the unused images and refuses to claim those a post has
+// started to show since.
+type stubLibrary struct {
+	unused    []image.WithVariants
+	shown     map[uuid.UUID]bool
+	scannedAt time.Time
+	cutoff    time.Time
+	deleted   []uuid.UUID
+	alt       string
+}
+
+func (s *stubLibrary) FindPage(context.Context, image.LibraryFilter, int, int) ([]image.LibraryItem, int, error) {
+	return nil, 0, nil
+}
+
+func (s *stubLibrary) UpdateAlt(_ context.Context, _ uuid.UUID, alt string) error {
+	s.alt = alt
+	return nil
+}
+
+func (s *stubLibrary) TrackPost(context.Context, uuid.UUID) error {
+	return nil
+}
+
+func (s *stubLibrary) ScanUsage(_ context.Context, now time.Time) error {
+	s.scannedAt = now
+	return nil
+}
+
+func (s *stubLibrary) FindUnusedSince(_ context.Context, cutoff time.Time) ([]image.WithVariants, error) {
+	s.cutoff = cutoff
+	return s.unused, nil
+}
+
+func (s *stubLibrary) Claim(_ context.Context, id uuid.UUID, _ time.Time) (bool, error) {
+	return !s.shown[id], nil
+}
+
+func (s *stubLibrary) Delete(_ context.Context, id uuid.UUID) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+// failingStore refuses to delete the keys it is given.
+type failingStore struct {
+	refuse  string
+	deleted []string
+}
+
+func (s *failingStore) Delete(_ context.Context, key string) error {
+	if key == s.refuse {
+		return errors.New("bucket unavailable")
+	}
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func unusedImage(key string, variantKeys ...string) image.WithVariants {
+	img := image.WithVariants{Image: image.Image{Id: uuid.New(), Key: key}}
+	for _, variantKey := range variantKeys {
+		img.Variants = append(img.Variants, image.Variant{Key: variantKey})
+	}
+	return img
+}
+
+// The scan has to come first: an image unused for a month may have been put
+// in a post an hour ago. An image the claim finds shown again is left alone.
+func TestSweep_DeletesImagesUnusedPastTheGrace(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	gone := unusedImage("images/gone.jpg", "images/gone-400w.webp")
+	reused := unusedImage("images/reused.jpg")
+	library := &stubLibrary{
+		unused: []image.WithVariants{gone, reused},
+		shown:  map[uuid.UUID]bool{reused.Id: true},
+	}
+	store := &failingStore{}
+	service := NewLibraryService(library, store, 30*24*time.Hour)
+	service.now = func() time.Time { return now }
+
+	deleted, err := service.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep() = %v", err)
+	}
+
+	if !library.scannedAt.Equal(now) {
+		t.Error("the sweep did not scan the posts first")
+	}
+	if want := now.Add(-30 * 24 * time.Hour); !library.cutoff.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", library.cutoff, want)
+	}
+	if deleted != 1 || !slices.Equal(library.deleted, []uuid.UUID{gone.Id}) {
+		t.Errorf("Sweep() = %d, forgot %v, want only the unused image", deleted, library.deleted)
+	}
+	if !slices.Equal(store.deleted, []string{"images/gone-400w.webp", "images/gone.jpg"}) {
+		t.Errorf("deleted from storage %v, want the image and its copy", store.deleted)
+	}
+}
+
+// Forgetting an image whose file is still stored would leave the file there
+// for good, with nothing left to find it by.
+func TestSweep_KeepsWhatStorageWouldNotDelete(t *testing.T) {
+	stuck := unusedImage("images/stuck.jpg", "images/stuck-400w.webp")
+	gone := unusedImage("images/gone.jpg")
+	library := &stubLibrary{unused: []image.WithVariants{stuck, gone}}
+	service := NewLibraryService(library, &failingStore{refuse: "images/stuck-400w.webp"}, time.Hour)
+
+	deleted, err := service.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep() = %v", err)
+	}
+
+	if deleted != 1 || !slices.Equal(library.deleted, []uuid.UUID{gone.Id}) {
+		t.Errorf("Sweep() = %d, forgot %v, want the stuck image kept", deleted, library.deleted)
+	}
+}
+
+func TestSetAlt(t *testing.T) {
+	library := &stubLibrary{}
+	service := NewLibraryService(library, &failingStore{}, time.Hour)
+
+	if err := service.SetAlt(context.Background(), uuid.New(), "  Изгрев над Мусала \n"); err != nil {
+		t.Fatalf("SetAlt() = %v", err)
+	}
+	if library.alt != "Изгрев над Мусала" {
+		t.Errorf("stored %q, want it trimmed", library.alt)
+	}
+
+	// Counted in characters: 250 Cyrillic letters are 500 bytes.
+	if err := service.SetAlt(context.Background(), uuid.New(), strings.Repeat("я", MaxAltLength)); err != nil {
+		t.Errorf("SetAlt() of %d letters = %v", MaxAltLength, err)
+	}
+	if err := service.SetAlt(context.Background(), uuid.New(), strings.Repeat("я", MaxAltLength+1)); !errors.Is(err, ErrAltTooLong) {
+		t.Errorf("SetAlt() = %v, want ErrAltTooLong", err)
+	}
+}
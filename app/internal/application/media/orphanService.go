@@ -0,0 +1,113 @@
+package media
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"server/internal/infrastructure/storage"
+	"server/internal/infrastructure/tracing"
+)
+
+type assetStore interface {
+	List(ctx context.Context) ([]storage.Asset, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type referenceRepository interface {
+	FindUnreferenced(ctx context.Context, needles []string) ([]string, error)
+}
+
+type recordedImages interface {
+	RecordedKeys(ctx context.Context) (map[string]bool, error)
+}
+
+// OrphanService finds uploads nothing links to any more: images of deleted
+// posts, covers that were replaced, files uploaded for a post that was never
+// saved. Uploads in the media library are not its business: they wait in the
+// library for their grace period and LibraryService.Sweep deletes them, with
+// their copies, once nothing has used them for that long.
+type OrphanService struct {
+	store      assetStore
+	references referenceRepository
+	images     recordedImages
+	now        func() time.Time
+}
+
+func NewOrphanService(store assetStore, references referenceRepository, images recordedImages) *OrphanService {
+	return &OrphanService{
+		store:      store,
+		references: references,
+		images:     images,
+		now:        time.Now,
+	}
+}
+
+// referenceBatch is how many keys are checked per query.
+const referenceBatch = 500
+
+// Find returns the assets older than minAge that no post or category refers
+// to and that are not in the media library. The age keeps an image uploaded
+// into a post that is still being written from counting as unused.
+func (s *OrphanService) Find(ctx context.Context, minAge time.Duration) ([]storage.Asset, error) {
+	ctx, span := tracing.Start(ctx, "OrphanService.Find")
+	defer span.End()
+
+	assets, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := s.images.RecordedKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := s.now().Add(-minAge)
+	candidates := map[string]storage.Asset{}
+	var keys []string
+	for _, asset := range assets {
+		if asset.CreatedAt.After(cutoff) || recorded[asset.Key] {
+			continue
+		}
+
+		candidates[asset.Key] = asset
+		keys = append(keys, asset.Key)
+	}
+
+	var orphans []storage.Asset
+	for start := 0; start < len(keys); start += referenceBatch {
+		end := min(start+referenceBatch, len(keys))
+
+		unreferenced, err := s.references.FindUnreferenced(ctx, keys[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range unreferenced {
+			orphans = append(orphans, candidates[key])
+		}
+	}
+
+	return orphans, nil
+}
+
+// Delete removes the given assets and returns how many went. It carries on
+// past failures, which are logged, so one stuck asset does not block the rest.
+func (s *OrphanService) Delete(ctx context.Context, orphans []storage.Asset) int {
+	ctx, span := tracing.Start(ctx, "OrphanService.Delete")
+	defer span.End()
+
+	deleted := 0
+	for _, orphan := range orphans {
+		if err := s.store.Delete(ctx, orphan.Key); err != nil {
+			slog.ErrorContext(ctx, "Could not delete orphaned asset", "key", orphan.Key, "error", err)
+			continue
+		}
+
+		slog.InfoContext(ctx, "Orphaned asset deleted", "key", orphan.Key, "bytes", orphan.Bytes)
+		deleted++
+	}
+
+	return deleted
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/infrastructure/storage"
+)
+
+type stubAssetStore struct {
+	assets  []storage.Asset
+	deleted []string
+}
+
+func (s *stubAssetStore) List(context.Context) ([]storage.Asset, error) {
+	return s.assets, nil
+}
+
+func (s *stubAssetStore) Delete(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+// stubReferences treats every key that appears in content as used.
+type stubReferences struct {
+	content string
+	asked   []string
+}
+
+func (s *stubReferences) FindUnreferenced(_ context.Context, needles []string) ([]string, error) {
+	s.asked = append(s.asked, needles...)
+	var unreferenced []string
+	for _, needle := range needles {
+		if !strings.Contains(s.content, needle) {
+			unreferenced = append(unreferenced, needle)
+		}
+	}
+	return unreferenced, nil
+}
+
+// stubImages knows which stored keys are in the media library.
+type stubImages struct {
+	recorded map[string]bool
+}
+
+func (s *stubImages) RecordedKeys(context.Context) (map[string]bool, error) {
+	return s.recorded, nil
+}
+
+// An image uploaded into a post that has not been saved yet is referenced by
+// nothing, and must survive until the author has had time to save.
+func TestFind_SkipsUsedAndRecentUploads(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &stubAssetStore{assets: []storage.Asset{
+		{Key: "blog/used", CreatedAt: now.Add(-72 * time.Hour)},
+		{Key: "blog/unused", CreatedAt: now.Add(-72 * time.Hour)},
+		{Key: "blog/fresh", CreatedAt: now.Add(-time.Hour)},
+	}}
+	references := &stubReferences{content: `<img src="https://res.cloudinary.com/x/image/upload/v1/blog/used.jpg">`}
+	service := NewOrphanService(store, references, &stubImages{})
+	service.now = func() time.Time { return now }
+
+	orphans, err := service.Find(context.Background(), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Find() = %v", err)
+	}
+
+	if len(orphans) != 1 || orphans[0].Key != "blog/unused" {
+		t.Errorf("Find() = %+v, want only blog/unused", orphans)
+	}
+	if slices.Contains(references.asked, "blog/fresh") {
+		t.Error("a recent upload was checked, so it could have been deleted")
+	}
+
+	if deleted := service.Delete(context.Background(), orphans); deleted != 1 || store.deleted[0] != "blog/unused" {
+		t.Errorf("Delete() removed %v", store.deleted)
+	}
+}
+
+// A library image waits out its grace period for someone to pick it, and the
+// library's sweep deletes it with its copies after that. Cleaning up orphans
+// must not take it a day after upload just because no post shows it yet.
+func TestFind_LeavesLibraryImagesToTheirSweep(t *testing.T) {
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &stubAssetStore{assets: []storage.Asset{
+		{Key: "images/library.jpg", CreatedAt: old},
+		{Key: "images/library-800w.webp", CreatedAt: old},
+		{Key: "images/stray.jpg", CreatedAt: old},
+	}}
+	images := &stubImages{recorded: map[string]bool{
+		"images/library.jpg":       true,
+		"images/library-800w.webp": true,
+	}}
+	references := &stubReferences{}
+	service := NewOrphanService(store, references, images)
+
+	orphans, err := service.Find(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("Find() = %v", err)
+	}
+
+	if len(orphans) != 1 || orphans[0].Key != "images/stray.jpg" {
+		t.Errorf("Find() = %+v, want only the upload outside the library", orphans)
+	}
+	if slices.Contains(references.asked, "images/library.jpg") {
+		t.Error("a library image was checked against the posts, so it could have been deleted")
+	}
+}
@@ -0,0 +1,168 @@
+package media
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/domain/image"
+	"server/util/imageutils"
+)
+
+// DefaultVariantIndexTTL bounds how long copies made by another instance go
+// unseen here.
+const DefaultVariantIndexTTL = 5 * time.Minute
+
+type variantSource interface {
+	FindWithVariants(ctx context.Context) ([]image.WithVariants, error)
+}
+
+// VariantIndex keeps the stored copies of every image in memory, for pages to
+// look up by URL while they render. A page can show a dozen images, and a
+// query for each would cost more than the copies save.
+//
+// Copies made on this instance are added as they are made. Those made on
+// another show up when the index is next reloaded, within the TTL; until then
+// the image is served as it was uploaded.
+type VariantIndex struct {
+	source variantSource
+	ttl    time.Duration
+	now    func() time.Time
+
+	// loading is held while the index is read from the database, so only
+	// one read runs at a time.
+	loading sync.Mutex
+
+	mu       sync.RWMutex
+	byURL    map[string][]imageutils.Variant
+	loadedAt time.Time
+	// added holds what Add recorded since the last load began, which that
+	// load's query may have run too early to see.
+	added map[string][]imageutils.Variant
+}
+
+func NewVariantIndex(source variantSource, ttl time.Duration) *VariantIndex {
+	if ttl <= 0 {
+		ttl = DefaultVariantIndexTTL
+	}
+
+	return &VariantIndex{
+		source: source,
+		ttl:    ttl,
+		now:    time.Now,
+		byURL:  map[string][]imageutils.Variant{},
+		added:  map[string][]imageutils.Variant{},
+	}
+}
+
+// Lookup returns the copies of the image at url, the original among them. It
+// is an imageutils.VariantLookup.
+//
+// The first lookup waits for the index to load; after that a stale index is
+// reloaded in the background and answers from what it has meanwhile.
+func (x *VariantIndex) Lookup(url string) []imageutils.Variant {
+	x.mu.RLock()
+	loadedAt := x.loadedAt
+	variants := x.byURL[url]
+	x.mu.RUnlock()
+
+	switch {
+	case loadedAt.IsZero():
+		x.loading.Lock()
+		defer x.loading.Unlock()
+		if x.loaded() {
+			break
+		}
+		x.load()
+	case x.now().Sub(loadedAt) > x.ttl:
+		if x.loading.TryLock() {
+			go func() {
+				defer x.loading.Unlock()
+				x.load()
+			}()
+		}
+		return variants
+	default:
+		return variants
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.byURL[url]
+}
+
+// Add records the copies of an image just uploaded, so its page offers them
+// straight away.
+func (x *VariantIndex) Add(img image.Image, variants []image.Variant) {
+	if len(variants) == 0 {
+		return
+	}
+
+	entry := indexEntry(img, variants)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.byURL[img.Url] = entry
+	x.added[img.Url] = entry
+}
+
+func (x *VariantIndex) loaded() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return !x.loadedAt.IsZero()
+}
+
+// load replaces the index with what the database holds. On failure the old
+// index stays and the next attempt waits out the TTL, so an unreachable
+// database is not asked again on every render.
+func (x *VariantIndex) load() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	x.mu.Lock()
+	added := x.added
+	x.added = map[string][]imageutils.Variant{}
+	x.mu.Unlock()
+
+	images, err := x.source.FindWithVariants(ctx)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.loadedAt = x.now()
+
+	if err != nil {
+		slog.Error("Could not load the image variants", "error", err)
+		maps.Copy(x.added, added)
+		return
+	}
+
+	byURL := make(map[string][]imageutils.Variant, len(images))
+	for _, img := range images {
+		byURL[img.Url] = indexEntry(img.Image, img.Variants)
+	}
+	maps.Copy(byURL, added)
+	maps.Copy(byURL, x.added)
+	x.byURL = byURL
+}
+
+func indexEntry(img image.Image, variants []image.Variant) []imageutils.Variant {
+	entry := []imageutils.Variant{{URL: img.Url, Width: img.Width, Format: formatOf(img.Key)}}
+	for _, variant := range variants {
+		entry = append(entry, imageutils.Variant{URL: variant.Url, Width: variant.Width, Format: variant.Format})
+	}
+	return entry
+}
+
+// formatOf names the format of a stored image by its extension, which
+// storage picks from the content.
+func formatOf(key string) string {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(key)), ".")
+	if ext == "jpg" {
+		return "jpeg"
+	}
+	return ext
+}
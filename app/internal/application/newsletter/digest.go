@@ -0,0 +1,215 @@
+package newsletter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/newsletter"
+	"server/internal/domain/posts"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DigestPeriod is how often the digest goes out.
+	DigestPeriod = 7 * 24 * time.Hour
+
+	// maxDigestPosts keeps a busy week's digest readable; the rest are a
+	// click away on the blog.
+	maxDigestPosts = 10
+
+	// pendingRetention is how long an unconfirmed sign up is kept. The link
+	// expires sooner; the margin lets a late click see the invalid link page
+	// rather than nothing at all.
+	pendingRetention = 7 * 24 * time.Hour
+
+	digestSubject = "Новото в Движи се тази седмица"
+)
+
+type sendRepository interface {
+	Start(ctx context.Context, send newsletter.Send) (int, error)
+	PendingRecipients(ctx context.Context, sendId uuid.UUID) ([]newsletter.Subscriber, error)
+	MarkQueued(ctx context.Context, sendId, subscriberId uuid.UUID) error
+	SetFailed(ctx context.Context, sendId uuid.UUID, failed int) error
+	Finish(ctx context.Context, sendId uuid.UUID, at time.Time) error
+	Latest(ctx context.Context) (*newsletter.Send, error)
+	FindAll(ctx context.Context, limit, offset int) ([]newsletter.Send, int, error)
+}
+
+type postSource interface {
+	FindPublishedBetween(ctx context.Context, since, until time.Time, limit int) ([]posts.PostWithAuthor, error)
+}
+
+// SendPage is one page of the send history and its total.
+type SendPage struct {
+	Sends []newsletter.Send
+	Total int
+}
+
+func (s *NewsletterService) Sends(ctx context.Context, page, pageSize int) (SendPage, error) {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Sends")
+	defer span.End()
+
+	sends, total, err := s.sends.FindAll(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return SendPage{}, err
+	}
+
+	return SendPage{Sends: sends, Total: total}, nil
+}
+
+// SendDigestIfDue mails the posts published since the last digest to every
+// confirmed subscriber, once a DigestPeriod has passed since it. A week with
+// nothing new sends nothing, and the next digest picks up from where the last
+// one ended. Returns the send, or nil when nothing went out.
+//
+// The send and its recipients are recorded before the first copy is queued,
+// and each recipient is ticked off as theirs is. A digest that did not finish
+// is resumed by the next call for whoever is left, rather than started again,
+// and only one send can cover a period however many instances try.
+func (s *NewsletterService) SendDigestIfDue(ctx context.Context) (*newsletter.Send, error) {
+	ctx, span := tracing.Start(ctx, "NewsletterService.SendDigestIfDue")
+	defer span.End()
+
+	now := s.now().UTC()
+	since := now.Add(-DigestPeriod)
+
+	latest, err := s.sends.Latest(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, err
+	case !latest.FinishedAt.Valid:
+		return latest, s.deliverDigest(ctx, latest)
+	case now.Sub(latest.FinishedAt.Time) < DigestPeriod:
+		return nil, nil
+	default:
+		since = latest.PeriodEnd
+	}
+
+	digestPosts, err := s.digestPosts(ctx, since, now)
+	if err != nil || len(digestPosts) == 0 {
+		return nil, err
+	}
+
+	send := newsletter.Send{
+		Id:          uuid.New(),
+		Subject:     digestSubject,
+		PeriodStart: since,
+		PeriodEnd:   now,
+		PostCount:   len(digestPosts),
+		StartedAt:   now,
+	}
+
+	send.RecipientCount, err = s.sends.Start(ctx, send)
+	if err != nil || send.RecipientCount == 0 {
+		return nil, err
+	}
+
+	return &send, s.deliverDigest(ctx, &send)
+}
+
+// deliverDigest queues a copy of the send for each recipient still without
+// one. A crash between queueing a copy and ticking it off sends that one
+// copy twice, never the whole list. Copies that fail are counted on the send
+// and returned as an error, so the job is retried for them.
+func (s *NewsletterService) deliverDigest(ctx context.Context, send *newsletter.Send) error {
+	digestPosts, err := s.digestPosts(ctx, send.PeriodStart, send.PeriodEnd)
+	if err != nil {
+		return err
+	}
+
+	// Posts unpublished since the send started leave nothing to send to the
+	// rest.
+	if len(digestPosts) > 0 {
+		pending, err := s.sends.PendingRecipients(ctx, send.Id)
+		if err != nil {
+			return err
+		}
+
+		send.FailedCount = 0
+		for _, subscriber := range pending {
+			if err := s.mailer.SendNewsletterDigest(ctx, subscriber.Email, send.Subject, digestPosts, s.UnsubscribeLink(subscriber.Id)); err != nil {
+				send.FailedCount++
+				slog.ErrorContext(ctx, "Failed to queue the newsletter digest", "error", err, "subscriberId", subscriber.Id)
+				continue
+			}
+
+			if err := s.sends.MarkQueued(ctx, send.Id, subscriber.Id); err != nil {
+				return err
+			}
+		}
+
+		if send.FailedCount > 0 {
+			if err := s.sends.SetFailed(ctx, send.Id, send.FailedCount); err != nil {
+				return err
+			}
+			return fmt.Errorf("%d of the newsletter digest's copies could not be queued", send.FailedCount)
+		}
+	}
+
+	finishedAt := s.now().UTC()
+	if err := s.sends.Finish(ctx, send.Id, finishedAt); err != nil {
+		return err
+	}
+	send.FinishedAt = sql.NullTime{Time: finishedAt, Valid: true}
+
+	return nil
+}
+
+// digestPosts returns the posts published after since and up to until, newest
+// first. The window is applied in the query: posts published after until,
+// as by the time a send is resumed, must not crowd the period's out.
+func (s *NewsletterService) digestPosts(ctx context.Context, since, until time.Time) ([]newsletter.DigestPost, error) {
+	published, err := s.posts.FindPublishedBetween(ctx, since, until, maxDigestPosts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []newsletter.DigestPost{}
+	for _, post := range published {
+		result = append(result, newsletter.DigestPost{
+			Title:       post.Title,
+			URL:         fmt.Sprintf("%s/blog/%s", s.baseURL, post.Slug),
+			Excerpt:     post.Excerpt,
+			PublishedAt: post.PublishedAt.Time,
+		})
+	}
+
+	return result, nil
+}
+
+// SendDigest is SendDigestIfDue for the job queue, which only needs to know
+// whether it failed.
+func (s *NewsletterService) SendDigest(ctx context.Context) error {
+	send, err := s.SendDigestIfDue(ctx)
+	if err != nil {
+		return err
+	}
+
+	if send != nil {
+		slog.InfoContext(ctx, "Sent the newsletter digest", "posts", send.PostCount, "recipients", send.RecipientCount)
+	}
+	return nil
+}
+
+// PurgePending drops the sign ups nobody confirmed within pendingRetention.
+func (s *NewsletterService) PurgePending(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "NewsletterService.PurgePending")
+	defer span.End()
+
+	deleted, err := s.subscribers.DeletePendingBefore(ctx, s.now().Add(-pendingRetention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Purged unconfirmed newsletter sign ups", "count", deleted)
+	}
+	return nil
+}
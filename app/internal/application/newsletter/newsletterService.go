@@ -0,0 +1,269 @@
+package newsletter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/domain/newsletter"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+	"server/util/ctxutils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid or expired confirmation token")
+	ErrInvalidSignature = errors.New("invalid unsubscribe signature")
+	ErrNotFound         = errors.New("subscriber not found")
+)
+
+type subscriberRepository interface {
+	FindById(ctx context.Context, id uuid.UUID) (*newsletter.Subscriber, error)
+	FindByEmail(ctx context.Context, email string) (*newsletter.Subscriber, error)
+	Create(ctx context.Context, subscriber newsletter.Subscriber, tokenHash string) error
+	ResetPending(ctx context.Context, id uuid.UUID, tokenHash string) error
+	Confirm(ctx context.Context, tokenHash string) (*newsletter.Subscriber, error)
+	Unsubscribe(ctx context.Context, id uuid.UUID) (bool, error)
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+	DeletePendingBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	FindByStatus(ctx context.Context, status newsletter.Status, limit, offset int) ([]newsletter.Subscriber, int, error)
+	AddConsent(ctx context.Context, consent newsletter.Consent) error
+	FindConsents(ctx context.Context, subscriberId uuid.UUID) ([]newsletter.Consent, error)
+}
+
+type newsletterMailer interface {
+	SendNewsletterConfirmation(ctx context.Context, toEmail, token string) error
+	SendNewsletterDigest(ctx context.Context, toEmail, subject string, posts []newsletter.DigestPost, unsubscribeLink string) error
+}
+
+// SubscriberPage is one page of subscribers and the total in that status.
+type SubscriberPage struct {
+	Subscribers []newsletter.Subscriber
+	Total       int
+}
+
+// NewsletterService signs readers up with double opt in and lets them leave
+// with one click. Sending the digest is in digest.go.
+type NewsletterService struct {
+	subscribers subscriberRepository
+	sends       sendRepository
+	posts       postSource
+	mailer      newsletterMailer
+	baseURL     string
+	key         []byte
+	now         func() time.Time
+}
+
+func NewNewsletterService(subscribers subscriberRepository, sends sendRepository, posts postSource, mailer newsletterMailer) *NewsletterService {
+	return &NewsletterService{
+		subscribers: subscribers,
+		sends:       sends,
+		posts:       posts,
+		mailer:      mailer,
+		baseURL:     config.BaseURL(),
+		key:         signingKey(),
+		now:         time.Now,
+	}
+}
+
+// Subscribe records the sign up and mails a confirmation link. Nothing is sent
+// until the link is followed. The outcome is the same for new, pending and
+// already confirmed addresses, so the form cannot be used to find out who is
+// subscribed.
+func (s *NewsletterService) Subscribe(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Subscribe")
+	defer span.End()
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	existing, err := s.subscribers.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if existing != nil && existing.Status == newsletter.StatusConfirmed {
+		slog.InfoContext(ctx, "Newsletter sign up for a confirmed subscriber", "subscriberId", existing.Id)
+		return nil
+	}
+
+	plainToken, tokenHash, err := user.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	subscriberId := uuid.New()
+	if existing != nil {
+		subscriberId = existing.Id
+		err = s.subscribers.ResetPending(ctx, subscriberId, tokenHash)
+	} else {
+		err = s.subscribers.Create(ctx, newsletter.Subscriber{Id: subscriberId, Email: email, CreatedAt: s.now().UTC()}, tokenHash)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.recordConsent(ctx, subscriberId, newsletter.ConsentSubscribe, newsletter.ConsentText)
+
+	if err := s.mailer.SendNewsletterConfirmation(ctx, email, plainToken); err != nil {
+		// The sign up stands; signing up again sends a new link.
+		slog.ErrorContext(ctx, "Failed to send newsletter confirmation", "error", err, "subscriberId", subscriberId)
+	}
+
+	return nil
+}
+
+// Confirm consumes the link from the confirmation email.
+func (s *NewsletterService) Confirm(ctx context.Context, plainToken string) error {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Confirm")
+	defer span.End()
+
+	subscriber, err := s.subscribers.Confirm(ctx, user.HashToken(plainToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	s.recordConsent(ctx, subscriber.Id, newsletter.ConsentConfirm, "")
+	slog.InfoContext(ctx, "Newsletter subscription confirmed", "subscriberId", subscriber.Id)
+
+	return nil
+}
+
+// Unsubscribe ends the subscription named by a signed link. Following the link
+// again is harmless.
+func (s *NewsletterService) Unsubscribe(ctx context.Context, subscriberId, signature string) error {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Unsubscribe")
+	defer span.End()
+
+	id, err := uuid.Parse(subscriberId)
+	if err != nil || !s.validSignature(id, signature) {
+		return ErrInvalidSignature
+	}
+
+	changed, err := s.subscribers.Unsubscribe(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		s.recordConsent(ctx, id, newsletter.ConsentUnsubscribe, "")
+		slog.InfoContext(ctx, "Newsletter subscription ended", "subscriberId", id)
+	}
+
+	return nil
+}
+
+// ValidUnsubscribeLink reports whether the link's parameters were signed by
+// us, so the page can say so before anything is posted.
+func (s *NewsletterService) ValidUnsubscribeLink(subscriberId, signature string) bool {
+	id, err := uuid.Parse(subscriberId)
+
+	return err == nil && s.validSignature(id, signature)
+}
+
+// UnsubscribeLink is the subscriber's personal unsubscribe URL. It never
+// expires: it must keep working in every email they ever received.
+func (s *NewsletterService) UnsubscribeLink(subscriberId uuid.UUID) string {
+	return fmt.Sprintf("%s/newsletter/unsubscribe?s=%s&sig=%s", s.baseURL, subscriberId, s.sign(subscriberId))
+}
+
+func (s *NewsletterService) Subscribers(ctx context.Context, status newsletter.Status, page, pageSize int) (SubscriberPage, error) {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Subscribers")
+	defer span.End()
+
+	subscribers, total, err := s.subscribers.FindByStatus(ctx, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return SubscriberPage{}, err
+	}
+
+	return SubscriberPage{Subscribers: subscribers, Total: total}, nil
+}
+
+// Subscriber returns one subscriber with their consent history.
+func (s *NewsletterService) Subscriber(ctx context.Context, id uuid.UUID) (*newsletter.Subscriber, []newsletter.Consent, error) {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Subscriber")
+	defer span.End()
+
+	subscriber, err := s.subscribers.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	consents, err := s.subscribers.FindConsents(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return subscriber, consents, nil
+}
+
+// Delete erases a subscriber and their consent history. It answers an erasure
+// request; a reader who only wants no more email should unsubscribe, which
+// keeps the record of their consent.
+func (s *NewsletterService) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "NewsletterService.Delete")
+	defer span.End()
+
+	deleted, err := s.subscribers.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// recordConsent is best effort: the subscription change it documents has
+// already happened and must not be undone by a failed insert.
+func (s *NewsletterService) recordConsent(ctx context.Context, subscriberId uuid.UUID, action newsletter.ConsentAction, text string) {
+	client := ctxutils.ClientFromContext(ctx)
+
+	err := s.subscribers.AddConsent(ctx, newsletter.Consent{
+		Id:           uuid.New(),
+		SubscriberId: subscriberId,
+		Action:       action,
+		ConsentText:  text,
+		IP:           client.IP,
+		UserAgent:    client.UserAgent,
+		CreatedAt:    s.now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record newsletter consent", "error", err, "subscriberId", subscriberId, "action", action)
+	}
+}
+
+func (s *NewsletterService) sign(subscriberId uuid.UUID) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(subscriberId[:])
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *NewsletterService) validSignature(subscriberId uuid.UUID, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(s.sign(subscriberId)))
+}
+
+// signingKey derives a key of its own from the XSRF secret, so unsubscribe
+// links share no MAC with anything else signed with it.
+func signingKey() []byte {
+	key := sha256.Sum256([]byte("newsletter-unsubscribe\x00" + config.XSRFKey()))
+
+	return key[:]
+}
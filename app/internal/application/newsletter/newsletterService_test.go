@@ -0,0 +1,461 @@
+package newsletter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/domain/newsletter"
+	"server/internal/domain/posts"
+
+	"github.com/google/uuid"
+)
+
+type stubSubscribers struct {
+	byId     map[uuid.UUID]*newsletter.Subscriber
+	tokens   map[string]uuid.UUID
+	consents []newsletter.Consent
+}
+
+func newStubSubscribers() *stubSubscribers {
+	return &stubSubscribers{byId: map[uuid.UUID]*newsletter.Subscriber{}, tokens: map[string]uuid.UUID{}}
+}
+
+func (s *stubSubscribers) FindById(_ context.Context, id uuid.UUID) (*newsletter.Subscriber, error) {
+	if subscriber, ok := s.byId[id]; ok {
+		return subscriber, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubSubscribers) FindByEmail(_ context.Context, email string) (*newsletter.Subscriber, error) {
+	for _, subscriber := range s.byId {
+		if subscriber.Email == email {
+			return subscriber, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubSubscribers) Create(_ context.Context, subscriber newsletter.Subscriber, tokenHash string) error {
+	subscriber.Status = newsletter.StatusPending
+	s.byId[subscriber.Id] = &subscriber
+	s.tokens[tokenHash] = subscriber.Id
+	return nil
+}
+
+func (s *stubSubscribers) ResetPending(_ context.Context, id uuid.UUID, tokenHash string) error {
+	s.byId[id].Status = newsletter.StatusPending
+	s.tokens[tokenHash] = id
+	return nil
+}
+
+func (s *stubSubscribers) Confirm(_ context.Context, tokenHash string) (*newsletter.Subscriber, error) {
+	id, ok := s.tokens[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delete(s.tokens, tokenHash)
+	s.byId[id].Status = newsletter.StatusConfirmed
+	return s.byId[id], nil
+}
+
+func (s *stubSubscribers) Unsubscribe(_ context.Context, id uuid.UUID) (bool, error) {
+	subscriber, ok := s.byId[id]
+	if !ok || subscriber.Status == newsletter.StatusUnsubscribed {
+		return false, nil
+	}
+	subscriber.Status = newsletter.StatusUnsubscribed
+	return true, nil
+}
+
+func (s *stubSubscribers) Delete(context.Context, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (s *stubSubscribers) DeletePendingBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *stubSubscribers) FindByStatus(context.Context, newsletter.Status, int, int) ([]newsletter.Subscriber, int, error) {
+	return nil, 0, nil
+}
+
+func (s *stubSubscribers) AddConsent(_ context.Context, consent newsletter.Consent) error {
+	s.consents = append(s.consents, consent)
+	return nil
+}
+
+func (s *stubSubscribers) FindConsents(context.Context, uuid.UUID) ([]newsletter.Consent, error) {
+	return s.consents, nil
+}
+
+// stubSends keeps each send's recipients in the order they subscribed, and
+// which of them have been queued.
+type stubSends struct {
+	subscribers *stubSubscribers
+	created     []newsletter.Send
+	recipients  map[uuid.UUID][]uuid.UUID
+	queued      map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func (s *stubSends) Start(_ context.Context, send newsletter.Send) (int, error) {
+	for _, existing := range s.created {
+		if existing.PeriodStart.Equal(send.PeriodStart) {
+			return 0, nil
+		}
+	}
+
+	recipients := []uuid.UUID{}
+	for _, subscriber := range s.subscribers.byId {
+		if subscriber.Status == newsletter.StatusConfirmed {
+			recipients = append(recipients, subscriber.Id)
+		}
+	}
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+	slices.SortFunc(recipients, func(a, b uuid.UUID) int {
+		return strings.Compare(s.subscribers.byId[a].Email, s.subscribers.byId[b].Email)
+	})
+
+	send.RecipientCount = len(recipients)
+	s.created = append(s.created, send)
+	s.recipients[send.Id] = recipients
+	s.queued[send.Id] = map[uuid.UUID]bool{}
+	return len(recipients), nil
+}
+
+func (s *stubSends) PendingRecipients(_ context.Context, sendId uuid.UUID) ([]newsletter.Subscriber, error) {
+	pending := []newsletter.Subscriber{}
+	for _, id := range s.recipients[sendId] {
+		if subscriber := s.subscribers.byId[id]; !s.queued[sendId][id] && subscriber.Status == newsletter.StatusConfirmed {
+			pending = append(pending, *subscriber)
+		}
+	}
+	return pending, nil
+}
+
+func (s *stubSends) MarkQueued(_ context.Context, sendId, subscriberId uuid.UUID) error {
+	s.queued[sendId][subscriberId] = true
+	return nil
+}
+
+func (s *stubSends) SetFailed(_ context.Context, sendId uuid.UUID, failed int) error {
+	s.find(sendId).FailedCount = failed
+	return nil
+}
+
+func (s *stubSends) Finish(_ context.Context, sendId uuid.UUID, at time.Time) error {
+	send := s.find(sendId)
+	send.FailedCount = 0
+	send.FinishedAt = sql.NullTime{Time: at, Valid: true}
+	return nil
+}
+
+func (s *stubSends) find(id uuid.UUID) *newsletter.Send {
+	for i := range s.created {
+		if s.created[i].Id == id {
+			return &s.created[i]
+		}
+	}
+	return nil
+}
+
+func (s *stubSends) Latest(context.Context) (*newsletter.Send, error) {
+	if len(s.created) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	latest := s.created[len(s.created)-1]
+	return &latest, nil
+}
+
+func (s *stubSends) FindAll(context.Context, int, int) ([]newsletter.Send, int, error) {
+	return s.created, len(s.created), nil
+}
+
+// stubPosts answers like the query: the window, newest first, up to limit.
+type stubPosts struct {
+	published []posts.PostWithAuthor
+}
+
+func (s *stubPosts) FindPublishedBetween(_ context.Context, since, until time.Time, limit int) ([]posts.PostWithAuthor, error) {
+	result := []posts.PostWithAuthor{}
+	for _, post := range s.published {
+		if post.PublishedAt.Time.After(since) && !post.PublishedAt.Time.After(until) {
+			result = append(result, post)
+		}
+	}
+	slices.SortFunc(result, func(a, b posts.PostWithAuthor) int {
+		return b.PublishedAt.Time.Compare(a.PublishedAt.Time)
+	})
+	return result[:min(limit, len(result))], nil
+}
+
+type digestMail struct {
+	to          string
+	posts       []newsletter.DigestPost
+	unsubscribe string
+}
+
+type stubMailer struct {
+	confirmations map[string]string
+	digests       []digestMail
+	// failFor refuses to queue a digest to these addresses.
+	failFor map[string]bool
+}
+
+func (m *stubMailer) SendNewsletterConfirmation(_ context.Context, toEmail, token string) error {
+	m.confirmations[toEmail] = token
+	return nil
+}
+
+func (m *stubMailer) SendNewsletterDigest(_ context.Context, toEmail, _ string, posts []newsletter.DigestPost, unsubscribeLink string) error {
+	if m.failFor[toEmail] {
+		return errors.New("outbox unavailable")
+	}
+	m.digests = append(m.digests, digestMail{toEmail, posts, unsubscribeLink})
+	return nil
+}
+
+func publishedAt(slug string, at time.Time) posts.PostWithAuthor {
+	return posts.PostWithAuthor{Post: posts.Post{Title: slug, Slug: slug, PublishedAt: sql.NullTime{Time: at, Valid: true}}}
+}
+
+func newTestService(now time.Time) (*NewsletterService, *stubSubscribers, *stubSends, *stubPosts, *stubMailer) {
+	subscribers := newStubSubscribers()
+	sends := &stubSends{subscribers: subscribers, recipients: map[uuid.UUID][]uuid.UUID{}, queued: map[uuid.UUID]map[uuid.UUID]bool{}}
+	source := &stubPosts{}
+	mailer := &stubMailer{confirmations: map[string]string{}}
+
+	return &NewsletterService{
+		subscribers: subscribers,
+		sends:       sends,
+		posts:       source,
+		mailer:      mailer,
+		baseURL:     "https://example.com",
+		key:         []byte("test key"),
+		now:         func() time.Time { return now },
+	}, subscribers, sends, source, mailer
+}
+
+// Nobody gets the digest on someone else's say-so: a sign up only mails a
+// confirmation link, and the address joins the list once it is followed.
+func TestSubscribe_DigestOnlyAfterConfirmation(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service, subscribers, _, source, mailer := newTestService(now)
+	source.published = []posts.PostWithAuthor{publishedAt("new-trail", now.Add(-time.Hour))}
+	ctx := context.Background()
+
+	if err := service.Subscribe(ctx, " Reader@Example.com "); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	token, ok := mailer.confirmations["reader@example.com"]
+	if !ok {
+		t.Fatalf("expected a confirmation mailed to the normalised address, got %v", mailer.confirmations)
+	}
+
+	if send, err := service.SendDigestIfDue(ctx); err != nil || send != nil {
+		t.Fatalf("expected no digest before confirmation, got %+v, %v", send, err)
+	}
+
+	if err := service.Confirm(ctx, token); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if err := service.Confirm(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a used token to be refused, got %v", err)
+	}
+
+	send, err := service.SendDigestIfDue(ctx)
+	if err != nil || send == nil {
+		t.Fatalf("expected a digest after confirmation, got %+v, %v", send, err)
+	}
+	if len(mailer.digests) != 1 || mailer.digests[0].to != "reader@example.com" {
+		t.Fatalf("expected one digest to the subscriber, got %+v", mailer.digests)
+	}
+
+	if len(subscribers.consents) != 2 || subscribers.consents[0].ConsentText != newsletter.ConsentText {
+		t.Fatalf("expected the sign up with its wording and the confirmation on record, got %+v", subscribers.consents)
+	}
+}
+
+// Signing up an address that is already confirmed must not send it mail or
+// reset it to pending; otherwise anyone could knock a reader off the list.
+func TestSubscribe_ConfirmedAddressIsLeftAlone(t *testing.T) {
+	service, subscribers, _, _, mailer := newTestService(time.Now())
+	id := uuid.New()
+	subscribers.byId[id] = &newsletter.Subscriber{Id: id, Email: "reader@example.com", Status: newsletter.StatusConfirmed}
+
+	if err := service.Subscribe(context.Background(), "reader@example.com"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if len(mailer.confirmations) != 0 || subscribers.byId[id].Status != newsletter.StatusConfirmed {
+		t.Fatalf("expected nothing to change, got status %s and mails %v", subscribers.byId[id].Status, mailer.confirmations)
+	}
+}
+
+// The signature is all that authorises an unsubscribe, so it must not carry
+// over to another subscriber, or come from another key.
+func TestUnsubscribe_RequiresTheSubscribersSignature(t *testing.T) {
+	service, subscribers, _, _, _ := newTestService(time.Now())
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	subscribers.byId[alice] = &newsletter.Subscriber{Id: alice, Status: newsletter.StatusConfirmed}
+	subscribers.byId[bob] = &newsletter.Subscriber{Id: bob, Status: newsletter.StatusConfirmed}
+
+	other, _, _, _, _ := newTestService(time.Now())
+	other.key = []byte("another key")
+
+	for name, signature := range map[string]string{
+		"another subscriber's": service.sign(alice),
+		"another key's":        other.sign(bob),
+		"empty":                "",
+	} {
+		if err := service.Unsubscribe(ctx, bob.String(), signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected %s signature to be refused, got %v", name, err)
+		}
+	}
+	if subscribers.byId[bob].Status != newsletter.StatusConfirmed {
+		t.Fatalf("expected bob to stay subscribed")
+	}
+
+	if err := service.Unsubscribe(ctx, bob.String(), service.sign(bob)); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if err := service.Unsubscribe(ctx, bob.String(), service.sign(bob)); err != nil {
+		t.Fatalf("expected a second click to be harmless, got %v", err)
+	}
+	if subscribers.byId[bob].Status != newsletter.StatusUnsubscribed || len(subscribers.consents) != 1 {
+		t.Fatalf("expected one recorded unsubscribe, got status %s and %d consents", subscribers.byId[bob].Status, len(subscribers.consents))
+	}
+}
+
+// Each digest covers the posts since the previous one ended, and none goes out
+// until a full period has passed.
+func TestSendDigestIfDue_PicksUpWhereTheLastEnded(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	now := start
+	service, subscribers, sends, source, mailer := newTestService(start)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	id := uuid.New()
+	subscribers.byId[id] = &newsletter.Subscriber{Id: id, Email: "reader@example.com", Status: newsletter.StatusConfirmed}
+
+	source.published = []posts.PostWithAuthor{
+		publishedAt("old", start.Add(-8*24*time.Hour)),
+		publishedAt("first", start.Add(-time.Hour)),
+	}
+	if _, err := service.SendDigestIfDue(ctx); err != nil {
+		t.Fatalf("SendDigestIfDue: %v", err)
+	}
+	if len(mailer.digests) != 1 || len(mailer.digests[0].posts) != 1 || mailer.digests[0].posts[0].URL != "https://example.com/blog/first" {
+		t.Fatalf("expected only the post from the last week, got %+v", mailer.digests)
+	}
+	if mailer.digests[0].unsubscribe != service.UnsubscribeLink(id) {
+		t.Fatalf("expected the subscriber's own unsubscribe link, got %q", mailer.digests[0].unsubscribe)
+	}
+
+	source.published = append([]posts.PostWithAuthor{publishedAt("second", start.Add(time.Hour))}, source.published...)
+
+	now = start.Add(DigestPeriod - time.Minute)
+	if send, _ := service.SendDigestIfDue(ctx); send != nil {
+		t.Fatalf("expected no digest before the period is up, got %+v", send)
+	}
+
+	now = start.Add(DigestPeriod)
+	send, err := service.SendDigestIfDue(ctx)
+	if err != nil || send == nil {
+		t.Fatalf("expected a second digest, got %+v, %v", send, err)
+	}
+	if !send.PeriodStart.Equal(start) || len(mailer.digests[1].posts) != 1 || mailer.digests[1].posts[0].Title != "second" {
+		t.Fatalf("expected the second digest to start where the first ended, got %+v and %+v", send, mailer.digests[1].posts)
+	}
+	if len(sends.created) != 2 {
+		t.Fatalf("expected both sends recorded, got %d", len(sends.created))
+	}
+}
+
+// A digest cut short is finished by the next run for whoever is left: the
+// readers who already have it do not get it again, and no second send starts
+// for the same week.
+func TestSendDigestIfDue_ResumesForTheRemainingRecipients(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service, subscribers, sends, source, mailer := newTestService(now)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		id := uuid.New()
+		subscribers.byId[id] = &newsletter.Subscriber{Id: id, Email: email, Status: newsletter.StatusConfirmed}
+	}
+	source.published = []posts.PostWithAuthor{publishedAt("new-trail", now.Add(-time.Hour))}
+
+	mailer.failFor = map[string]bool{"b@example.com": true}
+	if _, err := service.SendDigestIfDue(ctx); err == nil {
+		t.Fatalf("expected the failed copy to fail the run so it is retried")
+	}
+	if len(sends.created) != 1 || sends.created[0].FinishedAt.Valid || sends.created[0].FailedCount != 1 {
+		t.Fatalf("expected one unfinished send with one failure, got %+v", sends.created)
+	}
+
+	mailer.failFor = nil
+	send, err := service.SendDigestIfDue(ctx)
+	if err != nil || send == nil || !send.FinishedAt.Valid {
+		t.Fatalf("expected the retry to finish the send, got %+v, %v", send, err)
+	}
+
+	sent := []string{}
+	for _, digest := range mailer.digests {
+		sent = append(sent, digest.to)
+	}
+	if !slices.Equal(sent, []string{"a@example.com", "c@example.com", "b@example.com"}) {
+		t.Fatalf("expected each reader to get one copy, got %v", sent)
+	}
+	if len(sends.created) != 1 || sends.created[0].FailedCount != 0 {
+		t.Fatalf("expected the same send to be finished, got %+v", sends.created)
+	}
+
+	if send, err := service.SendDigestIfDue(ctx); err != nil || send != nil || len(mailer.digests) != 3 {
+		t.Fatalf("expected nothing more once it finished, got %+v, %v", send, err)
+	}
+}
+
+// A send resumed days late still covers its own week: the posts published
+// since must not take the places of the ones it was for.
+func TestSendDigestIfDue_ResumedSendKeepsItsOwnPosts(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	now := start
+	service, subscribers, sends, source, mailer := newTestService(start)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	id := uuid.New()
+	subscribers.byId[id] = &newsletter.Subscriber{Id: id, Email: "reader@example.com", Status: newsletter.StatusConfirmed}
+	source.published = []posts.PostWithAuthor{publishedAt("in-window", start.Add(-time.Hour))}
+
+	mailer.failFor = map[string]bool{"reader@example.com": true}
+	if _, err := service.SendDigestIfDue(ctx); err == nil {
+		t.Fatalf("expected the failed copy to leave the send unfinished")
+	}
+
+	now = start.Add(3 * 24 * time.Hour)
+	for i := range maxDigestPosts + 2 {
+		source.published = append(source.published, publishedAt(fmt.Sprintf("later-%d", i), start.Add(time.Duration(i+1)*time.Hour)))
+	}
+
+	mailer.failFor = nil
+	send, err := service.SendDigestIfDue(ctx)
+	if err != nil || send == nil || send.Id != sends.created[0].Id {
+		t.Fatalf("expected the first send to be resumed, got %+v, %v", send, err)
+	}
+	if len(mailer.digests) != 1 || len(mailer.digests[0].posts) != 1 || mailer.digests[0].posts[0].Title != "in-window" {
+		t.Fatalf("expected the resumed digest to carry its own week's post, got %+v", mailer.digests)
+	}
+}
@@ -0,0 +1,196 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/outbox"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sendTimeout bounds one delivery, however slow the mail server.
+	sendTimeout = 2 * time.Minute
+
+	// lease is how long a claimed message is left alone. It must outlast the
+	// delivery and the recording of it, or a second worker would send the
+	// message again. Messages are claimed one at a time for the same reason:
+	// a batch sent one after another would outlive a lease sized for one.
+	lease = sendTimeout + time.Minute
+
+	// The delay after a failed attempt doubles from firstRetryDelay up to
+	// maxRetryDelay, so a mail server that is down for an hour is not hammered
+	// and one that is down for a day is still retried.
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = 6 * time.Hour
+
+	// sentRetention is how long the record of a delivered message is kept.
+	sentRetention = 30 * 24 * time.Hour
+)
+
+var ErrNotFound = errors.New("no dead email with that id")
+
+type messageRepository interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Message, error)
+	MarkSent(ctx context.Context, id uuid.UUID) error
+	MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttemptAt time.Time) error
+	MarkDead(ctx context.Context, id uuid.UUID, lastError string) error
+	Retry(ctx context.Context, id uuid.UUID) (bool, error)
+	FindFailures(ctx context.Context, limit, offset int) ([]outbox.Message, int, error)
+	DeleteSentBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type mailSender interface {
+	Send(ctx context.Context, message outbox.Message) error
+}
+
+// OutboxService delivers the queued email in the background and keeps track
+// of what could not be delivered.
+type OutboxService struct {
+	messages    messageRepository
+	sender      mailSender
+	maxAttempts int
+	now         func() time.Time
+}
+
+func NewOutboxService(messages messageRepository, sender mailSender, maxAttempts int) *OutboxService {
+	return &OutboxService{
+		messages:    messages,
+		sender:      sender,
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+	}
+}
+
+// DeliverDue claims the next due message and tries it once. It reports
+// whether there was one, so the caller knows whether more may be waiting.
+func (s *OutboxService) DeliverDue(ctx context.Context) (bool, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.DeliverDue")
+	defer span.End()
+
+	messages, err := s.messages.Claim(ctx, 1, lease)
+	if err != nil {
+		return false, err
+	}
+
+	for _, message := range messages {
+		s.deliver(ctx, message)
+	}
+
+	return len(messages) > 0, nil
+}
+
+func (s *OutboxService) deliver(ctx context.Context, message outbox.Message) {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	sendErr := s.sender.Send(sendCtx, message)
+	cancel()
+
+	var err error
+	switch {
+	case sendErr == nil:
+		err = s.messages.MarkSent(ctx, message.Id)
+	case message.Attempts >= s.maxAttempts:
+		slog.ErrorContext(ctx, "Giving up on an email", "error", sendErr, "messageId", message.Id, "attempts", message.Attempts)
+		err = s.messages.MarkDead(ctx, message.Id, sendErr.Error())
+	default:
+		slog.WarnContext(ctx, "Failed to send an email, will retry", "error", sendErr, "messageId", message.Id, "attempts", message.Attempts)
+		err = s.messages.MarkFailed(ctx, message.Id, sendErr.Error(), s.now().Add(retryDelay(message.Attempts)))
+	}
+
+	// The lease still guards the message, so a lost update only means it is
+	// tried again once the lease runs out.
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record an email delivery attempt", "error", err, "messageId", message.Id)
+	}
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// MessagePage is one page of undelivered messages and their total.
+type MessagePage struct {
+	Messages []outbox.Message
+	Total    int
+}
+
+// Failures lists the messages that are dead or waiting to be retried.
+func (s *OutboxService) Failures(ctx context.Context, page, pageSize int) (MessagePage, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.Failures")
+	defer span.End()
+
+	messages, total, err := s.messages.FindFailures(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return MessagePage{}, err
+	}
+
+	return MessagePage{Messages: messages, Total: total}, nil
+}
+
+// Retry queues a dead message again, for when the cause has been fixed.
+func (s *OutboxService) Retry(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OutboxService.Retry")
+	defer span.End()
+
+	retried, err := s.messages.Retry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !retried {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Run delivers due messages every interval until ctx is cancelled. Each one
+// is followed straight away by the next, so a backlog drains without waiting
+// out the interval between messages.
+func (s *OutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			claimed, err := s.DeliverDue(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to claim queued emails", "error", err)
+			}
+			if err != nil || !claimed || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeSent deletes the record of delivered messages past sentRetention.
+func (s *OutboxService) PurgeSent(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "OutboxService.PurgeSent")
+	defer span.End()
+
+	purged, err := s.messages.DeleteSentBefore(ctx, s.now().Add(-sentRetention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		slog.InfoContext(ctx, "Purged sent emails", "count", purged)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/domain/outbox"
+
+	"github.com/google/uuid"
+)
+
+type outcome struct {
+	status        outbox.Status
+	lastError     string
+	nextAttemptAt time.Time
+}
+
+type stubMessages struct {
+	due      []outbox.Message
+	outcomes map[uuid.UUID]outcome
+	lease    time.Duration
+}
+
+func (s *stubMessages) Claim(_ context.Context, limit int, lease time.Duration) ([]outbox.Message, error) {
+	s.lease = lease
+	claimed := s.due[:min(limit, len(s.due))]
+	s.due = s.due[len(claimed):]
+	return claimed, nil
+}
+
+func (s *stubMessages) MarkSent(_ context.Context, id uuid.UUID) error {
+	s.outcomes[id] = outcome{status: outbox.StatusSent}
+	return nil
+}
+
+func (s *stubMessages) MarkFailed(_ context.Context, id uuid.UUID, lastError string, nextAttemptAt time.Time) error {
+	s.outcomes[id] = outcome{status: outbox.StatusPending, lastError: lastError, nextAttemptAt: nextAttemptAt}
+	return nil
+}
+
+func (s *stubMessages) MarkDead(_ context.Context, id uuid.UUID, lastError string) error {
+	s.outcomes[id] = outcome{status: outbox.StatusDead, lastError: lastError}
+	return nil
+}
+
+func (s *stubMessages) Retry(context.Context, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (s *stubMessages) FindFailures(context.Context, int, int) ([]outbox.Message, int, error) {
+	return nil, 0, nil
+}
+
+func (s *stubMessages) DeleteSentBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// failingSender refuses mail to the listed recipients.
+type failingSender struct {
+	refuse map[string]bool
+	// budget is the longest a send was allowed to take.
+	budget time.Duration
+}
+
+func (f *failingSender) Send(ctx context.Context, message outbox.Message) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		f.budget = max(f.budget, time.Until(deadline))
+	}
+	if f.refuse[message.Recipient] {
+		return errors.New("421 service not available")
+	}
+	return nil
+}
+
+// A failed attempt is retried later, and the attempt that reaches the limit
+// gives up, while the rest of the queue is delivered regardless.
+func TestDeliverDue_RetriesThenGivesUp(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	delivered, retried, dead := uuid.New(), uuid.New(), uuid.New()
+
+	messages := &stubMessages{
+		due: []outbox.Message{
+			{Id: delivered, Recipient: "ok@example.com", Attempts: 1},
+			{Id: retried, Recipient: "down@example.com", Attempts: 3},
+			{Id: dead, Recipient: "down@example.com", Attempts: 5},
+		},
+		outcomes: map[uuid.UUID]outcome{},
+	}
+	service := NewOutboxService(messages, &failingSender{refuse: map[string]bool{"down@example.com": true}}, 5)
+	service.now = func() time.Time { return now }
+
+	for range 3 {
+		if claimed, err := service.DeliverDue(context.Background()); err != nil || !claimed {
+			t.Fatalf("DeliverDue() = %v, %v", claimed, err)
+		}
+	}
+	if claimed, err := service.DeliverDue(context.Background()); err != nil || claimed {
+		t.Fatalf("DeliverDue() on an empty queue = %v, %v", claimed, err)
+	}
+
+	if got := messages.outcomes[delivered]; got.status != outbox.StatusSent {
+		t.Errorf("delivered message ended %+v", got)
+	}
+	if got := messages.outcomes[retried]; got.status != outbox.StatusPending || !got.nextAttemptAt.Equal(now.Add(4*time.Minute)) || got.lastError == "" {
+		t.Errorf("failed message ended %+v, want a retry in 4 minutes with the error", got)
+	}
+	if got := messages.outcomes[dead]; got.status != outbox.StatusDead || got.lastError == "" {
+		t.Errorf("message out of attempts ended %+v, want dead with the error", got)
+	}
+}
+
+// A message is claimed alone and its send cut short within the lease, so it
+// is never claimed again while it may still be going out.
+func TestDeliverDue_SendFitsInTheLease(t *testing.T) {
+	messages := &stubMessages{
+		due:      []outbox.Message{{Id: uuid.New(), Recipient: "a@example.com"}, {Id: uuid.New(), Recipient: "b@example.com"}},
+		outcomes: map[uuid.UUID]outcome{},
+	}
+	sender := &failingSender{}
+	service := NewOutboxService(messages, sender, 5)
+
+	if _, err := service.DeliverDue(context.Background()); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	if len(messages.outcomes) != 1 {
+		t.Errorf("one call sent %d messages, want 1", len(messages.outcomes))
+	}
+	if sender.budget <= 0 || sender.budget >= messages.lease {
+		t.Errorf("send allowed %v under a lease of %v", sender.budget, messages.lease)
+	}
+}
+
+// The delay doubles per failure but never exceeds the cap, however many
+// attempts an administrator's retries add up to.
+func TestRetryDelay_DoublesUpToTheCap(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:   time.Minute,
+		2:   2 * time.Minute,
+		5:   16 * time.Minute,
+		9:   4*time.Hour + 16*time.Minute,
+		10:  maxRetryDelay,
+		500: maxRetryDelay,
+	} {
+		if got := retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package posts
+
+import (
+	"context"
+	"errors"
+
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+// ErrForbidden means the actor's permissions do not cover the change.
+var ErrForbidden = errors.New("not permitted to change this post")
+
+// Actor is whoever is changing posts, reduced to what the rules below need.
+type Actor struct {
+	Id          uuid.UUID
+	Permissions []user.Permission
+}
+
+func (a Actor) can(permission string) bool {
+	return user.HasPermission(a.Permissions, permission)
+}
+
+// CanEdit reports whether the actor may edit the post: posts:edit covers every
+// post, posts:edit-own only those the actor created.
+func (a Actor) CanEdit(post *posts.Post) bool {
+	if a.can(user.PermPostsEdit) {
+		return true
+	}
+
+	return a.can(user.PermPostsEditOwn) && post.CreatorUserId == a.Id
+}
+
+// AuthorizeCreate checks that the actor may create a post with the given
+// status. Anything that goes live needs posts:publish.
+func AuthorizeCreate(actor Actor, status posts.PostStatus) error {
+	if !actor.can(user.PermPostsCreate) {
+		return ErrForbidden
+	}
+
+	if status == posts.PostStatusPublished && !actor.can(user.PermPostsPublish) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// AuthorizeUpdate checks that the actor may edit the post and leave it in the
+// given status. A post that is live, or would become live, needs
+// posts:publish: editing a published post changes what readers see as surely
+// as publishing it does.
+func (s *PostService) AuthorizeUpdate(ctx context.Context, actor Actor, id uuid.UUID, status posts.PostStatus) error {
+	ctx, span := tracing.Start(ctx, "PostService.AuthorizeUpdate")
+	defer span.End()
+
+	existing, err := s.postRepository.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !actor.CanEdit(existing) {
+		return ErrForbidden
+	}
+
+	live := status == posts.PostStatusPublished || existing.Status == posts.PostStatusPublished
+	if live && !actor.can(user.PermPostsPublish) {
+		return ErrForbidden
+	}
+
+	return nil
+}
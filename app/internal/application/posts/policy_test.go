@@ -0,0 +1,76 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func actorWith(permissions ...string) Actor {
+	actor := Actor{Id: uuid.New()}
+	for _, name := range permissions {
+		actor.Permissions = append(actor.Permissions, user.Permission{Name: name})
+	}
+	return actor
+}
+
+func TestAuthorizeCreate_PublishingNeedsPublishPermission(t *testing.T) {
+	editor := actorWith(user.PermPostsCreate, user.PermPostsEdit)
+
+	if err := AuthorizeCreate(editor, posts.PostStatusDraft); err != nil {
+		t.Errorf("draft by editor: %v", err)
+	}
+
+	if err := AuthorizeCreate(editor, posts.PostStatusPublished); !errors.Is(err, ErrForbidden) {
+		t.Errorf("published by editor: %v, want ErrForbidden", err)
+	}
+
+	admin := actorWith(user.PermPostsCreate, user.PermPostsPublish)
+	if err := AuthorizeCreate(admin, posts.PostStatusPublished); err != nil {
+		t.Errorf("published by admin: %v", err)
+	}
+}
+
+// posts:edit-own is checked against the post's creator, so an author cannot
+// reach another author's work by guessing its id.
+func TestAuthorizeUpdate_AuthorEditsOnlyOwnPosts(t *testing.T) {
+	repo := newMockPostRepository()
+	service := NewPostService(repo)
+	ctx := context.Background()
+
+	author := actorWith(user.PermPostsCreate, user.PermPostsEditOwn)
+	own, _ := repo.Create(ctx, posts.Post{Id: uuid.New(), Slug: "own", Status: posts.PostStatusDraft, CreatorUserId: author.Id})
+	other, _ := repo.Create(ctx, posts.Post{Id: uuid.New(), Slug: "other", Status: posts.PostStatusDraft, CreatorUserId: uuid.New()})
+
+	if err := service.AuthorizeUpdate(ctx, author, own.Id, posts.PostStatusDraft); err != nil {
+		t.Errorf("own post: %v", err)
+	}
+
+	if err := service.AuthorizeUpdate(ctx, author, other.Id, posts.PostStatusDraft); !errors.Is(err, ErrForbidden) {
+		t.Errorf("someone else's post: %v, want ErrForbidden", err)
+	}
+}
+
+// Editing a live post changes what readers see, so it is held to the same
+// rule as publishing even when the status is left alone.
+func TestAuthorizeUpdate_LivePostNeedsPublishPermission(t *testing.T) {
+	repo := newMockPostRepository()
+	service := NewPostService(repo)
+	ctx := context.Background()
+
+	live, _ := repo.Create(ctx, posts.Post{Id: uuid.New(), Slug: "live", Status: posts.PostStatusPublished, CreatorUserId: uuid.New()})
+
+	editor := actorWith(user.PermPostsEdit)
+	if err := service.AuthorizeUpdate(ctx, editor, live.Id, posts.PostStatusPublished); !errors.Is(err, ErrForbidden) {
+		t.Errorf("editor on a live post: %v, want ErrForbidden", err)
+	}
+
+	publisher := actorWith(user.PermPostsEdit, user.PermPostsPublish)
+	if err := service.AuthorizeUpdate(ctx, publisher, live.Id, posts.PostStatusPublished); err != nil {
+		t.Errorf("publisher on a live post: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"regexp"
 	"server/internal/domain/posts"
+	"server/internal/infrastructure/tracing"
 	"strings"
 	"time"
 	"unicode"
@@ -61,6 +62,9 @@ func NewPostService(repo postRepository) *PostService {
 }
 
 func (s *PostService) Create(ctx context.Context, input CreatePostInput, creatorId uuid.UUID) (*posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.Create")
+	defer span.End()
+
 	slug := s.GenerateSlug(input.Title)
 
 	exists, err := s.postRepository.ExistsBySlug(ctx, slug, nil)
@@ -100,6 +104,9 @@ func (s *PostService) Create(ctx context.Context, input CreatePostInput, creator
 }
 
 func (s *PostService) Update(ctx context.Context, id uuid.UUID, input UpdatePostInput, updatedBy string) (*posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.Update")
+	defer span.End()
+
 	existing, err := s.postRepository.FindById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -142,52 +149,85 @@ func (s *PostService) Update(ctx context.Context, id uuid.UUID, input UpdatePost
 }
 
 func (s *PostService) Delete(ctx context.Context, id uuid.UUID, deletedBy string) error {
+	ctx, span := tracing.Start(ctx, "PostService.Delete")
+	defer span.End()
+
 	return s.postRepository.Delete(ctx, id, deletedBy)
 }
 
 func (s *PostService) GetById(ctx context.Context, id uuid.UUID) (*posts.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetById")
+	defer span.End()
+
 	return s.postRepository.FindById(ctx, id)
 }
 
 func (s *PostService) GetBySlug(ctx context.Context, slug string) (*posts.PostWithAuthor, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetBySlug")
+	defer span.End()
+
 	return s.postRepository.FindBySlug(ctx, slug)
 }
 
 func (s *PostService) GetPublished(ctx context.Context, page, pageSize int) ([]posts.PostWithAuthor, int, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPublished")
+	defer span.End()
+
 	offset := (page - 1) * pageSize
 	return s.postRepository.FindPublished(ctx, pageSize, offset)
 }
 
 func (s *PostService) GetByCategory(ctx context.Context, categorySlug string, page, pageSize int) ([]posts.PostWithAuthor, int, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetByCategory")
+	defer span.End()
+
 	offset := (page - 1) * pageSize
 	return s.postRepository.FindByCategory(ctx, categorySlug, pageSize, offset)
 }
 
 func (s *PostService) GetAll(ctx context.Context, page, pageSize int) ([]posts.PostWithAuthor, int, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetAll")
+	defer span.End()
+
 	offset := (page - 1) * pageSize
 	return s.postRepository.FindAll(ctx, pageSize, offset)
 }
 
 func (s *PostService) GetByStatus(ctx context.Context, status posts.PostStatus, page, pageSize int) ([]posts.PostWithAuthor, int, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetByStatus")
+	defer span.End()
+
 	offset := (page - 1) * pageSize
 	return s.postRepository.FindByStatus(ctx, status, pageSize, offset)
 }
 
 // GetSitemapEntries returns just the fields the sitemap needs.
 func (s *PostService) GetSitemapEntries(ctx context.Context) ([]posts.SitemapEntry, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetSitemapEntries")
+	defer span.End()
+
 	return s.postRepository.FindPublishedSitemapEntries(ctx)
 }
 
 // GetCounts returns the dashboard totals in a single query.
 func (s *PostService) GetCounts(ctx context.Context) (posts.PostCounts, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetCounts")
+	defer span.End()
+
 	return s.postRepository.CountByStatus(ctx)
 }
 
 func (s *PostService) GetRecent(ctx context.Context, limit int) ([]posts.PostWithAuthor, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetRecent")
+	defer span.End()
+
 	return s.postRepository.FindRecent(ctx, limit)
 }
 
 func (s *PostService) SearchPublished(ctx context.Context, query string, page, pageSize int) ([]posts.PostWithAuthor, int, error) {
+	ctx, span := tracing.Start(ctx, "PostService.SearchPublished")
+	defer span.End()
+
 	offset := (page - 1) * pageSize
 	return s.postRepository.Search(ctx, query, pageSize, offset)
 }
@@ -212,6 +252,11 @@ func (s *PostService) GenerateSlug(title string) string {
 }
 
 func (s *PostService) CalculateReadingTime(content string) int {
+	return readingTime(content)
+}
+
+// readingTime assumes 200 words a minute and never says less than a minute.
+func readingTime(content string) int {
 	words := len(strings.Fields(content))
 	minutes := words / 200
 	if minutes < 1 {
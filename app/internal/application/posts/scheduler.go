@@ -0,0 +1,90 @@
+package posts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/posts"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPostNotFound       = errors.New("post not found")
+	ErrAlreadyPublished   = errors.New("post is already published")
+	ErrScheduledInThePast = errors.New("scheduled time is in the past")
+)
+
+type scheduleRepository interface {
+	FindBySlug(ctx context.Context, slug string) (*posts.PostWithAuthor, error)
+	Schedule(ctx context.Context, id uuid.UUID, at time.Time, updatedBy string) (bool, error)
+	PublishDue(ctx context.Context, now time.Time) ([]string, error)
+}
+
+// Scheduler publishes posts at a set time. Posts wait as drafts with a
+// scheduled time, and a recurring job publishes the ones that are due.
+type Scheduler struct {
+	posts scheduleRepository
+	now   func() time.Time
+}
+
+func NewScheduler(posts scheduleRepository) *Scheduler {
+	return &Scheduler{posts: posts, now: time.Now}
+}
+
+// Schedule sets the post to be published at the given time, replacing any
+// earlier schedule.
+func (s *Scheduler) Schedule(ctx context.Context, slug string, at time.Time, updatedBy string) error {
+	ctx, span := tracing.Start(ctx, "Scheduler.Schedule")
+	defer span.End()
+
+	if at.Before(s.now()) {
+		return ErrScheduledInThePast
+	}
+
+	post, err := s.posts.FindBySlug(ctx, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPostNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if post.IsPublished() {
+		return ErrAlreadyPublished
+	}
+
+	scheduled, err := s.posts.Schedule(ctx, post.Id, at.UTC(), updatedBy)
+	if err != nil {
+		return err
+	}
+	if !scheduled {
+		// Published or deleted between the two queries.
+		return ErrAlreadyPublished
+	}
+
+	slog.InfoContext(ctx, "Post scheduled", "slug", slug, "at", at.UTC(), "by", updatedBy)
+
+	return nil
+}
+
+// PublishDue publishes every post whose time has come and returns their slugs.
+func (s *Scheduler) PublishDue(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "Scheduler.PublishDue")
+	defer span.End()
+
+	slugs, err := s.posts.PublishDue(ctx, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, slug := range slugs {
+		slog.InfoContext(ctx, "Scheduled post published", "slug", slug)
+	}
+
+	return slugs, nil
+}
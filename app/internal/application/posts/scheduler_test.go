@@ -0,0 +1,68 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/domain/posts"
+
+	"github.com/google/uuid"
+)
+
+type stubScheduleRepo struct {
+	*mockPostRepository
+	scheduled map[uuid.UUID]time.Time
+}
+
+func (s *stubScheduleRepo) Schedule(_ context.Context, id uuid.UUID, at time.Time, _ string) (bool, error) {
+	s.scheduled[id] = at
+	return true, nil
+}
+
+func (s *stubScheduleRepo) PublishDue(context.Context, time.Time) ([]string, error) {
+	return nil, nil
+}
+
+var schedulerNow = time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func newTestScheduler() (*Scheduler, *stubScheduleRepo) {
+	repo := &stubScheduleRepo{mockPostRepository: newMockPostRepository(), scheduled: map[uuid.UUID]time.Time{}}
+	scheduler := NewScheduler(repo)
+	scheduler.now = func() time.Time { return schedulerNow }
+	return scheduler, repo
+}
+
+// A time in the past would publish on the next sweep, which is almost
+// certainly a typo rather than what the author meant.
+func TestSchedule_RefusesThePastAndPublishedPosts(t *testing.T) {
+	scheduler, repo := newTestScheduler()
+	repo.addPost(posts.Post{Id: uuid.New(), Slug: "live", Status: posts.PostStatusPublished})
+
+	if err := scheduler.Schedule(context.Background(), "live", schedulerNow.Add(time.Hour), "cli"); !errors.Is(err, ErrAlreadyPublished) {
+		t.Errorf("scheduling a published post = %v, want ErrAlreadyPublished", err)
+	}
+	if err := scheduler.Schedule(context.Background(), "live", schedulerNow.Add(-time.Hour), "cli"); !errors.Is(err, ErrScheduledInThePast) {
+		t.Errorf("scheduling in the past = %v, want ErrScheduledInThePast", err)
+	}
+	if err := scheduler.Schedule(context.Background(), "missing", schedulerNow.Add(time.Hour), "cli"); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("scheduling a missing post = %v, want ErrPostNotFound", err)
+	}
+}
+
+func TestSchedule_StoresUTC(t *testing.T) {
+	scheduler, repo := newTestScheduler()
+	id := uuid.New()
+	repo.addPost(posts.Post{Id: id, Slug: "draft", Status: posts.PostStatusDraft})
+
+	sofia := time.FixedZone("EEST", 3*60*60)
+	at := time.Date(2026, 5, 2, 9, 0, 0, 0, sofia)
+	if err := scheduler.Schedule(context.Background(), "draft", at, "cli"); err != nil {
+		t.Fatalf("Schedule() = %v", err)
+	}
+
+	if got := repo.scheduled[id]; !got.Equal(at) || got.Location() != time.UTC {
+		t.Errorf("scheduled at %v, want %v in UTC", got, at)
+	}
+}
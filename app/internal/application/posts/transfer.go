@@ -0,0 +1,297 @@
+package posts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"server/internal/domain/category"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+// ExportVersion is written into every export and checked on import, so a
+// future change to the format cannot be read as this one.
+const ExportVersion = 1
+
+// ErrUnsupportedExport is returned for a document that is not an export, or
+// one written in another version of the format.
+var ErrUnsupportedExport = errors.New("unsupported export format")
+
+// Export is the document written by Export and read by Import.
+type Export struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Posts      []ExportedPost `json:"posts"`
+}
+
+// ExportedPost refers to its category by slug and its author by email, which
+// stay the same from one database to the next where the ids do not.
+type ExportedPost struct {
+	Slug            string           `json:"slug"`
+	Title           string           `json:"title"`
+	Content         string           `json:"content"`
+	Excerpt         string           `json:"excerpt,omitempty"`
+	CoverImageUrl   string           `json:"coverImageUrl,omitempty"`
+	Status          posts.PostStatus `json:"status"`
+	PublishedAt     *time.Time       `json:"publishedAt,omitempty"`
+	MetaDescription string           `json:"metaDescription,omitempty"`
+	Category        string           `json:"category"`
+	Author          string           `json:"author"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	Metadata        json.RawMessage  `json:"metadata,omitempty"`
+}
+
+// ImportResult lists the slugs that were created and the ones left alone
+// because a post already had them.
+type ImportResult struct {
+	Created []string
+	Skipped []string
+}
+
+type transferPostRepository interface {
+	FindAll(ctx context.Context, limit, offset int) ([]posts.PostWithAuthor, int, error)
+	ExistsBySlug(ctx context.Context, slug string, excludeId *uuid.UUID) (bool, error)
+	Create(ctx context.Context, post posts.Post) (*posts.Post, error)
+}
+
+type categoryLookup interface {
+	FindBySlug(ctx context.Context, slug string) (*category.Category, error)
+}
+
+type authorLookup interface {
+	FindById(ctx context.Context, userId string) (user.User, error)
+	FindByEmail(ctx context.Context, email string) (user.User, error)
+}
+
+// TransferService moves posts between databases: a backup to keep, content
+// written on staging, or a move to a new server.
+type TransferService struct {
+	posts      transferPostRepository
+	categories categoryLookup
+	authors    authorLookup
+}
+
+func NewTransferService(posts transferPostRepository, categories categoryLookup, authors authorLookup) *TransferService {
+	return &TransferService{
+		posts:      posts,
+		categories: categories,
+		authors:    authors,
+	}
+}
+
+// exportPageSize is how many posts are read per query.
+const exportPageSize = 100
+
+// Export writes every post that is not deleted, in any status, and returns
+// how many there were.
+func (s *TransferService) Export(ctx context.Context, w io.Writer) (int, error) {
+	ctx, span := tracing.Start(ctx, "TransferService.Export")
+	defer span.End()
+
+	export := Export{Version: ExportVersion, ExportedAt: time.Now().UTC(), Posts: []ExportedPost{}}
+	emails := map[uuid.UUID]string{}
+
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.posts.FindAll(ctx, exportPageSize, offset)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, p := range page {
+			email, ok := emails[p.CreatorUserId]
+			if !ok {
+				author, err := s.authors.FindById(ctx, p.CreatorUserId.String())
+				if err != nil {
+					return 0, fmt.Errorf("author of %q: %w", p.Slug, err)
+				}
+				email = author.Email
+				emails[p.CreatorUserId] = email
+			}
+
+			exported := ExportedPost{
+				Slug:            p.Slug,
+				Title:           p.Title,
+				Content:         p.Content,
+				Excerpt:         p.Excerpt,
+				CoverImageUrl:   p.CoverImageUrl,
+				Status:          p.Status,
+				MetaDescription: p.MetaDescription,
+				Category:        p.CategorySlug,
+				Author:          email,
+				CreatedAt:       p.CreatedAt,
+				Metadata:        p.Metadata,
+			}
+			if p.PublishedAt.Valid {
+				publishedAt := p.PublishedAt.Time
+				exported.PublishedAt = &publishedAt
+			}
+
+			export.Posts = append(export.Posts, exported)
+		}
+
+		if len(page) == 0 || offset+len(page) >= total {
+			break
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return 0, err
+	}
+
+	return len(export.Posts), nil
+}
+
+// Import creates the posts of an export, keeping their slugs and dates. Posts
+// whose slug is taken are skipped rather than overwritten, so an import can
+// be repeated. Authors missing from this database are replaced by
+// defaultAuthor when it is given.
+//
+// Every post is checked before any is written, so a bad export imports
+// nothing.
+func (s *TransferService) Import(ctx context.Context, r io.Reader, defaultAuthor string) (ImportResult, error) {
+	ctx, span := tracing.Start(ctx, "TransferService.Import")
+	defer span.End()
+
+	var export Export
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return ImportResult{}, fmt.Errorf("%w: %v", ErrUnsupportedExport, err)
+	}
+	if export.Version != ExportVersion {
+		return ImportResult{}, fmt.Errorf("%w: version %d", ErrUnsupportedExport, export.Version)
+	}
+
+	categoryIds := map[string]uuid.UUID{}
+	authorIds := map[string]uuid.UUID{}
+	prepared := make([]posts.Post, 0, len(export.Posts))
+
+	for i, exported := range export.Posts {
+		post, err := s.prepare(ctx, exported, defaultAuthor, categoryIds, authorIds)
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("post %d (%q): %w", i+1, exported.Slug, err)
+		}
+		prepared = append(prepared, post)
+	}
+
+	var result ImportResult
+	for _, post := range prepared {
+		exists, err := s.posts.ExistsBySlug(ctx, post.Slug, nil)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Skipped = append(result.Skipped, post.Slug)
+			continue
+		}
+
+		if _, err := s.posts.Create(ctx, post); err != nil {
+			return result, fmt.Errorf("post %q: %w", post.Slug, err)
+		}
+		result.Created = append(result.Created, post.Slug)
+	}
+
+	return result, nil
+}
+
+// prepare checks an exported post and resolves its category and author,
+// remembering lookups across posts.
+func (s *TransferService) prepare(ctx context.Context, exported ExportedPost, defaultAuthor string, categoryIds, authorIds map[string]uuid.UUID) (posts.Post, error) {
+	if exported.Slug == "" || exported.Title == "" || exported.Content == "" {
+		return posts.Post{}, errors.New("slug, title and content are required")
+	}
+
+	switch exported.Status {
+	case posts.PostStatusCreated, posts.PostStatusDraft, posts.PostStatusPublished, posts.PostStatusArchived:
+	default:
+		return posts.Post{}, fmt.Errorf("unknown status %q", exported.Status)
+	}
+
+	categoryId, ok := categoryIds[exported.Category]
+	if !ok {
+		found, err := s.categories.FindBySlug(ctx, exported.Category)
+		if errors.Is(err, sql.ErrNoRows) {
+			return posts.Post{}, fmt.Errorf("unknown category %q", exported.Category)
+		}
+		if err != nil {
+			return posts.Post{}, err
+		}
+		categoryId = found.Id
+		categoryIds[exported.Category] = categoryId
+	}
+
+	authorId, err := s.resolveAuthor(ctx, exported.Author, defaultAuthor, authorIds)
+	if err != nil {
+		return posts.Post{}, err
+	}
+
+	createdAt := exported.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	metadata := exported.Metadata
+	if len(metadata) == 0 {
+		metadata = json.RawMessage(`{}`)
+	}
+
+	post := posts.Post{
+		Id:                 uuid.New(),
+		Title:              exported.Title,
+		Slug:               exported.Slug,
+		Content:            exported.Content,
+		Excerpt:            exported.Excerpt,
+		CoverImageUrl:      exported.CoverImageUrl,
+		Status:             exported.Status,
+		MetaDescription:    exported.MetaDescription,
+		ReadingTimeMinutes: readingTime(exported.Content),
+		CategoryId:         categoryId,
+		CreatorUserId:      authorId,
+		CreatedAt:          createdAt,
+		Metadata:           metadata,
+	}
+
+	switch {
+	case exported.PublishedAt != nil:
+		post.PublishedAt = sql.NullTime{Time: *exported.PublishedAt, Valid: true}
+	case exported.Status == posts.PostStatusPublished:
+		// A published post without a date would sort as the oldest on the
+		// blog; its creation date is the closest thing to one.
+		post.PublishedAt = sql.NullTime{Time: createdAt, Valid: true}
+	}
+
+	return post, nil
+}
+
+func (s *TransferService) resolveAuthor(ctx context.Context, email, defaultAuthor string, authorIds map[string]uuid.UUID) (uuid.UUID, error) {
+	for _, candidate := range []string{email, defaultAuthor} {
+		if candidate == "" {
+			continue
+		}
+
+		if id, ok := authorIds[candidate]; ok {
+			return id, nil
+		}
+
+		author, err := s.authors.FindByEmail(ctx, candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		authorIds[candidate] = author.Id
+		return author.Id, nil
+	}
+
+	return uuid.Nil, fmt.Errorf("author %q not found and no default author given", email)
+}
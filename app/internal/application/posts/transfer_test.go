@@ -0,0 +1,111 @@
+package posts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/domain/category"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+
+	"github.com/google/uuid"
+)
+
+type stubCategories map[string]uuid.UUID
+
+func (s stubCategories) FindBySlug(_ context.Context, slug string) (*category.Category, error) {
+	id, ok := s[slug]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &category.Category{Id: id, Slug: slug}, nil
+}
+
+type stubAuthors map[string]uuid.UUID
+
+func (s stubAuthors) FindById(_ context.Context, userId string) (user.User, error) {
+	for email, id := range s {
+		if id.String() == userId {
+			return user.User{Id: id, Email: email}, nil
+		}
+	}
+	return user.User{}, sql.ErrNoRows
+}
+
+func (s stubAuthors) FindByEmail(_ context.Context, email string) (user.User, error) {
+	id, ok := s[email]
+	if !ok {
+		return user.User{}, sql.ErrNoRows
+	}
+	return user.User{Id: id, Email: email}, nil
+}
+
+const importDocument = `{
+  "version": 1,
+  "posts": [
+    {"slug": "taken", "title": "Taken", "content": "text", "status": "draft", "category": "fitness", "author": "editor@example.com"},
+    {"slug": "new", "title": "New", "content": "text", "status": "published", "publishedAt": "2025-03-01T08:00:00Z",
+     "category": "fitness", "author": "gone@example.com", "createdAt": "2025-02-27T10:00:00Z"}
+  ]
+}`
+
+// Imports are meant to be repeatable, and a post moved between servers has
+// to keep its address and its date or links and the archive order break.
+func TestImport_SkipsTakenSlugsAndKeepsSlugAndDates(t *testing.T) {
+	repo := newMockPostRepository()
+	repo.addPost(posts.Post{Id: uuid.New(), Slug: "taken", Title: "Already here"})
+	fitness, editor := uuid.New(), uuid.New()
+	service := NewTransferService(repo, stubCategories{"fitness": fitness}, stubAuthors{"editor@example.com": editor})
+
+	result, err := service.Import(context.Background(), strings.NewReader(importDocument), "editor@example.com")
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+
+	if len(result.Created) != 1 || result.Created[0] != "new" || len(result.Skipped) != 1 || result.Skipped[0] != "taken" {
+		t.Fatalf("Import() = %+v, want new created and taken skipped", result)
+	}
+
+	created, _ := repo.FindBySlug(context.Background(), "new")
+	wantPublished := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	if !created.PublishedAt.Valid || !created.PublishedAt.Time.Equal(wantPublished) {
+		t.Errorf("PublishedAt = %v, want %v", created.PublishedAt, wantPublished)
+	}
+	if created.CategoryId != fitness {
+		t.Error("category not resolved by slug")
+	}
+	if created.CreatorUserId != editor {
+		t.Error("missing author not replaced by the default author")
+	}
+	if existing, _ := repo.FindBySlug(context.Background(), "taken"); existing.Title != "Already here" {
+		t.Error("an existing post was overwritten")
+	}
+}
+
+// A bad post anywhere in the file must not leave half an import behind.
+func TestImport_InvalidPostImportsNothing(t *testing.T) {
+	repo := newMockPostRepository()
+	service := NewTransferService(repo, stubCategories{}, stubAuthors{"editor@example.com": uuid.New()})
+
+	_, err := service.Import(context.Background(), strings.NewReader(importDocument), "")
+	if err == nil || !strings.Contains(err.Error(), "unknown category") {
+		t.Fatalf("Import() = %v, want an unknown category error", err)
+	}
+
+	if exists, _ := repo.ExistsBySlug(context.Background(), "new", nil); exists {
+		t.Error("a post was created despite the error")
+	}
+}
+
+func TestImport_RejectsOtherVersions(t *testing.T) {
+	service := NewTransferService(newMockPostRepository(), stubCategories{}, stubAuthors{})
+
+	_, err := service.Import(context.Background(), strings.NewReader(`{"version": 2, "posts": []}`), "")
+	if !errors.Is(err, ErrUnsupportedExport) {
+		t.Errorf("Import() = %v, want ErrUnsupportedExport", err)
+	}
+}
@@ -0,0 +1,118 @@
+package spam
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"server/internal/domain/spam"
+)
+
+const (
+	// minTrainedDocuments per label before the classifier is listened to. With
+	// fewer, a single moderation decision would swing every score.
+	minTrainedDocuments = 10
+
+	// interestingTokens is how many of the most telling tokens decide the
+	// verdict. Averaging over every word would drown a few loud ones.
+	interestingTokens = 15
+
+	// Robinson's smoothing: a token seen in n documents is pulled towards an
+	// even 0.5 with the weight of this many imaginary ones, so a word seen once
+	// is never taken as proof.
+	priorStrength    = 1.0
+	priorProbability = 0.5
+
+	minTokenLength = 3
+	maxTokenLength = 30
+)
+
+var (
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}][\p{L}\p{N}'_-]*`)
+	linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+)
+
+// Tokenize splits text into the unique tokens the classifier learns from:
+// lowercase words of a sensible length, plus the host of every link, since
+// the domain a spammer advertises says more than the words around it.
+func Tokenize(text string) []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	add := func(token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		if parsed, err := url.Parse(link); err == nil && parsed.Hostname() != "" {
+			add("url:" + strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www."))
+		}
+	}
+
+	for _, word := range wordPattern.FindAllString(linkPattern.ReplaceAllString(text, " "), -1) {
+		if length := utf8.RuneCountInString(word); length < minTokenLength || length > maxTokenLength {
+			continue
+		}
+		add(strings.ToLower(word))
+	}
+
+	return tokens
+}
+
+// spamProbability combines the most telling tokens into the chance that the
+// document is spam. It reports false while there is too little training or
+// none of the tokens has been seen, so no verdict is made up.
+func spamProbability(tokens []string, counts map[string]spam.TokenCount, totals spam.Totals) (float64, bool) {
+	if totals.Spam < minTrainedDocuments || totals.Ham < minTrainedDocuments {
+		return 0, false
+	}
+
+	probabilities := []float64{}
+	for _, token := range tokens {
+		count, ok := counts[token]
+		if !ok || count.Spam+count.Ham == 0 {
+			continue
+		}
+
+		// Frequencies rather than raw counts, so a label with more training
+		// does not win by volume.
+		spamFrequency := float64(count.Spam) / float64(totals.Spam)
+		hamFrequency := float64(count.Ham) / float64(totals.Ham)
+		p := spamFrequency / (spamFrequency + hamFrequency)
+
+		n := float64(count.Spam + count.Ham)
+		probabilities = append(probabilities, (priorStrength*priorProbability+n*p)/(priorStrength+n))
+	}
+
+	if len(probabilities) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(probabilities, func(i, j int) bool {
+		return math.Abs(probabilities[i]-0.5) > math.Abs(probabilities[j]-0.5)
+	})
+	if len(probabilities) > interestingTokens {
+		probabilities = probabilities[:interestingTokens]
+	}
+
+	// Naive Bayes in log space: a product of fifteen small numbers underflows.
+	var logSpam, logHam float64
+	for _, p := range probabilities {
+		logSpam += math.Log(p)
+		logHam += math.Log(1 - p)
+	}
+
+	return 1 / (1 + math.Exp(logHam-logSpam)), true
+}
+
+func countLinks(text string) int {
+	return len(linkPattern.FindAllStringIndex(text, -1))
+}
@@ -0,0 +1,55 @@
+package spam
+
+import (
+	"reflect"
+	"testing"
+
+	"server/internal/domain/spam"
+)
+
+// Tokens are unique, lowercase and of a useful length, and a link is reduced
+// to its host instead of being split into words.
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Купете ЕВТИНИ часовници на https://www.cheap-watches.example/buy?id=1 – евтини, евтини! ok")
+	want := []string{"url:cheap-watches.example", "купете", "евтини", "часовници"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+// Until both labels have enough examples the classifier stays silent rather
+// than guessing from a handful of decisions.
+func TestSpamProbability_NeedsTraining(t *testing.T) {
+	counts := map[string]spam.TokenCount{"часовници": {Spam: 5}}
+
+	if _, ok := spamProbability([]string{"часовници"}, counts, spam.Totals{Spam: 5, Ham: 50}); ok {
+		t.Error("spamProbability() gave a verdict with too little spam training")
+	}
+}
+
+// Words seen mostly in spam push the score up, words seen mostly in real
+// comments pull it down, and unknown words change nothing.
+func TestSpamProbability(t *testing.T) {
+	totals := spam.Totals{Spam: 20, Ham: 20}
+	counts := map[string]spam.TokenCount{
+		"часовници": {Spam: 18, Ham: 1},
+		"евтини":    {Spam: 15, Ham: 2},
+		"статия":    {Spam: 1, Ham: 16},
+		"благодаря": {Spam: 0, Ham: 12},
+	}
+
+	spammy, ok := spamProbability([]string{"евтини", "часовници", "непознато"}, counts, totals)
+	if !ok || spammy < 0.9 {
+		t.Errorf("spam probability = %v, %v, want above 0.9", spammy, ok)
+	}
+
+	hammy, ok := spamProbability([]string{"благодаря", "статия", "непознато"}, counts, totals)
+	if !ok || hammy > 0.1 {
+		t.Errorf("ham probability = %v, %v, want below 0.1", hammy, ok)
+	}
+
+	if _, ok := spamProbability([]string{"непознато"}, counts, totals); ok {
+		t.Error("spamProbability() gave a verdict with no known tokens")
+	}
+}
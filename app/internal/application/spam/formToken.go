@@ -0,0 +1,139 @@
+package spam
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/config"
+)
+
+// Field names a guarded form carries besides its own.
+const (
+	// HoneypotField is hidden from people but not from bots that fill in every
+	// input they find. Named like a real field so it looks worth filling.
+	HoneypotField        = "website"
+	FormTokenField       = "formToken"
+	ChallengeTokenField  = "challengeToken"
+	ChallengeAnswerField = "challengeAnswer"
+)
+
+const (
+	// minFillTime is quicker than anyone reads and types into a form, and
+	// slower than a script posting the moment the page loads.
+	minFillTime  = 3 * time.Second
+	formTokenTTL = 24 * time.Hour
+	challengeTTL = 10 * time.Minute
+
+	stampSize = 8
+	macSize   = 16
+)
+
+// Challenge is a question a person answers easily and a blind script does not.
+type Challenge struct {
+	Question string
+	Token    string
+}
+
+// IssueFormToken stamps a form with the moment it was rendered, signed so the
+// stamp cannot be moved back to fake a patient visitor.
+func IssueFormToken() string {
+	return issueFormToken(signingKey(), time.Now())
+}
+
+// NewChallenge asks for a small sum. The token carries the answer's signature,
+// not the answer, so nothing has to be remembered between the two requests.
+func NewChallenge() Challenge {
+	return newChallenge(signingKey(), rand.IntN(9)+1, rand.IntN(9)+1, time.Now())
+}
+
+// SolvedChallenge reports whether answer is right for a challenge issued in the
+// last few minutes.
+func SolvedChallenge(token, answer string) bool {
+	return solvedChallenge(signingKey(), token, answer, time.Now())
+}
+
+func issueFormToken(key []byte, now time.Time) string {
+	stamp := binary.BigEndian.AppendUint64(nil, uint64(now.UnixMilli()))
+
+	return base64.RawURLEncoding.EncodeToString(append(stamp, sign(key, "form", stamp, "")...))
+}
+
+// formTokenAge reports how long ago the token was issued, and false when it
+// was not issued by us or has expired.
+func formTokenAge(key []byte, token string, now time.Time) (time.Duration, bool) {
+	stamp, mac, ok := splitToken(token)
+	if !ok || !hmac.Equal(mac, sign(key, "form", stamp, "")) {
+		return 0, false
+	}
+
+	age := now.Sub(stampTime(stamp))
+	if age < 0 || age > formTokenTTL {
+		return 0, false
+	}
+
+	return age, true
+}
+
+func newChallenge(key []byte, a, b int, now time.Time) Challenge {
+	stamp := binary.BigEndian.AppendUint64(nil, uint64(now.UnixMilli()))
+	answer := strconv.Itoa(a + b)
+
+	return Challenge{
+		Question: fmt.Sprintf("Колко е %d + %d?", a, b),
+		Token:    base64.RawURLEncoding.EncodeToString(append(stamp, sign(key, "challenge", stamp, answer)...)),
+	}
+}
+
+func solvedChallenge(key []byte, token, answer string, now time.Time) bool {
+	stamp, mac, ok := splitToken(token)
+	if !ok {
+		return false
+	}
+
+	age := now.Sub(stampTime(stamp))
+	if age < 0 || age > challengeTTL {
+		return false
+	}
+
+	return hmac.Equal(mac, sign(key, "challenge", stamp, strings.TrimSpace(answer)))
+}
+
+func splitToken(token string) (stamp, mac []byte, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != stampSize+macSize {
+		return nil, nil, false
+	}
+
+	return raw[:stampSize], raw[stampSize:], true
+}
+
+func stampTime(stamp []byte) time.Time {
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(stamp)))
+}
+
+// sign binds the purpose into the MAC so a form token can never pass as a
+// challenge token or the other way round.
+func sign(key []byte, purpose string, stamp []byte, extra string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write(stamp)
+	mac.Write([]byte(extra))
+
+	return mac.Sum(nil)[:macSize]
+}
+
+// signingKey derives a key of its own from the XSRF secret, so these tokens
+// share no MAC with the CSRF ones.
+func signingKey() []byte {
+	key := sha256.Sum256([]byte("spam-form-token\x00" + config.XSRFKey()))
+
+	return key[:]
+}
@@ -0,0 +1,58 @@
+package spam
+
+import (
+	"testing"
+	"time"
+)
+
+var testKey = []byte("test-spam-key")
+
+// The token's age is what the too-fast signal is measured from, so it must
+// come back exactly, and only for tokens we signed.
+func TestFormTokenAge(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	token := issueFormToken(testKey, issued)
+
+	if age, ok := formTokenAge(testKey, token, issued.Add(5*time.Second)); !ok || age != 5*time.Second {
+		t.Errorf("formTokenAge() = %v, %v, want 5s, true", age, ok)
+	}
+
+	if _, ok := formTokenAge([]byte("another key"), token, issued.Add(5*time.Second)); ok {
+		t.Error("formTokenAge() accepted a token signed with another key")
+	}
+
+	if _, ok := formTokenAge(testKey, token, issued.Add(formTokenTTL+time.Second)); ok {
+		t.Error("formTokenAge() accepted an expired token")
+	}
+
+	if _, ok := formTokenAge(testKey, "not-a-token", issued); ok {
+		t.Error("formTokenAge() accepted garbage")
+	}
+}
+
+// A challenge is solved by the right sum only, only while it is fresh, and a
+// form token can never stand in for one.
+func TestSolvedChallenge(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	challenge := newChallenge(testKey, 3, 4, issued)
+
+	if challenge.Question != "Колко е 3 + 4?" {
+		t.Errorf("Question = %q", challenge.Question)
+	}
+
+	if !solvedChallenge(testKey, challenge.Token, " 7 ", issued.Add(time.Minute)) {
+		t.Error("the right answer was refused")
+	}
+
+	if solvedChallenge(testKey, challenge.Token, "8", issued.Add(time.Minute)) {
+		t.Error("a wrong answer was accepted")
+	}
+
+	if solvedChallenge(testKey, challenge.Token, "7", issued.Add(challengeTTL+time.Second)) {
+		t.Error("an expired challenge was accepted")
+	}
+
+	if solvedChallenge(testKey, issueFormToken(testKey, issued), "7", issued.Add(time.Minute)) {
+		t.Error("a form token passed as a challenge")
+	}
+}
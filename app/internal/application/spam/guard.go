@@ -0,0 +1,162 @@
+package spam
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"server/internal/domain/spam"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+// Reasons an assessment can give, in the order they are checked.
+const (
+	ReasonHoneypot         = "honeypot"
+	ReasonMissingFormToken = "missing_form_token"
+	ReasonInvalidFormToken = "invalid_form_token"
+	ReasonTooFast          = "too_fast"
+	ReasonLinks            = "links"
+	ReasonLinkDensity      = "link_density"
+	ReasonVelocity         = "velocity"
+	ReasonRepeated         = "repeated"
+	ReasonClassifier       = "classifier"
+)
+
+type tokenRepository interface {
+	Counts(ctx context.Context, tokens []string) (map[string]spam.TokenCount, spam.Totals, error)
+	Train(ctx context.Context, documentId uuid.UUID, tokens []string, label spam.Label) error
+}
+
+// Submission is what a public form sent, reduced to the signals the guard
+// scores. Reading the request is the caller's job.
+type Submission struct {
+	// Text is the free text of the form. Passwords must be left out: they are
+	// not the sender's words, and the classifier must never store them.
+	Text      string
+	Honeypot  string
+	FormToken string
+
+	// Velocity is how many submissions came from the same address recently,
+	// this one included.
+	Velocity int
+
+	// Repeats is how many times the same text was submitted recently, this
+	// one included.
+	Repeats int
+}
+
+// Assessment is the guard's verdict. Reasons name what added to the score.
+type Assessment struct {
+	Score      int
+	Reasons    []string
+	Suspicious bool
+}
+
+// Guard scores public form submissions without asking any outside service.
+// It never rejects on its own: the caller decides whether a suspicious
+// submission is held for review or has to answer a challenge first.
+type Guard struct {
+	tokens    tokenRepository
+	threshold int
+	key       []byte
+	now       func() time.Time
+}
+
+func NewGuard(repository tokenRepository, threshold int) *Guard {
+	return &Guard{
+		tokens:    repository,
+		threshold: threshold,
+		key:       signingKey(),
+		now:       time.Now,
+	}
+}
+
+func (g *Guard) Assess(ctx context.Context, submission Submission) Assessment {
+	ctx, span := tracing.Start(ctx, "Guard.Assess")
+	defer span.End()
+
+	var assessment Assessment
+	add := func(reason string, points int) {
+		assessment.Score += points
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	// People cannot see the field, so anything in it came from a script.
+	if strings.TrimSpace(submission.Honeypot) != "" {
+		add(ReasonHoneypot, 100)
+	}
+
+	if submission.FormToken == "" {
+		add(ReasonMissingFormToken, 40)
+	} else if age, ok := formTokenAge(g.key, submission.FormToken, g.now()); !ok {
+		add(ReasonInvalidFormToken, 40)
+	} else if age < minFillTime {
+		add(ReasonTooFast, 60)
+	}
+
+	// One link is a reference; a handful is an advert.
+	links := countLinks(submission.Text)
+	if links >= 2 {
+		add(ReasonLinks, min(links*15, 45))
+	}
+	if words := len(strings.Fields(submission.Text)); links > 0 && float64(links)/float64(words) > 0.5 {
+		add(ReasonLinkDensity, 25)
+	}
+
+	switch {
+	case submission.Velocity > 10:
+		add(ReasonVelocity, 40)
+	case submission.Velocity > 5:
+		add(ReasonVelocity, 20)
+	}
+
+	if submission.Repeats >= 3 {
+		add(ReasonRepeated, 30)
+	}
+
+	if p, ok := g.classify(ctx, submission.Text); ok {
+		switch {
+		case p >= 0.9:
+			add(ReasonClassifier, 40)
+		case p >= 0.75:
+			add(ReasonClassifier, 20)
+		}
+	}
+
+	assessment.Suspicious = assessment.Score >= g.threshold
+
+	return assessment
+}
+
+// Train teaches the classifier from a moderator's decision about a document.
+func (g *Guard) Train(ctx context.Context, documentId uuid.UUID, text string, isSpam bool) error {
+	ctx, span := tracing.Start(ctx, "Guard.Train")
+	defer span.End()
+
+	label := spam.LabelHam
+	if isSpam {
+		label = spam.LabelSpam
+	}
+
+	return g.tokens.Train(ctx, documentId, Tokenize(text), label)
+}
+
+// classify asks the learned model. A failed lookup only loses this one signal;
+// the others still stand, so it is logged rather than returned.
+func (g *Guard) classify(ctx context.Context, text string) (float64, bool) {
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return 0, false
+	}
+
+	counts, totals, err := g.tokens.Counts(ctx, tokens)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not read spam classifier counts", "error", err)
+		return 0, false
+	}
+
+	return spamProbability(tokens, counts, totals)
+}
@@ -0,0 +1,126 @@
+package spam
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"server/internal/domain/spam"
+
+	"github.com/google/uuid"
+)
+
+type stubTokens struct {
+	counts  map[string]spam.TokenCount
+	totals  spam.Totals
+	trained map[uuid.UUID]spam.Label
+}
+
+func (s *stubTokens) Counts(context.Context, []string) (map[string]spam.TokenCount, spam.Totals, error) {
+	return s.counts, s.totals, nil
+}
+
+func (s *stubTokens) Train(_ context.Context, documentId uuid.UUID, _ []string, label spam.Label) error {
+	s.trained[documentId] = label
+	return nil
+}
+
+func newTestGuard(tokens *stubTokens, now time.Time) *Guard {
+	return &Guard{tokens: tokens, threshold: 50, key: testKey, now: func() time.Time { return now }}
+}
+
+// An ordinary reader - a token from a minute ago, one link, nothing repeated -
+// stays under the threshold.
+func TestAssess_OrdinarySubmissionPasses(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&stubTokens{}, now)
+
+	assessment := guard.Assess(context.Background(), Submission{
+		Text:      "Много полезно, ето и официалната документация: https://go.dev/doc",
+		FormToken: issueFormToken(testKey, now.Add(-time.Minute)),
+		Velocity:  1,
+		Repeats:   1,
+	})
+
+	if assessment.Suspicious || assessment.Score != 0 {
+		t.Errorf("Assess() = %+v, want a clean pass", assessment)
+	}
+}
+
+// Each of these alone is enough to hold a submission: nobody fills the hidden
+// field, and nobody writes a comment in a second.
+func TestAssess_DecisiveSignals(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&stubTokens{}, now)
+	token := issueFormToken(testKey, now.Add(-time.Minute))
+
+	tests := []struct {
+		name       string
+		submission Submission
+		reason     string
+	}{
+		{"honeypot", Submission{Text: "Здравейте", Honeypot: "http://spam.example", FormToken: token}, ReasonHoneypot},
+		{"too fast", Submission{Text: "Здравейте", FormToken: issueFormToken(testKey, now.Add(-time.Second))}, ReasonTooFast},
+		{"link farm", Submission{Text: "http://a.example http://b.example http://c.example", FormToken: token}, ReasonLinks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := guard.Assess(context.Background(), tt.submission)
+
+			if !assessment.Suspicious || !slices.Contains(assessment.Reasons, tt.reason) {
+				t.Errorf("Assess() = %+v, want suspicious for %q", assessment, tt.reason)
+			}
+		})
+	}
+}
+
+// A missing token alone is not enough - it could be an API client - but with
+// a burst from the same address it is.
+func TestAssess_WeakSignalsAddUp(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&stubTokens{}, now)
+
+	if got := guard.Assess(context.Background(), Submission{Text: "Здравейте", Velocity: 1}); got.Suspicious {
+		t.Errorf("missing token alone = %+v, want a pass", got)
+	}
+
+	got := guard.Assess(context.Background(), Submission{Text: "Здравейте", Velocity: 7})
+	if !got.Suspicious || !slices.Equal(got.Reasons, []string{ReasonMissingFormToken, ReasonVelocity}) {
+		t.Errorf("missing token and velocity = %+v, want suspicious", got)
+	}
+}
+
+// The classifier's verdict counts once it has been trained.
+func TestAssess_UsesClassifier(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&stubTokens{
+		counts: map[string]spam.TokenCount{"часовници": {Spam: 30}, "евтини": {Spam: 25}},
+		totals: spam.Totals{Spam: 30, Ham: 30},
+	}, now)
+
+	got := guard.Assess(context.Background(), Submission{Text: "евтини часовници", FormToken: issueFormToken(testKey, now.Add(-time.Minute))})
+	if !slices.Contains(got.Reasons, ReasonClassifier) || got.Score != 40 {
+		t.Errorf("Assess() = %+v, want the classifier's 40 points", got)
+	}
+}
+
+// Training keeps the moderator's verdict as the document's label; the
+// repository needs it to take the lesson back if the verdict changes.
+func TestTrain_LabelsDocument(t *testing.T) {
+	tokens := &stubTokens{trained: map[uuid.UUID]spam.Label{}}
+	guard := newTestGuard(tokens, time.Now())
+	spamId, hamId := uuid.New(), uuid.New()
+
+	if err := guard.Train(context.Background(), spamId, "евтини часовници", true); err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+	if err := guard.Train(context.Background(), hamId, "полезна статия", false); err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+
+	if tokens.trained[spamId] != spam.LabelSpam || tokens.trained[hamId] != spam.LabelHam {
+		t.Errorf("trained = %v, want spam and ham", tokens.trained)
+	}
+}
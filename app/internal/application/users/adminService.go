@@ -0,0 +1,188 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"server/internal/domain/user"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownRole   = errors.New("unknown role")
+	ErrInvalidStatus = errors.New("invalid user status")
+)
+
+type userAdminRepository interface {
+	FindById(ctx context.Context, userId string) (user.User, error)
+	FindPage(ctx context.Context, emailQuery string, limit, offset int) ([]user.User, int, error)
+	ListRoles(ctx context.Context) ([]user.Role, error)
+	UpdateStatus(ctx context.Context, userId uuid.UUID, status user.UserStatus) error
+	GrantRole(ctx context.Context, userId uuid.UUID, roleName string) error
+	RevokeRole(ctx context.Context, userId uuid.UUID, roleName string) error
+	UpdatePassword(ctx context.Context, userId string, hashedPassword string) error
+	RevokeTokensIssuedBefore(ctx context.Context, userId string, cutoff time.Time) error
+}
+
+type passwordResetRequester interface {
+	RequestReset(ctx context.Context, emailAddr string) error
+}
+
+// UserAdminService is what the admin panel does to other people's accounts.
+// Every change is logged together with the administrator who made it.
+type UserAdminService struct {
+	users  userAdminRepository
+	resets passwordResetRequester
+}
+
+func NewUserAdminService(users userAdminRepository, resets passwordResetRequester) *UserAdminService {
+	return &UserAdminService{
+		users:  users,
+		resets: resets,
+	}
+}
+
+// UserPage is one page of the account list.
+type UserPage struct {
+	Users []user.User
+	Total int
+}
+
+func (s *UserAdminService) Search(ctx context.Context, emailQuery string, page, pageSize int) (UserPage, error) {
+	ctx, span := tracing.Start(ctx, "UserAdminService.Search")
+	defer span.End()
+
+	users, total, err := s.users.FindPage(ctx, emailQuery, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	return UserPage{Users: users, Total: total}, nil
+}
+
+func (s *UserAdminService) Roles(ctx context.Context) ([]user.Role, error) {
+	ctx, span := tracing.Start(ctx, "UserAdminService.Roles")
+	defer span.End()
+
+	return s.users.ListRoles(ctx)
+}
+
+// SetStatus changes the account status. Anything but Active also ends the
+// account's sessions; the repository refuses to suspend the last active
+// administrator.
+func (s *UserAdminService) SetStatus(ctx context.Context, actorId string, userId uuid.UUID, status user.UserStatus) error {
+	ctx, span := tracing.Start(ctx, "UserAdminService.SetStatus")
+	defer span.End()
+
+	if err := user.ValidateUserStatus(status); err != nil {
+		return ErrInvalidStatus
+	}
+
+	if err := s.users.UpdateStatus(ctx, userId, status); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Account status changed", "userId", userId, "status", status, "by", actorId)
+
+	return nil
+}
+
+func (s *UserAdminService) GrantRole(ctx context.Context, actorId string, userId uuid.UUID, roleName string) error {
+	ctx, span := tracing.Start(ctx, "UserAdminService.GrantRole")
+	defer span.End()
+
+	if err := s.ensureRoleExists(ctx, roleName); err != nil {
+		return err
+	}
+
+	if err := s.users.GrantRole(ctx, userId, roleName); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Role granted", "userId", userId, "role", roleName, "by", actorId)
+
+	return nil
+}
+
+// RevokeRole takes the role away and ends the account's sessions. Roles are
+// carried in the access token, so without the revocation the user would keep
+// the role until the token expired.
+func (s *UserAdminService) RevokeRole(ctx context.Context, actorId string, userId uuid.UUID, roleName string) error {
+	ctx, span := tracing.Start(ctx, "UserAdminService.RevokeRole")
+	defer span.End()
+
+	if err := s.ensureRoleExists(ctx, roleName); err != nil {
+		return err
+	}
+
+	if err := s.users.RevokeRole(ctx, userId, roleName); err != nil {
+		return err
+	}
+
+	if err := s.users.RevokeTokensIssuedBefore(ctx, userId.String(), time.Now().UTC()); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Role revoked", "userId", userId, "role", roleName, "by", actorId)
+
+	return nil
+}
+
+// ForcePasswordReset clears the password, which also ends every session, and
+// mails the owner a reset link. Until the link is used nobody can sign in to
+// the account, including whoever may have learned the old password.
+func (s *UserAdminService) ForcePasswordReset(ctx context.Context, actorId string, userId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserAdminService.ForcePasswordReset")
+	defer span.End()
+
+	u, err := s.users.FindById(ctx, userId.String())
+	if err != nil {
+		return err
+	}
+
+	if err := s.users.UpdatePassword(ctx, u.Id.String(), ""); err != nil {
+		return err
+	}
+
+	if err := s.resets.RequestReset(ctx, u.Email); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Password reset forced", "userId", userId, "by", actorId)
+
+	return nil
+}
+
+func (s *UserAdminService) RevokeSessions(ctx context.Context, actorId string, userId uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserAdminService.RevokeSessions")
+	defer span.End()
+
+	if _, err := s.users.FindById(ctx, userId.String()); err != nil {
+		return err
+	}
+
+	if err := s.users.RevokeTokensIssuedBefore(ctx, userId.String(), time.Now().UTC()); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Sessions revoked", "userId", userId, "by", actorId)
+
+	return nil
+}
+
+func (s *UserAdminService) ensureRoleExists(ctx context.Context, roleName string) error {
+	roles, err := s.users.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !user.HasRole(roles, roleName) {
+		return ErrUnknownRole
+	}
+
+	return nil
+}
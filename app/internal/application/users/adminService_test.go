@@ -0,0 +1,157 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/domain/user"
+
+	"github.com/google/uuid"
+)
+
+type stubAdminRepo struct {
+	user      user.User
+	roles     []user.Role
+	status    user.UserStatus
+	granted   []string
+	revoked   []string
+	passwords []string
+	cutoffs   int
+	statusErr error
+}
+
+func newStubAdminRepo() *stubAdminRepo {
+	return &stubAdminRepo{
+		user:  user.User{Id: uuid.New(), Email: "member@example.com", Password: "hash", Status: user.Active},
+		roles: []user.Role{{Name: user.RoleAdmin}, {Name: "USER"}},
+	}
+}
+
+func (s *stubAdminRepo) FindById(_ context.Context, userId string) (user.User, error) {
+	if userId != s.user.Id.String() {
+		return user.User{}, sql.ErrNoRows
+	}
+	return s.user, nil
+}
+
+func (s *stubAdminRepo) FindPage(context.Context, string, int, int) ([]user.User, int, error) {
+	return []user.User{s.user}, 1, nil
+}
+
+func (s *stubAdminRepo) ListRoles(context.Context) ([]user.Role, error) {
+	return s.roles, nil
+}
+
+func (s *stubAdminRepo) UpdateStatus(_ context.Context, _ uuid.UUID, status user.UserStatus) error {
+	if s.statusErr != nil {
+		return s.statusErr
+	}
+	s.status = status
+	return nil
+}
+
+func (s *stubAdminRepo) GrantRole(_ context.Context, _ uuid.UUID, roleName string) error {
+	s.granted = append(s.granted, roleName)
+	return nil
+}
+
+func (s *stubAdminRepo) RevokeRole(_ context.Context, _ uuid.UUID, roleName string) error {
+	s.revoked = append(s.revoked, roleName)
+	return nil
+}
+
+func (s *stubAdminRepo) UpdatePassword(_ context.Context, _ string, hashedPassword string) error {
+	s.passwords = append(s.passwords, hashedPassword)
+	return nil
+}
+
+func (s *stubAdminRepo) RevokeTokensIssuedBefore(context.Context, string, time.Time) error {
+	s.cutoffs++
+	return nil
+}
+
+type stubResetRequester struct {
+	requested []string
+}
+
+func (s *stubResetRequester) RequestReset(_ context.Context, emailAddr string) error {
+	s.requested = append(s.requested, emailAddr)
+	return nil
+}
+
+func TestSetStatus_RejectsUnknownStatus(t *testing.T) {
+	repo := newStubAdminRepo()
+	service := NewUserAdminService(repo, &stubResetRequester{})
+
+	if err := service.SetStatus(context.Background(), "admin", repo.user.Id, user.UserStatus("Banned")); !errors.Is(err, ErrInvalidStatus) {
+		t.Fatalf("SetStatus() error = %v, want ErrInvalidStatus", err)
+	}
+
+	if repo.status != "" {
+		t.Errorf("status was changed to %q", repo.status)
+	}
+}
+
+// The last-admin rule lives in the repository, where it can lock the rows; the
+// service must hand its refusal back untouched so the screen can explain it.
+func TestSetStatus_PassesOnTheLastAdminRefusal(t *testing.T) {
+	repo := newStubAdminRepo()
+	repo.statusErr = user.ErrLastAdmin
+	service := NewUserAdminService(repo, &stubResetRequester{})
+
+	err := service.SetStatus(context.Background(), "admin", repo.user.Id, user.Suspended)
+	if !errors.Is(err, user.ErrLastAdmin) {
+		t.Fatalf("SetStatus() error = %v, want ErrLastAdmin", err)
+	}
+}
+
+func TestGrantRole_RejectsUnknownRole(t *testing.T) {
+	repo := newStubAdminRepo()
+	service := NewUserAdminService(repo, &stubResetRequester{})
+
+	if err := service.GrantRole(context.Background(), "admin", repo.user.Id, "OWNER"); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("GrantRole() error = %v, want ErrUnknownRole", err)
+	}
+
+	if len(repo.granted) != 0 {
+		t.Errorf("granted %v", repo.granted)
+	}
+}
+
+// Roles travel inside the access token, so a revoked role is only really gone
+// once the sessions carrying it are.
+func TestRevokeRole_EndsSessions(t *testing.T) {
+	repo := newStubAdminRepo()
+	service := NewUserAdminService(repo, &stubResetRequester{})
+
+	if err := service.RevokeRole(context.Background(), "admin", repo.user.Id, user.RoleAdmin); err != nil {
+		t.Fatalf("RevokeRole() error = %v", err)
+	}
+
+	if len(repo.revoked) != 1 || repo.cutoffs != 1 {
+		t.Errorf("revoked = %v, cutoffs = %d; want the role revoked and sessions ended", repo.revoked, repo.cutoffs)
+	}
+}
+
+// A forced reset has to lock out whoever holds the old password, not merely
+// offer the owner a new one.
+func TestForcePasswordReset_ClearsPasswordAndMailsLink(t *testing.T) {
+	repo := newStubAdminRepo()
+	resets := &stubResetRequester{}
+	service := NewUserAdminService(repo, resets)
+
+	if err := service.ForcePasswordReset(context.Background(), "admin", repo.user.Id); err != nil {
+		t.Fatalf("ForcePasswordReset() error = %v", err)
+	}
+
+	if len(repo.passwords) != 1 || repo.passwords[0] != "" {
+		t.Errorf("passwords = %q, want the password cleared", repo.passwords)
+	}
+
+	if len(resets.requested) != 1 || resets.requested[0] != repo.user.Email {
+		t.Errorf("reset links = %v, want one to %s", resets.requested, repo.user.Email)
+	}
+}
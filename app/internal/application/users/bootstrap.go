@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -76,15 +77,7 @@ func EnsureAdmin(ctx context.Context, repo adminBootstrapRepository, email, pass
 		return fmt.Errorf("could not hash the administrator password: %w", err)
 	}
 
-	admin := user.User{
-		Id:        uuid.New(),
-		Email:     email,
-		Password:  hashed,
-		CreatedAt: time.Now().UTC(),
-		Status:    "Active",
-	}
-
-	if err := repo.CreateAdmin(ctx, admin); err != nil {
+	if err := repo.CreateAdmin(ctx, operatorAccount(email, hashed)); err != nil {
 		return fmt.Errorf("could not create the administrator: %w", err)
 	}
 
@@ -92,3 +85,75 @@ func EnsureAdmin(ctx context.Context, repo adminBootstrapRepository, email, pass
 
 	return nil
 }
+
+var (
+	ErrInvalidEmail = errors.New("not a valid email address")
+	ErrPasswordWeak = errors.New("password too weak")
+	ErrEmailTaken   = errors.New("email already registered")
+)
+
+type accountCreator interface {
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	Create(ctx context.Context, u user.User) error
+	CreateAdmin(ctx context.Context, u user.User) error
+}
+
+// CreateAccount creates an account from the command line. Unlike
+// registration the account is active straight away: the operator vouches for
+// the address. The password policy is the same one users are held to.
+func CreateAccount(ctx context.Context, repo accountCreator, email, password string, admin bool) (uuid.UUID, error) {
+	if !httputils.IsValidEmail(email) {
+		return uuid.Nil, ErrInvalidEmail
+	}
+
+	if !securityutil.IsPasswordStrong(password) {
+		return uuid.Nil, ErrPasswordWeak
+	}
+
+	if securityutil.IsPasswordBreached(password) {
+		return uuid.Nil, securityutil.ErrPasswordBreached
+	}
+
+	emailTaken, err := repo.ExistsByEmail(ctx, email)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if emailTaken {
+		return uuid.Nil, ErrEmailTaken
+	}
+
+	hashed, err := securityutil.HashPassword(password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	account := operatorAccount(email, hashed)
+	create := repo.Create
+	if admin {
+		create = repo.CreateAdmin
+	}
+
+	if err := create(ctx, account); err != nil {
+		return uuid.Nil, err
+	}
+
+	slog.InfoContext(ctx, "Account created from the command line", "userId", account.Id, "admin", admin)
+
+	return account.Id, nil
+}
+
+// operatorAccount is an active account for an address the operator supplied,
+// so there is nobody to prove ownership to.
+func operatorAccount(email, hashedPassword string) user.User {
+	now := time.Now().UTC()
+
+	return user.User{
+		Id:              uuid.New(),
+		Email:           email,
+		Password:        hashedPassword,
+		CreatedAt:       now,
+		Status:          user.Active,
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+	}
+}
@@ -12,6 +12,7 @@ type stubBootstrapRepo struct {
 	adminExists bool
 	emailTaken  bool
 	created     []user.User
+	users       []user.User
 	err         error
 }
 
@@ -28,6 +29,11 @@ func (s *stubBootstrapRepo) CreateAdmin(_ context.Context, u user.User) error {
 	return nil
 }
 
+func (s *stubBootstrapRepo) Create(_ context.Context, u user.User) error {
+	s.users = append(s.users, u)
+	return nil
+}
+
 const strongPassword = "Str0ng!Passw0rd"
 
 func TestEnsureAdmin_CreatesTheFirstAdministrator(t *testing.T) {
@@ -111,3 +117,39 @@ func TestEnsureAdmin_RefusesWhenTheEmailIsTaken(t *testing.T) {
 		t.Error("an administrator was created for an address already in use")
 	}
 }
+
+// An operator creating an account vouches for the address, so the account
+// must be usable at once rather than waiting on a verification email.
+func TestCreateAccount_IsActiveAndVerified(t *testing.T) {
+	repo := &stubBootstrapRepo{}
+
+	id, err := CreateAccount(context.Background(), repo, "author@example.com", strongPassword, false)
+	if err != nil {
+		t.Fatalf("CreateAccount() = %v", err)
+	}
+
+	if len(repo.users) != 1 || len(repo.created) != 0 {
+		t.Fatalf("created %d users and %d admins, want one user", len(repo.users), len(repo.created))
+	}
+	u := repo.users[0]
+	if u.Id != id || u.Status != user.Active || !u.EmailVerifiedAt.Valid {
+		t.Errorf("account = %+v, want active and verified", u)
+	}
+}
+
+func TestCreateAccount_HoldsOperatorsToThePolicy(t *testing.T) {
+	repo := &stubBootstrapRepo{}
+
+	if _, err := CreateAccount(context.Background(), repo, "author@example.com", "password", true); !errors.Is(err, ErrPasswordWeak) {
+		t.Errorf("weak password = %v, want ErrPasswordWeak", err)
+	}
+
+	repo.emailTaken = true
+	if _, err := CreateAccount(context.Background(), repo, "author@example.com", strongPassword, true); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("taken email = %v, want ErrEmailTaken", err)
+	}
+
+	if len(repo.created) != 0 {
+		t.Error("an account was created")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	"server/internal/infrastructure/metrics"
 )
 
 // DefaultRevocationCacheTTL bounds how long a revocation can go unnoticed.
@@ -21,6 +23,9 @@ type cutoffSource interface {
 	TokensValidAfter(ctx context.Context, userId string) (sql.NullTime, error)
 }
 
+var sessionCacheLookups = metrics.NewCounterVec("session_cache_lookups_total",
+	"Token revocation checks, by whether the cutoff was cached (hit) or read from the database (miss).", "result")
+
 type cachedCutoff struct {
 	cutoff    sql.NullTime
 	expiresAt time.Time
@@ -62,8 +67,10 @@ func (c *CachedSessionValidator) IsSessionValid(ctx context.Context, userId stri
 	now := time.Now()
 
 	if cutoff, ok := c.cached(userId, now); ok {
+		sessionCacheLookups.With("hit").Inc()
 		return sessionValidAt(cutoff, issuedAt)
 	}
+	sessionCacheLookups.With("miss").Inc()
 
 	cutoff, err := c.source.TokensValidAfter(ctx, userId)
 	if err != nil {
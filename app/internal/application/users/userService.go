@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"server/internal/domain/user"
 	"server/internal/http/handlers/models"
+	"server/internal/infrastructure/tracing"
 	"server/util/securityutil"
 	"time"
 
@@ -32,14 +33,23 @@ func NewUserService(userRepository userRepository) *UserService {
 }
 
 func (userService *UserService) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer span.End()
+
 	return userService.userRepository.FindByEmail(ctx, email)
 }
 
 func (userService *UserService) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ExistsByEmail")
+	defer span.End()
+
 	return userService.userRepository.ExistsByEmail(ctx, email)
 }
 
 func (userService *UserService) GetUserById(ctx context.Context, userId string) (user.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserById")
+	defer span.End()
+
 	return userService.userRepository.FindById(ctx, userId)
 }
 
@@ -49,6 +59,9 @@ func (userService *UserService) GetUserById(ctx context.Context, userId string)
 //
 // Errors fail closed: if the cutoff cannot be read, the session is refused.
 func (userService *UserService) IsSessionValid(ctx context.Context, userId string, issuedAt time.Time) bool {
+	ctx, span := tracing.Start(ctx, "UserService.IsSessionValid")
+	defer span.End()
+
 	validAfter, err := userService.userRepository.TokensValidAfter(ctx, userId)
 	if err != nil {
 		slog.ErrorContext(ctx, "Could not read the token revocation cutoff", "error", err, "userId", userId)
@@ -73,10 +86,16 @@ func sessionValidAt(cutoff sql.NullTime, issuedAt time.Time) bool {
 
 // RevokeSessions invalidates every access token issued for the user so far.
 func (userService *UserService) RevokeSessions(ctx context.Context, userId string) error {
+	ctx, span := tracing.Start(ctx, "UserService.RevokeSessions")
+	defer span.End()
+
 	return userService.userRepository.RevokeTokensIssuedBefore(ctx, userId, time.Now().UTC())
 }
 
 func (userService *UserService) RegisterUser(ctx context.Context, input *models.CreateUserResource) (uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RegisterUser")
+	defer span.End()
+
 	// Hashing and id generation can fail; returning the error keeps a bad
 	// password hash from taking the request down with a panic.
 	hashed, err := securityutil.HashPassword(input.Password)
@@ -94,7 +113,9 @@ func (userService *UserService) RegisterUser(ctx context.Context, input *models.
 		Email:     input.Email,
 		Password:  hashed,
 		CreatedAt: time.Now().UTC(),
-		Status:    "Active",
+		// Stays inactive until the address is verified; the same address
+		// receives password reset links, so it has to belong to the registrant.
+		Status: user.Inactive,
 	}
 
 	if err := userService.userRepository.Create(ctx, newUser); err != nil {
@@ -5,188 +5,405 @@ import (
 	"net/netip"
 	"net/url"
 	"os"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
 var (
-	c    *cfg
-	once sync.Once
+	c       *Config
+	loadErr error
+	once    sync.Once
 )
 
-type cfg struct {
-	// Server
-	port        string
-	environment string
-
-	// Database
-	dbHost     string
-	dbPort     string
-	dbUser     string
-	dbPassword string
-	dbName     string
-	dbSSLMode  string
-	dbMaxConns int
-
-	// JWT
-	jwtAccessKey  string
-	jwtRefreshKey string
-
-	// Security
-	xsrfKey           string
-	corsOrigins       []string
-	allowRegistration bool
-	trustedProxies    []netip.Prefix
-
-	// SMTP
-	smtpHost     string
-	smtpPort     string
-	smtpUsername string
-	smtpPassword string
-	smtpFrom     string
-
-	// Cloudinary
-	cloudinaryCloudName string
-	cloudinaryAPIKey    string
-	cloudinaryAPISecret string
-	cloudinaryFolder    string
-
-	// Feature flags
-	workoutsEnabled  bool
-	nutritionEnabled bool
-
-	// Admin bootstrap
-	adminEmail    string
-	adminPassword string
-
-	// App
-	baseURL             string
-	tinymceURL          string
-	privacyContactEmail string
+// Config is every setting the app reads. Each field names its environment
+// variable in the env tag and its key in a config file in the key tag; the
+// sections are tables in the file. Options after the variable name mark it
+// required or secret, and oneof lists the values it may take.
+type Config struct {
+	Server       ServerConfig       `key:"server"`
+	Database     DatabaseConfig     `key:"database"`
+	Security     SecurityConfig     `key:"security"`
+	Registration RegistrationConfig `key:"registration"`
+	Mail         MailConfig         `key:"mail"`
+	Jobs         JobsConfig         `key:"jobs"`
+	Metrics      MetricsConfig      `key:"metrics"`
+	Tracing      TracingConfig      `key:"tracing"`
+	AccessLog    AccessLogConfig    `key:"access_log"`
+	Shutdown     ShutdownConfig     `key:"shutdown"`
+	Storage      StorageConfig      `key:"storage"`
+	Cloudinary   CloudinaryConfig   `key:"cloudinary"`
+	S3           S3Config           `key:"s3"`
+	Images       ImagesConfig       `key:"images"`
+	Features     FeaturesConfig     `key:"features"`
+	Admin        AdminConfig        `key:"admin"`
+	App          AppConfig          `key:"app"`
+
+	// sources records where each variable's value came from, for Print.
+	sources map[string]string
 }
 
-func load() {
-	once.Do(func() {
-		c = &cfg{
-			// Server
-			port:        getEnv("PORT", "8080"),
-			environment: getEnv("ENVIRONMENT", "development"),
-
-			// Database
-			dbHost:     getEnv("DBHOST", "localhost"),
-			dbPort:     getEnv("DBPORT", "5432"),
-			dbUser:     getEnv("DBUSER", "postgres"),
-			dbPassword: getEnv("DBPASSWORD", ""),
-			dbName:     getEnv("DBNAME", "dviji_se"),
-			dbSSLMode:  getEnv("DBSSLMODE", "disable"),
-			dbMaxConns: getEnvInt("DBCONNECTIONS", 10),
-
-			// JWT
-			jwtAccessKey:  getEnvRequired("JWT_KEY"),
-			jwtRefreshKey: getEnvRequired("JWT_REFRESH_KEY"),
-
-			// Security
-			xsrfKey:     getEnvRequired("XSRF"),
-			corsOrigins: getEnvSlice("CORS_ORIGINS", ",", []string{"http://localhost:3000"}),
-			// Off by default: this is a single author blog, so public
-			// registration is opt in rather than something you must remember
-			// to switch off.
-			allowRegistration: getEnvBool("ALLOW_REGISTRATION", false),
-			trustedProxies:    getEnvPrefixes("TRUSTED_PROXIES"),
-
-			// SMTP
-			smtpHost:     getEnv("SMTP_HOST", ""),
-			smtpPort:     getEnv("SMTP_PORT", "587"),
-			smtpUsername: getEnv("SMTP_USERNAME", ""),
-			smtpPassword: getEnv("SMTP_PASSWORD", ""),
-			smtpFrom:     getEnv("SMTP_FROM", "noreply@example.com"),
-
-			// Cloudinary
-			cloudinaryCloudName: getEnv("CLOUDINARY_CLOUD_NAME", ""),
-			cloudinaryAPIKey:    getEnv("CLOUDINARY_API_KEY", ""),
-			cloudinaryAPISecret: getEnv("CLOUDINARY_API_SECRET", ""),
-			cloudinaryFolder:    getEnv("CLOUDINARY_FOLDER", "uploads"),
-
-			// Feature flags: the sections are hidden until their pages exist.
-			workoutsEnabled:  getEnvBool("ENABLED_WORKOUTS", false),
-			nutritionEnabled: getEnvBool("ENABLED_NUTRITION", false),
-
-			// Admin bootstrap
-			adminEmail:    getEnv("ADMIN_EMAIL", ""),
-			adminPassword: getEnv("ADMIN_PASSWORD", ""),
-
-			// App
-			baseURL:    getEnv("APP_BASE_URL", "http://localhost:8080"),
-			tinymceURL: getEnv("TINYMCE_URL", ""),
-		}
+type ServerConfig struct {
+	Port        string `env:"PORT" key:"port" default:"8080"`
+	Environment string `env:"ENVIRONMENT" key:"environment" default:"development"`
+}
+
+type DatabaseConfig struct {
+	Host     string `env:"DBHOST" key:"host" default:"localhost"`
+	Port     string `env:"DBPORT" key:"port" default:"5432"`
+	User     string `env:"DBUSER" key:"user" default:"postgres"`
+	Password string `env:"DBPASSWORD,secret" key:"password"`
+	Name     string `env:"DBNAME" key:"name" default:"dviji_se"`
+	SSLMode  string `env:"DBSSLMODE" key:"ssl_mode" default:"disable" oneof:"disable allow prefer require verify-ca verify-full"`
+	MaxConns int    `env:"DBCONNECTIONS" key:"max_connections" default:"10"`
+}
+
+type SecurityConfig struct {
+	JWTAccessKey  string   `env:"JWT_KEY,required,secret" key:"jwt_key"`
+	JWTRefreshKey string   `env:"JWT_REFRESH_KEY,required,secret" key:"jwt_refresh_key"`
+	XSRFKey       string   `env:"XSRF,required,secret" key:"xsrf_key"`
+	CORSOrigins   []string `env:"CORS_ORIGINS" key:"cors_origins" default:"http://localhost:3000"`
+	// Off by default: this is a single author blog, so public registration
+	// is opt in rather than something you must remember to switch off.
+	AllowRegistration  bool           `env:"ALLOW_REGISTRATION" key:"allow_registration" default:"false"`
+	TrustedProxies     []netip.Prefix `env:"TRUSTED_PROXIES" key:"trusted_proxies"`
+	AuditRetentionDays int            `env:"AUDIT_RETENTION_DAYS" key:"audit_retention_days" default:"180"`
+	SpamThreshold      int            `env:"SPAM_THRESHOLD" key:"spam_threshold" default:"50"`
+}
+
+type RegistrationConfig struct {
+	UnverifiedAccountDays int `env:"UNVERIFIED_ACCOUNT_DAYS" key:"unverified_account_days" default:"7"`
+	DeletionGraceDays     int `env:"ACCOUNT_DELETION_GRACE_DAYS" key:"deletion_grace_days" default:"14"`
+}
+
+type MailConfig struct {
+	Transport    string `env:"MAIL_TRANSPORT" key:"transport" oneof:"smtp sendmail file log"`
+	SMTPHost     string `env:"SMTP_HOST" key:"smtp_host"`
+	SMTPPort     string `env:"SMTP_PORT" key:"smtp_port" default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME" key:"smtp_username"`
+	SMTPPassword string `env:"SMTP_PASSWORD,secret" key:"smtp_password"`
+	SMTPFrom     string `env:"SMTP_FROM" key:"smtp_from" default:"noreply@example.com"`
+	SMTPTLS      string `env:"SMTP_TLS" key:"smtp_tls" default:"starttls" oneof:"starttls tls none"`
+	SendmailPath string `env:"SENDMAIL_PATH" key:"sendmail_path" default:"/usr/sbin/sendmail"`
+	DropDir      string `env:"MAIL_DROP_DIR" key:"drop_dir" default:"mail"`
+	DKIMDomain   string `env:"DKIM_DOMAIN" key:"dkim_domain"`
+	DKIMSelector string `env:"DKIM_SELECTOR" key:"dkim_selector"`
+	DKIMKeyFile  string `env:"DKIM_PRIVATE_KEY_FILE" key:"dkim_private_key_file"`
+	// MaxAttempts is how many delivery attempts a queued email gets.
+	MaxAttempts int `env:"EMAIL_MAX_ATTEMPTS" key:"max_attempts" default:"8"`
+}
+
+type JobsConfig struct {
+	Concurrency int `env:"JOB_CONCURRENCY" key:"concurrency" default:"4"`
+}
+
+type MetricsConfig struct {
+	Token string `env:"METRICS_TOKEN,secret" key:"token"`
+	Addr  string `env:"METRICS_ADDR" key:"addr"`
+}
+
+type TracingConfig struct {
+	Exporter string `env:"OTEL_TRACES_EXPORTER" key:"exporter" default:"none" oneof:"none otlp stdout console"`
+}
+
+type AccessLogConfig struct {
+	Format        string `env:"ACCESS_LOG_FORMAT" key:"format" default:"logfmt" oneof:"json logfmt off"`
+	File          string `env:"ACCESS_LOG_FILE" key:"file"`
+	MaxSizeMB     int    `env:"ACCESS_LOG_MAX_SIZE_MB" key:"max_size_mb" default:"100"`
+	MaxBackups    int    `env:"ACCESS_LOG_MAX_BACKUPS" key:"max_backups" default:"5"`
+	StaticPercent int    `env:"ACCESS_LOG_STATIC_SAMPLE_PERCENT" key:"static_sample_percent" default:"10"`
+}
+
+type ShutdownConfig struct {
+	DrainSeconds int `env:"SHUTDOWN_DRAIN_SECONDS" key:"drain_seconds" default:"5"`
+}
+
+type StorageConfig struct {
+	// Backend is empty by default, which means Cloudinary when it is
+	// configured and the local directory otherwise.
+	Backend  string `env:"STORAGE_BACKEND" key:"backend" oneof:"cloudinary local s3"`
+	LocalDir string `env:"STORAGE_LOCAL_DIR" key:"local_dir" default:"media"`
+}
+
+type CloudinaryConfig struct {
+	CloudName string `env:"CLOUDINARY_CLOUD_NAME" key:"cloud_name"`
+	APIKey    string `env:"CLOUDINARY_API_KEY" key:"api_key"`
+	APISecret string `env:"CLOUDINARY_API_SECRET,secret" key:"api_secret"`
+	Folder    string `env:"CLOUDINARY_FOLDER" key:"folder" default:"uploads"`
+}
+
+type S3Config struct {
+	Endpoint        string `env:"S3_ENDPOINT" key:"endpoint"`
+	Region          string `env:"S3_REGION" key:"region" default:"us-east-1"`
+	Bucket          string `env:"S3_BUCKET" key:"bucket"`
+	AccessKeyID     string `env:"S3_ACCESS_KEY_ID" key:"access_key_id"`
+	SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY,secret" key:"secret_access_key"`
+	PublicURL       string `env:"S3_PUBLIC_URL" key:"public_url"`
+}
+
+// ImagesConfig is how uploaded images are processed, and how long unused
+// ones are kept. WebP and AVIF are encoded by external commands; empty leaves
+// the format out.
+type ImagesConfig struct {
+	Quality     int    `env:"IMAGE_QUALITY" key:"quality" default:"82"`
+	WebPEncoder string `env:"IMAGE_WEBP_ENCODER" key:"webp_encoder" default:"cwebp"`
+	AVIFEncoder string `env:"IMAGE_AVIF_ENCODER" key:"avif_encoder" default:"avifenc"`
+	// UnusedGraceDays is how long an image nothing shows is kept before the
+	// sweep deletes it.
+	UnusedGraceDays int `env:"IMAGE_UNUSED_GRACE_DAYS" key:"unused_grace_days" default:"30"`
+}
+
+// FeaturesConfig hides sections until their pages exist.
+type FeaturesConfig struct {
+	Workouts  bool `env:"ENABLED_WORKOUTS" key:"workouts" default:"false"`
+	Nutrition bool `env:"ENABLED_NUTRITION" key:"nutrition" default:"false"`
+}
+
+type AdminConfig struct {
+	Email    string `env:"ADMIN_EMAIL" key:"email"`
+	Password string `env:"ADMIN_PASSWORD,secret" key:"password"`
+}
 
-		// The privacy policy has to name an address people can write to, so
-		// this falls back to privacy@<site host> rather than leaving the page
-		// telling visitors to contact nobody.
-		c.privacyContactEmail = getEnv("PRIVACY_CONTACT_EMAIL", defaultContactEmail(c.baseURL))
+type AppConfig struct {
+	BaseURL    string `env:"APP_BASE_URL" key:"base_url" default:"http://localhost:8080"`
+	TinyMCEURL string `env:"TINYMCE_URL" key:"tinymce_url"`
+	// PrivacyContactEmail falls back to privacy@<site host> when unset, see
+	// Load.
+	PrivacyContactEmail string `env:"PRIVACY_CONTACT_EMAIL" key:"privacy_contact_email"`
+}
+
+// Init loads the configuration from CONFIG_FILE, if set, and the environment,
+// and reports every problem with it. The server calls it first thing so a bad
+// deployment stops with the whole list; anything that reads a setting before
+// that loads it on first use instead.
+func Init() error {
+	once.Do(func() {
+		c, loadErr = Load(os.Getenv("CONFIG_FILE"))
 	})
+	return loadErr
 }
 
-func get() *cfg {
-	if c == nil {
-		load()
+func get() *Config {
+	if err := Init(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 	return c
 }
 
 // --- Server ---
 
-func Port() string        { return get().port }
-func Environment() string { return get().environment }
-func IsDevelopment() bool { return get().environment == "development" }
-func IsProduction() bool  { return get().environment == "production" }
-func IsTest() bool        { return get().environment == "test" }
+func Port() string        { return get().Server.Port }
+func Environment() string { return get().Server.Environment }
+func IsDevelopment() bool { return get().Server.Environment == "development" }
+func IsProduction() bool  { return get().Server.Environment == "production" }
+func IsTest() bool        { return get().Server.Environment == "test" }
 
 // --- Database ---
 
-func DBHost() string     { return get().dbHost }
-func DBPort() string     { return get().dbPort }
-func DBUser() string     { return get().dbUser }
-func DBPassword() string { return get().dbPassword }
-func DBName() string     { return get().dbName }
-func DBSSLMode() string  { return get().dbSSLMode }
-func DBMaxConns() int    { return get().dbMaxConns }
+func DBHost() string     { return get().Database.Host }
+func DBPort() string     { return get().Database.Port }
+func DBUser() string     { return get().Database.User }
+func DBPassword() string { return get().Database.Password }
+func DBName() string     { return get().Database.Name }
+func DBSSLMode() string  { return get().Database.SSLMode }
+func DBMaxConns() int    { return get().Database.MaxConns }
 
 // --- JWT ---
 
-func JWTAccessKey() string  { return get().jwtAccessKey }
-func JWTRefreshKey() string { return get().jwtRefreshKey }
+func JWTAccessKey() string  { return get().Security.JWTAccessKey }
+func JWTRefreshKey() string { return get().Security.JWTRefreshKey }
 
 // --- Security ---
 
-func XSRFKey() string         { return get().xsrfKey }
-func CORSOrigins() []string   { return get().corsOrigins }
-func AllowRegistration() bool { return get().allowRegistration }
+func XSRFKey() string         { return get().Security.XSRFKey }
+func CORSOrigins() []string   { return get().Security.CORSOrigins }
+func AllowRegistration() bool { return get().Security.AllowRegistration }
 
 // TrustedProxies lists the networks whose X-Forwarded-For / X-Real-IP headers
 // may be believed. Empty means trust none, which is the safe default: any
 // client can set those headers, so believing them without a proxy in front
 // lets a caller forge its own address.
-func TrustedProxies() []netip.Prefix { return get().trustedProxies }
+func TrustedProxies() []netip.Prefix { return get().Security.TrustedProxies }
+
+// AuditRetention is how long audit events are kept before the sweep deletes
+// them.
+func AuditRetention() time.Duration {
+	return time.Duration(get().Security.AuditRetentionDays) * 24 * time.Hour
+}
+
+// SpamThreshold is the score at which a public form submission is held for
+// review or challenged instead of being accepted outright.
+func SpamThreshold() int { return get().Security.SpamThreshold }
+
+// --- Registration ---
+
+// UnverifiedAccountTTL is how long a self-registered account may wait for its
+// email to be verified before it is deleted.
+func UnverifiedAccountTTL() time.Duration {
+	return time.Duration(get().Registration.UnverifiedAccountDays) * 24 * time.Hour
+}
+
+// AccountDeletionGrace is how long a confirmed deletion waits, during which
+// the owner can still sign in and cancel it.
+func AccountDeletionGrace() time.Duration {
+	return time.Duration(get().Registration.DeletionGraceDays) * 24 * time.Hour
+}
 
 // --- SMTP ---
 
-func SMTPHost() string     { return get().smtpHost }
-func SMTPPort() string     { return get().smtpPort }
-func SMTPUsername() string { return get().smtpUsername }
-func SMTPPassword() string { return get().smtpPassword }
-func SMTPFrom() string     { return get().smtpFrom }
-func SMTPConfigured() bool { return get().smtpHost != "" && get().smtpUsername != "" }
+func SMTPHost() string     { return get().Mail.SMTPHost }
+func SMTPPort() string     { return get().Mail.SMTPPort }
+func SMTPUsername() string { return get().Mail.SMTPUsername }
+func SMTPPassword() string { return get().Mail.SMTPPassword }
+func SMTPFrom() string     { return get().Mail.SMTPFrom }
+func SMTPConfigured() bool { return get().Mail.SMTPHost != "" && get().Mail.SMTPUsername != "" }
+
+// SMTPTLS is how the SMTP connection is secured: "starttls" upgrades a plain
+// connection and refuses servers that cannot, "tls" is implicit TLS (usually
+// port 465), and "none" is for a relay on the same machine or network.
+func SMTPTLS() string { return get().Mail.SMTPTLS }
+
+// --- Mail transport ---
+
+// MailTransport picks how email leaves the app: "smtp", "sendmail", "file"
+// (an .eml file per message, for development and tests) or "log". Empty means
+// SMTP when SMTP_HOST is set and the log otherwise.
+func MailTransport() string { return get().Mail.Transport }
+func SendmailPath() string  { return get().Mail.SendmailPath }
+func MailDropDir() string   { return get().Mail.DropDir }
+
+// DKIM signing is on when a selector and key file are set. The domain falls
+// back to the one in SMTP_FROM.
+func DKIMDomain() string         { return get().Mail.DKIMDomain }
+func DKIMSelector() string       { return get().Mail.DKIMSelector }
+func DKIMPrivateKeyFile() string { return get().Mail.DKIMKeyFile }
+
+// EmailMaxAttempts is how many times a queued email is tried before it is
+// marked dead and left for an administrator.
+func EmailMaxAttempts() int { return get().Mail.MaxAttempts }
+
+// --- Background jobs ---
+
+// JobConcurrency is how many background jobs one process runs at a time.
+func JobConcurrency() int { return get().Jobs.Concurrency }
+
+// --- Metrics ---
+
+// MetricsToken is the bearer token that unlocks /metrics on the public port.
+// Empty leaves the route unregistered.
+func MetricsToken() string { return get().Metrics.Token }
+
+// MetricsAddr is a separate listen address, normally on a private interface,
+// that serves /metrics without a token. Empty starts no listener.
+func MetricsAddr() string { return get().Metrics.Addr }
+
+// --- Tracing ---
+
+// TracesExporter is where spans go: "otlp", "stdout" or "none". The OTLP
+// exporter reads its endpoint from the standard OTEL_EXPORTER_OTLP_*
+// variables.
+func TracesExporter() string { return get().Tracing.Exporter }
+
+// --- Access log ---
+
+// AccessLogFormat is "logfmt", "json" or "off".
+func AccessLogFormat() string { return get().AccessLog.Format }
+
+// AccessLogFile is where the access log goes, rotated by size. Empty means
+// standard output.
+func AccessLogFile() string    { return get().AccessLog.File }
+func AccessLogMaxBytes() int64 { return int64(get().AccessLog.MaxSizeMB) << 20 }
+func AccessLogMaxBackups() int { return get().AccessLog.MaxBackups }
+
+// AccessLogStaticSample is the share of successful static asset requests
+// that are logged, from 0 to 1.
+func AccessLogStaticSample() float64 {
+	return float64(min(max(get().AccessLog.StaticPercent, 0), 100)) / 100
+}
+
+// --- Shutdown ---
+
+// ShutdownDrain is how long /readyz fails before the server stops taking
+// connections, for load balancers to notice and send traffic elsewhere.
+func ShutdownDrain() time.Duration {
+	return time.Duration(get().Shutdown.DrainSeconds) * time.Second
+}
+
+// --- Storage ---
+
+// StorageBackend is where uploads go: "cloudinary", "local" or "s3". Unset,
+// it is Cloudinary when that is configured, so existing setups keep working,
+// and the local directory otherwise.
+func StorageBackend() string {
+	if backend := get().Storage.Backend; backend != "" {
+		return backend
+	}
+	if CloudinaryConfigured() {
+		return "cloudinary"
+	}
+	return "local"
+}
+
+// StorageLocalDir is the directory the local backend writes to and serves
+// under /media/.
+func StorageLocalDir() string { return get().Storage.LocalDir }
+
+// StorageOrigin is the origin uploads are served from when it is not this
+// site, for the Content-Security-Policy. Empty for the local backend.
+func StorageOrigin() string {
+	switch StorageBackend() {
+	case "cloudinary":
+		return "https://res.cloudinary.com"
+	case "s3":
+		base := get().S3.PublicURL
+		if base == "" {
+			base = get().S3.Endpoint
+		}
+		if parsed, err := url.Parse(base); err == nil && parsed.Host != "" {
+			return parsed.Scheme + "://" + parsed.Host
+		}
+	}
+	return ""
+}
 
 // --- Cloudinary ---
 
-func CloudinaryCloudName() string { return get().cloudinaryCloudName }
-func CloudinaryAPIKey() string    { return get().cloudinaryAPIKey }
-func CloudinaryAPISecret() string { return get().cloudinaryAPISecret }
-func CloudinaryFolder() string    { return get().cloudinaryFolder }
+func CloudinaryCloudName() string { return get().Cloudinary.CloudName }
+func CloudinaryAPIKey() string    { return get().Cloudinary.APIKey }
+func CloudinaryAPISecret() string { return get().Cloudinary.APISecret }
+func CloudinaryFolder() string    { return get().Cloudinary.Folder }
 func CloudinaryConfigured() bool {
-	return get().cloudinaryCloudName != "" && get().cloudinaryAPIKey != "" && get().cloudinaryAPISecret != ""
+	return get().Cloudinary.CloudName != "" && get().Cloudinary.APIKey != "" && get().Cloudinary.APISecret != ""
+}
+
+// --- S3 ---
+
+// The S3 settings apply when STORAGE_BACKEND is "s3". S3_ENDPOINT is the
+// service URL, e.g. https://s3.eu-central-1.amazonaws.com or
+// http://localhost:9000 for MinIO. S3_PUBLIC_URL is where visitors read the
+// bucket from, usually a CDN; unset, it is the endpoint followed by the
+// bucket.
+func S3Endpoint() string        { return get().S3.Endpoint }
+func S3Region() string          { return get().S3.Region }
+func S3Bucket() string          { return get().S3.Bucket }
+func S3AccessKeyID() string     { return get().S3.AccessKeyID }
+func S3SecretAccessKey() string { return get().S3.SecretAccessKey }
+func S3PublicURL() string       { return get().S3.PublicURL }
+
+// --- Images ---
+
+// Uploaded images are re-encoded without their metadata, and on the local
+// and S3 backends copied at smaller widths and in WebP and AVIF. The copies
+// in those two formats are made by cwebp and avifenc, found on the PATH
+// unless given as paths; a format whose command is missing is skipped.
+func ImageQuality() int        { return get().Images.Quality }
+func ImageWebPEncoder() string { return get().Images.WebPEncoder }
+func ImageAVIFEncoder() string { return get().Images.AVIFEncoder }
+
+// ImageUnusedGrace is how long an uploaded image may go unused - uploaded
+// for a post never saved, or dropped from every post - before the hourly
+// sweep deletes it. Putting it back in a post before then keeps it.
+func ImageUnusedGrace() time.Duration {
+	return time.Duration(get().Images.UnusedGraceDays) * 24 * time.Hour
 }
 
 // --- Feature flags ---
@@ -196,26 +413,26 @@ func CloudinaryConfigured() bool {
 // route still has to exist or it will 404.
 
 // WorkoutsEnabled reports whether the workouts section is advertised.
-func WorkoutsEnabled() bool { return get().workoutsEnabled }
+func WorkoutsEnabled() bool { return get().Features.Workouts }
 
 // NutritionEnabled reports whether the nutrition section is advertised.
-func NutritionEnabled() bool { return get().nutritionEnabled }
+func NutritionEnabled() bool { return get().Features.Nutrition }
 
 // --- Admin bootstrap ---
 
 // AdminEmail and AdminPassword seed the first administrator when the database
 // has none. They are ignored once an administrator exists.
-func AdminEmail() string    { return get().adminEmail }
-func AdminPassword() string { return get().adminPassword }
+func AdminEmail() string    { return get().Admin.Email }
+func AdminPassword() string { return get().Admin.Password }
 
 // --- App ---
 
-func BaseURL() string    { return get().baseURL }
-func TinyMCEURL() string { return get().tinymceURL }
+func BaseURL() string    { return get().App.BaseURL }
+func TinyMCEURL() string { return get().App.TinyMCEURL }
 
 // PrivacyContactEmail is the address the privacy policy points data subjects
 // at. Set PRIVACY_CONTACT_EMAIL to a mailbox that is actually read.
-func PrivacyContactEmail() string { return get().privacyContactEmail }
+func PrivacyContactEmail() string { return get().App.PrivacyContactEmail }
 
 // defaultContactEmail derives privacy@<host> from the site's base URL. A base
 // URL that does not parse leaves the address empty, and the policy then omits
@@ -233,82 +450,3 @@ func defaultContactEmail(baseURL string) string {
 
 	return "privacy@" + host
 }
-
-// --- Helpers ---
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvRequired(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		log.Fatalf("Required environment variable %s is not set", key)
-	}
-	return value
-}
-
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			return intVal
-		}
-	}
-	return defaultValue
-}
-
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		return value == "true" || value == "1" || value == "yes"
-	}
-	return defaultValue
-}
-
-// getEnvPrefixes reads a comma separated list of CIDRs or bare IP addresses.
-// Entries that do not parse are dropped with a warning rather than failing
-// startup, so one bad entry cannot take the service down.
-func getEnvPrefixes(key string) []netip.Prefix {
-	var prefixes []netip.Prefix
-
-	for _, entry := range getEnvSlice(key, ",", nil) {
-		if strings.Contains(entry, "/") {
-			prefix, err := netip.ParsePrefix(entry)
-			if err != nil {
-				log.Printf("Ignoring invalid CIDR %q in %s: %v", entry, key, err)
-				continue
-			}
-
-			prefixes = append(prefixes, prefix)
-			continue
-		}
-
-		addr, err := netip.ParseAddr(entry)
-		if err != nil {
-			log.Printf("Ignoring invalid IP %q in %s: %v", entry, key, err)
-			continue
-		}
-
-		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
-	}
-
-	return prefixes
-}
-
-func getEnvSlice(key, separator string, defaultValue []string) []string {
-	if value := os.Getenv(key); value != "" {
-		parts := strings.Split(value, separator)
-		result := make([]string, 0, len(parts))
-		for _, p := range parts {
-			if trimmed := strings.TrimSpace(p); trimmed != "" {
-				result = append(result, trimmed)
-			}
-		}
-		if len(result) > 0 {
-			return result
-		}
-	}
-	return defaultValue
-}
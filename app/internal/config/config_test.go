@@ -1,7 +1,11 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -51,3 +55,170 @@ func TestFeatureFlags_ToggleIndependently(t *testing.T) {
 	}
 
 }
+
+// A typo in one variable must not hide the next: the deploy that fails should
+// list everything to fix.
+func TestCheck_ListsEveryProblem(t *testing.T) {
+	t.Setenv("JWT_KEY", "k")
+	t.Setenv("JWT_REFRESH_KEY", "")
+	t.Setenv("XSRF", "k")
+	t.Setenv("JOB_CONCURRENCY", "four")
+	t.Setenv("ACCESS_LOG_FORMAT", "xml")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,not-an-ip")
+
+	problems := strings.Join(Check(), "\n")
+
+	for _, want := range []string{"JWT_REFRESH_KEY is required", "JOB_CONCURRENCY", "ACCESS_LOG_FORMAT", `"not-an-ip"`} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Check() does not mention %s:\n%s", want, problems)
+		}
+	}
+	if strings.Contains(problems, "10.0.0.0/8") {
+		t.Errorf("a valid CIDR was reported:\n%s", problems)
+	}
+}
+
+// writeFile puts a config file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// problemsOf returns the problems of a Load error, failing on any other error.
+func problemsOf(t *testing.T, err error) string {
+	t.Helper()
+
+	var invalid *ValidationError
+	if err != nil && !errors.As(err, &invalid) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	if invalid == nil {
+		return ""
+	}
+	return strings.Join(invalid.Problems, "\n")
+}
+
+// The file holds what is shared between deployments and the environment what
+// differs, so a variable that is set must win over the file, and the file
+// over the default. YAML and TOML must read the same.
+func TestLoad_EnvironmentOverridesFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "server:\n  port: 9090\njobs:\n  concurrency: 2\nsecurity:\n  trusted_proxies: [10.0.0.0/8, 192.168.1.1]\n",
+		"config.toml": "[server]\nport = 9090\n[jobs]\nconcurrency = 2\n[security]\ntrusted_proxies = [\"10.0.0.0/8\", \"192.168.1.1\"]\n",
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("JWT_KEY", "k")
+			t.Setenv("JWT_REFRESH_KEY", "k")
+			t.Setenv("XSRF", "k")
+			t.Setenv("PORT", "")
+			t.Setenv("JOB_CONCURRENCY", "6")
+
+			cfg, err := Load(writeFile(t, name, content))
+			if problems := problemsOf(t, err); problems != "" {
+				t.Fatalf("Load() problems:\n%s", problems)
+			}
+
+			if cfg.Server.Port != "9090" {
+				t.Errorf("Port = %q, want the file's 9090", cfg.Server.Port)
+			}
+			if cfg.Jobs.Concurrency != 6 {
+				t.Errorf("Concurrency = %d, want the environment's 6", cfg.Jobs.Concurrency)
+			}
+			if cfg.Database.MaxConns != 10 {
+				t.Errorf("MaxConns = %d, want the default 10", cfg.Database.MaxConns)
+			}
+			if len(cfg.Security.TrustedProxies) != 2 || cfg.Security.TrustedProxies[1].String() != "192.168.1.1/32" {
+				t.Errorf("TrustedProxies = %v, want the CIDR and the bare IP", cfg.Security.TrustedProxies)
+			}
+		})
+	}
+}
+
+// A misspelt key in the file would otherwise leave the default in place
+// without anyone noticing.
+func TestLoad_UnknownFileKeyIsAProblem(t *testing.T) {
+	t.Setenv("JWT_KEY", "k")
+	t.Setenv("JWT_REFRESH_KEY", "k")
+	t.Setenv("XSRF", "k")
+
+	_, err := Load(writeFile(t, "config.yaml", "jobs:\n  concurency: 2\n"))
+
+	if problems := problemsOf(t, err); !strings.Contains(problems, "jobs.concurency") {
+		t.Errorf("the misspelt key was not reported:\n%s", problems)
+	}
+}
+
+// The keys in the compose file and CI are public, and a plain http base URL
+// would break the Secure cookies, so production refuses both. Development
+// must keep accepting them or local setups stop working.
+func TestLoad_ProductionRules(t *testing.T) {
+	t.Setenv("JWT_KEY", "local-dev-jwt-key")
+	t.Setenv("JWT_REFRESH_KEY", "local-dev-jwt-key")
+	t.Setenv("XSRF", "local-dev-xsrf-key")
+	t.Setenv("APP_BASE_URL", "http://dviji.se")
+	t.Setenv("ALLOW_REGISTRATION", "true")
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("MAIL_TRANSPORT", "")
+
+	t.Setenv("ENVIRONMENT", "development")
+	if _, err := Load(""); err != nil {
+		t.Fatalf("development Load() error = %v", err)
+	}
+
+	t.Setenv("ENVIRONMENT", "production")
+	_, err := Load("")
+	problems := problemsOf(t, err)
+
+	for _, want := range []string{
+		"APP_BASE_URL must use https",
+		"JWT_KEY is a development key",
+		"XSRF is shorter than 32",
+		"JWT_KEY and JWT_REFRESH_KEY must be different",
+		"ALLOW_REGISTRATION is on but no SMTP server",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("production problems do not mention %q:\n%s", want, problems)
+		}
+	}
+
+	t.Setenv("JWT_KEY", strings.Repeat("a", 64))
+	t.Setenv("JWT_REFRESH_KEY", strings.Repeat("b", 64))
+	t.Setenv("XSRF", strings.Repeat("c", 64))
+	t.Setenv("APP_BASE_URL", "https://dviji.se")
+	t.Setenv("SMTP_HOST", "smtp.dviji.se")
+	if _, err := Load(""); err != nil {
+		t.Errorf("a correct production Load() error = %v", err)
+	}
+}
+
+// config print output ends up in issues and chat, so a set secret must never
+// appear in it, while an unset one should still be visible as missing.
+func TestPrint_RedactsSecrets(t *testing.T) {
+	t.Setenv("JWT_KEY", "access-secret")
+	t.Setenv("JWT_REFRESH_KEY", "refresh-secret")
+	t.Setenv("XSRF", "xsrf-secret")
+	t.Setenv("DBPASSWORD", "")
+
+	cfg, _ := Load("")
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out.String(), "secret") {
+		t.Errorf("a secret was printed:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "JWT_KEY="+redacted) {
+		t.Errorf("JWT_KEY is not shown as redacted:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "DBPASSWORD= ") {
+		t.Errorf("the unset DBPASSWORD is not shown empty:\n%s", out.String())
+	}
+}
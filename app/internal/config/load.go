@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Where a value came from, as shown by Print. Later sources win: a variable
+// set in the environment overrides the file, which overrides the default.
+const (
+	fromDefault = "default"
+	fromFile    = "file"
+	fromEnv     = "env"
+	fromDerived = "derived"
+)
+
+// setting is one field of Config with what its tags say about it.
+type setting struct {
+	section  string
+	key      string
+	env      string
+	def      string
+	required bool
+	secret   bool
+	oneof    []string
+	value    reflect.Value
+}
+
+// Load builds a Config from the defaults, then the YAML or TOML file at path
+// when path is not empty, then the environment, and validates the result.
+// The error lists every problem found rather than the first, so one failed
+// start is enough to fix them all. The Config is returned either way, for
+// Print to show what was read.
+func Load(path string) (*Config, error) {
+	cfg := &Config{sources: map[string]string{}}
+	settings := settingsOf(cfg)
+
+	var problems []string
+
+	for _, s := range settings {
+		if s.def == "" {
+			continue
+		}
+		if err := s.set(s.split(s.def)); err != nil {
+			panic(fmt.Sprintf("config: default of %s: %v", s.env, err))
+		}
+		cfg.sources[s.env] = fromDefault
+	}
+
+	if path != "" {
+		problems = append(problems, loadFile(cfg, settings, path)...)
+	}
+
+	for _, s := range settings {
+		value, ok := os.LookupEnv(s.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := s.set(s.split(value)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.env, err))
+			continue
+		}
+		cfg.sources[s.env] = fromEnv
+	}
+
+	// The privacy policy has to name an address people can write to, so
+	// this falls back to privacy@<site host> rather than leaving the page
+	// telling visitors to contact nobody.
+	if cfg.App.PrivacyContactEmail == "" {
+		cfg.App.PrivacyContactEmail = defaultContactEmail(cfg.App.BaseURL)
+		cfg.sources["PRIVACY_CONTACT_EMAIL"] = fromDerived
+	}
+
+	problems = append(problems, cfg.validate(settings)...)
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return cfg, &ValidationError{Problems: problems}
+	}
+
+	return cfg, nil
+}
+
+// loadFile applies a config file. Keys that match no setting are reported,
+// since a misspelt key would otherwise leave its default in place unnoticed.
+func loadFile(cfg *Config, settings []setting, path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("CONFIG_FILE: %v", err)}
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return []string{fmt.Sprintf("CONFIG_FILE: %q is not a .yaml, .yml or .toml file", path)}
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("CONFIG_FILE: %s: %v", path, err)}
+	}
+
+	var problems []string
+
+	for section, body := range doc {
+		values, ok := body.(map[string]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: [%s] is not a section", path, section))
+			continue
+		}
+
+		for key, raw := range values {
+			i := slices.IndexFunc(settings, func(s setting) bool { return s.section == section && s.key == key })
+			if i < 0 {
+				problems = append(problems, fmt.Sprintf("%s: unknown setting %s.%s", path, section, key))
+				continue
+			}
+
+			s := settings[i]
+			if err := s.set(s.fileValues(raw)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s.%s: %v", path, section, key, err))
+				continue
+			}
+			cfg.sources[s.env] = fromFile
+		}
+	}
+
+	return problems
+}
+
+// settingsOf walks the sections of cfg in declaration order.
+func settingsOf(cfg *Config) []setting {
+	var settings []setting
+
+	root := reflect.ValueOf(cfg).Elem()
+	for i := range root.NumField() {
+		sectionField := root.Type().Field(i)
+		if !sectionField.IsExported() {
+			continue
+		}
+
+		section := root.Field(i)
+		for j := range section.NumField() {
+			field := section.Type().Field(j)
+			name, options, _ := strings.Cut(field.Tag.Get("env"), ",")
+
+			s := setting{
+				section: sectionField.Tag.Get("key"),
+				key:     field.Tag.Get("key"),
+				env:     name,
+				def:     field.Tag.Get("default"),
+				oneof:   strings.Fields(field.Tag.Get("oneof")),
+				value:   section.Field(j),
+			}
+			for _, option := range strings.Split(options, ",") {
+				switch option {
+				case "required":
+					s.required = true
+				case "secret":
+					s.secret = true
+				}
+			}
+
+			settings = append(settings, s)
+		}
+	}
+
+	return settings
+}
+
+// set parses values into the field. Scalars take the first value; lists take
+// them all.
+func (s setting) set(values []string) error {
+	if s.value.Kind() != reflect.Slice && len(values) > 1 {
+		return fmt.Errorf("takes one value, not a list")
+	}
+
+	var first string
+	if len(values) > 0 {
+		first = values[0]
+	}
+
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(first)
+	case int:
+		n, err := strconv.Atoi(first)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", first)
+		}
+		s.value.SetInt(int64(n))
+	case bool:
+		b, err := parseBool(first)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	case []string:
+		s.value.Set(reflect.ValueOf(values))
+	case []netip.Prefix:
+		prefixes := make([]netip.Prefix, 0, len(values))
+		for _, entry := range values {
+			prefix, err := parsePrefix(entry)
+			if err != nil {
+				return err
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		s.value.Set(reflect.ValueOf(prefixes))
+	default:
+		panic(fmt.Sprintf("config: %s has unsupported type %s", s.env, s.value.Type()))
+	}
+
+	return nil
+}
+
+// text is the value as it would be written in the environment.
+func (s setting) text() string {
+	switch v := s.value.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []netip.Prefix:
+		entries := make([]string, len(v))
+		for i, prefix := range v {
+			entries[i] = prefix.String()
+		}
+		return strings.Join(entries, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// split reads a value as written in the environment. Lists are comma
+// separated; anything else is taken whole, since a password or a display name
+// may well contain a comma.
+func (s setting) split(value string) []string {
+	if s.value.Kind() != reflect.Slice {
+		return []string{value}
+	}
+
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	return values
+}
+
+// fileValues turns a decoded YAML or TOML value into the strings set parses,
+// so the file and the environment go through the same checks. A list may
+// also be written as one comma separated string, as in the environment.
+func (s setting) fileValues(raw any) []string {
+	if raw == nil {
+		return nil
+	}
+
+	list, ok := raw.([]any)
+	if !ok {
+		return s.split(fmt.Sprint(raw))
+	}
+
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, fmt.Sprint(item))
+	}
+	return values
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "1", "yes", "on":
+		return true, nil
+	case "false", "0", "no", "off", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not true or false", value)
+}
+
+// parsePrefix reads a CIDR or a bare IP address, which stands for itself.
+func parsePrefix(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%q is not a valid CIDR", entry)
+		}
+		return prefix, nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%q is neither an IP nor a CIDR", entry)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// redacted stands in for a secret that is set. An unset one prints empty, so
+// a missing key still shows.
+const redacted = "[redacted]"
+
+// Print writes the settings as environment variables, grouped by config file
+// section, each with where its value came from. Secrets are redacted, so the
+// output can be pasted into an issue.
+func (c *Config) Print(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	section := ""
+	for _, s := range settingsOf(c) {
+		if s.section != section {
+			if section != "" {
+				fmt.Fprintln(table)
+			}
+			section = s.section
+			fmt.Fprintf(table, "# [%s]\n", section)
+		}
+
+		value := s.text()
+		if s.secret && value != "" {
+			value = redacted
+		}
+
+		source := c.sources[s.env]
+		if source == "" {
+			source = "unset"
+		}
+
+		fmt.Fprintf(table, "%s=%s\t# %s\n", s.env, value, source)
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// minSecretLength is the shortest signing key production accepts. The keys
+// are meant to come from `openssl rand -hex 32`, which gives 64.
+const minSecretLength = 32
+
+// publishedSecrets are keys that appear in this repository, in the compose
+// file, CI, the README and the tests. Anyone can read them, so production
+// refuses them however long they are.
+var publishedSecrets = []string{
+	"local-dev-jwt-key", "local-dev-jwt-refresh-key", "local-dev-xsrf-key",
+	"test-access-key-for-testing", "test-refresh-key-for-testing", "test-xsrf-key-for-testing",
+	"test-jwt-secret-key-for-testing-only", "test-jwt-refresh-secret-key-for-testing",
+	"your-jwt-secret-key", "your-jwt-refresh-secret-key", "your-xsrf-secret-key",
+}
+
+// ValidationError lists everything wrong with a configuration, one problem
+// per line.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "\n")
+}
+
+// Check loads the configuration the way the server would and returns what is
+// wrong with it, for `config check` to list.
+func Check() []string {
+	_, err := Load(os.Getenv("CONFIG_FILE"))
+
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Problems
+	}
+
+	return nil
+}
+
+// validate checks the values that parsed against each other and against what
+// the code that reads them expects.
+func (c *Config) validate(settings []setting) []string {
+	var problems []string
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for _, s := range settings {
+		value := s.text()
+		if s.required && value == "" {
+			report("%s is required", s.env)
+		}
+		if len(s.oneof) > 0 && value != "" && !slices.Contains(s.oneof, value) {
+			report("%s: %q is not one of %s", s.env, value, strings.Join(s.oneof, ", "))
+		}
+	}
+
+	for _, port := range []struct{ env, value string }{
+		{"PORT", c.Server.Port},
+		{"DBPORT", c.Database.Port},
+		{"SMTP_PORT", c.Mail.SMTPPort},
+	} {
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			report("%s: %q is not a port number", port.env, port.value)
+		}
+	}
+
+	for _, number := range []struct {
+		env        string
+		value, min int
+	}{
+		{"DBCONNECTIONS", c.Database.MaxConns, 1},
+		{"AUDIT_RETENTION_DAYS", c.Security.AuditRetentionDays, 1},
+		{"SPAM_THRESHOLD", c.Security.SpamThreshold, 0},
+		{"UNVERIFIED_ACCOUNT_DAYS", c.Registration.UnverifiedAccountDays, 1},
+		{"ACCOUNT_DELETION_GRACE_DAYS", c.Registration.DeletionGraceDays, 0},
+		{"IMAGE_UNUSED_GRACE_DAYS", c.Images.UnusedGraceDays, 1},
+		{"EMAIL_MAX_ATTEMPTS", c.Mail.MaxAttempts, 1},
+		{"JOB_CONCURRENCY", c.Jobs.Concurrency, 1},
+		{"SHUTDOWN_DRAIN_SECONDS", c.Shutdown.DrainSeconds, 0},
+		{"ACCESS_LOG_MAX_SIZE_MB", c.AccessLog.MaxSizeMB, 1},
+		{"ACCESS_LOG_MAX_BACKUPS", c.AccessLog.MaxBackups, 0},
+		{"ACCESS_LOG_STATIC_SAMPLE_PERCENT", c.AccessLog.StaticPercent, 0},
+	} {
+		if number.value < number.min {
+			report("%s: %d is below %d", number.env, number.value, number.min)
+		}
+	}
+	if c.AccessLog.StaticPercent > 100 {
+		report("ACCESS_LOG_STATIC_SAMPLE_PERCENT: %d is above 100", c.AccessLog.StaticPercent)
+	}
+	if c.Images.Quality < 1 || c.Images.Quality > 100 {
+		report("IMAGE_QUALITY: %d is not between 1 and 100", c.Images.Quality)
+	}
+
+	if !isAbsoluteURL(c.App.BaseURL) {
+		report("APP_BASE_URL: %q is not an absolute URL", c.App.BaseURL)
+	}
+	if c.App.TinyMCEURL != "" && !isAbsoluteURL(c.App.TinyMCEURL) {
+		report("TINYMCE_URL: %q is not an absolute URL", c.App.TinyMCEURL)
+	}
+
+	// The CORS middleware compares the Origin header exactly, and browsers
+	// send scheme://host[:port] with nothing after it.
+	for _, origin := range c.Security.CORSOrigins {
+		if parsed, err := url.Parse(origin); err != nil || !isAbsoluteURL(origin) || strings.TrimSuffix(parsed.Path, "/") != "" {
+			report("CORS_ORIGINS: %q is not an origin such as https://example.com", origin)
+		}
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			report("METRICS_ADDR: %q is not a host:port address", c.Metrics.Addr)
+		}
+	}
+
+	for _, address := range []struct{ env, value string }{
+		{"SMTP_FROM", c.Mail.SMTPFrom},
+		{"ADMIN_EMAIL", c.Admin.Email},
+		{"PRIVACY_CONTACT_EMAIL", c.App.PrivacyContactEmail},
+	} {
+		if address.value == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(address.value); err != nil {
+			report("%s: %q is not an email address", address.env, address.value)
+		}
+	}
+
+	// Settings that only work together. One half on its own is almost
+	// always a forgotten variable rather than a choice.
+	if (c.Mail.DKIMSelector == "") != (c.Mail.DKIMKeyFile == "") {
+		report("DKIM_SELECTOR and DKIM_PRIVATE_KEY_FILE must be set together")
+	}
+	if (c.Admin.Email == "") != (c.Admin.Password == "") {
+		report("ADMIN_EMAIL and ADMIN_PASSWORD must be set together")
+	}
+	cloudinary := []string{c.Cloudinary.CloudName, c.Cloudinary.APIKey, c.Cloudinary.APISecret}
+	if slices.Contains(cloudinary, "") && slices.ContainsFunc(cloudinary, func(v string) bool { return v != "" }) {
+		report("CLOUDINARY_CLOUD_NAME, CLOUDINARY_API_KEY and CLOUDINARY_API_SECRET must be set together")
+	}
+	switch c.Storage.Backend {
+	case "cloudinary":
+		if slices.Contains(cloudinary, "") {
+			report("STORAGE_BACKEND is cloudinary but the CLOUDINARY_ settings are not set")
+		}
+	case "s3":
+		if !isAbsoluteURL(c.S3.Endpoint) {
+			report("S3_ENDPOINT: %q is not an absolute URL", c.S3.Endpoint)
+		}
+		if c.S3.Bucket == "" || c.S3.AccessKeyID == "" || c.S3.SecretAccessKey == "" {
+			report("STORAGE_BACKEND is s3 but S3_BUCKET, S3_ACCESS_KEY_ID or S3_SECRET_ACCESS_KEY is not set")
+		}
+	}
+	if c.S3.PublicURL != "" && !isAbsoluteURL(c.S3.PublicURL) {
+		report("S3_PUBLIC_URL: %q is not an absolute URL", c.S3.PublicURL)
+	}
+	if c.Mail.Transport == "smtp" && c.Mail.SMTPHost == "" {
+		report("MAIL_TRANSPORT is smtp but SMTP_HOST is not set")
+	}
+
+	if c.Server.Environment == "production" {
+		problems = append(problems, c.validateProduction()...)
+	}
+
+	return problems
+}
+
+// validateProduction holds the rules that would only get in the way of a
+// local setup: real keys, HTTPS and working mail.
+func (c *Config) validateProduction() []string {
+	var problems []string
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if parsed, err := url.Parse(c.App.BaseURL); err == nil && parsed.Scheme != "https" {
+		report("APP_BASE_URL must use https in production; cookies are marked Secure")
+	}
+
+	secrets := []struct{ env, value string }{
+		{"JWT_KEY", c.Security.JWTAccessKey},
+		{"JWT_REFRESH_KEY", c.Security.JWTRefreshKey},
+		{"XSRF", c.Security.XSRFKey},
+	}
+	for i, secret := range secrets {
+		if secret.value == "" {
+			continue
+		}
+		if len(secret.value) < minSecretLength {
+			report("%s is shorter than %d characters; generate one with openssl rand -hex 32", secret.env, minSecretLength)
+		}
+		if slices.Contains(publishedSecrets, secret.value) {
+			report("%s is a development key published in this repository", secret.env)
+		}
+		for _, other := range secrets[i+1:] {
+			if secret.value == other.value {
+				report("%s and %s must be different keys", secret.env, other.env)
+			}
+		}
+	}
+
+	if c.Security.AllowRegistration && !c.deliversMail() {
+		report("ALLOW_REGISTRATION is on but no SMTP server is set; verification emails would never arrive")
+	}
+
+	return problems
+}
+
+// deliversMail reports whether the mail transport reaches real inboxes, as
+// opposed to the log or a drop directory.
+func (c *Config) deliversMail() bool {
+	switch c.Mail.Transport {
+	case "sendmail":
+		return true
+	case "", "smtp":
+		return c.Mail.SMTPHost != ""
+	}
+	return false
+}
+
+func isAbsoluteURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// likeEscaper escapes the LIKE wildcards so a search term matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Append stores the event. Free text that comes from the client is cut to the
+// column size rather than failing the insert.
+func (r *AuditRepository) Append(ctx context.Context, event Event) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_events (id, occurred_at, action, outcome, detail, actor_id, identifier, target_type, target_id, ip, user_agent, request_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''))`,
+		event.Id, event.OccurredAt, event.Action, event.Outcome, clip(event.Detail, 100), event.ActorId,
+		clip(event.Identifier, 255), event.TargetType, event.TargetId, clip(event.IP, 45), clip(event.UserAgent, 512), event.RequestId)
+
+	return err
+}
+
+// Find returns one page of matching events, newest first, and the total
+// number of matches.
+func (r *AuditRepository) Find(ctx context.Context, filter Filter, limit, offset int) ([]Event, int, error) {
+	where, args := filter.where()
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM audit_events e
+		LEFT JOIN users u ON u.id = e.actor_id
+		`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, limit, offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT e.id, e.occurred_at, e.action, e.outcome, COALESCE(e.detail, ''), e.actor_id, COALESCE(e.identifier, ''),
+		       COALESCE(e.target_type, ''), COALESCE(e.target_id, ''), COALESCE(e.ip, ''), COALESCE(e.user_agent, ''),
+		       COALESCE(e.request_id, ''), COALESCE(u.email, '')
+		FROM audit_events e
+		LEFT JOIN users u ON u.id = e.actor_id
+		%s
+		ORDER BY e.occurred_at DESC, e.id
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(
+			&event.Id, &event.OccurredAt, &event.Action, &event.Outcome, &event.Detail, &event.ActorId, &event.Identifier,
+			&event.TargetType, &event.TargetId, &event.IP, &event.UserAgent, &event.RequestId, &event.ActorEmail,
+		); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// DeleteBefore removes the events older than the cutoff and reports how many
+// there were.
+func (r *AuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM audit_events WHERE occurred_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// where builds the WHERE clause for the filter, numbering the placeholders
+// from $1.
+func (f Filter) where() (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.Action != "" {
+		add("e.action = $%d", f.Action)
+	}
+	if f.Outcome != "" {
+		add("e.outcome = $%d", f.Outcome)
+	}
+	if f.Query != "" {
+		pattern := "%" + likeEscaper.Replace(f.Query) + "%"
+		add("(u.email ILIKE $%[1]d OR e.identifier ILIKE $%[1]d)", pattern)
+	}
+	if !f.From.IsZero() {
+		add("e.occurred_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("e.occurred_at < $%d", f.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// clip shortens s to at most n characters without splitting one.
+func clip(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:n])
+}
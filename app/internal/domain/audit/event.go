@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Action string
+
+// The actions recorded. The strings are stored, so they must not change once
+// events carrying them exist.
+const (
+	ActionLogin                Action = "login"
+	ActionLogout               Action = "logout"
+	ActionTokenRefresh         Action = "token.refresh"
+	ActionPasswordResetRequest Action = "password.reset.request"
+	ActionPasswordReset        Action = "password.reset"
+	ActionPasswordResetForce   Action = "password.reset.force"
+	ActionSessionsRevoke       Action = "sessions.revoke"
+	ActionRoleGrant            Action = "role.grant"
+	ActionRoleRevoke           Action = "role.revoke"
+	ActionUserStatus           Action = "user.status"
+	ActionPostCreate           Action = "post.create"
+	ActionPostUpdate           Action = "post.update"
+	ActionPostDelete           Action = "post.delete"
+	ActionAccountUnlock        Action = "account.unlock"
+	ActionCommentModerate      Action = "comment.moderate"
+	ActionSubscriberDelete     Action = "newsletter.subscriber.delete"
+	ActionEmailRetry           Action = "email.retry"
+	ActionContactReply         Action = "contact.reply"
+	ActionContactUpdate        Action = "contact.update"
+	ActionContactDelete        Action = "contact.delete"
+	ActionJobRetry             Action = "job.retry"
+)
+
+// Actions lists every action, in the order the viewer offers them.
+var Actions = []Action{
+	ActionLogin,
+	ActionLogout,
+	ActionTokenRefresh,
+	ActionPasswordResetRequest,
+	ActionPasswordReset,
+	ActionPasswordResetForce,
+	ActionSessionsRevoke,
+	ActionRoleGrant,
+	ActionRoleRevoke,
+	ActionUserStatus,
+	ActionPostCreate,
+	ActionPostUpdate,
+	ActionPostDelete,
+	ActionAccountUnlock,
+	ActionCommentModerate,
+	ActionSubscriberDelete,
+	ActionEmailRetry,
+	ActionContactReply,
+	ActionContactUpdate,
+	ActionContactDelete,
+	ActionJobRetry,
+}
+
+type Outcome string
+
+const (
+	Success Outcome = "success"
+	Failure Outcome = "failure"
+)
+
+// Why a sign-in or refresh was refused.
+const (
+	ReasonUnknownAccount = "unknown_account"
+	ReasonWrongPassword  = "wrong_password"
+	ReasonUnverified     = "unverified"
+	ReasonSuspended      = "suspended"
+	ReasonNoAdminAccess  = "no_admin_access"
+	ReasonInvalidToken   = "invalid_token"
+	ReasonRevoked        = "revoked"
+	ReasonLocked         = "locked"
+	ReasonThrottled      = "throttled"
+)
+
+const (
+	TargetUser       = "user"
+	TargetPost       = "post"
+	TargetComment    = "comment"
+	TargetSubscriber = "subscriber"
+	TargetEmail      = "email"
+	TargetContact    = "contact"
+	TargetJob        = "job"
+)
+
+// Event is one row of the audit trail. ActorId is whoever acted, or tried to;
+// the target is what they acted on, when that is something else.
+type Event struct {
+	Id         uuid.UUID
+	OccurredAt time.Time
+	Action     Action
+	Outcome    Outcome
+	Detail     string
+	ActorId    uuid.NullUUID
+	Identifier string
+	TargetType string
+	TargetId   string
+	IP         string
+	UserAgent  string
+	RequestId  string
+
+	// ActorEmail is filled in when reading, from the actor's current account.
+	ActorEmail string
+}
+
+// Filter narrows a search of the trail. Zero fields match everything.
+type Filter struct {
+	Action  Action
+	Outcome Outcome
+	// Query matches the actor's email or the identifier, ignoring case.
+	Query string
+	From  time.Time
+	To    time.Time
+}
@@ -0,0 +1,108 @@
+package comments
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusSpam     Status = "spam"
+	StatusDeleted  Status = "deleted"
+)
+
+// Statuses lists the moderation states in the order the queue offers them.
+var Statuses = []Status{StatusPending, StatusApproved, StatusSpam, StatusDeleted}
+
+// MaxDepth is how deep replies are indented. A reply to a comment already at
+// this depth lines up with its parent rather than moving further in, so a long
+// back and forth does not squeeze the text into a sliver on a phone.
+const MaxDepth = 3
+
+type Comment struct {
+	Id          uuid.UUID
+	PostId      uuid.UUID
+	UserId      uuid.UUID
+	ParentId    uuid.NullUUID
+	Body        string
+	Status      Status
+	CreatedAt   time.Time
+	ModeratedAt sql.NullTime
+	ModeratedBy uuid.NullUUID
+
+	AuthorFirstName string
+	AuthorLastName  string
+}
+
+// QueueItem is a comment as the moderation queue shows it, with enough about
+// the author and the post to judge it without opening either.
+type QueueItem struct {
+	Comment
+	AuthorEmail string
+	PostTitle   string
+	PostSlug    string
+}
+
+// Node is a comment with the replies beneath it.
+type Node struct {
+	Comment
+	Depth   int
+	Replies []*Node
+}
+
+// Thread arranges comments, oldest first, into a tree. A reply whose parent is
+// not among them - it was rejected, or is still pending for someone else - is
+// left out along with its own replies, so nothing appears to answer a comment
+// the reader cannot see.
+func Thread(comments []Comment) []*Node {
+	nodes := make(map[uuid.UUID]*Node, len(comments))
+	for _, comment := range comments {
+		nodes[comment.Id] = &Node{Comment: comment}
+	}
+
+	var roots []*Node
+	for _, comment := range comments {
+		node := nodes[comment.Id]
+		if !comment.ParentId.Valid {
+			roots = append(roots, node)
+			continue
+		}
+
+		parent, ok := nodes[comment.ParentId.UUID]
+		if !ok {
+			continue
+		}
+		parent.Replies = append(parent.Replies, node)
+	}
+
+	for _, root := range roots {
+		setDepth(root, 0)
+	}
+
+	return roots
+}
+
+// setDepth numbers the levels, stopping at MaxDepth.
+func setDepth(node *Node, depth int) {
+	node.Depth = min(depth, MaxDepth)
+	for _, reply := range node.Replies {
+		setDepth(reply, depth+1)
+	}
+}
+
+// Flatten lists the tree in reading order: each comment followed by its
+// replies. Depth says how far to indent each one.
+func Flatten(roots []*Node) []*Node {
+	var result []*Node
+	for _, node := range roots {
+		result = append(result, node)
+		result = append(result, Flatten(node.Replies)...)
+	}
+
+	return result
+}
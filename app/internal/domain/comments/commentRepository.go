@@ -0,0 +1,169 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type CommentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+func (r *CommentRepository) Create(ctx context.Context, comment Comment) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO comments (id, post_id, user_id, parent_id, body, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		comment.Id, comment.PostId, comment.UserId, comment.ParentId, comment.Body, comment.Status, comment.CreatedAt)
+
+	return err
+}
+
+func (r *CommentRepository) FindById(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	var comment Comment
+	err := r.db.QueryRowContext(ctx, `
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.body, c.status, c.created_at, c.moderated_at, c.moderated_by,
+		       COALESCE(u.first_name, ''), COALESCE(u.last_name, '')
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1`, id).Scan(
+		&comment.Id, &comment.PostId, &comment.UserId, &comment.ParentId, &comment.Body, &comment.Status,
+		&comment.CreatedAt, &comment.ModeratedAt, &comment.ModeratedBy, &comment.AuthorFirstName, &comment.AuthorLastName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// FindVisible returns what a reader of the post may see, oldest first: the
+// approved comments, and the viewer's own comments still awaiting moderation
+// so they can tell theirs was received. Their comments held as spam count as
+// awaiting too: a false positive should look no different to its author.
+func (r *CommentRepository) FindVisible(ctx context.Context, postId uuid.UUID, viewerId uuid.NullUUID) ([]Comment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.body, c.status, c.created_at, c.moderated_at, c.moderated_by,
+		       COALESCE(u.first_name, ''), COALESCE(u.last_name, '')
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.post_id = $1
+		  AND (c.status = 'approved' OR (c.status IN ('pending', 'spam') AND c.user_id = $2))
+		ORDER BY c.created_at, c.id`, postId, viewerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(
+			&comment.Id, &comment.PostId, &comment.UserId, &comment.ParentId, &comment.Body, &comment.Status,
+			&comment.CreatedAt, &comment.ModeratedAt, &comment.ModeratedBy, &comment.AuthorFirstName, &comment.AuthorLastName,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, comment)
+	}
+
+	return result, rows.Err()
+}
+
+// FindByAuthor returns everything the user wrote, whatever became of it,
+// oldest first.
+func (r *CommentRepository) FindByAuthor(ctx context.Context, userId uuid.UUID) ([]QueueItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.body, c.status, c.created_at, c.moderated_at, c.moderated_by,
+		       COALESCE(u.first_name, ''), COALESCE(u.last_name, ''), u.email, p.title, p.slug
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN posts p ON p.id = c.post_id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at, c.id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []QueueItem{}
+	for rows.Next() {
+		var item QueueItem
+		if err := rows.Scan(
+			&item.Id, &item.PostId, &item.UserId, &item.ParentId, &item.Body, &item.Status,
+			&item.CreatedAt, &item.ModeratedAt, &item.ModeratedBy, &item.AuthorFirstName, &item.AuthorLastName,
+			&item.AuthorEmail, &item.PostTitle, &item.PostSlug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// FindByStatus returns one page of the moderation queue, oldest first so
+// nothing waits longer than it has to, and the total in that state.
+func (r *CommentRepository) FindByStatus(ctx context.Context, status Status, limit, offset int) ([]QueueItem, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.body, c.status, c.created_at, c.moderated_at, c.moderated_by,
+		       COALESCE(u.first_name, ''), COALESCE(u.last_name, ''), u.email, p.title, p.slug
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN posts p ON p.id = c.post_id
+		WHERE c.status = $1
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3`, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []QueueItem{}
+	for rows.Next() {
+		var item QueueItem
+		if err := rows.Scan(
+			&item.Id, &item.PostId, &item.UserId, &item.ParentId, &item.Body, &item.Status,
+			&item.CreatedAt, &item.ModeratedAt, &item.ModeratedBy, &item.AuthorFirstName, &item.AuthorLastName,
+			&item.AuthorEmail, &item.PostTitle, &item.PostSlug,
+		); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// SetStatus moves the comments to the status in one statement and reports how
+// many there were. Ids that match nothing are skipped.
+func (r *CommentRepository) SetStatus(ctx context.Context, ids []uuid.UUID, status Status, moderatorId uuid.UUID) (int64, error) {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE comments
+		SET status = $2, moderated_at = now() at time zone 'utc', moderated_by = $3
+		WHERE id = ANY($1::uuid[])`, values, status, moderatorId)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
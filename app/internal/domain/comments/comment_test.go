@@ -0,0 +1,42 @@
+package comments
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func reply(id, parent uuid.UUID) Comment {
+	return Comment{Id: id, ParentId: uuid.NullUUID{UUID: parent, Valid: true}}
+}
+
+// Replies are listed straight after the comment they answer, in the order
+// they were written, and deep replies stop moving right at MaxDepth.
+func TestThread_OrdersRepliesUnderTheirParent(t *testing.T) {
+	a, b, c, d, e, f := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	roots := Thread([]Comment{{Id: a}, {Id: b}, reply(c, a), reply(d, c), reply(e, d), reply(f, e)})
+	if len(roots) != 2 {
+		t.Fatalf("roots = %d, want 2", len(roots))
+	}
+
+	flat := Flatten(roots)
+	wantOrder := []uuid.UUID{a, c, d, e, f, b}
+	wantDepth := []int{0, 1, 2, 3, MaxDepth, 0}
+	for i, node := range flat {
+		if node.Id != wantOrder[i] || node.Depth != wantDepth[i] {
+			t.Errorf("position %d: got depth %d, want comment %d at depth %d", i, node.Depth, i, wantDepth[i])
+		}
+	}
+}
+
+// A reply to a comment the reader cannot see would look like it answers
+// nothing, so it is dropped together with the replies beneath it.
+func TestThread_DropsRepliesToHiddenComments(t *testing.T) {
+	hidden, child, grandchild := uuid.New(), uuid.New(), uuid.New()
+
+	roots := Thread([]Comment{reply(child, hidden), reply(grandchild, child)})
+	if flat := Flatten(roots); len(flat) != 0 {
+		t.Errorf("got %d comments, want none", len(flat))
+	}
+}
@@ -0,0 +1,81 @@
+package contact
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusOpen   Status = "open"
+	StatusClosed Status = "closed"
+	StatusSpam   Status = "spam"
+)
+
+// Statuses lists every status, in the order the inbox tabs show them.
+var Statuses = []Status{StatusOpen, StatusClosed, StatusSpam}
+
+type Category string
+
+const (
+	CategoryGeneral     Category = "general"
+	CategoryPrivacy     Category = "privacy"
+	CategoryContent     Category = "content"
+	CategoryPartnership Category = "partnership"
+)
+
+// Categories lists every category, in the order the form offers them.
+var Categories = []Category{CategoryGeneral, CategoryPrivacy, CategoryContent, CategoryPartnership}
+
+// PrivacyResponseTime is how long a privacy request may wait for an answer:
+// the month the GDPR allows (Art. 12(3)), counted as 30 days to be safe.
+const PrivacyResponseTime = 30 * 24 * time.Hour
+
+type Message struct {
+	Id        uuid.UUID
+	Name      string
+	Email     string
+	Category  Category
+	Subject   string
+	Body      string
+	Status    Status
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+	// DueAt is set for privacy requests only.
+	DueAt    sql.NullTime
+	ClosedAt sql.NullTime
+}
+
+// Reply is an answer sent from the inbox. AuthorName is empty once the
+// author's account is gone.
+type Reply struct {
+	Id         uuid.UUID
+	MessageId  uuid.UUID
+	AuthorId   uuid.NullUUID
+	AuthorName string
+	Body       string
+	CreatedAt  time.Time
+}
+
+// Summary is what the inbox header counts: open messages nobody has read, and
+// open privacy requests past or near their due date.
+type Summary struct {
+	Unread  int
+	DueSoon int
+	Overdue int
+}
+
+// DueDate is when a message of the category sent at createdAt must be
+// answered, or nothing for categories without a deadline.
+func DueDate(category Category, createdAt time.Time) sql.NullTime {
+	if category != CategoryPrivacy {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: createdAt.Add(PrivacyResponseTime), Valid: true}
+}
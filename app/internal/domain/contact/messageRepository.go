@@ -0,0 +1,218 @@
+package contact
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const messageColumns = `id, name, email, category, subject, body, status, ip, user_agent, created_at, read_at, due_at, closed_at`
+
+type MessageRepository struct {
+	db *sql.DB
+}
+
+func NewMessageRepository(db *sql.DB) *MessageRepository {
+	return &MessageRepository{db: db}
+}
+
+func (r *MessageRepository) Create(ctx context.Context, message Message) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO contact_messages (`+messageColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		message.Id, message.Name, message.Email, message.Category, message.Subject, message.Body, message.Status,
+		message.IP, message.UserAgent, message.CreatedAt, message.ReadAt, message.DueAt, message.ClosedAt)
+
+	return err
+}
+
+func (r *MessageRepository) FindById(ctx context.Context, id uuid.UUID) (*Message, error) {
+	return scanMessage(r.db.QueryRowContext(ctx, `SELECT `+messageColumns+` FROM contact_messages WHERE id = $1`, id))
+}
+
+// FindByStatus returns one page of messages in the status, and in the
+// category unless it is empty, with the total. Messages with a due date come
+// first, the most urgent on top; the rest follow newest first.
+func (r *MessageRepository) FindByStatus(ctx context.Context, status Status, category Category, limit, offset int) ([]Message, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM contact_messages
+		WHERE status = $1 AND ($2 = '' OR category = $2)`, status, category).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM contact_messages
+		WHERE status = $1 AND ($2 = '' OR category = $2)
+		ORDER BY due_at ASC NULLS LAST, created_at DESC, id
+		LIMIT $3 OFFSET $4`, status, category, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var message Message
+		if err := scanMessageInto(rows, &message); err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, total, rows.Err()
+}
+
+// Summary counts the open messages that want attention. A privacy request is
+// due soon when its due date falls before soon.
+func (r *MessageRepository) Summary(ctx context.Context, now, soon time.Time) (Summary, error) {
+	var summary Summary
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE read_at IS NULL),
+			COUNT(*) FILTER (WHERE due_at >= $1 AND due_at < $2),
+			COUNT(*) FILTER (WHERE due_at < $1)
+		FROM contact_messages
+		WHERE status = 'open'`, now.UTC(), soon.UTC()).Scan(&summary.Unread, &summary.DueSoon, &summary.Overdue)
+
+	return summary, err
+}
+
+// SetRead marks the message read or, with read false, unread again. It
+// reports whether the message exists.
+func (r *MessageRepository) SetRead(ctx context.Context, id uuid.UUID, read bool) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE contact_messages
+		SET read_at = CASE WHEN $2 THEN COALESCE(read_at, NOW()) ELSE NULL END
+		WHERE id = $1`, id, read)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+// SetCategory files the message under another category, with the due date
+// that goes with it.
+func (r *MessageRepository) SetCategory(ctx context.Context, id uuid.UUID, category Category, dueAt sql.NullTime) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE contact_messages SET category = $2, due_at = $3 WHERE id = $1`, id, category, dueAt)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+// SetStatus moves the message. Closing it records when, which stops the clock
+// on a privacy request; any other status clears that again.
+func (r *MessageRepository) SetStatus(ctx context.Context, id uuid.UUID, status Status) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE contact_messages
+		SET status = $2, closed_at = CASE WHEN $2 = 'closed' THEN COALESCE(closed_at, NOW()) ELSE NULL END
+		WHERE id = $1`, id, status)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+// Delete erases the message and its replies.
+func (r *MessageRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM contact_messages WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// DeleteExpired removes closed messages closed before closedCutoff and spam
+// received before spamCutoff, with their replies.
+func (r *MessageRepository) DeleteExpired(ctx context.Context, closedCutoff, spamCutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM contact_messages
+		WHERE (status = 'closed' AND closed_at < $1)
+		   OR (status = 'spam' AND created_at < $2)`, closedCutoff.UTC(), spamCutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// AddReply stores a reply and marks the message read: whoever answered it
+// has read it.
+func (r *MessageRepository) AddReply(ctx context.Context, reply Reply) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO contact_replies (id, message_id, author_id, body, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		reply.Id, reply.MessageId, reply.AuthorId, reply.Body, reply.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE contact_messages SET read_at = COALESCE(read_at, NOW()) WHERE id = $1`, reply.MessageId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindReplies returns the replies to a message, oldest first, with the name of
+// who wrote them.
+func (r *MessageRepository) FindReplies(ctx context.Context, messageId uuid.UUID) ([]Reply, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT cr.id, cr.message_id, cr.author_id, TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '')), cr.body, cr.created_at
+		FROM contact_replies cr
+		LEFT JOIN users u ON u.id = cr.author_id
+		WHERE cr.message_id = $1
+		ORDER BY cr.created_at, cr.id`, messageId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies := []Reply{}
+	for rows.Next() {
+		var reply Reply
+		if err := rows.Scan(&reply.Id, &reply.MessageId, &reply.AuthorId, &reply.AuthorName, &reply.Body, &reply.CreatedAt); err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+
+	return replies, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row *sql.Row) (*Message, error) {
+	var message Message
+	if err := scanMessageInto(row, &message); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func scanMessageInto(row scanner, message *Message) error {
+	return row.Scan(&message.Id, &message.Name, &message.Email, &message.Category, &message.Subject, &message.Body, &message.Status,
+		&message.IP, &message.UserAgent, &message.CreatedAt, &message.ReadAt, &message.DueAt, &message.ClosedAt)
+}
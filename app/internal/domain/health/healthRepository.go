@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// HealthRepository answers the questions the health checks ask of the
+// database itself rather than of any table the application owns.
+type HealthRepository struct {
+	db *sql.DB
+}
+
+func NewHealthRepository(db *sql.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+// Ping checks that a connection can be had and used.
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// MigrationVersion reads the version golang-migrate recorded. Dirty means a
+// migration failed halfway and the schema needs a hand before the next one
+// can run. A database that was never migrated reports version zero.
+func (r *HealthRepository) MigrationVersion(ctx context.Context) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
@@ -1,19 +1,75 @@
 package image
 
 import (
-	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Image is an uploaded image. Width, Height and Bytes are of the stored file,
+// which has been turned upright and stripped of metadata.
 type Image struct {
-	Id        uuid.UUID    `json:"id"`
-	Url       string       `json:"url"`
-	OwnerId   uuid.UUID    `json:"owner_id"`
-	OwnerType string       `json:"owner_type"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
-	UpdatedBy string       `json:"updated_by"`
-	IsDeleted bool         `json:"is_deleted"`
+	Id     uuid.UUID
+	Url    string
+	Key    string
+	Width  int
+	Height int
+	Bytes  int64
+	// Filename is the name the file was uploaded under.
+	Filename string
+	// Alt describes the image to those who cannot see it. The editor's picker
+	// inserts it along with the image.
+	Alt        string
+	UploadedBy uuid.NullUUID
+	CreatedAt  time.Time
+}
+
+// Variant is a copy of an image at another width or in another format, such
+// as the 800 pixel WebP offered to a phone.
+type Variant struct {
+	Format string
+	Width  int
+	Url    string
+	Key    string
+	Bytes  int64
+}
+
+// WithVariants is an image and every copy made of it.
+type WithVariants struct {
+	Image
+	Variants []Variant
+}
+
+// Where an image is used in a post.
+const (
+	UsageCover   = "cover"
+	UsageContent = "content"
+)
+
+// Usage is a post that shows an image, and where.
+type Usage struct {
+	PostId    uuid.UUID
+	PostTitle string
+	Field     string
+}
+
+// LibraryItem is an image as the media library lists it.
+type LibraryItem struct {
+	Image
+	// Uploader is the email of whoever uploaded the image, empty once their
+	// account is gone.
+	Uploader string
+	Usages   []Usage
+	// InCategory is set when a category shows the image.
+	InCategory bool
+	// UnusedSince is when the usage scan last found the image unused, or the
+	// zero time while something shows it.
+	UnusedSince time.Time
+}
+
+// LibraryFilter narrows the media library to images whose filename or alt
+// text contains Query, and to unused ones.
+type LibraryFilter struct {
+	Query      string
+	UnusedOnly bool
 }
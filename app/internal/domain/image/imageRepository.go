@@ -0,0 +1,361 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ImageRepository struct {
+	db *sql.DB
+}
+
+func NewImageRepository(db *sql.DB) *ImageRepository {
+	return &ImageRepository{db: db}
+}
+
+// Create records an image and its variants together, so a variant is never
+// stored without the image it was made from.
+func (r *ImageRepository) Create(ctx context.Context, img Image, variants []Variant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO images (id, url, key, width, height, bytes, filename, alt, uploaded_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		img.Id, img.Url, img.Key, img.Width, img.Height, img.Bytes, img.Filename, img.Alt, img.UploadedBy, img.CreatedAt.UTC()); err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO image_variants (image_id, format, width, url, key, bytes)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			img.Id, variant.Format, variant.Width, variant.Url, variant.Key, variant.Bytes); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindWithVariants returns every image that has variants. Images without any,
+// such as those kept by Cloudinary, have nothing to offer beyond their URL.
+func (r *ImageRepository) FindWithVariants(ctx context.Context) ([]WithVariants, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT i.id, i.url, i.key, i.width, i.height, i.created_at,
+		       v.format, v.width, v.url, v.key, v.bytes
+		FROM images i
+		JOIN image_variants v ON v.image_id = i.id
+		WHERE i.is_deleted = FALSE
+		ORDER BY i.id, v.format, v.width`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []WithVariants
+	for rows.Next() {
+		var img Image
+		var variant Variant
+		if err := rows.Scan(&img.Id, &img.Url, &img.Key, &img.Width, &img.Height, &img.CreatedAt,
+			&variant.Format, &variant.Width, &variant.Url, &variant.Key, &variant.Bytes); err != nil {
+			return nil, err
+		}
+
+		if len(images) == 0 || images[len(images)-1].Id != img.Id {
+			images = append(images, WithVariants{Image: img})
+		}
+		last := &images[len(images)-1]
+		last.Variants = append(last.Variants, variant)
+	}
+
+	return images, rows.Err()
+}
+
+// RecordedKeys returns the storage key of every image in the library and of
+// every copy made from one.
+func (r *ImageRepository) RecordedKeys(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key FROM images WHERE key IS NOT NULL
+		UNION ALL
+		SELECT key FROM image_variants`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys[key] = true
+	}
+
+	return keys, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards so a search term matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// where builds the WHERE clause for the filter, numbering the placeholders
+// from $1.
+func (f LibraryFilter) where() (string, []any) {
+	conditions := []string{"i.is_deleted = FALSE"}
+	var args []any
+
+	if f.Query != "" {
+		args = append(args, "%"+likeEscaper.Replace(f.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(i.filename ILIKE $%[1]d OR i.alt ILIKE $%[1]d)", len(args)))
+	}
+	if f.UnusedOnly {
+		conditions = append(conditions, "i.unused_since IS NOT NULL")
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// FindPage returns one page of the library, newest first, with where each
+// image is used, and the total number of matches.
+func (r *ImageRepository) FindPage(ctx context.Context, filter LibraryFilter, limit, offset int) ([]LibraryItem, int, error) {
+	where, args := filter.where()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM images i `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, limit, offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT i.id, i.url, i.key, i.width, i.height, i.bytes, i.filename, i.alt, i.uploaded_by, i.created_at,
+		       coalesce(u.email, ''), i.unused_since,
+		       EXISTS (SELECT 1 FROM categories c WHERE i.key <> '' AND strpos(c.image_url, i.key) > 0)
+		FROM images i
+		LEFT JOIN users u ON u.id = i.uploaded_by
+		%s
+		ORDER BY i.created_at DESC, i.id
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []LibraryItem{}
+	byId := map[uuid.UUID]int{}
+	for rows.Next() {
+		var item LibraryItem
+		var unusedSince sql.NullTime
+		if err := rows.Scan(&item.Id, &item.Url, &item.Key, &item.Width, &item.Height, &item.Bytes, &item.Filename, &item.Alt,
+			&item.UploadedBy, &item.CreatedAt, &item.Uploader, &unusedSince, &item.InCategory); err != nil {
+			return nil, 0, err
+		}
+		item.UnusedSince = unusedSince.Time
+		byId[item.Id] = len(items)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(items) == 0 {
+		return items, total, nil
+	}
+
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Id.String())
+	}
+
+	usages, err := r.db.QueryContext(ctx, `
+		SELECT u.image_id, u.post_id, p.title, u.field
+		FROM image_usages u
+		JOIN posts p ON p.id = u.post_id
+		WHERE u.image_id = ANY($1::uuid[])
+		ORDER BY p.title, u.field`, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer usages.Close()
+
+	for usages.Next() {
+		var imageId uuid.UUID
+		var usage Usage
+		if err := usages.Scan(&imageId, &usage.PostId, &usage.PostTitle, &usage.Field); err != nil {
+			return nil, 0, err
+		}
+		item := &items[byId[imageId]]
+		item.Usages = append(item.Usages, usage)
+	}
+
+	return items, total, usages.Err()
+}
+
+// UpdateAlt sets the alt text of an image. It returns sql.ErrNoRows when there
+// is no such image.
+func (r *ImageRepository) UpdateAlt(ctx context.Context, id uuid.UUID, alt string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE images SET alt = $2, updated_at = $3 WHERE id = $1 AND is_deleted = FALSE`,
+		id, alt, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// insertUsages finds the images the live posts show, all of them or only the
+// post given as $1. Matching is by storage key, which is part of every URL
+// the image is delivered under, Cloudinary's resized ones included.
+const insertUsages = `
+	INSERT INTO image_usages (image_id, post_id, field)
+	SELECT i.id, p.id, 'cover'
+	FROM images i
+	JOIN posts p ON strpos(coalesce(p.cover_image_url, ''), i.key) > 0
+	WHERE i.key <> '' AND p.is_deleted = FALSE AND ($1::uuid IS NULL OR p.id = $1)
+	UNION ALL
+	SELECT i.id, p.id, 'content'
+	FROM images i
+	JOIN posts p ON strpos(p.content, i.key) > 0
+	WHERE i.key <> '' AND p.is_deleted = FALSE AND ($1::uuid IS NULL OR p.id = $1)`
+
+// inUse holds for an image some post or category shows.
+const inUse = `(
+	EXISTS (SELECT 1 FROM image_usages u WHERE u.image_id = i.id)
+	OR EXISTS (SELECT 1 FROM categories c WHERE i.key <> '' AND strpos(c.image_url, i.key) > 0)
+)`
+
+// ScanUsage rebuilds where every image is used from the posts, and marks the
+// images nothing shows as unused since now. An image that is shown again
+// loses the mark.
+func (r *ImageRepository) ScanUsage(ctx context.Context, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM image_usages`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, insertUsages, uuid.NullUUID{}); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE images i SET unused_since = $1
+		WHERE i.unused_since IS NULL AND NOT `+inUse, now.UTC()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE images i SET unused_since = NULL
+		WHERE i.unused_since IS NOT NULL AND `+inUse); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TrackPost records where one post uses images, as it was just saved. Images
+// it shows are no longer unused; those it stopped showing are left for the
+// next scan, which sees the other posts too.
+func (r *ImageRepository) TrackPost(ctx context.Context, postId uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM image_usages WHERE post_id = $1`, postId); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, insertUsages, uuid.NullUUID{UUID: postId, Valid: true}); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE images SET unused_since = NULL
+		WHERE unused_since IS NOT NULL AND id IN (SELECT image_id FROM image_usages WHERE post_id = $1)`, postId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindUnusedSince returns the images marked unused at or before cutoff, with
+// their variants.
+func (r *ImageRepository) FindUnusedSince(ctx context.Context, cutoff time.Time) ([]WithVariants, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT i.id, i.url, i.key, i.width, i.height, i.bytes, i.filename, i.created_at,
+		       v.format, v.width, v.url, v.key, v.bytes
+		FROM images i
+		LEFT JOIN image_variants v ON v.image_id = i.id
+		WHERE i.unused_since <= $1
+		ORDER BY i.id, v.format, v.width`, cutoff.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []WithVariants
+	for rows.Next() {
+		var img Image
+		var format, url, key sql.NullString
+		var width, bytes sql.NullInt64
+		if err := rows.Scan(&img.Id, &img.Url, &img.Key, &img.Width, &img.Height, &img.Bytes, &img.Filename, &img.CreatedAt,
+			&format, &width, &url, &key, &bytes); err != nil {
+			return nil, err
+		}
+
+		if len(images) == 0 || images[len(images)-1].Id != img.Id {
+			images = append(images, WithVariants{Image: img})
+		}
+		if key.Valid {
+			last := &images[len(images)-1]
+			last.Variants = append(last.Variants, Variant{
+				Format: format.String,
+				Width:  int(width.Int64),
+				Url:    url.String,
+				Key:    key.String,
+				Bytes:  bytes.Int64,
+			})
+		}
+	}
+
+	return images, rows.Err()
+}
+
+// Claim marks an image unused since at or before cutoff as being deleted,
+// checking once more that nothing shows it: a post saved since the scan may
+// have started to. It reports whether the image was claimed. A claimed image
+// is out of the library, and is claimed again by the next sweep should this
+// one fail to delete it.
+func (r *ImageRepository) Claim(ctx context.Context, id uuid.UUID, cutoff time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE images i SET is_deleted = TRUE
+		WHERE i.id = $1 AND i.unused_since <= $2 AND (i.is_deleted OR NOT `+inUse+`)`, id, cutoff.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Delete forgets an image, with its variants and usages.
+func (r *ImageRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM images WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	// StatusPending covers both a job not run yet and one waiting for its
+	// next attempt.
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	// StatusDead is a job that ran out of attempts, or could never succeed.
+	// Only an administrator runs it again.
+	StatusDead Status = "dead"
+)
+
+// Statuses lists every status, in the order the admin panel shows them.
+var Statuses = []Status{StatusPending, StatusRunning, StatusDone, StatusDead}
+
+// Job is one unit of background work. Kind names the handler that runs it and
+// Payload holds that handler's arguments as JSON.
+type Job struct {
+	Id      uuid.UUID
+	Kind    string
+	Payload json.RawMessage
+	// UniqueKey, when set, keeps a second job with the same key from being
+	// queued while this one is pending or running.
+	UniqueKey string
+	Status    Status
+	Attempts  int
+	// RunAt is when a pending job is due, and when a running one's lease
+	// runs out.
+	RunAt      time.Time
+	LastError  string
+	CreatedAt  time.Time
+	StartedAt  sql.NullTime
+	FinishedAt sql.NullTime
+}
@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const jobColumns = `id, kind, payload, COALESCE(unique_key, ''), status, attempts, run_at, COALESCE(last_error, ''), created_at, started_at, finished_at`
+
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Enqueue stores the job as pending and due at its RunAt. It reports false,
+// and stores nothing, when a pending or running job holds the same unique key.
+func (r *JobRepository) Enqueue(ctx context.Context, job Job) (bool, error) {
+	payload := job.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, kind, payload, unique_key, status, run_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), 'pending', $5, $6)
+		ON CONFLICT (unique_key) WHERE status IN ('pending', 'running') DO NOTHING`,
+		job.Id, job.Kind, string(payload), job.UniqueKey, job.RunAt.UTC(), job.CreatedAt.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	return inserted > 0, err
+}
+
+// Claim takes up to limit due jobs of the kinds in leases, marks them running
+// and counts the attempt. The rows are locked with SKIP LOCKED only while they
+// are claimed, so workers never wait on each other, and claiming pushes run_at
+// out by the kind's lease: if the worker dies mid-job, the job comes due again
+// then.
+func (r *JobRepository) Claim(ctx context.Context, leases map[string]time.Duration, limit int) ([]Job, error) {
+	kinds := make([]string, 0, len(leases))
+	seconds := make([]float64, 0, len(leases))
+	for kind, lease := range leases {
+		kinds = append(kinds, kind)
+		seconds = append(seconds, lease.Seconds())
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, started_at = NOW(),
+			run_at = NOW() + make_interval(secs => l.lease_secs)
+		FROM unnest($1::text[], $2::float8[]) AS l(lease_kind, lease_secs)
+		WHERE jobs.kind = l.lease_kind AND jobs.id IN (
+			SELECT id FROM jobs
+			WHERE status IN ('pending', 'running') AND run_at <= NOW() AND kind = ANY($1)
+			ORDER BY run_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns, kinds, seconds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanJobs(rows)
+}
+
+// The updates below that record how an attempt went only apply while the job
+// is still on that attempt. A worker that outlived its lease finds the job
+// claimed again, and its late report is dropped.
+
+// Complete records the job as done.
+func (r *JobRepository) Complete(ctx context.Context, id uuid.UUID, attempt int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'done', finished_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2`, id, attempt)
+
+	return err
+}
+
+// Fail records a failed attempt and when to try again.
+func (r *JobRepository) Fail(ctx context.Context, id uuid.UUID, attempt int, lastError string, runAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'pending', last_error = $3, run_at = $4
+		WHERE id = $1 AND status = 'running' AND attempts = $2`, id, attempt, lastError, runAt.UTC())
+
+	return err
+}
+
+// Kill gives up on a job after its last failed attempt.
+func (r *JobRepository) Kill(ctx context.Context, id uuid.UUID, attempt int, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'dead', last_error = $3, finished_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2`, id, attempt, lastError)
+
+	return err
+}
+
+// Release hands back a job its worker had to abandon, due at once and without
+// counting the attempt.
+func (r *JobRepository) Release(ctx context.Context, id uuid.UUID, attempt int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'pending', attempts = attempts - 1, run_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2`, id, attempt)
+
+	return err
+}
+
+// Retry makes a dead or waiting job due now. A dead one gets a fresh set of
+// attempts. It reports false when there is no such job, or when another job
+// holding the same unique key has been queued since.
+func (r *JobRepository) Retry(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE jobs j
+		SET status = 'pending', run_at = NOW(),
+			attempts = CASE WHEN j.status = 'dead' THEN 0 ELSE j.attempts END,
+			finished_at = NULL
+		WHERE j.id = $1 AND j.status IN ('pending', 'dead')
+		  AND NOT EXISTS (
+			SELECT 1 FROM jobs o
+			WHERE o.unique_key = j.unique_key AND o.id <> j.id AND o.status IN ('pending', 'running')
+		  )`, id)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+func (r *JobRepository) FindById(ctx context.Context, id uuid.UUID) (*Job, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &jobs[0], nil
+}
+
+// Find returns one page of jobs, narrowed to a status and a kind unless they
+// are empty, with the total. Waiting jobs come in the order they are due,
+// the rest newest first.
+func (r *JobRepository) Find(ctx context.Context, status Status, kind string, limit, offset int) ([]Job, int, error) {
+	const where = `WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs `+where, string(status), kind).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		`+where+`
+		ORDER BY CASE WHEN status IN ('pending', 'running') THEN run_at END ASC NULLS LAST, created_at DESC, id
+		LIMIT $3 OFFSET $4`, string(status), kind, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs, err := scanJobs(rows)
+	return jobs, total, err
+}
+
+// Counts returns how many jobs there are in each status.
+func (r *JobRepository) Counts(ctx context.Context) (map[Status]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM jobs GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[Status]int{}
+	for rows.Next() {
+		var status Status
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// CountOverdue counts pending jobs that came due before the cutoff and have
+// still not been claimed.
+func (r *JobRepository) CountOverdue(ctx context.Context, cutoff time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE status = $1 AND run_at < $2`, StatusPending, cutoff).Scan(&count)
+
+	return count, err
+}
+
+// Kinds lists the kinds that have jobs on record.
+func (r *JobRepository) Kinds(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT kind FROM jobs ORDER BY kind`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	kinds := []string{}
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+
+	return kinds, rows.Err()
+}
+
+// DeleteFinishedBefore removes done jobs finished before doneCutoff and dead
+// ones before deadCutoff.
+func (r *JobRepository) DeleteFinishedBefore(ctx context.Context, doneCutoff, deadCutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM jobs
+		WHERE (status = 'done' AND finished_at < $1)
+		   OR (status = 'dead' AND finished_at < $2)`, doneCutoff.UTC(), deadCutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func scanJobs(rows *sql.Rows) ([]Job, error) {
+	jobs := []Job{}
+	for rows.Next() {
+		var job Job
+		var payload []byte
+		if err := rows.Scan(&job.Id, &job.Kind, &payload, &job.UniqueKey, &job.Status, &job.Attempts,
+			&job.RunAt, &job.LastError, &job.CreatedAt, &job.StartedAt, &job.FinishedAt); err != nil {
+			return nil, err
+		}
+		job.Payload = payload
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
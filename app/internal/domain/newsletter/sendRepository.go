@@ -0,0 +1,140 @@
+package newsletter
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const sendColumns = `id, subject, period_start, period_end, post_count, recipient_count, failed_count, started_at, finished_at`
+
+type SendRepository struct {
+	db *sql.DB
+}
+
+func NewSendRepository(db *sql.DB) *SendRepository {
+	return &SendRepository{db: db}
+}
+
+// Start records the send and fixes its recipients: every confirmed
+// subscriber. It returns how many there are, and 0 - with nothing recorded -
+// when there is nobody to send to or another send already covers the period.
+func (r *SendRepository) Start(ctx context.Context, send Send) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO newsletter_sends (`+sendColumns+`)
+		VALUES ($1, $2, $3, $4, $5, 0, 0, $6, NULL)
+		ON CONFLICT (period_start) DO NOTHING`,
+		send.Id, send.Subject, send.PeriodStart, send.PeriodEnd, send.PostCount, send.StartedAt)
+	if err != nil {
+		return 0, err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+		return 0, err
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO newsletter_send_recipients (send_id, subscriber_id)
+		SELECT $1, id FROM newsletter_subscribers WHERE status = 'confirmed'`, send.Id)
+	if err != nil {
+		return 0, err
+	}
+	recipients, err := result.RowsAffected()
+	if err != nil || recipients == 0 {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE newsletter_sends SET recipient_count = $2 WHERE id = $1`, send.Id, recipients); err != nil {
+		return 0, err
+	}
+
+	return int(recipients), tx.Commit()
+}
+
+// PendingRecipients returns the subscribers the send has not queued a copy
+// for yet, leaving out any who unsubscribed since it started.
+func (r *SendRepository) PendingRecipients(ctx context.Context, sendId uuid.UUID) ([]Subscriber, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+subscriberColumns+`
+		FROM newsletter_subscribers
+		JOIN newsletter_send_recipients ON subscriber_id = id
+		WHERE send_id = $1 AND queued_at IS NULL AND status = 'confirmed'
+		ORDER BY created_at`, sendId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSubscribers(rows)
+}
+
+// MarkQueued records that the subscriber's copy is in the outbox.
+func (r *SendRepository) MarkQueued(ctx context.Context, sendId, subscriberId uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE newsletter_send_recipients SET queued_at = NOW()
+		WHERE send_id = $1 AND subscriber_id = $2`, sendId, subscriberId)
+
+	return err
+}
+
+// SetFailed records how many copies the last attempt could not queue.
+func (r *SendRepository) SetFailed(ctx context.Context, sendId uuid.UUID, failed int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE newsletter_sends SET failed_count = $2 WHERE id = $1`, sendId, failed)
+
+	return err
+}
+
+// Finish marks the send done once every copy is queued.
+func (r *SendRepository) Finish(ctx context.Context, sendId uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE newsletter_sends SET failed_count = 0, finished_at = $2
+		WHERE id = $1`, sendId, at.UTC())
+
+	return err
+}
+
+// Latest returns the most recent send, finished or not, or sql.ErrNoRows
+// before the first.
+func (r *SendRepository) Latest(ctx context.Context) (*Send, error) {
+	var send Send
+	err := r.db.QueryRowContext(ctx, `SELECT `+sendColumns+` FROM newsletter_sends ORDER BY started_at DESC LIMIT 1`).Scan(
+		&send.Id, &send.Subject, &send.PeriodStart, &send.PeriodEnd, &send.PostCount, &send.RecipientCount, &send.FailedCount, &send.StartedAt, &send.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &send, nil
+}
+
+// FindAll returns one page of the send history, newest first, and the total.
+func (r *SendRepository) FindAll(ctx context.Context, limit, offset int) ([]Send, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM newsletter_sends`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sendColumns+` FROM newsletter_sends ORDER BY started_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	sends := []Send{}
+	for rows.Next() {
+		var send Send
+		if err := rows.Scan(&send.Id, &send.Subject, &send.PeriodStart, &send.PeriodEnd, &send.PostCount, &send.RecipientCount, &send.FailedCount, &send.StartedAt, &send.FinishedAt); err != nil {
+			return nil, 0, err
+		}
+		sends = append(sends, send)
+	}
+
+	return sends, total, rows.Err()
+}
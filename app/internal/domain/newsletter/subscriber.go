@@ -0,0 +1,80 @@
+package newsletter
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusPending      Status = "pending"
+	StatusConfirmed    Status = "confirmed"
+	StatusUnsubscribed Status = "unsubscribed"
+)
+
+// Statuses lists every status, in the order the admin tabs show them.
+var Statuses = []Status{StatusConfirmed, StatusPending, StatusUnsubscribed}
+
+// ConfirmTokenExpirationDuration is how long a confirmation link works. A
+// sign up nobody confirms is removed by the digest job's sweep.
+const ConfirmTokenExpirationDuration = 48 * time.Hour
+
+type Subscriber struct {
+	Id             uuid.UUID
+	Email          string
+	Status         Status
+	CreatedAt      time.Time
+	ConfirmedAt    sql.NullTime
+	UnsubscribedAt sql.NullTime
+}
+
+type ConsentAction string
+
+const (
+	ConsentSubscribe   ConsentAction = "subscribe"
+	ConsentConfirm     ConsentAction = "confirm"
+	ConsentUnsubscribe ConsentAction = "unsubscribe"
+)
+
+// ConsentText is the wording next to the sign up form. It is stored with every
+// sign up, so the record shows what was agreed to even after it is reworded.
+const ConsentText = "Съгласявам се да получавам седмичен бюлетин с новите публикации на Движи се на този имейл. Мога да се отпиша по всяко време от линка във всяко писмо."
+
+// Consent is one entry of a subscriber's consent history.
+type Consent struct {
+	Id           uuid.UUID
+	SubscriberId uuid.UUID
+	Action       ConsentAction
+	ConsentText  string
+	IP           string
+	UserAgent    string
+	CreatedAt    time.Time
+}
+
+// Send is one digest, recorded before its first copy is queued so that a
+// retry resumes it rather than starting over.
+type Send struct {
+	Id             uuid.UUID
+	Subject        string
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	PostCount      int
+	RecipientCount int
+	// FailedCount is the digests that could not be queued. Delivery failures
+	// after that are tracked by the email outbox.
+	FailedCount int
+	StartedAt   time.Time
+	// FinishedAt is unset while copies are still to be queued.
+	FinishedAt sql.NullTime
+}
+
+// DigestPost is a post as the digest email lists it.
+type DigestPost struct {
+	Title       string
+	URL         string
+	Excerpt     string
+	PublishedAt time.Time
+}
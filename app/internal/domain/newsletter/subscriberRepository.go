@@ -0,0 +1,177 @@
+package newsletter
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const subscriberColumns = `id, email, status, created_at, confirmed_at, unsubscribed_at`
+
+type SubscriberRepository struct {
+	db *sql.DB
+}
+
+func NewSubscriberRepository(db *sql.DB) *SubscriberRepository {
+	return &SubscriberRepository{db: db}
+}
+
+func (r *SubscriberRepository) FindById(ctx context.Context, id uuid.UUID) (*Subscriber, error) {
+	return scanSubscriber(r.db.QueryRowContext(ctx, `SELECT `+subscriberColumns+` FROM newsletter_subscribers WHERE id = $1`, id))
+}
+
+func (r *SubscriberRepository) FindByEmail(ctx context.Context, email string) (*Subscriber, error) {
+	return scanSubscriber(r.db.QueryRowContext(ctx, `SELECT `+subscriberColumns+` FROM newsletter_subscribers WHERE email = $1`, email))
+}
+
+// Create stores a new pending subscriber waiting on the given confirmation
+// token.
+func (r *SubscriberRepository) Create(ctx context.Context, subscriber Subscriber, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO newsletter_subscribers (id, email, status, confirm_token_hash, confirm_expires_at, created_at)
+		VALUES ($1, $2, 'pending', $3, $4, $5)`,
+		subscriber.Id, subscriber.Email, tokenHash, time.Now().UTC().Add(ConfirmTokenExpirationDuration), subscriber.CreatedAt)
+
+	return err
+}
+
+// ResetPending puts an existing subscriber back to pending on a new token. The
+// old link stops working, so only the newest email can confirm.
+func (r *SubscriberRepository) ResetPending(ctx context.Context, id uuid.UUID, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE newsletter_subscribers
+		SET status = 'pending', confirm_token_hash = $2, confirm_expires_at = $3, unsubscribed_at = NULL
+		WHERE id = $1`,
+		id, tokenHash, time.Now().UTC().Add(ConfirmTokenExpirationDuration))
+
+	return err
+}
+
+// Confirm consumes a confirmation token. It returns sql.ErrNoRows for a token
+// that is unknown, expired or already used.
+func (r *SubscriberRepository) Confirm(ctx context.Context, tokenHash string) (*Subscriber, error) {
+	return scanSubscriber(r.db.QueryRowContext(ctx, `
+		UPDATE newsletter_subscribers
+		SET status = 'confirmed', confirmed_at = NOW(), confirm_token_hash = NULL, confirm_expires_at = NULL
+		WHERE confirm_token_hash = $1 AND confirm_expires_at > NOW() AND status = 'pending'
+		RETURNING `+subscriberColumns, tokenHash))
+}
+
+// Unsubscribe reports whether the subscriber was still subscribed, so a
+// second click records nothing new.
+func (r *SubscriberRepository) Unsubscribe(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE newsletter_subscribers
+		SET status = 'unsubscribed', unsubscribed_at = NOW(), confirm_token_hash = NULL, confirm_expires_at = NULL
+		WHERE id = $1 AND status <> 'unsubscribed'`, id)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+// Delete erases the subscriber and their consent history, for a GDPR erasure
+// request.
+func (r *SubscriberRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM newsletter_subscribers WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// DeletePendingBefore removes sign ups that were never confirmed. Nobody
+// proved they own those addresses, so there is no reason to keep them.
+func (r *SubscriberRepository) DeletePendingBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM newsletter_subscribers WHERE status = 'pending' AND created_at < $1`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// FindByStatus returns one page of subscribers in the status, newest first,
+// and the total in it.
+func (r *SubscriberRepository) FindByStatus(ctx context.Context, status Status, limit, offset int) ([]Subscriber, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM newsletter_subscribers WHERE status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+subscriberColumns+`
+		FROM newsletter_subscribers
+		WHERE status = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3`, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	subscribers, err := scanSubscribers(rows)
+	return subscribers, total, err
+}
+
+func (r *SubscriberRepository) AddConsent(ctx context.Context, consent Consent) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO newsletter_consents (id, subscriber_id, action, consent_text, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		consent.Id, consent.SubscriberId, consent.Action, consent.ConsentText, consent.IP, consent.UserAgent, consent.CreatedAt)
+
+	return err
+}
+
+// FindConsents returns the subscriber's consent history, oldest first.
+func (r *SubscriberRepository) FindConsents(ctx context.Context, subscriberId uuid.UUID) ([]Consent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, subscriber_id, action, consent_text, ip, user_agent, created_at
+		FROM newsletter_consents
+		WHERE subscriber_id = $1
+		ORDER BY created_at, id`, subscriberId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []Consent{}
+	for rows.Next() {
+		var consent Consent
+		if err := rows.Scan(&consent.Id, &consent.SubscriberId, &consent.Action, &consent.ConsentText, &consent.IP, &consent.UserAgent, &consent.CreatedAt); err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+
+	return consents, rows.Err()
+}
+
+func scanSubscriber(row *sql.Row) (*Subscriber, error) {
+	var subscriber Subscriber
+	err := row.Scan(&subscriber.Id, &subscriber.Email, &subscriber.Status, &subscriber.CreatedAt, &subscriber.ConfirmedAt, &subscriber.UnsubscribedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscriber, nil
+}
+
+func scanSubscribers(rows *sql.Rows) ([]Subscriber, error) {
+	subscribers := []Subscriber{}
+	for rows.Next() {
+		var subscriber Subscriber
+		if err := rows.Scan(&subscriber.Id, &subscriber.Email, &subscriber.Status, &subscriber.CreatedAt, &subscriber.ConfirmedAt, &subscriber.UnsubscribedAt); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+
+	return subscribers, rows.Err()
+}
@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	// StatusPending covers both a message not tried yet and one waiting for
+	// its next attempt.
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+	// StatusDead is a message that ran out of attempts. Only an administrator
+	// sends it again.
+	StatusDead Status = "dead"
+)
+
+// Message is one queued email.
+type Message struct {
+	Id        uuid.UUID
+	Recipient string
+	Subject   string
+	Body      string
+	// Headers are added to the standard ones, such as List-Unsubscribe.
+	Headers       map[string]string
+	Status        Status
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        sql.NullTime
+}
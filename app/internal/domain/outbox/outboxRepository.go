@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const messageColumns = `id, recipient, subject, body, headers, status, attempts, next_attempt_at, COALESCE(last_error, ''), created_at, sent_at`
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue stores the message as pending and due at once.
+func (r *OutboxRepository) Enqueue(ctx context.Context, message Message) error {
+	headers := []byte("{}")
+	if len(message.Headers) > 0 {
+		var err error
+		if headers, err = json.Marshal(message.Headers); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO email_outbox (id, recipient, subject, body, headers, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', $6, $6)`,
+		message.Id, message.Recipient, message.Subject, message.Body, string(headers), message.CreatedAt)
+
+	return err
+}
+
+// Claim takes up to limit due messages for delivery and counts the attempt.
+// The rows are locked with SKIP LOCKED only while they are claimed, so
+// workers never wait on each other, and claiming pushes the next attempt out
+// by lease: if the worker dies mid-send the message comes due again then,
+// instead of being stuck.
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+messageColumns, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// MarkSent records the delivery and drops the body and headers. They can hold
+// links with live tokens, which have no business outliving the email.
+func (r *OutboxRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'sent', sent_at = NOW(), body = '', headers = '{}', last_error = NULL
+		WHERE id = $1`, id)
+
+	return err
+}
+
+// MarkFailed records a failed attempt and when to try again.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET last_error = $2, next_attempt_at = $3
+		WHERE id = $1`, id, lastError, nextAttemptAt.UTC())
+
+	return err
+}
+
+// MarkDead gives up on a message after its last failed attempt.
+func (r *OutboxRepository) MarkDead(ctx context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'dead', last_error = $2
+		WHERE id = $1`, id, lastError)
+
+	return err
+}
+
+// Retry puts a dead message back in the queue with a fresh set of attempts.
+// It reports false when there is no dead message with that id.
+func (r *OutboxRepository) Retry(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status = 'dead'`, id)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+// FindFailures returns one page of the messages that failed at least once and
+// were not delivered since: the dead ones and those waiting for a retry.
+// Newest first, with the total.
+func (r *OutboxRepository) FindFailures(ctx context.Context, limit, offset int) ([]Message, int, error) {
+	const where = `WHERE status = 'dead' OR (status = 'pending' AND last_error IS NOT NULL)`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM email_outbox `+where).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM email_outbox
+		`+where+`
+		ORDER BY created_at DESC, id
+		LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	return messages, total, err
+}
+
+// DeleteSentBefore removes the record of messages delivered before cutoff.
+func (r *OutboxRepository) DeleteSentBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM email_outbox WHERE status = 'sent' AND sent_at < $1`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func scanMessages(rows *sql.Rows) ([]Message, error) {
+	messages := []Message{}
+	for rows.Next() {
+		var message Message
+		var headers []byte
+		if err := rows.Scan(&message.Id, &message.Recipient, &message.Subject, &message.Body, &headers, &message.Status,
+			&message.Attempts, &message.NextAttemptAt, &message.LastError, &message.CreatedAt, &message.SentAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(headers, &message.Headers); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
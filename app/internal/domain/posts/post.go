@@ -47,4 +47,6 @@ type PostWithAuthor struct {
 	AuthorLastName  string `json:"author_last_name"`
 	CategoryName    string `json:"category_name"`
 	CategorySlug    string `json:"category_slug"`
+	// CommentCount is the number of approved comments.
+	CommentCount int `json:"comment_count"`
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/google/uuid"
@@ -55,7 +56,8 @@ func (r *PostRepository) Update(ctx context.Context, post Post) (*Post, error) {
 	query := `
 		UPDATE posts SET title = $1, slug = $2, content = $3, excerpt = $4, cover_image_url = $5,
 			status = $6, published_at = $7, meta_description = $8, reading_time_minutes = $9,
-			category_id = $10, updated_at = NOW(), updated_by = $11, metadata = $12
+			category_id = $10, updated_at = NOW(), updated_by = $11, metadata = $12,
+			scheduled_at = CASE WHEN $6 = 'published' THEN NULL ELSE scheduled_at END
 		WHERE id = $13 AND is_deleted = FALSE
 		RETURNING id, title, slug, content, excerpt, cover_image_url, status, published_at,
 			meta_description, reading_time_minutes, category_id, creator_user_id, created_at, updated_at, updated_by, is_deleted, metadata`
@@ -122,11 +124,54 @@ func (r *PostRepository) FindById(ctx context.Context, id uuid.UUID) (*Post, err
 	return &post, nil
 }
 
+// FindByCreator returns every post the user created, including soft deleted
+// ones, for the export of their personal data.
+func (r *PostRepository) FindByCreator(ctx context.Context, creatorId uuid.UUID) ([]Post, error) {
+	query := `
+		SELECT id, title, slug, content, excerpt, cover_image_url, status, published_at,
+			meta_description, reading_time_minutes, category_id, creator_user_id, created_at, updated_at, updated_by, is_deleted, metadata
+		FROM posts WHERE creator_user_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.Db.QueryContext(ctx, query, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Post
+	for rows.Next() {
+		var post Post
+		var excerpt, coverImageUrl, metaDescription, updatedBy sql.NullString
+		var metadata []byte
+
+		if err := rows.Scan(
+			&post.Id, &post.Title, &post.Slug, &post.Content,
+			&excerpt, &coverImageUrl, &post.Status, &post.PublishedAt,
+			&metaDescription, &post.ReadingTimeMinutes, &post.CategoryId,
+			&post.CreatorUserId, &post.CreatedAt, &post.UpdatedAt,
+			&updatedBy, &post.IsDeleted, &metadata,
+		); err != nil {
+			return nil, err
+		}
+
+		post.Excerpt = excerpt.String
+		post.CoverImageUrl = coverImageUrl.String
+		post.MetaDescription = metaDescription.String
+		post.UpdatedBy = updatedBy.String
+		post.Metadata = metadata
+		result = append(result, post)
+	}
+
+	return result, rows.Err()
+}
+
 func (r *PostRepository) FindBySlug(ctx context.Context, slug string) (*PostWithAuthor, error) {
 	query := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -142,7 +187,7 @@ func (r *PostRepository) FindBySlug(ctx context.Context, slug string) (*PostWith
 		&metaDescription, &post.ReadingTimeMinutes, &post.CategoryId,
 		&post.CreatorUserId, &post.CreatedAt, &post.UpdatedAt,
 		&updatedBy, &post.IsDeleted, &metadata,
-		&firstName, &lastName, &post.CategoryName, &post.CategorySlug,
+		&firstName, &lastName, &post.CategoryName, &post.CategorySlug, &post.CommentCount,
 	)
 
 	post.Excerpt = excerpt.String
@@ -169,7 +214,8 @@ func (r *PostRepository) FindPublished(ctx context.Context, limit, offset int) (
 	query := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -180,6 +226,31 @@ func (r *PostRepository) FindPublished(ctx context.Context, limit, offset int) (
 	return r.queryPostsWithAuthor(ctx, query, total, limit, offset)
 }
 
+// FindPublishedBetween returns up to limit posts published after since and
+// up to until, newest first.
+func (r *PostRepository) FindPublishedBetween(ctx context.Context, since, until time.Time, limit int) ([]PostWithAuthor, error) {
+	query := `
+		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
+			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
+		FROM posts p
+		JOIN users u ON p.creator_user_id = u.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.status = 'published' AND p.is_deleted = FALSE
+			AND p.published_at > $1 AND p.published_at <= $2
+		ORDER BY p.published_at DESC
+		LIMIT $3`
+
+	rows, err := r.Db.QueryContext(ctx, query, since, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanPostsWithAuthor(rows)
+}
+
 func (r *PostRepository) FindByCategory(ctx context.Context, categorySlug string, limit, offset int) ([]PostWithAuthor, int, error) {
 	countQuery := `
 		SELECT COUNT(*) FROM posts p
@@ -193,7 +264,8 @@ func (r *PostRepository) FindByCategory(ctx context.Context, categorySlug string
 	query := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -224,7 +296,8 @@ func (r *PostRepository) FindAll(ctx context.Context, limit, offset int) ([]Post
 	query := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -245,7 +318,8 @@ func (r *PostRepository) FindByStatus(ctx context.Context, status PostStatus, li
 	query := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -270,7 +344,8 @@ func (r *PostRepository) FindRecent(ctx context.Context, limit int) ([]PostWithA
 	query := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -305,7 +380,8 @@ func (r *PostRepository) Search(ctx context.Context, query string, limit, offset
 	selectQuery := `
 		SELECT p.id, p.title, p.slug, p.content, p.excerpt, p.cover_image_url, p.status, p.published_at,
 			p.meta_description, p.reading_time_minutes, p.category_id, p.creator_user_id, p.created_at, p.updated_at, p.updated_by, p.is_deleted, p.metadata,
-			u.first_name, u.last_name, c.name, c.slug
+			u.first_name, u.last_name, c.name, c.slug,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.post_id = p.id AND cm.status = 'approved')
 		FROM posts p
 		JOIN users u ON p.creator_user_id = u.id
 		JOIN categories c ON p.category_id = c.id
@@ -435,6 +511,49 @@ func (r *PostRepository) ExistsBySlug(ctx context.Context, slug string, excludeI
 	return exists, err
 }
 
+// Reindex rebuilds the search index. search_vector is kept up to date by
+// Postgres, but a GIN index bloats under heavy editing and a rebuild is the
+// way to shrink it. CONCURRENTLY keeps search working meanwhile.
+func (r *PostRepository) Reindex(ctx context.Context) error {
+	if _, err := r.Db.ExecContext(ctx, `REINDEX INDEX CONCURRENTLY idx_posts_search`); err != nil {
+		return err
+	}
+
+	_, err := r.Db.ExecContext(ctx, `ANALYZE posts`)
+	return err
+}
+
+// FindUnreferenced returns those of the given strings that appear in no live
+// post's cover or content and no category image. Matching is by substring, so
+// an asset's public id is found inside any of its delivery URLs.
+func (r *PostRepository) FindUnreferenced(ctx context.Context, needles []string) ([]string, error) {
+	rows, err := r.Db.QueryContext(ctx, `
+		SELECT needle FROM unnest($1::text[]) AS needle
+		WHERE NOT EXISTS (
+			SELECT 1 FROM posts
+			WHERE is_deleted = FALSE
+			  AND (strpos(coalesce(cover_image_url, ''), needle) > 0 OR strpos(content, needle) > 0)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM categories WHERE strpos(image_url, needle) > 0
+		)`, needles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unreferenced []string
+	for rows.Next() {
+		var needle string
+		if err := rows.Scan(&needle); err != nil {
+			return nil, err
+		}
+		unreferenced = append(unreferenced, needle)
+	}
+
+	return unreferenced, rows.Err()
+}
+
 func (r *PostRepository) queryPostsWithAuthor(ctx context.Context, query string, total int, limit, offset int) ([]PostWithAuthor, int, error) {
 	rows, err := r.Db.QueryContext(ctx, query, limit, offset)
 	if err != nil {
@@ -462,7 +581,7 @@ func (r *PostRepository) scanPostsWithAuthor(rows *sql.Rows) ([]PostWithAuthor,
 			&metaDescription, &post.ReadingTimeMinutes, &post.CategoryId,
 			&post.CreatorUserId, &post.CreatedAt, &post.UpdatedAt,
 			&updatedBy, &post.IsDeleted, &metadata,
-			&firstName, &lastName, &post.CategoryName, &post.CategorySlug,
+			&firstName, &lastName, &post.CategoryName, &post.CategorySlug, &post.CommentCount,
 		)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,49 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schedule sets when an unpublished post goes out. It reports false when the
+// post does not exist or is already published.
+func (r *PostRepository) Schedule(ctx context.Context, id uuid.UUID, at time.Time, updatedBy string) (bool, error) {
+	result, err := r.Db.ExecContext(ctx, `
+		UPDATE posts SET scheduled_at = $1, updated_at = NOW(), updated_by = $2
+		WHERE id = $3 AND status <> 'published' AND is_deleted = FALSE`,
+		at, updatedBy, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// PublishDue publishes the posts whose time has come and returns their slugs.
+// The publication date is the scheduled time, not the time the sweep ran.
+func (r *PostRepository) PublishDue(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := r.Db.QueryContext(ctx, `
+		UPDATE posts
+		SET status = 'published', published_at = scheduled_at, scheduled_at = NULL,
+			updated_at = NOW(), updated_by = 'scheduler'
+		WHERE scheduled_at <= $1 AND status <> 'published' AND is_deleted = FALSE
+		RETURNING slug`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, rows.Err()
+}
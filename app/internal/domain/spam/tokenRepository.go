@@ -0,0 +1,133 @@
+package spam
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type Label string
+
+const (
+	LabelSpam Label = "spam"
+	LabelHam  Label = "ham"
+)
+
+// TokenCount is how many spam and legitimate documents a token appeared in.
+type TokenCount struct {
+	Spam int
+	Ham  int
+}
+
+// Totals is how many documents the classifier has learned from per label.
+type Totals struct {
+	Spam int
+	Ham  int
+}
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// Counts returns the learned counts for the given tokens, leaving out the ones
+// never seen, and the document totals they are measured against.
+func (r *TokenRepository) Counts(ctx context.Context, tokens []string) (map[string]TokenCount, Totals, error) {
+	var totals Totals
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE label = 'spam'), COUNT(*) FILTER (WHERE label = 'ham')
+		FROM spam_documents`).Scan(&totals.Spam, &totals.Ham)
+	if err != nil {
+		return nil, Totals{}, err
+	}
+
+	counts := make(map[string]TokenCount, len(tokens))
+	if len(tokens) == 0 {
+		return counts, totals, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT token, spam_count, ham_count
+		FROM spam_tokens
+		WHERE token = ANY($1::text[])`, tokens)
+	if err != nil {
+		return nil, Totals{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var token string
+		var count TokenCount
+		if err := rows.Scan(&token, &count.Spam, &count.Ham); err != nil {
+			return nil, Totals{}, err
+		}
+		counts[token] = count
+	}
+
+	return counts, totals, rows.Err()
+}
+
+// Train teaches the classifier that a document carrying these tokens is spam
+// or ham. Tokens must already be unique. Training the same document again
+// with the same label is a no-op; with the other label, its earlier lesson is
+// taken back first so a moderator changing their mind is not counted twice.
+func (r *TokenRepository) Train(ctx context.Context, documentId uuid.UUID, tokens []string, label Label) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var previous Label
+	err = tx.QueryRowContext(ctx, `SELECT label FROM spam_documents WHERE id = $1 FOR UPDATE`, documentId).Scan(&previous)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case previous == label:
+		return nil
+	default:
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE spam_tokens
+			SET `+countColumn(previous)+` = GREATEST(`+countColumn(previous)+` - 1, 0)
+			WHERE token = ANY($1::text[])`, tokens); err != nil {
+			return fmt.Errorf("could not untrain %s: %w", previous, err)
+		}
+	}
+
+	if len(tokens) > 0 {
+		column := countColumn(label)
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO spam_tokens (token, `+column+`)
+			SELECT token, 1 FROM unnest($1::text[]) AS token
+			ON CONFLICT (token) DO UPDATE SET `+column+` = spam_tokens.`+column+` + 1`, tokens); err != nil {
+			return fmt.Errorf("could not train %s: %w", label, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO spam_documents (id, label, trained_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (id) DO UPDATE SET label = EXCLUDED.label, trained_at = EXCLUDED.trained_at`,
+		documentId, label); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// countColumn maps a label to its column. Only the two constants reach it, so
+// the result is safe to splice into a query.
+func countColumn(label Label) string {
+	if label == LabelSpam {
+		return "spam_count"
+	}
+
+	return "ham_count"
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeletionTokenExpirationDuration is short: the link starts an irreversible
+// process once the grace period runs out.
+const DeletionTokenExpirationDuration = 1 * time.Hour
+
+type AccountDeletionToken struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type AccountDeletionTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAccountDeletionTokenRepository(db *sql.DB) *AccountDeletionTokenRepository {
+	return &AccountDeletionTokenRepository{db: db}
+}
+
+// Create stores a new deletion token
+func (r *AccountDeletionTokenRepository) Create(ctx context.Context, userId uuid.UUID, tokenHash string) (*AccountDeletionToken, error) {
+	token := &AccountDeletionToken{
+		Id:        uuid.New(),
+		UserId:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(DeletionTokenExpirationDuration),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `
+		INSERT INTO account_deletion_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, token.Id, token.UserId, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// FindValidByHash finds a valid (not used, not expired) token by its hash
+func (r *AccountDeletionTokenRepository) FindValidByHash(ctx context.Context, tokenHash string) (*AccountDeletionToken, error) {
+	var token AccountDeletionToken
+
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM account_deletion_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.Id, &token.UserId, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateAllForUser marks all pending deletion tokens for a user as used
+func (r *AccountDeletionTokenRepository) InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error {
+	query := `UPDATE account_deletion_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+// DeleteExpired removes expired tokens (for the cleanup command)
+func (r *AccountDeletionTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM account_deletion_tokens WHERE expires_at < NOW()`
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,100 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeTokenExpirationDuration matches the reset token: a change of
+// address is as sensitive as a change of password.
+const EmailChangeTokenExpirationDuration = 1 * time.Hour
+
+// EmailChangeToken is a pending move of an account to NewEmail, confirmed by
+// the link sent to that address.
+type EmailChangeToken struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type EmailChangeTokenRepository struct {
+	db *sql.DB
+}
+
+func NewEmailChangeTokenRepository(db *sql.DB) *EmailChangeTokenRepository {
+	return &EmailChangeTokenRepository{db: db}
+}
+
+// Create stores a new email change token
+func (r *EmailChangeTokenRepository) Create(ctx context.Context, userId uuid.UUID, newEmail, tokenHash string) (*EmailChangeToken, error) {
+	token := &EmailChangeToken{
+		Id:        uuid.New(),
+		UserId:    userId,
+		NewEmail:  newEmail,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(EmailChangeTokenExpirationDuration),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `
+		INSERT INTO email_change_tokens (id, user_id, new_email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query, token.Id, token.UserId, token.NewEmail, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// FindValidByHash finds a valid (not used, not expired) token by its hash
+func (r *EmailChangeTokenRepository) FindValidByHash(ctx context.Context, tokenHash string) (*EmailChangeToken, error) {
+	var token EmailChangeToken
+
+	query := `
+		SELECT id, user_id, new_email, token_hash, expires_at, used_at, created_at
+		FROM email_change_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.Id, &token.UserId, &token.NewEmail, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkAsUsed marks a token as used
+func (r *EmailChangeTokenRepository) MarkAsUsed(ctx context.Context, tokenId uuid.UUID) error {
+	query := `UPDATE email_change_tokens SET used_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, tokenId)
+	return err
+}
+
+// InvalidateAllForUser marks all pending changes for a user as used, so a new
+// request replaces any earlier one.
+func (r *EmailChangeTokenRepository) InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error {
+	query := `UPDATE email_change_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+// DeleteExpired removes expired tokens (for the cleanup command)
+func (r *EmailChangeTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM email_change_tokens WHERE expires_at < NOW()`
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
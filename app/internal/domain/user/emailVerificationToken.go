@@ -0,0 +1,99 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VerificationTokenExpirationDuration is longer than a reset token's hour: a
+// new account is not at risk while it waits, and the mail may sit unread.
+const VerificationTokenExpirationDuration = 24 * time.Hour
+
+type EmailVerificationToken struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+// EmailVerificationTokenRepository stores verification tokens the same way
+// password reset tokens are stored: only the hash, generated with
+// GenerateToken and looked up with HashToken.
+type EmailVerificationTokenRepository struct {
+	db *sql.DB
+}
+
+func NewEmailVerificationTokenRepository(db *sql.DB) *EmailVerificationTokenRepository {
+	return &EmailVerificationTokenRepository{db: db}
+}
+
+// Create stores a new verification token
+func (r *EmailVerificationTokenRepository) Create(ctx context.Context, userId uuid.UUID, tokenHash string) (*EmailVerificationToken, error) {
+	token := &EmailVerificationToken{
+		Id:        uuid.New(),
+		UserId:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(VerificationTokenExpirationDuration),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `
+		INSERT INTO email_verification_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, token.Id, token.UserId, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// FindValidByHash finds a valid (not used, not expired) token by its hash
+func (r *EmailVerificationTokenRepository) FindValidByHash(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	var token EmailVerificationToken
+
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM email_verification_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.Id, &token.UserId, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkAsUsed marks a token as used
+func (r *EmailVerificationTokenRepository) MarkAsUsed(ctx context.Context, tokenId uuid.UUID) error {
+	query := `UPDATE email_verification_tokens SET used_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, tokenId)
+	return err
+}
+
+// InvalidateAllForUser marks all tokens for a user as used, so only the most
+// recently sent link works after a resend.
+func (r *EmailVerificationTokenRepository) InvalidateAllForUser(ctx context.Context, userId uuid.UUID) error {
+	query := `UPDATE email_verification_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+// DeleteExpired removes expired tokens (for cleanup job)
+func (r *EmailVerificationTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM email_verification_tokens WHERE expires_at < NOW()`
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
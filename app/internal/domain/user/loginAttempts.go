@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginState is the account's recent record of failed sign-ins.
+type LoginState struct {
+	FailedCount  int
+	LastFailedAt sql.NullTime
+	LockedUntil  sql.NullTime
+}
+
+// LockedAt reports whether the account is locked at the given instant.
+func (s LoginState) LockedAt(now time.Time) bool {
+	return s.LockedUntil.Valid && now.Before(s.LockedUntil.Time)
+}
+
+func (repo *UserRepository) FindLoginState(ctx context.Context, userId uuid.UUID) (LoginState, error) {
+	var state LoginState
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT failed_login_count, last_failed_login_at, locked_until
+		FROM users
+		WHERE id = $1`, userId).Scan(&state.FailedCount, &state.LastFailedAt, &state.LockedUntil)
+
+	return state, err
+}
+
+// RecordFailedLogin counts a failed sign-in and returns the new state. A
+// failure after a quiet spell longer than window starts the count over, so
+// mistypes spread over weeks never add up to a lockout. The increment is a
+// single statement, so concurrent attempts on different instances are all
+// counted.
+func (repo *UserRepository) RecordFailedLogin(ctx context.Context, userId uuid.UUID, window time.Duration) (LoginState, error) {
+	var state LoginState
+	err := repo.db.QueryRowContext(ctx, `
+		UPDATE users
+		SET failed_login_count = CASE WHEN last_failed_login_at > $2 THEN failed_login_count + 1 ELSE 1 END,
+		    last_failed_login_at = NOW()
+		WHERE id = $1
+		RETURNING failed_login_count, last_failed_login_at, locked_until`,
+		userId, time.Now().UTC().Add(-window)).Scan(&state.FailedCount, &state.LastFailedAt, &state.LockedUntil)
+
+	return state, err
+}
+
+// Lock locks the account until the given instant and starts the failure count
+// over. It reports false when the account was already locked, so with several
+// instances racing exactly one of them goes on to notify the owner.
+func (repo *UserRepository) Lock(ctx context.Context, userId uuid.UUID, until time.Time) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, `
+		UPDATE users
+		SET locked_until = $2, failed_login_count = 0
+		WHERE id = $1 AND (locked_until IS NULL OR locked_until <= NOW())`, userId, until.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ClearFailedLogins forgets the failures and lifts any lock.
+func (repo *UserRepository) ClearFailedLogins(ctx context.Context, userId uuid.UUID) error {
+	_, err := repo.db.ExecContext(ctx, `
+		UPDATE users
+		SET failed_login_count = 0, last_failed_login_at = NULL, locked_until = NULL
+		WHERE id = $1 AND (failed_login_count > 0 OR locked_until IS NOT NULL)`, userId)
+
+	return err
+}
@@ -108,7 +108,7 @@ func (r *PasswordResetTokenRepository) InvalidateAllForUser(ctx context.Context,
 	return err
 }
 
-// DeleteExpired removes expired tokens (for cleanup job)
+// DeleteExpired removes expired tokens (for the cleanup job)
 func (r *PasswordResetTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
 	query := `DELETE FROM password_reset_tokens WHERE expires_at < NOW()`
 	result, err := r.db.ExecContext(ctx, query)
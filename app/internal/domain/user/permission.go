@@ -7,6 +7,40 @@ import (
 	"github.com/google/uuid"
 )
 
+// The permissions the routes check. A user holds the union of what their
+// roles grant and what was granted to them directly; the names are seeded by
+// the 00006_permissions migration and later ones.
+const (
+	PermAdminAccess      = "admin:access"
+	PermPostsCreate      = "posts:create"
+	PermPostsEdit        = "posts:edit"
+	PermPostsEditOwn     = "posts:edit-own"
+	PermPostsPublish     = "posts:publish"
+	PermPostsDelete      = "posts:delete"
+	PermMediaUpload      = "media:upload"
+	PermCategoriesManage = "categories:manage"
+	PermUsersManage      = "users:manage"
+	PermAuditRead        = "audit:read"
+	PermCommentsModerate = "comments:moderate"
+	PermNewsletterManage = "newsletter:manage"
+	PermEmailsManage     = "emails:manage"
+	PermInboxManage      = "inbox:manage"
+	PermJobsManage       = "jobs:manage"
+	PermHealthRead       = "health:read"
+)
+
+// HasPermission reports whether the permissions contain one with the given
+// name.
+func HasPermission(permissions []Permission, name string) bool {
+	for _, permission := range permissions {
+		if permission.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Permission struct {
 	Id        uuid.UUID      `json:"id"`
 	Name      string         `json:"name"`
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenRecord is one link that was mailed for an account. The hash is never
+// part of it: it identifies nothing about the person and would only help an
+// attacker.
+type TokenRecord struct {
+	Purpose   string
+	NewEmail  sql.NullString
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+// FindTokenHistory lists every reset, verification, email change and deletion
+// link issued for the user, newest first.
+func (repo *UserRepository) FindTokenHistory(ctx context.Context, userId uuid.UUID) ([]TokenRecord, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT 'password_reset', NULL, created_at, expires_at, used_at FROM password_reset_tokens WHERE user_id = $1
+		UNION ALL
+		SELECT 'email_verification', NULL, created_at, expires_at, used_at FROM email_verification_tokens WHERE user_id = $1
+		UNION ALL
+		SELECT 'email_change', new_email, created_at, expires_at, used_at FROM email_change_tokens WHERE user_id = $1
+		UNION ALL
+		SELECT 'account_deletion', NULL, created_at, expires_at, used_at FROM account_deletion_tokens WHERE user_id = $1
+		ORDER BY 3 DESC`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []TokenRecord
+	for rows.Next() {
+		var record TokenRecord
+		if err := rows.Scan(&record.Purpose, &record.NewEmail, &record.CreatedAt, &record.ExpiresAt, &record.UsedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
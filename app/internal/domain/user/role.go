@@ -7,9 +7,14 @@ import (
 	"github.com/google/uuid"
 )
 
-// RoleAdmin is the only role that may reach the admin panel. Names are
-// compared exactly: the seed data stores them upper case.
-const RoleAdmin = "ADMIN"
+// Role names are compared exactly: the seed data stores them upper case.
+// Access is decided by permissions; ADMIN is singled out only so that the last
+// administrator cannot be removed.
+const (
+	RoleAdmin  = "ADMIN"
+	RoleEditor = "EDITOR"
+	RoleAuthor = "AUTHOR"
+)
 
 // HasRole reports whether the roles contain one with the given name.
 func HasRole(roles []Role, name string) bool {
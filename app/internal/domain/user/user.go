@@ -22,4 +22,11 @@ type User struct {
 
 	// TokensValidAfter invalidates every access token issued at or before it.
 	TokensValidAfter sql.NullTime
+
+	// EmailVerifiedAt is set once the owner of the address has followed the
+	// verification link. Self-registered accounts stay Inactive until then.
+	EmailVerifiedAt sql.NullTime
+
+	// DeletionScheduledAt is when a confirmed deletion request takes effect.
+	DeletionScheduledAt sql.NullTime
 }
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -10,6 +11,13 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrDeletionNotDue means the account has no deletion that is due.
+var ErrDeletionNotDue = errors.New("account deletion is not due")
+
+// ErrLastAdmin means the change would leave the site without an active
+// administrator, and with it nobody who could undo the change.
+var ErrLastAdmin = errors.New("the last active administrator cannot be removed")
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -43,81 +51,28 @@ func (repo *UserRepository) createWithRole(ctx context.Context, user User, roleN
 		}
 	}()
 
-	query := `INSERT INTO users (id, email, password, status, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err = tx.ExecContext(ctx, query, user.Id, user.Email, user.Password, user.Status, user.CreatedAt)
-	if err != nil {
-		return err
-	}
-
-	roleQuery := `
-		SELECT
-			r.id, r.name, r.created_at, r.updated_at, r.updated_by, r.is_deleted,
-			p.id, p.name, p.created_at, p.updated_at, p.updated_by, p.is_deleted
-		FROM roles r
-		JOIN roles_permissions rp ON r.id = rp.role_id
-		JOIN permissions p ON p.id = rp.permission_id
-		WHERE r.name = $1 AND r.is_deleted = FALSE`
-	roles, err := tx.QueryContext(ctx, roleQuery, roleName)
+	query := `INSERT INTO users (id, email, password, status, created_at, email_verified_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = tx.ExecContext(ctx, query, user.Id, user.Email, user.Password, user.Status, user.CreatedAt, user.EmailVerifiedAt)
 	if err != nil {
 		return err
 	}
 
-	defer roles.Close()
-
-	var role Role
-	role.Permissions = []Permission{}
-	for roles.Next() {
-		var perm Permission
-		// Assign to the outer err so the deferred rollback sees the failure.
-		err = roles.Scan(
-			&role.Id, &role.Name, &role.CreatedAt, &role.UpdatedAt, &role.UpdatedBy, &role.IsDeleted,
-			&perm.Id, &perm.Name, &perm.CreatedAt, &perm.UpdatedAt, &perm.UpdatedBy, &perm.IsDeleted,
-		)
-		if err != nil {
-			return err
-		}
-
-		role.Permissions = append(role.Permissions, perm)
-	}
-
-	if err = roles.Err(); err != nil {
-		return err
-	}
-
-	if role.Id == uuid.Nil {
+	// The role's permissions are resolved through users_roles when the user
+	// loads, so nothing is copied onto the user here.
+	var roleId uuid.UUID
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM roles WHERE name = $1 AND is_deleted = FALSE ORDER BY created_at LIMIT 1`, roleName).Scan(&roleId)
+	if errors.Is(err, sql.ErrNoRows) {
 		err = fmt.Errorf("role %q not found, cannot create user", roleName)
 		return err
 	}
-
-	rolesQuery := `INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2)`
-	_, err = tx.ExecContext(ctx, rolesQuery, user.Id, role.Id)
 	if err != nil {
 		return err
 	}
 
-	// A role with no permissions is legitimate; skip the insert rather than
-	// building "INSERT INTO users_permissions VALUES " with no rows.
-	if len(role.Permissions) > 0 {
-		var permissionsQuery strings.Builder
-		permissionsQuery.WriteString("INSERT INTO users_permissions (user_id, permission_id) VALUES ")
-
-		args := make([]any, 0, len(role.Permissions)*2)
-		argPos := 1
-
-		for i, perm := range role.Permissions {
-			if i > 0 {
-				permissionsQuery.WriteString(", ")
-			}
-
-			fmt.Fprintf(&permissionsQuery, "($%d, $%d)", argPos, argPos+1)
-			args = append(args, user.Id, perm.Id)
-			argPos += 2
-		}
-
-		_, err = tx.ExecContext(ctx, permissionsQuery.String(), args...)
-		if err != nil {
-			return err
-		}
+	_, err = tx.ExecContext(ctx, `INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2)`, user.Id, roleId)
+	if err != nil {
+		return err
 	}
 
 	err = tx.Commit()
@@ -135,11 +90,11 @@ func (repo *UserRepository) FindByEmail(ctx context.Context, email string) (User
 	var updatedAt sql.NullTime
 
 	err := repo.db.QueryRowContext(ctx, `
-		SELECT id, email, first_name, last_name, password, status, created_at, updated_at, is_deleted, tokens_valid_after
+		SELECT id, email, first_name, last_name, password, status, created_at, updated_at, is_deleted, tokens_valid_after, email_verified_at, deletion_scheduled_at
 		FROM users
 		WHERE email = $1 AND is_deleted = FALSE`, email).Scan(
 		&user.Id, &user.Email, &firstName, &lastName, &user.Password,
-		&user.Status, &user.CreatedAt, &updatedAt, &user.IsDeleted, &user.TokensValidAfter,
+		&user.Status, &user.CreatedAt, &updatedAt, &user.IsDeleted, &user.TokensValidAfter, &user.EmailVerifiedAt, &user.DeletionScheduledAt,
 	)
 	if err != nil {
 		return User{}, err
@@ -182,11 +137,20 @@ func (repo *UserRepository) loadRolesAndPermissions(ctx context.Context, user *U
 		return rowsErr
 	}
 
+	// Permissions granted through a role and those granted to the user
+	// directly, each once.
 	permRows, err := repo.db.QueryContext(ctx, `
 		SELECT p.id, p.name, p.created_at, p.updated_at, p.updated_by, p.is_deleted
 		FROM permissions p
-		JOIN users_permissions up ON p.id = up.permission_id
-		WHERE up.user_id = $1 AND p.is_deleted = FALSE`, user.Id)
+		WHERE p.is_deleted = FALSE AND p.id IN (
+			SELECT up.permission_id FROM users_permissions up WHERE up.user_id = $1
+			UNION
+			SELECT rp.permission_id FROM roles_permissions rp
+			JOIN users_roles ur ON ur.role_id = rp.role_id
+			JOIN roles r ON r.id = rp.role_id
+			WHERE ur.user_id = $1 AND r.is_deleted = FALSE
+		)
+		ORDER BY p.name`, user.Id)
 	if err != nil {
 		return err
 	}
@@ -233,11 +197,11 @@ func (repo *UserRepository) FindById(ctx context.Context, userId string) (User,
 	var updatedAt sql.NullTime
 
 	err := repo.db.QueryRowContext(ctx, `
-		SELECT id, email, first_name, last_name, password, status, created_at, updated_at, is_deleted, tokens_valid_after
+		SELECT id, email, first_name, last_name, password, status, created_at, updated_at, is_deleted, tokens_valid_after, email_verified_at, deletion_scheduled_at
 		FROM users
 		WHERE id = $1 AND is_deleted = FALSE`, userId).Scan(
 		&user.Id, &user.Email, &firstName, &lastName, &user.Password,
-		&user.Status, &user.CreatedAt, &updatedAt, &user.IsDeleted, &user.TokensValidAfter,
+		&user.Status, &user.CreatedAt, &updatedAt, &user.IsDeleted, &user.TokensValidAfter, &user.EmailVerifiedAt, &user.DeletionScheduledAt,
 	)
 	if err != nil {
 		return User{}, err
@@ -285,3 +249,410 @@ func (repo *UserRepository) TokensValidAfter(ctx context.Context, userId string)
 
 	return validAfter, err
 }
+
+// MarkEmailVerified records that the user owns their address and activates the
+// account. Only an account that was never verified is touched, so an old link
+// cannot reactivate an account that was deactivated after verification.
+func (repo *UserRepository) MarkEmailVerified(ctx context.Context, userId uuid.UUID) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, `
+		UPDATE users
+		SET email_verified_at = NOW(), status = $1, updated_at = NOW()
+		WHERE id = $2 AND email_verified_at IS NULL AND is_deleted = FALSE`, Active, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+// DeleteUnverifiedBefore removes accounts that were registered before cutoff
+// and never verified. They are deleted outright rather than flagged: nobody
+// ever proved they own the address, and keeping the row would stop its real
+// owner from registering it.
+func (repo *UserRepository) DeleteUnverifiedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Anonymised accounts also end up unverified and Inactive, but their rows
+	// are kept on purpose, so they are left out.
+	stale := `SELECT id FROM users WHERE email_verified_at IS NULL AND status = 'Inactive' AND is_deleted = FALSE AND created_at < $1`
+
+	for _, table := range []string{"email_verification_tokens", "email_change_tokens", "password_reset_tokens", "account_deletion_tokens", "account_unlock_tokens", "users_permissions", "users_roles"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id IN (`+stale+`)`, cutoff.UTC()); err != nil {
+			return 0, fmt.Errorf("could not clear %s: %w", table, err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id IN (`+stale+`)`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// UpdateName sets the optional first and last name.
+func (repo *UserRepository) UpdateName(ctx context.Context, userId string, firstName, lastName sql.NullString) error {
+	query := `UPDATE users SET first_name = $1, last_name = $2, updated_at = NOW() WHERE id = $3 AND is_deleted = FALSE`
+	_, err := repo.db.ExecContext(ctx, query, firstName, lastName, userId)
+
+	return err
+}
+
+// UpdateEmail moves the account to a new address. The new address has just
+// been confirmed through a link sent to it, so it counts as verified.
+func (repo *UserRepository) UpdateEmail(ctx context.Context, userId uuid.UUID, email string) error {
+	query := `
+		UPDATE users
+		SET email = $1, email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND is_deleted = FALSE`
+	_, err := repo.db.ExecContext(ctx, query, email, userId)
+
+	return err
+}
+
+// ScheduleDeletion marks the account for anonymisation at the given instant.
+func (repo *UserRepository) ScheduleDeletion(ctx context.Context, userId uuid.UUID, at time.Time) error {
+	query := `UPDATE users SET deletion_scheduled_at = $1, updated_at = NOW() WHERE id = $2 AND is_deleted = FALSE`
+	_, err := repo.db.ExecContext(ctx, query, at.UTC(), userId)
+
+	return err
+}
+
+// CancelDeletion withdraws a scheduled deletion. It reports false when none
+// was pending.
+func (repo *UserRepository) CancelDeletion(ctx context.Context, userId string) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, `
+		UPDATE users SET deletion_scheduled_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deletion_scheduled_at IS NOT NULL AND is_deleted = FALSE`, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+// FindDueForDeletion lists accounts whose grace period ended before now.
+func (repo *UserRepository) FindDueForDeletion(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id FROM users
+		WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= $1 AND is_deleted = FALSE`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Anonymise carries out a deletion. The row is kept, since posts reference it,
+// but everything that identifies the person is dropped: the address is
+// replaced with one that cannot receive mail, the password with a value no hash
+// can match, and the name is cleared. Comments are emptied and marked deleted
+// rather than removed, so the replies of others keep their place in the
+// thread. Every session is revoked and authored posts move to the longest
+// serving remaining administrator.
+//
+// An account whose deletion was cancelled or moved later in the meantime is
+// left alone and reported with ErrDeletionNotDue, and the last active
+// administrator with ErrLastAdmin.
+func (repo *UserRepository) Anonymise(ctx context.Context, userId uuid.UUID) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Locks the row so a concurrent cancel cannot interleave with the wipe.
+	var scheduled sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT deletion_scheduled_at FROM users WHERE id = $1 AND is_deleted = FALSE FOR UPDATE`, userId).Scan(&scheduled)
+	if err != nil {
+		return err
+	}
+
+	if !scheduled.Valid || scheduled.Time.After(time.Now()) {
+		return ErrDeletionNotDue
+	}
+
+	if err := ensureNotLastAdmin(ctx, tx, userId); err != nil {
+		return err
+	}
+
+	var successor uuid.NullUUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT u.id FROM users u
+		JOIN users_roles ur ON u.id = ur.user_id
+		JOIN roles r ON r.id = ur.role_id
+		WHERE r.name = 'ADMIN' AND r.is_deleted = FALSE AND u.is_deleted = FALSE
+			AND u.deletion_scheduled_at IS NULL AND u.id <> $1
+		ORDER BY u.created_at
+		LIMIT 1`, userId).Scan(&successor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if successor.Valid {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE posts SET creator_user_id = $1, updated_at = NOW()
+			WHERE creator_user_id = $2`, successor.UUID, userId); err != nil {
+			return fmt.Errorf("could not reassign posts: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE comments
+		SET body = '', status = 'deleted', moderated_at = now() at time zone 'utc', moderated_by = NULL
+		WHERE user_id = $1`, userId); err != nil {
+		return fmt.Errorf("could not clear comments: %w", err)
+	}
+
+	for _, table := range []string{"email_verification_tokens", "email_change_tokens", "password_reset_tokens", "account_deletion_tokens", "account_unlock_tokens", "users_permissions", "users_roles"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userId); err != nil {
+			return fmt.Errorf("could not clear %s: %w", table, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET email = 'deleted-' || id || '@deleted.invalid', password = '',
+			first_name = NULL, last_name = NULL, status = $1,
+			email_verified_at = NULL, deletion_scheduled_at = NULL,
+			tokens_valid_after = NOW(), is_deleted = TRUE, updated_at = NOW()
+		WHERE id = $2`, Inactive, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// likeEscaper escapes the LIKE wildcards so a search term matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FindPage returns one page of accounts, newest first, with their roles, and
+// the total number of matches. An empty query matches every account; otherwise
+// the email has to contain it, ignoring case.
+func (repo *UserRepository) FindPage(ctx context.Context, emailQuery string, limit, offset int) ([]User, int, error) {
+	pattern := "%" + likeEscaper.Replace(emailQuery) + "%"
+
+	var total int
+	err := repo.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE is_deleted = FALSE AND email ILIKE $1`, pattern).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, email, first_name, last_name, status, created_at, updated_at, tokens_valid_after, email_verified_at, deletion_scheduled_at
+		FROM users
+		WHERE is_deleted = FALSE AND email ILIKE $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3`, pattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(
+			&user.Id, &user.Email, &user.FirstName, &user.LastName, &user.Status, &user.CreatedAt,
+			&user.UpdatedAt, &user.TokensValidAfter, &user.EmailVerifiedAt, &user.DeletionScheduledAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range users {
+		if err := repo.loadRolesAndPermissions(ctx, &users[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return users, total, nil
+}
+
+// ListRoles returns every role that can be granted.
+func (repo *UserRepository) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, name, created_at, updated_at, updated_by, is_deleted
+		FROM roles
+		WHERE is_deleted = FALSE
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.Id, &role.Name, &role.CreatedAt, &role.UpdatedAt, &role.UpdatedBy, &role.IsDeleted); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// UpdateStatus changes the account status. Any status other than Active also
+// revokes the sessions that are open, in the same statement, so a suspension
+// cannot be outlived by an access token. Taking the last active administrator
+// out of service is refused with ErrLastAdmin.
+func (repo *UserRepository) UpdateStatus(ctx context.Context, userId uuid.UUID, status UserStatus) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if status != Active {
+		if err := ensureNotLastAdmin(ctx, tx, userId); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET status = $1,
+			tokens_valid_after = CASE WHEN $1 = $2 THEN tokens_valid_after ELSE NOW() END,
+			updated_at = NOW()
+		WHERE id = $3 AND is_deleted = FALSE`, status, Active, userId)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// GrantRole gives the user the named role. Granting a role the user already
+// holds is not an error; a role that does not exist is reported as
+// sql.ErrNoRows.
+func (repo *UserRepository) GrantRole(ctx context.Context, userId uuid.UUID, roleName string) error {
+	var roleId uuid.UUID
+	err := repo.db.QueryRowContext(ctx,
+		`SELECT id FROM roles WHERE name = $1 AND is_deleted = FALSE ORDER BY created_at LIMIT 1`, roleName).Scan(&roleId)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.db.ExecContext(ctx, `
+		INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, role_id) DO NOTHING`, userId, roleId)
+
+	return err
+}
+
+// RevokeRole takes the named role away from the user. Taking ADMIN from the
+// last active administrator is refused with ErrLastAdmin.
+func (repo *UserRepository) RevokeRole(ctx context.Context, userId uuid.UUID, roleName string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if roleName == RoleAdmin {
+		if err := ensureNotLastAdmin(ctx, tx, userId); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM users_roles
+		WHERE user_id = $1 AND role_id IN (SELECT id FROM roles WHERE name = $2)`, userId, roleName)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EnsureNotLastAdmin returns ErrLastAdmin when userId is the only active
+// administrator, for changes that are refused up front rather than in the
+// transaction that makes them.
+func (repo *UserRepository) EnsureNotLastAdmin(ctx context.Context, userId uuid.UUID) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	return ensureNotLastAdmin(ctx, tx, userId)
+}
+
+// ensureNotLastAdmin returns ErrLastAdmin when userId is the only active
+// administrator. The administrator rows stay locked until the transaction
+// ends, so two admins demoting each other at the same moment cannot both
+// succeed.
+func ensureNotLastAdmin(ctx context.Context, tx *sql.Tx, userId uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT u.id FROM users u
+		JOIN users_roles ur ON u.id = ur.user_id
+		JOIN roles r ON r.id = ur.role_id
+		WHERE r.name = $1 AND r.is_deleted = FALSE AND u.is_deleted = FALSE AND u.status = $2
+		FOR UPDATE OF u`, RoleAdmin, Active)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	isAdmin, others := false, 0
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		if id == userId {
+			isAdmin = true
+		} else {
+			others++
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if isAdmin && others == 0 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
@@ -18,3 +18,9 @@ func ValidateUserStatus(status UserStatus) error {
 		return errors.New("Invalid user status!")
 	}
 }
+
+// AllowsSignIn reports whether an account in this status may start a session.
+// Suspended and Inactive accounts are turned away even with the right password.
+func (status UserStatus) AllowsSignIn() bool {
+	return status != Suspended && status != Inactive
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"server/internal/application/account"
+	"server/internal/config"
+	"server/internal/domain/user"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/ctxutils"
+	"server/util/httputils"
+	"server/util/securityutil"
+	"server/web/templates"
+	"time"
+)
+
+type AccountHandler struct {
+	accountService *account.AccountService
+	privacyService *account.PrivacyService
+}
+
+func NewAccountHandler(accountService *account.AccountService, privacyService *account.PrivacyService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		privacyService: privacyService,
+	}
+}
+
+func (handler *AccountHandler) GetAccount(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		http.Redirect(writer, req, "/", http.StatusSeeOther)
+		return
+	}
+
+	current, err := handler.accountService.GetAccount(ctx, loggedUser.Id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load the account", "error", err, "userId", loggedUser.Id)
+		http.Redirect(writer, req, "/error", http.StatusSeeOther)
+		return
+	}
+
+	util.Must(templates.Layout(
+		templates.Account(models.AccountFromDomain(current)),
+		"Профил",
+		"Управлявайте името, паролата и имейл адреса на профила си.",
+		"/account",
+		ctxutils.GetCSRF(ctx),
+		config.AllowRegistration(),
+	).Render(ctx, writer))
+}
+
+func (handler *AccountHandler) HandleUpdateProfile(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		writer.Header().Add("HX-Redirect", "/")
+		return
+	}
+
+	input := new(models.UpdateProfileResource)
+	result := httputils.ProcessBody(writer, req, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if result.ValidationErrors != nil {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Името може да е най-много 50 символа", "error-profile").Render(ctx, writer))
+		return
+	}
+
+	if err := handler.accountService.UpdateProfile(ctx, loggedUser.Id, input.FirstName, input.LastName); err != nil {
+		slog.ErrorContext(ctx, "Failed to update the profile", "error", err, "userId", loggedUser.Id)
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	util.Must(templates.AccountSaved("Профилът е обновен.").Render(ctx, writer))
+}
+
+// HandleChangePassword ends every other session on success. The one making the
+// change is kept by issuing it fresh cookies, so nobody is logged out for
+// having changed their own password.
+func (handler *AccountHandler) HandleChangePassword(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		writer.Header().Add("HX-Redirect", "/")
+		return
+	}
+
+	input := new(models.ChangePasswordResource)
+	result := httputils.ProcessBody(writer, req, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if input.Password != input.RepeatPassword {
+		result.ValidationErrors = append(result.ValidationErrors, &httputils.ValidationError{
+			Value: "",
+			Field: "repeatPassword",
+			Error: "Паролите не съвпадат",
+		})
+	}
+
+	if result.ValidationErrors != nil {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.FormErrors(result.ValidationErrors).Render(ctx, writer))
+		return
+	}
+
+	changed, err := handler.accountService.ChangePassword(ctx, loggedUser.Id, input.CurrentPassword, input.Password)
+	if errors.Is(err, account.ErrWrongPassword) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Грешна текуща парола", "error-current-password").Render(ctx, writer))
+		return
+	}
+	if errors.Is(err, account.ErrPasswordWeak) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Паролата трябва да е поне 12 символа и да съдържа главна и малка буква, цифра и символ", "error-password").Render(ctx, writer))
+		return
+	}
+	if errors.Is(err, securityutil.ErrPasswordBreached) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.BreachedPassword().Render(ctx, writer))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to change the password", "error", err, "userId", loggedUser.Id)
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	accessToken, accessExpiry := securityutil.GenerateAccessToken(changed, false)
+	refreshToken, refreshExpiry := securityutil.GenerateRefreshToken(changed, false)
+	httputils.SetAuthCookie(httputils.AuthCookieName, accessToken, accessExpiry, false, writer)
+	httputils.SetRefreshCookie(refreshToken, refreshExpiry, writer)
+
+	util.Must(templates.AccountSaved("Паролата е сменена. Всички други сесии са прекратени.").Render(ctx, writer))
+}
+
+func (handler *AccountHandler) HandleChangeEmail(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		writer.Header().Add("HX-Redirect", "/")
+		return
+	}
+
+	input := new(models.ChangeEmailResource)
+	result := httputils.ProcessBody(writer, req, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	// FormErrors would target the new password field of the password form,
+	// which shares the page, so these are mapped onto this form's own fields.
+	if result.ValidationErrors != nil {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		for _, validationErr := range result.ValidationErrors {
+			if validationErr.Field == "password" {
+				util.Must(templates.InvalidMessage("Въведете паролата си", "error-email-password").Render(ctx, writer))
+				continue
+			}
+			util.Must(templates.InvalidMessage("Невалиден имейл", "error-new-email").Render(ctx, writer))
+		}
+		return
+	}
+
+	err = handler.accountService.RequestEmailChange(ctx, loggedUser.Id, input.Password, input.Email)
+	if errors.Is(err, account.ErrWrongPassword) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Грешна парола", "error-email-password").Render(ctx, writer))
+		return
+	}
+	if errors.Is(err, account.ErrSameEmail) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Това е текущият ви имейл адрес", "error-new-email").Render(ctx, writer))
+		return
+	}
+	if errors.Is(err, account.ErrEmailTaken) {
+		writer.WriteHeader(http.StatusConflict)
+		util.Must(templates.InvalidMessage("Имейл адресът вече е регистриран", "error-new-email").Render(ctx, writer))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to request an email change", "error", err, "userId", loggedUser.Id)
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	util.Must(templates.AccountSaved("Изпратихме линк за потвърждение на новия адрес. Имейлът ще бъде сменен, след като го отворите.").Render(ctx, writer))
+}
+
+// GetConfirmEmailChange consumes the link mailed to the new address. It needs
+// no session: following the link from the new inbox is the proof asked for.
+func (handler *AccountHandler) GetConfirmEmailChange(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(writer, req, "/account", http.StatusSeeOther)
+		return
+	}
+
+	err := handler.accountService.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		if !errors.Is(err, account.ErrInvalidToken) && !errors.Is(err, account.ErrEmailTaken) {
+			slog.ErrorContext(ctx, "Failed to confirm an email change", "error", err)
+		}
+
+		util.Must(templates.SimpleLayout(
+			templates.EmailChangeInvalid(),
+			"Невалиден линк",
+			"Линкът за смяна на имейл е невалиден или изтекъл.",
+			ctxutils.GetCSRF(ctx),
+		).Render(ctx, writer))
+		return
+	}
+
+	util.Must(templates.SimpleLayout(
+		templates.EmailChangeConfirmed(),
+		"Имейлът е сменен",
+		"Новият имейл адрес е потвърден.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, writer))
+}
+
+// GetExport downloads everything held about the signed in user as JSON.
+func (handler *AccountHandler) GetExport(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		http.Redirect(writer, req, "/", http.StatusSeeOther)
+		return
+	}
+
+	consent := ""
+	if cookie, err := req.Cookie("consent"); err == nil {
+		consent = cookie.Value
+	}
+
+	export, err := handler.privacyService.Export(ctx, loggedUser.Id, consent)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to export personal data", "error", err, "userId", loggedUser.Id)
+		http.Redirect(writer, req, "/error", http.StatusSeeOther)
+		return
+	}
+
+	filename := fmt.Sprintf("dviji-se-data-%s.json", time.Now().UTC().Format("2006-01-02"))
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writer.Header().Set("Cache-Control", "no-store")
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		slog.ErrorContext(ctx, "Failed to write the personal data export", "error", err)
+	}
+}
+
+func (handler *AccountHandler) HandleRequestDeletion(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		writer.Header().Add("HX-Redirect", "/")
+		return
+	}
+
+	input := new(models.DeleteAccountResource)
+	result := httputils.ProcessBody(writer, req, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if result.ValidationErrors != nil {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Въведете паролата си", "error-delete-password").Render(ctx, writer))
+		return
+	}
+
+	err = handler.privacyService.RequestDeletion(ctx, loggedUser.Id, input.Password)
+	if errors.Is(err, account.ErrWrongPassword) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Грешна парола", "error-delete-password").Render(ctx, writer))
+		return
+	}
+	if errors.Is(err, user.ErrLastAdmin) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Вие сте единственият администратор. Назначете друг администратор, преди да изтриете акаунта си.", "error-delete-password").Render(ctx, writer))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to request account deletion", "error", err, "userId", loggedUser.Id)
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	util.Must(templates.AccountSaved("Изпратихме ви линк за потвърждение на изтриването.").Render(ctx, writer))
+}
+
+// GetConfirmDeletion consumes the link from the deletion email. Like the other
+// emailed links it needs no session.
+func (handler *AccountHandler) GetConfirmDeletion(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(writer, req, "/account", http.StatusSeeOther)
+		return
+	}
+
+	at, err := handler.privacyService.ConfirmDeletion(ctx, token)
+	if err != nil {
+		if !errors.Is(err, account.ErrInvalidToken) {
+			slog.ErrorContext(ctx, "Failed to confirm account deletion", "error", err)
+		}
+
+		util.Must(templates.SimpleLayout(
+			templates.DeletionInvalid(),
+			"Невалиден линк",
+			"Линкът за изтриване на акаунт е невалиден или изтекъл.",
+			ctxutils.GetCSRF(ctx),
+		).Render(ctx, writer))
+		return
+	}
+
+	util.Must(templates.SimpleLayout(
+		templates.DeletionScheduled(at.Format("02.01.2006")),
+		"Изтриването е потвърдено",
+		"Акаунтът ще бъде изтрит след гратисния период.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, writer))
+}
+
+func (handler *AccountHandler) HandleCancelDeletion(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		writer.Header().Add("HX-Redirect", "/")
+		return
+	}
+
+	err = handler.privacyService.CancelDeletion(ctx, loggedUser.Id)
+	if err != nil && !errors.Is(err, account.ErrDeletionNotPending) {
+		slog.ErrorContext(ctx, "Failed to cancel account deletion", "error", err, "userId", loggedUser.Id)
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	writer.Header().Set("HX-Redirect", "/account")
+	writer.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appAudit "server/internal/application/audit"
+	"server/internal/domain/audit"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/httputils"
+	"server/web/templates/admin"
+)
+
+type AdminAuditHandler struct {
+	auditService *appAudit.AuditService
+}
+
+func NewAdminAuditHandler(auditService *appAudit.AuditService) *AdminAuditHandler {
+	return &AdminAuditHandler{
+		auditService: auditService,
+	}
+}
+
+func (h *AdminAuditHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	query := r.URL.Query()
+	if p := query.Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	filters := admin.AuditFilters{
+		Action:  query.Get("action"),
+		Outcome: query.Get("outcome"),
+		Query:   strings.TrimSpace(query.Get("q")),
+		From:    query.Get("from"),
+		To:      query.Get("to"),
+	}
+
+	result, err := h.auditService.Search(ctx, auditFilter(filters), page, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching audit events", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.AuditList(models.AuditEventsFromDomain(result.Events), audit.Actions, filters, page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+// auditFilter turns the form into a filter. Values that do not parse are
+// ignored rather than refused, as with the page number. The dates are whole
+// days, so "to" includes the day it names.
+func auditFilter(filters admin.AuditFilters) audit.Filter {
+	filter := audit.Filter{Query: filters.Query}
+
+	for _, action := range audit.Actions {
+		if string(action) == filters.Action {
+			filter.Action = action
+		}
+	}
+
+	if outcome := audit.Outcome(filters.Outcome); outcome == audit.Success || outcome == audit.Failure {
+		filter.Outcome = outcome
+	}
+
+	if from, err := time.Parse(time.DateOnly, filters.From); err == nil {
+		filter.From = from
+	}
+	if to, err := time.Parse(time.DateOnly, filters.To); err == nil {
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	return filter
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appAudit "server/internal/application/audit"
+	appComments "server/internal/application/comments"
+	"server/internal/domain/audit"
+	"server/internal/domain/comments"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/ctxutils"
+	"server/util/httputils"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+// moderationActions maps the buttons of the queue to the status they set.
+// Rejected comments are kept as deleted rather than removed.
+var moderationActions = map[string]comments.Status{
+	"approve": comments.StatusApproved,
+	"reject":  comments.StatusDeleted,
+	"spam":    comments.StatusSpam,
+}
+
+// maxModeratedAtOnce is a full page of the queue and then some.
+const maxModeratedAtOnce = 100
+
+type AdminCommentHandler struct {
+	commentService *appComments.CommentService
+	auditService   *appAudit.AuditService
+}
+
+func NewAdminCommentHandler(commentService *appComments.CommentService, auditService *appAudit.AuditService) *AdminCommentHandler {
+	return &AdminCommentHandler{
+		commentService: commentService,
+		auditService:   auditService,
+	}
+}
+
+func (h *AdminCommentHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	status := comments.Status(r.URL.Query().Get("status"))
+	if status == "" {
+		status = comments.StatusPending
+	}
+
+	result, err := h.commentService.Queue(ctx, status, page, pageSize)
+	if errors.Is(err, appComments.ErrInvalidStatus) {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid status")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching the comment queue", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.CommentQueue(models.CommentQueueFromDomain(result.Items), status, page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+// Moderate applies one decision to every comment ticked in the queue. The
+// form is sent url-encoded: a single ticked box would otherwise arrive as a
+// string rather than a list.
+func (h *AdminCommentHandler) Moderate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	moderator, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	moderatorId, err := uuid.Parse(moderator.Id)
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid form")
+		return
+	}
+
+	status, ok := moderationActions[r.PostForm.Get("action")]
+	if !ok {
+		httputils.SendBadRequestResponse(ctx, w, "comment.action.invalid")
+		return
+	}
+
+	values := r.PostForm["ids"]
+	if len(values) == 0 || len(values) > maxModeratedAtOnce {
+		httputils.SendBadRequestResponse(ctx, w, "comment.selection.invalid")
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			httputils.SendBadRequestResponse(ctx, w, "comment.selection.invalid")
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := h.commentService.Moderate(ctx, moderatorId, ids, status); err != nil {
+		slog.ErrorContext(ctx, "Error moderating comments", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	for _, id := range ids {
+		h.auditService.Record(ctx, audit.Event{
+			Action:     audit.ActionCommentModerate,
+			Detail:     string(status),
+			TargetType: audit.TargetComment,
+			TargetId:   id.String(),
+		})
+	}
+
+	w.Header().Set("HX-Refresh", "true")
+	httputils.SendSuccessResponse(ctx, w, "Comments moderated", nil, http.StatusOK)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appAudit "server/internal/application/audit"
+	appOutbox "server/internal/application/outbox"
+	"server/internal/domain/audit"
+	"server/internal/http/handlers/models"
+	"server/internal/infrastructure/email"
+	"server/util"
+	"server/util/httputils"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+type AdminEmailHandler struct {
+	outboxService *appOutbox.OutboxService
+	auditService  *appAudit.AuditService
+}
+
+func NewAdminEmailHandler(outboxService *appOutbox.OutboxService, auditService *appAudit.AuditService) *AdminEmailHandler {
+	return &AdminEmailHandler{
+		outboxService: outboxService,
+		auditService:  auditService,
+	}
+}
+
+// GetFailures lists the emails that could not be delivered yet.
+func (h *AdminEmailHandler) GetFailures(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	result, err := h.outboxService.Failures(ctx, page, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching failed emails", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.EmailFailures(models.OutboxMessagesFromDomain(result.Messages), page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+// Retry queues a dead email again.
+func (h *AdminEmailHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid email ID")
+		return
+	}
+
+	err = h.outboxService.Retry(ctx, id)
+	if errors.Is(err, appOutbox.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Email not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error retrying an email", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	h.auditService.Record(ctx, audit.Event{
+		Action:     audit.ActionEmailRetry,
+		TargetType: audit.TargetEmail,
+		TargetId:   id.String(),
+	})
+
+	w.Header().Set("HX-Refresh", "true")
+	httputils.SendSuccessResponse(ctx, w, "Email queued", nil, http.StatusOK)
+}
+
+// GetPreviews lists the email templates that can be previewed.
+func (h *AdminEmailHandler) GetPreviews(w http.ResponseWriter, r *http.Request) {
+	util.Must(admin.EmailPreviews(email.PreviewNames()).Render(r.Context(), w))
+}
+
+// GetPreview renders one email template with sample data exactly as it is
+// sent, or its plain text alternative with ?format=text.
+func (h *AdminEmailHandler) GetPreview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	preview, err := email.RenderPreview(ctx, r.PathValue("name"))
+	if errors.Is(err, email.ErrUnknownPreview) {
+		httputils.SendNotFoundResponse(ctx, w, "Email template not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error rendering an email preview", "error", err, "name", r.PathValue("name"))
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("Subject: " + preview.Subject + "\n\n" + preview.Text))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(preview.HTML))
+}
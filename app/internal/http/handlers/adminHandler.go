@@ -2,18 +2,25 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	appAudit "server/internal/application/audit"
 	"server/internal/application/categories"
+	"server/internal/application/media"
 	appPosts "server/internal/application/posts"
+	"server/internal/domain/audit"
 	"server/internal/domain/posts"
 	"server/internal/http/handlers/models"
-	"server/internal/infrastructure/cloudinary"
+	"server/internal/infrastructure/imaging"
+	"server/internal/infrastructure/storage"
 	"server/util"
 	"server/util/ctxutils"
 	"server/util/httputils"
@@ -23,20 +30,29 @@ import (
 )
 
 type AdminHandler struct {
-	postService       *appPosts.PostService
-	categoryService   *categories.CategoryService
-	cloudinaryService *cloudinary.CloudinaryService
+	postService     *appPosts.PostService
+	categoryService *categories.CategoryService
+	storage         storage.Storage
+	imageService    *media.ImageService
+	libraryService  *media.LibraryService
+	auditService    *appAudit.AuditService
 }
 
 func NewAdminHandler(
 	postService *appPosts.PostService,
 	categoryService *categories.CategoryService,
-	cloudinaryService *cloudinary.CloudinaryService,
+	store storage.Storage,
+	imageService *media.ImageService,
+	libraryService *media.LibraryService,
+	auditService *appAudit.AuditService,
 ) *AdminHandler {
 	return &AdminHandler{
-		postService:       postService,
-		categoryService:   categoryService,
-		cloudinaryService: cloudinaryService,
+		postService:     postService,
+		categoryService: categoryService,
+		storage:         store,
+		imageService:    imageService,
+		libraryService:  libraryService,
+		auditService:    auditService,
 	}
 }
 
@@ -133,6 +149,12 @@ func (h *AdminHandler) GetPostForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, err := actorFrom(r.Context())
+	if err != nil || !actor.CanEdit(post) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	util.Must(admin.PostForm(post, categoryResources).Render(r.Context(), w))
 }
 
@@ -145,18 +167,12 @@ func (h *AdminHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := ctxutils.GetUser(r.Context())
+	actor, err := actorFrom(r.Context())
 	if err != nil {
 		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	creatorId, err := uuid.Parse(user.Id)
-	if err != nil {
-		httputils.SendBadRequestResponse(ctx, w, "Invalid user ID")
-		return
-	}
-
 	categoryId, err := uuid.Parse(input.CategoryId)
 	if err != nil {
 		httputils.SendBadRequestResponse(ctx, w, "Invalid category ID")
@@ -168,6 +184,12 @@ func (h *AdminHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		status = posts.PostStatus(input.Status)
 	}
 
+	if err := appPosts.AuthorizeCreate(actor, status); err != nil {
+		h.auditService.Record(ctx, audit.Event{Action: audit.ActionPostCreate, Outcome: audit.Failure, Detail: "post.publish.forbidden"})
+		httputils.SendErrorResponse(ctx, w, "post.publish.forbidden", http.StatusForbidden)
+		return
+	}
+
 	createInput := appPosts.CreatePostInput{
 		Title:           input.Title,
 		Content:         input.Content,
@@ -179,7 +201,7 @@ func (h *AdminHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		Metadata:        input.Metadata,
 	}
 
-	post, err := h.postService.Create(ctx, createInput, creatorId)
+	post, err := h.postService.Create(ctx, createInput, actor.Id)
 	if err != nil {
 		slog.ErrorContext(ctx, "Error creating post", "error", err)
 		httputils.SendInternalServerResponse(w, r)
@@ -187,6 +209,8 @@ func (h *AdminHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.InfoContext(ctx, fmt.Sprintf("Successfully created post [id=%s]", post.Id.String()))
+	h.trackImages(ctx, post.Id)
+	h.auditService.Record(ctx, postEvent(audit.ActionPostCreate, post.Id, string(post.Status)))
 	httputils.SendSuccessResponse(ctx, w, "Post created successfully", map[string]string{"id": post.Id.String()}, http.StatusCreated)
 }
 
@@ -218,6 +242,30 @@ func (h *AdminHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, err := actorFrom(r.Context())
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.postService.AuthorizeUpdate(ctx, actor, id, posts.PostStatus(input.Status)); err != nil {
+		if errors.Is(err, appPosts.ErrForbidden) {
+			event := postEvent(audit.ActionPostUpdate, id, "post.edit.forbidden")
+			event.Outcome = audit.Failure
+			h.auditService.Record(ctx, event)
+			httputils.SendErrorResponse(ctx, w, "post.edit.forbidden", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			httputils.SendNotFoundResponse(ctx, w, "Post not found")
+			return
+		}
+
+		slog.ErrorContext(ctx, "Error checking post permissions", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
 	updateInput := appPosts.UpdatePostInput{
 		Title:           input.Title,
 		Content:         input.Content,
@@ -237,6 +285,8 @@ func (h *AdminHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.InfoContext(ctx, fmt.Sprintf("Successfully updated post [id=%s]", post.Id.String()))
+	h.trackImages(ctx, post.Id)
+	h.auditService.Record(ctx, postEvent(audit.ActionPostUpdate, post.Id, string(post.Status)))
 	httputils.SendSuccessResponse(ctx, w, "Post updated successfully", map[string]string{"id": post.Id.String()}, http.StatusOK)
 }
 
@@ -265,6 +315,8 @@ func (h *AdminHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.InfoContext(ctx, fmt.Sprintf("Successfully deleted post [id=%s]", id.String()))
+	h.trackImages(ctx, id)
+	h.auditService.Record(ctx, postEvent(audit.ActionPostDelete, id, ""))
 	httputils.SendSuccessResponse(ctx, w, "Post deleted successfully", nil, http.StatusOK)
 }
 
@@ -286,16 +338,30 @@ func (h *AdminHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.cloudinaryService == nil {
+	if h.imageService == nil {
 		httputils.SendErrorResponse(ctx, w, "Качването на изображения не е настроено", http.StatusServiceUnavailable)
 		return
 	}
 
-	result, err := h.cloudinaryService.Upload(ctx, file, header.Filename)
-	if err != nil {
-		// Cloudinary has its own size ceiling - 10 MB on the free plan - so a
-		// file this server accepted can still be refused there. Saying so
-		// beats a blank 500 that looks like the site broke.
+	// The library records who uploaded the image; without a parseable id it
+	// is simply recorded without one.
+	uploader, _ := actorFrom(r.Context())
+
+	started := time.Now()
+	result, err := h.imageService.Upload(ctx, file, header.Filename, uploader.Id)
+	observeUpload("image", header.Size, started, err)
+	switch {
+	case errors.Is(err, imaging.ErrUnreadable):
+		httputils.SendBadRequestResponse(ctx, w, "Изображението не може да бъде прочетено. Запази го отново като JPEG или PNG.")
+		return
+	case errors.Is(err, imaging.ErrTooLarge):
+		httputils.SendBadRequestResponse(ctx, w, "Изображението е с твърде голяма резолюция. Намали го под 50 мегапиксела.")
+		return
+	case err != nil:
+		// Cloudinary has its own size ceiling - 10 MB on the free plan - and a
+		// bucket or disk can be full, so a file this server accepted can still
+		// be refused there. Saying so beats a blank 500 that looks like the
+		// site broke.
 		slog.ErrorContext(ctx, "Error uploading image", "error", err, "filename", header.Filename, "size", header.Size)
 		httputils.SendErrorResponse(ctx, w, "Хранилището за изображения отказа файла. Пробвай с по-малък файл.", http.StatusBadGateway)
 		return
@@ -322,12 +388,14 @@ func (h *AdminHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.cloudinaryService == nil {
+	if h.storage == nil {
 		httputils.SendErrorResponse(ctx, w, "Качването на файлове не е настроено", http.StatusServiceUnavailable)
 		return
 	}
 
-	result, err := h.cloudinaryService.UploadRaw(ctx, file, header.Filename)
+	started := time.Now()
+	result, err := h.storage.UploadRaw(ctx, file, header.Filename)
+	observeUpload("file", header.Size, started, err)
 	if err != nil {
 		slog.ErrorContext(ctx, "Error uploading file", "error", err, "filename", header.Filename, "size", header.Size)
 		httputils.SendErrorResponse(ctx, w, "Хранилището отказа файла. Пробвай с по-малък файл.", http.StatusBadGateway)
@@ -338,3 +406,38 @@ func (h *AdminHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		"location": result.URL,
 	}, http.StatusOK)
 }
+
+// trackImages records where a post just saved or deleted uses images, so the
+// media library shows it straight away. The sweep scans every post again
+// before it deletes anything, so a failure here is only logged.
+func (h *AdminHandler) trackImages(ctx context.Context, postId uuid.UUID) {
+	if err := h.libraryService.TrackPost(ctx, postId); err != nil {
+		slog.WarnContext(ctx, "Could not track the images of a post", "error", err, "id", postId)
+	}
+}
+
+// postEvent describes an action on a post. The detail is the status the post
+// was left in, or why the action was refused.
+func postEvent(action audit.Action, postId uuid.UUID, detail string) audit.Event {
+	return audit.Event{
+		Action:     action,
+		TargetType: audit.TargetPost,
+		TargetId:   postId.String(),
+		Detail:     detail,
+	}
+}
+
+// actorFrom describes the signed in user to the post rules.
+func actorFrom(ctx context.Context) (appPosts.Actor, error) {
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		return appPosts.Actor{}, err
+	}
+
+	id, err := uuid.Parse(loggedUser.Id)
+	if err != nil {
+		return appPosts.Actor{}, err
+	}
+
+	return appPosts.Actor{Id: id, Permissions: loggedUser.Permissions}, nil
+}
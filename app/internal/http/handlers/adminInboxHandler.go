@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	appAudit "server/internal/application/audit"
+	appContact "server/internal/application/contact"
+	"server/internal/domain/audit"
+	"server/internal/domain/contact"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/ctxutils"
+	"server/util/httputils"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+type AdminInboxHandler struct {
+	contactService *appContact.ContactService
+	auditService   *appAudit.AuditService
+}
+
+func NewAdminInboxHandler(contactService *appContact.ContactService, auditService *appAudit.AuditService) *AdminInboxHandler {
+	return &AdminInboxHandler{
+		contactService: contactService,
+		auditService:   auditService,
+	}
+}
+
+func (h *AdminInboxHandler) GetInbox(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	query := r.URL.Query()
+	if p := query.Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	status := contact.Status(query.Get("status"))
+	if status == "" {
+		status = contact.StatusOpen
+	}
+	category := contact.Category(query.Get("category"))
+
+	result, err := h.contactService.Inbox(ctx, status, category, page, pageSize)
+	if errors.Is(err, appContact.ErrInvalidStatus) || errors.Is(err, appContact.ErrInvalidCategory) {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid filter")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching the inbox", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	summary, err := h.contactService.Summary(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting the inbox", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+	items := models.ContactMessagesFromDomain(result.Messages, time.Now())
+
+	util.Must(admin.Inbox(items, admin.InboxFilters{Status: status, Category: category}, summary, page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+// GetMessage shows a message with the replies sent so far. Opening it marks
+// it read.
+func (h *AdminInboxHandler) GetMessage(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendNotFoundResponse(ctx, w, "Message not found")
+		return
+	}
+
+	message, replies, err := h.contactService.Open(ctx, id)
+	if errors.Is(err, appContact.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Message not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching a contact message", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	util.Must(admin.InboxMessage(models.ContactMessageFromDomain(*message, time.Now()), models.ContactRepliesFromDomain(replies)).Render(r.Context(), w))
+}
+
+// Reply emails the answer to the sender and keeps it under the message.
+func (h *AdminInboxHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, ok := h.messageId(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	author, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	authorId, err := uuid.Parse(author.Id)
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	input := new(models.ContactReplyResource)
+	if !httputils.ProcessRequestBody(w, r, input) {
+		return
+	}
+
+	_, err = h.contactService.Reply(ctx, id, authorId, input.Body)
+	h.respond(ctx, w, r, err, "Reply sent", contactEvent(audit.ActionContactReply, id, ""))
+}
+
+func (h *AdminInboxHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, ok := h.messageId(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	input := new(models.ContactCategoryResource)
+	if !httputils.ProcessRequestBody(w, r, input) {
+		return
+	}
+
+	err := h.contactService.Categorize(ctx, id, contact.Category(input.Category))
+	h.respond(ctx, w, r, err, "Category updated", contactEvent(audit.ActionContactUpdate, id, "category:"+input.Category))
+}
+
+func (h *AdminInboxHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, ok := h.messageId(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	input := new(models.ContactStatusResource)
+	if !httputils.ProcessRequestBody(w, r, input) {
+		return
+	}
+
+	err := h.contactService.SetStatus(ctx, id, contact.Status(input.Status))
+	h.respond(ctx, w, r, err, "Status updated", contactEvent(audit.ActionContactUpdate, id, "status:"+input.Status))
+}
+
+// MarkUnread returns to the inbox, since staying on the message would mark it
+// read again.
+func (h *AdminInboxHandler) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, ok := h.messageId(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.contactService.MarkUnread(ctx, id)
+	if errors.Is(err, appContact.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Message not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error marking a contact message unread", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/admin/inbox")
+	httputils.SendSuccessResponse(ctx, w, "Marked unread", nil, http.StatusOK)
+}
+
+// Delete erases the message and its replies, for an erasure request.
+func (h *AdminInboxHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, ok := h.messageId(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.contactService.Delete(ctx, id)
+	if errors.Is(err, appContact.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Message not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting a contact message", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	h.auditService.Record(ctx, contactEvent(audit.ActionContactDelete, id, ""))
+
+	w.Header().Set("HX-Redirect", "/admin/inbox")
+	httputils.SendSuccessResponse(ctx, w, "Message deleted", nil, http.StatusOK)
+}
+
+func (h *AdminInboxHandler) messageId(ctx context.Context, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid message ID")
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+func (h *AdminInboxHandler) respond(ctx context.Context, w http.ResponseWriter, r *http.Request, err error, message string, event audit.Event) {
+	switch {
+	case err == nil:
+		h.auditService.Record(ctx, event)
+		w.Header().Set("HX-Refresh", "true")
+		httputils.SendSuccessResponse(ctx, w, message, nil, http.StatusOK)
+	case errors.Is(err, appContact.ErrNotFound):
+		httputils.SendNotFoundResponse(ctx, w, "Message not found")
+	case errors.Is(err, appContact.ErrInvalidStatus):
+		httputils.SendBadRequestResponse(ctx, w, "contact.status.invalid")
+	case errors.Is(err, appContact.ErrInvalidCategory):
+		httputils.SendBadRequestResponse(ctx, w, "contact.category.invalid")
+	case errors.Is(err, appContact.ErrEmptyReply):
+		httputils.SendBadRequestResponse(ctx, w, "contact.reply.empty")
+	default:
+		slog.ErrorContext(ctx, "Error managing a contact message", "error", err, "path", r.URL.Path)
+		httputils.SendInternalServerResponse(w, r)
+	}
+}
+
+// contactEvent describes an action on a contact message. The detail says what
+// was changed, where there is more to it than the action itself.
+func contactEvent(action audit.Action, id uuid.UUID, detail string) audit.Event {
+	return audit.Event{
+		Action:     action,
+		TargetType: audit.TargetContact,
+		TargetId:   id.String(),
+		Detail:     detail,
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appAudit "server/internal/application/audit"
+	appJobs "server/internal/application/jobs"
+	"server/internal/domain/audit"
+	"server/internal/domain/jobs"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/httputils"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+type AdminJobHandler struct {
+	jobService   *appJobs.JobService
+	auditService *appAudit.AuditService
+}
+
+func NewAdminJobHandler(jobService *appJobs.JobService, auditService *appAudit.AuditService) *AdminJobHandler {
+	return &AdminJobHandler{
+		jobService:   jobService,
+		auditService: auditService,
+	}
+}
+
+// GetJobs lists background jobs, filtered by ?status= and ?kind=.
+func (h *AdminJobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	query := r.URL.Query()
+	if p := query.Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	filters := admin.JobFilters{Status: jobs.Status(query.Get("status")), Kind: query.Get("kind")}
+
+	result, err := h.jobService.Jobs(ctx, filters.Status, filters.Kind, page, pageSize)
+	if errors.Is(err, appJobs.ErrInvalidStatus) {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid filter")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching jobs", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	counts, err := h.jobService.Counts(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting jobs", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	kinds, err := h.jobService.Kinds(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error listing job kinds", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.Jobs(models.JobsFromDomain(result.Jobs), filters, counts, kinds, page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+// GetJob shows one job with its arguments and last error.
+func (h *AdminJobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobService.Job(ctx, id)
+	if errors.Is(err, appJobs.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Job not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching a job", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	util.Must(admin.Job(models.JobFromDomain(*job)).Render(r.Context(), w))
+}
+
+// Retry runs a dead job again, or a waiting one now.
+func (h *AdminJobHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid job ID")
+		return
+	}
+
+	err = h.jobService.Retry(ctx, id)
+	switch {
+	case errors.Is(err, appJobs.ErrNotFound):
+		httputils.SendNotFoundResponse(ctx, w, "Job not found")
+		return
+	case errors.Is(err, appJobs.ErrNotRetryable):
+		httputils.SendConflictResponse(ctx, w, "The job is running or finished, or the same work has been queued since")
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "Error retrying a job", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	h.auditService.Record(ctx, audit.Event{
+		Action:     audit.ActionJobRetry,
+		TargetType: audit.TargetJob,
+		TargetId:   id.String(),
+	})
+
+	w.Header().Set("HX-Refresh", "true")
+	httputils.SendSuccessResponse(ctx, w, "Job queued", nil, http.StatusOK)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"server/internal/application/media"
+	"server/internal/domain/image"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/httputils"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+type AdminMediaHandler struct {
+	libraryService *media.LibraryService
+}
+
+func NewAdminMediaHandler(libraryService *media.LibraryService) *AdminMediaHandler {
+	return &AdminMediaHandler{
+		libraryService: libraryService,
+	}
+}
+
+// GetLibrary lists the uploaded images, filtered by ?q= and ?unused=1.
+func (h *AdminMediaHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 24
+
+	query := r.URL.Query()
+	if p := query.Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	filter := image.LibraryFilter{Query: query.Get("q"), UnusedOnly: query.Get("unused") == "1"}
+
+	result, err := h.libraryService.Search(ctx, filter, page, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching the media library", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+	items := models.MediaFromDomain(result.Items, h.libraryService.Grace())
+
+	util.Must(admin.MediaLibrary(items, admin.MediaFilters{Query: filter.Query, UnusedOnly: filter.UnusedOnly}, page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+// GetPicker answers the editor's image picker with the newest images matching
+// ?q=, as a fragment for it to swap in.
+func (h *AdminMediaHandler) GetPicker(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	result, err := h.libraryService.Search(ctx, image.LibraryFilter{Query: r.URL.Query().Get("q")}, 1, 48)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching the media library", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	util.Must(admin.MediaPickerResults(models.MediaFromDomain(result.Items, h.libraryService.Grace())).Render(r.Context(), w))
+}
+
+// UpdateAlt sets the alt text the picker inserts with an image.
+func (h *AdminMediaHandler) UpdateAlt(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid image ID")
+		return
+	}
+
+	input := new(models.UpdateAltResource)
+	if !httputils.ProcessRequestBody(w, r, input) {
+		return
+	}
+
+	err = h.libraryService.SetAlt(ctx, id, input.Alt)
+	switch {
+	case errors.Is(err, media.ErrAltTooLong):
+		httputils.SendErrorResponse(ctx, w, "media.alt.too.long", http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		httputils.SendErrorResponse(ctx, w, "media.not.found", http.StatusNotFound)
+	case err != nil:
+		slog.ErrorContext(ctx, "Error updating alt text", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+	default:
+		httputils.SendSuccessResponse(ctx, w, "Alt text updated", nil, http.StatusOK)
+	}
+}
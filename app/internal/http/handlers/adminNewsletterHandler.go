@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appAudit "server/internal/application/audit"
+	appNewsletter "server/internal/application/newsletter"
+	"server/internal/domain/audit"
+	"server/internal/domain/newsletter"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/httputils"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+type AdminNewsletterHandler struct {
+	newsletterService *appNewsletter.NewsletterService
+	auditService      *appAudit.AuditService
+}
+
+func NewAdminNewsletterHandler(newsletterService *appNewsletter.NewsletterService, auditService *appAudit.AuditService) *AdminNewsletterHandler {
+	return &AdminNewsletterHandler{
+		newsletterService: newsletterService,
+		auditService:      auditService,
+	}
+}
+
+func (h *AdminNewsletterHandler) GetSubscribers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	status := newsletter.StatusConfirmed
+	for _, known := range newsletter.Statuses {
+		if string(known) == r.URL.Query().Get("status") {
+			status = known
+		}
+	}
+
+	result, err := h.newsletterService.Subscribers(ctx, status, page, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching newsletter subscribers", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.NewsletterSubscribers(models.SubscribersFromDomain(result.Subscribers), status, page, totalPages, result.Total).Render(r.Context(), w))
+}
+
+func (h *AdminNewsletterHandler) GetSubscriber(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendNotFoundResponse(ctx, w, "Subscriber not found")
+		return
+	}
+
+	subscriber, consents, err := h.newsletterService.Subscriber(ctx, id)
+	if errors.Is(err, appNewsletter.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Subscriber not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching a newsletter subscriber", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	util.Must(admin.NewsletterSubscriber(models.SubscriberFromDomain(*subscriber), models.ConsentsFromDomain(consents)).Render(r.Context(), w))
+}
+
+// DeleteSubscriber carries out an erasure request: the address and its
+// consent history are removed for good.
+func (h *AdminNewsletterHandler) DeleteSubscriber(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid subscriber ID")
+		return
+	}
+
+	err = h.newsletterService.Delete(ctx, id)
+	if errors.Is(err, appNewsletter.ErrNotFound) {
+		httputils.SendNotFoundResponse(ctx, w, "Subscriber not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error deleting a newsletter subscriber", "error", err, "id", id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	h.auditService.Record(ctx, audit.Event{
+		Action:     audit.ActionSubscriberDelete,
+		TargetType: audit.TargetSubscriber,
+		TargetId:   id.String(),
+	})
+
+	w.Header().Set("HX-Redirect", "/admin/newsletter")
+	httputils.SendSuccessResponse(ctx, w, "Subscriber deleted", nil, http.StatusOK)
+}
+
+func (h *AdminNewsletterHandler) GetSends(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 50
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	result, err := h.newsletterService.Sends(ctx, page, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching newsletter sends", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.NewsletterSends(models.NewsletterSendsFromDomain(result.Sends), page, totalPages, result.Total).Render(r.Context(), w))
+}
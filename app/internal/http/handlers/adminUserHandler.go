@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	appAudit "server/internal/application/audit"
+	"server/internal/application/users"
+	"server/internal/domain/audit"
+	"server/internal/domain/user"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/ctxutils"
+	"server/util/httputils"
+	"server/util/securityutil"
+	"server/web/templates/admin"
+
+	"github.com/google/uuid"
+)
+
+type AdminUserHandler struct {
+	userAdminService *users.UserAdminService
+	auditService     *appAudit.AuditService
+}
+
+func NewAdminUserHandler(userAdminService *users.UserAdminService, auditService *appAudit.AuditService) *AdminUserHandler {
+	return &AdminUserHandler{
+		userAdminService: userAdminService,
+		auditService:     auditService,
+	}
+}
+
+func (h *AdminUserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	page := 1
+	pageSize := 20
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	result, err := h.userAdminService.Search(ctx, query, page, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching users", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	roles, err := h.userAdminService.Roles(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching roles", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	totalPages := (result.Total + pageSize - 1) / pageSize
+
+	util.Must(admin.UsersList(models.UserListFromDomain(result.Users), roleNames, page, totalPages, result.Total, query).Render(r.Context(), w))
+}
+
+func (h *AdminUserHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	actor, userId, ok := h.target(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	input := new(models.UpdateUserStatusResource)
+	if !httputils.ProcessRequestBody(w, r, input) {
+		return
+	}
+
+	err := h.userAdminService.SetStatus(ctx, actor.Id, userId, user.UserStatus(input.Status))
+	h.respond(ctx, w, r, err, "Status updated", userEvent(audit.ActionUserStatus, userId, input.Status))
+}
+
+func (h *AdminUserHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	actor, userId, ok := h.target(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	input := new(models.GrantRoleResource)
+	if !httputils.ProcessRequestBody(w, r, input) {
+		return
+	}
+
+	err := h.userAdminService.GrantRole(ctx, actor.Id, userId, input.Role)
+	h.respond(ctx, w, r, err, "Role granted", userEvent(audit.ActionRoleGrant, userId, input.Role))
+}
+
+func (h *AdminUserHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	actor, userId, ok := h.target(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userAdminService.RevokeRole(ctx, actor.Id, userId, r.PathValue("role"))
+	h.respond(ctx, w, r, err, "Role revoked", userEvent(audit.ActionRoleRevoke, userId, r.PathValue("role")))
+}
+
+func (h *AdminUserHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	actor, userId, ok := h.target(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userAdminService.ForcePasswordReset(ctx, actor.Id, userId)
+	h.respond(ctx, w, r, err, "Password reset sent", userEvent(audit.ActionPasswordResetForce, userId, ""))
+}
+
+func (h *AdminUserHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	actor, userId, ok := h.target(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userAdminService.RevokeSessions(ctx, actor.Id, userId)
+	h.respond(ctx, w, r, err, "Sessions revoked", userEvent(audit.ActionSessionsRevoke, userId, ""))
+}
+
+// target reads the acting administrator and the account from the path. It
+// writes the error response itself and reports whether to go on.
+func (h *AdminUserHandler) target(ctx context.Context, w http.ResponseWriter, r *http.Request) (*securityutil.LoggedInUser, uuid.UUID, bool) {
+	actor, err := ctxutils.GetUser(r.Context())
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return nil, uuid.Nil, false
+	}
+
+	userId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httputils.SendBadRequestResponse(ctx, w, "Invalid user ID")
+		return nil, uuid.Nil, false
+	}
+
+	return actor, userId, true
+}
+
+// respond maps the outcome of an action to the response and records it in
+// the audit trail, a refused action together with the reason. On success the
+// page is refreshed so the list shows the account as it now is.
+func (h *AdminUserHandler) respond(ctx context.Context, w http.ResponseWriter, r *http.Request, err error, message string, event audit.Event) {
+	switch {
+	case err == nil:
+		h.auditService.Record(ctx, event)
+		w.Header().Set("HX-Refresh", "true")
+		httputils.SendSuccessResponse(ctx, w, message, nil, http.StatusOK)
+	case errors.Is(err, user.ErrLastAdmin):
+		h.refuse(ctx, w, event, "user.last.admin", http.StatusConflict)
+	case errors.Is(err, users.ErrInvalidStatus):
+		h.refuse(ctx, w, event, "user.status.invalid", http.StatusBadRequest)
+	case errors.Is(err, users.ErrUnknownRole):
+		h.refuse(ctx, w, event, "user.role.unknown", http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		h.refuse(ctx, w, event, "user.not.found", http.StatusNotFound)
+	default:
+		slog.ErrorContext(ctx, "Error managing a user", "error", err, "path", r.URL.Path)
+		httputils.SendInternalServerResponse(w, r)
+	}
+}
+
+func (h *AdminUserHandler) refuse(ctx context.Context, w http.ResponseWriter, event audit.Event, reason string, code int) {
+	event.Outcome = audit.Failure
+	event.Detail = reason
+	h.auditService.Record(ctx, event)
+
+	httputils.SendErrorResponse(ctx, w, reason, code)
+}
+
+// userEvent describes an action on an account. The detail says what was
+// changed, where there is more to it than the action itself.
+func userEvent(action audit.Action, userId uuid.UUID, detail string) audit.Event {
+	return audit.Event{
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetId:   userId.String(),
+		Detail:     detail,
+	}
+}
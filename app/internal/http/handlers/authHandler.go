@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	appAudit "server/internal/application/audit"
 	"server/internal/application/auth"
 	"server/internal/application/users"
+	"server/internal/config"
+	"server/internal/domain/audit"
 	"server/internal/domain/user"
 	"server/internal/http/handlers/models"
 	"server/util"
@@ -20,23 +23,33 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
-	userService          *users.UserService
-	authService          *auth.AuthService
-	passwordResetService *auth.PasswordResetService
+	userService              *users.UserService
+	authService              *auth.AuthService
+	passwordResetService     *auth.PasswordResetService
+	emailVerificationService *auth.EmailVerificationService
+	lockoutService           *auth.LoginLockoutService
+	auditService             *appAudit.AuditService
 }
 
 func NewAuthHandler(
 	userService *users.UserService,
 	authService *auth.AuthService,
 	passwordResetService *auth.PasswordResetService,
+	emailVerificationService *auth.EmailVerificationService,
+	lockoutService *auth.LoginLockoutService,
+	auditService *appAudit.AuditService,
 ) *AuthHandler {
 	return &AuthHandler{
-		userService:          userService,
-		authService:          authService,
-		passwordResetService: passwordResetService,
+		userService:              userService,
+		authService:              authService,
+		passwordResetService:     passwordResetService,
+		emailVerificationService: emailVerificationService,
+		lockoutService:           lockoutService,
+		auditService:             auditService,
 	}
 }
 
@@ -90,8 +103,15 @@ func (handler *AuthHandler) HandleRegister(writer http.ResponseWriter, req *http
 	}
 
 	slog.InfoContext(ctx, fmt.Sprintf("User successfully created. [id=%s]", id.String()))
+
+	// The account is inactive until the link is followed. A failure here is
+	// not fatal: the user can ask for another link from the next page.
+	if err := handler.emailVerificationService.SendVerification(ctx, id, input.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to issue a verification link", "error", err, "userId", id)
+	}
+
+	writer.Header().Set("HX-Redirect", "/verify-email/sent")
 	writer.WriteHeader(http.StatusOK)
-	writer.Header().Add("HX-Redirect", "/")
 }
 
 func (handler *AuthHandler) HandleLogin(writer http.ResponseWriter, req *http.Request) {
@@ -128,6 +148,29 @@ func (handler *AuthHandler) HandleLogin(writer http.ResponseWriter, req *http.Re
 
 	if errors.Is(err, sql.ErrNoRows) || account.Email == "" {
 		slog.InfoContext(ctx, fmt.Sprintf("Attempt to login with invalid credentials. [email=%s]", input.Email))
+		handler.auditService.Record(ctx, audit.Event{Action: audit.ActionLogin, Outcome: audit.Failure, Detail: audit.ReasonUnknownAccount, Identifier: input.Email})
+		writer.WriteHeader(http.StatusNotFound)
+		util.Must(templates.InvalidMessage("Невалиден имейл или парола", "error-email").Render(ctx, writer))
+		return
+	}
+
+	// Asked before the password is checked, and answered exactly like a wrong
+	// password, so a lockout neither lets guessing continue nor tells anyone
+	// that the address belongs to an account.
+	if err := handler.lockoutService.Check(ctx, account.Id); err != nil {
+		if !errors.Is(err, auth.ErrAccountLocked) && !errors.Is(err, auth.ErrLoginThrottled) {
+			slog.ErrorContext(ctx, "Could not check the login lockout", "error", err, "userId", account.Id)
+			writer.Header().Add("HX-Redirect", "/error")
+			return
+		}
+
+		reason := audit.ReasonThrottled
+		if errors.Is(err, auth.ErrAccountLocked) {
+			reason = audit.ReasonLocked
+		}
+
+		slog.InfoContext(ctx, "Refused a login to a locked or throttled account", "userId", account.Id, "reason", reason)
+		handler.recordLoginFailure(ctx, account.Id, reason)
 		writer.WriteHeader(http.StatusNotFound)
 		util.Must(templates.InvalidMessage("Невалиден имейл или парола", "error-email").Render(ctx, writer))
 		return
@@ -136,6 +179,10 @@ func (handler *AuthHandler) HandleLogin(writer http.ResponseWriter, req *http.Re
 	tokenResult, err := handler.authService.Authenticate(account, input.Password, input.RememberMe, ctx)
 	if errors.Is(err, auth.ErrHashNotMatch) {
 		slog.InfoContext(ctx, fmt.Sprintf("Attempt to login with invalid credentials. [email=%s]", input.Email))
+		handler.recordLoginFailure(ctx, account.Id, audit.ReasonWrongPassword)
+		if err := handler.lockoutService.RecordFailure(ctx, account); err != nil {
+			slog.ErrorContext(ctx, "Could not count a failed login", "error", err, "userId", account.Id)
+		}
 		writer.WriteHeader(http.StatusNotFound)
 		util.Must(templates.InvalidMessage("Невалиден имейл или парола", "error-email").Render(ctx, writer))
 		return
@@ -147,13 +194,37 @@ func (handler *AuthHandler) HandleLogin(writer http.ResponseWriter, req *http.Re
 		return
 	}
 
+	if err := handler.lockoutService.RecordSuccess(ctx, account.Id); err != nil {
+		slog.ErrorContext(ctx, "Could not clear failed logins", "error", err, "userId", account.Id)
+	}
+
+	// Also checked after the password, so only the owner of the account learns
+	// that it is waiting for verification.
+	if !account.EmailVerifiedAt.Valid {
+		slog.InfoContext(ctx, "Rejected a login to an unverified account", "userId", account.Id)
+		handler.recordLoginFailure(ctx, account.Id, audit.ReasonUnverified)
+		writer.WriteHeader(http.StatusForbidden)
+		util.Must(templates.UnverifiedAccount().Render(ctx, writer))
+		return
+	}
+
+	// Also after the password, for the same reason as the verification check.
+	if !account.Status.AllowsSignIn() {
+		slog.InfoContext(ctx, "Rejected a login to a suspended account", "userId", account.Id, "status", account.Status)
+		handler.recordLoginFailure(ctx, account.Id, audit.ReasonSuspended)
+		writer.WriteHeader(http.StatusForbidden)
+		util.Must(templates.InvalidMessage("Акаунтът е спрян. Свържете се с администратор.", "error-email").Render(ctx, writer))
+		return
+	}
+
 	// Checked after the password so the response cannot be used to tell an
 	// administrator's address apart from any other, and answered with the same
-	// message for the same reason. No cookie is issued: a non administrator has
-	// nothing to do behind /admin, and a session minted here would otherwise
-	// survive as an ordinary login.
-	if adminLogin && !user.HasRole(account.Roles, user.RoleAdmin) {
-		slog.WarnContext(ctx, "Rejected a non administrator at the admin login", "email", input.Email)
+	// message for the same reason. No cookie is issued: without admin:access
+	// there is nothing to do behind /admin, and a session minted here would
+	// otherwise survive as an ordinary login.
+	if adminLogin && !user.HasPermission(account.Permissions, user.PermAdminAccess) {
+		slog.WarnContext(ctx, "Rejected a user without admin access at the admin login", "email", input.Email)
+		handler.recordLoginFailure(ctx, account.Id, audit.ReasonNoAdminAccess)
 		writer.WriteHeader(http.StatusNotFound)
 		util.Must(templates.InvalidMessage("Невалиден имейл или парола", "error-email").Render(ctx, writer))
 		return
@@ -162,6 +233,8 @@ func (handler *AuthHandler) HandleLogin(writer http.ResponseWriter, req *http.Re
 	httputils.SetAuthCookie(httputils.AuthCookieName, tokenResult.Token, tokenResult.TokenTime, input.RememberMe, writer)
 	httputils.SetRefreshCookie(tokenResult.RefreshToken, tokenResult.RefreshTokenTime, writer)
 
+	handler.auditService.Record(ctx, audit.Event{Action: audit.ActionLogin, ActorId: appAudit.ActorOf(account.Id)})
+
 	redirect := "/"
 	if adminLogin {
 		redirect = "/admin"
@@ -171,6 +244,12 @@ func (handler *AuthHandler) HandleLogin(writer http.ResponseWriter, req *http.Re
 }
 
 func (handler *AuthHandler) HandleLogout(writer http.ResponseWriter, req *http.Request) {
+	// Only a session that was still valid counts; anything else is someone
+	// clearing cookies that no longer meant anything.
+	if _, err := ctxutils.GetUser(req.Context()); err == nil {
+		handler.auditService.Record(req.Context(), audit.Event{Action: audit.ActionLogout})
+	}
+
 	handler.clearSession(writer)
 	httputils.ClearCookie(httputils.XSRFCookieName, writer)
 
@@ -194,6 +273,7 @@ func (handler *AuthHandler) RefreshToken(writer http.ResponseWriter, req *http.R
 	token, err := securityutil.ValidateRefreshToken(refreshCookie.Value)
 	if err != nil {
 		slog.InfoContext(ctx, "Rejected an invalid refresh token", "error", err)
+		handler.auditService.Record(ctx, audit.Event{Action: audit.ActionTokenRefresh, Outcome: audit.Failure, Detail: audit.ReasonInvalidToken})
 		handler.clearSession(writer)
 		httputils.SendErrorResponse(ctx, writer, "refresh.token.invalid", http.StatusUnauthorized)
 		return
@@ -217,6 +297,7 @@ func (handler *AuthHandler) RefreshToken(writer http.ResponseWriter, req *http.R
 	currentUser, err := handler.userService.GetUserById(ctx, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			handler.recordRefreshFailure(ctx, userId, audit.ReasonUnknownAccount)
 			handler.clearSession(writer)
 			httputils.SendErrorResponse(ctx, writer, "refresh.token.invalid", http.StatusUnauthorized)
 			return
@@ -227,9 +308,18 @@ func (handler *AuthHandler) RefreshToken(writer http.ResponseWriter, req *http.R
 		return
 	}
 
+	if !currentUser.Status.AllowsSignIn() {
+		slog.InfoContext(ctx, "Rejected a refresh for a suspended account", "userId", userId, "status", currentUser.Status)
+		handler.recordRefreshFailure(ctx, userId, audit.ReasonSuspended)
+		handler.clearSession(writer)
+		httputils.SendErrorResponse(ctx, writer, "refresh.token.suspended", http.StatusUnauthorized)
+		return
+	}
+
 	issuedAt := time.Unix(int64(claimFloat(claims, "iat")), 0).UTC()
 	if !handler.userService.IsSessionValid(ctx, userId, issuedAt) {
 		slog.InfoContext(ctx, "Rejected a revoked refresh token", "userId", userId)
+		handler.recordRefreshFailure(ctx, userId, audit.ReasonRevoked)
 		handler.clearSession(writer)
 		httputils.SendErrorResponse(ctx, writer, "refresh.token.revoked", http.StatusUnauthorized)
 		return
@@ -239,6 +329,7 @@ func (handler *AuthHandler) RefreshToken(writer http.ResponseWriter, req *http.R
 	httputils.SetAuthCookie(httputils.AuthCookieName, accessToken, accessExpiry, false, writer)
 
 	slog.InfoContext(ctx, "Refreshed an access token", "userId", userId)
+	handler.auditService.Record(ctx, audit.Event{Action: audit.ActionTokenRefresh, ActorId: appAudit.ActorOf(currentUser.Id)})
 	httputils.SendSuccessResponse(ctx, writer, "Token refreshed", nil, http.StatusOK)
 }
 
@@ -249,6 +340,28 @@ func (handler *AuthHandler) clearSession(writer http.ResponseWriter) {
 	httputils.ClearCookieAtPath(httputils.RefreshCookieName, httputils.RefreshTokenPath, writer)
 }
 
+// recordLoginFailure records a refused sign-in against the account it was
+// aimed at.
+func (handler *AuthHandler) recordLoginFailure(ctx context.Context, accountId uuid.UUID, reason string) {
+	handler.auditService.Record(ctx, audit.Event{
+		Action:  audit.ActionLogin,
+		Outcome: audit.Failure,
+		Detail:  reason,
+		ActorId: appAudit.ActorOf(accountId),
+	})
+}
+
+// recordRefreshFailure records a refused refresh against the account the token
+// named. The id comes from a verified token, so it is well formed.
+func (handler *AuthHandler) recordRefreshFailure(ctx context.Context, userId string, reason string) {
+	event := audit.Event{Action: audit.ActionTokenRefresh, Outcome: audit.Failure, Detail: reason}
+	if id, err := uuid.Parse(userId); err == nil {
+		event.ActorId = appAudit.ActorOf(id)
+	}
+
+	handler.auditService.Record(ctx, event)
+}
+
 func claimFloat(claims jwt.MapClaims, key string) float64 {
 	value, _ := claims[key].(float64)
 
@@ -327,6 +440,8 @@ func (handler *AuthHandler) HandleForgotPassword(writer http.ResponseWriter, req
 		return
 	}
 
+	handler.auditService.Record(ctx, audit.Event{Action: audit.ActionPasswordResetRequest, Identifier: input.Email})
+
 	// Always return success to prevent email enumeration
 	err := handler.passwordResetService.RequestReset(ctx, input.Email)
 	if err != nil {
@@ -392,8 +507,9 @@ func (handler *AuthHandler) HandleResetPassword(writer http.ResponseWriter, req
 		return
 	}
 
-	err := handler.passwordResetService.ResetPassword(ctx, input.Token, input.Password)
+	accountId, err := handler.passwordResetService.ResetPassword(ctx, input.Token, input.Password)
 	if errors.Is(err, auth.ErrInvalidToken) {
+		handler.auditService.Record(ctx, audit.Event{Action: audit.ActionPasswordReset, Outcome: audit.Failure, Detail: audit.ReasonInvalidToken})
 		writer.WriteHeader(http.StatusBadRequest)
 		util.Must(templates.InvalidMessage("Линкът е невалиден или изтекъл. Моля, заявете нов.", "error-token").Render(ctx, writer))
 		return
@@ -403,12 +519,146 @@ func (handler *AuthHandler) HandleResetPassword(writer http.ResponseWriter, req
 		util.Must(templates.InvalidMessage("Паролата трябва да е поне 12 символа и да съдържа главна и малка буква, цифра и символ", "error-password").Render(ctx, writer))
 		return
 	}
+	if errors.Is(err, securityutil.ErrPasswordBreached) {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.BreachedPassword().Render(ctx, writer))
+		return
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to reset password", "error", err)
 		writer.Header().Add("HX-Redirect", "/error")
 		return
 	}
 
+	handler.auditService.Record(ctx, audit.Event{Action: audit.ActionPasswordReset, ActorId: appAudit.ActorOf(accountId)})
+
 	writer.Header().Set("HX-Redirect", "/login")
 	writer.WriteHeader(http.StatusOK)
 }
+
+func (handler *AuthHandler) GetVerifyEmailSent(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	util.Must(templates.SimpleLayout(
+		templates.VerifyEmailSent(),
+		"Потвърдете имейла си",
+		"Изпратихме ви линк за потвърждение на имейл адреса.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, writer))
+}
+
+// GetVerifyEmail consumes the link from the verification email. The token is
+// single use, so a reload after success shows the invalid link page.
+func (handler *AuthHandler) GetVerifyEmail(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(writer, req, "/verify-email/resend", http.StatusSeeOther)
+		return
+	}
+
+	err := handler.emailVerificationService.Verify(ctx, token)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			slog.ErrorContext(ctx, "Failed to verify an email address", "error", err)
+		}
+
+		util.Must(templates.SimpleLayout(
+			templates.VerifyEmailInvalid(),
+			"Невалиден линк",
+			"Линкът за потвърждение на имейл е невалиден или изтекъл.",
+			ctxutils.GetCSRF(ctx),
+		).Render(ctx, writer))
+		return
+	}
+
+	util.Must(templates.SimpleLayout(
+		templates.VerifyEmailSuccess(),
+		"Имейлът е потвърден",
+		"Акаунтът ви е активен.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, writer))
+}
+
+// GetUnlockAccount lifts a lockout from the link in the notice sent when the
+// account was locked.
+func (handler *AuthHandler) GetUnlockAccount(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(writer, req, "/", http.StatusSeeOther)
+		return
+	}
+
+	loginPath := "/admin/login"
+	if config.AllowRegistration() {
+		loginPath = "/login"
+	}
+
+	accountId, err := handler.lockoutService.Unlock(ctx, token)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			slog.ErrorContext(ctx, "Failed to unlock an account", "error", err)
+		}
+
+		handler.auditService.Record(ctx, audit.Event{Action: audit.ActionAccountUnlock, Outcome: audit.Failure, Detail: audit.ReasonInvalidToken})
+		util.Must(templates.SimpleLayout(
+			templates.UnlockAccountInvalid(),
+			"Невалиден линк",
+			"Линкът за отключване е невалиден или изтекъл.",
+			ctxutils.GetCSRF(ctx),
+		).Render(ctx, writer))
+		return
+	}
+
+	handler.auditService.Record(ctx, audit.Event{Action: audit.ActionAccountUnlock, ActorId: appAudit.ActorOf(accountId)})
+
+	util.Must(templates.SimpleLayout(
+		templates.UnlockAccountSuccess(loginPath),
+		"Акаунтът е отключен",
+		"Вече можете да влезете.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, writer))
+}
+
+func (handler *AuthHandler) GetResendVerification(writer http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	util.Must(templates.SimpleLayout(
+		templates.ResendVerification(),
+		"Потвърждение на имейл",
+		"Заявете нов линк за потвърждение на имейл адреса.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, writer))
+}
+
+func (handler *AuthHandler) HandleResendVerification(writer http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), cancelTime)
+	defer cancel()
+
+	input := new(models.ResendVerificationResource)
+	result := httputils.ProcessBody(writer, req, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		writer.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if result.ValidationErrors != nil {
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.FormErrors(result.ValidationErrors).Render(ctx, writer))
+		return
+	}
+
+	// Same answer whatever happened, so the form cannot be used to probe for
+	// registered addresses.
+	if err := handler.emailVerificationService.ResendVerification(ctx, input.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to process a verification resend", "error", err)
+	}
+
+	util.Must(templates.ResendVerificationSuccess().Render(ctx, writer))
+}
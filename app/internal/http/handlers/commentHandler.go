@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	appComments "server/internal/application/comments"
+	appPosts "server/internal/application/posts"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/internal/http/handlers/models"
+	"server/internal/http/middleware"
+	"server/util"
+	"server/util/ctxutils"
+	"server/util/httputils"
+	"server/web/templates"
+
+	"github.com/google/uuid"
+)
+
+type CommentHandler struct {
+	postService    *appPosts.PostService
+	commentService *appComments.CommentService
+}
+
+func NewCommentHandler(postService *appPosts.PostService, commentService *appComments.CommentService) *CommentHandler {
+	return &CommentHandler{
+		postService:    postService,
+		commentService: commentService,
+	}
+}
+
+func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	post, ok := h.publishedPost(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	h.renderSection(ctx, w, r, post)
+}
+
+func (h *CommentHandler) PostComment(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		w.Header().Add("HX-Redirect", "/login")
+		return
+	}
+
+	post, ok := h.publishedPost(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	input := new(models.CreateCommentResource)
+	result := httputils.ProcessBody(w, r, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		w.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if result.ValidationErrors != nil {
+		h.refuse(ctx, w, "Напишете коментар до 2000 символа", input.ParentId)
+		return
+	}
+
+	userId, err := uuid.Parse(loggedUser.Id)
+	if err != nil {
+		httputils.SendErrorResponse(ctx, w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var parentId uuid.NullUUID
+	if input.ParentId != "" {
+		parentId = uuid.NullUUID{UUID: uuid.MustParse(input.ParentId), Valid: true}
+	}
+
+	_, err = h.commentService.Post(ctx, appComments.NewComment{
+		PostId:   post.Id,
+		UserId:   userId,
+		ParentId: parentId,
+		Body:     input.Body,
+		Trusted:  user.HasPermission(loggedUser.Permissions, user.PermCommentsModerate),
+
+		Suspicious: middleware.SpamAssessmentFrom(ctx).Suspicious,
+	})
+	switch {
+	case errors.Is(err, appComments.ErrEmptyComment):
+		h.refuse(ctx, w, "Напишете коментар до 2000 символа", input.ParentId)
+		return
+	case errors.Is(err, appComments.ErrParentNotFound):
+		h.refuse(ctx, w, "Коментарът, на който отговаряте, вече не е наличен", input.ParentId)
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "Error posting a comment", "error", err, "postId", post.Id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	h.renderSection(ctx, w, r, post)
+}
+
+// publishedPost loads the post named in the path. Drafts and archived posts
+// take no comments and show none.
+func (h *CommentHandler) publishedPost(ctx context.Context, w http.ResponseWriter, r *http.Request) (*posts.PostWithAuthor, bool) {
+	post, err := h.postService.GetBySlug(ctx, r.PathValue("slug"))
+	if err != nil || post.Status != posts.PostStatusPublished {
+		httputils.SendNotFoundResponse(ctx, w, "Post not found")
+		return nil, false
+	}
+
+	return post, true
+}
+
+// renderSection renders the discussion as the signed in reader, if any, sees
+// it: their own pending comments are included.
+func (h *CommentHandler) renderSection(ctx context.Context, w http.ResponseWriter, r *http.Request, post *posts.PostWithAuthor) {
+	var viewerId uuid.NullUUID
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err == nil {
+		if id, err := uuid.Parse(loggedUser.Id); err == nil {
+			viewerId = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+
+	thread, err := h.commentService.Thread(ctx, post.Id, viewerId)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error fetching comments", "error", err, "postId", post.Id)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	util.Must(templates.CommentSection(post.Slug, models.CommentThreadFromDomain(thread), viewerId.Valid).Render(r.Context(), w))
+}
+
+// refuse shows the message under the form that was sent and leaves the rest of
+// the discussion, and what the reader typed, as it is.
+func (h *CommentHandler) refuse(ctx context.Context, w http.ResponseWriter, message, parentId string) {
+	w.Header().Set("HX-Reswap", "none")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	util.Must(templates.InvalidMessage(message, templates.CommentErrorId(parentId)).Render(ctx, w))
+}
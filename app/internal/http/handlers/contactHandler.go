@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	appContact "server/internal/application/contact"
+	"server/internal/domain/contact"
+	"server/internal/http/handlers/models"
+	"server/internal/http/middleware"
+	"server/util"
+	"server/util/httputils"
+	"server/web/templates"
+)
+
+type ContactHandler struct {
+	contactService *appContact.ContactService
+}
+
+func NewContactHandler(contactService *appContact.ContactService) *ContactHandler {
+	return &ContactHandler{
+		contactService: contactService,
+	}
+}
+
+func (h *ContactHandler) GetContact(w http.ResponseWriter, r *http.Request) {
+	category := contact.Category(r.URL.Query().Get("category"))
+	if category == "" {
+		category = contact.CategoryGeneral
+	}
+
+	util.Must(templates.Contact(category).Render(r.Context(), w))
+}
+
+// Submit stores the message. One held by the spam check is thanked like any
+// other, so a script learns nothing from the answer.
+func (h *ContactHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	input := new(models.ContactResource)
+	result := httputils.ProcessBody(w, r, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		w.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if result.ValidationErrors != nil {
+		h.refuse(ctx, w, "Попълнете всички полета. Съобщението е до 5000 символа, а имейлът трябва да е валиден.")
+		return
+	}
+
+	_, err := h.contactService.Submit(ctx, appContact.NewMessage{
+		Name:     input.Name,
+		Email:    input.Email,
+		Category: contact.Category(input.Category),
+		Subject:  input.Subject,
+		Body:     input.Body,
+
+		Suspicious: middleware.SpamAssessmentFrom(ctx).Suspicious,
+	})
+	switch {
+	case errors.Is(err, appContact.ErrEmptyMessage):
+		h.refuse(ctx, w, "Напишете съобщение.")
+		return
+	case errors.Is(err, appContact.ErrInvalidCategory):
+		h.refuse(ctx, w, "Изберете тема.")
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "Error storing a contact message", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	util.Must(templates.ContactSent().Render(ctx, w))
+}
+
+// refuse shows the message under the form and leaves what the reader typed
+// as it is.
+func (h *ContactHandler) refuse(ctx context.Context, w http.ResponseWriter, message string) {
+	w.Header().Set("HX-Reswap", "none")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	util.Must(templates.InvalidMessage(message, templates.ContactErrorId).Render(ctx, w))
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	appHealth "server/internal/application/health"
+)
+
+type HealthHandler struct {
+	healthService *appHealth.HealthService
+}
+
+func NewHealthHandler(healthService *appHealth.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+type probeResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Livez answers as long as the process can serve a request at all. It checks
+// nothing else: a restart would not fix a database outage.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, r, http.StatusOK, probeResponse{Status: "ok"})
+}
+
+// Readyz tells load balancers whether to send traffic here. It fails while
+// starting up, once draining for shutdown has begun, and while the database
+// is out of reach.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	err := h.healthService.Ready(r.Context())
+	switch {
+	case err == nil:
+		writeProbe(w, r, http.StatusOK, probeResponse{Status: "ready"})
+	case errors.Is(err, appHealth.ErrStarting), errors.Is(err, appHealth.ErrDraining):
+		writeProbe(w, r, http.StatusServiceUnavailable, probeResponse{Status: err.Error()})
+	default:
+		slog.WarnContext(r.Context(), "Readiness check failed", "error", err)
+		writeProbe(w, r, http.StatusServiceUnavailable, probeResponse{Status: "unavailable", Reason: "database unreachable"})
+	}
+}
+
+// GetDetails reports on every dependency for an administrator. A failed check
+// turns the response into a 503 so the page can be monitored too.
+func (h *HealthHandler) GetDetails(w http.ResponseWriter, r *http.Request) {
+	report := h.healthService.Details(r.Context())
+
+	status := http.StatusOK
+	if report.Status == appHealth.StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	writeProbe(w, r, status, report)
+}
+
+// writeProbe sets the content type itself: the ContentType middleware has
+// already claimed HTML for these extensionless paths, and jsonutils adds a
+// second header rather than replacing it.
+func writeProbe(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.WarnContext(r.Context(), "Failed to write health check response", "error", err)
+	}
+}
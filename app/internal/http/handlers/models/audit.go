@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"server/internal/domain/audit"
+)
+
+type AuditEventItem struct {
+	OccurredAt time.Time
+	Action     string
+	Success    bool
+	Detail     string
+	// Actor is the actor's email, or the address typed in when there was no
+	// actor, or empty.
+	Actor     string
+	Target    string
+	IP        string
+	UserAgent string
+	RequestId string
+}
+
+func AuditEventsFromDomain(events []audit.Event) []AuditEventItem {
+	items := make([]AuditEventItem, 0, len(events))
+	for _, e := range events {
+		item := AuditEventItem{
+			OccurredAt: e.OccurredAt,
+			Action:     string(e.Action),
+			Success:    e.Outcome == audit.Success,
+			Detail:     e.Detail,
+			Actor:      e.ActorEmail,
+			IP:         e.IP,
+			UserAgent:  e.UserAgent,
+			RequestId:  e.RequestId,
+		}
+
+		// An actor whose account has since been removed is still shown by id.
+		if item.Actor == "" && e.ActorId.Valid {
+			item.Actor = e.ActorId.UUID.String()
+		}
+		if item.Actor == "" {
+			item.Actor = e.Identifier
+		}
+
+		if e.TargetId != "" {
+			item.Target = e.TargetType + " " + e.TargetId
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
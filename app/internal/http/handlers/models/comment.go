@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"server/internal/domain/comments"
+
+	"github.com/google/uuid"
+)
+
+type CreateCommentResource struct {
+	Body     string `json:"body" validate:"required,max=2000"`
+	ParentId string `json:"parentId" validate:"omitempty,uuid"`
+}
+
+// CommentItem is one comment of a thread, in reading order. Depth says how far
+// it is indented under the comment it answers.
+type CommentItem struct {
+	Id             uuid.UUID
+	AuthorName     string
+	AuthorInitials string
+	Body           string
+	CreatedAt      time.Time
+	Depth          int
+	// Pending comments, and ones held as spam, are only ever shown to their
+	// author, marked as awaiting moderation.
+	Pending bool
+}
+
+type CommentQueueItem struct {
+	Id          uuid.UUID
+	AuthorName  string
+	AuthorEmail string
+	Body        string
+	PostTitle   string
+	PostSlug    string
+	IsReply     bool
+	CreatedAt   time.Time
+}
+
+// anonymousAuthor stands in for a commenter who never gave a name, which
+// registration does not ask for.
+const anonymousAuthor = "Читател"
+
+func commentAuthor(firstName, lastName string) string {
+	if name := strings.TrimSpace(firstName + " " + lastName); name != "" {
+		return name
+	}
+
+	return anonymousAuthor
+}
+
+func CommentThreadFromDomain(roots []*comments.Node) []CommentItem {
+	nodes := comments.Flatten(roots)
+
+	items := make([]CommentItem, 0, len(nodes))
+	for _, node := range nodes {
+		items = append(items, CommentItem{
+			Id:             node.Id,
+			AuthorName:     commentAuthor(node.AuthorFirstName, node.AuthorLastName),
+			AuthorInitials: AuthorInitials(node.AuthorFirstName, node.AuthorLastName),
+			Body:           node.Body,
+			CreatedAt:      node.CreatedAt,
+			Depth:          node.Depth,
+			Pending:        node.Status != comments.StatusApproved,
+		})
+	}
+
+	return items
+}
+
+func CommentQueueFromDomain(queue []comments.QueueItem) []CommentQueueItem {
+	items := make([]CommentQueueItem, 0, len(queue))
+	for _, c := range queue {
+		items = append(items, CommentQueueItem{
+			Id:          c.Id,
+			AuthorName:  commentAuthor(c.AuthorFirstName, c.AuthorLastName),
+			AuthorEmail: c.AuthorEmail,
+			Body:        c.Body,
+			PostTitle:   c.PostTitle,
+			PostSlug:    c.PostSlug,
+			IsReply:     c.ParentId.Valid,
+			CreatedAt:   c.CreatedAt,
+		})
+	}
+
+	return items
+}
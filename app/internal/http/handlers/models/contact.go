@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"server/internal/domain/contact"
+
+	"github.com/google/uuid"
+)
+
+type ContactResource struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Category string `json:"category" validate:"required"`
+	Subject  string `json:"subject" validate:"required,max=200"`
+	Body     string `json:"body" validate:"required,max=5000"`
+}
+
+type ContactReplyResource struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type ContactCategoryResource struct {
+	Category string `json:"category" validate:"required"`
+}
+
+type ContactStatusResource struct {
+	Status string `json:"status" validate:"required"`
+}
+
+type ContactMessageItem struct {
+	Id        uuid.UUID
+	Name      string
+	Email     string
+	Category  contact.Category
+	Subject   string
+	Body      string
+	Status    contact.Status
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	Unread    bool
+	// DueAt is set for privacy requests, DaysLeft counts down to it and goes
+	// negative once it has passed. Closed requests keep both for the record.
+	DueAt    *time.Time
+	DaysLeft int
+	ClosedAt *time.Time
+}
+
+// Overdue reports an open request past its due date.
+func (m ContactMessageItem) Overdue() bool {
+	return m.DueAt != nil && m.Status == contact.StatusOpen && m.DaysLeft < 0
+}
+
+type ContactReplyItem struct {
+	AuthorName string
+	Body       string
+	CreatedAt  time.Time
+}
+
+func ContactMessageFromDomain(message contact.Message, now time.Time) ContactMessageItem {
+	item := ContactMessageItem{
+		Id:        message.Id,
+		Name:      message.Name,
+		Email:     message.Email,
+		Category:  message.Category,
+		Subject:   message.Subject,
+		Body:      message.Body,
+		Status:    message.Status,
+		IP:        message.IP,
+		UserAgent: message.UserAgent,
+		CreatedAt: message.CreatedAt,
+		Unread:    !message.ReadAt.Valid,
+	}
+
+	if message.DueAt.Valid {
+		dueAt := message.DueAt.Time
+		item.DueAt = &dueAt
+		item.DaysLeft = daysUntil(now, dueAt)
+	}
+	if message.ClosedAt.Valid {
+		closedAt := message.ClosedAt.Time
+		item.ClosedAt = &closedAt
+	}
+
+	return item
+}
+
+func ContactMessagesFromDomain(messages []contact.Message, now time.Time) []ContactMessageItem {
+	items := make([]ContactMessageItem, 0, len(messages))
+	for _, message := range messages {
+		items = append(items, ContactMessageFromDomain(message, now))
+	}
+
+	return items
+}
+
+func ContactRepliesFromDomain(replies []contact.Reply) []ContactReplyItem {
+	items := make([]ContactReplyItem, 0, len(replies))
+	for _, reply := range replies {
+		items = append(items, ContactReplyItem{
+			AuthorName: reply.AuthorName,
+			Body:       reply.Body,
+			CreatedAt:  reply.CreatedAt,
+		})
+	}
+
+	return items
+}
+
+// daysUntil counts whole days left until the deadline. The day it falls on
+// counts as zero days left; the day after, as minus one.
+func daysUntil(now, deadline time.Time) int {
+	remaining := deadline.Sub(now)
+	days := int(remaining / (24 * time.Hour))
+	if remaining < 0 && remaining%(24*time.Hour) != 0 {
+		days--
+	}
+
+	return days
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"server/internal/domain/outbox"
+
+	"github.com/google/uuid"
+)
+
+type OutboxMessageItem struct {
+	Id        uuid.UUID
+	Recipient string
+	Subject   string
+	Dead      bool
+	Attempts  int
+	LastError string
+	// NextAttemptAt is when a message still being retried is tried again.
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+func OutboxMessagesFromDomain(messages []outbox.Message) []OutboxMessageItem {
+	items := make([]OutboxMessageItem, 0, len(messages))
+	for _, m := range messages {
+		items = append(items, OutboxMessageItem{
+			Id:            m.Id,
+			Recipient:     m.Recipient,
+			Subject:       m.Subject,
+			Dead:          m.Status == outbox.StatusDead,
+			Attempts:      m.Attempts,
+			LastError:     m.LastError,
+			NextAttemptAt: m.NextAttemptAt,
+			CreatedAt:     m.CreatedAt,
+		})
+	}
+
+	return items
+}
@@ -138,6 +138,11 @@ func SitemapFromPosts(domainPosts []posts.SitemapEntry, baseURL string) Sitemap
 			ChangeFreq: "monthly",
 			Priority:   "0.5",
 		},
+		{
+			Loc:        baseURL + "/contact",
+			ChangeFreq: "yearly",
+			Priority:   "0.3",
+		},
 		{
 			Loc:        baseURL + "/privacy",
 			ChangeFreq: "yearly",
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"server/internal/domain/jobs"
+
+	"github.com/google/uuid"
+)
+
+type JobItem struct {
+	Id        uuid.UUID
+	Kind      string
+	Status    jobs.Status
+	UniqueKey string
+	Attempts  int
+	LastError string
+	// RunAt is when a waiting job is due, or when a running one's lease ends.
+	RunAt      time.Time
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	// Payload is the job's arguments, indented for reading.
+	Payload string
+}
+
+// Retryable reports whether the panel offers to run the job again.
+func (j JobItem) Retryable() bool {
+	return j.Status == jobs.StatusDead || j.Status == jobs.StatusPending
+}
+
+func JobFromDomain(job jobs.Job) JobItem {
+	item := JobItem{
+		Id:        job.Id,
+		Kind:      job.Kind,
+		Status:    job.Status,
+		UniqueKey: job.UniqueKey,
+		Attempts:  job.Attempts,
+		LastError: job.LastError,
+		RunAt:     job.RunAt,
+		CreatedAt: job.CreatedAt,
+		Payload:   string(job.Payload),
+	}
+
+	if job.StartedAt.Valid {
+		item.StartedAt = &job.StartedAt.Time
+	}
+	if job.FinishedAt.Valid {
+		item.FinishedAt = &job.FinishedAt.Time
+	}
+
+	var indented bytes.Buffer
+	if json.Indent(&indented, job.Payload, "", "  ") == nil {
+		item.Payload = indented.String()
+	}
+
+	return item
+}
+
+func JobsFromDomain(found []jobs.Job) []JobItem {
+	items := make([]JobItem, 0, len(found))
+	for _, job := range found {
+		items = append(items, JobFromDomain(job))
+	}
+
+	return items
+}
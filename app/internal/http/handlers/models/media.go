@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"server/internal/domain/image"
+
+	"github.com/google/uuid"
+)
+
+type MediaItem struct {
+	Id       uuid.UUID
+	Url      string
+	Filename string
+	Alt      string
+	Width    int
+	Height   int
+	Bytes    int64
+	Uploader string
+	Usages   []image.Usage
+	// InCategory is set when a category shows the image.
+	InCategory bool
+	CreatedAt  time.Time
+	// DeleteAt is when the sweep deletes an unused image, nil while the image
+	// is in use.
+	DeleteAt *time.Time
+}
+
+func (m MediaItem) Used() bool {
+	return len(m.Usages) > 0 || m.InCategory
+}
+
+// MediaFromDomain lists the library. grace is how long an image goes unused
+// before it is deleted.
+func MediaFromDomain(items []image.LibraryItem, grace time.Duration) []MediaItem {
+	media := make([]MediaItem, 0, len(items))
+	for _, item := range items {
+		m := MediaItem{
+			Id:         item.Id,
+			Url:        item.Url,
+			Filename:   item.Filename,
+			Alt:        item.Alt,
+			Width:      item.Width,
+			Height:     item.Height,
+			Bytes:      item.Bytes,
+			Uploader:   item.Uploader,
+			Usages:     item.Usages,
+			InCategory: item.InCategory,
+			CreatedAt:  item.CreatedAt,
+		}
+		if !item.UnusedSince.IsZero() {
+			deleteAt := item.UnusedSince.Add(grace)
+			m.DeleteAt = &deleteAt
+		}
+		media = append(media, m)
+	}
+	return media
+}
+
+type UpdateAltResource struct {
+	Alt string `json:"alt"`
+}
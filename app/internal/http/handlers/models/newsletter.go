@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"server/internal/domain/newsletter"
+
+	"github.com/google/uuid"
+)
+
+type SubscribeResource struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type SubscriberItem struct {
+	Id     uuid.UUID
+	Email  string
+	Status newsletter.Status
+	// Since is when the subscriber entered their current status.
+	Since time.Time
+}
+
+type ConsentItem struct {
+	Action      string
+	ConsentText string
+	IP          string
+	UserAgent   string
+	CreatedAt   time.Time
+}
+
+type NewsletterSendItem struct {
+	Subject        string
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	PostCount      int
+	RecipientCount int
+	FailedCount    int
+	StartedAt      time.Time
+	// FinishedAt is nil while the send is still queueing copies.
+	FinishedAt *time.Time
+}
+
+func SubscriberFromDomain(subscriber newsletter.Subscriber) SubscriberItem {
+	item := SubscriberItem{
+		Id:     subscriber.Id,
+		Email:  subscriber.Email,
+		Status: subscriber.Status,
+		Since:  subscriber.CreatedAt,
+	}
+
+	switch {
+	case subscriber.Status == newsletter.StatusConfirmed && subscriber.ConfirmedAt.Valid:
+		item.Since = subscriber.ConfirmedAt.Time
+	case subscriber.Status == newsletter.StatusUnsubscribed && subscriber.UnsubscribedAt.Valid:
+		item.Since = subscriber.UnsubscribedAt.Time
+	}
+
+	return item
+}
+
+func SubscribersFromDomain(subscribers []newsletter.Subscriber) []SubscriberItem {
+	items := make([]SubscriberItem, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		items = append(items, SubscriberFromDomain(subscriber))
+	}
+
+	return items
+}
+
+func ConsentsFromDomain(consents []newsletter.Consent) []ConsentItem {
+	items := make([]ConsentItem, 0, len(consents))
+	for _, c := range consents {
+		items = append(items, ConsentItem{
+			Action:      string(c.Action),
+			ConsentText: c.ConsentText,
+			IP:          c.IP,
+			UserAgent:   c.UserAgent,
+			CreatedAt:   c.CreatedAt,
+		})
+	}
+
+	return items
+}
+
+func NewsletterSendsFromDomain(sends []newsletter.Send) []NewsletterSendItem {
+	items := make([]NewsletterSendItem, 0, len(sends))
+	for _, s := range sends {
+		item := NewsletterSendItem{
+			Subject:        s.Subject,
+			PeriodStart:    s.PeriodStart,
+			PeriodEnd:      s.PeriodEnd,
+			PostCount:      s.PostCount,
+			RecipientCount: s.RecipientCount,
+			FailedCount:    s.FailedCount,
+			StartedAt:      s.StartedAt,
+		}
+		if s.FinishedAt.Valid {
+			item.FinishedAt = &s.FinishedAt.Time
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
@@ -49,6 +49,7 @@ type PostResponseResource struct {
 	AuthorLastName     string          `json:"authorLastName"`
 	CreatedAt          time.Time       `json:"createdAt"`
 	UpdatedAt          *time.Time      `json:"updatedAt"`
+	CommentCount       int             `json:"commentCount"`
 }
 
 type PostListItem struct {
@@ -65,6 +66,7 @@ type PostListItem struct {
 	AuthorFirstName    string     `json:"authorFirstName"`
 	AuthorLastName     string     `json:"authorLastName"`
 	CreatedAt          time.Time  `json:"createdAt"`
+	CommentCount       int        `json:"commentCount"`
 }
 
 // AuthorInitials renders the avatar initials. Author names are optional -
@@ -125,6 +127,7 @@ func PostResponseFromDomain(p *posts.PostWithAuthor) PostResponseResource {
 		CreatedAt:          p.CreatedAt,
 		UpdatedAt:          updatedAt,
 		Metadata:           p.Metadata,
+		CommentCount:       p.CommentCount,
 	}
 }
 
@@ -148,6 +151,7 @@ func PostListItemFromDomain(p *posts.PostWithAuthor) PostListItem {
 		AuthorFirstName:    p.AuthorFirstName,
 		AuthorLastName:     p.AuthorLastName,
 		CreatedAt:          p.CreatedAt,
+		CommentCount:       p.CommentCount,
 	}
 }
 
@@ -1,5 +1,13 @@
 package models
 
+import (
+	"time"
+
+	"server/internal/domain/user"
+
+	"github.com/google/uuid"
+)
+
 type CreateUserResource struct {
 	Email          string `json:"email" validate:"required,email"`
 	Password       string `json:"password" validate:"required,strongpassword"`
@@ -23,3 +31,94 @@ type ResetPasswordResource struct {
 	Password       string `json:"password" validate:"required,strongpassword"`
 	RepeatPassword string `json:"repeatPassword" validate:"required"`
 }
+
+type ResendVerificationResource struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type UpdateProfileResource struct {
+	FirstName string `json:"firstName" validate:"max=50"`
+	LastName  string `json:"lastName" validate:"max=50"`
+}
+
+type ChangePasswordResource struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	Password        string `json:"password" validate:"required,strongpassword"`
+	RepeatPassword  string `json:"repeatPassword" validate:"required"`
+}
+
+type ChangeEmailResource struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type DeleteAccountResource struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type AccountResponseResource struct {
+	Email     string
+	FirstName string
+	LastName  string
+
+	// DeletionScheduledFor is the formatted date of a pending deletion, or
+	// empty when none is pending.
+	DeletionScheduledFor string
+}
+
+func AccountFromDomain(u user.User) AccountResponseResource {
+	account := AccountResponseResource{
+		Email:     u.Email,
+		FirstName: u.FirstName.String,
+		LastName:  u.LastName.String,
+	}
+
+	if u.DeletionScheduledAt.Valid {
+		account.DeletionScheduledFor = u.DeletionScheduledAt.Time.Format("02.01.2006")
+	}
+
+	return account
+}
+
+type UpdateUserStatusResource struct {
+	Status string `json:"status" validate:"required"`
+}
+
+type GrantRoleResource struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type UserListItem struct {
+	Id        uuid.UUID
+	Email     string
+	FirstName string
+	LastName  string
+	Status    string
+	Verified  bool
+	Roles     []string
+	CreatedAt time.Time
+}
+
+func UserListFromDomain(users []user.User) []UserListItem {
+	items := make([]UserListItem, 0, len(users))
+	for _, u := range users {
+		item := UserListItem{
+			Id:        u.Id,
+			Email:     u.Email,
+			FirstName: u.FirstName.String,
+			LastName:  u.LastName.String,
+			Status:    string(u.Status),
+			Verified:  u.EmailVerifiedAt.Valid,
+			Roles:     []string{},
+			CreatedAt: u.CreatedAt,
+		}
+
+		for _, role := range u.Roles {
+			item.Roles = append(item.Roles, role.Name)
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
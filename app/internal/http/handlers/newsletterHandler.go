@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	appNewsletter "server/internal/application/newsletter"
+	"server/internal/http/handlers/models"
+	"server/util"
+	"server/util/ctxutils"
+	"server/util/httputils"
+	"server/web/templates"
+)
+
+type NewsletterHandler struct {
+	newsletterService *appNewsletter.NewsletterService
+}
+
+func NewNewsletterHandler(newsletterService *appNewsletter.NewsletterService) *NewsletterHandler {
+	return &NewsletterHandler{
+		newsletterService: newsletterService,
+	}
+}
+
+func (h *NewsletterHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	input := new(models.SubscribeResource)
+	result := httputils.ProcessBody(w, r, input)
+	if result.ParsingError != nil {
+		slog.ErrorContext(ctx, result.ParsingError.Error())
+		w.Header().Add("HX-Redirect", "/error")
+		return
+	}
+
+	if result.ValidationErrors != nil {
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		util.Must(templates.InvalidMessage("Въведете валиден имейл", templates.NewsletterErrorId).Render(ctx, w))
+		return
+	}
+
+	// The answer never depends on the address, so the form cannot be used to
+	// find out who is subscribed.
+	if err := h.newsletterService.Subscribe(ctx, input.Email); err != nil {
+		slog.ErrorContext(ctx, "Failed to process a newsletter sign up", "error", err)
+	}
+
+	util.Must(templates.NewsletterPending().Render(ctx, w))
+}
+
+// Confirm consumes the link from the confirmation email.
+func (h *NewsletterHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	err := h.newsletterService.Confirm(ctx, r.URL.Query().Get("token"))
+	if err != nil {
+		if !errors.Is(err, appNewsletter.ErrInvalidToken) {
+			slog.ErrorContext(ctx, "Failed to confirm a newsletter subscription", "error", err)
+		}
+
+		util.Must(templates.SimpleLayout(
+			templates.NewsletterInvalidLink(),
+			"Невалиден линк",
+			"Линкът за потвърждение на абонамента е невалиден или изтекъл.",
+			ctxutils.GetCSRF(ctx),
+		).Render(ctx, w))
+		return
+	}
+
+	util.Must(templates.SimpleLayout(
+		templates.NewsletterConfirmed(),
+		"Абонаментът е потвърден",
+		"Ще получавате седмичния бюлетин на Движи се.",
+		ctxutils.GetCSRF(ctx),
+	).Render(ctx, w))
+}
+
+// GetUnsubscribe asks before unsubscribing. Following the link does nothing on
+// its own: mail scanners open links, and must not unsubscribe anyone by it.
+func (h *NewsletterHandler) GetUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	content := templates.NewsletterInvalidLink()
+	if h.newsletterService.ValidUnsubscribeLink(query.Get("s"), query.Get("sig")) {
+		content = templates.NewsletterUnsubscribe(query.Get("s"), query.Get("sig"))
+	}
+
+	util.Must(templates.SimpleLayout(content, "Отписване от бюлетина", "Отписване от бюлетина на Движи се.", ctxutils.GetCSRF(ctx)).Render(ctx, w))
+}
+
+// Unsubscribe serves both the button on the unsubscribe page and the one-click
+// POST a mail client sends for List-Unsubscribe-Post. The latter expects no
+// page, only a status.
+func (h *NewsletterHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), cancelTime)
+	defer cancel()
+
+	query := r.URL.Query()
+	err := h.newsletterService.Unsubscribe(ctx, query.Get("s"), query.Get("sig"))
+	if err != nil && !errors.Is(err, appNewsletter.ErrInvalidSignature) {
+		slog.ErrorContext(ctx, "Failed to unsubscribe from the newsletter", "error", err)
+		httputils.SendInternalServerResponse(w, r)
+		return
+	}
+
+	if !httputils.IsHTMXRequest(r) {
+		if err != nil {
+			http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err != nil {
+		util.Must(templates.NewsletterInvalidLink().Render(ctx, w))
+		return
+	}
+
+	util.Must(templates.NewsletterUnsubscribed().Render(ctx, w))
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"server/internal/application/spam"
+	"server/util"
+	"server/web/templates"
+)
+
+// SpamChallenge answers a sign in or password form the spam check found
+// suspicious with a question in the form's challenge slot. Only the slot
+// changes, so nothing the visitor typed is lost.
+func SpamChallenge(w http.ResponseWriter, r *http.Request, challenge spam.Challenge) {
+	renderSpamChallenge(w, r, templates.SpamChallengeId, challenge)
+}
+
+// NewsletterSpamChallenge is SpamChallenge for the footer's newsletter form,
+// which has a slot of its own because it shares pages with the other forms.
+func NewsletterSpamChallenge(w http.ResponseWriter, r *http.Request, challenge spam.Challenge) {
+	renderSpamChallenge(w, r, templates.NewsletterChallengeId, challenge)
+}
+
+func renderSpamChallenge(w http.ResponseWriter, r *http.Request, slotId string, challenge spam.Challenge) {
+	w.Header().Set("HX-Reswap", "none")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	util.Must(templates.SpamChallenge(slotId, challenge).Render(r.Context(), w))
+}
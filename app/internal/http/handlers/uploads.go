@@ -9,6 +9,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"server/internal/http/middleware"
+	"server/internal/infrastructure/metrics"
 	"server/util/httputils"
 	"slices"
 	"strings"
@@ -25,6 +26,26 @@ var uploadTime = 5 * time.Minute
 // into 20 MiB of resident memory per request.
 const uploadParseMemory = 4 << 20 // 4 MiB
 
+var (
+	uploadSize = metrics.NewHistogramVec("upload_size_bytes",
+		"Size of files handed to storage, by kind.",
+		metrics.ExponentialBuckets(16<<10, 4, 8), "kind")
+	uploadDuration = metrics.NewHistogramVec("upload_duration_seconds",
+		"Time spent handing a file to storage, by kind and result.",
+		[]float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "kind", "result")
+)
+
+// observeUpload records a finished transfer to storage. Only files that got as
+// far as storage are counted; rejected forms say nothing about upload times.
+func observeUpload(kind string, size int64, started time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	uploadSize.With(kind).Observe(float64(size))
+	uploadDuration.With(kind, result).Observe(time.Since(started).Seconds())
+}
+
 // allowedImageTypes are the formats accepted for images. Detection is by
 // content rather than by the extension or the browser's Content-Type, both of
 // which the client chooses.
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"server/internal/config"
+	"time"
+)
+
+// Access log formats. Logfmt is slog's text output: key=value pairs.
+const (
+	AccessLogJSON   = "json"
+	AccessLogLogfmt = "logfmt"
+	AccessLogOff    = "off"
+)
+
+// redactedParams are query parameters that carry secrets: reset, verification
+// and unsubscribe links all put a token in the URL, and the log must not
+// become a list of working links. Unsubscribe links are a subscriber id (s)
+// and its signature (sig), and never expire.
+var redactedParams = map[string]bool{
+	"token":    true,
+	"password": true,
+	"secret":   true,
+	"s":        true,
+	"sig":      true,
+}
+
+// NewAccessLogger writes one record per line to w in the given format. It is
+// separate from the application log so that either can be shipped or
+// rotated on its own.
+func NewAccessLogger(w io.Writer, format string) (*slog.Logger, error) {
+	switch format {
+	case AccessLogJSON:
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	case AccessLogLogfmt:
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", format)
+	}
+}
+
+// AccessLog records every request once it is done. It sits near the top of
+// the chain so the duration and byte count cover compression, and reads the
+// route, request id and user that the middleware below it find out.
+//
+// Successful static asset hits are the bulk of the traffic and the least
+// interesting part of it, so only staticSample of them (0 to 1) are logged.
+// Failed ones always are.
+func AccessLog(logger *slog.Logger, staticSample float64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			req, facts := withRequestFacts(req)
+			recorder := &statusRecorder{ResponseWriter: writer}
+
+			completed := false
+			defer func() {
+				status := recorder.status
+				switch {
+				case !completed:
+					// A panic on its way to Recovery.
+					status = http.StatusInternalServerError
+				case status == 0:
+					status = http.StatusOK
+				}
+				if isStaticAssetPath(req.URL.Path) && status < http.StatusBadRequest && rand.Float64() >= staticSample {
+					return
+				}
+
+				route := facts.route
+				if route == "" {
+					route = unmatchedRoute
+				}
+
+				attrs := []slog.Attr{
+					slog.String("method", req.Method),
+					slog.String("route", route),
+					slog.String("path", redactedPath(req.URL)),
+					slog.Int("status", status),
+					slog.Int("bytes", recorder.bytes),
+					slog.Float64("durationMs", float64(time.Since(start).Microseconds())/1000),
+					slog.String("ip", getClientIP(req, config.TrustedProxies())),
+					slog.String("requestId", facts.requestId),
+				}
+				if facts.userId != "" {
+					attrs = append(attrs, slog.String("userId", facts.userId))
+				}
+				if encoding := recorder.Header().Get("Content-Encoding"); encoding != "" {
+					attrs = append(attrs, slog.String("compression", encoding))
+				}
+				if cache := cacheOutcome(status, recorder.Header()); cache != "" {
+					attrs = append(attrs, slog.String("cache", cache))
+				}
+
+				// A background context: the request's may already be cancelled,
+				// and the request id is logged explicitly above.
+				logger.LogAttrs(context.Background(), slog.LevelInfo, "request", attrs...)
+			}()
+
+			next.ServeHTTP(recorder, req)
+			completed = true
+		})
+	}
+}
+
+// redactedPath is the path and query with secret parameters blanked.
+func redactedPath(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+
+	query := u.Query()
+	for name := range query {
+		if redactedParams[name] {
+			query[name] = []string{"REDACTED"}
+		}
+	}
+
+	return u.Path + "?" + query.Encode()
+}
+
+// cacheOutcome says how the response stood with the browser's cache: a hit
+// when the copy it had was still good, a miss when an asset that could have
+// been revalidated was sent in full. Pages carry no validator and get none.
+func cacheOutcome(status int, header http.Header) string {
+	switch {
+	case status == http.StatusNotModified:
+		return "hit"
+	case header.Get("ETag") != "":
+		return "miss"
+	}
+	return ""
+}
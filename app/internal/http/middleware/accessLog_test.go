@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestAccessLog(t *testing.T, staticSample float64, next http.Handler) (http.Handler, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	logger, err := NewAccessLogger(&out, AccessLogJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return AccessLog(logger, staticSample)(next), &out
+}
+
+// Reset and verification links carry a working token; the log must not.
+func TestAccessLogRedactsTokens(t *testing.T) {
+	handler, out := newTestAccessLog(t, 1, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reset-password?token=abc123&lang=bg", nil))
+
+	if strings.Contains(out.String(), "abc123") {
+		t.Fatalf("token leaked into the log: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "token=REDACTED") || !strings.Contains(out.String(), "lang=bg") {
+		t.Errorf("query not kept apart from the token: %s", out.String())
+	}
+}
+
+// An unsubscribe link never expires, so neither half of it may be kept.
+func TestAccessLogRedactsUnsubscribeLinks(t *testing.T) {
+	handler, out := newTestAccessLog(t, 1, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/newsletter/unsubscribe?s=5f0c6f0e-subscriber&sig=c2lnbmF0dXJl", nil))
+
+	if strings.Contains(out.String(), "5f0c6f0e-subscriber") || strings.Contains(out.String(), "c2lnbmF0dXJl") {
+		t.Fatalf("unsubscribe link leaked into the log: %s", out.String())
+	}
+}
+
+// The route, request id and user are only known inside the chain, and have
+// to reach the record through the shared facts.
+func TestAccessLogRecordsInnerFacts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /posts/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		facts := factsFrom(r.Context())
+		facts.requestId = "req-1"
+		facts.userId = "user-1"
+		w.Write([]byte("hello"))
+	})
+	handler, out := newTestAccessLog(t, 1, RecordRoute(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/first", nil))
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("not one JSON record: %v\n%s", err, out.String())
+	}
+	want := map[string]any{
+		"route":     "/posts/{slug}",
+		"path":      "/posts/first",
+		"requestId": "req-1",
+		"userId":    "user-1",
+		"status":    float64(200),
+		"bytes":     float64(5),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
+
+// Unsampled static hits are dropped, but a missing asset is a broken page
+// and always logged.
+func TestAccessLogSamplesOnlySuccessfulStatic(t *testing.T) {
+	handler, out := newTestAccessLog(t, 0, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "missing.css") {
+			http.NotFound(w, r)
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/static/site.css", nil))
+	if out.Len() != 0 {
+		t.Fatalf("sampled-out hit logged: %s", out.String())
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/static/missing.css", nil))
+	if !strings.Contains(out.String(), `"status":404`) {
+		t.Errorf("failed static request not logged: %s", out.String())
+	}
+}
@@ -1,23 +1,34 @@
 package middleware
 
 import (
+	"log/slog"
 	"net/http"
 	"server/internal/domain/user"
 	"server/util/ctxutils"
 )
 
 func RequireAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		loggedUser, err := ctxutils.GetUser(r.Context())
-		if err != nil || loggedUser == nil {
-			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
-			return
-		}
+	return RequireAuthAt("/admin/login")(next)
+}
 
-		next.ServeHTTP(w, r)
-	})
+// RequireAuthAt is RequireAuth for pages whose visitors sign in somewhere
+// other than the admin login.
+func RequireAuthAt(loginPath string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			loggedUser, err := ctxutils.GetUser(r.Context())
+			if err != nil || loggedUser == nil {
+				http.Redirect(w, r, loginPath, http.StatusSeeOther)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
+// RequireAdmin checks for the ADMIN role itself. Routes are guarded with
+// RequirePermission instead, so that EDITOR and AUTHOR can be let in too.
 func RequireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		loggedUser, err := ctxutils.GetUser(r.Context())
@@ -34,3 +45,29 @@ func RequireAdmin(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequirePermission lets the request through when the user holds at least one
+// of the given permissions, and answers 403 otherwise. Checks that depend on
+// the resource, such as editing only one's own post, stay in the handler.
+func RequirePermission(permissions ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			loggedUser, err := ctxutils.GetUser(r.Context())
+			if err != nil || loggedUser == nil {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			for _, permission := range permissions {
+				if user.HasPermission(loggedUser.Permissions, permission) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			slog.WarnContext(r.Context(), "Refused a request without the required permission",
+				"userId", loggedUser.Id, "path", r.URL.Path, "required", permissions)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+}
@@ -272,3 +272,90 @@ func TestRequireAdmin_AdminRoleCaseSensitive(t *testing.T) {
 		})
 	}
 }
+
+// Public accounts sign in at /login, so sending them to the admin login would
+// strand them on a form that rejects them.
+func TestRequireAuthAt_RedirectsToGivenLogin(t *testing.T) {
+	handler := RequireAuthAt("/login")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("RequireAuthAt should not call next handler for unauthenticated user")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/account", nil))
+
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+
+	if location := w.Header().Get("Location"); location != "/login" {
+		t.Errorf("Redirect location = %q, want /login", location)
+	}
+}
+
+func TestRequirePermission_AnyOfTheGivenPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []user.Permission
+		expected    int
+	}{
+		{"holds the first", []user.Permission{{Name: user.PermPostsEdit}}, http.StatusOK},
+		{"holds the second", []user.Permission{{Name: user.PermPostsEditOwn}}, http.StatusOK},
+		{"holds neither", []user.Permission{{Name: user.PermPostsCreate}}, http.StatusForbidden},
+		{"holds none", nil, http.StatusForbidden},
+	}
+
+	handler := RequirePermission(user.PermPostsEdit, user.PermPostsEditOwn)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/posts/1", nil)
+			req = req.WithContext(ctxutils.WithLoggedUser(req.Context(), &securityutil.LoggedInUser{
+				Id:          uuid.New().String(),
+				Permissions: tt.permissions,
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Status = %d, want %d", w.Code, tt.expected)
+			}
+		})
+	}
+}
+
+// Holding the ADMIN role is no longer enough by itself: what a route allows is
+// decided by the permissions the role carries.
+func TestRequirePermission_RoleWithoutPermissionIsRefused(t *testing.T) {
+	handler := RequirePermission(user.PermUsersManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("RequirePermission should not call next handler without the permission")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req = req.WithContext(ctxutils.WithLoggedUser(req.Context(), &securityutil.LoggedInUser{
+		Id:    uuid.New().String(),
+		Roles: []user.Role{{Name: user.RoleAdmin}},
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestRequirePermission_NotAuthenticated(t *testing.T) {
+	handler := RequirePermission(user.PermAdminAccess)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("RequirePermission should not call next handler for unauthenticated user")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -45,6 +45,10 @@ func CheckAuth(sessions SessionValidator) Middleware {
 					continue
 				}
 
+				if facts := factsFrom(r.Context()); facts != nil {
+					facts.userId = loggedInUser.Id
+				}
+
 				h.ServeHTTP(w, r.WithContext(ctxutils.WithLoggedUser(r.Context(), loggedInUser)))
 				return
 			}
@@ -54,10 +58,11 @@ func CheckAuth(sessions SessionValidator) Middleware {
 	}
 }
 
-// isStaticAssetPath reports whether the request is for a file under /static,
-// which is public and identical for every visitor.
+// isStaticAssetPath reports whether the request is for a file under /static
+// or an upload under /media, which are public and identical for every
+// visitor.
 func isStaticAssetPath(path string) bool {
-	return strings.HasPrefix(path, "/static/")
+	return strings.HasPrefix(path, "/static/") || strings.HasPrefix(path, "/media/")
 }
 
 // authTokens returns the candidate tokens in precedence order: the bearer
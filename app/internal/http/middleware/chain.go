@@ -1,13 +1,22 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"server/internal/infrastructure/tracing"
+)
 
 type Middleware func(http.Handler) http.Handler
 
+// CreateChain composes middlewares, the first one outermost. With tracing on
+// each gets its own span; with it off they are composed as they are, sparing
+// every request a dozen no-op spans.
 func CreateChain(middlewares ...Middleware) Middleware {
 	return func(next http.Handler) http.Handler {
 		for i := len(middlewares) - 1; i >= 0; i-- {
 			middleware := middlewares[i]
+			if tracing.Enabled() {
+				middleware = traceMiddleware(middleware)
+			}
 			next = middleware(next)
 		}
 
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"server/internal/config"
+	"server/util/ctxutils"
+)
+
+// PopulateClientInfo records the caller's address and user agent on the
+// context, so code far from the request, such as the audit trail, can say
+// where an action came from. The address is resolved the same way the rate
+// limiter resolves it.
+func PopulateClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx := ctxutils.WithClient(req.Context(), ctxutils.Client{
+			IP:        getClientIP(req, config.TrustedProxies()),
+			UserAgent: req.UserAgent(),
+		})
+
+		next.ServeHTTP(writer, req.WithContext(ctx))
+	})
+}
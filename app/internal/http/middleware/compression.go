@@ -5,9 +5,20 @@ import (
 	"compress/gzip"
 	"io"
 	"net/http"
+	"server/internal/infrastructure/metrics"
 	"strings"
 )
 
+var (
+	compressionRatio = metrics.NewHistogramVec("http_compression_ratio",
+		"Compressed size over original size of each compressed response, by encoding.",
+		[]float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}, "encoding")
+	compressionIn = metrics.NewCounterVec("http_compression_input_bytes_total",
+		"Bytes of response bodies before compression, by encoding.", "encoding")
+	compressionOut = metrics.NewCounterVec("http_compression_output_bytes_total",
+		"Bytes of response bodies after compression, by encoding.", "encoding")
+)
+
 const (
 	acceptEncodingHeader  = "Accept-Encoding"
 	contentEncodingHeader = "Content-Encoding"
@@ -26,6 +37,22 @@ type compressedResponseWriter struct {
 	encoding    string
 	compressor  io.WriteCloser
 	wroteHeader bool
+
+	// bytesIn and out measure what compression saved.
+	bytesIn int
+	out     *countingWriter
+}
+
+// countingWriter counts the compressed bytes on their way to the client.
+type countingWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (cw *countingWriter) Write(payload []byte) (int, error) {
+	n, err := cw.w.Write(payload)
+	cw.count += n
+	return n, err
 }
 
 func (crw *compressedResponseWriter) WriteHeader(status int) {
@@ -42,11 +69,12 @@ func (crw *compressedResponseWriter) WriteHeader(status int) {
 		// The compressed length is not known ahead of time.
 		crw.Header().Del(contentLengthHeader)
 
+		crw.out = &countingWriter{w: crw.ResponseWriter}
 		switch crw.encoding {
 		case gzipEncoding:
-			crw.compressor = gzip.NewWriter(crw.ResponseWriter)
+			crw.compressor = gzip.NewWriter(crw.out)
 		case deflateEncoding:
-			if flateWriter, err := flate.NewWriter(crw.ResponseWriter, flate.BestCompression); err == nil {
+			if flateWriter, err := flate.NewWriter(crw.out, flate.BestCompression); err == nil {
 				crw.compressor = flateWriter
 			}
 		}
@@ -61,7 +89,9 @@ func (crw *compressedResponseWriter) Write(payload []byte) (int, error) {
 	}
 
 	if crw.compressor != nil {
-		return crw.compressor.Write(payload)
+		n, err := crw.compressor.Write(payload)
+		crw.bytesIn += n
+		return n, err
 	}
 
 	return crw.ResponseWriter.Write(payload)
@@ -79,8 +109,15 @@ func (crw *compressedResponseWriter) Flush() {
 }
 
 func (crw *compressedResponseWriter) Close() {
-	if crw.compressor != nil {
-		_ = crw.compressor.Close()
+	if crw.compressor == nil {
+		return
+	}
+	_ = crw.compressor.Close()
+
+	if crw.bytesIn > 0 {
+		compressionRatio.With(crw.encoding).Observe(float64(crw.out.count) / float64(crw.bytesIn))
+		compressionIn.With(crw.encoding).Add(float64(crw.bytesIn))
+		compressionOut.With(crw.encoding).Add(float64(crw.out.count))
 	}
 }
 
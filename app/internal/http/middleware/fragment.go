@@ -15,6 +15,12 @@ import (
 // header keeps the fragment out of search results even when it is fetched
 // directly.
 func FragmentOnly(hostPage string, next http.Handler) http.Handler {
+	return FragmentOf(func(*http.Request) string { return hostPage }, next)
+}
+
+// FragmentOf is FragmentOnly for a fragment whose host page depends on the
+// request, such as the comments of one particular post.
+func FragmentOf(hostPage func(r *http.Request) string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Robots-Tag", "noindex")
 
@@ -23,7 +29,7 @@ func FragmentOnly(hostPage string, next http.Handler) http.Handler {
 			return
 		}
 
-		target := hostPage
+		target := hostPage(r)
 		if r.URL.RawQuery != "" {
 			target += "?" + r.URL.RawQuery
 		}
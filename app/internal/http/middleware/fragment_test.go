@@ -75,3 +75,18 @@ func TestFragmentOnly_MarksResponsesNoindex(t *testing.T) {
 		}
 	}
 }
+
+// The comments of a post belong on that post's page, which only the request
+// can say.
+func TestFragmentOf_RedirectsToThePageTheRequestNames(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/blog/comments/fitnes", nil)
+	req.SetPathValue("slug", "fitnes")
+	w := httptest.NewRecorder()
+
+	hostPage := func(r *http.Request) string { return "/blog/" + r.PathValue("slug") }
+	FragmentOf(hostPage, fragmentHandler()).ServeHTTP(w, req)
+
+	if location := w.Header().Get("Location"); location != "/blog/fitnes" {
+		t.Errorf("Location = %q, want /blog/fitnes", location)
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/infrastructure/metrics"
+	"server/internal/infrastructure/tracing"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("http_requests_total",
+		"HTTP requests served, by method, route pattern and status code.", "method", "route", "code")
+	httpDuration = metrics.NewHistogramVec("http_request_duration_seconds",
+		"Time to serve an HTTP request, by method and route pattern.", metrics.DefBuckets, "method", "route")
+	httpInFlight = metrics.NewGaugeVec("http_requests_in_flight",
+		"HTTP requests being served.").With()
+)
+
+// unmatchedRoute labels requests no pattern matched. Their paths are whatever
+// a client sent, so they must not become labels.
+const unmatchedRoute = "unmatched"
+
+// statusRecorder remembers the status code a handler sent and counts the
+// bytes of the body.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(payload []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(payload)
+	sr.bytes += n
+	return n, err
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Metrics counts and times every request by its route pattern. It sits near
+// the top of the chain so the time includes compression and the rest of the
+// middleware; the pattern is only known once the mux has matched, so
+// RecordRoute passes it back up.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		req, facts := withRequestFacts(req)
+		recorder := &statusRecorder{ResponseWriter: writer}
+
+		httpInFlight.Add(1)
+		completed := false
+		defer func() {
+			httpInFlight.Add(-1)
+
+			status := recorder.status
+			switch {
+			case !completed:
+				// A panic on its way to Recovery.
+				status = http.StatusInternalServerError
+			case status == 0:
+				status = http.StatusOK
+			}
+
+			label := facts.route
+			if label == "" {
+				label = unmatchedRoute
+			}
+			httpRequests.With(req.Method, label, strconv.Itoa(status)).Inc()
+			httpDuration.With(req.Method, label).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(recorder, req)
+		completed = true
+	})
+}
+
+// RecordRoute wraps the mux, runs the handler in its own span and hands the
+// pattern it matched to Metrics, Trace and AccessLog. The method is dropped from the
+// pattern; both record it separately.
+func RecordRoute(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx, span := tracing.Start(req.Context(), "handler")
+		defer span.End()
+
+		// The mux records the pattern on the request it is given.
+		req = req.WithContext(ctx)
+		mux.ServeHTTP(writer, req)
+
+		pattern := req.Pattern
+		if _, path, hasMethod := strings.Cut(pattern, " "); hasMethod {
+			pattern = path
+		}
+		if pattern == "" {
+			span.SetName("handler " + unmatchedRoute)
+			return
+		}
+		span.SetName("handler " + req.Method + " " + pattern)
+
+		if facts := factsFrom(req.Context()); facts != nil {
+			facts.route = pattern
+		}
+	})
+}
+
+// RequireBearerToken lets a request through only if it carries the token in
+// an Authorization header. Scrapers cannot log in, so the usual session checks
+// do not apply to the routes this guards.
+func RequireBearerToken(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		got := []byte(req.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(writer, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/infrastructure/metrics"
+)
+
+// Requests must be labelled by the pattern that matched, not the path;
+// otherwise every post id becomes its own series.
+func TestMetricsLabelsRequestsByPattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := Metrics(RecordRoute(mux))
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test-missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var out bytes.Buffer
+	metrics.Default.Write(&out)
+	scrape := out.String()
+
+	if !strings.Contains(scrape, `http_requests_total{method="GET",route="/metrics-test/{id}",code="418"} 2`) {
+		t.Errorf("pattern series missing:\n%s", scrape)
+	}
+	if strings.Contains(scrape, `route="/metrics-test/1"`) {
+		t.Error("a raw path became a label")
+	}
+	if !strings.Contains(scrape, `route="unmatched",code="404"`) {
+		t.Error("unmatched request not counted as unmatched")
+	}
+}
+
+// The token is the only thing between the public internet and the metrics.
+func TestRequireBearerToken(t *testing.T) {
+	handler := RequireBearerToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		header string
+		want   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("Authorization %q: got %d, want %d", tt.header, rec.Code, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha1"
 	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"log/slog"
 	"mime"
@@ -15,6 +16,7 @@ import (
 	"server/util/ctxutils"
 	"server/util/httputils"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/xsrftoken"
@@ -81,6 +83,14 @@ func CSRFCookie(next http.Handler) http.Handler {
 	})
 }
 
+// csrfExempt lists the unsafe requests that carry their own proof instead of
+// a CSRF token. One-click unsubscribe (RFC 8058) is posted by the reader's mail
+// client, which has neither the cookie nor the header; the signature in its
+// link is what authorises it.
+var csrfExempt = map[string]bool{
+	"POST /newsletter/unsubscribe": true,
+}
+
 func CSRFValidate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
@@ -88,6 +98,11 @@ func CSRFValidate(next http.Handler) http.Handler {
 			return
 		}
 
+		if csrfExempt[r.Method+" "+r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		cookie, err := r.Cookie(string(httputils.XSRFCookieName))
 		if err != nil {
 			csrfError(w, r, "No CSRF token")
@@ -163,12 +178,15 @@ func SecurityHeaders(next http.Handler) http.Handler {
 // adminCSP is looser than the public policy: TinyMCE is loaded from its CDN and
 // the post form carries inline scripts, so 'unsafe-inline' is still required
 // here. Tightening it means moving that inline JS into a static file.
+//
+// Both policies take the storage origin as %[1]s, so uploads load from a
+// bucket served over plain http, as a local MinIO is.
 const adminCSP = `
 			  default-src 'self';
 			  script-src 'self' 'unsafe-inline' https://cdn.tiny.cloud;
 			  style-src 'self' 'unsafe-inline' https://cdn.tiny.cloud;
 			  font-src 'self';
-			  img-src 'self' https: data: blob:;
+			  img-src 'self' https: data: blob: %[1]s;
 			  connect-src 'self' https://cdn.tiny.cloud;
 			  frame-ancestors 'none';
 			  form-action 'self';
@@ -181,17 +199,26 @@ const publicCSP = `
 			  script-src 'self';
 			  style-src 'self' 'unsafe-inline';
 			  font-src 'self';
-			  img-src 'self' https: data:;
-			  connect-src 'self' https://res.cloudinary.com;
+			  img-src 'self' https: data: %[1]s;
+			  connect-src 'self' https://res.cloudinary.com %[1]s;
 			  frame-ancestors 'none';
 			  form-action 'self';
 			  object-src 'none';`
 
+// policies fills in the storage origin once. Cloudinary stays in the public
+// connect-src regardless: GPX tracks of posts written before a switch of
+// backend are still fetched from there.
+var policies = sync.OnceValues(func() (public, admin string) {
+	origin := config.StorageOrigin()
+	return fmt.Sprintf(publicCSP, origin), fmt.Sprintf(adminCSP, origin)
+})
+
 func ContentSecurityPolicy(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cspHeader := publicCSP
+		public, admin := policies()
+		cspHeader := public
 		if strings.HasPrefix(r.URL.Path, "/admin") {
-			cspHeader = adminCSP
+			cspHeader = admin
 		}
 
 		w.Header().Set("Content-Security-Policy", cspHeader)
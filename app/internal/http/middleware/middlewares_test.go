@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Mail clients post one-click unsubscribes without our cookies, so that one
+// route must get through; its neighbours must still be refused.
+func TestCSRFValidate_ExemptsOnlyOneClickUnsubscribe(t *testing.T) {
+	handler := CSRFValidate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for target, want := range map[string]int{
+		"/newsletter/unsubscribe?s=id&sig=sig": http.StatusNoContent,
+		"/newsletter/subscribe":                http.StatusForbidden,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, target, nil))
+
+		if rr.Code != want {
+			t.Errorf("POST %s: expected %d, got %d", target, want, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/newsletter/unsubscribe", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected other methods on the path to need a token, got %d", rr.Code)
+	}
+}
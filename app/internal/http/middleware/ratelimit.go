@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/netip"
 	"server/internal/config"
+	"server/internal/infrastructure/metrics"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -16,7 +18,28 @@ import (
 // the map until the next sweep - or without bound if they keep arriving.
 const maxTrackedClients = 50_000
 
+var (
+	rateLimitRejections = metrics.NewCounterVec("ratelimit_rejections_total",
+		"Requests refused by a rate limiter.", "limiter")
+	rateLimitEvictions = metrics.NewCounterVec("ratelimit_evictions_total",
+		"Clients dropped from a full rate limiter while their window was still open.", "limiter")
+
+	// rateLimiters lets the scrape count the clients each limiter tracks.
+	// Limiters are made while the routes are registered and live as long as
+	// the process.
+	rateLimiters   []*RateLimiter
+	rateLimitersMu sync.Mutex
+)
+
+func init() {
+	metrics.NewGaugeFunc("ratelimit_tracked_clients", "Clients a rate limiter is tracking, summed over limiters of the same name.",
+		[]string{"limiter"}, collectTrackedClients)
+}
+
 type RateLimiter struct {
+	// name labels the limiter's metrics; limiters built for the same purpose
+	// share one.
+	name       string
 	requests   map[string]*clientRequests
 	mu         sync.RWMutex
 	limit      int
@@ -29,14 +52,19 @@ type clientRequests struct {
 	firstSeen time.Time
 }
 
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+func NewRateLimiter(name string, limit int, window time.Duration) *RateLimiter {
 	rl := &RateLimiter{
+		name:       name,
 		requests:   make(map[string]*clientRequests),
 		limit:      limit,
 		window:     window,
 		maxClients: maxTrackedClients,
 	}
 
+	rateLimitersMu.Lock()
+	rateLimiters = append(rateLimiters, rl)
+	rateLimitersMu.Unlock()
+
 	// Start cleanup goroutine
 	go rl.cleanup()
 
@@ -86,6 +114,33 @@ func (rl *RateLimiter) isAllowed(ip string) bool {
 	return true
 }
 
+// Count records a hit for key and returns how many hits it has had in the
+// current window, this one included. Nothing is refused: callers that only
+// want to know how busy a key is (the spam check's velocity and repeat
+// signals) read the number and decide for themselves. The limit is ignored.
+func (rl *RateLimiter) Count(key string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	client, exists := rl.requests[key]
+
+	if !exists || now.Sub(client.firstSeen) > rl.window {
+		if !exists {
+			rl.makeRoomLocked(now)
+		}
+
+		rl.requests[key] = &clientRequests{
+			count:     1,
+			firstSeen: now,
+		}
+		return 1
+	}
+
+	client.count++
+	return client.count
+}
+
 // makeRoomLocked keeps the map under its ceiling before a new client is added.
 // Expired entries go first; if that is not enough, the oldest surviving entry
 // is dropped. Evicting beats refusing to track, which would let an attacker
@@ -118,6 +173,29 @@ func (rl *RateLimiter) makeRoomLocked(now time.Time) {
 
 	if oldestIP != "" {
 		delete(rl.requests, oldestIP)
+		rateLimitEvictions.With(rl.name).Inc()
+	}
+}
+
+func collectTrackedClients(emit func(float64, ...string)) {
+	rateLimitersMu.Lock()
+	limiters := slices.Clone(rateLimiters)
+	rateLimitersMu.Unlock()
+
+	tracked := map[string]int{}
+	for _, rl := range limiters {
+		rl.mu.RLock()
+		tracked[rl.name] += len(rl.requests)
+		rl.mu.RUnlock()
+	}
+
+	names := make([]string, 0, len(tracked))
+	for name := range tracked {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		emit(float64(tracked[name]), name)
 	}
 }
 
@@ -127,6 +205,7 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 
 		if !rl.isAllowed(ip) {
 			slog.WarnContext(r.Context(), "Rate limit exceeded", "ip", ip, "path", r.URL.Path)
+			rateLimitRejections.With(rl.name).Inc()
 			http.Error(w, "Too many requests. Please try again later.", http.StatusTooManyRequests)
 			return
 		}
@@ -201,15 +280,27 @@ func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
 
 // AuthRateLimiter - strict rate limiting for auth endpoints (5 requests per minute)
 func AuthRateLimiter() *RateLimiter {
-	return NewRateLimiter(5, time.Minute)
+	return NewRateLimiter("auth", 5, time.Minute)
 }
 
 // PasswordResetRateLimiter - very strict for password reset (3 requests per 5 minutes)
 func PasswordResetRateLimiter() *RateLimiter {
-	return NewRateLimiter(3, 5*time.Minute)
+	return NewRateLimiter("password_reset", 3, 5*time.Minute)
+}
+
+// CommentRateLimiter - for posting comments (5 requests per 10 minutes). Enough
+// for a conversation, too few to flood the moderation queue from one address
+func CommentRateLimiter() *RateLimiter {
+	return NewRateLimiter("comment", 5, 10*time.Minute)
+}
+
+// ContactRateLimiter - for the contact form (3 requests per 10 minutes). A
+// reader rarely has more to say than that, and each message waits for a person
+func ContactRateLimiter() *RateLimiter {
+	return NewRateLimiter("contact", 3, 10*time.Minute)
 }
 
 // APIRateLimiter - more permissive for general API (100 requests per minute)
 func APIRateLimiter() *RateLimiter {
-	return NewRateLimiter(100, time.Minute)
+	return NewRateLimiter("api", 100, time.Minute)
 }
@@ -10,7 +10,7 @@ import (
 )
 
 func TestNewRateLimiter(t *testing.T) {
-	rl := NewRateLimiter(5, time.Minute)
+	rl := NewRateLimiter("test", 5, time.Minute)
 	if rl == nil {
 		t.Fatal("NewRateLimiter() should return non-nil")
 	}
@@ -78,6 +78,33 @@ func TestRateLimiter_WindowReset(t *testing.T) {
 	}
 }
 
+// The spam check reads Count to measure velocity; it must keep counting past
+// the limit instead of stopping where isAllowed would refuse, and restart once
+// the window lapses.
+func TestRateLimiter_Count(t *testing.T) {
+	rl := &RateLimiter{
+		requests: make(map[string]*clientRequests),
+		limit:    2,
+		window:   50 * time.Millisecond,
+	}
+
+	for want := 1; want <= 4; want++ {
+		if got := rl.Count("key"); got != want {
+			t.Errorf("Count() = %d, want %d", got, want)
+		}
+	}
+
+	if got := rl.Count("other"); got != 1 {
+		t.Errorf("Count() for another key = %d, want 1", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if got := rl.Count("key"); got != 1 {
+		t.Errorf("Count() after the window = %d, want 1", got)
+	}
+}
+
 func TestRateLimiter_Middleware(t *testing.T) {
 	rl := &RateLimiter{
 		requests: make(map[string]*clientRequests),
@@ -244,6 +271,16 @@ func TestPasswordResetRateLimiter(t *testing.T) {
 	}
 }
 
+func TestCommentRateLimiter(t *testing.T) {
+	rl := CommentRateLimiter()
+	if rl.limit != 5 {
+		t.Errorf("CommentRateLimiter limit = %d, want 5", rl.limit)
+	}
+	if rl.window != 10*time.Minute {
+		t.Errorf("CommentRateLimiter window = %v, want %v", rl.window, 10*time.Minute)
+	}
+}
+
 func TestAPIRateLimiter(t *testing.T) {
 	rl := APIRateLimiter()
 	if rl.limit != 100 {
@@ -357,7 +394,7 @@ func TestRateLimiter_PrefersEvictingExpiredEntries(t *testing.T) {
 
 // NewRateLimiter must set a ceiling; a zero value one is explicitly unbounded.
 func TestNewRateLimiter_SetsClientCeiling(t *testing.T) {
-	if rl := NewRateLimiter(5, time.Minute); rl.maxClients <= 0 {
+	if rl := NewRateLimiter("test", 5, time.Minute); rl.maxClients <= 0 {
 		t.Errorf("maxClients = %d, want a positive ceiling", rl.maxClients)
 	}
 }
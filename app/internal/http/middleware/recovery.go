@@ -13,6 +13,7 @@ func Recovery(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				slog.ErrorContext(req.Context(), "Caught panic", "panic", err, "stack", string(debug.Stack()))
+				tracePanic(req, err)
 
 				// Content-Type belongs on the response. Setting it on req did
 				// nothing, and SendInternalServerResponse writes its own anyway.
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// requestFacts carries what the inner middleware and the mux learn about a
+// request back out to the middleware that reports on it. Metrics, Trace and
+// AccessLog wrap the chain, so the route, the request id and the user are
+// all decided after they start and would otherwise be out of their reach.
+type requestFacts struct {
+	route     string
+	requestId string
+	userId    string
+}
+
+type factsKey struct{}
+
+// withRequestFacts returns the request with somewhere for the inner
+// middleware to leave what they find, reusing the one an outer middleware
+// already made.
+func withRequestFacts(req *http.Request) (*http.Request, *requestFacts) {
+	if facts := factsFrom(req.Context()); facts != nil {
+		return req, facts
+	}
+
+	facts := &requestFacts{}
+	return req.WithContext(context.WithValue(req.Context(), factsKey{}, facts)), facts
+}
+
+// factsFrom returns nil when no reporting middleware is listening.
+func factsFrom(ctx context.Context) *requestFacts {
+	facts, _ := ctx.Value(factsKey{}).(*requestFacts)
+	return facts
+}
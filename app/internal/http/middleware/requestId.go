@@ -4,8 +4,13 @@ import (
 	"net/http"
 	"server/util/ctxutils"
 	"server/util/httputils"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+// PopulateRequestId gives the request an id for the logs and audit trail.
+// Inside a trace the trace id is used, so a log line leads straight to the
+// trace and back.
 func PopulateRequestId(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
@@ -15,10 +20,19 @@ func PopulateRequestId(next http.Handler) http.Handler {
 			return
 		}
 
-		id, err := ctxutils.NewRequestId()
-		if err != nil {
-			httputils.SendInternalServerResponse(writer, req)
-			return
+		var id string
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+			id = spanContext.TraceID().String()
+		} else {
+			var err error
+			if id, err = ctxutils.NewRequestId(); err != nil {
+				httputils.SendInternalServerResponse(writer, req)
+				return
+			}
+		}
+
+		if facts := factsFrom(ctx); facts != nil {
+			facts.requestId = id
 		}
 
 		ctx = ctxutils.WithRequestId(ctx, id)
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"server/internal/application/spam"
+	"server/internal/config"
+)
+
+// SpamCheck puts public form posts in front of the spam guard. It reads the
+// submission, adds what only the server can see - how busy the sender and the
+// text have been lately - and acts on the verdict in one of two ways: Hold
+// passes it to the handler, Challenge asks a question first.
+type SpamCheck struct {
+	guard *spam.Guard
+
+	// The counters reuse the rate limiter's bookkeeping but never refuse;
+	// their counts feed the score instead.
+	senders *RateLimiter
+	texts   *RateLimiter
+
+	// answers remembers spent challenge tokens so one solved challenge cannot
+	// be replayed by a script. The window outlasts the challenge itself.
+	answers *RateLimiter
+}
+
+func NewSpamCheck(guard *spam.Guard) *SpamCheck {
+	return &SpamCheck{
+		guard:   guard,
+		senders: NewRateLimiter("spam_senders", 0, 10*time.Minute),
+		texts:   NewRateLimiter("spam_texts", 0, time.Hour),
+		answers: NewRateLimiter("spam_answers", 0, 15*time.Minute),
+	}
+}
+
+// Hold scores the submission and hands the verdict to the handler through the
+// context. Nothing is refused here: the handler is expected to keep a
+// suspicious submission out of sight until a moderator has looked at it.
+func (s *SpamCheck) Hold(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assessment, _, err := s.assess(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if assessment.Suspicious {
+			slog.InfoContext(r.Context(), "Spam check held a submission", "path", r.URL.Path, "score", assessment.Score, "reasons", assessment.Reasons)
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), assessmentKey{}, assessment)))
+	})
+}
+
+type assessmentKey struct{}
+
+// SpamAssessmentFrom returns what Hold made of the request. Requests it did
+// not look at come back as not suspicious.
+func SpamAssessmentFrom(ctx context.Context) spam.Assessment {
+	assessment, _ := ctx.Value(assessmentKey{}).(spam.Assessment)
+	return assessment
+}
+
+// Challenge lets an unsuspicious submission through and answers a suspicious
+// one with a question, rendered by render. The form is sent again with the
+// answer, and a right answer lets it through whatever the score. Middleware
+// cannot import the templates, hence the renderer.
+func (s *SpamCheck) Challenge(render func(w http.ResponseWriter, r *http.Request, challenge spam.Challenge)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assessment, fields, err := s.assess(r)
+			if err != nil || !assessment.Suspicious || s.solved(fields) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			slog.WarnContext(r.Context(), "Spam check challenged a submission", "path", r.URL.Path, "score", assessment.Score, "reasons", assessment.Reasons)
+			render(w, r, spam.NewChallenge())
+		})
+	}
+}
+
+func (s *SpamCheck) assess(r *http.Request) (spam.Assessment, map[string]string, error) {
+	fields, err := readFields(r)
+	if err != nil {
+		return spam.Assessment{}, nil, err
+	}
+
+	text := submissionText(fields)
+
+	repeats := 0
+	if normalized := strings.Join(strings.Fields(strings.ToLower(text)), " "); normalized != "" {
+		sum := sha256.Sum256([]byte(normalized))
+		repeats = s.texts.Count(hex.EncodeToString(sum[:]))
+	}
+
+	assessment := s.guard.Assess(r.Context(), spam.Submission{
+		Text:      text,
+		Honeypot:  fields[spam.HoneypotField],
+		FormToken: fields[spam.FormTokenField],
+		Velocity:  s.senders.Count(getClientIP(r, config.TrustedProxies())),
+		Repeats:   repeats,
+	})
+
+	return assessment, fields, nil
+}
+
+// solved reports whether the submission carries a right answer to a challenge
+// that has not been used before.
+func (s *SpamCheck) solved(fields map[string]string) bool {
+	token := fields[spam.ChallengeTokenField]
+	if token == "" || !spam.SolvedChallenge(token, fields[spam.ChallengeAnswerField]) {
+		return false
+	}
+
+	return s.answers.Count(token) == 1
+}
+
+// readFields reads a JSON or url-encoded body into its string fields and puts
+// the body back for the handler.
+func readFields(r *http.Request) (map[string]string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	fields := map[string]string{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var values map[string]any
+		if err := json.Unmarshal(body, &values); err != nil {
+			// Left for the handler to refuse; an unreadable body is no spam signal.
+			return fields, nil
+		}
+		for key, value := range values {
+			if text, ok := value.(string); ok {
+				fields[key] = text
+			}
+		}
+
+		return fields, nil
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return fields, nil
+	}
+	for key := range values {
+		fields[key] = values.Get(key)
+	}
+
+	return fields, nil
+}
+
+// submissionText joins the fields a person wrote, in a stable order. The
+// guard's own fields are left out, and so is anything that looks like a
+// password: it is not the sender's words and must never reach the classifier.
+func submissionText(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		switch key {
+		case spam.HoneypotField, spam.FormTokenField, spam.ChallengeTokenField, spam.ChallengeAnswerField:
+			continue
+		}
+		if strings.Contains(strings.ToLower(key), "password") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if value := strings.TrimSpace(fields[key]); value != "" {
+			parts = append(parts, value)
+		}
+	}
+
+	return strings.Join(parts, "\n")
+}
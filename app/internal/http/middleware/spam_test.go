@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server/internal/application/spam"
+	domainSpam "server/internal/domain/spam"
+
+	"github.com/google/uuid"
+)
+
+type untrainedTokens struct{}
+
+func (untrainedTokens) Counts(context.Context, []string) (map[string]domainSpam.TokenCount, domainSpam.Totals, error) {
+	return nil, domainSpam.Totals{}, nil
+}
+
+func (untrainedTokens) Train(context.Context, uuid.UUID, []string, domainSpam.Label) error {
+	return nil
+}
+
+func jsonPost(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "203.0.113.7:1234"
+	return req
+}
+
+// A bot filling the honeypot gets a question instead of a silent refusal. The
+// right answer lets the same submission through with its body intact, but
+// only once: a solved challenge cannot be replayed.
+func TestSpamCheck_Challenge(t *testing.T) {
+	check := NewSpamCheck(spam.NewGuard(untrainedTokens{}, 50))
+
+	var issued spam.Challenge
+	var received string
+	handler := check.Challenge(func(w http.ResponseWriter, r *http.Request, challenge spam.Challenge) {
+		issued = challenge
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, jsonPost(`{"email":"bot@example.com","website":"http://spam.example"}`))
+	if rec.Code != http.StatusUnprocessableEntity || issued.Token == "" || received != "" {
+		t.Fatalf("first attempt: code %d, challenge %+v, handler saw %q; want a challenge", rec.Code, issued, received)
+	}
+
+	var a, b int
+	if _, err := fmt.Sscanf(issued.Question, "Колко е %d + %d?", &a, &b); err != nil {
+		t.Fatalf("could not read the question %q: %v", issued.Question, err)
+	}
+	answered := fmt.Sprintf(`{"email":"bot@example.com","website":"http://spam.example","challengeToken":%q,"challengeAnswer":"%d"}`, issued.Token, a+b)
+
+	handler.ServeHTTP(httptest.NewRecorder(), jsonPost(answered))
+	if received != answered {
+		t.Errorf("handler saw %q, want the submission as sent", received)
+	}
+
+	received = ""
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, jsonPost(answered))
+	if rec.Code != http.StatusUnprocessableEntity || received != "" {
+		t.Errorf("replayed answer: code %d, handler saw %q; want another challenge", rec.Code, received)
+	}
+}
+
+// Hold never refuses. The handler gets the verdict, and passwords never make
+// it into the text the guard scores.
+func TestSpamCheck_Hold(t *testing.T) {
+	check := NewSpamCheck(spam.NewGuard(untrainedTokens{}, 50))
+
+	var assessment spam.Assessment
+	handler := check.Hold(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assessment = SpamAssessmentFrom(r.Context())
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), jsonPost(`{"body":"Здравейте","website":"filled"}`))
+	if !assessment.Suspicious {
+		t.Errorf("assessment = %+v, want suspicious", assessment)
+	}
+
+	text := submissionText(map[string]string{"body": "Здравейте", "password": "secret", "repeatPassword": "secret", "formToken": "x"})
+	if text != "Здравейте" {
+		t.Errorf("submissionText() = %q, want only the body", text)
+	}
+}
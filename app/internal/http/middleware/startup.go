@@ -0,0 +1,34 @@
+package middleware
+
+import "net/http"
+
+// StartupState is what GateStartup needs to know about the process.
+type StartupState interface {
+	Starting() bool
+}
+
+// startupPaths keep answering while the process starts: the probes, so that
+// the orchestrator can tell starting from dead, and the metrics.
+var startupPaths = map[string]bool{
+	"/livez":   true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// GateStartup turns requests away until startup has finished. The server
+// listens while migrations run so liveness can be checked, and a request that
+// slipped past the load balancer would otherwise meet a half-migrated schema.
+func GateStartup(state StartupState) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			if state.Starting() && !startupPaths[req.URL.Path] {
+				writer.Header().Set("Retry-After", "5")
+				http.Error(writer, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubStartup bool
+
+func (s stubStartup) Starting() bool { return bool(s) }
+
+// While starting, only the probes answer; everything else would meet a
+// schema still being migrated.
+func TestGateStartup(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		starting bool
+		path     string
+		want     int
+	}{
+		{true, "/blog", http.StatusServiceUnavailable},
+		{true, "/livez", http.StatusOK},
+		{true, "/readyz", http.StatusOK},
+		{false, "/blog", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		GateStartup(stubStartup(tt.starting))(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.want {
+			t.Errorf("starting=%v %s: got %d, want %d", tt.starting, tt.path, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"server/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace opens the server span for a request, continuing the caller's trace
+// when a traceparent header names one. It wraps the whole chain, Recovery
+// included, so the span covers everything the server did. The span is named
+// after the route pattern once the mux has matched.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracing.Start(ctx, req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLPath(req.URL.Path),
+			),
+		)
+		defer span.End()
+
+		req, facts := withRequestFacts(req.WithContext(ctx))
+		recorder := &statusRecorder{ResponseWriter: writer}
+
+		next.ServeHTTP(recorder, req)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if facts.route != "" {
+			span.SetName(req.Method + " " + facts.route)
+			span.SetAttributes(semconv.HTTPRoute(facts.route))
+		}
+	})
+}
+
+// traceMiddleware gives a middleware its own span, so a trace shows how long
+// each step of the chain took before handing on.
+func traceMiddleware(middleware Middleware) Middleware {
+	name := "middleware " + middlewareName(middleware)
+
+	return func(next http.Handler) http.Handler {
+		handler := middleware(next)
+
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			ctx, span := tracing.Start(req.Context(), name)
+			defer span.End()
+
+			handler.ServeHTTP(writer, req.WithContext(ctx))
+		})
+	}
+}
+
+// middlewareName turns "server/internal/http/middleware.CheckAuth.func1" into
+// "CheckAuth".
+func middlewareName(middleware Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(middleware).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	if _, rest, ok := strings.Cut(name, "."); ok {
+		name = rest
+	}
+	name, _, _ = strings.Cut(name, ".")
+
+	return name
+}
+
+// tracePanic marks the span a panic went through, since Recovery turns it
+// into an ordinary redirect by the time Trace sees the response.
+func tracePanic(req *http.Request, recovered any) {
+	tracing.Fail(trace.SpanFromContext(req.Context()), fmt.Errorf("panic: %v", recovered))
+}
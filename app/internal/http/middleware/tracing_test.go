@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"server/util/ctxutils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useSpanRecorder installs a tracer provider that keeps finished spans in
+// memory, and puts the globals back when the test ends.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+// A caller's traceparent must be continued rather than starting a new trace,
+// the span must be named by the route rather than the raw path, and the
+// request id must be the trace id so logs lead to the trace.
+func TestTraceContinuesIncomingTraceAndUsesItAsRequestId(t *testing.T) {
+	recorder := useSpanRecorder(t)
+
+	var requestId string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blog/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		requestId = ctxutils.RequestIdFromContext(r.Context())
+	})
+	handler := Trace(PopulateRequestId(RecordRoute(mux)))
+
+	req := httptest.NewRequest(http.MethodGet, "/blog/first-post", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if requestId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("request id = %q, want the trace id", requestId)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want the server and handler spans", len(spans))
+	}
+	handlerSpan, serverSpan := spans[0], spans[1]
+
+	if serverSpan.Name() != "GET /blog/{slug}" {
+		t.Errorf("server span = %q", serverSpan.Name())
+	}
+	if serverSpan.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("server span parent = %s, want the caller's span", serverSpan.Parent().SpanID())
+	}
+	if handlerSpan.Name() != "handler GET /blog/{slug}" {
+		t.Errorf("handler span = %q", handlerSpan.Name())
+	}
+	if handlerSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Error("handler span is not a child of the server span")
+	}
+}
+
+// Span names come from the function, including for middlewares built by a
+// constructor, which are closures.
+func TestMiddlewareName(t *testing.T) {
+	if got := middlewareName(Recovery); got != "Recovery" {
+		t.Errorf("got %q", got)
+	}
+	if got := middlewareName(CheckAuth(nil)); got != "CheckAuth" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"server/internal/application/account"
+	"server/internal/config"
+	"server/internal/domain/comments"
+	"server/internal/domain/outbox"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/internal/http/handlers"
+	"server/internal/http/middleware"
+	"server/internal/infrastructure/email"
+)
+
+func AccountRoutes(mux *http.ServeMux, db *sql.DB) {
+	emailService := email.NewEmailService(outbox.NewOutboxRepository(db))
+
+	accountService := account.NewAccountService(
+		user.NewUserRepository(db),
+		user.NewEmailChangeTokenRepository(db),
+		user.NewPasswordResetTokenRepository(db),
+		emailService,
+	)
+
+	privacyService := account.NewPrivacyService(
+		user.NewUserRepository(db),
+		posts.NewPostRepository(db),
+		comments.NewCommentRepository(db),
+		user.NewAccountDeletionTokenRepository(db),
+		emailService,
+		config.AccountDeletionGrace(),
+	)
+
+	handler := handlers.NewAccountHandler(accountService, privacyService)
+
+	// Administrators keep their own login when public registration is off.
+	loginPath := "/admin/login"
+	if config.AllowRegistration() {
+		loginPath = "/login"
+	}
+	requireAuth := middleware.RequireAuthAt(loginPath)
+
+	// These check the password, so they are limited like the login.
+	authLimiter := middleware.AuthRateLimiter()
+
+	mux.Handle("GET /account", requireAuth(http.HandlerFunc(handler.GetAccount)))
+	mux.Handle("POST /account/profile", requireAuth(http.HandlerFunc(handler.HandleUpdateProfile)))
+	mux.Handle("POST /account/password", requireAuth(authLimiter.Middleware(http.HandlerFunc(handler.HandleChangePassword))))
+	mux.Handle("POST /account/email", requireAuth(authLimiter.Middleware(http.HandlerFunc(handler.HandleChangeEmail))))
+	mux.Handle("GET /account/export", requireAuth(http.HandlerFunc(handler.GetExport)))
+	mux.Handle("POST /account/delete", requireAuth(authLimiter.Middleware(http.HandlerFunc(handler.HandleRequestDeletion))))
+	mux.Handle("POST /account/delete/cancel", requireAuth(http.HandlerFunc(handler.HandleCancelDeletion)))
+
+	// The links may be opened in a browser with no session, e.g. the mail app
+	// on a phone.
+	mux.HandleFunc("GET /account/email/confirm", handler.GetConfirmEmailChange)
+	mux.HandleFunc("GET /account/delete/confirm", handler.GetConfirmDeletion)
+}
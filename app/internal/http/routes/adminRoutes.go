@@ -4,46 +4,138 @@ import (
 	"database/sql"
 	"net/http"
 
+	appAudit "server/internal/application/audit"
+	"server/internal/application/auth"
 	"server/internal/application/categories"
+	appComments "server/internal/application/comments"
+	appContact "server/internal/application/contact"
+	appJobs "server/internal/application/jobs"
+	"server/internal/application/media"
+	appNewsletter "server/internal/application/newsletter"
+	appOutbox "server/internal/application/outbox"
 	appPosts "server/internal/application/posts"
+	appSpam "server/internal/application/spam"
+	"server/internal/application/users"
+	"server/internal/config"
+	"server/internal/domain/audit"
 	"server/internal/domain/category"
+	"server/internal/domain/comments"
+	"server/internal/domain/contact"
+	"server/internal/domain/jobs"
+	"server/internal/domain/newsletter"
+	"server/internal/domain/outbox"
 	"server/internal/domain/posts"
+	"server/internal/domain/spam"
+	"server/internal/domain/user"
 	"server/internal/http/handlers"
 	"server/internal/http/middleware"
-	"server/internal/infrastructure/cloudinary"
+	"server/internal/infrastructure/email"
+	"server/internal/infrastructure/storage"
 )
 
-func AdminRoutes(mux *http.ServeMux, db *sql.DB) {
+func AdminRoutes(mux *http.ServeMux, db *sql.DB, store storage.Storage, images *media.ImageService, library *media.LibraryService) {
 	postRepo := posts.NewPostRepository(db)
 	postService := appPosts.NewPostService(postRepo)
 
 	categoryRepo := category.NewCategoryRepository(db)
 	categoryService := categories.NewCategoryService(categoryRepo)
 
-	cloudinaryService, _ := cloudinary.NewCloudinaryService()
+	auditService := appAudit.NewAuditService(audit.NewAuditRepository(db), config.AuditRetention())
 
-	handler := handlers.NewAdminHandler(postService, categoryService, cloudinaryService)
+	handler := handlers.NewAdminHandler(postService, categoryService, store, images, library, auditService)
 
-	// Wrap all admin routes with auth and admin middleware
-	adminAuth := func(h http.HandlerFunc) http.Handler {
-		return middleware.RequireAuth(middleware.RequireAdmin(h))
+	outboxRepo := outbox.NewOutboxRepository(db)
+	emailService := email.NewEmailService(outboxRepo)
+
+	userRepo := user.NewUserRepository(db)
+	resetService := auth.NewPasswordResetService(userRepo, user.NewPasswordResetTokenRepository(db), emailService)
+	userHandler := handlers.NewAdminUserHandler(users.NewUserAdminService(userRepo, resetService), auditService)
+	auditHandler := handlers.NewAdminAuditHandler(auditService)
+	spamGuard := appSpam.NewGuard(spam.NewTokenRepository(db), config.SpamThreshold())
+	commentHandler := handlers.NewAdminCommentHandler(appComments.NewCommentService(comments.NewCommentRepository(db), spamGuard), auditService)
+	newsletterService := appNewsletter.NewNewsletterService(newsletter.NewSubscriberRepository(db), newsletter.NewSendRepository(db), postRepo, emailService)
+	newsletterHandler := handlers.NewAdminNewsletterHandler(newsletterService, auditService)
+	contactService := appContact.NewContactService(contact.NewMessageRepository(db), emailService, spamGuard)
+	inboxHandler := handlers.NewAdminInboxHandler(contactService, auditService)
+	// The panel only lists failures and requeues them; the worker started in
+	// main delivers, so this service needs no sender.
+	emailHandler := handlers.NewAdminEmailHandler(appOutbox.NewOutboxService(outboxRepo, nil, config.EmailMaxAttempts()), auditService)
+
+	mediaHandler := handlers.NewAdminMediaHandler(library)
+
+	jobHandler := handlers.NewAdminJobHandler(appJobs.NewJobService(jobs.NewJobRepository(db)), auditService)
+
+	// Every admin route wants a session plus one of the listed permissions.
+	// Rules that depend on the post itself are applied by the handler.
+	requires := func(h http.HandlerFunc, permissions ...string) http.Handler {
+		return middleware.RequireAuth(middleware.RequirePermission(permissions...)(h))
 	}
 
 	// Dashboard
-	mux.Handle("GET /admin", adminAuth(handler.GetDashboard))
-	mux.Handle("GET /admin/", adminAuth(handler.GetDashboard))
+	mux.Handle("GET /admin", requires(handler.GetDashboard, user.PermAdminAccess))
+	mux.Handle("GET /admin/", requires(handler.GetDashboard, user.PermAdminAccess))
 
 	// Posts management
-	mux.Handle("GET /admin/posts", adminAuth(handler.GetPosts))
-	mux.Handle("GET /admin/posts/new", adminAuth(handler.GetPostForm))
-	mux.Handle("GET /admin/posts/{id}", adminAuth(handler.GetPostForm))
-	mux.Handle("POST /admin/posts", adminAuth(handler.CreatePost))
-	mux.Handle("PUT /admin/posts/{id}", adminAuth(handler.UpdatePost))
-	mux.Handle("DELETE /admin/posts/{id}", adminAuth(handler.DeletePost))
+	mux.Handle("GET /admin/posts", requires(handler.GetPosts, user.PermAdminAccess))
+	mux.Handle("GET /admin/posts/new", requires(handler.GetPostForm, user.PermPostsCreate))
+	mux.Handle("GET /admin/posts/{id}", requires(handler.GetPostForm, user.PermPostsEdit, user.PermPostsEditOwn))
+	mux.Handle("POST /admin/posts", requires(handler.CreatePost, user.PermPostsCreate))
+	mux.Handle("PUT /admin/posts/{id}", requires(handler.UpdatePost, user.PermPostsEdit, user.PermPostsEditOwn))
+	mux.Handle("DELETE /admin/posts/{id}", requires(handler.DeletePost, user.PermPostsDelete))
+
+	// User management
+	mux.Handle("GET /admin/users", requires(userHandler.GetUsers, user.PermUsersManage))
+	mux.Handle("POST /admin/users/{id}/status", requires(userHandler.UpdateStatus, user.PermUsersManage))
+	mux.Handle("POST /admin/users/{id}/roles", requires(userHandler.GrantRole, user.PermUsersManage))
+	mux.Handle("DELETE /admin/users/{id}/roles/{role}", requires(userHandler.RevokeRole, user.PermUsersManage))
+	mux.Handle("POST /admin/users/{id}/password-reset", requires(userHandler.ForcePasswordReset, user.PermUsersManage))
+	mux.Handle("POST /admin/users/{id}/sessions/revoke", requires(userHandler.RevokeSessions, user.PermUsersManage))
+
+	// Comment moderation
+	mux.Handle("GET /admin/comments", requires(commentHandler.GetQueue, user.PermCommentsModerate))
+	mux.Handle("POST /admin/comments/moderate", requires(commentHandler.Moderate, user.PermCommentsModerate))
+
+	// Newsletter
+	mux.Handle("GET /admin/newsletter", requires(newsletterHandler.GetSubscribers, user.PermNewsletterManage))
+	mux.Handle("GET /admin/newsletter/sends", requires(newsletterHandler.GetSends, user.PermNewsletterManage))
+	mux.Handle("GET /admin/newsletter/subscribers/{id}", requires(newsletterHandler.GetSubscriber, user.PermNewsletterManage))
+	mux.Handle("DELETE /admin/newsletter/subscribers/{id}", requires(newsletterHandler.DeleteSubscriber, user.PermNewsletterManage))
+
+	// Contact inbox
+	mux.Handle("GET /admin/inbox", requires(inboxHandler.GetInbox, user.PermInboxManage))
+	mux.Handle("GET /admin/inbox/{id}", requires(inboxHandler.GetMessage, user.PermInboxManage))
+	mux.Handle("POST /admin/inbox/{id}/reply", requires(inboxHandler.Reply, user.PermInboxManage))
+	mux.Handle("POST /admin/inbox/{id}/category", requires(inboxHandler.UpdateCategory, user.PermInboxManage))
+	mux.Handle("POST /admin/inbox/{id}/status", requires(inboxHandler.UpdateStatus, user.PermInboxManage))
+	mux.Handle("POST /admin/inbox/{id}/unread", requires(inboxHandler.MarkUnread, user.PermInboxManage))
+	mux.Handle("DELETE /admin/inbox/{id}", requires(inboxHandler.Delete, user.PermInboxManage))
+
+	// Email delivery
+	mux.Handle("GET /admin/emails", requires(emailHandler.GetFailures, user.PermEmailsManage))
+	mux.Handle("POST /admin/emails/{id}/retry", requires(emailHandler.Retry, user.PermEmailsManage))
+
+	// Every email template with sample data, for working on them locally.
+	if config.IsDevelopment() {
+		mux.Handle("GET /admin/emails/preview", requires(emailHandler.GetPreviews, user.PermEmailsManage))
+		mux.Handle("GET /admin/emails/preview/{name}", requires(emailHandler.GetPreview, user.PermEmailsManage))
+	}
+
+	// Background jobs
+	mux.Handle("GET /admin/jobs", requires(jobHandler.GetJobs, user.PermJobsManage))
+	mux.Handle("GET /admin/jobs/{id}", requires(jobHandler.GetJob, user.PermJobsManage))
+	mux.Handle("POST /admin/jobs/{id}/retry", requires(jobHandler.Retry, user.PermJobsManage))
+
+	// Audit trail
+	mux.Handle("GET /admin/audit", requires(auditHandler.GetEvents, user.PermAuditRead))
+
+	// Media library. Anyone who may upload images may reuse them.
+	mux.Handle("GET /admin/media", requires(mediaHandler.GetLibrary, user.PermMediaUpload))
+	mux.Handle("GET /admin/media/picker", requires(mediaHandler.GetPicker, user.PermMediaUpload))
+	mux.Handle("POST /admin/media/{id}/alt", requires(mediaHandler.UpdateAlt, user.PermMediaUpload))
 
 	// Image upload
-	mux.Handle("POST /api/admin/upload", adminAuth(handler.UploadImage))
+	mux.Handle("POST /api/admin/upload", requires(handler.UploadImage, user.PermMediaUpload))
 
 	// File upload (GPX etc.)
-	mux.Handle("POST /api/admin/upload-file", adminAuth(handler.UploadFile))
+	mux.Handle("POST /api/admin/upload-file", requires(handler.UploadFile, user.PermMediaUpload))
 }
@@ -3,9 +3,14 @@ package routes
 import (
 	"database/sql"
 	"net/http"
+	appAudit "server/internal/application/audit"
 	"server/internal/application/auth"
+	appSpam "server/internal/application/spam"
 	"server/internal/application/users"
 	"server/internal/config"
+	"server/internal/domain/audit"
+	"server/internal/domain/outbox"
+	"server/internal/domain/spam"
 	"server/internal/domain/user"
 	"server/internal/http/handlers"
 	"server/internal/http/middleware"
@@ -18,19 +23,30 @@ func AuthRoutes(mux *http.ServeMux, db *sql.DB) {
 	tokenRepository := user.NewPasswordResetTokenRepository(db)
 	userService := users.NewUserService(userRepository)
 	authService := auth.NewAuthService()
-	emailService := email.NewEmailService()
+	emailService := email.NewEmailService(outbox.NewOutboxRepository(db))
 	passwordResetService := auth.NewPasswordResetService(userRepository, tokenRepository, emailService)
+	emailVerificationService := auth.NewEmailVerificationService(userRepository, user.NewEmailVerificationTokenRepository(db), emailService)
 
-	authHandler := handlers.NewAuthHandler(userService, authService, passwordResetService)
+	lockoutService := auth.NewLoginLockoutService(userRepository, user.NewAccountUnlockTokenRepository(db), emailService)
+	auditService := appAudit.NewAuditService(audit.NewAuditRepository(db), config.AuditRetention())
+
+	authHandler := handlers.NewAuthHandler(userService, authService, passwordResetService, emailVerificationService, lockoutService, auditService)
 
 	// Rate limiters
 	authLimiter := middleware.AuthRateLimiter()
 	passwordResetLimiter := middleware.PasswordResetRateLimiter()
 
+	// Signups and reset requests have no moderation queue to hold them in, so
+	// a suspicious one is asked a question before it goes through.
+	spamChallenge := middleware.NewSpamCheck(appSpam.NewGuard(spam.NewTokenRepository(db), config.SpamThreshold())).Challenge(handlers.SpamChallenge)
+
 	// Admin login (always available)
 	mux.HandleFunc("GET /admin/login", authHandler.GetAdminLogin)
 	mux.Handle("POST /admin/login", authLimiter.Middleware(http.HandlerFunc(authHandler.HandleLogin)))
 
+	// Unlock links go to admins too, so they work with registration off.
+	mux.HandleFunc("GET /unlock-account", authHandler.GetUnlockAccount)
+
 	// Logout (always available)
 	mux.HandleFunc("POST /logout", authHandler.HandleLogout)
 
@@ -43,11 +59,15 @@ func AuthRoutes(mux *http.ServeMux, db *sql.DB) {
 		mux.HandleFunc("GET /login", authHandler.GetLogin)
 		mux.Handle("POST /login", authLimiter.Middleware(http.HandlerFunc(authHandler.HandleLogin)))
 		mux.HandleFunc("GET /register", authHandler.GetRegister)
-		mux.Handle("POST /register", authLimiter.Middleware(http.HandlerFunc(authHandler.HandleRegister)))
+		mux.Handle("POST /register", authLimiter.Middleware(spamChallenge(http.HandlerFunc(authHandler.HandleRegister))))
 		mux.HandleFunc("GET /forgot-password", authHandler.GetForgotPassword)
-		mux.Handle("POST /forgot-password", passwordResetLimiter.Middleware(http.HandlerFunc(authHandler.HandleForgotPassword)))
+		mux.Handle("POST /forgot-password", passwordResetLimiter.Middleware(spamChallenge(http.HandlerFunc(authHandler.HandleForgotPassword))))
 		mux.HandleFunc("GET /reset-password", authHandler.GetResetPassword)
 		mux.Handle("POST /reset-password", passwordResetLimiter.Middleware(http.HandlerFunc(authHandler.HandleResetPassword)))
+		mux.HandleFunc("GET /verify-email/sent", authHandler.GetVerifyEmailSent)
+		mux.HandleFunc("GET /verify-email", authHandler.GetVerifyEmail)
+		mux.HandleFunc("GET /verify-email/resend", authHandler.GetResendVerification)
+		mux.Handle("POST /verify-email/resend", passwordResetLimiter.Middleware(http.HandlerFunc(authHandler.HandleResendVerification)))
 
 		return
 	}
@@ -58,7 +78,7 @@ func AuthRoutes(mux *http.ServeMux, db *sql.DB) {
 	//
 	// The admin login is deliberately not among these: pointing the public at
 	// it would only invite password guessing.
-	for _, path := range []string{"/login", "/register", "/forgot-password", "/reset-password"} {
+	for _, path := range []string{"/login", "/register", "/forgot-password", "/reset-password", "/verify-email", "/verify-email/sent", "/verify-email/resend"} {
 		mux.Handle("GET "+path, http.RedirectHandler("/", http.StatusSeeOther))
 		mux.Handle("POST "+path, http.RedirectHandler("/", http.StatusSeeOther))
 	}
@@ -20,7 +20,8 @@ func BaseRoutes(mux *http.ServeMux, db *sql.DB) {
 	mux.HandleFunc("GET /not-found", handler.HandleNotFound)
 	mux.HandleFunc("GET /error", handler.HandleError)
 
-	// Health check endpoint
+	// The original health check, kept for whatever still polls it. New checks
+	// should use /livez and /readyz from HealthRoutes.
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
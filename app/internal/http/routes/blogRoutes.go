@@ -5,9 +5,14 @@ import (
 	"net/http"
 
 	"server/internal/application/categories"
+	appComments "server/internal/application/comments"
 	appPosts "server/internal/application/posts"
+	appSpam "server/internal/application/spam"
+	"server/internal/config"
 	"server/internal/domain/category"
+	"server/internal/domain/comments"
 	"server/internal/domain/posts"
+	"server/internal/domain/spam"
 	"server/internal/http/handlers"
 	"server/internal/http/middleware"
 )
@@ -21,6 +26,12 @@ func BlogRoutes(mux *http.ServeMux, db *sql.DB) {
 
 	handler := handlers.NewBlogHandler(postService, categoryService)
 
+	spamGuard := appSpam.NewGuard(spam.NewTokenRepository(db), config.SpamThreshold())
+	commentService := appComments.NewCommentService(comments.NewCommentRepository(db), spamGuard)
+	commentHandler := handlers.NewCommentHandler(postService, commentService)
+	commentLimiter := middleware.CommentRateLimiter()
+	spamCheck := middleware.NewSpamCheck(spamGuard)
+
 	// Blog list page
 	mux.HandleFunc("GET /blog", handler.GetBlogList)
 
@@ -31,6 +42,13 @@ func BlogRoutes(mux *http.ServeMux, db *sql.DB) {
 	// Recent posts (HTMX endpoint for home page)
 	mux.Handle("GET /blog/recent", middleware.FragmentOnly("/blog", http.HandlerFunc(handler.GetRecentPosts)))
 
+	// Comments (HTMX fragments under a post)
+	postPage := func(r *http.Request) string { return "/blog/" + r.PathValue("slug") }
+	mux.Handle("GET /blog/comments/{slug}", middleware.FragmentOf(postPage, http.HandlerFunc(commentHandler.GetComments)))
+	// Suspicious comments are held for moderation rather than challenged: the
+	// queue is there anyway, and a false positive costs the reader nothing.
+	mux.Handle("POST /blog/comments/{slug}", commentLimiter.Middleware(spamCheck.Hold(http.HandlerFunc(commentHandler.PostComment))))
+
 	// Blog by category
 	mux.HandleFunc("GET /blog/category/{slug}", handler.GetBlogByCategory)
 
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"server/internal/application/categories"
 	"server/internal/domain/category"
+	"server/internal/domain/user"
 	"server/internal/http/handlers"
 	"server/internal/http/middleware"
 )
@@ -21,9 +22,9 @@ func CategoriesRoutes(mux *http.ServeMux, db *sql.DB) {
 	// @Router /categories [get]
 	mux.Handle("GET "+prefix, middleware.FragmentOnly("/", http.HandlerFunc(controller.GetCategories)))
 
-	// @Description Create a category (admin only)
+	// @Description Create a category (categories:manage)
 	// @Produce json
 	// @Success 201 categories.CategoryResponseResource
 	// @Router /categories [post]
-	mux.Handle("POST "+prefix, middleware.RequireAuth(middleware.RequireAdmin(http.HandlerFunc(controller.Create))))
+	mux.Handle("POST "+prefix, middleware.RequireAuth(middleware.RequirePermission(user.PermCategoriesManage)(http.HandlerFunc(controller.Create))))
 }
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+
+	appContact "server/internal/application/contact"
+	appSpam "server/internal/application/spam"
+	"server/internal/config"
+	"server/internal/domain/contact"
+	"server/internal/domain/outbox"
+	"server/internal/domain/spam"
+	"server/internal/http/handlers"
+	"server/internal/http/middleware"
+	"server/internal/infrastructure/email"
+)
+
+func ContactRoutes(mux *http.ServeMux, db *sql.DB) {
+	spamGuard := appSpam.NewGuard(spam.NewTokenRepository(db), config.SpamThreshold())
+	contactService := appContact.NewContactService(contact.NewMessageRepository(db), email.NewEmailService(outbox.NewOutboxRepository(db)), spamGuard)
+	handler := handlers.NewContactHandler(contactService)
+
+	limiter := middleware.ContactRateLimiter()
+	spamCheck := middleware.NewSpamCheck(spamGuard)
+
+	mux.HandleFunc("GET /contact", handler.GetContact)
+	// Suspicious messages are held in the inbox's spam folder rather than
+	// challenged, like comments: someone reads the inbox anyway.
+	mux.Handle("POST /contact", limiter.Middleware(spamCheck.Hold(http.HandlerFunc(handler.Submit))))
+}
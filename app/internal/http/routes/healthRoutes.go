@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+
+	appHealth "server/internal/application/health"
+	"server/internal/config"
+	"server/internal/domain/health"
+	"server/internal/domain/jobs"
+	"server/internal/domain/user"
+	"server/internal/http/handlers"
+	"server/internal/http/middleware"
+	"server/internal/infrastructure/email"
+	"server/internal/infrastructure/storage"
+)
+
+// HealthRoutes registers the probes. /livez and /readyz are for the
+// orchestrator and load balancer and say as little as possible; the details
+// are for administrators.
+func HealthRoutes(mux *http.ServeMux, db *sql.DB, readiness *appHealth.State, store storage.Storage) {
+	storageBackend := ""
+	if store != nil {
+		storageBackend = config.StorageBackend()
+	}
+
+	healthService := appHealth.NewHealthService(readiness, health.NewHealthRepository(db), jobs.NewJobRepository(db), appHealth.Options{
+		SMTPAddr: email.SMTPAddr(),
+		Storage:  storageBackend,
+		// Uploads are spooled to the temporary directory on their way to
+		// storage, so that is the disk that must not fill up.
+		DiskPath: os.TempDir(),
+	})
+	handler := handlers.NewHealthHandler(healthService)
+
+	mux.HandleFunc("GET /livez", handler.Livez)
+	mux.HandleFunc("GET /readyz", handler.Readyz)
+	mux.Handle("GET /health/details", middleware.RequireAuth(middleware.RequirePermission(user.PermHealthRead)(http.HandlerFunc(handler.GetDetails))))
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"net/http"
+
+	"server/internal/config"
+	"server/internal/http/middleware"
+	"server/internal/infrastructure/metrics"
+)
+
+// MetricsRoutes exposes /metrics on the public port when a token is set. A
+// deployment that prefers a private listener sets METRICS_ADDR instead and
+// leaves this route out.
+func MetricsRoutes(mux *http.ServeMux) {
+	token := config.MetricsToken()
+	if token == "" {
+		return
+	}
+
+	mux.Handle("GET /metrics", middleware.RequireBearerToken(token, metrics.Handler()))
+}
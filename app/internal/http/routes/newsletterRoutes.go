@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+
+	appNewsletter "server/internal/application/newsletter"
+	appSpam "server/internal/application/spam"
+	"server/internal/config"
+	"server/internal/domain/newsletter"
+	"server/internal/domain/outbox"
+	"server/internal/domain/posts"
+	"server/internal/domain/spam"
+	"server/internal/http/handlers"
+	"server/internal/http/middleware"
+	"server/internal/infrastructure/email"
+)
+
+func NewsletterRoutes(mux *http.ServeMux, db *sql.DB) {
+	newsletterService := appNewsletter.NewNewsletterService(newsletter.NewSubscriberRepository(db), newsletter.NewSendRepository(db), posts.NewPostRepository(db), email.NewEmailService(outbox.NewOutboxRepository(db)))
+	handler := handlers.NewNewsletterHandler(newsletterService)
+
+	// Every sign up sends an email, so the form gets the same limits as the
+	// other forms that do.
+	limiter := middleware.PasswordResetRateLimiter()
+	spamChallenge := middleware.NewSpamCheck(appSpam.NewGuard(spam.NewTokenRepository(db), config.SpamThreshold())).Challenge(handlers.NewsletterSpamChallenge)
+
+	mux.Handle("POST /newsletter/subscribe", limiter.Middleware(spamChallenge(http.HandlerFunc(handler.Subscribe))))
+	mux.HandleFunc("GET /newsletter/confirm", handler.Confirm)
+	mux.HandleFunc("GET /newsletter/unsubscribe", handler.GetUnsubscribe)
+	mux.HandleFunc("POST /newsletter/unsubscribe", handler.Unsubscribe)
+}
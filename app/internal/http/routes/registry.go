@@ -5,10 +5,17 @@ import (
 	"log/slog"
 	"net/http"
 	"path/filepath"
+	appHealth "server/internal/application/health"
+	"server/internal/application/media"
+	"server/internal/config"
+	"server/internal/domain/image"
 	"server/internal/http/middleware"
+	"server/internal/infrastructure/imaging"
+	"server/internal/infrastructure/storage"
+	"server/util/imageutils"
 )
 
-func RegisterRoutes(db *sql.DB) *http.ServeMux {
+func RegisterRoutes(db *sql.DB, readiness *appHealth.State) *http.ServeMux {
 	slog.Info("Registering routes")
 
 	mux := http.NewServeMux()
@@ -23,12 +30,41 @@ func RegisterRoutes(db *sql.DB) *http.ServeMux {
 
 	mux.Handle("GET /static/", http.StripPrefix("/static/", middleware.CacheStaticAssets(fileServer, staticDir)))
 
+	// Without storage the admin still works, but uploads answer 503.
+	store, err := storage.New()
+	if err != nil {
+		slog.Error("Upload storage is not available", "backend", config.StorageBackend(), "error", err)
+	}
+	if local, ok := store.(*storage.Local); ok {
+		mux.Handle("GET /media/", http.StripPrefix("/media", local.Handler()))
+	}
+
+	// Uploaded images lose their metadata, and away from Cloudinary, which
+	// resizes on delivery, get copies that pages find through the index.
+	imageRepo := image.NewImageRepository(db)
+	variantIndex := media.NewVariantIndex(imageRepo, media.DefaultVariantIndexTTL)
+	imageutils.UseVariants(variantIndex.Lookup)
+	var images *media.ImageService
+	if store != nil {
+		processor := imaging.NewProcessor(imaging.Options{
+			Quality:     config.ImageQuality(),
+			WebPEncoder: config.ImageWebPEncoder(),
+			AVIFEncoder: config.ImageAVIFEncoder(),
+		})
+		images = media.NewImageService(store, imageRepo, processor, variantIndex, config.StorageBackend() != "cloudinary", imageutils.VariantWidths())
+	}
+
 	BaseRoutes(mux, db)
 	CategoriesRoutes(mux, db)
 	AuthRoutes(mux, db)
 	BlogRoutes(mux, db)
-	AdminRoutes(mux, db)
+	AccountRoutes(mux, db)
+	AdminRoutes(mux, db, store, images, media.NewLibraryService(imageRepo, store, config.ImageUnusedGrace()))
 	FeedRoutes(mux, db)
+	NewsletterRoutes(mux, db)
+	ContactRoutes(mux, db)
+	MetricsRoutes(mux)
+	HealthRoutes(mux, db, readiness, store)
 
 	return mux
 }
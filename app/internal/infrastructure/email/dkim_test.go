@@ -0,0 +1,155 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/domain/outbox"
+
+	"github.com/google/uuid"
+)
+
+// The canonicalization example from RFC 6376 §3.4.5. Any difference from it
+// and receivers compute another hash than we signed.
+func TestRelaxedCanonicalization_RFCExample(t *testing.T) {
+	fields := splitHeaderFields([]byte("A: X\r\nB : Y\t\r\n\tZ  \r\n"))
+
+	var header string
+	for _, field := range fields {
+		header += relaxedHeader(field)
+	}
+	if want := "a:X\r\nb:Y Z\r\n"; header != want {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+
+	if got, want := string(relaxedBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))), " C\r\nD E\r\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+// A signed message must verify with the public key after the folding a relay
+// might add, for both RSA and Ed25519 keys, and the signature must cover the
+// headers that matter.
+func TestDKIMSigner_SignatureVerifies(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string][]byte{
+		"rsa":     pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		"ed25519": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}),
+	}
+
+	message := outbox.Message{
+		Id:        uuid.New(),
+		Recipient: "reader@example.com",
+		Subject:   "Новото в Движи се тази седмица",
+		Body:      "<p>Здравейте!</p>",
+		Headers:   map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	}
+	raw, err := buildMessage("Движи се <noreply@example.com>", message, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, keyPEM := range keys {
+		t.Run(name, func(t *testing.T) {
+			signer, err := NewDKIMSigner("example.com", "mail", keyPEM)
+			if err != nil {
+				t.Fatalf("NewDKIMSigner() error = %v", err)
+			}
+
+			signed, err := signer.Sign(raw, time.Now())
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			// Refold the subject the way a relay may; relaxed
+			// canonicalization has to absorb it.
+			signed = bytes.Replace(signed, []byte("Subject: "), []byte("Subject:\r\n\t  "), 1)
+
+			var public crypto.PublicKey = rsaKey.Public()
+			if name == "ed25519" {
+				public = edKey.Public()
+			}
+			verifyDKIM(t, signed, public)
+		})
+	}
+}
+
+// verifyDKIM checks the message's signature as a receiver would.
+func verifyDKIM(t *testing.T, msg []byte, public crypto.PublicKey) {
+	t.Helper()
+
+	headerEnd := bytes.Index(msg, []byte("\r\n\r\n"))
+	fields := splitHeaderFields(msg[:headerEnd+2])
+	body := msg[headerEnd+4:]
+
+	signature := fields[0]
+	if !strings.HasPrefix(signature, "DKIM-Signature:") {
+		t.Fatalf("first header is not the signature: %q", signature)
+	}
+
+	tags := map[string]string{}
+	for _, tag := range strings.Split(relaxedHeader(signature)[len("dkim-signature:"):], ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		tags[key] = strings.ReplaceAll(value, " ", "")
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	if got := base64.StdEncoding.EncodeToString(bodyHash[:]); got != tags["bh"] {
+		t.Fatalf("bh = %s, want %s", tags["bh"], got)
+	}
+
+	names := strings.Split(tags["h"], ":")
+	for _, want := range []string{"from", "to", "subject", "date", "message-id", "list-unsubscribe"} {
+		if !strings.Contains(":"+tags["h"]+":", ":"+want+":") {
+			t.Errorf("h= does not cover %s: %s", want, tags["h"])
+		}
+	}
+
+	signed, _ := selectHeaderFields(fields[1:], names)
+	h := sha256.New()
+	for _, field := range signed {
+		h.Write([]byte(relaxedHeader(field)))
+	}
+	emptied := regexp.MustCompile(`b=[^;]*$`).ReplaceAllString(strings.TrimSuffix(relaxedHeader(signature), "\r\n"), "b=")
+	h.Write([]byte(emptied))
+	digest := h.Sum(nil)
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		t.Fatalf("b= is not base64: %v", err)
+	}
+
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig); err != nil {
+			t.Errorf("RSA signature does not verify: %v", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest, sig) {
+			t.Error("Ed25519 signature does not verify")
+		}
+	}
+}
@@ -4,121 +4,154 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"html/template"
 	"log/slog"
-	"net/smtp"
 	"server/internal/config"
+	"server/internal/domain/newsletter"
+	"server/internal/domain/outbox"
+	"server/web/templates/emails"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/google/uuid"
 )
 
+type messageQueue interface {
+	Enqueue(ctx context.Context, message outbox.Message) error
+}
+
+// EmailService renders the site's emails and queues them in the outbox, from
+// which MailSender delivers them in the background. No request waits on the
+// mail server.
 type EmailService struct {
-	host     string
-	port     string
-	username string
-	password string
-	from     string
-	baseURL  string
+	queue   messageQueue
+	baseURL string
 }
 
-func NewEmailService() *EmailService {
+func NewEmailService(queue messageQueue) *EmailService {
 	return &EmailService{
-		host:     config.SMTPHost(),
-		port:     config.SMTPPort(),
-		username: config.SMTPUsername(),
-		password: config.SMTPPassword(),
-		from:     config.SMTPFrom(),
-		baseURL:  config.BaseURL(),
+		queue:   queue,
+		baseURL: config.BaseURL(),
 	}
 }
 
 func (s *EmailService) SendPasswordResetEmail(ctx context.Context, toEmail, token string) error {
 	resetLink := fmt.Sprintf("%s/reset-password?token=%s", s.baseURL, token)
 
-	subject := "Заявка за смяна на парола - Движи се"
-	body, err := s.renderPasswordResetTemplate(resetLink)
-	if err != nil {
-		return err
-	}
+	return s.sendEmail(ctx, toEmail, "Заявка за смяна на парола - Движи се", emails.PasswordReset(resetLink))
+}
 
-	return s.sendEmail(ctx, toEmail, subject, body)
-}
-
-func (s *EmailService) renderPasswordResetTemplate(resetLink string) (string, error) {
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
-    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
-        <h2 style="color: #2563eb;">Смяна на парола</h2>
-        <p>Получихме заявка за смяна на паролата на вашия акаунт.</p>
-        <p>Кликнете на бутона по-долу, за да зададете нова парола:</p>
-        <p style="margin: 30px 0;">
-            <a href="{{.ResetLink}}"
-               style="background-color: #2563eb; color: white; padding: 12px 24px; text-decoration: none; border-radius: 6px; display: inline-block;">
-                Смяна на паролата
-            </a>
-        </p>
-        <p style="color: #666; font-size: 14px;">
-            Този линк е валиден 1 час. Ако не сте заявили смяна на паролата, игнорирайте този имейл.
-        </p>
-        <hr style="border: none; border-top: 1px solid #eee; margin: 30px 0;">
-        <p style="color: #999; font-size: 12px;">
-            Движи се - Фитнес блог
-        </p>
-    </div>
-</body>
-</html>`
-
-	t, err := template.New("passwordReset").Parse(tmpl)
-	if err != nil {
-		return "", err
-	}
+func (s *EmailService) SendVerificationEmail(ctx context.Context, toEmail, token string) error {
+	verifyLink := fmt.Sprintf("%s/verify-email?token=%s", s.baseURL, token)
 
-	var buf bytes.Buffer
-	err = t.Execute(&buf, map[string]string{"ResetLink": resetLink})
-	if err != nil {
-		return "", err
-	}
+	return s.sendEmail(ctx, toEmail, "Потвърдете имейл адреса си - Движи се", emails.Verification(verifyLink))
+}
 
-	return buf.String(), nil
+func (s *EmailService) SendEmailChangeConfirmation(ctx context.Context, toEmail, token string) error {
+	confirmLink := fmt.Sprintf("%s/account/email/confirm?token=%s", s.baseURL, token)
+
+	return s.sendEmail(ctx, toEmail, "Потвърдете новия си имейл адрес - Движи се", emails.EmailChange(confirmLink))
 }
 
-func (s *EmailService) sendEmail(ctx context.Context, to, subject, htmlBody string) error {
-	// If SMTP is not configured, log the email instead (for development)
-	if s.host == "" || s.from == "" {
-		slog.InfoContext(ctx, "Email would be sent (SMTP not configured)",
-			"to", to,
-			"subject", subject,
-		)
-		slog.DebugContext(ctx, "Email body", "body", htmlBody)
-		return nil
-	}
+func (s *EmailService) SendDeletionConfirmation(ctx context.Context, toEmail, token string) error {
+	confirmLink := fmt.Sprintf("%s/account/delete/confirm?token=%s", s.baseURL, token)
 
-	headers := make(map[string]string)
-	headers["From"] = s.from
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
+	return s.sendEmail(ctx, toEmail, "Потвърдете изтриването на акаунта си - Движи се", emails.AccountDeletion(confirmLink))
+}
+
+func (s *EmailService) SendAccountLockedEmail(ctx context.Context, toEmail, token string, until time.Time) error {
+	unlockLink := fmt.Sprintf("%s/unlock-account?token=%s", s.baseURL, token)
+
+	return s.sendEmail(ctx, toEmail, "Акаунтът ви е временно заключен - Движи се",
+		emails.AccountLocked(unlockLink, until.UTC().Format("02.01.2006 15:04")))
+}
+
+// The notices below go to the address the account had before the change, so
+// the owner hears about it even when someone else is at the keyboard.
 
-	var msg bytes.Buffer
-	for k, v := range headers {
-		fmt.Fprintf(&msg, "%s: %s\r\n", k, v)
+func (s *EmailService) SendProfileChangedNotice(ctx context.Context, toEmail string) error {
+	return s.sendAccountNotice(ctx, toEmail, "Профилът ви беше променен - Движи се",
+		"Данните в профила ви бяха променени.")
+}
+
+func (s *EmailService) SendPasswordChangedNotice(ctx context.Context, toEmail string) error {
+	return s.sendAccountNotice(ctx, toEmail, "Паролата ви беше сменена - Движи се",
+		"Паролата на акаунта ви беше сменена и всички други сесии бяха прекратени.")
+}
+
+func (s *EmailService) SendEmailChangeRequestedNotice(ctx context.Context, toEmail, newEmail string) error {
+	return s.sendAccountNotice(ctx, toEmail, "Заявка за смяна на имейл - Движи се",
+		fmt.Sprintf("Беше заявена смяна на имейла на акаунта ви на %s. Смяната ще влезе в сила, след като бъде потвърдена от новия адрес.", newEmail))
+}
+
+func (s *EmailService) SendEmailChangedNotice(ctx context.Context, toEmail, newEmail string) error {
+	return s.sendAccountNotice(ctx, toEmail, "Имейлът ви беше сменен - Движи се",
+		fmt.Sprintf("Имейлът на акаунта ви беше сменен на %s. Писмата вече ще се изпращат на новия адрес.", newEmail))
+}
+
+func (s *EmailService) SendDeletionScheduledNotice(ctx context.Context, toEmail string, at time.Time) error {
+	return s.sendAccountNotice(ctx, toEmail, "Акаунтът ви ще бъде изтрит - Движи се",
+		fmt.Sprintf("Изтриването на акаунта ви е потвърдено и ще се извърши на %s. Дотогава можете да влезете и да го откажете от страницата на профила.", at.Format("02.01.2006")))
+}
+
+func (s *EmailService) SendDeletionCancelledNotice(ctx context.Context, toEmail string) error {
+	return s.sendAccountNotice(ctx, toEmail, "Изтриването на акаунта е отказано - Движи се",
+		"Изтриването на акаунта ви беше отказано. Акаунтът остава активен.")
+}
+
+func (s *EmailService) sendAccountNotice(ctx context.Context, toEmail, subject, message string) error {
+	return s.sendEmail(ctx, toEmail, subject, emails.AccountNotice(message, s.baseURL+"/forgot-password"))
+}
+
+func (s *EmailService) SendNewsletterConfirmation(ctx context.Context, toEmail, token string) error {
+	confirmLink := fmt.Sprintf("%s/newsletter/confirm?token=%s", s.baseURL, token)
+
+	return s.sendEmail(ctx, toEmail, "Потвърдете абонамента си - Движи се", emails.NewsletterConfirmation(confirmLink))
+}
+
+// SendNewsletterDigest mails the digest to one subscriber. The List-Unsubscribe
+// headers let mail clients offer their own unsubscribe button, and the Post
+// variant (RFC 8058) makes it one click, with no page to visit.
+func (s *EmailService) SendNewsletterDigest(ctx context.Context, toEmail, subject string, posts []newsletter.DigestPost, unsubscribeLink string) error {
+	return s.sendEmailWithHeaders(ctx, toEmail, subject, emails.NewsletterDigest(posts, unsubscribeLink), map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeLink + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	})
+}
+
+// SendContactReply answers a message sent through the contact form. The
+// reader is pointed back to the form rather than to this address, which
+// nobody reads.
+func (s *EmailService) SendContactReply(ctx context.Context, toEmail, name, subject, reply, original string) error {
+	return s.sendEmail(ctx, toEmail, "Re: "+subject, emails.ContactReply(name, reply, original, s.baseURL+"/contact"))
+}
+
+func (s *EmailService) sendEmail(ctx context.Context, to, subject string, content templ.Component) error {
+	return s.sendEmailWithHeaders(ctx, to, subject, content, nil)
+}
+
+// sendEmailWithHeaders renders the email and queues it with headers of the
+// caller's on top of the standard ones. Delivery happens in the background,
+// so an error here means only that the message could not be queued.
+func (s *EmailService) sendEmailWithHeaders(ctx context.Context, to, subject string, content templ.Component, extraHeaders map[string]string) error {
+	var body bytes.Buffer
+	if err := content.Render(ctx, &body); err != nil {
+		return err
 	}
-	msg.WriteString("\r\n")
-	msg.WriteString(htmlBody)
 
-	auth := smtp.PlainAuth("", s.username, s.password, s.host)
-	addr := fmt.Sprintf("%s:%s", s.host, s.port)
+	message := outbox.Message{
+		Id:        uuid.New(),
+		Recipient: to,
+		Subject:   subject,
+		Body:      body.String(),
+		Headers:   extraHeaders,
+		CreatedAt: time.Now().UTC(),
+	}
 
-	err := smtp.SendMail(addr, auth, s.from, []string{to}, msg.Bytes())
-	if err != nil {
-		slog.ErrorContext(ctx, "Failed to send email", "error", err, "to", to)
+	if err := s.queue.Enqueue(ctx, message); err != nil {
+		slog.ErrorContext(ctx, "Failed to queue email", "error", err, "to", to)
 		return err
 	}
 
-	slog.InfoContext(ctx, "Email sent successfully", "to", to, "subject", subject)
+	slog.InfoContext(ctx, "Email queued", "to", to, "subject", subject, "messageId", message.Id)
 	return nil
 }
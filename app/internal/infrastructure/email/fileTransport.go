@@ -0,0 +1,34 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileTransport writes every message into a directory as an .eml file, which
+// any mail client opens. It is for development and tests; nothing is sent.
+type FileTransport struct {
+	dir string
+	now func() time.Time
+}
+
+func NewFileTransport(dir string) *FileTransport {
+	return &FileTransport{dir: dir, now: time.Now}
+}
+
+func (t *FileTransport) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return err
+	}
+
+	// Sorting the directory by name sorts it by time. The messages carry live
+	// tokens, so only the owner may read them.
+	name := fmt.Sprintf("%s-%s.eml", t.now().UTC().Format("20060102T150405.000000000"), uuid.NewString())
+
+	return os.WriteFile(filepath.Join(t.dir, name), msg, 0o600)
+}
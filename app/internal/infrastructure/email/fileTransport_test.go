@@ -0,0 +1,47 @@
+package email
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Messages land as readable .eml files, one per send, in a directory that
+// does not have to exist yet, and are kept from other users since they carry
+// live tokens.
+func TestFileTransport_WritesEMLFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	transport := NewFileTransport(dir)
+
+	msg := []byte("Subject: test\r\n\r\nbody\r\n")
+	for range 2 {
+		if err := transport.Send(context.Background(), "noreply@example.com", []string{"reader@example.com"}, msg); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d .eml files, want 2", len(files))
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(msg) {
+		t.Errorf("file content = %q, want %q", content, msg)
+	}
+
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+}
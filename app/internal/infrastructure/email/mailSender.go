@@ -0,0 +1,96 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"server/internal/config"
+	"server/internal/domain/outbox"
+	"server/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MailSender delivers queued messages through the configured transport,
+// DKIM-signed when a key is set. Only the outbox worker uses it; everything
+// else queues through EmailService.
+type MailSender struct {
+	transport MailTransport
+	signer    *DKIMSigner
+	from      string
+	now       func() time.Time
+}
+
+// NewMailSender fails on a transport or DKIM key that cannot work, so a
+// misconfiguration stops the server at startup rather than failing every
+// message later.
+func NewMailSender() (*MailSender, error) {
+	transport, err := NewTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := dkimSignerFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MailSender{transport: transport, signer: signer, from: config.SMTPFrom(), now: time.Now}, nil
+}
+
+func (s *MailSender) Send(ctx context.Context, message outbox.Message) (err error) {
+	ctx, span := tracing.Start(ctx, "mail.Send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("mail.transport", fmt.Sprintf("%T", s.transport)),
+			attribute.String("mail.message_id", message.Id.String()),
+		),
+	)
+	defer func() {
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
+	now := s.now()
+
+	msg, err := buildMessage(s.from, message, now)
+	if err != nil {
+		return err
+	}
+
+	if s.signer != nil {
+		if msg, err = s.signer.Sign(msg, now); err != nil {
+			return err
+		}
+	}
+
+	if err := s.transport.Send(ctx, envelopeAddress(s.from), []string{message.Recipient}, msg); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Email sent successfully", "to", message.Recipient, "subject", message.Subject, "messageId", message.Id)
+	return nil
+}
+
+// dkimSignerFromConfig returns nil when signing is not configured.
+func dkimSignerFromConfig() (*DKIMSigner, error) {
+	if config.DKIMSelector() == "" || config.DKIMPrivateKeyFile() == "" {
+		return nil, nil
+	}
+
+	keyPEM, err := os.ReadFile(config.DKIMPrivateKeyFile())
+	if err != nil {
+		return nil, err
+	}
+
+	domain := config.DKIMDomain()
+	if domain == "" {
+		domain = domainOf(config.SMTPFrom())
+	}
+
+	return NewDKIMSigner(domain, config.DKIMSelector(), keyPEM)
+}
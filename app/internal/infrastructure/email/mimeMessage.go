@@ -0,0 +1,113 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+
+	"server/internal/domain/outbox"
+)
+
+// buildMessage assembles the message as sent over SMTP: a multipart/
+// alternative with the plain text version first and the HTML last, as
+// clients show the last part they understand. Both parts are quoted-printable
+// UTF-8, and headers with Cyrillic in them, such as the subject, are encoded
+// words, since raw 8-bit headers are a common reason mail is marked as spam.
+func buildMessage(from string, message outbox.Message, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", PlainText(message.Body)},
+		{"text/html; charset=UTF-8", message.Body},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	writeHeader := func(name, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, value)
+	}
+
+	writeHeader("From", encodeAddress(from))
+	writeHeader("To", encodeAddress(message.Recipient))
+	writeHeader("Subject", mime.BEncoding.Encode("UTF-8", message.Subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", fmt.Sprintf("<%s@%s>", message.Id, domainOf(from)))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}))
+
+	// The caller's own headers, such as List-Unsubscribe, in a stable order.
+	names := make([]string, 0, len(message.Headers))
+	for name := range message.Headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		writeHeader(name, mime.QEncoding.Encode("UTF-8", message.Headers[name]))
+	}
+
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// encodeAddress encodes the display name of an address such as
+// "Движи се <noreply@example.com>". Anything that does not parse is left as it
+// is for the server to judge.
+func encodeAddress(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+
+	return parsed.String()
+}
+
+// envelopeAddress is the bare address SMTP wants for MAIL FROM.
+func envelopeAddress(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+
+	return parsed.Address
+}
+
+func domainOf(address string) string {
+	bare := envelopeAddress(address)
+	if at := strings.LastIndex(bare, "@"); at >= 0 {
+		return bare[at+1:]
+	}
+
+	return "localhost"
+}
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/domain/outbox"
+
+	"github.com/google/uuid"
+)
+
+// The message must survive a strict parser: an encoded Cyrillic subject and
+// sender name, and a text part ahead of the HTML one, both decoding back to
+// what was queued.
+func TestBuildMessage_MultipartAlternativeWithEncodedHeaders(t *testing.T) {
+	message := outbox.Message{
+		Id:        uuid.New(),
+		Recipient: "reader@example.com",
+		Subject:   "Заявка за смяна на парола - Движи се",
+		Body:      `<p>Здравейте! <a href="https://example.com/reset?token=abc">Смяна на паролата</a></p>`,
+		Headers:   map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	}
+
+	raw, err := buildMessage("Движи се <noreply@example.com>", message, time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+
+	for _, b := range raw[:bytes.Index(raw, []byte("\r\n\r\n"))] {
+		if b > 127 {
+			t.Fatalf("headers contain raw 8-bit bytes:\n%s", raw)
+		}
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	if subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject")); err != nil || subject != message.Subject {
+		t.Errorf("Subject = %q, %v; want %q", subject, err, message.Subject)
+	}
+	if from, err := parsed.Header.AddressList("From"); err != nil || from[0].Name != "Движи се" || from[0].Address != "noreply@example.com" {
+		t.Errorf("From = %v, %v", from, err)
+	}
+	if got := parsed.Header.Get("Message-ID"); got != "<"+message.Id.String()+"@example.com>" {
+		t.Errorf("Message-ID = %q", got)
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<https://example.com/u>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	var types, contents []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		contents = append(contents, string(content))
+	}
+
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("parts = %v, want text then HTML", types)
+	}
+	// Quoted-printable text has CRLF line endings, as mail wants.
+	if contents[0] != "Здравейте! Смяна на паролата (https://example.com/reset?token=abc)\r\n" {
+		t.Errorf("text part = %q", contents[0])
+	}
+	if contents[1] != message.Body {
+		t.Errorf("HTML part = %q, want the body unchanged", contents[1])
+	}
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements end a line in the plain text version.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "hr": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// PlainText derives the text/plain alternative of an HTML email: the text in
+// reading order, one paragraph per block, and each link followed by its
+// address, which is all a text-only client can offer instead of a button.
+func PlainText(htmlBody string) string {
+	doc, err := html.Parse(strings.NewReader(htmlBody))
+	if err != nil {
+		return ""
+	}
+
+	var out strings.Builder
+	writePlainText(&out, doc)
+
+	// Collapse the spacing of the source, and the runs of blank lines the
+	// nested blocks leave behind.
+	var lines []string
+	blank := true
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+func writePlainText(out *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// Line breaks in the source are only spacing; the blocks decide
+		// where lines end.
+		out.WriteString(strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return ' '
+			}
+			return r
+		}, n.Data))
+		return
+	case html.ElementNode:
+		switch n.Data {
+		case "head", "style", "script", "img":
+			return
+		}
+	}
+
+	if n.Type == html.ElementNode && blockElements[n.Data] {
+		out.WriteString("\n\n")
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writePlainText(out, child)
+	}
+
+	if n.Type == html.ElementNode && n.Data == "a" {
+		if href := attr(n, "href"); href != "" && href != strings.TrimSpace(textOf(n)) {
+			out.WriteString(" (" + href + ")")
+		}
+	}
+
+	if n.Type == html.ElementNode && blockElements[n.Data] {
+		out.WriteString("\n\n")
+	}
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var text strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(textOf(child))
+	}
+
+	return text.String()
+}
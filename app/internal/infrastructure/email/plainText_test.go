@@ -0,0 +1,28 @@
+package email
+
+import "testing"
+
+// A text-only reader must still get every sentence and every link; buttons
+// become their label with the address beside it.
+func TestPlainText_KeepsTextAndLinks(t *testing.T) {
+	html := `<!DOCTYPE html>
+<html><head><title>Смяна на парола</title><style>p { color: red; }</style></head>
+<body>
+	<div><a href="https://example.com"><img src="logo.png" alt="logo"/> <span>Движи се</span></a></div>
+	<h2>Смяна на парола</h2>
+	<p>Получихме заявка за
+		смяна на паролата.</p>
+	<p><a href="https://example.com/reset?token=abc">Смяна на паролата</a></p>
+	<p>Вижте <a href="https://example.com/blog">https://example.com/blog</a>.</p>
+</body></html>`
+
+	want := "Движи се (https://example.com)\n\n" +
+		"Смяна на парола\n\n" +
+		"Получихме заявка за смяна на паролата.\n\n" +
+		"Смяна на паролата (https://example.com/reset?token=abc)\n\n" +
+		"Вижте https://example.com/blog.\n"
+
+	if got := PlainText(html); got != want {
+		t.Errorf("PlainText() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"server/internal/config"
+	"server/internal/domain/newsletter"
+	"server/web/templates/emails"
+
+	"github.com/a-h/templ"
+)
+
+var ErrUnknownPreview = errors.New("no email template with that name")
+
+// Preview is an email rendered with sample data, as it would be sent.
+type Preview struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// previews holds every template with sample data, so each one can be checked
+// in a browser without triggering the flow that sends it.
+var previews = map[string]func(baseURL string) (string, templ.Component){
+	"password-reset": func(baseURL string) (string, templ.Component) {
+		return "Заявка за смяна на парола - Движи се", emails.PasswordReset(baseURL + "/reset-password?token=sample")
+	},
+	"verification": func(baseURL string) (string, templ.Component) {
+		return "Потвърдете имейл адреса си - Движи се", emails.Verification(baseURL + "/verify-email?token=sample")
+	},
+	"email-change": func(baseURL string) (string, templ.Component) {
+		return "Потвърдете новия си имейл адрес - Движи се", emails.EmailChange(baseURL + "/account/email/confirm?token=sample")
+	},
+	"account-deletion": func(baseURL string) (string, templ.Component) {
+		return "Потвърдете изтриването на акаунта си - Движи се", emails.AccountDeletion(baseURL + "/account/delete/confirm?token=sample")
+	},
+	"account-locked": func(baseURL string) (string, templ.Component) {
+		return "Акаунтът ви е временно заключен - Движи се", emails.AccountLocked(baseURL+"/unlock-account?token=sample", time.Now().UTC().Add(time.Hour).Format("02.01.2006 15:04"))
+	},
+	"account-notice": func(baseURL string) (string, templ.Component) {
+		return "Паролата ви беше сменена - Движи се", emails.AccountNotice("Паролата на акаунта ви беше сменена и всички други сесии бяха прекратени.", baseURL+"/forgot-password")
+	},
+	"contact-reply": func(baseURL string) (string, templ.Component) {
+		return "Re: Въпрос за плана за 5 км", emails.ContactReply("Мария",
+			"Благодарим за въпроса!\n\nДа, планът е подходящ и за начинаещи. Започнете с първата седмица и не бързайте.",
+			"Здравейте,\nподходящ ли е планът за 5 км за човек, който никога не е бягал?", baseURL+"/contact")
+	},
+	"newsletter-confirmation": func(baseURL string) (string, templ.Component) {
+		return "Потвърдете абонамента си - Движи се", emails.NewsletterConfirmation(baseURL + "/newsletter/confirm?token=sample")
+	},
+	"newsletter-digest": func(baseURL string) (string, templ.Component) {
+		now := time.Now().UTC()
+		return "Новото в Движи се тази седмица", emails.NewsletterDigest([]newsletter.DigestPost{
+			{Title: "Първите 5 км без спиране", URL: baseURL + "/blog/parvite-5-km", Excerpt: "План за осем седмици от ходене до бягане.", PublishedAt: now.Add(-24 * time.Hour)},
+			{Title: "Клек с дъмбели", URL: baseURL + "/blog/klek-s-dambeli", PublishedAt: now.Add(-72 * time.Hour)},
+		}, baseURL+"/newsletter/unsubscribe?s=sample&sig=sample")
+	},
+}
+
+// PreviewNames lists the templates Preview knows, sorted.
+func PreviewNames() []string {
+	names := make([]string, 0, len(previews))
+	for name := range previews {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// RenderPreview renders the named template with sample data, along with the
+// plain text alternative it would be sent with.
+func RenderPreview(ctx context.Context, name string) (*Preview, error) {
+	sample, ok := previews[name]
+	if !ok {
+		return nil, ErrUnknownPreview
+	}
+
+	subject, content := sample(config.BaseURL())
+
+	var body bytes.Buffer
+	if err := content.Render(ctx, &body); err != nil {
+		return nil, err
+	}
+
+	return &Preview{Subject: subject, HTML: body.String(), Text: PlainText(body.String())}, nil
+}
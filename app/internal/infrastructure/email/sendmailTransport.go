@@ -0,0 +1,38 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SendmailTransport hands messages to the local MTA through its sendmail
+// command, which Postfix, Exim and msmtp all provide.
+type SendmailTransport struct {
+	path string
+}
+
+func NewSendmailTransport(path string) *SendmailTransport {
+	return &SendmailTransport{path: path}
+}
+
+func (t *SendmailTransport) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	// -i keeps a line holding a lone dot from ending the message, and "--"
+	// keeps a recipient from being read as an option.
+	args := append([]string{"-i", "-f", from, "--"}, to...)
+	cmd := exec.CommandContext(ctx, t.path, args...)
+
+	// sendmail expects local line endings and converts them itself.
+	cmd.Stdin = bytes.NewReader(bytes.ReplaceAll(msg, []byte("\r\n"), []byte("\n")))
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sendmail: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
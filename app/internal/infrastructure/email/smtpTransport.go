@@ -0,0 +1,114 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// smtpTimeout bounds a whole delivery when the context sets no deadline, so a
+// server that stops answering cannot hold the outbox worker forever.
+const smtpTimeout = 2 * time.Minute
+
+const (
+	tlsStartTLS = "starttls"
+	tlsImplicit = "tls"
+	tlsNone     = "none"
+)
+
+// SMTPTransport delivers to a mail server, usually the provider's relay.
+type SMTPTransport struct {
+	host     string
+	port     string
+	username string
+	password string
+	tlsMode  string
+}
+
+func NewSMTPTransport(host, port, username, password, tlsMode string) (*SMTPTransport, error) {
+	switch tlsMode {
+	case tlsStartTLS, tlsImplicit, tlsNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", tlsMode)
+	}
+
+	return &SMTPTransport{host: host, port: port, username: username, password: password, tlsMode: tlsMode}, nil
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	addr := net.JoinHostPort(t.host, t.port)
+	tlsConfig := &tls.Config{ServerName: t.host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if t.tlsMode == tlsImplicit {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// Cancelling ctx, on shutdown, interrupts a conversation in progress.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if t.tlsMode == tlsStartTLS {
+		// Carrying on in plain text would send the password and every
+		// message readable to anyone on the path.
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server does not offer STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if t.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.username, t.password, t.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
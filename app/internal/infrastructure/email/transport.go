@@ -0,0 +1,67 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"server/internal/config"
+)
+
+// MailTransport hands a finished message to whatever carries it onwards. from
+// and to are the envelope addresses, which need not match the headers.
+type MailTransport interface {
+	Send(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+// NewTransport returns the transport picked by MAIL_TRANSPORT.
+func NewTransport() (MailTransport, error) {
+	switch name := transportName(); name {
+	case "smtp":
+		return NewSMTPTransport(config.SMTPHost(), config.SMTPPort(), config.SMTPUsername(), config.SMTPPassword(), config.SMTPTLS())
+	case "sendmail":
+		return NewSendmailTransport(config.SendmailPath()), nil
+	case "file":
+		return NewFileTransport(config.MailDropDir()), nil
+	case "log":
+		return logTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", name)
+	}
+}
+
+// transportName is MAIL_TRANSPORT, or the default it implies: SMTP when a
+// host is set and logging otherwise.
+func transportName() string {
+	name := config.MailTransport()
+	if name == "" {
+		name = "log"
+		if config.SMTPHost() != "" {
+			name = "smtp"
+		}
+	}
+
+	return name
+}
+
+// SMTPAddr is the mail server's address when mail goes out over SMTP, and
+// empty otherwise. The health checks dial it.
+func SMTPAddr() string {
+	if transportName() != "smtp" {
+		return ""
+	}
+
+	return net.JoinHostPort(config.SMTPHost(), config.SMTPPort())
+}
+
+// logTransport only logs, so development works without a mail server. Use the
+// file transport to read what would have been sent.
+type logTransport struct{}
+
+func (logTransport) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	slog.InfoContext(ctx, "Email would be sent (no mail transport configured)", "from", from, "to", to, "size", len(msg))
+	slog.DebugContext(ctx, "Email message", "message", string(msg))
+
+	return nil
+}
@@ -0,0 +1,229 @@
+// Package imaging prepares uploaded images for the web. It turns a photo
+// upright and drops its metadata - the GPS position a phone writes into every
+// picture included - and makes narrower copies in JPEG or PNG and in the
+// modern formats, for srcset and <picture>.
+//
+// JPEG and PNG are encoded here. WebP and AVIF are handed to the cwebp and
+// avifenc commands, the way mail is handed to sendmail; a format whose
+// command is not installed is left out.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"log/slog"
+	"os/exec"
+	"slices"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// The formats images are stored in, as image.Decode names them.
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+	GIF  = "gif"
+	WebP = "webp"
+	AVIF = "avif"
+)
+
+var (
+	ErrUnreadable = errors.New("the image cannot be decoded")
+	ErrTooLarge   = errors.New("the image has too many pixels")
+)
+
+// maxPixels keeps a small file that declares a huge canvas from being
+// decoded. Fifty megapixels is more than any phone takes and decodes to about
+// 200 MB.
+const maxPixels = 50_000_000
+
+type Options struct {
+	// Quality is the JPEG, WebP and AVIF quality, 1 to 100.
+	Quality int
+	// WebPEncoder and AVIFEncoder are the cwebp and avifenc commands. Empty,
+	// or a command that cannot be found, leaves the format out.
+	WebPEncoder string
+	AVIFEncoder string
+}
+
+type Processor struct {
+	quality  int
+	encoders map[string]encoder
+	// modern are the formats offered through <source>, best first.
+	modern []string
+}
+
+func NewProcessor(opts Options) *Processor {
+	p := &Processor{
+		quality: opts.Quality,
+		encoders: map[string]encoder{
+			JPEG: jpegEncoder{},
+			PNG:  pngEncoder{},
+		},
+	}
+
+	// AVIF comes first because a browser takes the first <source> it
+	// supports, and AVIF is the smaller of the two.
+	for _, command := range []struct{ format, path string }{
+		{AVIF, opts.AVIFEncoder},
+		{WebP, opts.WebPEncoder},
+	} {
+		if command.path == "" {
+			continue
+		}
+		path, err := exec.LookPath(command.path)
+		if err != nil {
+			slog.Warn("Image encoder not found; uploads get no variants in this format", "format", command.format, "command", command.path)
+			continue
+		}
+		p.encoders[command.format] = newCommandEncoder(command.format, path)
+		p.modern = append(p.modern, command.format)
+	}
+
+	return p
+}
+
+// Formats lists the modern formats variants are made in.
+func (p *Processor) Formats() []string {
+	return slices.Clone(p.modern)
+}
+
+// Cleaned is an upload ready to be stored.
+type Cleaned struct {
+	Data   []byte
+	Format string
+	Width  int
+	Height int
+
+	// img is the decoded picture the variants are made from. Nil for a GIF,
+	// which is stored untouched.
+	img image.Image
+}
+
+// Clean decodes an upload, turns it upright and encodes it again, which
+// leaves every bit of metadata behind. It keeps the upload's format where it
+// can encode it, and falls back to JPEG, or PNG for an image with
+// transparency, where it cannot.
+//
+// A GIF is returned as it is: it cannot carry EXIF, and decoding it would
+// keep only the first frame of an animation.
+func (p *Processor) Clean(ctx context.Context, data []byte) (*Cleaned, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	if format == GIF {
+		return &Cleaned{Data: data, Format: GIF, Width: config.Width, Height: config.Height}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	img = orient(img, exifOrientation(format, data))
+
+	if _, ok := p.encoders[format]; !ok {
+		format = fallbackFormat(img)
+	}
+	encoded, err := p.encoders[format].encode(ctx, img, p.quality)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+
+	bounds := img.Bounds()
+	return &Cleaned{Data: encoded, Format: format, Width: bounds.Dx(), Height: bounds.Dy(), img: img}, nil
+}
+
+// Variant is one copy of an image.
+type Variant struct {
+	Format string
+	Width  int
+	Data   []byte
+}
+
+// Variants makes a copy of the cleaned image at each of widths that is
+// narrower than it, in the fallback format and in each modern format. An
+// image narrower than the widest also gets copies at its own width in the
+// formats it is not stored in, so a small picture still comes as AVIF.
+//
+// A format that fails is left out and reported in the error, alongside the
+// variants that were made.
+func (p *Processor) Variants(ctx context.Context, c *Cleaned, widths []int) ([]Variant, error) {
+	if c.img == nil {
+		return nil, nil
+	}
+
+	formats := append([]string{fallbackFormat(c.img)}, p.modern...)
+	failed := map[string]error{}
+
+	var variants []Variant
+	for _, width := range targetWidths(c.Width, widths) {
+		resized := c.img
+		if width < c.Width {
+			resized = resize(c.img, width)
+		}
+
+		for _, format := range formats {
+			if failed[format] != nil || (width == c.Width && format == c.Format) {
+				continue
+			}
+
+			data, err := p.encoders[format].encode(ctx, resized, p.quality)
+			if err != nil {
+				failed[format] = fmt.Errorf("%s at %dpx: %w", format, width, err)
+				continue
+			}
+			variants = append(variants, Variant{Format: format, Width: width, Data: data})
+		}
+	}
+
+	var errs []error
+	for _, format := range formats {
+		errs = append(errs, failed[format])
+	}
+	return variants, errors.Join(errs...)
+}
+
+// targetWidths are the widths copies are made at for an image original
+// pixels wide.
+func targetWidths(original int, widths []int) []int {
+	var targets []int
+	for _, width := range widths {
+		if width < original {
+			targets = append(targets, width)
+		}
+	}
+	if len(widths) > 0 && original <= slices.Max(widths) {
+		targets = append(targets, original)
+	}
+
+	slices.Sort(targets)
+	return slices.Compact(targets)
+}
+
+// fallbackFormat is the format every browser shows: PNG where there is
+// transparency to keep, JPEG otherwise.
+func fallbackFormat(img image.Image) string {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		return PNG
+	}
+	return JPEG
+}
+
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	height := max(1, (bounds.Dy()*width+bounds.Dx()/2)/bounds.Dx())
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+	return resized
+}
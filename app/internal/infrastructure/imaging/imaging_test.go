@@ -0,0 +1,205 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withExif inserts an APP1 segment after the start of a JPEG, the way a
+// camera writes it, holding an orientation and a stand-in for the GPS data.
+func withExif(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 42.6977N 23.3219E"...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	return append(append(append([]byte{}, jpg[:2]...), app1...), jpg[2:]...)
+}
+
+// halves is a w×h picture, red on the left half and blue on the right.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{B: 255, A: 255}
+			if x < w/2 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// A phone photo carries where it was taken and is stored sideways with a
+// note to turn it. Cleaning has to drop the first and act on the second, or
+// the picture ends up on its side once the note is gone.
+func TestClean_TurnsThePhotoUprightAndDropsTheMetadata(t *testing.T) {
+	upload := withExif(t, encodeJPEG(t, halves(32, 16)), 6)
+
+	cleaned, err := NewProcessor(Options{Quality: 90}).Clean(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("Clean() = %v", err)
+	}
+
+	if cleaned.Format != JPEG || cleaned.Width != 16 || cleaned.Height != 32 {
+		t.Errorf("Clean() = %s %dx%d, want a 16x32 jpeg", cleaned.Format, cleaned.Width, cleaned.Height)
+	}
+	if bytes.Contains(cleaned.Data, []byte("Exif")) || bytes.Contains(cleaned.Data, []byte("GPS")) {
+		t.Error("the cleaned image still carries the EXIF data")
+	}
+
+	// Turned clockwise, the red left half is now on top.
+	img, err := jpeg.Decode(bytes.NewReader(cleaned.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, b, _ := img.At(8, 4).RGBA(); r < b {
+		t.Error("the top of the cleaned image is not the left of the photo")
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	jpg := encodeJPEG(t, halves(4, 4))
+
+	for _, orientation := range []uint16{1, 3, 6, 8} {
+		if got := exifOrientation(JPEG, withExif(t, jpg, orientation)); got != int(orientation) {
+			t.Errorf("exifOrientation() = %d, want %d", got, orientation)
+		}
+	}
+	if got := exifOrientation(JPEG, jpg); got != 1 {
+		t.Errorf("exifOrientation() without EXIF = %d, want 1", got)
+	}
+	if got := exifOrientation(JPEG, []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}); got != 1 {
+		t.Errorf("exifOrientation() of a truncated segment = %d, want 1", got)
+	}
+}
+
+// fakeEncoder stands in for cwebp.
+type fakeEncoder struct{}
+
+func (fakeEncoder) encode(_ context.Context, img image.Image, _ int) ([]byte, error) {
+	return []byte(img.Bounds().String()), nil
+}
+
+// Nothing is scaled up, and the original is not stored twice; a picture
+// narrower than the widest width still gets the modern formats at its own
+// size.
+func TestVariants_Widths(t *testing.T) {
+	p := NewProcessor(Options{Quality: 80})
+	p.encoders[WebP] = fakeEncoder{}
+	p.modern = []string{WebP}
+
+	cleaned, err := p.Clean(context.Background(), encodeJPEG(t, halves(1000, 500)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants, err := p.Variants(context.Background(), cleaned, []int{400, 800, 1200})
+	if err != nil {
+		t.Fatalf("Variants() = %v", err)
+	}
+
+	var got []string
+	for _, v := range variants {
+		got = append(got, fmt.Sprintf("%s %d", v.Format, v.Width))
+	}
+	want := []string{"jpeg 400", "webp 400", "jpeg 800", "webp 800", "webp 1000"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Variants() = %v, want %v", got, want)
+	}
+
+	if string(variants[1].Data) != image.Rect(0, 0, 400, 200).String() {
+		t.Errorf("the 400 pixel WebP was made from a %s image", variants[1].Data)
+	}
+}
+
+// JPEG has no transparency, so a logo on a transparent background would get
+// a black box around it.
+func TestVariants_TransparencyStaysPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	p := NewProcessor(Options{Quality: 80})
+	cleaned, err := p.Clean(context.Background(), buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants, _ := p.Variants(context.Background(), cleaned, []int{400})
+
+	if cleaned.Format != PNG || len(variants) != 1 || variants[0].Format != PNG {
+		t.Errorf("Clean() = %s, Variants() = %+v, want png throughout", cleaned.Format, variants)
+	}
+}
+
+func TestClean_RefusesWhatIsNotAnImage(t *testing.T) {
+	if _, err := NewProcessor(Options{}).Clean(context.Background(), []byte("<svg/>")); err == nil {
+		t.Error("Clean() of an SVG succeeded")
+	}
+}
+
+// The command gets the picture as a file and leaves its result in another;
+// what it writes to stderr is what explains a failure.
+func TestCommandEncoder(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "encode")
+	os.WriteFile(script, []byte("#!/bin/sh\n[ \"$3\" = fail ] && { echo broken >&2; exit 1; }\ncp \"$1\" \"$2\"\n"), 0o755)
+
+	e := &commandEncoder{path: script, ext: ".webp", args: func(in, out string, quality int) []string {
+		if quality == 0 {
+			return []string{in, out, "fail"}
+		}
+		return []string{in, out, "ok"}
+	}}
+
+	data, err := e.encode(context.Background(), halves(6, 4), 80)
+	if err != nil {
+		t.Fatalf("encode() = %v", err)
+	}
+	if img, err := png.Decode(bytes.NewReader(data)); err != nil || img.Bounds().Dx() != 6 {
+		t.Errorf("encode() did not return what the command wrote (err %v)", err)
+	}
+
+	if _, err := e.encode(context.Background(), halves(6, 4), 0); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("encode() of a failing command = %v, want its stderr", err)
+	}
+}
+
+func TestNewProcessor_LeavesOutMissingEncoders(t *testing.T) {
+	p := NewProcessor(Options{WebPEncoder: "no-such-cwebp", AVIFEncoder: ""})
+
+	if formats := p.Formats(); len(formats) != 0 {
+		t.Errorf("Formats() = %v, want none", formats)
+	}
+}
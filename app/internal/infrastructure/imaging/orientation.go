@@ -0,0 +1,166 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// orientationTag is the EXIF tag that says how a camera was held. Phones
+// store the sensor's pixels as they are and set this instead of rotating
+// them, so dropping the metadata without applying it turns portraits on
+// their side.
+const orientationTag = 0x0112
+
+// exifOrientation returns the EXIF orientation of a JPEG or WebP, 1 to 8, or
+// 1 when there is none.
+func exifOrientation(format string, data []byte) int {
+	var tiff []byte
+	switch format {
+	case JPEG:
+		tiff = jpegExif(data)
+	case WebP:
+		tiff = webpExif(data)
+	}
+
+	if orientation := tiffOrientation(tiff); orientation >= 1 && orientation <= 8 {
+		return orientation
+	}
+	return 1
+}
+
+// jpegExif finds the APP1 segment holding EXIF among the segments before the
+// image data.
+func jpegExif(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	for at := 2; at+4 <= len(data); {
+		if data[at] != 0xFF {
+			return nil
+		}
+		marker := data[at+1]
+		// Start of scan: the metadata segments all come before it.
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[at+2:]))
+		end := at + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+
+		if segment := data[at+4 : end]; marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		at = end
+	}
+
+	return nil
+}
+
+// webpExif finds the EXIF chunk of an extended WebP.
+func webpExif(data []byte) []byte {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil
+	}
+
+	for at := 12; at+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[at+4:]))
+		end := at + 8 + size
+		if size < 0 || end > len(data) {
+			return nil
+		}
+
+		if string(data[at:at+4]) == "EXIF" {
+			// Some writers keep the JPEG style prefix.
+			return bytes.TrimPrefix(data[at+8:end], []byte("Exif\x00\x00"))
+		}
+		at = end + size%2
+	}
+
+	return nil
+}
+
+// tiffOrientation reads the orientation from the first directory of the TIFF
+// structure EXIF is stored in.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	directory := int(order.Uint32(tiff[4:]))
+	if directory < 8 || directory+2 > len(tiff) {
+		return 0
+	}
+
+	entries := int(order.Uint16(tiff[directory:]))
+	for i := range entries {
+		entry := directory + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 0
+}
+
+// orient turns img the way its EXIF orientation says it should be shown.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for dy := range dh {
+		for dx := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-dx, dy
+			case 3: // upside down
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // mirrored upside down
+				sx, sy = dx, h-1-dy
+			case 5: // mirrored, on its left side
+				sx, sy = dy, dx
+			case 6: // on its left side, shown turned clockwise
+				sx, sy = dy, h-1-dx
+			case 7: // mirrored, on its right side
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // on its right side, shown turned anticlockwise
+				sx, sy = w-1-dy, dx
+			}
+
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,71 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return string(content)
+}
+
+// Rotation keeps whole lines together, shifts older files up and drops the
+// one past the limit, so the disk use stays bounded.
+func TestRotatingFileRotatesAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := readFile(t, path); got != "fourth\n" {
+		t.Errorf("current = %q", got)
+	}
+	if got := readFile(t, path+".1"); got != "third\n" {
+		t.Errorf("backup 1 = %q", got)
+	}
+	if got := readFile(t, path+".2"); got != "second\n" {
+		t.Errorf("backup 2 = %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("a third backup was kept")
+	}
+}
+
+// A restart appends to the existing file and counts what is already in it.
+func TestRotatingFileAppendsAcrossOpens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("earlier\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write([]byte("later\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, path); got != "later\n" {
+		t.Errorf("current = %q, want a rotation since the existing content counts", got)
+	}
+	if got := readFile(t, path+".1"); got != "earlier\n" {
+		t.Errorf("backup = %q", got)
+	}
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import "database/sql"
+
+// RegisterDBStats exposes the connection pool of db. Call it once, for the
+// app's one pool.
+func RegisterDBStats(db *sql.DB) {
+	gauge := func(name, help string, read func(sql.DBStats) float64) {
+		NewGaugeFunc(name, help, nil, func(emit func(float64, ...string)) { emit(read(db.Stats())) })
+	}
+	counter := func(name, help string, read func(sql.DBStats) float64) {
+		NewCounterFunc(name, help, nil, func(emit func(float64, ...string)) { emit(read(db.Stats())) })
+	}
+
+	gauge("db_connections_max_open", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("db_connections_open", "Connections to the database, in use or idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("db_connections_in_use", "Connections to the database currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("db_connections_idle", "Idle connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+
+	// Waits are the number to watch: a pool too small for the load shows up
+	// here before anywhere else.
+	counter("db_connection_waits_total", "Times a query waited for a free connection.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("db_connection_wait_seconds_total", "Time spent waiting for a free connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+
+	NewCounterFunc("db_connections_closed_total", "Connections closed by the pool, by reason.", []string{"reason"}, func(emit func(float64, ...string)) {
+		s := db.Stats()
+		emit(float64(s.MaxIdleClosed), "max_idle")
+		emit(float64(s.MaxIdleTimeClosed), "max_idle_time")
+		emit(float64(s.MaxLifetimeClosed), "max_lifetime")
+	})
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// ContentType is the text exposition format's media type.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler serves Default. It is buffered, so a failing collector cannot leave
+// half a scrape on the wire.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var body bytes.Buffer
+		Default.Write(&body)
+
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(body.Bytes())
+	})
+}
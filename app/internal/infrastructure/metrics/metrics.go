@@ -0,0 +1,359 @@
+// Package metrics keeps counters, gauges and histograms in memory and writes
+// them in the Prometheus text exposition format (version 0.0.4), so the app
+// can be scraped without a client library or an agent.
+//
+// Metrics are created once, usually as package variables, and registered in
+// Default. Label values must come from a small known set - route patterns,
+// never raw paths - or the scrape grows without bound.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type kind string
+
+const (
+	kindCounter   kind = "counter"
+	kindGauge     kind = "gauge"
+	kindHistogram kind = "histogram"
+)
+
+// Registry holds the metrics one endpoint exposes.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]collector
+}
+
+// Default is the registry the constructors below register in and Handler
+// serves.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]collector{}}
+}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+// register panics on a duplicate name: two metrics with one name is a
+// programming mistake that would otherwise show up as a broken scrape.
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.families[name]; exists {
+		panic("metrics: " + name + " registered twice")
+	}
+	r.families[name] = c
+}
+
+// Write writes every metric, sorted by name.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make([]collector, 0, len(names))
+	slices.Sort(names)
+	for _, name := range names {
+		families = append(families, r.families[name])
+	}
+	r.mu.Unlock()
+
+	for _, family := range families {
+		family.write(w)
+	}
+}
+
+// family is what every metric type shares: its name, help, label names and
+// one series per distinct set of label values.
+type family[S any] struct {
+	name   string
+	help   string
+	kind   kind
+	labels []string
+	create func() *S
+
+	mu     sync.RWMutex
+	series map[string]*S
+	values map[string][]string
+}
+
+func newFamily[S any](name, help string, k kind, labels []string, create func() *S) *family[S] {
+	return &family[S]{
+		name:   name,
+		help:   help,
+		kind:   k,
+		labels: labels,
+		create: create,
+		series: map[string]*S{},
+		values: map[string][]string{},
+	}
+}
+
+func (f *family[S]) with(values []string) *S {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	s = f.create()
+	f.series[key] = s
+	f.values[key] = slices.Clone(values)
+
+	return s
+}
+
+// each calls fn for every series, ordered by label values.
+func (f *family[S]) each(fn func(values []string, s *S)) {
+	f.mu.RLock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	f.mu.RUnlock()
+
+	slices.Sort(keys)
+	for _, key := range keys {
+		f.mu.RLock()
+		s, values := f.series[key], f.values[key]
+		f.mu.RUnlock()
+		fn(values, s)
+	}
+}
+
+func (f *family[S]) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+}
+
+// value is a float64 updated atomically.
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) set(x float64) { v.bits.Store(math.Float64bits(x)) }
+func (v *value) get() float64  { return math.Float64frombits(v.bits.Load()) }
+
+func newValue() *value { return &value{} }
+
+func (v *value) write(w io.Writer, name string, labels, values []string) {
+	fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels, values), formatFloat(v.get()))
+}
+
+// Counter only goes up.
+type Counter struct{ v *value }
+
+func (c Counter) Inc()              { c.v.add(1) }
+func (c Counter) Add(delta float64) { c.v.add(max(delta, 0)) }
+
+// CounterVec is a counter with labels.
+type CounterVec struct{ f *family[value] }
+
+// NewCounterVec registers a counter in Default. With no label names it has a
+// single series, reached with With().
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{f: newFamily(name, help, kindCounter, labels, newValue)}
+	Default.register(name, c)
+	return c
+}
+
+// With returns the counter for the given label values, in the order the
+// label names were declared.
+func (c *CounterVec) With(values ...string) Counter { return Counter{c.f.with(values)} }
+
+func (c *CounterVec) write(w io.Writer) {
+	c.f.header(w)
+	c.f.each(func(values []string, v *value) { v.write(w, c.f.name, c.f.labels, values) })
+}
+
+// Gauge goes up and down.
+type Gauge struct{ v *value }
+
+func (g Gauge) Set(x float64)     { g.v.set(x) }
+func (g Gauge) Add(delta float64) { g.v.add(delta) }
+
+type GaugeVec struct{ f *family[value] }
+
+// NewGaugeVec registers a gauge in Default.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{f: newFamily(name, help, kindGauge, labels, newValue)}
+	Default.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) With(values ...string) Gauge { return Gauge{g.f.with(values)} }
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.f.header(w)
+	g.f.each(func(values []string, v *value) { v.write(w, g.f.name, g.f.labels, values) })
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct{ h *histogram }
+
+type histogram struct {
+	bounds []float64
+	counts []atomic.Uint64
+	sum    value
+	count  atomic.Uint64
+}
+
+func (h Histogram) Observe(x float64) {
+	// Buckets are written cumulatively; each observation lands in the first
+	// one it fits.
+	i, _ := slices.BinarySearch(h.h.bounds, x)
+	h.h.counts[i].Add(1)
+	h.h.sum.add(x)
+	h.h.count.Add(1)
+}
+
+type HistogramVec struct {
+	f      *family[histogram]
+	bounds []float64
+}
+
+// NewHistogramVec registers a histogram in Default with the given upper
+// bounds; the +Inf bucket is added.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+
+	h := &HistogramVec{bounds: bounds}
+	h.f = newFamily(name, help, kindHistogram, labels, func() *histogram {
+		return &histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+	})
+	Default.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) With(values ...string) Histogram { return Histogram{h.f.with(values)} }
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.f.header(w)
+	bucketLabels := append(slices.Clone(h.f.labels), "le")
+
+	h.f.each(func(values []string, s *histogram) {
+		var cumulative uint64
+		for i, bound := range s.bounds {
+			cumulative += s.counts[i].Load()
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.f.name, formatLabels(bucketLabels, append(slices.Clone(values), formatFloat(bound))), cumulative)
+		}
+		cumulative += s.counts[len(s.bounds)].Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.f.name, formatLabels(bucketLabels, append(slices.Clone(values), "+Inf")), cumulative)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.f.name, formatLabels(h.f.labels, values), formatFloat(s.sum.get()))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.f.name, formatLabels(h.f.labels, values), s.count.Load())
+	})
+}
+
+// funcFamily reads its values when scraped, for numbers that something else
+// already keeps, such as the database pool's.
+type funcFamily struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	collect func(emit func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc registers a gauge whose series are whatever collect emits at
+// scrape time.
+func NewGaugeFunc(name, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) {
+	Default.register(name, &funcFamily{name: name, help: help, kind: kindGauge, labels: labels, collect: collect})
+}
+
+// NewCounterFunc is NewGaugeFunc for a total kept elsewhere that only grows.
+func NewCounterFunc(name, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) {
+	Default.register(name, &funcFamily{name: name, help: help, kind: kindCounter, labels: labels, collect: collect})
+}
+
+func (f *funcFamily) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+	f.collect(func(v float64, values ...string) {
+		fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, values), formatFloat(v))
+	})
+}
+
+// DefBuckets suit request latencies in seconds, from 5ms to 10s.
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ExponentialBuckets returns count bounds starting at start, each factor times
+// the one before.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string { return helpEscaper.Replace(help) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
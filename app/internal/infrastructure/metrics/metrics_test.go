@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Buckets are cumulative and a value equal to a bound belongs in that bound's
+// bucket; getting either wrong shifts every percentile a dashboard draws.
+func TestHistogramWritesCumulativeBuckets(t *testing.T) {
+	h := NewHistogramVec("test_histogram_seconds", "A test histogram.", []float64{1, 0.5}, "route")
+	h.With("/a").Observe(0.5)
+	h.With("/a").Observe(0.7)
+	h.With("/a").Observe(3)
+
+	var out bytes.Buffer
+	h.write(&out)
+
+	want := `# HELP test_histogram_seconds A test histogram.
+# TYPE test_histogram_seconds histogram
+test_histogram_seconds_bucket{route="/a",le="0.5"} 1
+test_histogram_seconds_bucket{route="/a",le="1"} 2
+test_histogram_seconds_bucket{route="/a",le="+Inf"} 3
+test_histogram_seconds_sum{route="/a"} 4.2
+test_histogram_seconds_count{route="/a"} 3
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+// Label values come from requests and limiter names; a quote or newline left
+// unescaped would break the whole scrape, not just one line.
+func TestLabelValuesAreEscaped(t *testing.T) {
+	c := NewCounterVec("test_escaped_total", "Help with a \\ and\na newline.", "value")
+	c.With("say \"hi\"\n\\").Inc()
+
+	var out bytes.Buffer
+	c.write(&out)
+
+	if !strings.Contains(out.String(), `# HELP test_escaped_total Help with a \\ and\na newline.`) {
+		t.Errorf("help not escaped:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), `test_escaped_total{value="say \"hi\"\n\\"} 1`) {
+		t.Errorf("label not escaped:\n%s", out.String())
+	}
+}
+
+// Counters only go up; a negative Add would make rate() report a reset.
+func TestCounterIgnoresNegativeAdd(t *testing.T) {
+	c := NewCounterVec("test_monotonic_total", "A counter.")
+	c.With().Add(2)
+	c.With().Add(-5)
+
+	var out bytes.Buffer
+	c.write(&out)
+
+	if !strings.Contains(out.String(), "test_monotonic_total 2\n") {
+		t.Errorf("got\n%s", out.String())
+	}
+}
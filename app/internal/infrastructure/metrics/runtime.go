@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+var startTime = time.Now()
+
+func init() {
+	NewGaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch.", nil, func(emit func(float64, ...string)) {
+		emit(float64(startTime.UnixNano()) / 1e9)
+	})
+	NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", nil, func(emit func(float64, ...string)) {
+		emit(float64(runtime.NumGoroutine()))
+	})
+	NewGaugeFunc("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.", nil, func(emit func(float64, ...string)) {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		emit(float64(stats.HeapAlloc))
+	})
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"server/internal/config"
+	"server/internal/infrastructure/tracing"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// Cloudinary stores uploads in a Cloudinary folder. Keys are public ids;
+// files go in a files subfolder, which is how Delete tells them from images,
+// since Cloudinary keeps the two apart.
+type Cloudinary struct {
+	client *cloudinary.Cloudinary
+	folder string
+}
+
+func NewCloudinary() (*Cloudinary, error) {
+	if !config.CloudinaryConfigured() {
+		return nil, fmt.Errorf("cloudinary credentials not configured")
+	}
+
+	cld, err := cloudinary.NewFromParams(
+		config.CloudinaryCloudName(),
+		config.CloudinaryAPIKey(),
+		config.CloudinaryAPISecret(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloudinary client: %w", err)
+	}
+
+	return &Cloudinary{
+		client: cld,
+		folder: config.CloudinaryFolder(),
+	}, nil
+}
+
+func (s *Cloudinary) UploadImage(ctx context.Context, file io.Reader, filename string) (*UploadResult, error) {
+	ctx, span := startSpan(ctx, "cloudinary.UploadImage", filename)
+	defer span.End()
+
+	ext := filepath.Ext(filename)
+	name := strings.TrimSuffix(filename, ext)
+
+	uploadParams := uploader.UploadParams{
+		Folder:   s.folder,
+		PublicID: name,
+	}
+
+	result, err := s.client.Upload.Upload(ctx, file, uploadParams)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+
+	return &UploadResult{
+		URL: result.SecureURL,
+		Key: result.PublicID,
+	}, nil
+}
+
+func (s *Cloudinary) UploadRaw(ctx context.Context, file io.Reader, filename string) (*UploadResult, error) {
+	ctx, span := startSpan(ctx, "cloudinary.UploadRaw", filename)
+	defer span.End()
+
+	ext := filepath.Ext(filename)
+	name := strings.TrimSuffix(filename, ext)
+
+	uploadParams := uploader.UploadParams{
+		Folder:       s.filesFolder(),
+		PublicID:     name,
+		ResourceType: "raw",
+	}
+
+	result, err := s.client.Upload.Upload(ctx, file, uploadParams)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return &UploadResult{
+		URL: result.SecureURL,
+		Key: result.PublicID,
+	}, nil
+}
+
+func (s *Cloudinary) Delete(ctx context.Context, key string) error {
+	ctx, span := startSpan(ctx, "cloudinary.Delete", key)
+	defer span.End()
+
+	_, err := s.client.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     key,
+		ResourceType: s.resourceType(key),
+	})
+	if err != nil {
+		tracing.Fail(span, err)
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+	return nil
+}
+
+func (s *Cloudinary) PublicURL(key string) string {
+	build := s.client.Image
+	if s.resourceType(key) == "raw" {
+		build = s.client.File
+	}
+
+	asset, err := build(key)
+	if err != nil {
+		return ""
+	}
+	asset.Config.URL.Secure = true
+
+	url, err := asset.String()
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// List returns every image and file in the folder.
+func (s *Cloudinary) List(ctx context.Context) ([]Asset, error) {
+	ctx, span := startSpan(ctx, "cloudinary.List", s.folder)
+	defer span.End()
+
+	var assets []Asset
+	for _, assetType := range []api.AssetType{api.Image, api.File} {
+		params := admin.AssetsParams{
+			AssetType:    assetType,
+			DeliveryType: "upload",
+			Prefix:       s.folder + "/",
+			MaxResults:   500,
+		}
+
+		for {
+			result, err := s.client.Admin.Assets(ctx, params)
+			if err == nil && result.Error.Message != "" {
+				err = errors.New(result.Error.Message)
+			}
+			if err != nil {
+				tracing.Fail(span, err)
+				return nil, fmt.Errorf("failed to list assets: %w", err)
+			}
+
+			for _, found := range result.Assets {
+				assets = append(assets, Asset{
+					Key:       found.PublicID,
+					URL:       found.SecureURL,
+					Bytes:     int64(found.Bytes),
+					CreatedAt: found.CreatedAt,
+				})
+			}
+
+			if result.NextCursor == "" {
+				break
+			}
+			params.NextCursor = result.NextCursor
+		}
+	}
+
+	return assets, nil
+}
+
+func (s *Cloudinary) filesFolder() string {
+	return s.folder + "/files"
+}
+
+// resourceType is what Cloudinary filed the key under: "raw" for anything
+// UploadRaw stored, "image" for the rest.
+func (s *Cloudinary) resourceType(key string) string {
+	if strings.HasPrefix(key, s.filesFolder()+"/") {
+		return "raw"
+	}
+	return "image"
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"server/internal/infrastructure/tracing"
+)
+
+// Local stores uploads in a directory on this machine, for development, tests
+// and single server setups. Handler serves them. All access goes through an
+// os.Root, so a key cannot reach outside the directory.
+type Local struct {
+	root      *os.Root
+	urlPrefix string
+	now       func() time.Time
+}
+
+// NewLocal stores under dir, creating it if needed, and serves under
+// urlPrefix, which ends in a slash.
+func NewLocal(dir, urlPrefix string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage directory: %w", err)
+	}
+
+	return &Local{
+		root:      root,
+		urlPrefix: urlPrefix,
+		now:       time.Now,
+	}, nil
+}
+
+func (s *Local) UploadImage(ctx context.Context, file io.Reader, filename string) (*UploadResult, error) {
+	ctx, span := startSpan(ctx, "local.UploadImage", filename)
+	defer span.End()
+
+	file, ext, err := sniffImage(file, filename)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	result, err := s.save(newKey(imagesPrefix, filename, ext, s.now()), file)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+	return result, nil
+}
+
+func (s *Local) UploadRaw(ctx context.Context, file io.Reader, filename string) (*UploadResult, error) {
+	ctx, span := startSpan(ctx, "local.UploadRaw", filename)
+	defer span.End()
+
+	result, err := s.save(newKey(filesPrefix, filename, strings.ToLower(path.Ext(filename)), s.now()), file)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+	return result, nil
+}
+
+// save writes to a temporary name and renames it into place, so a request
+// for the key never sees half a file.
+func (s *Local) save(key string, file io.Reader) (*UploadResult, error) {
+	if err := s.root.MkdirAll(path.Dir(key), 0o755); err != nil {
+		return nil, err
+	}
+
+	temp := path.Join(path.Dir(key), "."+path.Base(key)+".part")
+	out, err := s.root.Create(temp)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(out, file)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = s.root.Rename(temp, key)
+	}
+	if err != nil {
+		s.root.Remove(temp)
+		return nil, err
+	}
+
+	return &UploadResult{URL: s.PublicURL(key), Key: key}, nil
+}
+
+func (s *Local) Delete(ctx context.Context, key string) error {
+	_, span := startSpan(ctx, "local.Delete", key)
+	defer span.End()
+
+	if err := s.root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		tracing.Fail(span, err)
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+	return nil
+}
+
+func (s *Local) PublicURL(key string) string {
+	return s.urlPrefix + key
+}
+
+// List walks the directory. Files still being written are left out.
+func (s *Local) List(ctx context.Context) ([]Asset, error) {
+	_, span := startSpan(ctx, "local.List", "")
+	defer span.End()
+
+	var assets []Asset
+	err := fs.WalkDir(s.root.FS(), ".", func(key string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		assets = append(assets, Asset{
+			Key:       key,
+			URL:       s.PublicURL(key),
+			Bytes:     info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to list assets: %w", err)
+	}
+
+	return assets, nil
+}
+
+// Handler serves the stored files, mounted with the URL prefix stripped.
+// Keys never change content, so responses are cached for a year. Uploads are
+// whatever an editor picked, so they are also sandboxed: a file that turns
+// out to be HTML cannot run script as this site.
+func (s *Local) Handler() http.Handler {
+	files := http.FileServerFS(s.root.FS())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if key == "" || strings.HasSuffix(key, "/") || strings.HasPrefix(path.Base(key), ".") {
+			http.NotFound(w, r)
+			return
+		}
+
+		info, err := s.root.Stat(key)
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType(key))
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestLocal(t *testing.T) (*Local, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := NewLocal(dir, "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.now = func() time.Time { return time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC) }
+	return store, dir
+}
+
+// The extension is what a browser goes by when the object is served, so it
+// has to follow the bytes: a PNG saved from a phone as "IMG 001.JPG" must not
+// be stored as a JPEG.
+func TestLocal_UploadImageNamesTheKeyAfterTheContent(t *testing.T) {
+	store, dir := newTestLocal(t)
+	content := pngBytes(t)
+
+	result, err := store.UploadImage(context.Background(), bytes.NewReader(content), "IMG 001.JPG")
+	if err != nil {
+		t.Fatalf("UploadImage() = %v", err)
+	}
+
+	if !strings.HasPrefix(result.Key, "images/2026/05/img-001-") || !strings.HasSuffix(result.Key, ".png") {
+		t.Errorf("key = %q, want images/2026/05/img-001-<random>.png", result.Key)
+	}
+	if result.URL != "/media/"+result.Key {
+		t.Errorf("URL = %q, want it under /media/", result.URL)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(result.Key)))
+	if err != nil || !bytes.Equal(stored, content) {
+		t.Errorf("stored file differs from the upload (err %v)", err)
+	}
+
+	again, _ := store.UploadImage(context.Background(), bytes.NewReader(content), "IMG 001.JPG")
+	if again.Key == result.Key {
+		t.Error("a second upload of the same name replaced the first")
+	}
+}
+
+// Keys never change content, so the handler may tell browsers to keep them
+// for good; what it must not do is serve anything outside the directory or
+// let an uploaded file run as a page of this site.
+func TestLocal_Handler(t *testing.T) {
+	store, dir := newTestLocal(t)
+	result, err := store.UploadRaw(context.Background(), strings.NewReader("<gpx/>"), "Run.GPX")
+	if err != nil {
+		t.Fatalf("UploadRaw() = %v", err)
+	}
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("secret"), 0o600)
+
+	handler := http.StripPrefix("/media", store.Handler())
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get(result.URL)
+	if rec.Code != http.StatusOK || rec.Body.String() != "<gpx/>" {
+		t.Fatalf("GET %s = %d %q", result.URL, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/gpx+xml" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); !strings.Contains(got, "immutable") {
+		t.Errorf("Cache-Control = %q, want a long lived immutable response", got)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); !strings.Contains(got, "sandbox") {
+		t.Errorf("Content-Security-Policy = %q, want the upload sandboxed", got)
+	}
+
+	for _, path := range []string{"/media/", "/media/files/", "/media/../secret.txt", "/media/files/2026/05/missing.gpx"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, rec.Code)
+		}
+	}
+
+	if err := store.Delete(context.Background(), result.Key); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if rec := get(result.URL); rec.Code != http.StatusNotFound {
+		t.Errorf("GET after Delete = %d, want 404", rec.Code)
+	}
+	if err := store.Delete(context.Background(), result.Key); err != nil {
+		t.Errorf("deleting twice = %v, want nil", err)
+	}
+}
+
+// The orphan sweep lists what is stored and deletes what nothing links to, so
+// a file still being written must not show up in the list.
+func TestLocal_ListSkipsPartialUploads(t *testing.T) {
+	store, dir := newTestLocal(t)
+	result, err := store.UploadRaw(context.Background(), strings.NewReader("<gpx/>"), "run.gpx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "files", ".upload.gpx.part"), []byte("half"), 0o600)
+
+	assets, err := store.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() = %v", err)
+	}
+
+	if len(assets) != 1 || assets[0].Key != result.Key || assets[0].Bytes != 6 {
+		t.Errorf("List() = %+v, want only %s", assets, result.Key)
+	}
+}
+
+// Reading the first bytes to sniff the type must not lose them.
+func TestSniffImageKeepsTheWholeFile(t *testing.T) {
+	content := pngBytes(t)
+
+	r, ext, err := sniffImage(bytes.NewReader(content), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+
+	if ext != ".png" || !bytes.Equal(got, content) {
+		t.Errorf("sniffImage() = %q and %d bytes, want .png and %d", ext, len(got), len(content))
+	}
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"server/internal/infrastructure/tracing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options configure an S3 compatible bucket: AWS, MinIO, R2 and the like.
+type S3Options struct {
+	// Endpoint is the service URL, such as https://s3.eu-central-1.amazonaws.com
+	// or http://localhost:9000 for MinIO.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PublicURL is where the bucket is read from, usually a CDN in front of
+	// it. Empty means Endpoint/Bucket, which needs the bucket to allow
+	// anonymous reads.
+	PublicURL string
+}
+
+// S3 stores uploads in a bucket. Keys are object names.
+type S3 struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+	now       func() time.Time
+}
+
+// uploadPartSize bounds the memory an upload of unknown length takes: the
+// client buffers one part at a time, and anything smaller goes up in a
+// single request.
+const uploadPartSize = 16 << 20
+
+func NewS3(opts S3Options) (*S3, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+	}
+
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: endpoint.Scheme == "https",
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	publicURL := opts.PublicURL
+	if publicURL == "" {
+		publicURL = strings.TrimSuffix(opts.Endpoint, "/") + "/" + opts.Bucket
+	}
+
+	return &S3{
+		client:    client,
+		bucket:    opts.Bucket,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		now:       time.Now,
+	}, nil
+}
+
+func (s *S3) UploadImage(ctx context.Context, file io.Reader, filename string) (*UploadResult, error) {
+	ctx, span := startSpan(ctx, "s3.UploadImage", filename)
+	defer span.End()
+
+	file, ext, err := sniffImage(file, filename)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	result, err := s.put(ctx, newKey(imagesPrefix, filename, ext, s.now()), file)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+	return result, nil
+}
+
+func (s *S3) UploadRaw(ctx context.Context, file io.Reader, filename string) (*UploadResult, error) {
+	ctx, span := startSpan(ctx, "s3.UploadRaw", filename)
+	defer span.End()
+
+	result, err := s.put(ctx, newKey(filesPrefix, filename, strings.ToLower(path.Ext(filename)), s.now()), file)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+	return result, nil
+}
+
+func (s *S3) put(ctx context.Context, key string, file io.Reader) (*UploadResult, error) {
+	_, err := s.client.PutObject(ctx, s.bucket, key, file, -1, minio.PutObjectOptions{
+		ContentType:  contentType(key),
+		CacheControl: cacheControl,
+		PartSize:     uploadPartSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{URL: s.PublicURL(key), Key: key}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	ctx, span := startSpan(ctx, "s3.Delete", key)
+	defer span.End()
+
+	// S3 answers a delete of a missing object with success, which is what
+	// Storage promises anyway.
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		tracing.Fail(span, err)
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+	return nil
+}
+
+func (s *S3) PublicURL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+// List returns every object in the bucket under the upload prefixes.
+func (s *S3) List(ctx context.Context) ([]Asset, error) {
+	ctx, span := startSpan(ctx, "s3.List", s.bucket)
+	defer span.End()
+
+	// Returning early leaves the listing goroutine waiting to send; cancelling
+	// is how it is told to stop.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var assets []Asset
+	for _, prefix := range []string{imagesPrefix, filesPrefix} {
+		for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix + "/", Recursive: true}) {
+			if object.Err != nil {
+				tracing.Fail(span, object.Err)
+				return nil, fmt.Errorf("failed to list assets: %w", object.Err)
+			}
+
+			assets = append(assets, Asset{
+				Key:       object.Key,
+				URL:       s.PublicURL(object.Key),
+				Bytes:     object.Size,
+				CreatedAt: object.LastModified,
+			})
+		}
+	}
+
+	return assets, nil
+}
@@ -0,0 +1,170 @@
+// Package storage keeps uploaded images and files. The backend is picked by
+// STORAGE_BACKEND: Cloudinary, a directory served under /media/, or an S3
+// compatible bucket.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Storage is where uploads go. Keys are what the backend calls the object,
+// and are what Delete and PublicURL take back.
+type Storage interface {
+	// UploadImage stores an image for posts and covers.
+	UploadImage(ctx context.Context, file io.Reader, filename string) (*UploadResult, error)
+	// UploadRaw stores a file that is served as is, such as a GPX track.
+	UploadRaw(ctx context.Context, file io.Reader, filename string) (*UploadResult, error)
+	// Delete removes an object. Deleting one that is already gone is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// PublicURL is where visitors fetch the object from.
+	PublicURL(key string) string
+	// List returns every object the backend holds for this site.
+	List(ctx context.Context) ([]Asset, error)
+}
+
+type UploadResult struct {
+	URL string
+	Key string
+}
+
+// Asset is an object found by List.
+type Asset struct {
+	Key       string
+	URL       string
+	Bytes     int64
+	CreatedAt time.Time
+}
+
+// Where the two kinds of upload live, for the backends that name keys
+// themselves. Cloudinary keeps its own folder layout.
+const (
+	imagesPrefix = "images"
+	filesPrefix  = "files"
+)
+
+// New builds the configured backend.
+func New() (Storage, error) {
+	// Each case returns its own nil rather than the constructor's, which
+	// would be a typed nil and compare unequal to nil as a Storage.
+	switch backend := config.StorageBackend(); backend {
+	case "cloudinary":
+		store, err := NewCloudinary()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "local":
+		store, err := NewLocal(config.StorageLocalDir(), "/media/")
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "s3":
+		store, err := NewS3(S3Options{
+			Endpoint:  config.S3Endpoint(),
+			Region:    config.S3Region(),
+			Bucket:    config.S3Bucket(),
+			AccessKey: config.S3AccessKeyID(),
+			SecretKey: config.S3SecretAccessKey(),
+			PublicURL: config.S3PublicURL(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// imageExtensions maps the image types the upload handler accepts to the
+// extension they are stored under.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// sniffImage reads the start of file to name the extension after what the
+// bytes are rather than what the file was called, so the object is served
+// with the right type. The returned reader still yields the whole file.
+func sniffImage(file io.Reader, filename string) (io.Reader, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, "", err
+	}
+	head = head[:n]
+
+	ext, ok := imageExtensions[http.DetectContentType(head)]
+	if !ok {
+		ext = strings.ToLower(path.Ext(filename))
+	}
+
+	return io.MultiReader(bytes.NewReader(head), file), ext, nil
+}
+
+var unsafeKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// newKey names an upload prefix/yyyy/mm/name-xxxxxxxx.ext. The random part
+// keeps two uploads of photo.jpg apart and means an object never changes
+// under its key, so it can be cached for good.
+func newKey(prefix, filename, ext string, now time.Time) string {
+	name := strings.TrimSuffix(path.Base(strings.ReplaceAll(filename, `\`, "/")), path.Ext(filename))
+	name = strings.Trim(unsafeKeyChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "-")
+	}
+	if name == "" {
+		name = strings.TrimSuffix(prefix, "s")
+	}
+
+	random := make([]byte, 4)
+	rand.Read(random)
+
+	return fmt.Sprintf("%s/%s/%s-%s%s", prefix, now.UTC().Format("2006/01"), name, hex.EncodeToString(random), ext)
+}
+
+// contentType is what an object is served as, going by its key.
+func contentType(key string) string {
+	ext := strings.ToLower(path.Ext(key))
+	if ext == ".gpx" {
+		return "application/gpx+xml"
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// cacheControl is sent with every object. Keys are never reused, so the
+// object under one never changes.
+const cacheControl = "public, max-age=31536000, immutable"
+
+// startSpan opens a client span for a call to a backend. The upload time is
+// usually most of the request, so it should stand apart in a trace.
+func startSpan(ctx context.Context, name, file string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage.file", file)),
+	)
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer gives every SQL statement its own span. database/sql passes the
+// caller's context down to pgx, so the span lands under whichever service
+// method ran the query.
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := operationOf(data.SQL)
+
+	ctx, _ = Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+
+	return ctx
+}
+
+// TraceQueryEnd runs when the rows are closed, so the span covers reading
+// the results as well as the round trip.
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	Fail(span, data.Err)
+	if data.Err == nil {
+		span.SetAttributes(semconv.DBResponseReturnedRows(int(data.CommandTag.RowsAffected())))
+	}
+	span.End()
+}
+
+// operationOf is the statement's leading keyword, such as SELECT or UPDATE,
+// which names the span. The text itself is an attribute: spans named by
+// whole statements cannot be grouped.
+func operationOf(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if i := strings.IndexFunc(sql, func(r rune) bool { return !unicode.IsLetter(r) }); i >= 0 {
+		sql = sql[:i]
+	}
+	if sql == "" {
+		return "SQL"
+	}
+	return strings.ToUpper(sql)
+}
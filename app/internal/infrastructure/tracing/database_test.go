@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Spans are grouped by name, so the name must be the operation and never the
+// statement with its literals.
+func TestOperationOf(t *testing.T) {
+	tests := map[string]string{
+		"SELECT id FROM posts":        "SELECT",
+		"\n\t\tupdate jobs SET x = 1": "UPDATE",
+		"WITH due AS (SELECT 1)":      "WITH",
+		"(SELECT 1) UNION (SELECT 2)": "SQL",
+		"":                            "SQL",
+	}
+
+	for sql, want := range tests {
+		if got := operationOf(sql); got != want {
+			t.Errorf("operationOf(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+// A failed statement has to show as failed, or a trace of a broken page looks
+// healthy all the way down.
+func TestQueryTracerRecordsFailures(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	tracer := QueryTracer{}
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "DELETE FROM jobs"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("deadlock detected")})
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	if spans[0].Name() != "DELETE" {
+		t.Errorf("name = %q", spans[0].Name())
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", spans[0].Status().Code)
+	}
+}
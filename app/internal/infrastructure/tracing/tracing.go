@@ -0,0 +1,113 @@
+// Package tracing sets up OpenTelemetry and holds the helpers the rest of the
+// server uses to open spans.
+//
+// Until Init installs an exporter the global tracer is a no-op, so spans cost
+// next to nothing in tests and in deployments that leave tracing off.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"server/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporter names accepted in OTEL_TRACES_EXPORTER.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// serviceName is reported unless OTEL_SERVICE_NAME says otherwise.
+const serviceName = "dviji-se"
+
+const instrumentationName = "server"
+
+// Init installs the tracer provider for the configured exporter and the W3C
+// trace context propagator. The returned function flushes buffered spans and
+// must be called on shutdown. The propagator is installed even with tracing
+// off, so an incoming traceparent still becomes the request id.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := config.TracesExporter(); name {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		// Endpoint, headers and timeouts come from the standard
+		// OTEL_EXPORTER_OTLP_* variables.
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout, "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating the traces exporter: %w", err)
+	}
+
+	// Attributes given later win, so OTEL_SERVICE_NAME and
+	// OTEL_RESOURCE_ATTRIBUTES override the default name.
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("describing the service: %w", err)
+	}
+
+	// With no sampler given the SDK reads OTEL_TRACES_SAMPLER, and otherwise
+	// samples every trace a caller has not already decided against.
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	enabled.Store(true)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("Tracing error", "error", err)
+	}))
+
+	slog.Info("Tracing enabled", "exporter", config.TracesExporter())
+
+	return provider.Shutdown, nil
+}
+
+// enabled is set once Init has installed an exporter.
+var enabled atomic.Bool
+
+// Enabled reports whether spans go anywhere. Code that would do extra work
+// only to feed spans, such as wrapping every middleware, checks it first.
+func Enabled() bool { return enabled.Load() }
+
+// Start opens a span as a child of whatever span ctx holds.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// Fail marks the span as failed by err. A nil err leaves it alone, so it can
+// be called unconditionally on the way out.
+func Fail(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -3,35 +3,63 @@ package server
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"server/internal/application/health"
 	"server/internal/application/users"
 	"server/internal/config"
 	"server/internal/domain/user"
 	"server/internal/http/middleware"
 	"server/internal/http/routes"
+	"server/internal/infrastructure/logfile"
+	"server/internal/infrastructure/metrics"
 	"time"
 )
 
 type ApiServer struct {
 	port string
 	http *http.Server
+
+	// metrics is the private listener for /metrics, nil unless METRICS_ADDR
+	// is set.
+	metrics *http.Server
+
+	// accessLog is the access log file, nil when it goes to standard output
+	// or nowhere.
+	accessLog io.Closer
 }
 
 var api *ApiServer
 
-func Initialize(db *sql.DB) {
+// Initialize builds the server. It is called before migrations run, so that
+// the probes answer during startup; readiness says when the rest may.
+func Initialize(db *sql.DB, readiness *health.State) error {
 	middleware.InitLogger()
 
-	router := routes.RegisterRoutes(db)
+	accessLog, accessLogFile, err := newAccessLog()
+	if err != nil {
+		return err
+	}
+
+	router := routes.RegisterRoutes(db, readiness)
+	metrics.RegisterDBStats(db)
 
 	// CheckAuth needs to know whether a token has been revoked, which is a
 	// database question. Every authenticated request asks it, so the answer is
 	// cached briefly; revocations take effect within the TTL.
 	sessions := users.NewCachedSessionValidator(user.NewUserRepository(db), users.DefaultRevocationCacheTTL)
 
-	stack := middleware.CreateChain(
-		middleware.Recovery,
+	// The access log goes right under Metrics, to see the same requests
+	// with the same timing.
+	chain := []middleware.Middleware{middleware.Recovery, middleware.Metrics}
+	if accessLog != nil {
+		chain = append(chain, accessLog)
+	}
+	chain = append(chain,
+		middleware.GateStartup(readiness),
 		middleware.LimitRequestBody,
 		middleware.EnableCompression,
 		middleware.EnableCORS,
@@ -39,6 +67,7 @@ func Initialize(db *sql.DB) {
 		// CheckAuth before the CSRF pair because CSRF tokens are bound to the
 		// authenticated identity.
 		middleware.PopulateRequestId,
+		middleware.PopulateClientInfo,
 		middleware.CheckAuth(sessions),
 		middleware.CSRFValidate,
 		middleware.CSRFCookie,
@@ -46,13 +75,19 @@ func Initialize(db *sql.DB) {
 		middleware.SecurityHeaders,
 		middleware.ContentSecurityPolicy,
 	)
+	stack := middleware.CreateChain(chain...)
+
+	// Trace sits outside the chain so the middleware spans nest under the
+	// request's span, and RecordRoute inside it so the handler's does too.
+	handler := middleware.Trace(stack(middleware.RecordRoute(router)))
 
 	port := config.Port()
 	api = &ApiServer{
-		port: port,
+		port:      port,
+		accessLog: accessLogFile,
 		http: &http.Server{
 			Addr:    ":" + port,
-			Handler: stack(router),
+			Handler: handler,
 
 			// Without these a client can hold a connection open by trickling a
 			// request one byte at a time and exhaust the server. The header
@@ -64,15 +99,87 @@ func Initialize(db *sql.DB) {
 			IdleTimeout:       120 * time.Second,
 		},
 	}
+
+	if addr := config.MetricsAddr(); addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+
+		api.metrics = &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+		}
+	}
+
+	return nil
+}
+
+// newAccessLog builds the access log middleware from the configuration. It
+// returns nil when the log is off, and the file to close when there is one.
+func newAccessLog() (middleware.Middleware, io.Closer, error) {
+	format := config.AccessLogFormat()
+	if format == middleware.AccessLogOff {
+		return nil, nil, nil
+	}
+
+	var sink io.Writer = os.Stdout
+	var file *logfile.RotatingFile
+	if path := config.AccessLogFile(); path != "" {
+		var err error
+		if file, err = logfile.Open(path, config.AccessLogMaxBytes(), config.AccessLogMaxBackups()); err != nil {
+			return nil, nil, fmt.Errorf("opening the access log: %w", err)
+		}
+		sink = file
+	}
+
+	logger, err := middleware.NewAccessLogger(sink, format)
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return nil, nil, err
+	}
+
+	// A nil *RotatingFile would make a non-nil io.Closer.
+	if file == nil {
+		return middleware.AccessLog(logger, config.AccessLogStaticSample()), nil, nil
+	}
+	return middleware.AccessLog(logger, config.AccessLogStaticSample()), file, nil
 }
 
 func Run() error {
 	slog.Info("Starting server on port: " + api.port)
 
+	if api.metrics != nil {
+		go func() {
+			slog.Info("Serving metrics on " + api.metrics.Addr)
+			if err := api.metrics.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Metrics listener stopped", "error", err)
+			}
+		}()
+	}
+
 	return api.http.ListenAndServe()
 }
 
 func Shutdown(ctx context.Context) error {
 	slog.Info("Shutting down server...")
-	return api.http.Shutdown(ctx)
+
+	if api.metrics != nil {
+		if err := api.metrics.Shutdown(ctx); err != nil {
+			slog.Warn("Error shutting down the metrics listener", "error", err)
+		}
+	}
+
+	err := api.http.Shutdown(ctx)
+
+	// Closed after the server, which has logged its last request by now.
+	if api.accessLog != nil {
+		if closeErr := api.accessLog.Close(); closeErr != nil {
+			slog.Warn("Error closing the access log", "error", closeErr)
+		}
+	}
+
+	return err
 }
@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/application/account"
+	"server/internal/domain/comments"
+	"server/internal/domain/posts"
+	"server/internal/domain/user"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// Anonymising must leave nothing that identifies the person, end their
+// sessions, keep their posts online under an administrator, and take down
+// their comments without losing the replies of others.
+func TestAccountDeletion_AnonymiseWipesPersonalData(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := user.NewUserRepository(tdb.DB)
+
+	admin := seedRevocationUser(t, tdb, "admin-successor@example.com")
+	if _, err := tdb.DB.Exec(
+		`INSERT INTO users_roles (user_id, role_id) SELECT $1, id FROM roles WHERE name = 'ADMIN'`, admin); err != nil {
+		t.Fatalf("failed to make the successor an administrator: %v", err)
+	}
+
+	leaving := seedRevocationUser(t, tdb, "leaving@example.com")
+	tdb.EnsureCategories(t)
+	postId := tdb.SeedTestPost(t, "Моята статия", "moyata-statiya", "Съдържание", tdb.GetCategoryId(t, "recepti"), leaving.String(), "published")
+
+	commentRepo := comments.NewCommentRepository(tdb.DB)
+	commentId := addComment(t, commentRepo, uuid.MustParse(postId), leaving, comments.StatusApproved)
+	replyId := uuid.New()
+	if err := commentRepo.Create(ctx, comments.Comment{
+		Id: replyId, PostId: uuid.MustParse(postId), UserId: admin, ParentId: uuid.NullUUID{UUID: commentId, Valid: true},
+		Body: "Благодаря", Status: comments.StatusApproved, CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("Create() reply error = %v", err)
+	}
+
+	// The export carries what they wrote, before it goes.
+	privacy := account.NewPrivacyService(repo, posts.NewPostRepository(tdb.DB), commentRepo, user.NewAccountDeletionTokenRepository(tdb.DB), nil, time.Hour)
+	export, err := privacy.Export(ctx, leaving.String(), "")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(export.Comments) != 1 || export.Comments[0].Id != commentId || export.Comments[0].Body != "Коментар" || export.Comments[0].PostTitle != "Моята статия" {
+		t.Errorf("exported comments = %+v, want the one comment", export.Comments)
+	}
+
+	if err := repo.Anonymise(ctx, leaving); err == nil {
+		t.Fatal("an account without a due deletion must not be anonymised")
+	}
+
+	if err := repo.ScheduleDeletion(ctx, leaving, time.Now().UTC().Add(-time.Minute)); err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+
+	due, err := repo.FindDueForDeletion(ctx, time.Now().UTC())
+	if err != nil || len(due) != 1 || due[0] != leaving {
+		t.Fatalf("FindDueForDeletion() = %v, %v; want [%v]", due, err, leaving)
+	}
+
+	if err := repo.Anonymise(ctx, leaving); err != nil {
+		t.Fatalf("Anonymise() error = %v", err)
+	}
+
+	if exists, _ := repo.ExistsByEmail(ctx, "leaving@example.com"); exists {
+		t.Error("the old address should be free again")
+	}
+
+	var email string
+	var firstName, lastName *string
+	var deleted bool
+	err = tdb.DB.QueryRow(`SELECT email, first_name, last_name, is_deleted FROM users WHERE id = $1`, leaving).
+		Scan(&email, &firstName, &lastName, &deleted)
+	if err != nil {
+		t.Fatalf("failed to read the anonymised row: %v", err)
+	}
+
+	if email == "leaving@example.com" || firstName != nil || lastName != nil || !deleted {
+		t.Errorf("row still identifies the person: email=%q first=%v last=%v deleted=%v", email, firstName, lastName, deleted)
+	}
+
+	// The session validator fails closed once the row no longer loads.
+	if _, err := repo.TokensValidAfter(ctx, leaving.String()); err == nil {
+		t.Error("sessions of an anonymised account should no longer validate")
+	}
+
+	var creator uuid.UUID
+	if err := tdb.DB.QueryRow(`SELECT creator_user_id FROM posts WHERE id = $1`, postId).Scan(&creator); err != nil {
+		t.Fatalf("failed to read the post: %v", err)
+	}
+
+	if creator != admin {
+		t.Errorf("post creator = %v, want the administrator %v", creator, admin)
+	}
+
+	comment, err := commentRepo.FindById(ctx, commentId)
+	if err != nil {
+		t.Fatalf("FindById() error = %v", err)
+	}
+	if comment.Body != "" || comment.Status != comments.StatusDeleted {
+		t.Errorf("comment after anonymising = %q (%s), want emptied and deleted", comment.Body, comment.Status)
+	}
+	if reply, err := commentRepo.FindById(ctx, replyId); err != nil || reply.Body != "Благодаря" {
+		t.Errorf("the reply of another reader = %+v, %v; want it kept", reply, err)
+	}
+
+	// The row is kept on purpose, so the unverified purge must leave it alone.
+	if _, err := repo.DeleteUnverifiedBefore(ctx, time.Now().UTC().Add(time.Hour)); err != nil {
+		t.Fatalf("DeleteUnverifiedBefore() error = %v", err)
+	}
+
+	var remaining int
+	_ = tdb.DB.QueryRow(`SELECT COUNT(*) FROM users WHERE id = $1`, leaving).Scan(&remaining)
+	if remaining != 1 {
+		t.Error("the anonymised row should survive the unverified purge")
+	}
+}
@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/domain/audit"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+func appendEvent(t *testing.T, repo *audit.AuditRepository, event audit.Event) {
+	t.Helper()
+
+	event.Id = uuid.New()
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	if err := repo.Append(context.Background(), event); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+}
+
+// A trail that can be rewritten proves nothing, so the database itself
+// refuses edits; only the retention sweep may remove rows.
+func TestAudit_EventsCannotBeEdited(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	repo := audit.NewAuditRepository(tdb.DB)
+	appendEvent(t, repo, audit.Event{Action: audit.ActionLogin, Outcome: audit.Failure, Detail: audit.ReasonWrongPassword})
+
+	if _, err := tdb.DB.Exec(`UPDATE audit_events SET outcome = 'success'`); err == nil {
+		t.Error("UPDATE on audit_events succeeded, want it refused")
+	}
+}
+
+// Filters combine, and the query matches the address typed at a failed
+// sign-in as well as a known actor's email.
+func TestAudit_FindFilters(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := audit.NewAuditRepository(tdb.DB)
+
+	member := seedRevocationUser(t, tdb, "member@example.com")
+	appendEvent(t, repo, audit.Event{Action: audit.ActionLogin, Outcome: audit.Success, ActorId: uuid.NullUUID{UUID: member, Valid: true}})
+	appendEvent(t, repo, audit.Event{Action: audit.ActionLogin, Outcome: audit.Failure, Identifier: "stranger@example.com"})
+	appendEvent(t, repo, audit.Event{Action: audit.ActionLogout, Outcome: audit.Success, ActorId: uuid.NullUUID{UUID: member, Valid: true}})
+
+	found, total, err := repo.Find(ctx, audit.Filter{Action: audit.ActionLogin, Query: "MEMBER"}, 20, 0)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if total != 1 || len(found) != 1 || found[0].ActorEmail != "member@example.com" {
+		t.Errorf("Find(login, MEMBER) = %d results, total %d", len(found), total)
+	}
+
+	if _, total, _ := repo.Find(ctx, audit.Filter{Outcome: audit.Failure, Query: "stranger"}, 20, 0); total != 1 {
+		t.Errorf("Find(failure, stranger) total = %d, want 1", total)
+	}
+
+	if _, total, _ := repo.Find(ctx, audit.Filter{From: time.Now().Add(time.Hour)}, 20, 0); total != 0 {
+		t.Errorf("Find(from the future) total = %d, want 0", total)
+	}
+}
+
+func TestAudit_DeleteBeforeKeepsRecentEvents(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := audit.NewAuditRepository(tdb.DB)
+
+	appendEvent(t, repo, audit.Event{Action: audit.ActionLogin, Outcome: audit.Success, OccurredAt: time.Now().UTC().AddDate(0, 0, -200)})
+	appendEvent(t, repo, audit.Event{Action: audit.ActionLogin, Outcome: audit.Success})
+
+	deleted, err := repo.DeleteBefore(ctx, time.Now().UTC().AddDate(0, 0, -180))
+	if err != nil {
+		t.Fatalf("DeleteBefore() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteBefore() = %d, want 1", deleted)
+	}
+
+	if _, total, _ := repo.Find(ctx, audit.Filter{}, 20, 0); total != 1 {
+		t.Errorf("remaining events = %d, want 1", total)
+	}
+}
@@ -3,10 +3,12 @@ package integration
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"server/internal/application/health"
 	"server/internal/domain/user"
 	"server/internal/http/routes"
 	"server/tests/integration/testdb"
@@ -43,7 +45,7 @@ func TestAuthAPI_Register(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	tdb.CleanupTables(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -155,7 +157,7 @@ func TestAuthAPI_Login(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	tdb.CleanupTables(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -170,6 +172,7 @@ func TestAuthAPI_Login(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := http.DefaultClient.Do(req)
 	resp.Body.Close()
+	tdb.VerifyUserEmail(t, "logintest@example.com")
 
 	t.Run("successful login", func(t *testing.T) {
 		payload := map[string]interface{}{
@@ -263,7 +266,7 @@ func TestAuthAPI_PasswordReset(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	tdb.CleanupTables(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -360,7 +363,7 @@ func TestAuthAPI_AdminLoginRejectsNonAdmin(t *testing.T) {
 	tdb.CleanupTables(t)
 	defer tdb.CleanupTables(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -380,17 +383,21 @@ func TestAuthAPI_AdminLoginRejectsNonAdmin(t *testing.T) {
 		Password:  hashed,
 		Status:    "Active",
 		CreatedAt: time.Now().UTC(),
+		// Created directly rather than registered, so nothing sends the
+		// verification link.
+		EmailVerifiedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 	}
 	if err := repo.Create(context.Background(), plainUser); err != nil {
 		t.Fatalf("failed to create the user: %v", err)
 	}
 
 	admin := user.User{
-		Id:        uuid.New(),
-		Email:     "real-admin@example.com",
-		Password:  hashed,
-		Status:    "Active",
-		CreatedAt: time.Now().UTC(),
+		Id:              uuid.New(),
+		Email:           "real-admin@example.com",
+		Password:        hashed,
+		Status:          "Active",
+		CreatedAt:       time.Now().UTC(),
+		EmailVerifiedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 	}
 	if err := repo.CreateAdmin(context.Background(), admin); err != nil {
 		t.Fatalf("failed to create the administrator: %v", err)
@@ -471,3 +478,144 @@ func TestAuthAPI_AdminLoginRejectsNonAdmin(t *testing.T) {
 		}
 	})
 }
+
+// A self-registered account must not be usable until its owner proves the
+// address is theirs: the same address receives password reset links.
+func TestAuthAPI_EmailVerification(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	cleanup := setupAuthTestEnv(t)
+	defer cleanup()
+
+	tdb := testdb.SetupTestDB(t)
+	tdb.CleanupTables(t)
+	defer tdb.CleanupTables(t)
+
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	const email = "verify-me@example.com"
+	const password = "Str0ng!Passw0rd"
+
+	body, _ := json.Marshal(map[string]string{
+		"email":          email,
+		"password":       password,
+		"repeatPassword": password,
+	})
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := resp.Header.Get("HX-Redirect"); got != "/verify-email/sent" {
+		t.Errorf("HX-Redirect = %q, want %q", got, "/verify-email/sent")
+	}
+
+	login := func(t *testing.T) *http.Response {
+		t.Helper()
+
+		body, _ := json.Marshal(map[string]any{"email": email, "password": password, "rememberMe": false})
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		return resp
+	}
+
+	t.Run("unverified account cannot log in", func(t *testing.T) {
+		resp := login(t)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+
+		for _, c := range resp.Cookies() {
+			if c.Name == "X-LOGIN-TOKEN" {
+				t.Error("an unverified account must not be issued a session")
+			}
+		}
+	})
+
+	t.Run("following the link activates the account", func(t *testing.T) {
+		ctx := context.Background()
+		account, err := user.NewUserRepository(tdb.DB).FindByEmail(ctx, email)
+		if err != nil {
+			t.Fatalf("failed to load the account: %v", err)
+		}
+
+		if account.Status != user.Inactive {
+			t.Errorf("Status = %q before verification, want %q", account.Status, user.Inactive)
+		}
+
+		// The emailed token cannot be read back, so issue a fresh one the same
+		// way the service does.
+		plainToken, tokenHash, err := user.GenerateToken()
+		if err != nil {
+			t.Fatalf("failed to generate a token: %v", err)
+		}
+		if _, err := user.NewEmailVerificationTokenRepository(tdb.DB).Create(ctx, account.Id, tokenHash); err != nil {
+			t.Fatalf("failed to store the token: %v", err)
+		}
+
+		resp, err := http.Get(server.URL + "/verify-email?token=" + plainToken)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+
+		loginResp := login(t)
+		defer loginResp.Body.Close()
+
+		if loginResp.StatusCode != http.StatusOK {
+			t.Errorf("login after verification: Status = %d, want %d", loginResp.StatusCode, http.StatusOK)
+		}
+	})
+
+	t.Run("stale unverified accounts are purged", func(t *testing.T) {
+		ctx := context.Background()
+		repo := user.NewUserRepository(tdb.DB)
+
+		stale := user.User{
+			Id:        uuid.New(),
+			Email:     "never-verified@example.com",
+			Password:  "irrelevant-hash",
+			Status:    user.Inactive,
+			CreatedAt: time.Now().UTC().Add(-30 * 24 * time.Hour),
+		}
+		if err := repo.Create(ctx, stale); err != nil {
+			t.Fatalf("failed to create the account: %v", err)
+		}
+
+		deleted, err := repo.DeleteUnverifiedBefore(ctx, time.Now().UTC().Add(-7*24*time.Hour))
+		if err != nil {
+			t.Fatalf("DeleteUnverifiedBefore() error = %v", err)
+		}
+
+		if deleted != 1 {
+			t.Errorf("deleted %d accounts, want only the stale one", deleted)
+		}
+
+		if exists, _ := repo.ExistsByEmail(ctx, stale.Email); exists {
+			t.Error("the stale account should be gone")
+		}
+
+		if exists, _ := repo.ExistsByEmail(ctx, email); !exists {
+			t.Error("a verified account must survive the purge")
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"server/internal/application/health"
 	"server/internal/http/middleware"
 	"server/internal/http/routes"
 	"server/tests/integration/testdb"
@@ -37,7 +38,7 @@ func (alwaysValidSessions) IsSessionValid(context.Context, string, time.Time) bo
 
 // createTestHandler creates a handler with middleware stack for testing
 func createTestHandler(tdb *testdb.TestDB) http.Handler {
-	router := routes.RegisterRoutes(tdb.DB)
+	router := routes.RegisterRoutes(tdb.DB, health.NewState())
 
 	// Apply middleware stack similar to server.Initialize
 	stack := middleware.CreateChain(
@@ -59,7 +60,7 @@ func TestBlogAPI_GetBlogList(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -123,7 +124,7 @@ func TestBlogAPI_GetBlogPost(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -193,7 +194,7 @@ func TestBlogAPI_GetBlogByCategory(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -256,7 +257,7 @@ func TestBlogAPI_GetRecentPosts(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -365,6 +366,7 @@ func TestAdminAPI_CreatePost(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		resp, _ := client.Do(req)
 		resp.Body.Close()
+		tdb.VerifyUserEmail(t, "regularuser@example.com")
 
 		// Login
 		loginPayload := map[string]interface{}{
@@ -432,6 +434,7 @@ func TestAdminAPI_CreatePost(t *testing.T) {
 			t.Fatalf("Failed to get user ID: %v", err)
 		}
 		tdb.AssignRoleToUser(t, userId, "22222222-2222-2222-2222-222222222222") // ADMIN role
+		tdb.VerifyUserEmail(t, adminEmail)
 
 		// Login
 		loginPayload := map[string]interface{}{
@@ -555,7 +558,7 @@ func TestBlogAPI_SearchPosts(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -653,7 +656,7 @@ func TestBlogAPI_SearchSuggestions(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	handler := routes.RegisterRoutes(tdb.DB)
+	handler := routes.RegisterRoutes(tdb.DB, health.NewState())
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -748,7 +751,7 @@ func TestBlogAPI_FragmentEndpointsRedirectDirectVisits(t *testing.T) {
 	tdb.CleanupTables(t)
 	tdb.EnsureCategories(t)
 
-	server := httptest.NewServer(routes.RegisterRoutes(tdb.DB))
+	server := httptest.NewServer(routes.RegisterRoutes(tdb.DB, health.NewState()))
 	defer server.Close()
 
 	client := &http.Client{
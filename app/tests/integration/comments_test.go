@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/domain/comments"
+	"server/internal/domain/posts"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+func addComment(t *testing.T, repo *comments.CommentRepository, postId, userId uuid.UUID, status comments.Status) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	err := repo.Create(context.Background(), comments.Comment{
+		Id:        id,
+		PostId:    postId,
+		UserId:    userId,
+		Body:      "Коментар",
+		Status:    status,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	return id
+}
+
+// Readers see approved comments and their own pending ones, never somebody
+// else's pending, spam or rejected comments. Cards count only approved ones.
+func TestComments_VisibilityAndCounts(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+	tdb.EnsureCategories(t)
+
+	ctx := context.Background()
+	repo := comments.NewCommentRepository(tdb.DB)
+
+	author := seedRevocationUser(t, tdb, "author@example.com")
+	reader := seedRevocationUser(t, tdb, "reader@example.com")
+	postId := uuid.MustParse(tdb.SeedTestPost(t, "Post", "post", "Content", tdb.GetCategoryId(t, "fitnes-zali"), author.String(), "published"))
+
+	addComment(t, repo, postId, author, comments.StatusApproved)
+	addComment(t, repo, postId, author, comments.StatusSpam)
+	mine := addComment(t, repo, postId, reader, comments.StatusPending)
+
+	asReader, err := repo.FindVisible(ctx, postId, uuid.NullUUID{UUID: reader, Valid: true})
+	if err != nil {
+		t.Fatalf("FindVisible() error = %v", err)
+	}
+	if len(asReader) != 2 || asReader[1].Id != mine {
+		t.Errorf("the reader sees %d comments, want the approved one and their own", len(asReader))
+	}
+
+	if anonymous, _ := repo.FindVisible(ctx, postId, uuid.NullUUID{}); len(anonymous) != 1 {
+		t.Errorf("an anonymous reader sees %d comments, want 1", len(anonymous))
+	}
+
+	published, _, err := posts.NewPostRepository(tdb.DB).FindPublished(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("FindPublished() error = %v", err)
+	}
+	if len(published) != 1 || published[0].CommentCount != 1 {
+		t.Errorf("CommentCount = %v, want 1", published)
+	}
+}
+
+// A bulk decision moves every selected comment and records who made it.
+func TestComments_SetStatusMovesTheSelection(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+	tdb.EnsureCategories(t)
+
+	ctx := context.Background()
+	repo := comments.NewCommentRepository(tdb.DB)
+
+	author := seedRevocationUser(t, tdb, "author@example.com")
+	postId := uuid.MustParse(tdb.SeedTestPost(t, "Post", "post", "Content", tdb.GetCategoryId(t, "fitnes-zali"), author.String(), "published"))
+
+	first := addComment(t, repo, postId, author, comments.StatusPending)
+	second := addComment(t, repo, postId, author, comments.StatusPending)
+	addComment(t, repo, postId, author, comments.StatusPending)
+
+	moved, err := repo.SetStatus(ctx, []uuid.UUID{first, second}, comments.StatusApproved, author)
+	if err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("SetStatus() moved %d, want 2", moved)
+	}
+
+	queue, total, err := repo.FindByStatus(ctx, comments.StatusPending, 10, 0)
+	if err != nil {
+		t.Fatalf("FindByStatus() error = %v", err)
+	}
+	if total != 1 || len(queue) != 1 || queue[0].PostSlug != "post" {
+		t.Errorf("pending queue = %d items, total %d, want 1", len(queue), total)
+	}
+
+	approved, err := repo.FindById(ctx, first)
+	if err != nil {
+		t.Fatalf("FindById() error = %v", err)
+	}
+	if !approved.ModeratedBy.Valid || approved.ModeratedBy.UUID != author || !approved.ModeratedAt.Valid {
+		t.Error("the moderator and time were not recorded")
+	}
+}
@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/domain/contact"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// Privacy requests are listed ahead of everything else in the open folder,
+// the most urgent first, and the summary counts them as overdue once their
+// deadline has passed.
+func TestContactMessages_PrivacyRequestsComeFirst(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := contact.NewMessageRepository(tdb.DB)
+	now := time.Now().UTC()
+
+	create := func(category contact.Category, createdAt time.Time) uuid.UUID {
+		t.Helper()
+		message := contact.Message{
+			Id:        uuid.New(),
+			Name:      "Читател",
+			Email:     "reader@example.com",
+			Category:  category,
+			Subject:   "Тема",
+			Body:      "Съобщение",
+			Status:    contact.StatusOpen,
+			CreatedAt: createdAt,
+			DueAt:     contact.DueDate(category, createdAt),
+		}
+		if err := repo.Create(ctx, message); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return message.Id
+	}
+
+	general := create(contact.CategoryGeneral, now)
+	overdue := create(contact.CategoryPrivacy, now.Add(-40*24*time.Hour))
+	recent := create(contact.CategoryPrivacy, now.Add(-24*time.Hour))
+
+	messages, total, err := repo.FindByStatus(ctx, contact.StatusOpen, "", 10, 0)
+	if err != nil {
+		t.Fatalf("FindByStatus() error = %v", err)
+	}
+	if total != 3 || len(messages) != 3 {
+		t.Fatalf("got %d of %d messages, want 3", len(messages), total)
+	}
+	for i, want := range []uuid.UUID{overdue, recent, general} {
+		if messages[i].Id != want {
+			t.Errorf("messages[%d] = %s, want %s", i, messages[i].Id, want)
+		}
+	}
+
+	summary, err := repo.Summary(ctx, now, now.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("Summary() error = %v", err)
+	}
+	if summary.Unread != 3 || summary.Overdue != 1 || summary.DueSoon != 0 {
+		t.Errorf("summary = %+v, want 3 unread, 1 overdue", summary)
+	}
+
+	// Closing stops the clock and takes the request out of the count.
+	if changed, err := repo.SetStatus(ctx, overdue, contact.StatusClosed); err != nil || !changed {
+		t.Fatalf("SetStatus() = %v, %v", changed, err)
+	}
+	closed, err := repo.FindById(ctx, overdue)
+	if err != nil {
+		t.Fatalf("FindById() error = %v", err)
+	}
+	if !closed.ClosedAt.Valid {
+		t.Error("closing did not record when")
+	}
+	if summary, _ := repo.Summary(ctx, now, now.Add(7*24*time.Hour)); summary.Overdue != 0 {
+		t.Errorf("overdue after closing = %d, want 0", summary.Overdue)
+	}
+}
+
+// A reply marks its message read, is listed with its author's name, and goes
+// when the message is deleted.
+func TestContactMessages_Replies(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := contact.NewMessageRepository(tdb.DB)
+	author := uuid.MustParse(tdb.SeedTestUser(t, "author@example.com", "hash"))
+
+	id := uuid.New()
+	err := repo.Create(ctx, contact.Message{Id: id, Name: "Читател", Email: "reader@example.com", Category: contact.CategoryGeneral, Subject: "Тема", Body: "Съобщение", Status: contact.StatusOpen, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	reply := contact.Reply{Id: uuid.New(), MessageId: id, AuthorId: uuid.NullUUID{UUID: author, Valid: true}, Body: "Отговор", CreatedAt: time.Now().UTC()}
+	if err := repo.AddReply(ctx, reply); err != nil {
+		t.Fatalf("AddReply() error = %v", err)
+	}
+
+	message, err := repo.FindById(ctx, id)
+	if err != nil {
+		t.Fatalf("FindById() error = %v", err)
+	}
+	if !message.ReadAt.Valid {
+		t.Error("replying did not mark the message read")
+	}
+
+	replies, err := repo.FindReplies(ctx, id)
+	if err != nil {
+		t.Fatalf("FindReplies() error = %v", err)
+	}
+	if len(replies) != 1 || replies[0].AuthorName != "Test User" {
+		t.Errorf("replies = %+v, want one with its author's name", replies)
+	}
+
+	if deleted, err := repo.Delete(ctx, id); err != nil || !deleted {
+		t.Fatalf("Delete() = %v, %v", deleted, err)
+	}
+	var left int
+	if err := tdb.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM contact_replies WHERE message_id = $1`, id).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("replies left after deleting the message = %d, want 0", left)
+	}
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server/internal/application/health"
+	"server/internal/domain/jobs"
+	"server/internal/http/routes"
+	"server/tests/integration/testdb"
+)
+
+// /livez answers throughout; /readyz only between startup and draining.
+func TestHealth_Probes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tdb := testdb.SetupTestDB(t)
+	readiness := health.NewState()
+	handler := routes.RegisterRoutes(tdb.DB, readiness)
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if code := get("/livez"); code != http.StatusOK {
+		t.Errorf("/livez while starting = %d", code)
+	}
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while starting = %d, want 503", code)
+	}
+
+	readiness.MarkReady()
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz when ready = %d", code)
+	}
+
+	readiness.MarkDraining()
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while draining = %d, want 503", code)
+	}
+	if code := get("/livez"); code != http.StatusOK {
+		t.Errorf("/livez while draining = %d", code)
+	}
+
+	if code := get("/health/details"); code == http.StatusOK {
+		t.Error("/health/details answered without a session")
+	}
+}
+
+// Only pending jobs past the cutoff count as overdue.
+func TestHealth_CountOverdueJobs(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := jobs.NewJobRepository(tdb.DB)
+	now := time.Now().UTC()
+
+	for _, runAt := range []time.Time{now.Add(-time.Hour), now.Add(-time.Minute), now.Add(time.Hour)} {
+		if _, err := repo.Enqueue(ctx, newJob("test.overdue", "", runAt)); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	overdue, err := repo.CountOverdue(ctx, now.Add(-5*time.Minute))
+	if err != nil {
+		t.Fatalf("CountOverdue() error = %v", err)
+	}
+	if overdue != 1 {
+		t.Errorf("CountOverdue() = %d, want 1", overdue)
+	}
+}
@@ -0,0 +1,159 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/domain/image"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// The copies are found through the image they were made from for the pages,
+// and the image and its copies are all known to the orphan cleanup as the
+// library's.
+func TestImages_Variants(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := image.NewImageRepository(tdb.DB)
+
+	img := image.Image{Id: uuid.New(), Url: "/media/images/run.jpg", Key: "images/run.jpg", Width: 1200, Height: 800, CreatedAt: time.Now()}
+	variants := []image.Variant{
+		{Format: "jpeg", Width: 800, Url: "/media/images/run-800w.jpg", Key: "images/run-800w.jpg", Bytes: 1000},
+		{Format: "webp", Width: 800, Url: "/media/images/run-800w.webp", Key: "images/run-800w.webp", Bytes: 600},
+	}
+	if err := repo.Create(ctx, img, variants); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	plain := image.Image{Id: uuid.New(), Url: "https://res.cloudinary.com/demo/image/upload/v1/plain.jpg", Key: "plain", Width: 600, Height: 400, CreatedAt: time.Now()}
+	if err := repo.Create(ctx, plain, nil); err != nil {
+		t.Fatalf("Create() without variants = %v", err)
+	}
+
+	found, err := repo.FindWithVariants(ctx)
+	if err != nil {
+		t.Fatalf("FindWithVariants() = %v", err)
+	}
+	if len(found) != 1 || found[0].Url != img.Url || found[0].Width != 1200 || len(found[0].Variants) != 2 {
+		t.Fatalf("FindWithVariants() = %+v, want only the image with copies", found)
+	}
+
+	recorded, err := repo.RecordedKeys(ctx)
+	if err != nil {
+		t.Fatalf("RecordedKeys() = %v", err)
+	}
+	for _, key := range []string{"images/run.jpg", "images/run-800w.jpg", "images/run-800w.webp", "plain"} {
+		if !recorded[key] {
+			t.Errorf("RecordedKeys() is missing %s: %v", key, recorded)
+		}
+	}
+	if len(recorded) != 4 {
+		t.Errorf("RecordedKeys() = %v, want the two images and the copies", recorded)
+	}
+}
+
+// The library knows where each image is shown from the posts themselves,
+// and an image is only ever claimed for deletion while nothing shows it.
+func TestImages_Library(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := image.NewImageRepository(tdb.DB)
+
+	tdb.EnsureCategories(t)
+	userId := tdb.SeedTestUser(t, "editor@example.com", "hash")
+	postId := tdb.SeedTestPost(t, "Мусала", "musala", `<p>Изгрев</p><img src="/media/images/in-post.jpg">`,
+		tdb.GetCategoryId(t, "recepti"), userId, "published")
+
+	uploader := uuid.NullUUID{UUID: uuid.MustParse(userId), Valid: true}
+	inPost := image.Image{Id: uuid.New(), Url: "/media/images/in-post.jpg", Key: "images/in-post.jpg", Width: 1200, Height: 800,
+		Bytes: 2048, Filename: "IMG_0001.jpg", UploadedBy: uploader, CreatedAt: time.Now()}
+	unused := image.Image{Id: uuid.New(), Url: "/media/images/unused.jpg", Key: "images/unused.jpg", Width: 600, Height: 400,
+		Bytes: 1024, Filename: "forgotten.jpg", CreatedAt: time.Now()}
+	for _, img := range []image.Image{inPost, unused} {
+		if err := repo.Create(ctx, img, nil); err != nil {
+			t.Fatalf("Create() = %v", err)
+		}
+	}
+
+	scanned := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := repo.ScanUsage(ctx, scanned); err != nil {
+		t.Fatalf("ScanUsage() = %v", err)
+	}
+
+	items, total, err := repo.FindPage(ctx, image.LibraryFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("FindPage() = %v", err)
+	}
+	if total != 2 || len(items) != 2 {
+		t.Fatalf("FindPage() = %d items of %d, want both", len(items), total)
+	}
+	for _, item := range items {
+		switch item.Id {
+		case inPost.Id:
+			if len(item.Usages) != 1 || item.Usages[0].PostTitle != "Мусала" || item.Usages[0].Field != image.UsageContent || !item.UnusedSince.IsZero() {
+				t.Errorf("the image in the post is listed as %+v", item)
+			}
+			if item.Uploader != "editor@example.com" || item.Filename != "IMG_0001.jpg" {
+				t.Errorf("uploaded by %q as %q", item.Uploader, item.Filename)
+			}
+		case unused.Id:
+			if len(item.Usages) != 0 || !item.UnusedSince.Equal(scanned) {
+				t.Errorf("the unused image is listed as %+v", item)
+			}
+		}
+	}
+
+	if err := repo.UpdateAlt(ctx, inPost.Id, "Изгрев над Мусала"); err != nil {
+		t.Fatalf("UpdateAlt() = %v", err)
+	}
+	if err := repo.UpdateAlt(ctx, uuid.New(), "нищо"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateAlt() of an unknown image = %v, want sql.ErrNoRows", err)
+	}
+	if found, _, _ := repo.FindPage(ctx, image.LibraryFilter{Query: "мусала"}, 10, 0); len(found) != 1 || found[0].Id != inPost.Id {
+		t.Errorf("a search by alt text found %+v", found)
+	}
+	if found, _, _ := repo.FindPage(ctx, image.LibraryFilter{UnusedOnly: true}, 10, 0); len(found) != 1 || found[0].Id != unused.Id {
+		t.Errorf("the unused filter found %+v", found)
+	}
+
+	// The post now shows the forgotten image too: the claim has to see that,
+	// although no scan has run since.
+	if _, err := tdb.DB.Exec(`UPDATE posts SET content = content || '<img src="/media/images/unused.jpg">' WHERE id = $1`, postId); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.TrackPost(ctx, uuid.MustParse(postId)); err != nil {
+		t.Fatalf("TrackPost() = %v", err)
+	}
+	if claimed, err := repo.Claim(ctx, unused.Id, time.Now()); err != nil || claimed {
+		t.Errorf("Claim() of an image back in use = %v, %v", claimed, err)
+	}
+
+	// Once the post is gone, so is every use of its images.
+	if _, err := tdb.DB.Exec(`UPDATE posts SET is_deleted = TRUE WHERE id = $1`, postId); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ScanUsage(ctx, scanned); err != nil {
+		t.Fatalf("ScanUsage() = %v", err)
+	}
+	due, err := repo.FindUnusedSince(ctx, time.Now())
+	if err != nil || len(due) != 2 {
+		t.Fatalf("FindUnusedSince() = %+v, %v, want both images", due, err)
+	}
+	if claimed, err := repo.Claim(ctx, unused.Id, time.Now()); err != nil || !claimed {
+		t.Fatalf("Claim() = %v, %v", claimed, err)
+	}
+	if err := repo.Delete(ctx, unused.Id); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if _, total, _ := repo.FindPage(ctx, image.LibraryFilter{}, 10, 0); total != 1 {
+		t.Errorf("the library still holds %d images, want 1", total)
+	}
+}
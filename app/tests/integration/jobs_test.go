@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/domain/jobs"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+func newJob(kind, uniqueKey string, runAt time.Time) jobs.Job {
+	return jobs.Job{Id: uuid.New(), Kind: kind, Payload: []byte(`{}`), UniqueKey: uniqueKey, RunAt: runAt, CreatedAt: time.Now().UTC()}
+}
+
+// A unique key holds while its job waits or runs and frees up once it is
+// done; retrying a dead job is refused while another holds its key.
+func TestJobs_UniqueKeys(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := jobs.NewJobRepository(tdb.DB)
+	now := time.Now().UTC()
+
+	first := newJob("test.kind", "schedule:test.kind", now.Add(-time.Minute))
+	if queued, err := repo.Enqueue(ctx, first); err != nil || !queued {
+		t.Fatalf("Enqueue() = %v, %v", queued, err)
+	}
+	if queued, err := repo.Enqueue(ctx, newJob("test.kind", "schedule:test.kind", now)); err != nil || queued {
+		t.Fatalf("Enqueue() with a held key = %v, %v, want false", queued, err)
+	}
+
+	claimed, err := repo.Claim(ctx, map[string]time.Duration{"test.kind": time.Minute}, 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Claim() = %d jobs, %v", len(claimed), err)
+	}
+	if err := repo.Kill(ctx, first.Id, claimed[0].Attempts, "boom"); err != nil {
+		t.Fatalf("Kill() error = %v", err)
+	}
+
+	second := newJob("test.kind", "schedule:test.kind", now.Add(time.Hour))
+	if queued, err := repo.Enqueue(ctx, second); err != nil || !queued {
+		t.Fatalf("Enqueue() after the first died = %v, %v", queued, err)
+	}
+	if retried, err := repo.Retry(ctx, first.Id); err != nil || retried {
+		t.Errorf("Retry() with the key taken = %v, %v, want false", retried, err)
+	}
+}
+
+// Claiming takes only due jobs of the kinds asked for, never one another
+// worker holds, and a report from an attempt that lost its lease is dropped.
+func TestJobs_ClaimAndReport(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := jobs.NewJobRepository(tdb.DB)
+	now := time.Now().UTC()
+
+	due := newJob("test.due", "", now.Add(-time.Minute))
+	for _, job := range []jobs.Job{due, newJob("test.due", "", now.Add(time.Hour)), newJob("test.other", "", now.Add(-time.Minute))} {
+		if _, err := repo.Enqueue(ctx, job); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	claimed, err := repo.Claim(ctx, map[string]time.Duration{"test.due": time.Minute}, 10)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Id != due.Id || claimed[0].Attempts != 1 || claimed[0].Status != jobs.StatusRunning {
+		t.Fatalf("Claim() = %+v, want the due job on its first attempt", claimed)
+	}
+	if again, _ := repo.Claim(ctx, map[string]time.Duration{"test.due": time.Minute}, 10); len(again) != 0 {
+		t.Errorf("a running job was claimed twice")
+	}
+
+	// Its lease runs out and another worker takes it.
+	if _, err := tdb.DB.ExecContext(ctx, `UPDATE jobs SET run_at = NOW() - INTERVAL '1 second' WHERE id = $1`, due.Id); err != nil {
+		t.Fatal(err)
+	}
+	reclaimed, err := repo.Claim(ctx, map[string]time.Duration{"test.due": time.Minute}, 10)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].Attempts != 2 {
+		t.Fatalf("Claim() after the lease = %+v, %v", reclaimed, err)
+	}
+
+	if err := repo.Complete(ctx, due.Id, 1); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	job, err := repo.FindById(ctx, due.Id)
+	if err != nil {
+		t.Fatalf("FindById() error = %v", err)
+	}
+	if job.Status != jobs.StatusRunning {
+		t.Errorf("status after a stale report = %q, want running", job.Status)
+	}
+
+	if err := repo.Complete(ctx, due.Id, 2); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	counts, err := repo.Counts(ctx)
+	if err != nil {
+		t.Fatalf("Counts() error = %v", err)
+	}
+	if counts[jobs.StatusDone] != 1 || counts[jobs.StatusPending] != 2 {
+		t.Errorf("counts = %v, want 1 done and 2 pending", counts)
+	}
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/domain/user"
+	"server/tests/integration/testdb"
+)
+
+// The count lives in the database, so every instance adds to the same number,
+// and only one of them gets to lock the account and send the notice.
+func TestLoginLockout_CountsAndLocksOnce(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := user.NewUserRepository(tdb.DB)
+	member := seedRevocationUser(t, tdb, "member@example.com")
+
+	for i := 1; i <= 3; i++ {
+		state, err := repo.RecordFailedLogin(ctx, member, time.Hour)
+		if err != nil {
+			t.Fatalf("RecordFailedLogin() error = %v", err)
+		}
+		if state.FailedCount != i {
+			t.Errorf("failure %d: count = %d", i, state.FailedCount)
+		}
+	}
+
+	until := time.Now().UTC().Add(time.Hour)
+	if locked, err := repo.Lock(ctx, member, until); err != nil || !locked {
+		t.Fatalf("first Lock() = %v, %v; want true", locked, err)
+	}
+	if locked, _ := repo.Lock(ctx, member, until); locked {
+		t.Error("second Lock() = true, want false while the account is locked")
+	}
+
+	state, err := repo.FindLoginState(ctx, member)
+	if err != nil {
+		t.Fatalf("FindLoginState() error = %v", err)
+	}
+	if !state.LockedAt(time.Now().UTC()) || state.FailedCount != 0 {
+		t.Errorf("state after Lock() = %+v", state)
+	}
+
+	if err := repo.ClearFailedLogins(ctx, member); err != nil {
+		t.Fatalf("ClearFailedLogins() error = %v", err)
+	}
+	if state, _ := repo.FindLoginState(ctx, member); state.LockedUntil.Valid {
+		t.Error("lock survived ClearFailedLogins()")
+	}
+}
+
+// Failures further apart than the window do not add up.
+func TestLoginLockout_OldFailuresAreForgotten(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := user.NewUserRepository(tdb.DB)
+	member := seedRevocationUser(t, tdb, "member@example.com")
+
+	if _, err := repo.RecordFailedLogin(ctx, member, time.Hour); err != nil {
+		t.Fatalf("RecordFailedLogin() error = %v", err)
+	}
+	if _, err := tdb.DB.Exec(`UPDATE users SET last_failed_login_at = NOW() - INTERVAL '2 hours' WHERE id = $1`, member); err != nil {
+		t.Fatalf("backdating the failure: %v", err)
+	}
+
+	state, err := repo.RecordFailedLogin(ctx, member, time.Hour)
+	if err != nil {
+		t.Fatalf("RecordFailedLogin() error = %v", err)
+	}
+	if state.FailedCount != 1 {
+		t.Errorf("count = %d, want 1", state.FailedCount)
+	}
+}
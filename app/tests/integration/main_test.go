@@ -12,7 +12,16 @@ func TestMain(m *testing.M) {
 	// has to be set before any test reads it.
 	os.Setenv("ALLOW_REGISTRATION", "true")
 
+	// Uploads default to a media directory in the working directory, which
+	// here is the source tree.
+	mediaDir, err := os.MkdirTemp("", "media")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("STORAGE_LOCAL_DIR", mediaDir)
+
 	code := m.Run()
 	testdb.Terminate()
+	os.RemoveAll(mediaDir)
 	os.Exit(code)
 }
@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"server/internal/domain/newsletter"
+	"server/internal/domain/posts"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// A confirmation token works once, a second unsubscribe reports no change,
+// and erasing a subscriber takes their consent history with them.
+func TestNewsletterSubscribers_Lifecycle(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := newsletter.NewSubscriberRepository(tdb.DB)
+	id := uuid.New()
+
+	if err := repo.Create(ctx, newsletter.Subscriber{Id: id, Email: "reader@example.com", CreatedAt: time.Now().UTC()}, "token-hash"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.AddConsent(ctx, newsletter.Consent{Id: uuid.New(), SubscriberId: id, Action: newsletter.ConsentSubscribe, ConsentText: newsletter.ConsentText, CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("AddConsent() error = %v", err)
+	}
+
+	confirmed, err := repo.Confirm(ctx, "token-hash")
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if confirmed.Status != newsletter.StatusConfirmed || !confirmed.ConfirmedAt.Valid {
+		t.Errorf("confirmed = %+v, want confirmed with a time", confirmed)
+	}
+	if _, err := repo.Confirm(ctx, "token-hash"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second Confirm() error = %v, want sql.ErrNoRows", err)
+	}
+
+	for i, want := range []bool{true, false} {
+		changed, err := repo.Unsubscribe(ctx, id)
+		if err != nil {
+			t.Fatalf("Unsubscribe() error = %v", err)
+		}
+		if changed != want {
+			t.Errorf("Unsubscribe() #%d changed = %v, want %v", i+1, changed, want)
+		}
+	}
+
+	if deleted, err := repo.Delete(ctx, id); err != nil || !deleted {
+		t.Fatalf("Delete() = %v, %v", deleted, err)
+	}
+	consents, err := repo.FindConsents(ctx, id)
+	if err != nil {
+		t.Fatalf("FindConsents() error = %v", err)
+	}
+	if len(consents) != 0 {
+		t.Errorf("consents after erasure = %d, want none", len(consents))
+	}
+}
+
+// A send fixes its recipients when it starts and ticks them off as they are
+// queued; a second send for the same period is refused so two instances can
+// never both mail the list.
+func TestNewsletterSends_StartAndResume(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	subscribers := newsletter.NewSubscriberRepository(tdb.DB)
+	sends := newsletter.NewSendRepository(tdb.DB)
+
+	ids := []uuid.UUID{}
+	for i, email := range []string{"a@example.com", "b@example.com"} {
+		id := uuid.New()
+		token := "token-" + email
+		if err := subscribers.Create(ctx, newsletter.Subscriber{Id: id, Email: email, CreatedAt: time.Now().UTC().Add(time.Duration(i) * time.Second)}, token); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := subscribers.Confirm(ctx, token); err != nil {
+			t.Fatalf("Confirm() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := subscribers.Create(ctx, newsletter.Subscriber{Id: uuid.New(), Email: "pending@example.com", CreatedAt: time.Now().UTC()}, "token-pending"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	send := newsletter.Send{Id: uuid.New(), Subject: "Digest", PeriodStart: now.Add(-7 * 24 * time.Hour), PeriodEnd: now, PostCount: 1, StartedAt: now}
+
+	recipients, err := sends.Start(ctx, send)
+	if err != nil || recipients != 2 {
+		t.Fatalf("Start() = %d, %v, want the two confirmed subscribers", recipients, err)
+	}
+
+	again := send
+	again.Id = uuid.New()
+	if recipients, err := sends.Start(ctx, again); err != nil || recipients != 0 {
+		t.Fatalf("second Start() for the period = %d, %v, want it refused", recipients, err)
+	}
+
+	if err := sends.MarkQueued(ctx, send.Id, ids[0]); err != nil {
+		t.Fatalf("MarkQueued() error = %v", err)
+	}
+	pending, err := sends.PendingRecipients(ctx, send.Id)
+	if err != nil {
+		t.Fatalf("PendingRecipients() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].Id != ids[1] {
+		t.Fatalf("pending = %+v, want only the subscriber not yet queued", pending)
+	}
+
+	latest, err := sends.Latest(ctx)
+	if err != nil || latest.Id != send.Id || latest.FinishedAt.Valid || latest.RecipientCount != 2 {
+		t.Fatalf("Latest() = %+v, %v, want the unfinished send", latest, err)
+	}
+
+	if err := sends.Finish(ctx, send.Id, now); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if latest, err := sends.Latest(ctx); err != nil || !latest.FinishedAt.Valid {
+		t.Fatalf("Latest() after Finish = %+v, %v, want it finished", latest, err)
+	}
+}
+
+// The digest's posts are chosen by the window in the query, so the posts
+// published after it cannot take the places of those inside it.
+func TestNewsletterDigest_PostsInTheWindow(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+	tdb.EnsureCategories(t)
+
+	ctx := context.Background()
+	author := seedRevocationUser(t, tdb, "author@example.com")
+	category := tdb.GetCategoryId(t, "fitnes-zali")
+
+	until := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+	since := until.Add(-7 * 24 * time.Hour)
+	publish := func(slug string, at time.Time) {
+		id := tdb.SeedTestPost(t, slug, slug, "Content", category, author.String(), "published")
+		if _, err := tdb.DB.Exec(`UPDATE posts SET published_at = $2 WHERE id = $1`, id, at); err != nil {
+			t.Fatalf("setting published_at: %v", err)
+		}
+	}
+
+	publish("before", since)
+	publish("first", since.Add(time.Hour))
+	publish("last", until)
+	for i := range 12 {
+		publish(fmt.Sprintf("after-%d", i), until.Add(time.Duration(i+1)*time.Minute))
+	}
+
+	found, err := posts.NewPostRepository(tdb.DB).FindPublishedBetween(ctx, since, until, 10)
+	if err != nil {
+		t.Fatalf("FindPublishedBetween() error = %v", err)
+	}
+	if len(found) != 2 || found[0].Slug != "last" || found[1].Slug != "first" {
+		t.Fatalf("FindPublishedBetween() = %+v, want last and first", found)
+	}
+}
@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"server/internal/domain/outbox"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// A claimed message is not handed to another worker while its lease lasts,
+// delivering it drops the body, and only a dead message can be retried.
+func TestOutbox_ClaimDeliverAndRetry(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := outbox.NewOutboxRepository(tdb.DB)
+	id := uuid.New()
+
+	err := repo.Enqueue(ctx, outbox.Message{
+		Id:        id,
+		Recipient: "reader@example.com",
+		Subject:   "Смяна на парола",
+		Body:      "<a href=\"/reset-password?token=secret\">",
+		Headers:   map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+		CreatedAt: time.Now().UTC().Add(-time.Second),
+	})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	claimed, err := repo.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Attempts != 1 || claimed[0].Headers["List-Unsubscribe"] == "" {
+		t.Fatalf("claimed = %+v, want the message on its first attempt with its headers", claimed)
+	}
+	if again, err := repo.Claim(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Fatalf("second Claim() = %d messages, %v; want none while leased", len(again), err)
+	}
+
+	if retried, err := repo.Retry(ctx, id); err != nil || retried {
+		t.Errorf("Retry() of a pending message = %v, %v; want false", retried, err)
+	}
+
+	if err := repo.MarkSent(ctx, id); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	var body string
+	if err := tdb.DB.QueryRowContext(ctx, `SELECT body FROM email_outbox WHERE id = $1`, id).Scan(&body); err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if body != "" {
+		t.Errorf("body after delivery = %q, want it dropped", body)
+	}
+
+	deadId := uuid.New()
+	if err := repo.Enqueue(ctx, outbox.Message{Id: deadId, Recipient: "gone@example.com", Subject: "s", Body: "b", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := repo.MarkDead(ctx, deadId, "550 no such user"); err != nil {
+		t.Fatalf("MarkDead() error = %v", err)
+	}
+
+	failures, total, err := repo.FindFailures(ctx, 10, 0)
+	if err != nil || total != 1 || failures[0].Id != deadId || failures[0].LastError != "550 no such user" {
+		t.Fatalf("FindFailures() = %+v, %d, %v; want the dead message only", failures, total, err)
+	}
+
+	if retried, err := repo.Retry(ctx, deadId); err != nil || !retried {
+		t.Fatalf("Retry() of a dead message = %v, %v", retried, err)
+	}
+	if claimed, err := repo.Claim(ctx, 10, time.Minute); err != nil || len(claimed) != 1 || claimed[0].Attempts != 1 {
+		t.Fatalf("Claim() after retry = %+v, %v; want it due again with fresh attempts", claimed, err)
+	}
+}
@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"server/internal/domain/spam"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// Training counts each document once per label. Training it again the same
+// way changes nothing, and relabelling it moves its tokens across instead of
+// counting them twice.
+func TestSpamTokens_TrainAndRelabel(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := spam.NewTokenRepository(tdb.DB)
+	document := uuid.New()
+
+	for range 2 {
+		if err := repo.Train(ctx, document, []string{"евтини", "часовници"}, spam.LabelSpam); err != nil {
+			t.Fatalf("Train() error = %v", err)
+		}
+	}
+	if err := repo.Train(ctx, uuid.New(), []string{"евтини"}, spam.LabelHam); err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+
+	counts, totals, err := repo.Counts(ctx, []string{"евтини", "часовници", "непознато"})
+	if err != nil {
+		t.Fatalf("Counts() error = %v", err)
+	}
+	if totals != (spam.Totals{Spam: 1, Ham: 1}) {
+		t.Errorf("totals = %+v, want one of each", totals)
+	}
+	if counts["евтини"] != (spam.TokenCount{Spam: 1, Ham: 1}) || counts["часовници"] != (spam.TokenCount{Spam: 1}) {
+		t.Errorf("counts = %+v", counts)
+	}
+	if _, ok := counts["непознато"]; ok {
+		t.Error("an unseen token was returned")
+	}
+
+	if err := repo.Train(ctx, document, []string{"евтини", "часовници"}, spam.LabelHam); err != nil {
+		t.Fatalf("relabel Train() error = %v", err)
+	}
+
+	counts, totals, err = repo.Counts(ctx, []string{"евтини", "часовници"})
+	if err != nil {
+		t.Fatalf("Counts() error = %v", err)
+	}
+	if totals != (spam.Totals{Ham: 2}) {
+		t.Errorf("totals after relabel = %+v, want two ham", totals)
+	}
+	if counts["евтини"] != (spam.TokenCount{Ham: 2}) || counts["часовници"] != (spam.TokenCount{Ham: 1}) {
+		t.Errorf("counts after relabel = %+v", counts)
+	}
+}
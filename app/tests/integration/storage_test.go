@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"server/internal/infrastructure/storage"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	minioUser     = "minioadmin"
+	minioPassword = "minioadmin"
+	minioBucket   = "uploads"
+)
+
+// startMinIO runs MinIO with a bucket anyone may read, the way a bucket
+// behind S3_PUBLIC_URL is set up, and returns its endpoint.
+func startMinIO(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "minio/minio:latest",
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{"9000/tcp"},
+			Env: map[string]string{
+				"MINIO_ROOT_USER":     minioUser,
+				"MINIO_ROOT_PASSWORD": minioPassword,
+			},
+			WaitingFor: wait.ForHTTP("/minio/health/ready").WithPort("9000/tcp").WithStartupTimeout(60 * time.Second),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to start MinIO: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "9000/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := fmt.Sprintf("%s:%s", host, port.Port())
+
+	admin, err := minio.New(endpoint, &minio.Options{Creds: credentials.NewStaticV4(minioUser, minioPassword, "")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.MakeBucket(ctx, minioBucket, minio.MakeBucketOptions{}); err != nil {
+		t.Fatalf("MakeBucket: %v", err)
+	}
+	policy := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::%s/*"]}]}`, minioBucket)
+	if err := admin.SetBucketPolicy(ctx, minioBucket, policy); err != nil {
+		t.Fatalf("SetBucketPolicy: %v", err)
+	}
+
+	return "http://" + endpoint
+}
+
+// The S3 backend against a real S3 API: what is uploaded can be fetched from
+// its public URL with the headers set at upload, is listed, and is gone after
+// Delete.
+func TestStorage_S3(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	store, err := storage.NewS3(storage.S3Options{
+		Endpoint:  startMinIO(t),
+		Region:    "us-east-1",
+		Bucket:    minioBucket,
+		AccessKey: minioUser,
+		SecretKey: minioPassword,
+	})
+	if err != nil {
+		t.Fatalf("NewS3() = %v", err)
+	}
+
+	result, err := store.UploadRaw(ctx, strings.NewReader("<gpx/>"), "Morning Run.gpx")
+	if err != nil {
+		t.Fatalf("UploadRaw() = %v", err)
+	}
+	if !strings.HasPrefix(result.Key, "files/") || !strings.HasSuffix(result.Key, ".gpx") {
+		t.Errorf("key = %q, want files/...gpx", result.Key)
+	}
+
+	resp, err := http.Get(result.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "<gpx/>" {
+		t.Fatalf("GET %s = %d %q", result.URL, resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/gpx+xml" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := resp.Header.Get("Cache-Control"); !strings.Contains(got, "immutable") {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	assets, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() = %v", err)
+	}
+	if len(assets) != 1 || assets[0].Key != result.Key || assets[0].Bytes != 6 {
+		t.Errorf("List() = %+v", assets)
+	}
+
+	if err := store.Delete(ctx, result.Key); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if assets, _ := store.List(ctx); len(assets) != 0 {
+		t.Errorf("List() after Delete = %+v", assets)
+	}
+	if err := store.Delete(ctx, result.Key); err != nil {
+		t.Errorf("deleting twice = %v, want nil", err)
+	}
+}
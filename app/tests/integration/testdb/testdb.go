@@ -228,6 +228,20 @@ func seedInitialData(db *sql.DB) error {
 		return fmt.Errorf("failed to seed roles_permissions: %w", err)
 	}
 
+	// The test ADMIN role stands in for the migrated one, so it needs the same
+	// permissions or every admin route would turn the tests away.
+	_, err = db.Exec(`
+		INSERT INTO roles_permissions (role_id, permission_id)
+		SELECT '22222222-2222-2222-2222-222222222222', rp.permission_id
+		FROM roles_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		WHERE r.name = 'ADMIN' AND r.id <> '22222222-2222-2222-2222-222222222222'
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to copy the admin permissions: %w", err)
+	}
+
 	// Seed categories
 	_, err = db.Exec(`
 		INSERT INTO categories (id, name, slug, image_url, is_deleted) VALUES
@@ -248,7 +262,25 @@ func (tdb *TestDB) CleanupTables(t *testing.T) {
 	t.Helper()
 
 	tables := []string{
+		"audit_events",
+		"contact_replies",
+		"contact_messages",
+		"email_outbox",
+		"jobs",
+		"newsletter_send_recipients",
+		"newsletter_consents",
+		"newsletter_subscribers",
+		"newsletter_sends",
+		"spam_documents",
+		"spam_tokens",
+		"email_verification_tokens",
+		"email_change_tokens",
+		"account_deletion_tokens",
+		"account_unlock_tokens",
 		"password_reset_tokens",
+		"comments",
+		"image_usages",
+		"image_variants",
 		"images",
 		"posts",
 		"categories",
@@ -285,8 +317,8 @@ func (tdb *TestDB) SeedTestUser(t *testing.T, email, hashedPassword string) stri
 
 	id := "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
 	_, err := tdb.DB.Exec(`
-		INSERT INTO users (id, email, password, first_name, last_name, status, is_deleted)
-		VALUES ($1, $2, $3, 'Test', 'User', 'ACTIVE', false)
+		INSERT INTO users (id, email, password, first_name, last_name, status, is_deleted, email_verified_at)
+		VALUES ($1, $2, $3, 'Test', 'User', 'ACTIVE', false, NOW())
 	`, id, email, hashedPassword)
 	if err != nil {
 		t.Fatalf("Failed to seed test user: %v", err)
@@ -295,6 +327,20 @@ func (tdb *TestDB) SeedTestUser(t *testing.T, email, hashedPassword string) stri
 	return id
 }
 
+// VerifyUserEmail marks a registered user's address as verified, standing in
+// for the link a real registration receives by email.
+func (tdb *TestDB) VerifyUserEmail(t *testing.T, email string) {
+	t.Helper()
+
+	_, err := tdb.DB.Exec(`
+		UPDATE users SET email_verified_at = NOW(), status = 'Active'
+		WHERE email = $1
+	`, email)
+	if err != nil {
+		t.Fatalf("Failed to verify test user: %v", err)
+	}
+}
+
 // SeedTestRole creates a test role and returns the role ID
 func (tdb *TestDB) SeedTestRole(t *testing.T, name string) string {
 	t.Helper()
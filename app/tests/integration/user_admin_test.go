@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"server/internal/domain/user"
+	"server/tests/integration/testdb"
+
+	"github.com/google/uuid"
+)
+
+// Whatever order the changes come in, one active administrator has to remain,
+// or nobody is left who could undo them.
+func TestUserAdmin_LastAdministratorCannotBeRemoved(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := user.NewUserRepository(tdb.DB)
+
+	first := seedRevocationUser(t, tdb, "first-admin@example.com")
+	second := seedRevocationUser(t, tdb, "second-admin@example.com")
+	for _, id := range []uuid.UUID{first, second} {
+		if err := repo.GrantRole(ctx, id, user.RoleAdmin); err != nil {
+			t.Fatalf("GrantRole() error = %v", err)
+		}
+	}
+
+	if err := repo.UpdateStatus(ctx, first, user.Suspended); err != nil {
+		t.Fatalf("suspending one of two administrators: %v", err)
+	}
+
+	if err := repo.RevokeRole(ctx, second, user.RoleAdmin); !errors.Is(err, user.ErrLastAdmin) {
+		t.Errorf("RevokeRole() on the last active administrator = %v, want ErrLastAdmin", err)
+	}
+
+	if err := repo.UpdateStatus(ctx, second, user.Suspended); !errors.Is(err, user.ErrLastAdmin) {
+		t.Errorf("UpdateStatus() on the last active administrator = %v, want ErrLastAdmin", err)
+	}
+
+	if err := repo.EnsureNotLastAdmin(ctx, second); !errors.Is(err, user.ErrLastAdmin) {
+		t.Errorf("EnsureNotLastAdmin() on the last active administrator = %v, want ErrLastAdmin", err)
+	}
+
+	// Nor can they erase their account, even once the deletion falls due.
+	if err := repo.ScheduleDeletion(ctx, second, time.Now().UTC().Add(-time.Minute)); err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+	if err := repo.Anonymise(ctx, second); !errors.Is(err, user.ErrLastAdmin) {
+		t.Errorf("Anonymise() on the last active administrator = %v, want ErrLastAdmin", err)
+	}
+	if _, err := repo.CancelDeletion(ctx, second.String()); err != nil {
+		t.Fatalf("CancelDeletion() error = %v", err)
+	}
+
+	// Bringing the first one back makes room to demote the second.
+	if err := repo.UpdateStatus(ctx, first, user.Active); err != nil {
+		t.Fatalf("reactivating an administrator: %v", err)
+	}
+
+	if err := repo.RevokeRole(ctx, second, user.RoleAdmin); err != nil {
+		t.Errorf("RevokeRole() with another active administrator = %v", err)
+	}
+}
+
+// Suspension has to end the sessions already open, not only refuse new ones.
+func TestUserAdmin_SuspensionRevokesSessions(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := user.NewUserRepository(tdb.DB)
+
+	member := seedRevocationUser(t, tdb, "member@example.com")
+
+	if err := repo.UpdateStatus(ctx, member, user.Suspended); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	cutoff, err := repo.TokensValidAfter(ctx, member.String())
+	if err != nil || !cutoff.Valid {
+		t.Fatalf("TokensValidAfter() = %v, %v; want a cutoff", cutoff, err)
+	}
+}
+
+// The query is matched literally, so a wildcard in it cannot list everyone.
+func TestUserAdmin_SearchByEmail(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	defer tdb.CleanupTables(t)
+
+	ctx := context.Background()
+	repo := user.NewUserRepository(tdb.DB)
+
+	seedRevocationUser(t, tdb, "ivana@example.com")
+	seedRevocationUser(t, tdb, "petar@example.com")
+
+	found, total, err := repo.FindPage(ctx, "IVANA", 20, 0)
+	if err != nil {
+		t.Fatalf("FindPage() error = %v", err)
+	}
+
+	if total != 1 || len(found) != 1 || found[0].Email != "ivana@example.com" {
+		t.Errorf("FindPage(IVANA) = %d results, total %d", len(found), total)
+	}
+
+	if _, total, _ := repo.FindPage(ctx, "%", 20, 0); total != 0 {
+		t.Errorf("FindPage(%%) total = %d, want 0", total)
+	}
+}
@@ -0,0 +1,107 @@
+// Package bloom is a Bloom filter over SHA-1 digests, compact enough to ship a
+// large password corpus inside the binary. Keying on SHA-1 lets the filter be
+// built straight from hash lists such as Have I Been Pwned's, without the
+// plaintext passwords.
+package bloom
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var magic = [4]byte{'B', 'L', 'M', '1'}
+
+var ErrInvalidFormat = errors.New("not a bloom filter file")
+
+type Filter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint32
+}
+
+// New sizes a filter for n entries with the given false positive rate.
+func New(n int, falsePositiveRate float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+
+	return &Filter{bits: make([]uint64, (m+63)/64), m: m, hashes: k}
+}
+
+func (f *Filter) Add(digest [sha1.Size]byte) {
+	h1, h2 := split(digest)
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether the digest may have been added. False positives
+// happen at the rate the filter was sized for; false negatives never do.
+func (f *Filter) Contains(digest [sha1.Size]byte) bool {
+	h1, h2 := split(digest)
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteTo writes the filter as the magic, the hash count, the bit count and
+// the bits, all big endian.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 16)
+	copy(header, magic[:])
+	binary.BigEndian.PutUint32(header[4:], f.hashes)
+	binary.BigEndian.PutUint64(header[8:], f.m)
+
+	written, err := w.Write(header)
+	if err != nil {
+		return int64(written), err
+	}
+
+	body := make([]byte, 8*len(f.bits))
+	for i, word := range f.bits {
+		binary.BigEndian.PutUint64(body[8*i:], word)
+	}
+
+	n, err := w.Write(body)
+	return int64(written + n), err
+}
+
+// Parse reads a filter written by WriteTo.
+func Parse(data []byte) (*Filter, error) {
+	if len(data) < 16 || [4]byte(data[:4]) != magic {
+		return nil, ErrInvalidFormat
+	}
+
+	hashes := binary.BigEndian.Uint32(data[4:])
+	m := binary.BigEndian.Uint64(data[8:])
+	words := (m + 63) / 64
+	if hashes == 0 || m == 0 || uint64(len(data)-16) != 8*words {
+		return nil, fmt.Errorf("%w: header does not match the size", ErrInvalidFormat)
+	}
+
+	bits := make([]uint64, words)
+	for i := range bits {
+		bits[i] = binary.BigEndian.Uint64(data[16+8*i:])
+	}
+
+	return &Filter{bits: bits, m: m, hashes: hashes}, nil
+}
+
+// split derives the two base hashes of double hashing from the digest. The
+// second is forced odd so it never collapses every probe onto one bit.
+func split(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
@@ -0,0 +1,54 @@
+package bloom
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"testing"
+)
+
+// Whatever was added must be found again after a round trip through the file
+// format, and the false positive rate must stay near what was asked for.
+func TestFilter_RoundTrip(t *testing.T) {
+	filter := New(1000, 0.001)
+	for i := 0; i < 1000; i++ {
+		filter.Add(sha1.Sum([]byte(fmt.Sprintf("member-%d", i))))
+	}
+
+	var buf bytes.Buffer
+	if _, err := filter.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	parsed, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !parsed.Contains(sha1.Sum([]byte(fmt.Sprintf("member-%d", i)))) {
+			t.Fatalf("member-%d missing after the round trip", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if parsed.Contains(sha1.Sum([]byte(fmt.Sprintf("stranger-%d", i)))) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("%d false positives in 10000, want about 10", falsePositives)
+	}
+}
+
+func TestParse_RejectsTruncatedFile(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := New(10, 0.01).WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	if _, err := Parse(buf.Bytes()[:buf.Len()-1]); err == nil {
+		t.Error("Parse() accepted a truncated file")
+	}
+}
@@ -16,8 +16,15 @@ const (
 	contextKeyRequestId contextKey = "requestId"
 	xsrfKey             contextKey = "xsrf"
 	loggedUser          contextKey = "user"
+	clientKey           contextKey = "client"
 )
 
+// Client is who sent the request, as far as the server can tell.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
 func RequestIdFromContext(ctx context.Context) string {
 	value := ctx.Value(contextKeyRequestId)
 	if value == nil {
@@ -36,6 +43,16 @@ func WithRequestId(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, contextKeyRequestId, id)
 }
 
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey, client)
+}
+
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey).(Client)
+
+	return client
+}
+
 func WithCSRFToken(ctx context.Context, token string) context.Context {
 	return context.WithValue(ctx, xsrfKey, token)
 }
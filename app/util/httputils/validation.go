@@ -75,8 +75,11 @@ func newValidator() *validator.Validate {
 
 	// "strongpassword" is for passwords being set, never for login: existing
 	// accounts may hold weaker passwords and must still be able to sign in.
+	// Composition alone lets "Password123!" through, so known breached
+	// passwords are refused as well.
 	if err := v.RegisterValidation("strongpassword", func(fl validator.FieldLevel) bool {
-		return securityutil.IsPasswordStrong(fl.Field().String())
+		password := fl.Field().String()
+		return securityutil.IsPasswordStrong(password) && !securityutil.IsPasswordBreached(password)
 	}); err != nil {
 		panic(fmt.Sprintf("could not register the strongpassword rule: %v", err))
 	}
@@ -128,6 +131,11 @@ func isSecretField(field string) bool {
 	return strings.Contains(lower, "password") || strings.Contains(lower, "token") || strings.Contains(lower, "secret")
 }
 
+// MsgPasswordBreached is the strongpassword message for a password that is
+// well formed but known from a breach. The forms look for it to explain the
+// refusal instead of repeating the composition rules.
+const MsgPasswordBreached = "password appears in a list of breached passwords"
+
 // getErrorMessage renders a human readable message for a failed rule. These
 // surface in JSON responses via SendFailedValidationResponse; the HTMX form
 // path renders its own copy keyed on the field name. Unknown tags must still
@@ -145,6 +153,9 @@ func getErrorMessage(fieldErr validator.FieldError) string {
 	case "oneof":
 		return fmt.Sprintf("field must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
 	case "strongpassword":
+		if password, ok := fieldErr.Value().(string); ok && securityutil.IsPasswordStrong(password) {
+			return MsgPasswordBreached
+		}
 		return fmt.Sprintf(
 			"password must be at least %d characters and include an upper case letter, a lower case letter, a digit and a symbol",
 			securityutil.MinPasswordLength)
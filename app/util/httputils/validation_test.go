@@ -348,3 +348,20 @@ func TestProcessRequestBody_TooLarge(t *testing.T) {
 		t.Errorf("Status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
 	}
 }
+
+// A password that passes the composition rules but is known from a breach is
+// refused with its own message, so the form can say why.
+func TestValidatePayload_StrongPasswordRefusesBreached(t *testing.T) {
+	type payload struct {
+		Password string `json:"password" validate:"required,strongpassword"`
+	}
+
+	errors := validatePayload(&payload{Password: "Password123!"})
+	if len(errors) != 1 || errors[0].Error != MsgPasswordBreached {
+		t.Fatalf("validatePayload(Password123!) = %v, want the breached message", errors)
+	}
+
+	if errors := validatePayload(&payload{Password: "Tr4m-Lines!Kestrel"}); len(errors) != 0 {
+		t.Errorf("validatePayload(uncommon password) = %v", errors)
+	}
+}
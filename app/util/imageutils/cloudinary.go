@@ -4,6 +4,10 @@
 // The transformation happens on delivery rather than on upload: the original
 // stays in Cloudinary, so a crop can be redone and a future format can be
 // adopted without re-uploading anything. Only the bytes on the wire change.
+//
+// Images kept on disk or in a bucket have nothing to transform them on
+// delivery, so copies are made when they are uploaded. The same functions
+// serve those copies once UseVariants tells the package where to find them.
 package imageutils
 
 import (
@@ -34,13 +38,17 @@ var HeroWidths = []int{640, 1024, 1600, 2000}
 // 848 CSS pixels.
 const ContentWidth = 1600
 
-// Resized returns the URL for a single width. A URL that is not a Cloudinary
-// delivery URL is returned unchanged: the cover field accepts any address the
-// author pastes, and rewriting a foreign one would produce a broken link.
+// Resized returns the URL for a single width. A URL that is neither a
+// Cloudinary delivery URL nor an upload with stored copies is returned
+// unchanged: the cover field accepts any address the author pastes, and
+// rewriting a foreign one would produce a broken link.
 //
 // c_limit only ever shrinks, so an image smaller than the requested width is
 // served as it is rather than upscaled into blur.
 func Resized(rawURL string, width int) string {
+	if resized, ok := storedResized(rawURL, width); ok {
+		return resized
+	}
 	return transform(rawURL, fmt.Sprintf("%s,c_limit,w_%d", autoFormat, width))
 }
 
@@ -55,7 +63,7 @@ func Optimized(rawURL string) string {
 // element entirely rather than emitting a srcset of one useless candidate.
 func Srcset(rawURL string, widths []int) string {
 	if !isCloudinary(rawURL) {
-		return ""
+		return storedSrcset(rawURL, widths)
 	}
 
 	candidates := make([]string, 0, len(widths))
@@ -70,8 +78,9 @@ func Srcset(rawURL string, widths []int) string {
 // URLs. The editor saves whatever URL the upload returned, so this is the only
 // place the article body can be improved without rewriting the database.
 //
-// Only the Cloudinary delivery prefix is touched; the surrounding markup is
-// left exactly as the author saved it.
+// Only the Cloudinary delivery prefix is touched, and images with stored
+// copies gain a srcset and a <picture>; the surrounding markup is left
+// exactly as the author saved it.
 func RewriteContentImages(html string) string {
 	params := fmt.Sprintf("%s,c_limit,w_%d", autoFormat, ContentWidth)
 
@@ -104,7 +113,7 @@ func RewriteContentImages(html string) string {
 		html = out.String()
 	}
 
-	return html
+	return rewriteStoredImages(html)
 }
 
 func transform(rawURL string, params string) string {
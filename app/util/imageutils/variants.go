@@ -0,0 +1,206 @@
+package imageutils
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Variant is a stored copy of an uploaded image. Storage other than
+// Cloudinary cannot resize on delivery, so the copies are made when the image
+// is uploaded.
+type Variant struct {
+	URL   string
+	Width int
+	// Format is "jpeg", "png", "gif", "webp" or "avif".
+	Format string
+}
+
+// VariantLookup returns the stored copies of the image at url, the original
+// among them, or nothing when there are none.
+type VariantLookup func(url string) []Variant
+
+var lookup atomic.Pointer[VariantLookup]
+
+// UseVariants sets where stored variants are found. Until it is called no
+// URL has any, and only Cloudinary URLs are resized.
+func UseVariants(fn VariantLookup) {
+	lookup.Store(&fn)
+}
+
+func storedVariants(rawURL string) []Variant {
+	if fn := lookup.Load(); fn != nil {
+		return (*fn)(rawURL)
+	}
+	return nil
+}
+
+// ContentWidths are the widths offered for images inside the article body.
+var ContentWidths = []int{400, 800, 1200, ContentWidth}
+
+// contentSizes is how wide an image in the article body is painted: the
+// whole column, which stops at 848 pixels.
+const contentSizes = "(min-width: 848px) 848px, 100vw"
+
+// VariantWidths are the widths copies of an upload are made at, enough to
+// serve every srcset on the site.
+func VariantWidths() []int {
+	widths := slices.Concat(CardWidths, HeroWidths, ContentWidths)
+	slices.Sort(widths)
+	return slices.Compact(widths)
+}
+
+// modernFormats are offered through <source>, best first: a browser takes
+// the first one it supports.
+var modernFormats = []string{"avif", "webp"}
+
+// Source is one <source> of a <picture>.
+type Source struct {
+	Type   string
+	Srcset string
+}
+
+// Sources returns the <source> elements offering the stored copies of an
+// image in the modern formats. It is empty for Cloudinary, which picks the
+// format per browser on its own, and for an image without copies.
+func Sources(rawURL string, widths []int) []Source {
+	variants := storedVariants(rawURL)
+
+	var sources []Source
+	for _, format := range modernFormats {
+		if picked := candidates(variants, format, widths); len(picked) > 0 {
+			sources = append(sources, Source{Type: "image/" + format, Srcset: joinCandidates(picked)})
+		}
+	}
+
+	return sources
+}
+
+// storedResized is the stored copy to show at width: the narrowest that is
+// at least as wide, or the widest there is. Only the format every browser
+// shows is considered, since a plain src has no type to fall back on.
+func storedResized(rawURL string, width int) (string, bool) {
+	variants := storedVariants(rawURL)
+	picked := candidates(variants, fallbackFormat(variants), []int{width})
+	if len(picked) == 0 {
+		return "", false
+	}
+	return picked[0].URL, true
+}
+
+// storedSrcset is Srcset for an image with stored copies.
+func storedSrcset(rawURL string, widths []int) string {
+	variants := storedVariants(rawURL)
+	picked := candidates(variants, fallbackFormat(variants), widths)
+	if len(picked) < 2 {
+		return ""
+	}
+	return joinCandidates(picked)
+}
+
+// fallbackFormat is the format of the copies every browser shows, which is
+// that of the original unless it was WebP or AVIF.
+func fallbackFormat(variants []Variant) string {
+	for _, variant := range variants {
+		if !slices.Contains(modernFormats, variant.Format) {
+			return variant.Format
+		}
+	}
+	return ""
+}
+
+// candidates picks the copies in format to offer for the given widths: for
+// each, the narrowest copy at least that wide, or the widest there is when
+// none is. The result is ordered by width.
+func candidates(variants []Variant, format string, widths []int) []Variant {
+	var ofFormat []Variant
+	for _, variant := range variants {
+		if variant.Format == format {
+			ofFormat = append(ofFormat, variant)
+		}
+	}
+	if len(ofFormat) == 0 {
+		return nil
+	}
+	slices.SortFunc(ofFormat, func(a, b Variant) int { return a.Width - b.Width })
+
+	var picked []Variant
+	for _, width := range widths {
+		at := slices.IndexFunc(ofFormat, func(v Variant) bool { return v.Width >= width })
+		if at < 0 {
+			at = len(ofFormat) - 1
+		}
+		if !slices.Contains(picked, ofFormat[at]) {
+			picked = append(picked, ofFormat[at])
+		}
+	}
+
+	slices.SortFunc(picked, func(a, b Variant) int { return a.Width - b.Width })
+	return picked
+}
+
+func joinCandidates(variants []Variant) string {
+	candidates := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		candidates = append(candidates, variant.URL+" "+strconv.Itoa(variant.Width)+"w")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+var (
+	imgTag  = regexp.MustCompile(`<img\s[^>]*>`)
+	srcAttr = regexp.MustCompile(`\ssrc="([^"]*)"`)
+)
+
+// rewriteStoredImages gives each image in post HTML that has stored copies a
+// srcset of them, and wraps it in a <picture> offering the modern formats.
+// An image that already has a srcset was given one by its author and is left
+// alone, as is everything around the images.
+func rewriteStoredImages(content string) string {
+	if lookup.Load() == nil {
+		return content
+	}
+
+	return imgTag.ReplaceAllStringFunc(content, func(tag string) string {
+		if strings.Contains(tag, " srcset=") {
+			return tag
+		}
+		match := srcAttr.FindStringSubmatchIndex(tag)
+		if match == nil {
+			return tag
+		}
+
+		src := html.UnescapeString(tag[match[2]:match[3]])
+		resized, ok := storedResized(src, ContentWidth)
+		if !ok {
+			return tag
+		}
+
+		attributes := ""
+		if srcset := storedSrcset(src, ContentWidths); srcset != "" {
+			attributes = ` srcset="` + html.EscapeString(srcset) + `" sizes="` + contentSizes + `"`
+		}
+		end := len(tag) - len(">")
+		if strings.HasSuffix(tag, "/>") {
+			end = len(tag) - len("/>")
+		}
+		img := tag[:match[2]] + html.EscapeString(resized) + strings.TrimRight(tag[match[3]:end], " ") + attributes + tag[end:]
+
+		sources := Sources(src, ContentWidths)
+		if len(sources) == 0 {
+			return img
+		}
+
+		var picture strings.Builder
+		picture.WriteString("<picture>")
+		for _, source := range sources {
+			picture.WriteString(`<source type="` + source.Type + `" srcset="` + html.EscapeString(source.Srcset) + `" sizes="` + contentSizes + `">`)
+		}
+		picture.WriteString(img)
+		picture.WriteString("</picture>")
+		return picture.String()
+	})
+}
@@ -0,0 +1,105 @@
+package imageutils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const storedURL = "/media/images/2026/05/run-0a1b2c3d.jpg"
+
+// useTestVariants stores copies of storedURL, a 1500 pixel JPEG, at 400 and
+// 800 pixels and in WebP.
+func useTestVariants(t *testing.T) {
+	t.Helper()
+
+	variants := []Variant{
+		{URL: storedURL, Width: 1500, Format: "jpeg"},
+		{URL: "/media/run-400.jpg", Width: 400, Format: "jpeg"},
+		{URL: "/media/run-800.jpg", Width: 800, Format: "jpeg"},
+		{URL: "/media/run-400.webp", Width: 400, Format: "webp"},
+		{URL: "/media/run-800.webp", Width: 800, Format: "webp"},
+		{URL: "/media/run-1500.webp", Width: 1500, Format: "webp"},
+	}
+	UseVariants(func(url string) []Variant {
+		if url == storedURL {
+			return variants
+		}
+		return nil
+	})
+	t.Cleanup(func() { lookup.Store(nil) })
+}
+
+// A plain src has no type to fall back on, so it is always the original's
+// format, at the narrowest copy that still fills the width.
+func TestResized_PicksTheStoredCopy(t *testing.T) {
+	useTestVariants(t)
+
+	for width, want := range map[int]string{300: "/media/run-400.jpg", 600: "/media/run-800.jpg", 2000: storedURL} {
+		if got := Resized(storedURL, width); got != want {
+			t.Errorf("Resized(%d) = %q, want %q", width, got, want)
+		}
+	}
+	if got := Resized("https://example.com/photo.jpg", 800); got != "https://example.com/photo.jpg" {
+		t.Errorf("Resized() of a foreign URL = %q", got)
+	}
+}
+
+func TestSrcsetAndSources_StoredCopies(t *testing.T) {
+	useTestVariants(t)
+
+	if got, want := Srcset(storedURL, CardWidths), "/media/run-400.jpg 400w, /media/run-800.jpg 800w, "+storedURL+" 1500w"; got != want {
+		t.Errorf("Srcset() = %q, want %q", got, want)
+	}
+
+	sources := Sources(storedURL, CardWidths)
+	if len(sources) != 1 || sources[0].Type != "image/webp" || !strings.Contains(sources[0].Srcset, "/media/run-1500.webp 1500w") {
+		t.Errorf("Sources() = %+v, want the three WebP copies", sources)
+	}
+
+	if got := Sources(cloudinaryURL, CardWidths); len(got) != 0 {
+		t.Errorf("Sources() of a Cloudinary URL = %+v, want none", got)
+	}
+}
+
+// The article body is the author's markup, saved once and rendered many
+// times: only the images with copies change, and only by gaining them.
+func TestRewriteContentImages_StoredCopies(t *testing.T) {
+	useTestVariants(t)
+
+	html := `<p>Преди</p><img src="` + storedURL + `" alt="Снимка" /><img src="/media/other.jpg" alt="Друга">` +
+		`<img src="` + storedURL + `" srcset="` + storedURL + ` 1500w" alt="Своя">`
+
+	got := RewriteContentImages(html)
+
+	for _, want := range []string{
+		`<p>Преди</p><picture><source type="image/webp" srcset="/media/run-400.webp 400w, /media/run-800.webp 800w, /media/run-1500.webp 1500w" sizes="` + contentSizes + `">`,
+		`<img src="` + storedURL + `" alt="Снимка" srcset="/media/run-400.jpg 400w, /media/run-800.jpg 800w, ` + storedURL + ` 1500w" sizes="` + contentSizes + `"/></picture>`,
+		`<img src="/media/other.jpg" alt="Друга">`,
+		`<img src="` + storedURL + `" srcset="` + storedURL + ` 1500w" alt="Своя">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RewriteContentImages() = %s\nmissing %s", got, want)
+		}
+	}
+	if strings.Count(got, "<picture>") != 1 {
+		t.Errorf("RewriteContentImages() = %s, want exactly one <picture>", got)
+	}
+}
+
+// Every width some srcset asks for must have been made, or the browser is
+// handed the next size up.
+func TestVariantWidths(t *testing.T) {
+	got := VariantWidths()
+
+	for _, widths := range [][]int{CardWidths, HeroWidths, ContentWidths} {
+		for _, width := range widths {
+			if !slices.Contains(got, width) {
+				t.Errorf("VariantWidths() = %v, missing %d", got, width)
+			}
+		}
+	}
+	if !slices.IsSorted(got) {
+		t.Errorf("VariantWidths() = %v, want them in order", got)
+	}
+}
@@ -0,0 +1,38 @@
+package securityutil
+
+import (
+	"crypto/sha1"
+	_ "embed"
+	"errors"
+	"strings"
+	"sync"
+
+	"server/util/bloom"
+)
+
+// ErrPasswordBreached is returned when a new password is known from a breach.
+var ErrPasswordBreached = errors.New("password appears in a list of breached passwords")
+
+// breachedPasswords is built by cmd/breached. It ships inside the binary, so
+// the check needs no network and no file next to the server.
+//
+//go:embed breached-passwords.bloom
+var breachedPasswords []byte
+
+var loadBreached = sync.OnceValue(func() *bloom.Filter {
+	filter, err := bloom.Parse(breachedPasswords)
+	if err != nil {
+		panic("the embedded breached password filter is corrupt: " + err.Error())
+	}
+	return filter
+})
+
+// IsPasswordBreached reports whether the password, or its lower case form,
+// is in the breached password filter. Like the composition rules it is only
+// for passwords being set; a rare false positive just means picking another.
+func IsPasswordBreached(password string) bool {
+	filter := loadBreached()
+
+	return filter.Contains(sha1.Sum([]byte(password))) ||
+		filter.Contains(sha1.Sum([]byte(strings.ToLower(password))))
+}
@@ -0,0 +1,18 @@
+package securityutil
+
+import "testing"
+
+// The shipped filter has to catch the passwords that merely decorate a common
+// word to satisfy the composition rules, in any case. The words are from the
+// seed list, Mark Burnett's top 10,000.
+func TestIsPasswordBreached(t *testing.T) {
+	for _, password := range []string{"Password123!", "Passw0rd1234", "Welcome2024!", "PASSWORD123!", "Letmein@123", "Dragon2026!"} {
+		if !IsPasswordBreached(password) {
+			t.Errorf("IsPasswordBreached(%q) = false, want true", password)
+		}
+	}
+
+	if IsPasswordBreached("Tr4m-Lines!Kestrel") {
+		t.Error("IsPasswordBreached() flagged an uncommon password")
+	}
+}
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"context"
+
+	"server/internal/domain/user"
+	"server/util/ctxutils"
+)
+
+// hasPermission hides controls the signed in user could not use anyway. It is
+// cosmetic: the routes enforce the same permissions.
+func hasPermission(ctx context.Context, name string) bool {
+	loggedUser, err := ctxutils.GetUser(ctx)
+	if err != nil {
+		return false
+	}
+
+	return user.HasPermission(loggedUser.Permissions, name)
+}
@@ -93,3 +93,36 @@ func TestImage_ThumbnailNeedsNoSizes(t *testing.T) {
 		t.Error("the thumbnail is not resized")
 	}
 }
+
+// Away from Cloudinary the modern formats exist only as stored copies, and
+// only a <source> can offer a format a browser may not support.
+func TestImage_PictureForStoredCopies(t *testing.T) {
+	const stored = "/media/images/cover.jpg"
+	imageutils.UseVariants(func(url string) []imageutils.Variant {
+		if url != stored {
+			return nil
+		}
+		return []imageutils.Variant{
+			{URL: stored, Width: 1600, Format: "jpeg"},
+			{URL: "/media/images/cover-800w.jpg", Width: 800, Format: "jpeg"},
+			{URL: "/media/images/cover-800w.avif", Width: 800, Format: "avif"},
+		}
+	})
+	t.Cleanup(func() { imageutils.UseVariants(func(string) []imageutils.Variant { return nil }) })
+
+	html := renderImage(t, ResponsiveImage{URL: stored, Sizes: "100vw", Widths: imageutils.CardWidths, FallbackWidth: 800})
+
+	for _, want := range []string{
+		`<picture><source type="image/avif" srcset="/media/images/cover-800w.avif 800w" sizes="100vw">`,
+		`src="/media/images/cover-800w.jpg"`,
+		`srcset="/media/images/cover-800w.jpg 800w, /media/images/cover.jpg 1600w"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Image() = %s\nmissing %s", html, want)
+		}
+	}
+
+	if plain := renderImage(t, ResponsiveImage{URL: cloudinaryCover, FallbackWidth: 800}); strings.Contains(plain, "<picture>") {
+		t.Errorf("a Cloudinary image got a <picture>: %s", plain)
+	}
+}
@@ -37,6 +37,12 @@ type SEO struct {
 	ModifiedAt  *time.Time
 	AuthorName  string
 	Section     string
+
+	// Discussion marks an article readers can comment on. It is then described
+	// as a DiscussionForumPosting, which is itself a kind of Article, carrying
+	// the number of approved comments.
+	Discussion   bool
+	CommentCount int
 }
 
 // IsArticle reports whether the page describes a published post.
@@ -160,6 +166,17 @@ func (s SEO) StructuredData() map[string]any {
 		article["articleSection"] = s.Section
 	}
 
+	if s.Discussion {
+		article["@type"] = "DiscussionForumPosting"
+		article["text"] = s.Description
+		article["commentCount"] = s.CommentCount
+		article["interactionStatistic"] = map[string]any{
+			"@type":                "InteractionCounter",
+			"interactionType":      "https://schema.org/CommentAction",
+			"userInteractionCount": s.CommentCount,
+		}
+	}
+
 	return article
 }
 
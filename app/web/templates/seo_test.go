@@ -128,6 +128,31 @@ func TestSEO_StructuredDataForArticle(t *testing.T) {
 	}
 }
 
+// A post open for comments is a DiscussionForumPosting, and search engines
+// read the comment count from it even when there are none yet.
+func TestSEO_StructuredDataForDiscussion(t *testing.T) {
+	published := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	data := SEO{Title: "Фитнес", Description: "Описание", PublishedAt: &published, Discussion: true, CommentCount: 4}.StructuredData()
+
+	if got, _ := data["@type"].(string); got != "DiscussionForumPosting" {
+		t.Errorf("@type = %q, want DiscussionForumPosting", got)
+	}
+	if got, _ := data["commentCount"].(int); got != 4 {
+		t.Errorf("commentCount = %d, want 4", got)
+	}
+
+	statistic, _ := data["interactionStatistic"].(map[string]any)
+	if got, _ := statistic["userInteractionCount"].(int); got != 4 {
+		t.Errorf("userInteractionCount = %d, want 4", got)
+	}
+
+	empty := SEO{Title: "Фитнес", PublishedAt: &published, Discussion: true}.StructuredData()
+	if _, ok := empty["commentCount"]; !ok {
+		t.Error("commentCount is missing when there are no comments")
+	}
+}
+
 func TestSEO_StructuredDataForNonArticle(t *testing.T) {
 	data := SEO{Title: "Начало"}.StructuredData()
 